@@ -8,14 +8,23 @@
 
 //
 // Synopsis:
-//	boot [-v][-no-load][-no-exec]
+//	boot [-v][-no-load][-no-exec][-timeout DURATION][-vpd=false][-netboot IFACES]
 //
 // Description:
 //	If returns to u-root shell, the code didn't found a local bootable option
 //
+//	boot shows a menu of the GRUB, syslinux, BootLoaderSpec and ESXi
+//	entries found on local disks, the boot entries in VPD, and optionally
+//	netboot. Entries are chosen by number or with the arrow keys. If no
+//	entry is chosen before the timeout, the default entries are tried in
+//	order.
+//
 //      -v prints messages
 //      -no-load prints the boot image paths it was going to load, but doesn't load + exec them
 //      -no-exec loads the boot image, but doesn't exec it
+//      -timeout how long to wait for a choice before booting the default entries
+//      -vpd adds the VPD boot entries (Boot0000 etc.), as systemboot boots them
+//      -netboot adds an entry that runs pxeboot on interfaces matching IFACES
 //
 // Notes:
 //	The code is looking for boot/grub/grub.cfg file as to identify the
@@ -30,8 +39,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/u-root/u-root/pkg/boot"
 	"github.com/u-root/u-root/pkg/boot/bootcmd"
@@ -48,10 +59,13 @@ var (
 	noLoad  = flag.Bool("no-load", false, "print chosen boot configuration, but do not load + exec it")
 	noExec  = flag.Bool("no-exec", false, "load boot configuration, but do not exec it")
 
-	removeCmdlineItem = flag.String("remove", "console", "comma separated list of kernel params value to remove from parsed kernel configuration (default to console)")
+	removeCmdlineItem = flag.String("remove", "console", "comma separated list of kernel params value to remove from parsed kernel configuration, a trailing * matches all params with that prefix (default to console)")
 	reuseCmdlineItem  = flag.String("reuse", "console", "comma separated list of kernel params value to reuse from current kernel (default to console)")
 	appendCmdline     = flag.String("append", "", "Additional kernel params")
 	blockList         = flag.String("block", "", "comma separated list of pci vendor and device ids to ignore (format vendor:device). E.g. 0x8086:0x1234,0x8086:0xabcd")
+	timeout           = flag.Duration("timeout", 10*time.Second, "how long to wait for a menu choice before booting the default entries")
+	useVPD            = flag.Bool("vpd", true, "add the VPD boot entries (Boot0000 etc.)")
+	netbootIfaces     = flag.String("netboot", "", "add an entry to netboot with pxeboot on interfaces matching this regexp, e.g. ^e.*")
 )
 
 // updateBootCmdline get the kernel command line parameters and filter it:
@@ -98,14 +112,28 @@ func main() {
 	for _, img := range images {
 		// Make changes to the kernel command line based on our cmdline.
 		if li, ok := img.(*boot.LinuxImage); ok {
-			li.Cmdline = updateBootCmdline(li.Cmdline)
+			li.Edit(updateBootCmdline)
 		}
 	}
 
 	menuEntries := menu.OSImages(*verbose, images...)
+	if *useVPD {
+		menuEntries = append(menuEntries, vpdEntries(*verbose)...)
+	}
+	if *netbootIfaces != "" {
+		args := []string{"pxeboot"}
+		if *verbose {
+			args = append(args, "-v")
+		}
+		menuEntries = append(menuEntries, commandEntry{
+			label: fmt.Sprintf("Netboot on %s", *netbootIfaces),
+			args:  append(args, *netbootIfaces),
+		})
+	}
 	menuEntries = append(menuEntries, menu.Reboot{})
 	menuEntries = append(menuEntries, menu.StartShell{})
 
+	menu.SetInitialTimeout(*timeout)
 	// Boot does not return.
 	bootcmd.ShowMenuAndBoot(menuEntries, mountPool, *noLoad, *noExec)
 }
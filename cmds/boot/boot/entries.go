@@ -0,0 +1,94 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/u-root/u-root/pkg/boot/menu"
+	"github.com/u-root/u-root/pkg/boot/systembooter"
+)
+
+// booterEntry is a menu.Entry that runs a VPD boot entry, like systemboot.
+// The booter runs a command, e.g. fbnetboot or localboot, that kexecs.
+type booterEntry struct {
+	systembooter.BootEntry
+	verbose bool
+}
+
+// Label implements menu.Entry.
+func (b booterEntry) Label() string {
+	return fmt.Sprintf("VPD %s (%s)", b.Name, b.Booter.TypeName())
+}
+
+// String implements fmt.Stringer.
+func (b booterEntry) String() string {
+	return fmt.Sprintf("%s: %s", b.Label(), b.Config)
+}
+
+// Edit implements menu.Entry. The kernel command line is up to the booter.
+func (booterEntry) Edit(func(cmdline string) string) {}
+
+// Load implements menu.Entry. The booter loads the kernel itself.
+func (booterEntry) Load() error { return nil }
+
+// Exec implements menu.Entry.
+func (b booterEntry) Exec() error {
+	if err := b.Booter.Boot(b.verbose); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s returned without booting", b.Label())
+}
+
+// IsDefault implements menu.Entry.
+func (booterEntry) IsDefault() bool { return true }
+
+// vpdEntries returns the boot entries in VPD that have a booter.
+func vpdEntries(verbose bool) []menu.Entry {
+	var entries []menu.Entry
+	for _, e := range systembooter.GetBootEntries() {
+		if _, ok := e.Booter.(*systembooter.NullBooter); ok || e.Booter == nil {
+			continue
+		}
+		entries = append(entries, booterEntry{BootEntry: e, verbose: verbose})
+	}
+	return entries
+}
+
+// commandEntry is a menu.Entry that runs a boot command, e.g. pxeboot.
+type commandEntry struct {
+	label string
+	args  []string
+}
+
+// Label implements menu.Entry.
+func (c commandEntry) Label() string { return c.label }
+
+// String implements fmt.Stringer.
+func (c commandEntry) String() string {
+	return fmt.Sprintf("%s: %v", c.label, c.args)
+}
+
+// Edit implements menu.Entry.
+func (commandEntry) Edit(func(cmdline string) string) {}
+
+// Load implements menu.Entry.
+func (commandEntry) Load() error { return nil }
+
+// Exec implements menu.Entry.
+func (c commandEntry) Exec() error {
+	cmd := exec.Command(c.args[0], c.args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %v", c.args, err)
+	}
+	return fmt.Errorf("%v returned without booting", c.args)
+}
+
+// IsDefault implements menu.Entry. Netbooting takes a while, so it is only
+// done if chosen.
+func (commandEntry) IsDefault() bool { return false }
@@ -5,9 +5,9 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/insomniacslk/dhcp/dhcpv4"
 	"github.com/insomniacslk/dhcp/dhcpv6"
 	"github.com/insomniacslk/dhcp/iana"
@@ -28,6 +29,8 @@ import (
 	"github.com/insomniacslk/dhcp/netboot"
 	"github.com/u-root/u-root/pkg/boot/kexec"
 	"github.com/u-root/u-root/pkg/crypto"
+	"github.com/u-root/u-root/pkg/curl"
+	"github.com/u-root/u-root/pkg/uio"
 )
 
 var (
@@ -44,6 +47,7 @@ var (
 	caCertFile         = flag.String("cacerts", "/etc/cacerts.pem", "CA cert file")
 	skipCertVerify     = flag.Bool("skip-cert-verify", false, "Don't authenticate https certs")
 	doFix              = flag.Bool("fix", false, "Try to run fixmynetboot if netboot fails")
+	pubKeyFile         = flag.String("pubkey", "", "PEM public key (RSA or ED25519) that has to verify the detached signature at the boot file URL + \".sig\". If empty, the boot file is not verified")
 )
 
 const (
@@ -76,6 +80,13 @@ func main() {
 	}
 	log.Print(banner)
 
+	if *pubKeyFile != "" {
+		var err error
+		if pubKey, err = loadPublicKey(*pubKeyFile); err != nil {
+			log.Fatalf("Cannot load public key: %v", err)
+		}
+	}
+
 	if !*useV6 && !*useV4 {
 		log.Fatal("At least one of DHCPv6 and DHCPv4 is required")
 	}
@@ -184,46 +195,23 @@ func boot(ifname string, dhcp dhcpFunc) error {
 		bootconf.BootfileURL = *overrideNetbootURL
 	}
 	debug("DHCP: boot file URL is %s", bootconf.BootfileURL)
-	// check for supported schemes
-	scheme, err := getScheme(bootconf.BootfileURL)
-	if err != nil {
-		return fmt.Errorf("DHCP: cannot get scheme from URL: %v", err)
-	}
-	if scheme == "" {
-		return errors.New("DHCP: no valid scheme found in URL")
-	}
-
-	client, err := getClientForBootfile(bootconf.BootfileURL)
+	log.Printf("DHCP: fetching boot file URL: %s", bootconf.BootfileURL)
+	body, err := fetchBootfile(bootconf.BootfileURL)
 	if err != nil {
-		return fmt.Errorf("DHCP: cannot get client for %s: %v", bootconf.BootfileURL, err)
+		return fmt.Errorf("DHCP: %v", err)
 	}
-	log.Printf("DHCP: fetching boot file URL: %s", bootconf.BootfileURL)
-
-	var resp *http.Response
-	for attempt := 0; attempt < maxHTTPAttempts; attempt++ {
-		log.Printf("netboot: attempt %d for http.Get", attempt+1)
-		req, err := http.NewRequest(http.MethodGet, bootconf.BootfileURL, nil)
+	if pubKey != nil {
+		sigURL := bootconf.BootfileURL + sigSuffix
+		log.Printf("DHCP: fetching boot file signature URL: %s", sigURL)
+		sig, err := fetchBootfile(sigURL)
 		if err != nil {
-			return fmt.Errorf("could not build request for %s: %v", bootconf.BootfileURL, err)
-		}
-		resp, err = client.Do(req)
-		if err != nil && retryableNetError(err) || retryableHTTPError(resp) {
-			time.Sleep(retryInterval)
-			continue
+			return fmt.Errorf("DHCP: %v", err)
 		}
-		if err == nil {
-			break
+		hash, err := verifySignature(pubKey, body, sig)
+		if err != nil {
+			return fmt.Errorf("DHCP: refusing to boot %s: %v", bootconf.BootfileURL, err)
 		}
-		return fmt.Errorf("DHCP: http.Get of %s failed: %v", bootconf.BootfileURL, err)
-	}
-	// FIXME this will not be called if something fails after this point
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("status code is not 200 OK: %d", resp.StatusCode)
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("DHCP: cannot read boot file from the network: %v", err)
+		log.Printf("DHCP: verified boot file, SHA-256 %x", hash)
 	}
 	crypto.TryMeasureData(crypto.BootConfigPCR, body, bootconf.BootfileURL)
 	u, err := url.Parse(bootconf.BootfileURL)
@@ -268,12 +256,79 @@ func getScheme(urlstring string) (string, error) {
 		return "", err
 	}
 	scheme := strings.ToLower(u.Scheme)
-	if scheme != "http" && scheme != "https" {
-		return "", fmt.Errorf("URL scheme '%s' must be http or https", scheme)
+	if scheme != "http" && scheme != "https" && scheme != "tftp" {
+		return "", fmt.Errorf("URL scheme '%s' must be http, https or tftp", scheme)
 	}
 	return scheme, nil
 }
 
+// fetchBootfile fetches the boot file over HTTP(S), following redirects, or
+// over TFTP, depending on the scheme of its URL.
+func fetchBootfile(bootfile string) ([]byte, error) {
+	scheme, err := getScheme(bootfile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get scheme from URL: %v", err)
+	}
+	if scheme == "tftp" {
+		return fetchTFTP(bootfile)
+	}
+	return fetchHTTP(bootfile)
+}
+
+func fetchHTTP(bootfile string) ([]byte, error) {
+	client, err := getClientForBootfile(bootfile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get client for %s: %v", bootfile, err)
+	}
+
+	var resp *http.Response
+	for attempt := 0; attempt < maxHTTPAttempts; attempt++ {
+		log.Printf("netboot: attempt %d for http.Get", attempt+1)
+		req, err := http.NewRequest(http.MethodGet, bootfile, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not build request for %s: %v", bootfile, err)
+		}
+		resp, err = client.Do(req)
+		if err != nil && retryableNetError(err) || retryableHTTPError(resp) {
+			time.Sleep(retryInterval)
+			continue
+		}
+		if err == nil {
+			break
+		}
+		return nil, fmt.Errorf("http.Get of %s failed: %v", bootfile, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("http.Get of %s failed %d times", bootfile, maxHTTPAttempts)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status code is not 200 OK: %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read boot file from the network: %v", err)
+	}
+	return body, nil
+}
+
+func fetchTFTP(bootfile string) ([]byte, error) {
+	u, err := url.Parse(bootfile)
+	if err != nil {
+		return nil, err
+	}
+	tftp := &curl.SchemeWithRetries{
+		Scheme:  curl.DefaultTFTPClient,
+		DoRetry: curl.RetryTFTP,
+		BackOff: backoff.WithMaxRetries(backoff.NewConstantBackOff(retryInterval), maxHTTPAttempts-1),
+	}
+	r, err := tftp.Fetch(context.Background(), u)
+	if err != nil {
+		return nil, fmt.Errorf("TFTP fetch of %s failed: %v", bootfile, err)
+	}
+	return uio.ReadAll(r)
+}
+
 func loadCaCerts() (*x509.CertPool, error) {
 	rootCAs, err := x509.SystemCertPool()
 	if err != nil {
@@ -306,7 +361,9 @@ func getClientForBootfile(bootfile string) (*http.Client, error) {
 	}
 
 	switch scheme {
-	case "https":
+	case "http", "https":
+		// The TLS options also apply to an http URL, as the server
+		// may redirect to https.
 		var config *tls.Config
 		if *skipCertVerify {
 			config = &tls.Config{
@@ -314,20 +371,21 @@ func getClientForBootfile(bootfile string) (*http.Client, error) {
 			}
 		} else if *caCertFile != "" {
 			rootCAs, err := loadCaCerts()
-			if err != nil {
+			switch {
+			case err == nil:
+				config = &tls.Config{
+					RootCAs: rootCAs,
+				}
+			case scheme == "https":
 				return nil, err
-			}
-			config = &tls.Config{
-				RootCAs: rootCAs,
+			default:
+				debug("%v, using system certs for redirects", err)
 			}
 		}
 		tr := &http.Transport{TLSClientConfig: config}
 		client = &http.Client{Transport: tr}
-		debug("https client setup (use certs from VPD: %t, skipCertVerify %t)",
-			*caCertFile != "", *skipCertVerify)
-	case "http":
-		client = &http.Client{}
-		debug("http client setup")
+		debug("%s client setup (use certs from VPD: %t, skipCertVerify %t)",
+			scheme, *caCertFile != "", *skipCertVerify)
 	default:
 		return nil, fmt.Errorf("Scheme %s is unsupported", scheme)
 	}
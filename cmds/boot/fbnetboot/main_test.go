@@ -0,0 +1,59 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetScheme(t *testing.T) {
+	for _, tt := range []struct {
+		url    string
+		scheme string
+		err    bool
+	}{
+		{url: "http://example.com/boot", scheme: "http"},
+		{url: "HTTPS://example.com/boot", scheme: "https"},
+		{url: "tftp://10.0.0.1/boot", scheme: "tftp"},
+		{url: "ftp://example.com/boot", err: true},
+		{url: "/boot", err: true},
+	} {
+		scheme, err := getScheme(tt.url)
+		if (err != nil) != tt.err {
+			t.Errorf("getScheme(%q) = %v, want error %t", tt.url, err, tt.err)
+			continue
+		}
+		if scheme != tt.scheme {
+			t.Errorf("getScheme(%q) = %q, want %q", tt.url, scheme, tt.scheme)
+		}
+	}
+}
+
+func TestFetchBootfileRedirect(t *testing.T) {
+	*caCertFile = ""
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/boot" {
+			http.Redirect(w, r, "/kernel", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "kernel")
+	}))
+	defer s.Close()
+
+	body, err := fetchBootfile(s.URL + "/boot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "kernel" {
+		t.Errorf("fetchBootfile() = %q, want %q", body, "kernel")
+	}
+
+	if _, err := fetchBootfile("ftp://example.com/boot"); err == nil {
+		t.Error("fetchBootfile(ftp://...) succeeded, want error")
+	}
+}
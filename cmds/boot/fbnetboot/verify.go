@@ -0,0 +1,83 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// sigSuffix is appended to the boot file URL to get the URL of its detached
+// signature.
+const sigSuffix = ".sig"
+
+// pubKey, if set, has to verify the boot file.
+var pubKey crypto.PublicKey
+
+// loadPublicKey loads the first public key of the PEM file path. It accepts
+// PKIX ("PUBLIC KEY") RSA and ED25519 keys, PKCS #1 ("RSA PUBLIC KEY") RSA
+// keys, and the raw ED25519 keys that pkg/crypto generates.
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no public key found", path)
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			if len(block.Bytes) == ed25519.PublicKeySize {
+				return ed25519.PublicKey(block.Bytes), nil
+			}
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			switch pub.(type) {
+			case ed25519.PublicKey, *rsa.PublicKey:
+				return pub, nil
+			}
+			return nil, fmt.Errorf("%s: unsupported public key type %T, want RSA or ED25519", path, pub)
+		case "RSA PUBLIC KEY":
+			pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			return pub, nil
+		}
+	}
+}
+
+// verifySignature verifies the detached signature sig over data with pub.
+// ED25519 signatures are over data itself, RSA signatures are PKCS #1 v1.5
+// signatures over its SHA-256 hash, as made by "openssl dgst -sha256 -sign".
+// It returns the SHA-256 hash of data.
+func verifySignature(pub crypto.PublicKey, data, sig []byte) ([]byte, error) {
+	h := sha256.Sum256(data)
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return nil, errors.New("ED25519 signature verification failed")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig); err != nil {
+			return nil, fmt.Errorf("RSA signature verification failed: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	return h[:], nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, dir, typ string, b []byte) string {
+	p := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestVerifySignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fbnetboot-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []byte("kernel")
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(data)
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaPriv, crypto.SHA256, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPKIX, err := x509.MarshalPKIXPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPKIX, err := x509.MarshalPKIXPublicKey(&rsaPriv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		typ  string
+		key  []byte
+		sig  []byte
+	}{
+		{name: "raw ed25519", typ: "PUBLIC KEY", key: edPub, sig: ed25519.Sign(edPriv, data)},
+		{name: "pkix ed25519", typ: "PUBLIC KEY", key: edPKIX, sig: ed25519.Sign(edPriv, data)},
+		{name: "pkix rsa", typ: "PUBLIC KEY", key: rsaPKIX, sig: rsaSig},
+		{name: "pkcs1 rsa", typ: "RSA PUBLIC KEY", key: x509.MarshalPKCS1PublicKey(&rsaPriv.PublicKey), sig: rsaSig},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, err := loadPublicKey(writePEM(t, dir, tt.typ, tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash, err := verifySignature(pub, data, tt.sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(hash) != string(h[:]) {
+				t.Errorf("verifySignature() = %x, want %x", hash, h)
+			}
+			if _, err := verifySignature(pub, []byte("evil"), tt.sig); err == nil {
+				t.Error("verifySignature() of modified data succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := loadPublicKey(writePEM(t, dir, "CERTIFICATE", []byte("x"))); err == nil {
+		t.Error("loadPublicKey() without a public key succeeded, want error")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/u-root/u-root/pkg/boot"
+	"github.com/u-root/u-root/pkg/boot/bls"
+	"github.com/u-root/u-root/pkg/boot/menu"
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/ulog"
+)
+
+// espGUID is the partition type GUID of EFI system partitions.
+const espGUID = "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+
+// scanESPs mounts the EFI system partitions among devices read-only, and
+// returns the systemd-boot entries and loader.conf of the first one that has
+// entries. The caller has to unmount the returned mount point.
+func scanESPs(devices block.BlockDevices, baseMountpoint string) ([]boot.OSImage, bls.LoaderConf, *mount.MountPoint, error) {
+	esps := devices.FilterPartType(espGUID)
+	if len(esps) == 0 {
+		return nil, bls.LoaderConf{}, nil, fmt.Errorf("no EFI system partition found")
+	}
+	for _, esp := range esps {
+		mp, err := esp.Mount(filepath.Join(baseMountpoint, esp.Name), mount.MS_RDONLY)
+		if err != nil {
+			log.Printf("Failed to mount EFI system partition %s: %v", esp, err)
+			continue
+		}
+		imgs, err := bls.ScanBLSEntries(ulog.Log, mp.Path)
+		if err == nil && len(imgs) > 0 {
+			lc, err := bls.ReadLoaderConf(mp.Path)
+			if err != nil {
+				log.Printf("EFI system partition %s: %v", esp, err)
+			}
+			return imgs, lc, mp, nil
+		}
+		log.Printf("No boot entries found on EFI system partition %s", esp)
+		if err := mp.Unmount(mount.MNT_DETACH); err != nil {
+			debug("Failed to unmount %v: %v", mp, err)
+		}
+	}
+	return nil, bls.LoaderConf{}, nil, fmt.Errorf("no boot entries found on EFI system partitions %v", esps)
+}
+
+// BootESPMode tries to boot a systemd-boot entry in ESP mode. This means:
+// * look for EFI system partitions and mount them read-only
+// * parse the entries and loader.conf of the first one with entries
+// * boot the chosen entry, see bootBLSImages
+func BootESPMode(devices block.BlockDevices, baseMountpoint string, dryrun bool, configIdx int) error {
+	imgs, lc, mp, err := scanESPs(devices, baseMountpoint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := mp.Unmount(mount.MNT_DETACH); err != nil {
+			debug("Failed to unmount %v: %v", mp, err)
+		}
+	}()
+	log.Printf("Found %d boot entries on %s", len(imgs), mp.Device)
+	return bootBLSImages(imgs, lc, dryrun, configIdx)
+}
+
+// BootBLSMode tries to boot a Boot Loader Specification entry in BLS mode.
+// This means:
+// * mount all partitions read-only
+// * parse the entries in loader/entries and boot/loader/entries of each one
+// * boot the chosen entry, see bootBLSImages
+//
+// The former is where entries are on a separate /boot partition, the latter
+// on a root file system. The entries of each partition are ranked by
+// version, and the loader.conf of the first partition with entries is used.
+func BootBLSMode(devices block.BlockDevices, baseMountpoint string, dryrun bool, configIdx int) error {
+	var (
+		imgs    []boot.OSImage
+		lc      bls.LoaderConf
+		mounted []*mount.MountPoint
+	)
+	defer func() {
+		for _, mp := range mounted {
+			if err := mp.Unmount(mount.MNT_DETACH); err != nil {
+				debug("Failed to unmount %v: %v", mp, err)
+			}
+		}
+	}()
+	for _, dev := range devices {
+		mp, err := dev.Mount(filepath.Join(baseMountpoint, dev.Name), mount.MS_RDONLY)
+		if err != nil {
+			debug("Failed to mount %s: %v", dev, err)
+			continue
+		}
+		mounted = append(mounted, mp)
+		for _, root := range []string{mp.Path, filepath.Join(mp.Path, "boot")} {
+			found, err := bls.ScanBLSEntries(ulog.Log, root)
+			if err != nil || len(found) == 0 {
+				continue
+			}
+			log.Printf("Found %d boot entries in %s on %s", len(found), root, dev)
+			if len(imgs) == 0 {
+				if lc, err = bls.ReadLoaderConf(root); err != nil {
+					log.Printf("%s: %v", root, err)
+				}
+			}
+			imgs = append(imgs, found...)
+		}
+	}
+	if len(imgs) == 0 {
+		return fmt.Errorf("no Boot Loader Specification entries found")
+	}
+	return bootBLSImages(imgs, lc, dryrun, configIdx)
+}
+
+// bootBLSImages boots one of imgs. If configIdx is not -1, the entry with
+// that index is booted. Otherwise, if the loader.conf timeout is not 0, a
+// menu is shown, else the entries are tried in order, default entries first.
+func bootBLSImages(imgs []boot.OSImage, lc bls.LoaderConf, dryrun bool, configIdx int) error {
+	for n, img := range imgs {
+		log.Printf("  %d: %s", n, img.Label())
+		debug("%s", img)
+	}
+	if configIdx > -1 {
+		if configIdx >= len(imgs) {
+			return fmt.Errorf("invalid arg -config %d: there are only %d boot entries available", configIdx, len(imgs))
+		}
+		imgs = imgs[configIdx : configIdx+1]
+	}
+	if dryrun {
+		debug("Dry-run mode: will not boot %s", imgs[0].Label())
+		return nil
+	}
+
+	if configIdx == -1 && lc.Timeout != 0 {
+		entry := menu.ShowMenuAndLoad(os.Stdin, menu.OSImages(*flagDebug, imgs...)...)
+		if entry == nil {
+			return fmt.Errorf("no boot entry could be loaded")
+		}
+		return entry.Exec()
+	}
+	for _, img := range imgs {
+		debug("Trying boot entry %s", img)
+		if err := img.Load(*flagDebug); err != nil {
+			log.Printf("Failed to load %s: %v", img.Label(), err)
+			continue
+		}
+		return boot.Execute()
+	}
+	return fmt.Errorf("no boot entry could be loaded")
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/u-root/u-root/pkg/boot/jsonboot"
 	"github.com/u-root/u-root/pkg/mount"
@@ -26,10 +27,13 @@ var (
 	flagDebug          = flag.Bool("d", false, "Print debug output")
 	flagConfigIdx      = flag.Int("config", -1, "Specify the index of the configuration to boot. The order is determined by the menu entries in the Grub config")
 	flagGrubMode       = flag.Bool("grub", false, "Use GRUB mode, i.e. look for valid Grub/Grub2 configuration in default locations to boot a kernel. GRUB mode ignores -kernel/-initramfs/-cmdline")
+	flagESPMode        = flag.Bool("esp", false, "Use ESP mode, i.e. boot systemd-boot entries (loader/entries/*.conf) from the EFI system partition. ESP mode ignores -kernel/-initramfs/-cmdline")
+	flagBLSMode        = flag.Bool("bls", false, "Use BLS mode, i.e. boot Boot Loader Specification entries (loader/entries/*.conf) from any partition, e.g. Fedora's /boot. BLS mode ignores -kernel/-initramfs/-cmdline")
 	flagKernelPath     = flag.String("kernel", "", "Specify the path of the kernel to execute. If using -grub, this argument is ignored")
 	flagInitramfsPath  = flag.String("initramfs", "", "Specify the path of the initramfs to load. If using -grub, this argument is ignored")
 	flagKernelCmdline  = flag.String("cmdline", "", "Specify the kernel command line. If using -grub, this argument is ignored")
 	flagDeviceGUID     = flag.String("guid", "", "GUID of the device where the kernel (and optionally initramfs) are located. Ignored if -grub is set or if -kernel is not specified")
+	flagDevice         = flag.String("dev", "", "Partition where the kernel (and optionally initramfs) are located, as PARTUUID=..., UUID=..., LABEL=..., PARTLABEL=... or a device name like sda1. Requires -kernel, overrides -guid")
 )
 
 var debug = func(string, ...interface{}) {}
@@ -55,6 +59,40 @@ func mountByGUID(devices block.BlockDevices, guid, baseMountpoint string) (*moun
 	return partitions[0].Mount(mountpath, mount.MS_RDONLY)
 }
 
+// findPartition returns the partition identified by spec, which is one of
+// PARTUUID=GUID, UUID=FSUUID, LABEL=FSLABEL, PARTLABEL=LABEL or a device
+// name. Exactly one partition has to match.
+func findPartition(devices block.BlockDevices, spec string) (*block.BlockDev, error) {
+	var matches block.BlockDevices
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) == 1 {
+		matches = devices.FilterNames(spec)
+	} else {
+		switch key, value := strings.ToUpper(kv[0]), kv[1]; key {
+		case "PARTUUID":
+			matches = devices.FilterPartID(value)
+		case "UUID":
+			matches = devices.FilterFSUUID(strings.ToLower(value))
+		case "LABEL":
+			matches = devices.FilterFSLabel(value)
+		case "PARTLABEL":
+			var err error
+			if matches, err = devices.FilterPartLabel(value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown identifier %s in %q, want PARTUUID, UUID, LABEL or PARTLABEL", kv[0], spec)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no partition matches %q", spec)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%d partitions match %q: %v", len(matches), spec, matches)
+}
+
 // BootGrubMode tries to boot a kernel in GRUB mode. GRUB mode means:
 // * look for the partition with the specified GUID, and mount it
 // * if no GUID is specified, mount all of the specified devices
@@ -156,7 +194,7 @@ func BootGrubMode(devices block.BlockDevices, baseMountpoint string, guid string
 }
 
 // BootPathMode tries to boot a kernel in PATH mode. This means:
-// * look for a partition with the given GUID and mount it
+// * look for the partition identified by dev (or else by GUID) and mount it
 // * look for the kernel and initramfs in the provided locations
 // * boot the kernel with the provided command line
 //
@@ -165,21 +203,48 @@ func BootGrubMode(devices block.BlockDevices, baseMountpoint string, guid string
 // The second parameter, `baseMountPoint`, is the directory where the mount
 // points for each device will be created.
 // The third parameter, `guid`, is the partition GUID to look for.
-// The fourth parameter, `dryrun`, will not boot the found configurations if set
+// The fourth parameter, `dev`, identifies the partition as accepted by
+// findPartition, e.g. PARTUUID=..., UUID=... or LABEL=...
+// The fifth parameter, `dryrun`, will not boot the found configurations if set
 // to true.
-func BootPathMode(devices block.BlockDevices, baseMountpoint string, guid string, dryrun bool) error {
-	mount, err := mountByGUID(devices, guid, baseMountpoint)
-	if err != nil {
-		return err
+func BootPathMode(devices block.BlockDevices, baseMountpoint string, guid string, dev string, dryrun bool) error {
+	var mp *mount.MountPoint
+	if dev != "" {
+		part, err := findPartition(devices, dev)
+		if err != nil {
+			return err
+		}
+		log.Printf("Partition %s is %s", dev, part)
+		if mp, err = part.Mount(filepath.Join(baseMountpoint, part.Name), mount.MS_RDONLY); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if mp, err = mountByGUID(devices, guid, baseMountpoint); err != nil {
+			return err
+		}
 	}
+	defer func() {
+		if err := mp.Unmount(mount.MNT_DETACH); err != nil {
+			debug("Failed to unmount %v: %v", mp, err)
+		}
+	}()
 
-	fullKernelPath := path.Join(mount.Path, *flagKernelPath)
-	fullInitramfsPath := path.Join(mount.Path, *flagInitramfsPath)
 	cfg := jsonboot.BootConfig{
-		Kernel:     fullKernelPath,
-		Initramfs:  fullInitramfsPath,
+		Kernel:     path.Join(mp.Path, *flagKernelPath),
 		KernelArgs: *flagKernelCmdline,
 	}
+	if *flagInitramfsPath != "" {
+		cfg.Initramfs = path.Join(mp.Path, *flagInitramfsPath)
+	}
+	for _, f := range []string{cfg.Kernel, cfg.Initramfs} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("%s not found on %s: %v", strings.TrimPrefix(f, mp.Path), mp.Device, err)
+		}
+	}
 	debug("Trying boot configuration %+v", cfg)
 	if dryrun {
 		log.Printf("Dry-run, will not actually boot")
@@ -196,6 +261,15 @@ func main() {
 	if *flagGrubMode && *flagKernelPath != "" {
 		log.Fatal("Options -grub and -kernel are mutually exclusive")
 	}
+	if *flagESPMode && (*flagGrubMode || *flagKernelPath != "") {
+		log.Fatal("Option -esp is mutually exclusive with -grub and -kernel")
+	}
+	if *flagBLSMode && (*flagESPMode || *flagGrubMode || *flagKernelPath != "") {
+		log.Fatal("Option -bls is mutually exclusive with -esp, -grub and -kernel")
+	}
+	if *flagDevice != "" && *flagKernelPath == "" {
+		log.Fatal("Option -dev requires -kernel")
+	}
 	if *flagDebug {
 		debug = log.Printf
 	}
@@ -223,18 +297,24 @@ func main() {
 		}
 	}
 
-	// TODO boot from EFI system partitions.
-
-	if *flagGrubMode {
+	if *flagBLSMode {
+		if err := BootBLSMode(devices, *flagBaseMountPoint, *flagDryRun, *flagConfigIdx); err != nil {
+			log.Fatal(err)
+		}
+	} else if *flagESPMode {
+		if err := BootESPMode(devices, *flagBaseMountPoint, *flagDryRun, *flagConfigIdx); err != nil {
+			log.Fatal(err)
+		}
+	} else if *flagGrubMode {
 		if err := BootGrubMode(devices, *flagBaseMountPoint, *flagDeviceGUID, *flagDryRun, *flagConfigIdx); err != nil {
 			log.Fatal(err)
 		}
 	} else if *flagKernelPath != "" {
-		if err := BootPathMode(devices, *flagBaseMountPoint, *flagDeviceGUID, *flagDryRun); err != nil {
+		if err := BootPathMode(devices, *flagBaseMountPoint, *flagDeviceGUID, *flagDevice, *flagDryRun); err != nil {
 			log.Fatal(err)
 		}
 	} else {
-		log.Fatal("You must specify either -grub or -kernel")
+		log.Fatal("You must specify either -bls, -esp, -grub or -kernel")
 	}
 	os.Exit(1)
 }
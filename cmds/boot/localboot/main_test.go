@@ -0,0 +1,42 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+)
+
+func TestFindPartition(t *testing.T) {
+	devices := block.BlockDevices{
+		{Name: "sda1", FsUUID: "2a6f-1c3e"},
+		{Name: "sda2", FsUUID: "0c8f2d5e-74b8-4c1e-9d1a-7a1f3c9b2e11"},
+		{Name: "sdb1", FsUUID: "0c8f2d5e-74b8-4c1e-9d1a-7a1f3c9b2e11"},
+	}
+	for _, tt := range []struct {
+		spec string
+		want string
+		err  bool
+	}{
+		{spec: "sda1", want: "sda1"},
+		{spec: "/dev/sda1", want: "sda1"},
+		{spec: "UUID=2A6F-1C3E", want: "sda1"},
+		{spec: "uuid=2a6f-1c3e", want: "sda1"},
+		{spec: "UUID=0c8f2d5e-74b8-4c1e-9d1a-7a1f3c9b2e11", err: true},
+		{spec: "UUID=1234-5678", err: true},
+		{spec: "sdc1", err: true},
+		{spec: "FOO=bar", err: true},
+	} {
+		got, err := findPartition(devices, tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("findPartition(%q) = %v, want error %t", tt.spec, err, tt.err)
+			continue
+		}
+		if err == nil && got.Name != tt.want {
+			t.Errorf("findPartition(%q) = %s, want %s", tt.spec, got.Name, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+// framebuffers is a variable so it can be changed in tests.
+var framebuffers = "/sys/class/graphics/fb[0-9]*"
+
+// consolePaths returns the device paths of the -console flag value spec: a
+// comma-separated list of device paths, or "auto" for the console= devices
+// of the kernel command line cs.
+func consolePaths(spec string, cs []cmdline.Console) []string {
+	if spec == "" {
+		return nil
+	}
+	if spec != "auto" {
+		return strings.Split(spec, ",")
+	}
+	var paths []string
+	for _, c := range cs {
+		paths = append(paths, filepath.Join("/dev", c.Name))
+	}
+	return paths
+}
+
+// isVT returns whether path is a virtual terminal, which is shown on the
+// framebuffer.
+func isVT(path string) bool {
+	name := strings.TrimPrefix(path, "/dev/")
+	return len(name) > 3 && strings.HasPrefix(name, "tty") && strings.Trim(name[3:], "0123456789") == ""
+}
+
+// openConsoles opens the devices in paths for writing. Devices that fail to
+// open are skipped, and so is standard error, which is logged to anyway.
+func openConsoles(paths []string) []io.Writer {
+	stderr, _ := os.Stderr.Stat()
+	var ws []io.Writer
+	for _, p := range paths {
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			log.Printf("Cannot log to console %s: %v", p, err)
+			continue
+		}
+		if fi, err := f.Stat(); err == nil && stderr != nil && os.SameFile(fi, stderr) {
+			f.Close()
+			continue
+		}
+		ws = append(ws, f)
+	}
+	return ws
+}
+
+// setupConsoles makes the logger also write to the consoles of spec, and
+// returns where the banner goes: the logger, and if there is a framebuffer
+// that is not a console yet, its terminal /dev/tty0.
+func setupConsoles(spec string) io.Writer {
+	paths := consolePaths(spec, cmdline.Consoles())
+	consoles := openConsoles(paths)
+	if len(consoles) > 0 {
+		log.SetOutput(io.MultiWriter(append([]io.Writer{os.Stderr}, consoles...)...))
+	}
+	banner := log.Writer()
+	if fbs, _ := filepath.Glob(framebuffers); len(fbs) > 0 && spec != "" {
+		for _, p := range paths {
+			if isVT(p) {
+				return banner
+			}
+		}
+		if vt := openConsoles([]string{"/dev/tty0"}); len(vt) > 0 {
+			return io.MultiWriter(banner, vt[0])
+		}
+	}
+	return banner
+}
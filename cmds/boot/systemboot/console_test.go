@@ -0,0 +1,73 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+func TestConsolePaths(t *testing.T) {
+	cs := []cmdline.Console{{Name: "tty0"}, {Name: "ttyS0", Options: "115200n8"}}
+	for _, tt := range []struct {
+		spec string
+		want []string
+	}{
+		{spec: "", want: nil},
+		{spec: "auto", want: []string{"/dev/tty0", "/dev/ttyS0"}},
+		{spec: "/dev/ttyS1", want: []string{"/dev/ttyS1"}},
+		{spec: "/dev/ttyS1,/dev/ttyUSB0", want: []string{"/dev/ttyS1", "/dev/ttyUSB0"}},
+	} {
+		if got := consolePaths(tt.spec, cs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("consolePaths(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+	if got := consolePaths("auto", nil); got != nil {
+		t.Errorf("consolePaths(auto) without console= = %q, want nil", got)
+	}
+}
+
+func TestIsVT(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/dev/tty0":    true,
+		"tty1":         true,
+		"/dev/tty":     false,
+		"/dev/ttyS0":   false,
+		"/dev/ttyAMA0": false,
+		"/dev/hvc0":    false,
+	} {
+		if got := isVT(path); got != want {
+			t.Errorf("isVT(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestOpenConsoles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemboot-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	console := filepath.Join(dir, "console")
+	if err := ioutil.WriteFile(console, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := openConsoles([]string{console, filepath.Join(dir, "missing")})
+	if len(ws) != 1 {
+		t.Fatalf("openConsoles() = %d writers, want 1", len(ws))
+	}
+	if _, err := ws[0].Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(console); err != nil || string(b) != "hello\n" {
+		t.Errorf("console = %q, %v, want %q", b, err, "hello\n")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event levels.
+const (
+	levelInfo    = "info"
+	levelWarning = "warning"
+	levelError   = "error"
+)
+
+// Event outcomes.
+const (
+	outcomeOK      = "ok"
+	outcomeFailed  = "failed"
+	outcomeStarted = "started"
+	outcomeSkipped = "skipped"
+)
+
+// logRecord is an event of systemboot, e.g. a boot attempt or an IPMI step.
+// With -json, it is logged as a JSON line, otherwise only its message is.
+type logRecord struct {
+	Time    time.Time `json:"timestamp"`
+	Level   string    `json:"level"`
+	Event   string    `json:"event"`
+	Entry   string    `json:"entry,omitempty"`
+	Outcome string    `json:"outcome,omitempty"`
+	Message string    `json:"message,omitempty"`
+	// Attempts are the boot attempts of a summary.
+	Attempts attempts `json:"attempts,omitempty"`
+}
+
+// eventLog is where JSON events go. It is nil in text mode.
+var eventLog *jsonLines
+
+// jsonLines writes events as JSON lines. As the standard logger's output, it
+// turns every line logged into a "log" event, so that all of the output is
+// JSON.
+type jsonLines struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func (j *jsonLines) write(e logRecord) error {
+	if e.Time.IsZero() {
+		e.Time = j.now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(b, '\n'))
+	return err
+}
+
+// Write implements io.Writer.
+func (j *jsonLines) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := j.write(logRecord{Level: levelInfo, Event: "log", Message: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// setupJSONLog makes the standard logger and logEvent write JSON lines to
+// the standard logger's output.
+func setupJSONLog() {
+	eventLog = &jsonLines{w: log.Writer(), now: time.Now}
+	log.SetFlags(0)
+	log.SetOutput(eventLog)
+}
+
+// logEvent logs e: as a JSON line with -json, otherwise its message.
+func logEvent(e logRecord) {
+	if eventLog == nil {
+		log.Print(e.Message)
+		return
+	}
+	if err := eventLog.write(e); err != nil {
+		log.Printf("Failed to log event %+v: %v", e, err)
+	}
+}
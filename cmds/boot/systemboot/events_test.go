@@ -0,0 +1,43 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLines(t *testing.T) {
+	var b bytes.Buffer
+	j := &jsonLines{w: &b, now: func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC) }}
+
+	if err := j.write(logRecord{Level: levelWarning, Event: "boot_attempt", Entry: "Boot0000", Outcome: outcomeFailed, Message: "failed"}); err != nil {
+		t.Fatal(err)
+	}
+	l := log.New(j, "", 0)
+	l.Print("line 1\nline 2")
+	fmt.Fprint(j, "\n")
+
+	want := []string{
+		`{"timestamp":"2021-03-04T05:06:07Z","level":"warning","event":"boot_attempt","entry":"Boot0000","outcome":"failed","message":"failed"}`,
+		`{"timestamp":"2021-03-04T05:06:07Z","level":"info","event":"log","message":"line 1"}`,
+		`{"timestamp":"2021-03-04T05:06:07Z","level":"info","event":"log","message":"line 2"}`,
+	}
+	got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, line := range got {
+		var r logRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("%s: %v", line, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -26,6 +27,16 @@ var (
 	doQuiet          = flag.Bool("q", false, fmt.Sprintf("Disable verbose output. If not specified, read it from VPD var '%s'. Default false", vpdSystembootLogLevel))
 	interval         = flag.Int("I", 1, "Interval in seconds before looping to the next boot command")
 	noDefaultBoot    = flag.Bool("nodefault", false, "Do not attempt default boot entries if regular ones fail")
+	doMeasure        = flag.Bool("measure", false, "Measure each boot entry into the TPM before trying it")
+	measureLog       = flag.String("measure-log", "/tmp/systemboot-events.json", "Event log of the boot entry measurements")
+	console          = flag.String("console", "", "Also log to these consoles: comma-separated device paths, or \"auto\" for the console= devices on the kernel command line. With a framebuffer, the banner is also shown on /dev/tty0")
+	jsonOutput       = flag.Bool("json", false, "Log JSON lines with timestamp, level, event, entry and outcome instead of text, and skip the banner")
+	firmware         = flag.String("firmware", "auto", "Firmware type that selects the default boot sequence: uefi, bios, or auto to detect it")
+	oemConfig        = flag.String("oem-config", "", fmt.Sprintf("JSON file with the products that get OEM IPMI commands. If not specified, read it from VPD var '%s', or use the built-in list", vpdOEMProducts))
+	ipmiTimeout      = flag.Duration("ipmi-timeout", 30*time.Second, "Maximum time to wait for the BMC after stopping the watchdog")
+	dryRun           = flag.Bool("dryrun", false, "Discover and log the boot entries and read from the BMC, but do not boot, run the default boot sequence or send commands to the BMC")
+	dryRunWatchdog   = flag.Bool("dryrun-stop-watchdog", false, "Stop the IPMI watchdog in dry-run mode too")
+	vpdResetKeys     = flag.String("vpd-reset-keys", "", "Comma-separated RW_VPD keys to delete on CMOS clear. If empty, all of RW_VPD is cleared")
 )
 
 const (
@@ -44,30 +55,59 @@ func isFlagPassed(name string) bool {
 	return found
 }
 
+var banner = `
+                     ____            _                 _                 _
+                    / ___| _   _ ___| |_ ___ _ __ ___ | |__   ___   ___ | |_
+                    \___ \| | | / __| __/ _ \ '_ ` + "`" + ` _ \| '_ \ / _ \ / _ \| __|
+                     ___) | |_| \__ \ ||  __/ | | | | | |_) | (_) | (_) | |_
+                    |____/ \__, |___/\__\___|_| |_| |_|_.__/ \___/ \___/ \__|
+                           |___/
+`
+
+// defaultBootsequence is the default boot sequence on legacy BIOS systems.
 var defaultBootsequence = [][]string{
 	{"fbnetboot", "-userclass", "linuxboot"},
 	{"localboot", "-grub"},
 }
 
+// uefiBootsequence is the default boot sequence on UEFI systems, which boot
+// systemd-boot entries from the EFI system partition, then GRUB configs.
+var uefiBootsequence = [][]string{
+	{"fbnetboot", "-userclass", "linuxboot"},
+	{"localboot", "-esp"},
+	{"localboot", "-grub"},
+}
+
+// efiDir exists if the system booted with UEFI. It is a variable so it can be
+// changed in tests.
+var efiDir = "/sys/firmware/efi"
+
+// bootSequence returns the default boot sequence for firmware, which is
+// "uefi", "bios" or "auto" to detect it.
+func bootSequence(firmware string) ([][]string, error) {
+	if firmware == "auto" {
+		firmware = "bios"
+		if _, err := os.Stat(efiDir); err == nil {
+			firmware = "uefi"
+		}
+		log.Printf("Detected %s firmware", firmware)
+	}
+	switch firmware {
+	case "uefi":
+		return uefiBootsequence, nil
+	case "bios":
+		return defaultBootsequence, nil
+	}
+	return nil, fmt.Errorf("unknown firmware type %q, want auto, uefi or bios", firmware)
+}
+
 // VPD variable for enabling IPMI BMC overriding boot order, default is not set
 const VpdBmcBootOrderOverride = "bmc_bootorder_override"
 
 var bmcBootOverride bool
 
-// Product list for running IPMI OEM commands
-var productList = [3]string{"Tioga Pass", "Mono Lake", "Delta Lake"}
-
 var selRecorded bool
 
-func isMatched(productName string) bool {
-	for _, v := range productList {
-		if strings.HasPrefix(productName, v) {
-			return true
-		}
-	}
-	return false
-}
-
 func getBaseboardProductName(si *smbios.Info) (string, error) {
 	t2, err := si.GetBaseboardInfo()
 	if err != nil {
@@ -88,11 +128,15 @@ func getSystemFWVersion(si *smbios.Info) (string, error) {
 
 func checkCMOSClear(ipmi *ipmi.IPMI) error {
 	if cmosclear, bootorder, err := ocp.IsCMOSClearSet(ipmi); cmosclear {
-		log.Printf("CMOS clear starts")
+		logEvent(logRecord{Level: levelInfo, Event: "cmos_clear", Outcome: outcomeStarted, Message: "CMOS clear starts"})
 		if err = cmosClear(); err != nil {
 			return err
 		}
-		if err = ocp.ClearRwVpd(); err != nil {
+		var keys []string
+		if *vpdResetKeys != "" {
+			keys = strings.Split(*vpdResetKeys, ",")
+		}
+		if err = ocp.ClearRwVpd(keys...); err != nil {
 			return err
 		}
 
@@ -110,83 +154,223 @@ func checkCMOSClear(ipmi *ipmi.IPMI) error {
 	return nil
 }
 
-func runIPMICommands() {
+// smbiosResult is the result of reading SMBIOS in the background.
+type smbiosResult struct {
+	si  *smbios.Info
+	err error
+}
+
+// oemConfigResult is the configuration of the OEM commands, read from VPD
+// and the product list in the background.
+type oemConfigResult struct {
+	bmcBootOverride bool
+	products        []oemProduct
+}
+
+// runUntilDone runs f in a goroutine and waits for it to return or for ctx
+// to be done. It returns whether f returned.
+func runUntilDone(ctx context.Context, f func()) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runIPMICommands stops the watchdog, then sends the firmware version and OEM
+// commands to the BMC. It waits at most timeout for the latter, so that a
+// slow BMC does not delay boot, and returns whether they completed.
+func runIPMICommands(timeout time.Duration) bool {
 	i, err := ipmi.Open(0)
 	if err != nil {
 		log.Printf("Failed to open ipmi device %v, watchdog may still be running", err)
-		return
+		return false
 	}
-	defer i.Close()
 
-	if err = i.ShutoffWatchdog(); err != nil {
-		log.Printf("Failed to stop watchdog %v.", err)
+	// Stop the watchdog before anything else, so it does not reset the
+	// system while we wait for slower commands.
+	if *dryRun && !*dryRunWatchdog {
+		logEvent(logRecord{Level: levelInfo, Event: "ipmi_watchdog", Outcome: outcomeSkipped, Message: "Dry run: not stopping the watchdog."})
+	} else if err = i.ShutoffWatchdog(); err != nil {
+		logEvent(logRecord{Level: levelError, Event: "ipmi_watchdog", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to stop watchdog %v.", err)})
 	} else {
-		log.Printf("Watchdog is stopped.")
+		logEvent(logRecord{Level: levelInfo, Event: "ipmi_watchdog", Outcome: outcomeOK, Message: "Watchdog is stopped."})
 	}
-	// Try RW_VPD first
-	value, err := systembooter.Get(VpdBmcBootOrderOverride, false)
-	if err != nil {
-		// Try RO_VPD
-		value, err = systembooter.Get(VpdBmcBootOrderOverride, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// SMBIOS and VPD are read while the BMC handles the first command.
+	// The channels are buffered, so the readers never block.
+	siCh := make(chan smbiosResult, 1)
+	go func() {
+		si, err := smbios.FromSysfs()
+		siCh <- smbiosResult{si, err}
+	}()
+	cfgCh := make(chan oemConfigResult, 1)
+	go func() {
+		cfg := oemConfigResult{products: defaultProductList}
+		// Try RW_VPD first
+		value, err := systembooter.Get(VpdBmcBootOrderOverride, false)
+		if err != nil {
+			// Try RO_VPD
+			value, err = systembooter.Get(VpdBmcBootOrderOverride, true)
+		}
+		cfg.bmcBootOverride = err == nil && string(value) == "1"
+		log.Printf("VPD %s is %v", VpdBmcBootOrderOverride, string(value))
+		if products, err := loadProductList(*oemConfig); err != nil {
+			log.Printf("Failed to load the OEM product list, using the built-in one: %v", err)
+		} else {
+			cfg.products = products
+		}
+		cfgCh <- cfg
+	}()
+
+	// On timeout, the commands go on in the background and close the
+	// device when they are done.
+	done := runUntilDone(ctx, func() {
+		defer i.Close()
+		runBMCCommands(ctx, i, siCh, cfgCh)
+	})
+	if !done {
+		logEvent(logRecord{Level: levelWarning, Event: "ipmi", Outcome: outcomeFailed, Message: fmt.Sprintf("IPMI commands did not complete within %v, continuing boot", timeout)})
 	}
-	if err == nil && string(value) == "1" {
-		bmcBootOverride = true
+	return done
+}
+
+// dryRunSkip logs what would be done in dry-run mode, and returns whether it
+// has to be skipped.
+func dryRunSkip(format string, v ...interface{}) bool {
+	if *dryRun {
+		log.Printf("Dry run: would "+format, v...)
 	}
-	log.Printf("VPD %s is %v", VpdBmcBootOrderOverride, string(value))
+	return *dryRun
+}
+
+// runBMCCommands sends the firmware version and the OEM commands. It stops
+// early when ctx is done.
+func runBMCCommands(ctx context.Context, i *ipmi.IPMI, siCh <-chan smbiosResult, cfgCh <-chan oemConfigResult) {
 	// Below IPMI commands would require SMBIOS data
-	si, err := smbios.FromSysfs()
-	if err != nil {
-		log.Printf("Error reading SMBIOS info: %v", err)
+	var r smbiosResult
+	select {
+	case r = <-siCh:
+	case <-ctx.Done():
 		return
 	}
+	if r.err != nil {
+		log.Printf("Error reading SMBIOS info: %v", r.err)
+		return
+	}
+	si := r.si
 
 	if fwVersion, err := getSystemFWVersion(si); err == nil {
 		log.Printf("System firmware version: %s", fwVersion)
-		if err = i.SetSystemFWVersion(fwVersion); err != nil {
-			log.Printf("Failed to set system firmware version to BMC %v.", err)
+		if !dryRunSkip("set the system firmware version on the BMC") {
+			if err = i.SetSystemFWVersion(fwVersion); err != nil {
+				logEvent(logRecord{Level: levelError, Event: "ipmi_fw_version", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to set system firmware version to BMC %v.", err)})
+			} else {
+				logEvent(logRecord{Level: levelInfo, Event: "ipmi_fw_version", Outcome: outcomeOK, Message: "Set system firmware version to BMC."})
+			}
 		}
 	}
 
+	var cfg oemConfigResult
+	select {
+	case cfg = <-cfgCh:
+	case <-ctx.Done():
+		return
+	}
+	bmcBootOverride = cfg.bmcBootOverride
+	productList = cfg.products
+
 	if productName, err := getBaseboardProductName(si); err == nil {
-		if isMatched(productName) {
+		if product, ok := isMatched(productName); ok {
 			log.Printf("Running OEM IPMI commands.")
-			if err = checkCMOSClear(i); err != nil {
-				log.Printf("IPMI CMOS clear err: %v", err)
+			// runs also checks for the deadline between commands.
+			runs := func(action string) bool {
+				return ctx.Err() == nil && product.runs(action) && !dryRunSkip("run OEM action %s", action)
 			}
-			if err = ocp.CheckBMCBootOrder(i, bmcBootOverride); err != nil {
-				log.Printf("Failed to sync BMC Boot Order %v.", err)
+			if runs(actionCMOSClear) {
+				if err = checkCMOSClear(i); err != nil {
+					logEvent(logRecord{Level: levelError, Event: "cmos_clear", Outcome: outcomeFailed, Message: fmt.Sprintf("IPMI CMOS clear err: %v", err)})
+				}
 			}
-			dimmInfo, err := ocp.GetOemIpmiDimmInfo(si)
-			if err == nil {
-				if err = ocp.SendOemIpmiDimmInfo(i, dimmInfo); err == nil {
-					log.Printf("Send the information of DIMMs to BMC.")
-				} else {
-					log.Printf("Failed to send the information of DIMMs to BMC: %v.", err)
+			if runs(actionBootOrder) {
+				if err = ocp.CheckBMCBootOrder(i, bmcBootOverride); err != nil {
+					logEvent(logRecord{Level: levelError, Event: "ipmi_boot_order", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to sync BMC Boot Order %v.", err)})
 				}
-			} else {
-				log.Printf("Failed to get the information of DIMMs: %v.", err)
 			}
-
-			processorInfo, err := ocp.GetOemIpmiProcessorInfo(si)
-			if err == nil {
-				if err = ocp.SendOemIpmiProcessorInfo(i, processorInfo); err == nil {
-					log.Printf("Send the information of processors to BMC.")
+			if runs(actionDIMMInfo) {
+				sendDIMMInfo(i, si)
+			}
+			if runs(actionProcessorInfo) {
+				sendProcessorInfo(i, si)
+			}
+			if runs(actionPostEnd) {
+				if err = ocp.SetOemIpmiPostEnd(i); err == nil {
+					logEvent(logRecord{Level: levelInfo, Event: "ipmi_post_end", Outcome: outcomeOK, Message: "Send IPMI POST end to BMC"})
 				} else {
-					log.Printf("Failed to send the information of processors to BMC: %v.", err)
+					logEvent(logRecord{Level: levelError, Event: "ipmi_post_end", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to send IPMI POST end to BMC: %v.", err)})
 				}
-			} else {
-				log.Printf("Failed to get the information of Processors: %v.", err)
 			}
+		} else {
+			log.Printf("No product name is matched for OEM commands.")
+		}
+	}
+}
 
-			if err = ocp.SetOemIpmiPostEnd(i); err == nil {
-				log.Printf("Send IPMI POST end to BMC")
-			} else {
-				log.Printf("Failed to send IPMI POST end to BMC: %v.", err)
-			}
+// hardware is the SMBIOS information that boot entries match against.
+type hardware struct {
+	*systembooter.Hardware
+	err error
+}
 
+func readHardware() *hardware {
+	si, err := smbios.FromSysfs()
+	if err != nil {
+		return &hardware{err: err}
+	}
+	hw, err := systembooter.HardwareFromSMBIOS(si)
+	return &hardware{Hardware: hw, err: err}
+}
+
+// check returns why m does not match the hardware, or nil if it does.
+func (hw *hardware) check(m *systembooter.Match) error {
+	if hw.err != nil {
+		return fmt.Errorf("reading SMBIOS for the match block: %v", hw.err)
+	}
+	return m.Check(hw.Hardware)
+}
+
+func sendDIMMInfo(i *ipmi.IPMI, si *smbios.Info) {
+	dimmInfo, err := ocp.GetOemIpmiDimmInfo(si)
+	if err == nil {
+		if err = ocp.SendOemIpmiDimmInfo(i, dimmInfo); err == nil {
+			logEvent(logRecord{Level: levelInfo, Event: "ipmi_dimm_info", Outcome: outcomeOK, Message: "Send the information of DIMMs to BMC."})
 		} else {
-			log.Printf("No product name is matched for OEM commands.")
+			logEvent(logRecord{Level: levelError, Event: "ipmi_dimm_info", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to send the information of DIMMs to BMC: %v.", err)})
+		}
+	} else {
+		logEvent(logRecord{Level: levelError, Event: "ipmi_dimm_info", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to get the information of DIMMs: %v.", err)})
+	}
+}
+
+func sendProcessorInfo(i *ipmi.IPMI, si *smbios.Info) {
+	processorInfo, err := ocp.GetOemIpmiProcessorInfo(si)
+	if err == nil {
+		if err = ocp.SendOemIpmiProcessorInfo(i, processorInfo); err == nil {
+			logEvent(logRecord{Level: levelInfo, Event: "ipmi_processor_info", Outcome: outcomeOK, Message: "Send the information of processors to BMC."})
+		} else {
+			logEvent(logRecord{Level: levelError, Event: "ipmi_processor_info", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to send the information of processors to BMC: %v.", err)})
 		}
+	} else {
+		logEvent(logRecord{Level: levelError, Event: "ipmi_processor_info", Outcome: outcomeFailed, Message: fmt.Sprintf("Failed to get the information of Processors: %v.", err)})
 	}
 }
 
@@ -271,18 +455,18 @@ func getDebugEnabled() bool {
 
 func main() {
 	flag.Parse()
+	bannerOut := setupConsoles(*console)
+	if *jsonOutput {
+		setupJSONLog()
+	}
 
 	debugEnabled := getDebugEnabled()
 
-	log.Print(`
-                     ____            _                 _                 _
-                    / ___| _   _ ___| |_ ___ _ __ ___ | |__   ___   ___ | |_
-                    \___ \| | | / __| __/ _ \ '_ ` + "`" + ` _ \| '_ \ / _ \ / _ \| __|
-                     ___) | |_| \__ \ ||  __/ | | | | | |_) | (_) | (_) | |_
-                    |____/ \__, |___/\__\___|_| |_| |_|_.__/ \___/ \___/ \__|
-                           |___/
-`)
-	runIPMICommands()
+	if !*jsonOutput {
+		fmt.Fprint(bannerOut, banner)
+	}
+	logEvent(logRecord{Level: levelInfo, Event: "start", Message: "systemboot starting"})
+	ipmiDone := runIPMICommands(*ipmiTimeout)
 	sleepInterval := time.Duration(*interval) * time.Second
 	if *allowInteractive {
 		log.Printf("**************************************************************************")
@@ -295,19 +479,45 @@ func main() {
 
 	// Get and show boot entries
 	var bootEntries []systembooter.BootEntry
-	if bmcBootOverride && ocp.BmcUpdatedBootorder {
+	// The BMC boot order is only used if the IPMI commands completed,
+	// otherwise they may still be updating it.
+	if ipmiDone && bmcBootOverride && ocp.BmcUpdatedBootorder {
 		bootEntries = ocp.BootEntries
 	} else {
 		bootEntries = systembooter.GetBootEntries()
 	}
 	log.Printf("BOOT ENTRIES:")
 	for _, entry := range bootEntries {
-		log.Printf("    %v) %+v", entry.Name, string(entry.Config))
+		logEvent(logRecord{Level: levelInfo, Event: "boot_entry", Entry: entry.Name, Message: fmt.Sprintf("    %v) %+v", entry.Name, string(entry.Config))})
+	}
+	var m *measurer
+	if *doMeasure {
+		m = newMeasurer(*measureLog)
 	}
+	var tried attempts
+	var hw *hardware
 	for _, entry := range bootEntries {
-		log.Printf("Trying boot entry %s: %s", entry.Name, string(entry.Config))
-		if err := entry.Booter.Boot(debugEnabled); err != nil {
-			log.Printf("Warning: failed to boot with configuration: %+v", entry)
+		if entry.Match != nil {
+			if hw == nil {
+				hw = readHardware()
+			}
+			if err := hw.check(entry.Match); err != nil {
+				logEvent(logRecord{Level: levelInfo, Event: "boot_attempt", Entry: entry.Name, Outcome: outcomeSkipped, Message: fmt.Sprintf("Skipping boot entry %s: %v", entry.Name, err)})
+				continue
+			}
+		}
+		if *dryRun {
+			logEvent(logRecord{Level: levelInfo, Event: "boot_attempt", Entry: entry.Name, Outcome: outcomeSkipped, Message: fmt.Sprintf("Dry run: would boot entry %s with the %s booter: %+v", entry.Name, entry.Booter.TypeName(), entry.Booter)})
+			continue
+		}
+		logEvent(logRecord{Level: levelInfo, Event: "boot_attempt", Entry: entry.Name, Outcome: outcomeStarted, Message: fmt.Sprintf("Trying boot entry %s: %s", entry.Name, string(entry.Config))})
+		if m != nil {
+			if err := m.measure(entry); err != nil {
+				logEvent(logRecord{Level: levelWarning, Event: "measure", Entry: entry.Name, Outcome: outcomeFailed, Message: fmt.Sprintf("Warning: failed to measure boot entry %s: %v", entry.Name, err)})
+			}
+		}
+		if err := tried.run(entry.Name, entry.Booter.TypeName(), func() error { return entry.Booter.Boot(debugEnabled) }); err != nil {
+			logEvent(logRecord{Level: levelWarning, Event: "boot_attempt", Entry: entry.Name, Outcome: outcomeFailed, Message: fmt.Sprintf("Warning: failed to boot with configuration: %+v", entry)})
 			addSEL(entry.Booter.TypeName())
 		}
 		if debugEnabled {
@@ -317,26 +527,52 @@ func main() {
 	}
 
 	// if boot entries failed, use the default boot sequence
-	log.Printf("Boot entries failed")
+	if !*dryRun {
+		logEvent(logRecord{Level: levelWarning, Event: "boot_entries", Outcome: outcomeFailed, Message: "Boot entries failed"})
+		tried.logSummary()
+	}
 
 	if !*noDefaultBoot {
-		log.Print("Falling back to the default boot sequence")
+		logEvent(logRecord{Level: levelInfo, Event: "fallback", Outcome: outcomeStarted, Message: "Falling back to the default boot sequence"})
+		sequence, err := bootSequence(*firmware)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *dryRun {
+			for _, bootcmd := range sequence {
+				logEvent(logRecord{Level: levelInfo, Event: "boot_attempt", Entry: bootcmd[0], Outcome: outcomeSkipped, Message: fmt.Sprintf("Dry run: would run boot command: %v", bootcmd)})
+			}
+			return
+		}
 		for {
-			for _, bootcmd := range defaultBootsequence {
+			for _, bootcmd := range sequence {
 				if debugEnabled {
 					bootcmd = append(bootcmd, "-d")
 				}
-				log.Printf("Running boot command: %v", bootcmd)
+				logEvent(logRecord{Level: levelInfo, Event: "boot_attempt", Entry: bootcmd[0], Outcome: outcomeStarted, Message: fmt.Sprintf("Running boot command: %v", bootcmd)})
 				cmd := exec.Command(bootcmd[0], bootcmd[1:]...)
 				cmd.Stdout = os.Stdout
 				cmd.Stderr = os.Stderr
-				if err := cmd.Run(); err != nil {
-					log.Printf("Error executing %v: %v", cmd, err)
+				if eventLog != nil {
+					// Keep the output JSON.
+					cmd.Stdout, cmd.Stderr = eventLog, eventLog
+				}
+				// The default boot sequence is retried forever, only
+				// its first round is summarized.
+				run := cmd.Run
+				if !selRecorded {
+					run = func() error { return tried.run(bootcmd[0], "command", cmd.Run) }
+				}
+				if err := run(); err != nil {
+					logEvent(logRecord{Level: levelWarning, Event: "boot_attempt", Entry: bootcmd[0], Outcome: outcomeFailed, Message: fmt.Sprintf("Error executing %v: %v", cmd, err)})
 					if !selRecorded {
 						addSEL(bootcmd[0])
 					}
 				}
 			}
+			if !selRecorded {
+				tried.logSummary()
+			}
 			selRecorded = true
 
 			if debugEnabled {
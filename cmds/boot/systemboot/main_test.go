@@ -0,0 +1,79 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBootSequence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { efiDir = old }(efiDir)
+
+	for _, tt := range []struct {
+		name     string
+		firmware string
+		efi      bool
+		want     [][]string
+		wantErr  bool
+	}{
+		{name: "auto uefi", firmware: "auto", efi: true, want: uefiBootsequence},
+		{name: "auto bios", firmware: "auto", want: defaultBootsequence},
+		{name: "forced uefi", firmware: "uefi", want: uefiBootsequence},
+		{name: "forced bios", firmware: "bios", efi: true, want: defaultBootsequence},
+		{name: "unknown", firmware: "coreboot", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			efiDir = filepath.Join(dir, "missing")
+			if tt.efi {
+				efiDir = dir
+			}
+			got, err := bootSequence(tt.firmware)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bootSequence(%q) = %v, want error %v", tt.firmware, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bootSequence(%q) = %v, want %v", tt.firmware, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUntilDone(t *testing.T) {
+	if !runUntilDone(context.Background(), func() {}) {
+		t.Error("runUntilDone() = false for a function that returned")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	if runUntilDone(ctx, func() { <-block }) {
+		t.Error("runUntilDone() = true for a function that blocks past the deadline")
+	}
+}
+
+func TestDryRunSkip(t *testing.T) {
+	defer func(old bool) { *dryRun = old }(*dryRun)
+
+	*dryRun = false
+	if dryRunSkip("run OEM action %s", actionPostEnd) {
+		t.Error("dryRunSkip() = true without -dryrun")
+	}
+	*dryRun = true
+	if !dryRunSkip("run OEM action %s", actionPostEnd) {
+		t.Error("dryRunSkip() = false with -dryrun")
+	}
+}
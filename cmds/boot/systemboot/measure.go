@@ -0,0 +1,109 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/boot/systembooter"
+	bootcrypto "github.com/u-root/u-root/pkg/crypto"
+	"github.com/u-root/u-root/pkg/tss"
+)
+
+// With -measure, every boot entry is measured into bootcrypto.BootConfigPCR
+// before it is tried, and the measurement is appended to the event log
+// given by -measure-log. The log has one JSON object per line:
+//
+//     {"pcr":8,"alg":"sha256","digest":"<hex>","type":"systemboot-entry","data":"Boot0000={...}"}
+//
+// data is the boot entry's name, an equals sign and its configuration, and
+// digest is its alg hash, which is what the PCR was extended with. A
+// verifier replays the log by checking each digest against data and
+// extending a PCR of all zeros with the digests in order.
+//
+// The kernel and initramfs an entry loads are measured by the booter, e.g.
+// localboot, into bootcrypto.BlobPCR.
+
+// eventType is the type of the events written by systemboot.
+const eventType = "systemboot-entry"
+
+// event is an entry of the event log.
+type event struct {
+	PCR    uint32 `json:"pcr"`
+	Alg    string `json:"alg"`
+	Digest string `json:"digest"`
+	Type   string `json:"type"`
+	Data   string `json:"data"`
+}
+
+// extender extends PCRs. It is implemented by *tss.TPM.
+type extender interface {
+	ExtendBank(hash []byte, pcrIndex uint32, alg crypto.Hash) error
+}
+
+// measurer measures boot entries into a TPM and logs the events.
+type measurer struct {
+	tpm extender
+	alg crypto.Hash
+	log io.Writer
+}
+
+var algNames = map[crypto.Hash]string{
+	crypto.SHA1:   "sha1",
+	crypto.SHA256: "sha256",
+}
+
+// newMeasurer opens the TPM and the event log. It returns nil if there is
+// no TPM, so that boot can continue without measurements.
+func newMeasurer(logPath string) *measurer {
+	tpm, err := tss.NewTPM()
+	if err != nil {
+		log.Printf("Cannot open TPM, boot entries will not be measured: %v", err)
+		return nil
+	}
+	// TPM 1.2 only has a SHA-1 bank.
+	alg := crypto.SHA256
+	if tpm.GetVersion() == tss.TPMVersion12 {
+		alg = crypto.SHA1
+	}
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("Cannot open measurement log, boot entries will not be measured: %v", err)
+		tpm.Close()
+		return nil
+	}
+	return &measurer{tpm: tpm, alg: alg, log: f}
+}
+
+// measure extends the PCR with the boot entry and logs the event.
+func (m *measurer) measure(entry systembooter.BootEntry) error {
+	data := entry.Name + "=" + string(entry.Config)
+	h := m.alg.New()
+	h.Write([]byte(data))
+	digest := h.Sum(nil)
+	if err := m.tpm.ExtendBank(digest, bootcrypto.BootConfigPCR, m.alg); err != nil {
+		return fmt.Errorf("extending PCR %d: %v", bootcrypto.BootConfigPCR, err)
+	}
+	b, err := json.Marshal(event{
+		PCR:    bootcrypto.BootConfigPCR,
+		Alg:    algNames[m.alg],
+		Digest: hex.EncodeToString(digest),
+		Type:   eventType,
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.log.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/boot/systembooter"
+)
+
+// fakeTPM records the extends of a PCR, starting from all zeros.
+type fakeTPM struct {
+	pcrs map[uint32][]byte
+	err  error
+}
+
+func (f *fakeTPM) ExtendBank(hash []byte, pcr uint32, alg crypto.Hash) error {
+	if f.err != nil {
+		return f.err
+	}
+	h := alg.New()
+	old, ok := f.pcrs[pcr]
+	if !ok {
+		old = make([]byte, alg.Size())
+	}
+	h.Write(old)
+	h.Write(hash)
+	f.pcrs[pcr] = h.Sum(nil)
+	return nil
+}
+
+func TestMeasure(t *testing.T) {
+	tpm := &fakeTPM{pcrs: map[uint32][]byte{}}
+	var log bytes.Buffer
+	m := &measurer{tpm: tpm, alg: crypto.SHA256, log: &log}
+	for _, e := range []systembooter.BootEntry{
+		{Name: "Boot0000", Config: []byte(`{"type":"netboot","method":"dhcpv6"}`)},
+		{Name: "Boot0001", Config: []byte(`{"type":"localboot","method":"grub"}`)},
+	} {
+		if err := m.measure(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replay the log like a verifier would.
+	pcr := make([]byte, sha256.Size)
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log has %d lines, want 2:\n%s", len(lines), log.String())
+	}
+	for _, l := range lines {
+		var e event
+		if err := json.Unmarshal([]byte(l), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.PCR != 8 || e.Alg != "sha256" || e.Type != eventType {
+			t.Errorf("event %+v has wrong PCR, alg or type", e)
+		}
+		d := sha256.Sum256([]byte(e.Data))
+		if hex.EncodeToString(d[:]) != e.Digest {
+			t.Errorf("digest of %q is %x, log says %s", e.Data, d, e.Digest)
+		}
+		n := sha256.Sum256(append(pcr, d[:]...))
+		pcr = n[:]
+	}
+	if !bytes.Equal(pcr, tpm.pcrs[8]) {
+		t.Errorf("replayed PCR is %x, TPM has %x", pcr, tpm.pcrs[8])
+	}
+	if !strings.Contains(lines[1], `"data":"Boot0001={\"type\":\"localboot\",\"method\":\"grub\"}"`) {
+		t.Errorf("unexpected event data: %s", lines[1])
+	}
+}
+
+func TestMeasureError(t *testing.T) {
+	var log bytes.Buffer
+	m := &measurer{tpm: &fakeTPM{err: errors.New("TPM error")}, alg: crypto.SHA256, log: &log}
+	if err := m.measure(systembooter.BootEntry{Name: "Boot0000"}); err == nil {
+		t.Errorf("measure succeeded, want error")
+	}
+	if log.Len() != 0 {
+		t.Errorf("failed measurement was logged: %q", log.String())
+	}
+}
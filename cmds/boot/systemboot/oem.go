@@ -0,0 +1,126 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/vpd"
+)
+
+// vpdOEMProducts is the name of the VPD variable with the OEM product list.
+const vpdOEMProducts = "systemboot_oem_products"
+
+// OEM IPMI actions.
+const (
+	actionCMOSClear     = "cmos_clear"
+	actionBootOrder     = "boot_order"
+	actionDIMMInfo      = "dimm_info"
+	actionProcessorInfo = "processor_info"
+	actionPostEnd       = "post_end"
+)
+
+var allActions = []string{actionCMOSClear, actionBootOrder, actionDIMMInfo, actionProcessorInfo, actionPostEnd}
+
+func validAction(action string) bool {
+	for _, a := range allActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// oemProduct is a board that gets OEM IPMI commands. Its baseboard product
+// name starts with Prefix. If Actions is empty, all actions run.
+type oemProduct struct {
+	Prefix  string   `json:"prefix"`
+	Actions []string `json:"actions,omitempty"`
+}
+
+// runs returns whether action runs on p.
+func (p oemProduct) runs(action string) bool {
+	if len(p.Actions) == 0 {
+		return true
+	}
+	for _, a := range p.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultProductList is used unless a product list is configured.
+var defaultProductList = []oemProduct{
+	{Prefix: "Tioga Pass"},
+	{Prefix: "Mono Lake"},
+	{Prefix: "Delta Lake"},
+}
+
+// productList is the list of products for running IPMI OEM commands.
+var productList = defaultProductList
+
+// parseProductList parses a JSON product list, e.g.
+// [{"prefix": "Tioga Pass"}, {"prefix": "Yosemite", "actions": ["post_end"]}]
+func parseProductList(data []byte) ([]oemProduct, error) {
+	var products []oemProduct
+	if err := json.Unmarshal(data, &products); err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		if p.Prefix == "" {
+			return nil, errors.New("product with an empty prefix")
+		}
+		for _, a := range p.Actions {
+			if !validAction(a) {
+				return nil, fmt.Errorf("product %q: unknown action %q, want one of %s", p.Prefix, a, strings.Join(allActions, ", "))
+			}
+		}
+	}
+	return products, nil
+}
+
+// loadProductList reads the product list from the file path, or if path is
+// empty, from the VPD variable systemboot_oem_products. Without either, it
+// returns the built-in list.
+func loadProductList(path string) ([]oemProduct, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path != "" {
+		if data, err = ioutil.ReadFile(path); err != nil {
+			return nil, err
+		}
+	} else {
+		// Try RW_VPD first, then RO_VPD.
+		if data, err = vpd.Get(vpdOEMProducts, false); err != nil {
+			if data, err = vpd.Get(vpdOEMProducts, true); err != nil {
+				return defaultProductList, nil
+			}
+		}
+		path = "VPD variable " + vpdOEMProducts
+	}
+	products, err := parseProductList(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return products, nil
+}
+
+// isMatched returns the entry of productList that productName matches.
+func isMatched(productName string) (oemProduct, bool) {
+	for _, p := range productList {
+		if strings.HasPrefix(productName, p.Prefix) {
+			return p, true
+		}
+	}
+	return oemProduct{}, false
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProductList(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		data    string
+		want    []oemProduct
+		wantErr bool
+	}{
+		{
+			name: "products",
+			data: `[{"prefix": "Tioga Pass"}, {"prefix": "Yosemite", "actions": ["dimm_info", "post_end"]}]`,
+			want: []oemProduct{{Prefix: "Tioga Pass"}, {Prefix: "Yosemite", Actions: []string{"dimm_info", "post_end"}}},
+		},
+		{name: "empty prefix", data: `[{"prefix": ""}]`, wantErr: true},
+		{name: "unknown action", data: `[{"prefix": "Yosemite", "actions": ["reboot"]}]`, wantErr: true},
+		{name: "not JSON", data: `Tioga Pass`, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProductList([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProductList() = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProductList() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProductList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "products.json")
+	if err := ioutil.WriteFile(path, []byte(`[{"prefix": "Yosemite"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadProductList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []oemProduct{{Prefix: "Yosemite"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loadProductList(%q) = %+v, want %+v", path, got, want)
+	}
+	if _, err := loadProductList(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loadProductList() of a missing file = nil, want error")
+	}
+}
+
+func TestIsMatched(t *testing.T) {
+	defer func(old []oemProduct) { productList = old }(productList)
+	productList = []oemProduct{{Prefix: "Tioga Pass"}, {Prefix: "Yosemite", Actions: []string{actionPostEnd}}}
+
+	if _, ok := isMatched("Wedge"); ok {
+		t.Error("isMatched(Wedge) = true, want false")
+	}
+	p, ok := isMatched("Tioga Pass Single Side")
+	if !ok || !p.runs(actionCMOSClear) {
+		t.Errorf("isMatched(Tioga Pass Single Side) = %+v, %v, want all actions", p, ok)
+	}
+	p, ok = isMatched("Yosemite V2")
+	if !ok || p.runs(actionCMOSClear) || !p.runs(actionPostEnd) {
+		t.Errorf("isMatched(Yosemite V2) = %+v, %v, want only %s", p, ok, actionPostEnd)
+	}
+}
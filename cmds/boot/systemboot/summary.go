@@ -0,0 +1,65 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// attempt is a boot attempt of an entry or a default boot command.
+type attempt struct {
+	Entry   string  `json:"entry"`
+	Booter  string  `json:"booter"`
+	Err     string  `json:"error,omitempty"`
+	Elapsed float64 `json:"elapsed_seconds"`
+}
+
+// attempts collects the boot attempts for the summary.
+type attempts []attempt
+
+// run runs boot, which returns only if booting failed, and records the
+// attempt.
+func (a *attempts) run(entry, booter string, boot func() error) error {
+	start := time.Now()
+	err := boot()
+	at := attempt{Entry: entry, Booter: booter, Elapsed: time.Since(start).Seconds()}
+	if err != nil {
+		at.Err = err.Error()
+	}
+	*a = append(*a, at)
+	return err
+}
+
+// writeTable writes the attempts as a table.
+func (a attempts) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tBOOTER\tRESULT\tELAPSED\tERROR")
+	for _, at := range a {
+		result := "returned"
+		if at.Err != "" {
+			result = "failed"
+		}
+		elapsed := time.Duration(at.Elapsed * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\n", at.Entry, at.Booter, result, elapsed, strings.ReplaceAll(at.Err, "\n", " "))
+	}
+	return tw.Flush()
+}
+
+// logSummary logs the attempts: as a table, or as a "summary" event with
+// -json.
+func (a attempts) logSummary() {
+	if eventLog != nil {
+		logEvent(logRecord{Level: levelInfo, Event: "summary", Message: fmt.Sprintf("%d boot attempts", len(a)), Attempts: a})
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Boot attempts:\n")
+	a.writeTable(&b)
+	logEvent(logRecord{Message: b.String()})
+}
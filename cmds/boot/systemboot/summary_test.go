@@ -0,0 +1,44 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAttempts(t *testing.T) {
+	var a attempts
+	if err := a.run("Boot0000", "netboot", func() error { return errors.New("no DHCP\nlease") }); err == nil {
+		t.Error("run() = nil, want the error of boot")
+	}
+	if err := a.run("localboot", "command", func() error { return nil }); err != nil {
+		t.Errorf("run() = %v, want nil", err)
+	}
+	if len(a) != 2 || a[0].Err != "no DHCP\nlease" || a[1].Err != "" {
+		t.Fatalf("attempts = %+v", a)
+	}
+
+	var b strings.Builder
+	if err := a.writeTable(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("table = %q, want 3 lines", b.String())
+	}
+	for i, want := range [][]string{
+		{"ENTRY", "BOOTER", "RESULT", "ELAPSED", "ERROR"},
+		{"Boot0000", "netboot", "failed", "no DHCP lease"},
+		{"localboot", "command", "returned"},
+	} {
+		for _, w := range want {
+			if !strings.Contains(lines[i], w) {
+				t.Errorf("line %d = %q, want it to contain %q", i, lines[i], w)
+			}
+		}
+	}
+}
@@ -6,37 +6,95 @@
 //
 // Synopsis:
 //     basename NAME [SUFFIX]
+//     basename OPTION... NAME...
 //
+// Description:
+//     Print NAME with any leading directory components removed. If
+//     specified, also remove a trailing SUFFIX.
+//
+// Options:
+//     -a, --multiple: support multiple arguments and treat each as a NAME
+//     -s, --suffix:   remove a trailing SUFFIX; implies -a
+//     -z, --zero:     end each output line with NUL, not newline
 package main
 
 import (
-	"fmt"
+	"bufio"
+	"io"
 	"log"
-	"path/filepath"
+	"os"
 	"strings"
 
 	flag "github.com/spf13/pflag"
 )
 
+var (
+	multiple = flag.BoolP("multiple", "a", false, "support multiple arguments and treat each as a NAME")
+	suffix   = flag.StringP("suffix", "s", "", "remove a trailing SUFFIX; implies -a")
+	zero     = flag.BoolP("zero", "z", false, "end each output line with NUL, not newline")
+)
+
 func usage() {
-	log.Fatal("Usage: basename NAME [SUFFIX]")
+	log.Fatal("Usage: basename NAME [SUFFIX] or basename OPTION... NAME...")
+}
+
+// base returns the last element of name, as described by POSIX basename.
+// Trailing slashes are removed first; a name consisting only of slashes
+// yields "/" and an empty name yields "". If suffix is non-empty, differs
+// from the resulting element and is a suffix of it, it is removed.
+func base(name, suffix string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.TrimRight(name, "/")
+	if name == "" {
+		return "/"
+	}
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	if suffix != "" && name != suffix {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
+
+func run(w io.Writer, names []string, suffix string, zero bool) error {
+	b := bufio.NewWriter(w)
+	end := byte('\n')
+	if zero {
+		end = 0
+	}
+	for _, n := range names {
+		b.WriteString(base(n, suffix))
+		b.WriteByte(end)
+	}
+	return b.Flush()
 }
 
 func main() {
 	flag.Parse()
 
 	args := flag.Args()
-	switch len(args) {
-	case 2:
-		fileName := filepath.Base(args[0])
-		if fileName != args[1] {
-			fileName = strings.TrimSuffix(fileName, args[1])
-		}
-		fmt.Println(fileName)
-	case 1:
-		fileName := filepath.Base(args[0])
-		fmt.Println(fileName)
-	default:
+	if len(args) == 0 {
+		log.Print("basename: missing operand")
 		usage()
 	}
+
+	s := *suffix
+	if !*multiple && s == "" {
+		switch len(args) {
+		case 1:
+		case 2:
+			s = args[1]
+			args = args[:1]
+		default:
+			log.Printf("basename: extra operand %q", args[2])
+			usage()
+		}
+	}
+
+	if err := run(os.Stdout, args, s, *zero); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -46,6 +46,24 @@ func TestBasename(t *testing.T) {
 			stdErr:     "",
 			exitStatus: 0,
 		},
+		{
+			flags:      []string{"-a", "/a/b.c", "d/", "/"},
+			out:        "b.c\nd\n/\n",
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-s", ".c", "/a/b.c", "e.c", ".c"},
+			out:        "b\ne\n.c\n",
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-z", "-a", "/a/b", "c"},
+			out:        "b\x00c\x00",
+			stdErr:     "",
+			exitStatus: 0,
+		},
 	}
 
 	// Table-driven testing
@@ -72,6 +90,39 @@ func TestBasename(t *testing.T) {
 	}
 }
 
+// The examples below follow the POSIX basename specification and the
+// behavior of GNU coreutils for the implementation-defined cases.
+func TestBase(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		suffix string
+		want   string
+	}{
+		{name: "", want: ""},
+		{name: "/", want: "/"},
+		{name: "//", want: "/"},
+		{name: "///", want: "/"},
+		{name: ".", want: "."},
+		{name: "..", want: ".."},
+		{name: "usr", want: "usr"},
+		{name: "usr/", want: "usr"},
+		{name: "/usr/", want: "usr"},
+		{name: "/usr/lib", want: "lib"},
+		{name: "//usr//lib//", want: "lib"},
+		{name: "/home//dwc//test", want: "test"},
+		{name: "include/stdio.h", suffix: ".h", want: "stdio"},
+		{name: "/usr/src/cmd/cat.c", suffix: ".c", want: "cat"},
+		{name: "/usr/src/cmd/cat.c/", suffix: ".c", want: "cat"},
+		{name: "cat.c", suffix: "cat.c", want: "cat.c"},
+		{name: "/", suffix: "/", want: "/"},
+		{name: "a.b.c", suffix: "c", want: "a.b."},
+	} {
+		if got := base(tt.name, tt.suffix); got != tt.want {
+			t.Errorf("base(%q, %q) = %q, want %q", tt.name, tt.suffix, got, tt.want)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
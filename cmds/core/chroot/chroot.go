@@ -4,10 +4,26 @@
 
 // +build !windows !plan9
 
+// chroot runs a command with a different root directory.
+//
+// Synopsis:
+//     chroot [-u|--userspec UID:GID] [-g|--groups G1,G2,...] [-s] NEWROOT [COMMAND [ARG...]]
+//
+// Description:
+//     Change the working directory to NEWROOT, then run COMMAND with NEWROOT
+//     as its root directory, or /bin/sh -i if there is no COMMAND. COMMAND
+//     is looked up in the PATH inside NEWROOT.
+//
+// Options:
+//     -u, --userspec: run COMMAND as user UID and group GID
+//     -g, --groups:   set the supplementary group IDs of COMMAND
+//     -s:             do not change the working directory to NEWROOT; only
+//                     permitted when NEWROOT is /
 package main
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -105,7 +121,9 @@ var (
 
 func init() {
 	flag.Var(&user, "u", "specify user and group (ID only) as USER:GROUP")
+	flag.Var(&user, "userspec", "specify user and group (ID only) as USER:GROUP")
 	flag.Var(&groups, "g", "specify supplementary group ids as g1,g2,..,gN")
+	flag.Var(&groups, "groups", "specify supplementary group ids as g1,g2,..,gN")
 	flag.BoolVar(&skipchdirFlag, "s", false, fmt.Sprint("Use this option to not change",
 		"the working directory to / after changing the root directory to newroot, i.e., ",
 		"inside the chroot. This option is only permitted when newroot is the old / directory."))
@@ -134,6 +152,42 @@ func parseRoot(args []string) (root string, err error) {
 	return filepath.Abs(args[0])
 }
 
+// defaultPath is searched for commands when PATH is not set.
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// lookPath returns the path of the command name inside root, searching the
+// directories of path if name has no slash. The returned path is relative to
+// root, so that it can be executed after the chroot.
+func lookPath(root, name, path string) (string, error) {
+	isExec := func(p string) error {
+		fi, err := os.Stat(filepath.Join(root, p))
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || fi.Mode()&0111 == 0 {
+			return fmt.Errorf("%s: not an executable file", filepath.Join(root, p))
+		}
+		return nil
+	}
+	if strings.Contains(name, "/") {
+		p := filepath.Join("/", name)
+		if err := isExec(p); err != nil {
+			return "", fmt.Errorf("cannot run %q in %s: %v", name, root, err)
+		}
+		return p, nil
+	}
+	if path == "" {
+		path = defaultPath
+	}
+	for _, dir := range filepath.SplitList(path) {
+		p := filepath.Join("/", dir, name)
+		if isExec(p) == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("cannot run %q: not found in %s of %s", name, path, root)
+}
+
 func isRoot(dir string) (bool, error) {
 	realPath, err := filepath.EvalSymlinks(dir)
 	if err != nil {
@@ -149,42 +203,34 @@ func isRoot(dir string) (bool, error) {
 	return false, nil
 }
 
-func main() {
-	var (
-		newRoot   string
-		isOldroot bool
-		err       error
-	)
-
-	flag.Parse()
-
-	if flag.NFlag() == 0 && flag.NArg() == 0 {
-		flag.PrintDefaults()
-		os.Exit(1)
-	}
-
-	newRoot, err = parseRoot(flag.Args())
+func run(args []string) error {
+	newRoot, err := parseRoot(args)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	isOldroot, err = isRoot(newRoot)
+	isOldroot, err := isRoot(newRoot)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
+	// The working directory must be inside the new root before the
+	// chroot, or the command would start outside of it.
 	if !skipchdirFlag {
-		err = os.Chdir(newRoot)
-		if err != nil {
-			log.Fatal(err)
+		if err := os.Chdir(newRoot); err != nil {
+			return err
 		}
 	} else if !isOldroot {
-		log.Fatal("The -s option is only permitted when newroot is the old / directory")
+		return errors.New("the -s option is only permitted when newroot is the old / directory")
 	}
 
-	argv := parseCommand(flag.Args())
-
-	cmd := exec.Command(argv[0], argv[1:]...)
+	argv := parseCommand(args)
+	name, err := lookPath(newRoot, argv[0], os.Getenv("PATH"))
+	if err != nil {
+		return err
+	}
 
+	cmd := exec.Command(name, argv[1:]...)
+	cmd.Args[0] = argv[0]
 	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Credential: &syscall.Credential{
@@ -194,8 +240,22 @@ func main() {
 		},
 		Chroot: newRoot,
 	}
+	if !skipchdirFlag {
+		// Changed to after the chroot.
+		cmd.Dir = "/"
+	}
+	return cmd.Run()
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NFlag() == 0 && flag.NArg() == 0 {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
 
-	if err = cmd.Run(); err != nil {
+	if err := run(flag.Args()); err != nil {
 		log.Fatal(err)
 	}
 }
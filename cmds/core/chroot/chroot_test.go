@@ -6,6 +6,9 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -129,3 +132,47 @@ func TestGroupsSet(t *testing.T) {
 	}
 
 }
+
+func TestLookPath(t *testing.T) {
+	root, err := ioutil.TempDir("", "chroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, d := range []string{"bin", "usr/bin", "sbin"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p, mode := range map[string]os.FileMode{
+		"bin/sh":       0755,
+		"usr/bin/true": 0755,
+		"bin/data":     0644,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(root, p), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		path string
+		want string
+		err  bool
+	}{
+		{name: "/bin/sh", want: "/bin/sh"},
+		{name: "bin/sh", want: "/bin/sh"},
+		{name: "sh", want: "/bin/sh"},
+		{name: "true", path: "/sbin:/usr/bin", want: "/usr/bin/true"},
+		{name: "true", path: "/sbin", err: true},
+		{name: "/bin/bash", err: true},
+		{name: "bash", err: true},
+		{name: "/bin/data", err: true},
+		{name: "/usr/bin", err: true},
+	} {
+		got, err := lookPath(root, tt.name, tt.path)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("lookPath(%q, %q) = %q, %v, want %q, error %t", tt.name, tt.path, got, err, tt.want, tt.err)
+		}
+	}
+}
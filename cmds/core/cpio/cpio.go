@@ -5,45 +5,275 @@
 // cpio operates on cpio files using a cpio package
 // It only implements basic cpio options.
 //
-//
 // Synopsis:
-//     cpio
+//
+//	cpio -o [-H FORMAT] < NAMES > ARCHIVE
+//	cpio -i [-d] [--no-absolute-filenames] [-H FORMAT] [PATTERN...] < ARCHIVE
+//	cpio -t [-v] [-H FORMAT] [PATTERN...] < ARCHIVE
+//	cpio [-v] o|i|t
 //
 // Description:
 //
+//	-o reads file names, one per line, from stdin and writes an archive of
+//	the files to stdout. Hard links to a file share an inode number in the
+//	archive and only the first one carries the content. Inode numbers are
+//	renumbered from 2 and device numbers are zeroed, so that the archive
+//	is reproducible.
+//
+//	-i extracts the files of the archive on stdin that match any PATTERN,
+//	or all files. -t lists them. PATTERNs are shell patterns, where * and
+//	? match / too.
+//
+//	The single letter commands o, i and t are the old way to run cpio.
+//	i creates directories, as -i -d does, and t lists files as -t -v does.
+//
 // Options:
-//     o: output an archive to stdout given a pattern
-//     i: output files from a stdin stream
-//     t: print table of contents
-//     -v: debug prints
 //
-// Bugs: in i mode, it can't use non-seekable stdin, i.e. a pipe. Yep, this sucks.
-// But if we implement seek on such things, we have to do it by reading, which
-// really sucks. It's doable, we'll do it if we have to, but for now I'd like
-// to avoid the complexity. cpio is a 40 year old concept. If you want something
-// better, see ../archive which has a VTOC and separates data from metadata (unlike cpio).
-// We could test for ESPIPE and fix it that way ... later.
+//	-o: output an archive to stdout given a pattern
+//	-i: output files from a stdin stream
+//	-t: print table of contents
+//	-H: archive format, newc by default
+//	-d: create missing parent directories when extracting
+//	--no-absolute-filenames: extract absolute file names relative to the
+//	    current directory
+//	-v: debug prints, and list files like ls -l with -t
 package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/u-root/u-root/pkg/cpio"
 )
 
 var (
-	debug  = func(string, ...interface{}) {}
-	d      = flag.Bool("v", false, "Debug prints")
-	format = flag.String("H", "newc", "format")
+	debug      = func(string, ...interface{}) {}
+	d          = flag.Bool("v", false, "Debug prints")
+	format     = flag.String("H", "newc", "format")
+	create     = flag.Bool("o", false, "Output an archive of the files named on stdin")
+	extract    = flag.Bool("i", false, "Extract the files of the archive on stdin")
+	list       = flag.Bool("t", false, "List the files of the archive on stdin")
+	makeDirs   = flag.Bool("d", false, "Create missing parent directories when extracting")
+	noAbsolute = flag.Bool("no-absolute-filenames", false, "Extract absolute file names relative to the current directory")
 )
 
+var errExtracted = errors.New("some files could not be extracted")
+
 func usage() {
-	log.Fatalf("Usage: cpio")
+	log.Fatalf("Usage: cpio -o|-i|-t [-d] [--no-absolute-filenames] [-H FORMAT] [-v] [PATTERN...]")
+}
+
+// globRegexp converts the shell pattern p, in which * and ? match / too, to
+// a regular expression.
+func globRegexp(p string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(p) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		case '[':
+			// A ] right after [ or [! is part of the set.
+			j := i + 1
+			if j < len(p) && p[j] == '!' {
+				j++
+			}
+			if j < len(p) && p[j] == ']' {
+				j++
+			}
+			end := strings.IndexByte(p[j:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("pattern %q: unterminated [", p)
+			}
+			set := p[i+1 : j+end]
+			b.WriteString("[")
+			if strings.HasPrefix(set, "!") {
+				b.WriteString("^")
+				set = set[1:]
+			}
+			for _, r := range set {
+				if strings.ContainsRune(`\[]^`, r) {
+					b.WriteString(`\`)
+				}
+				b.WriteRune(r)
+			}
+			b.WriteString("]")
+			i = j + end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// matcher returns whether a file name matches any of patterns, or true if
+// there are none.
+func matcher(patterns []string) (func(string) bool, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := globRegexp(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return func(name string) bool {
+		if len(res) == 0 {
+			return true
+		}
+		for _, re := range res {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// extractRecords extracts the records of rr that match into the current
+// directory, or / for absolute names unless noAbsolute is set.
+func extractRecords(rr cpio.RecordReader, match func(string) bool, makeDirs, noAbsolute bool) error {
+	var inums map[uint64]string
+	inums = make(map[uint64]string)
+	var failed bool
+
+	for {
+		rec, err := rr.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading records: %v", err)
+		}
+		debug("record name %s ino %d\n", rec.Name, rec.Info.Ino)
+		if !match(rec.Name) {
+			continue
+		}
+		root := "."
+		if filepath.IsAbs(rec.Name) && !noAbsolute {
+			root = "/"
+		}
+		if !makeDirs {
+			dir := filepath.Dir(filepath.Join(root, rec.Name))
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				log.Printf("Creating %q failed: directory %q does not exist, use -d to create it", rec.Name, dir)
+				failed = true
+				continue
+			}
+		}
+		name := filepath.Join(root, rec.Name)
+
+		// A file with zero size could be a hard link to another file
+		// in the archive. The file with content always comes first.
+		//
+		// But we should ignore files with Ino of 0; that's an illegal value.
+		// The current most common use of this command is with u-root
+		// initramfs cpio files on Linux and Harvey.
+		// (nobody else cares about cpio any more save kernels).
+		// Those always have Ino of zero for reproducible builds.
+		// Hence doing the Ino != 0 test first saves a bit of work.
+		if rec.Info.Ino != 0 {
+			switch rec.Mode & cpio.S_IFMT {
+			// In any Unix past about V1, you can't do os.Link from user mode.
+			// Except via mkdir of course :-).
+			case cpio.S_IFDIR:
+			default:
+				// FileSize of non-zero means it is the first and possibly
+				// only instance of this file.
+				if rec.Info.FileSize != 0 {
+					break
+				}
+				// If the file is not in []inums it is a true zero-length file,
+				// not a hard link to a file already seen.
+				// (pedantic mode: on Unix all files are hard links;
+				// so what this comment really means is "file with more than one
+				// hard link).
+				ino, ok := inums[rec.Info.Ino]
+				if !ok {
+					break
+				}
+				err := os.Link(ino, name)
+				debug("Hard linking %s to %s", ino, name)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			inums[rec.Info.Ino] = name
+		}
+		debug("Creating file %s", name)
+		if err := cpio.CreateFileInRoot(rec, root, true); err != nil {
+			log.Printf("Creating %q failed: %v", rec.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errExtracted
+	}
+	return nil
+}
+
+// listRecords prints the names of the records of rr that match, or the
+// records formatted like ls -l if long is set.
+func listRecords(w io.Writer, rr cpio.RecordReader, match func(string) bool, long bool) error {
+	for {
+		rec, err := rr.ReadRecord()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading records: %v", err)
+		}
+		if !match(rec.Name) {
+			continue
+		}
+		if long {
+			fmt.Fprintln(w, rec)
+		} else {
+			fmt.Fprintln(w, rec.Name)
+		}
+	}
+}
+
+// createArchive writes an archive of the files named in r to rw.
+func createArchive(rw cpio.RecordWriter, r io.Reader) error {
+	cr := cpio.NewRecorder()
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		name := scanner.Text()
+		rec, err := cr.GetRecord(name)
+		if err != nil {
+			return fmt.Errorf("Getting record of %q failed: %v", name, err)
+		}
+		if err := rw.WriteRecord(rec); err != nil {
+			return fmt.Errorf("Writing record %q failed: %v", name, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading stdin: %v", err)
+	}
+	if err := cpio.WriteTrailer(rw); err != nil {
+		return fmt.Errorf("Error writing trailer record: %v", err)
+	}
+	return nil
 }
 
 func main() {
@@ -54,10 +284,25 @@ func main() {
 
 	a := flag.Args()
 	debug("Args %v", a)
-	if len(a) < 1 {
+	var op string
+	long := *d
+	switch {
+	case *create && !*extract && !*list:
+		op = "o"
+	case *extract && !*create && !*list:
+		op = "i"
+	case *list && !*create && !*extract:
+		op = "t"
+	case *create || *extract || *list:
+		usage()
+	case len(a) > 0:
+		// The old single letter commands.
+		op, a = a[0], a[1:]
+		*makeDirs = true
+		long = true
+	default:
 		usage()
 	}
-	op := a[0]
 
 	archiver, err := cpio.Format(*format)
 	if err != nil {
@@ -65,106 +310,31 @@ func main() {
 	}
 
 	switch op {
-	case "i":
-		var inums map[uint64]string
-		inums = make(map[uint64]string)
-
+	case "i", "t":
+		match, err := matcher(a)
+		if err != nil {
+			log.Fatal(err)
+		}
 		rr, err := archiver.NewFileReader(os.Stdin)
 		if err != nil {
 			log.Fatal(err)
 		}
-		for {
-			rec, err := rr.ReadRecord()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Fatalf("error reading records: %v", err)
-			}
-			debug("record name %s ino %d\n", rec.Name, rec.Info.Ino)
-
-			// A file with zero size could be a hard link to another file
-			// in the archive. The file with content always comes first.
-			//
-			// But we should ignore files with Ino of 0; that's an illegal value.
-			// The current most common use of this command is with u-root
-			// initramfs cpio files on Linux and Harvey.
-			// (nobody else cares about cpio any more save kernels).
-			// Those always have Ino of zero for reproducible builds.
-			// Hence doing the Ino != 0 test first saves a bit of work.
-			if rec.Info.Ino != 0 {
-				switch rec.Mode & cpio.S_IFMT {
-				// In any Unix past about V1, you can't do os.Link from user mode.
-				// Except via mkdir of course :-).
-				case cpio.S_IFDIR:
-				default:
-					// FileSize of non-zero means it is the first and possibly
-					// only instance of this file.
-					if rec.Info.FileSize != 0 {
-						break
-					}
-					// If the file is not in []inums it is a true zero-length file,
-					// not a hard link to a file already seen.
-					// (pedantic mode: on Unix all files are hard links;
-					// so what this comment really means is "file with more than one
-					// hard link).
-					ino, ok := inums[rec.Info.Ino]
-					if !ok {
-						break
-					}
-					err := os.Link(ino, rec.Name)
-					debug("Hard linking %s to %s", ino, rec.Name)
-					if err != nil {
-						log.Fatal(err)
-					}
-					continue
-				}
-				inums[rec.Info.Ino] = rec.Name
-			}
-			debug("Creating file %s", rec.Name)
-			if err := cpio.CreateFile(rec); err != nil {
-				log.Printf("Creating %q failed: %v", rec.Name, err)
-			}
+		if op == "i" {
+			err = extractRecords(rr, match, *makeDirs, *noAbsolute)
+		} else {
+			err = listRecords(os.Stdout, rr, match, long)
 		}
-
-	case "o":
-		rw := archiver.Writer(os.Stdout)
-		cr := cpio.NewRecorder()
-		scanner := bufio.NewScanner(os.Stdin)
-
-		for scanner.Scan() {
-			name := scanner.Text()
-			rec, err := cr.GetRecord(name)
-			if err != nil {
-				log.Fatalf("Getting record of %q failed: %v", name, err)
-			}
-			if err := rw.WriteRecord(rec); err != nil {
-				log.Fatalf("Writing record %q failed: %v", name, err)
-			}
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		if err := scanner.Err(); err != nil {
-			log.Fatalf("Error reading stdin: %v", err)
-		}
-		if err := cpio.WriteTrailer(rw); err != nil {
-			log.Fatalf("Error writing trailer record: %v", err)
+	case "o":
+		if len(a) > 0 {
+			usage()
 		}
-
-	case "t":
-		rr, err := archiver.NewFileReader(os.Stdin)
-		if err != nil {
+		if err := createArchive(archiver.Writer(os.Stdout), os.Stdin); err != nil {
 			log.Fatal(err)
 		}
-		for {
-			rec, err := rr.ReadRecord()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Fatalf("error reading records: %v", err)
-			}
-			fmt.Println(rec)
-		}
 
 	default:
 		usage()
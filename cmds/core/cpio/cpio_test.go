@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/u-root/u-root/pkg/cpio"
 	"github.com/u-root/u-root/pkg/testutil"
 )
 
@@ -172,3 +173,165 @@ func TestDirectoryHardLink(t *testing.T) {
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
+
+func TestMatcher(t *testing.T) {
+	for _, tt := range []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{nil, "anything", true},
+		{[]string{"bin/*"}, "bin/sh", true},
+		{[]string{"bin/*"}, "bin/sub/sh", true},
+		{[]string{"*.ko"}, "lib/modules/e1000.ko", true},
+		{[]string{"*.ko"}, "lib/modules/e1000.ko.xz", false},
+		{[]string{"bin/?h"}, "bin/sh", true},
+		{[]string{"bin/[a-s]h"}, "bin/sh", true},
+		{[]string{"bin/[!a-s]h"}, "bin/sh", false},
+		{[]string{"bin/[]x]"}, "bin/]", true},
+		{[]string{`a\*`}, "a*", true},
+		{[]string{`a\*`}, "ab", false},
+		{[]string{"a.b"}, "axb", false},
+		{[]string{"etc", "init"}, "init", true},
+		{[]string{"etc", "init"}, "etc/passwd", false},
+	} {
+		match, err := matcher(tt.patterns)
+		if err != nil {
+			t.Fatalf("matcher(%q) = %v", tt.patterns, err)
+		}
+		if got := match(tt.name); got != tt.want {
+			t.Errorf("matcher(%q)(%q) = %t, want %t", tt.patterns, tt.name, got, tt.want)
+		}
+	}
+	if _, err := matcher([]string{"bin/[ab"}); err == nil {
+		t.Error("matcher with an unterminated [ = nil, want error")
+	}
+}
+
+// writeArchive writes an archive of recs to a temporary file.
+func writeArchive(t *testing.T, dir string, recs ...cpio.Record) string {
+	t.Helper()
+	var b bytes.Buffer
+	w := cpio.Newc.Writer(&b)
+	if err := cpio.WriteRecords(w, recs); err != nil {
+		t.Fatal(err)
+	}
+	if err := cpio.WriteTrailer(w); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "archive.cpio")
+	if err := ioutil.WriteFile(p, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestList(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "TestCpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	archive := writeArchive(t, tempDir,
+		cpio.Directory("bin", 0755),
+		cpio.StaticFile("bin/sh", "#!", 0755),
+		cpio.StaticFile("etc/passwd", "root", 0644),
+	)
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-t"}, "bin\nbin/sh\netc/passwd\n"},
+		{[]string{"-t", "bin/*", "*wd"}, "bin/sh\netc/passwd\n"},
+		{[]string{"-t", "nothing"}, ""},
+	} {
+		f, err := os.Open(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := testutil.Command(t, tt.args...)
+		c.Stdin = f
+		out, err := c.Output()
+		f.Close()
+		if err != nil {
+			t.Fatalf("cpio %q: %v", tt.args, err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("cpio %q = %q, want %q", tt.args, out, tt.want)
+		}
+	}
+}
+
+func TestExtractFlags(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		records []cpio.Record
+		files   []string
+		missing []string
+		err     bool
+	}{
+		{
+			name:    "pattern",
+			args:    []string{"-i", "-d", "etc/*"},
+			records: []cpio.Record{cpio.StaticFile("bin/sh", "#!", 0755), cpio.StaticFile("etc/passwd", "root", 0644)},
+			files:   []string{"etc/passwd"},
+			missing: []string{"bin/sh"},
+		},
+		{
+			name:    "no -d",
+			args:    []string{"-i"},
+			records: []cpio.Record{cpio.StaticFile("init", "#!", 0755), cpio.StaticFile("etc/passwd", "root", 0644)},
+			files:   []string{"init"},
+			missing: []string{"etc"},
+			err:     true,
+		},
+		{
+			name:    "directory records",
+			args:    []string{"-i"},
+			records: []cpio.Record{cpio.Directory("etc", 0755), cpio.StaticFile("etc/passwd", "root", 0644)},
+			files:   []string{"etc/passwd"},
+		},
+		{
+			name:    "no absolute file names",
+			args:    []string{"-i", "-d", "--no-absolute-filenames"},
+			records: []cpio.Record{cpio.StaticFile("/etc/passwd", "root", 0644)},
+			files:   []string{"etc/passwd"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "TestCpio")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+			f, err := os.Open(writeArchive(t, tempDir, tt.records...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			extractDir := filepath.Join(tempDir, "extract")
+			if err := os.Mkdir(extractDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			c := testutil.Command(t, tt.args...)
+			c.Dir = extractDir
+			c.Stdin = f
+			if out, err := c.CombinedOutput(); (err != nil) != tt.err {
+				t.Fatalf("cpio %q: %v, want error %t\n%s", tt.args, err, tt.err, out)
+			}
+			for _, name := range tt.files {
+				if _, err := os.Stat(filepath.Join(extractDir, name)); err != nil {
+					t.Errorf("%s was not extracted: %v", name, err)
+				}
+			}
+			for _, name := range tt.missing {
+				if _, err := os.Stat(filepath.Join(extractDir, name)); !os.IsNotExist(err) {
+					t.Errorf("%s was extracted, want it missing", name)
+				}
+			}
+		})
+	}
+}
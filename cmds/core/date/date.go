@@ -2,18 +2,37 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// date prints the date.
+// date prints or sets the date.
 //
 // Synopsis:
-//     date [-u] [+format] | date [-u] [MMDDhhmm[CC]YY[.ss]]
+//     date [-u] [-d STRING | -r FILE] [+format]
+//     date [-u] -s STRING | date [-u] [MMDDhhmm[[CC]YY][.ss]]
+//
+// Description:
+//     Print the date, or the date of -d or -r, in the default format or in
+//     format, where the conversion specifications of strftime, e.g. %Y-%m-%d,
+//     are replaced.
+//
+//     Set the system clock to STRING, or the legacy MMDDhhmm[[CC]YY][.ss],
+//     and print the new date. STRING is one of
+//
+//     2006-01-02 15:04:05, 2006-01-02 15:04, 2006-01-02T15:04:05Z07:00,
+//     2006-01-02, 15:04:05, 15:04, @SECONDS since the epoch, now, or the
+//     default output format of date.
+//
+// Options:
+//     -u: Coordinated Universal Time (UTC)
+//     -d: print the date STRING instead of now
+//     -r: print the last modification time of FILE
+//     -s: set the date to STRING
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -22,33 +41,45 @@ import (
 var (
 	// default format map from format.go on time lib
 	// Help to make format of date with posix compliant
-	fmtMap = map[string]string{
-		"%a": "Mon",
-		"%A": "Monday",
-		"%b": "Jan",
-		"%h": "Jan",
-		"%B": "January",
-		"%c": time.UnixDate,
-		"%d": "02",
-		"%e": "_2",
-		"%H": "15",
-		"%I": "03",
-		"%m": "1",
-		"%M": "04",
-		"%p": "PM",
-		"%S": "05",
-		"%y": "06",
-		"%Y": "2006",
-		"%z": "-0700",
-		"%Z": "MST",
+	fmtMap = map[byte]string{
+		'a': "Mon",
+		'A': "Monday",
+		'b': "Jan",
+		'h': "Jan",
+		'B': "January",
+		'c': time.UnixDate,
+		'd': "02",
+		'e': "_2",
+		'H': "15",
+		'I': "03",
+		'm': "01",
+		'M': "04",
+		'p': "PM",
+		'S': "05",
+		'y': "06",
+		'Y': "2006",
+		'z': "-0700",
+		'Z': "MST",
+	}
+	// composite specifications, expanded before formatting
+	compositeMap = map[byte]string{
+		'D': "%m/%d/%y",
+		'F': "%Y-%m-%d",
+		'r': "%I:%M:%S %p",
+		'R': "%H:%M",
+		'T': "%H:%M:%S",
+		'x': "%m/%d/%y",    // TODO: decision algorithm
+		'X': "%I:%M:%S %p", // TODO: decision algorithm
 	}
 	flags struct {
 		universal bool
 		reference string
+		date      string
+		set       string
 	}
 )
 
-const cmd = "date [-u] [-d FILE] [+format] | date [-u] [-d FILE] [MMDDhhmm[CC]YY[.ss]]"
+const cmd = "date [-u] [-d STRING | -r FILE] [+format] | date [-u] -s STRING | date [-u] [MMDDhhmm[[CC]YY][.ss]]"
 
 func init() {
 	defUsage := flag.Usage
@@ -58,101 +89,115 @@ func init() {
 	}
 	flag.BoolVar(&flags.universal, "u", false, "Coordinated Universal Time (UTC)")
 	flag.StringVar(&flags.reference, "r", "", "Display the last modification time of FILE")
+	flag.StringVar(&flags.date, "d", "", "Display the date STRING instead of now")
+	flag.StringVar(&flags.set, "s", "", "Set the date to STRING")
 }
 
-// regex search for +format POSIX patterns
-func formatParser(args string) []string {
-	pattern := regexp.MustCompile("%[a-zA-Z]")
-	match := pattern.FindAll([]byte(args), -1)
-
-	var results []string
-	for _, m := range match {
-		results = append(results, string(m[:]))
-	}
-
-	return results
+// weekOfYear returns the week of the year of t, where weeks start on
+// firstDay and the days before the first firstDay are in week 0.
+func weekOfYear(t time.Time, firstDay time.Weekday) int {
+	yday := t.YearDay() - 1
+	wday := (int(t.Weekday()) - int(firstDay) + 7) % 7
+	return (yday + 7 - wday) / 7
 }
 
 // replace map for the format patterns according POSIX and GNU implementations
 func dateMap(t time.Time, z *time.Location, format string) string {
 	d := t.In(z)
-	var toReplace string
-	for _, match := range formatParser(format) {
-		translate, exists := fmtMap[match]
-		switch {
-		case exists:
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		c := format[i]
+		if layout, ok := fmtMap[c]; ok {
 			// Values defined by fmtMap
-			toReplace = d.Format(translate)
-		case match == "%C":
+			b.WriteString(d.Format(layout))
+			continue
+		}
+		if f, ok := compositeMap[c]; ok {
+			b.WriteString(dateMap(t, z, f))
+			continue
+		}
+		switch c {
+		case '%':
+			b.WriteByte('%')
+		case 'C':
 			// Century (a year divided by 100 and truncated to an integer)
 			// as a decimal number [00,99].
-			toReplace = strconv.Itoa(d.Year() / 100)
-		case match == "%D":
-			// Date in the format mm/dd/yy.
-			toReplace = dateMap(t, z, "%m/%d/%y")
-		case match == "%j":
-			// Day of the year as a decimal number [001,366]."
-			year, weekYear := d.ISOWeek()
-			firstWeekDay := time.Date(year, 1, 1, 1, 1, 1, 1, time.UTC).Weekday()
-			weekDay := d.Weekday()
-			dayYear := int(weekYear)*7 - (int(firstWeekDay) - 1) + int(weekDay)
-			toReplace = strconv.Itoa(dayYear)
-		case match == "%n":
+			fmt.Fprintf(&b, "%02d", d.Year()/100)
+		case 'g', 'G':
+			// Year of the ISO week.
+			year, _ := d.ISOWeek()
+			if c == 'g' {
+				fmt.Fprintf(&b, "%02d", year%100)
+			} else {
+				fmt.Fprintf(&b, "%d", year)
+			}
+		case 'j':
+			// Day of the year as a decimal number [001,366].
+			fmt.Fprintf(&b, "%03d", d.YearDay())
+		case 'k':
+			// Hour (24-hour clock) padded with a space [ 0,23].
+			fmt.Fprintf(&b, "%2d", d.Hour())
+		case 'l':
+			// Hour (12-hour clock) padded with a space [ 1,12].
+			fmt.Fprintf(&b, "%2d", (d.Hour()+11)%12+1)
+		case 'n':
 			// A <newline>.
-			toReplace = "\n"
-		case match == "%r":
-			// 12-hour clock time [01,12] using the AM/PM notation;
-			// in the POSIX locale, this shall be equivalent to %I : %M : %S %p.
-			toReplace = dateMap(t, z, "%I:%M:%S %p")
-		case match == "%t":
+			b.WriteByte('\n')
+		case 'N':
+			// Nanoseconds [000000000,999999999].
+			fmt.Fprintf(&b, "%09d", d.Nanosecond())
+		case 'P':
+			b.WriteString(d.Format("pm"))
+		case 's':
+			// Seconds since the epoch.
+			fmt.Fprintf(&b, "%d", d.Unix())
+		case 't':
 			// A <tab>.
-			toReplace = "\t"
-		case match == "%T":
-			toReplace = dateMap(t, z, "%H:%M:%S")
-		case match == "%W":
+			b.WriteByte('\t')
+		case 'u':
+			// Weekday as a decimal number [1,7] (1=Monday).
+			fmt.Fprintf(&b, "%d", (int(d.Weekday())+6)%7+1)
+		case 'U':
 			// Week of the year (Sunday as the first day of the week)
 			// as a decimal number [00,53]. All days in a new year preceding
 			// the first Sunday shall be considered to be in week 0.
-			_, weekYear := d.ISOWeek()
-			weekDay := int(d.Weekday())
-			isNotSunday := 1
-			if weekDay == 0 {
-				isNotSunday = 0
-			}
-			toReplace = strconv.Itoa(weekYear - (isNotSunday))
-		case match == "%w":
-			// Weekday as a decimal number [0,6] (0=Sunday).
-			toReplace = strconv.Itoa(int(d.Weekday()))
-		case match == "%V":
+			fmt.Fprintf(&b, "%02d", weekOfYear(d, time.Sunday))
+		case 'V':
 			// Week of the year (Monday as the first day of the week)
 			// as a decimal number [01,53]. If the week containing January 1
 			// has four or more days in the new year, then it shall be
 			// considered week 1; otherwise, it shall be the last week
 			// of the previous year, and the next week shall be week 1.
-			_, weekYear := d.ISOWeek()
-			toReplace = strconv.Itoa(int(weekYear))
-		case match == "%x":
-			// Locale's appropriate date representation.
-			toReplace = dateMap(t, z, "%m/%d/%y") // TODO: decision algorithm
-		case match == "%F":
-			// Date yyyy-mm-dd defined by GNU implementation
-			toReplace = dateMap(t, z, "%Y-%m-%d")
-		case match == "%X":
-			// Locale's appropriate time representation.
-			toReplace = dateMap(t, z, "%I:%M:%S %p") // TODO: decision algorithm
+			_, week := d.ISOWeek()
+			fmt.Fprintf(&b, "%02d", week)
+		case 'w':
+			// Weekday as a decimal number [0,6] (0=Sunday).
+			fmt.Fprintf(&b, "%d", int(d.Weekday()))
+		case 'W':
+			// Week of the year (Monday as the first day of the week)
+			// as a decimal number [00,53]. All days in a new year preceding
+			// the first Monday shall be considered to be in week 0.
+			fmt.Fprintf(&b, "%02d", weekOfYear(d, time.Monday))
 		default:
-			continue
+			// Unknown specifications are printed as is.
+			b.WriteByte('%')
+			b.WriteByte(c)
 		}
-
-		format = strings.Replace(format, match, toReplace, 1)
-		// fmt.Printf("Iteration[%d]: %v\n", iter, format)
 	}
-	return format
+	return b.String()
 }
 
 func ints(s string, i ...*int) error {
 	var err error
 	for _, p := range i {
+		if len(s) < 2 {
+			return fmt.Errorf("%q is too short", s)
+		}
 		if *p, err = strconv.Atoi(s[0:2]); err != nil {
 			return err
 		}
@@ -162,83 +207,171 @@ func ints(s string, i ...*int) error {
 }
 
 // getTime gets the desired time as a time.Time.
-// It derives it from a unix date command string.
-// Some values in the string are optional, namely
-// YY and SS. For these values, we use
-// time.Now(). For the timezone, we use whatever
-// one we are in, or UTC if desired.
-func getTime(z *time.Location, s string) (t time.Time, err error) {
-	var MM, DD, hh, mm int
+// It derives it from a unix date command string,
+// MMDDhhmm[[CC]YY][.ss]. Some values in the string are
+// optional, namely CC, YY and ss. For CC and YY we use
+// the year of now, and ss is 0. For the timezone, we use
+// whatever one we are in, or UTC if desired.
+func getTime(z *time.Location, s string, now time.Time) (t time.Time, err error) {
+	var MM, DD, hh, mm, SS int
 	// CC is the year / 100, not the "century".
 	// i.e. for 2001, CC is 20, not 21.
-	YY := time.Now().Year() % 100
-	CC := time.Now().Year() / 100
-	SS := time.Now().Second()
+	YY := now.Year() % 100
+	CC := now.Year() / 100
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '.' {
+			return t, fmt.Errorf("invalid character %q in %q", c, s)
+		}
+	}
+	if len(s) < 8 {
+		return t, fmt.Errorf("%q is not MMDDhhmm[[CC]YY][.ss]", s)
+	}
 	if err = ints(s, &MM, &DD, &hh, &mm); err != nil {
 		return
 	}
 	s = s[8:]
+	if i := strings.Index(s, "."); i >= 0 {
+		if len(s)-i != 3 {
+			return t, fmt.Errorf("seconds %q are not .ss", s[i:])
+		}
+		if err = ints(s[i+1:], &SS); err != nil {
+			return
+		}
+		s = s[:i]
+	}
 	switch len(s) {
 	case 0:
 	case 2:
 		err = ints(s, &YY)
-	case 3:
-		err = ints(s[1:], &SS)
 	case 4:
 		err = ints(s, &CC, &YY)
-	case 5:
-		s = s[0:2] + s[3:]
-		err = ints(s, &YY, &SS)
-	case 7:
-		s = s[0:4] + s[5:]
-		err = ints(s, &CC, &YY, &SS)
 	default:
 		err = fmt.Errorf("optional string is %v instead of [[CC]YY][.ss]", s)
 	}
-
 	if err != nil {
 		return
 	}
 
 	YY = YY + CC*100
+	if MM < 1 || MM > 12 || DD < 1 || DD > 31 || hh > 23 || mm > 59 || SS > 60 {
+		return t, fmt.Errorf("%02d%02d%02d%02d.%02d is out of range", MM, DD, hh, mm, SS)
+	}
 	t = time.Date(YY, time.Month(MM), DD, hh, mm, SS, 0, z)
+	if t.Day() != DD {
+		return time.Time{}, fmt.Errorf("%d-%02d has no day %d", YY, MM, DD)
+	}
 	return
 }
 
+// layouts are the formats of date strings, which parseDate tries in turn.
+var layouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.UnixDate,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// clockLayouts are times of the day of now.
+var clockLayouts = []string{
+	"15:04:05",
+	"15:04",
+}
+
+// parseDate parses the date string s of -d and -s in z.
+func parseDate(z *time.Location, s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "now":
+		return now, nil
+	case strings.HasPrefix(s, "@"):
+		sec, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid seconds since the epoch %q", s)
+		}
+		return time.Unix(sec, 0), nil
+	}
+	for _, l := range layouts {
+		if t, err := time.ParseInLocation(l, s, z); err == nil {
+			return t, nil
+		}
+	}
+	n := now.In(z)
+	for _, l := range clockLayouts {
+		if t, err := time.ParseInLocation(l, s, z); err == nil {
+			return time.Date(n.Year(), n.Month(), n.Day(), t.Hour(), t.Minute(), t.Second(), 0, z), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
 func date(t time.Time, z *time.Location) string {
 	return t.In(z).Format(time.UnixDate)
 }
 
-func main() {
-	flag.Parse()
-
+func run(args []string) error {
 	t := time.Now()
 	z := time.Local
 	if flags.universal {
 		z = time.UTC
 	}
+	if flags.date != "" && flags.reference != "" {
+		return errors.New("-d and -r are mutually exclusive")
+	}
 	if flags.reference != "" {
 		stat, err := os.Stat(flags.reference)
 		if err != nil {
-			log.Fatalf("Unable to gather stats of file %v", flags.reference)
+			return fmt.Errorf("Unable to gather stats of file %v", flags.reference)
 		}
 		t = stat.ModTime()
 	}
+	if flags.date != "" {
+		var err error
+		if t, err = parseDate(z, flags.date, t); err != nil {
+			return err
+		}
+	}
 
-	a := flag.Args()
-	switch len(a) {
-	case 0:
-		fmt.Printf("%v\n", date(t, z))
-	case 1:
-		a0 := a[0]
-		if strings.HasPrefix(a0, "+") {
-			fmt.Printf("%v\n", dateMap(t, z, a0[1:]))
-		} else {
-			if err := setDate(a[0], z); err != nil {
-				log.Fatalf("%v: %v", a0, err)
-			}
+	format := ""
+	switch {
+	case len(args) == 1 && strings.HasPrefix(args[0], "+"):
+		format = args[0][1:]
+	case len(args) == 1 && flags.set == "":
+		var err error
+		if t, err = getTime(z, args[0], t); err != nil {
+			return fmt.Errorf("%v: %v", args[0], err)
 		}
-	default:
+		if err := setDate(t); err != nil {
+			return fmt.Errorf("%v: %v", args[0], err)
+		}
+	case len(args) > 0:
 		flag.Usage()
+		os.Exit(2)
+	}
+	if flags.set != "" {
+		var err error
+		if t, err = parseDate(z, flags.set, t); err != nil {
+			return err
+		}
+		if err := setDate(t); err != nil {
+			return fmt.Errorf("%v: %v", flags.set, err)
+		}
+	}
+
+	if format != "" {
+		fmt.Println(dateMap(t, z, format))
+	} else {
+		fmt.Println(date(t, z))
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -44,18 +44,6 @@ func TestDateUniversal(t *testing.T) {
 	}
 }
 
-func TestFormatParser(t *testing.T) {
-	test := "%d %w %x %D %% asdf qwer qwe s sd fqwer % qwer"
-	expected := []string{"%d", "%w", "%x", "%D"}
-	t.Log(":: Test of FormatParser greping the n flags")
-	for index, match := range formatParser(test) {
-		t.Logf(":: Parsed on iteration %d: %v", index, match)
-		if match != expected[index] {
-			t.Errorf("Parsing Error; Want %v, got %v\n", expected[index], match)
-		}
-	}
-}
-
 func TestDateMap(t *testing.T) {
 	t.Log(":: Test of DateMap formatting")
 	posixFormat := "%a %b %e %H:%M:%S %Z %Y"
@@ -97,3 +85,131 @@ func TestDateMapExamples(t *testing.T) {
 		t.Logf(" Output: \n%v\n", dateMap(n, time.Local, test.format))
 	}
 }
+
+func TestDateMapSpecifiers(t *testing.T) {
+	// A Sunday, in the ISO week 53 of 2020.
+	d := time.Date(2021, time.January, 3, 14, 5, 9, 42, time.UTC)
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"%Y-%m-%d %H:%M:%S", "2021-01-03 14:05:09"},
+		{"%F %T", "2021-01-03 14:05:09"},
+		{"%D %R", "01/03/21 14:05"},
+		{"%a %A %b %B %h", "Sun Sunday Jan January Jan"},
+		{"%C %y %e", "20 21  3"},
+		{"%I %l %k %p %P", "02  2 14 PM pm"},
+		{"%r", "02:05:09 PM"},
+		{"%j %u %w", "003 7 0"},
+		{"%U %W %V %G %g", "01 00 53 2020 20"},
+		{"%s.%N", "1609682709.000000042"},
+		{"%z %Z", "+0000 UTC"},
+		{"100%% %n%t", "100% \n\t"},
+		{"%q %", "%q %"},
+		{"%%Y", "%Y"},
+	} {
+		if got := dateMap(d, time.UTC, tt.format); got != tt.want {
+			t.Errorf("dateMap(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestWeekOfYear(t *testing.T) {
+	for _, tt := range []struct {
+		date   time.Time
+		sunday int
+		monday int
+	}{
+		// Friday.
+		{time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), 0, 0},
+		// Monday.
+		{time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC), 1, 1},
+		// Sunday, the last day of the year.
+		{time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC), 53, 52},
+	} {
+		if got := weekOfYear(tt.date, time.Sunday); got != tt.sunday {
+			t.Errorf("weekOfYear(%v, Sunday) = %d, want %d", tt.date, got, tt.sunday)
+		}
+		if got := weekOfYear(tt.date, time.Monday); got != tt.monday {
+			t.Errorf("weekOfYear(%v, Monday) = %d, want %d", tt.date, got, tt.monday)
+		}
+	}
+}
+
+func TestGetTime(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 10, 20, 30, 0, time.UTC)
+	for _, tt := range []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{in: "12312359", want: time.Date(2021, time.December, 31, 23, 59, 0, 0, time.UTC)},
+		{in: "0102030499", want: time.Date(2099, time.January, 2, 3, 4, 0, 0, time.UTC)},
+		{in: "010203041999", want: time.Date(1999, time.January, 2, 3, 4, 0, 0, time.UTC)},
+		{in: "01020304.05", want: time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "0102030422.05", want: time.Date(2022, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "010203041999.59", want: time.Date(1999, time.January, 2, 3, 4, 59, 0, time.UTC)},
+		{in: "02290000", err: true},
+		{in: "022900002024", want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{in: "", err: true},
+		{in: "0102", err: true},
+		{in: "0102030", err: true},
+		{in: "01020304.5", err: true},
+		{in: "01020304.555", err: true},
+		{in: "010203041", err: true},
+		{in: "01020304199", err: true},
+		{in: "0102030419991", err: true},
+		{in: "01020304x9", err: true},
+		{in: "13010000", err: true},
+		{in: "01320000", err: true},
+		{in: "01002400", err: true},
+		{in: "01010060", err: true},
+		{in: "01010000.61", err: true},
+		{in: "+1010000", err: true},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := getTime(time.UTC, tt.in, now)
+			if (err != nil) != tt.err {
+				t.Fatalf("getTime(%q) = %v, %v, want error %t", tt.in, got, err, tt.err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("getTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 10, 20, 30, 0, time.UTC)
+	cet := time.FixedZone("CET", 3600)
+	for _, tt := range []struct {
+		in   string
+		z    *time.Location
+		want time.Time
+		err  bool
+	}{
+		{in: "2021-01-02 03:04:05", z: time.UTC, want: time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "2021-01-02 03:04", z: cet, want: time.Date(2021, time.January, 2, 3, 4, 0, 0, cet)},
+		{in: "2021-01-02T03:04:05+02:00", z: time.UTC, want: time.Date(2021, time.January, 2, 1, 4, 5, 0, time.UTC)},
+		{in: "2021-01-02T03:04:05", z: time.UTC, want: time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{in: " 2021-01-02 ", z: time.UTC, want: time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{in: "Sat Jan  2 03:04:05 UTC 2021", z: time.UTC, want: time.Date(2021, time.January, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "23:59", z: time.UTC, want: time.Date(2021, time.June, 15, 23, 59, 0, 0, time.UTC)},
+		{in: "01:02:03", z: cet, want: time.Date(2021, time.June, 15, 1, 2, 3, 0, cet)},
+		{in: "@1609459200", z: time.UTC, want: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "now", z: time.UTC, want: now},
+		{in: "@soon", z: time.UTC, err: true},
+		{in: "tomorrow", z: time.UTC, err: true},
+		{in: "2021-13-01", z: time.UTC, err: true},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDate(tt.z, tt.in, now)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseDate(%q) = %v, %v, want error %t", tt.in, got, err, tt.err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,16 +7,11 @@
 package main
 
 import (
-	"log"
 	"syscall"
 	"time"
 )
 
-func setDate(d string, z *time.Location) error {
-	t, err := getTime(z, d)
-	if err != nil {
-		log.Fatalf("%v: %v", d, err)
-	}
+func setDate(t time.Time) error {
 	tv := syscall.NsecToTimeval(t.UnixNano())
 	return syscall.Settimeofday(&tv)
 }
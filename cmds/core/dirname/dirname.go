@@ -5,21 +5,68 @@
 // dirname prints out the directory name of one or more args.
 // If no arg is given it returns an error and prints a message which,
 // per the man page, is incorrect, but per the standard, is correct.
+//
+// Synopsis:
+//     dirname [OPTION] NAME...
+//
+// Options:
+//     -z, --zero: end each output line with NUL, not newline
 package main
 
 import (
-	"fmt"
+	"bufio"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
+
+	flag "github.com/spf13/pflag"
 )
 
+var zero = flag.BoolP("zero", "z", false, "end each output line with NUL, not newline")
+
+// dir returns name with its last non-slash component and trailing slashes
+// removed, as described by POSIX dirname. Unlike filepath.Dir, it does not
+// clean the result, so "usr/" yields "." and "//usr//lib" yields "//usr".
+func dir(name string) string {
+	if name == "" {
+		return "."
+	}
+	name = strings.TrimRight(name, "/")
+	if name == "" {
+		return "/"
+	}
+	i := strings.LastIndexByte(name, '/')
+	if i < 0 {
+		return "."
+	}
+	name = strings.TrimRight(name[:i], "/")
+	if name == "" {
+		return "/"
+	}
+	return name
+}
+
+func run(w io.Writer, names []string, zero bool) error {
+	b := bufio.NewWriter(w)
+	end := byte('\n')
+	if zero {
+		end = 0
+	}
+	for _, n := range names {
+		b.WriteString(dir(n))
+		b.WriteByte(end)
+	}
+	return b.Flush()
+}
+
 func main() {
-	if len(os.Args) < 2 {
+	flag.Parse()
+	if flag.NArg() < 1 {
 		log.Fatalf("dirname: missing operand")
 	}
 
-	for _, n := range os.Args[1:] {
-		fmt.Println(filepath.Dir(n))
+	if err := run(os.Stdout, flag.Args(), *zero); err != nil {
+		log.Fatal(err)
 	}
 }
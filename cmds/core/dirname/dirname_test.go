@@ -25,6 +25,35 @@ var dirnameTests = []test{
 	{args: []string{"/this/that"}, out: "/this\n"},
 	{args: []string{"/this/that", "/other"}, out: "/this\n/\n"},
 	{args: []string{"/this/that", "/other thing/space"}, out: "/this\n/other thing\n"},
+	{args: []string{"-z", "/this/that", "usr/"}, out: "/this\x00.\x00"},
+}
+
+// The examples below follow the POSIX dirname specification and the
+// behavior of GNU coreutils for the implementation-defined cases.
+func TestDir(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{name: "", want: "."},
+		{name: "/", want: "/"},
+		{name: "//", want: "/"},
+		{name: "///", want: "/"},
+		{name: ".", want: "."},
+		{name: "..", want: "."},
+		{name: "usr", want: "."},
+		{name: "usr/", want: "."},
+		{name: "/usr/", want: "/"},
+		{name: "/usr/lib", want: "/usr"},
+		{name: "//usr//lib//", want: "//usr"},
+		{name: "/home//dwc//test", want: "/home//dwc"},
+		{name: "a//b", want: "a"},
+		{name: "//a", want: "/"},
+	} {
+		if got := dir(tt.name); got != tt.want {
+			t.Errorf("dir(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
 }
 
 func TestDirName(t *testing.T) {
@@ -0,0 +1,495 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/find"
+)
+
+// expr is a node of the expression tree. It reports whether file matches.
+type expr func(f *file) bool
+
+// file is the file an expression is evaluated on.
+type file struct {
+	path  string
+	info  os.FileInfo
+	depth int
+}
+
+// parser turns the expression arguments into an expr.
+type parser struct {
+	args []string
+	f    *finder
+	// action is set once an action like -print or -exec was parsed. If
+	// not, matching files are printed.
+	action bool
+}
+
+func (p *parser) peek() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	return p.args[0]
+}
+
+func (p *parser) next() string {
+	a := p.peek()
+	if len(p.args) > 0 {
+		p.args = p.args[1:]
+	}
+	return a
+}
+
+func (p *parser) arg(primary string) (string, error) {
+	if len(p.args) == 0 {
+		return "", fmt.Errorf("missing argument to `%s'", primary)
+	}
+	return p.next(), nil
+}
+
+// parse parses the whole expression. An empty expression matches all
+// files.
+func (p *parser) parse() (expr, error) {
+	e := expr(func(*file) bool { return true })
+	if len(p.args) > 0 {
+		var err error
+		if e, err = p.or(); err != nil {
+			return nil, err
+		}
+		if len(p.args) > 0 {
+			return nil, fmt.Errorf("unexpected `%s'", p.peek())
+		}
+	}
+	if !p.action {
+		inner, print := e, p.f.print("\n")
+		e = func(f *file) bool { return inner(f) && print(f) }
+	}
+	return e, nil
+}
+
+// or := and { ( -o | -or ) and }
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = func(l, r expr) expr {
+			return func(f *file) bool { return l(f) || r(f) }
+		}(l, r)
+	}
+	return l, nil
+}
+
+// and := unary { [ -a | -and ] unary }
+func (p *parser) and() (expr, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", ")", "-o", "-or":
+			return l, nil
+		case "-a", "-and":
+			p.next()
+		}
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = func(l, r expr) expr {
+			return func(f *file) bool { return l(f) && r(f) }
+		}(l, r)
+	}
+}
+
+// unary := ( ! | -not ) unary | ( or ) | primary
+func (p *parser) unary() (expr, error) {
+	switch a := p.next(); a {
+	case "":
+		return nil, fmt.Errorf("expected an expression")
+	case "!", "-not":
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *file) bool { return !e(f) }, nil
+	case "(":
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing `)'")
+		}
+		return e, nil
+	default:
+		return p.primary(a)
+	}
+}
+
+// numeric parses a [+-]N argument into a comparison function.
+func numeric(primary, a string) (func(int64) bool, error) {
+	cmp := a[:0]
+	if len(a) > 0 && (a[0] == '+' || a[0] == '-') {
+		cmp, a = a[:1], a[1:]
+	}
+	n, err := strconv.ParseInt(a, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid argument `%s%s' to `%s'", cmp, a, primary)
+	}
+	switch cmp {
+	case "+":
+		return func(v int64) bool { return v > n }, nil
+	case "-":
+		return func(v int64) bool { return v < n }, nil
+	default:
+		return func(v int64) bool { return v == n }, nil
+	}
+}
+
+var fileTypes = map[byte]os.FileMode{
+	'f': 0,
+	'd': os.ModeDir,
+	'l': os.ModeSymlink,
+	'p': os.ModeNamedPipe,
+	's': os.ModeSocket,
+	'c': os.ModeDevice | os.ModeCharDevice,
+	'b': os.ModeDevice,
+}
+
+var sizeUnits = map[byte]int64{
+	'c': 1,
+	'w': 2,
+	'b': 512,
+	'k': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+}
+
+func (p *parser) primary(a string) (expr, error) {
+	switch a {
+	case "-true":
+		return func(*file) bool { return true }, nil
+	case "-false":
+		return func(*file) bool { return false }, nil
+
+	case "-name", "-iname", "-path", "-ipath", "-wholename":
+		pat, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := filepath.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern `%s' to `%s': %v", pat, a, err)
+		}
+		fold := a == "-iname" || a == "-ipath"
+		if fold {
+			pat = strings.ToLower(pat)
+		}
+		base := a == "-name" || a == "-iname"
+		return func(f *file) bool {
+			n := f.path
+			if base {
+				n = filepath.Base(n)
+			}
+			if fold {
+				n = strings.ToLower(n)
+			}
+			m, _ := filepath.Match(pat, n)
+			return m
+		}, nil
+
+	case "-regex":
+		pat, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		// The regular expression has to match the whole path.
+		re, err := regexp.Compile("^(?:" + pat + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression `%s': %v", pat, err)
+		}
+		return func(f *file) bool { return re.MatchString(f.path) }, nil
+
+	case "-type":
+		t, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		var modes []os.FileMode
+		for _, c := range strings.Split(t, ",") {
+			if len(c) != 1 {
+				return nil, fmt.Errorf("unknown argument to -type: %s", c)
+			}
+			m, ok := fileTypes[c[0]]
+			if !ok {
+				return nil, fmt.Errorf("unknown argument to -type: %s", c)
+			}
+			modes = append(modes, m)
+		}
+		return func(f *file) bool {
+			for _, m := range modes {
+				if f.info.Mode()&os.ModeType == m {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case "-perm":
+		s, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		kind := byte(0)
+		if len(s) > 0 && (s[0] == '-' || s[0] == '/') {
+			kind, s = s[0], s[1:]
+		}
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || m > 07777 {
+			return nil, fmt.Errorf("invalid mode `%s'", s)
+		}
+		want := uint32(m)
+		return func(f *file) bool {
+			perm := unixPerm(f.info.Mode())
+			switch kind {
+			case '-':
+				return perm&want == want
+			case '/':
+				return want == 0 || perm&want != 0
+			default:
+				return perm == want
+			}
+		}, nil
+
+	case "-size":
+		s, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		unit := int64(512)
+		if len(s) > 0 {
+			if u, ok := sizeUnits[s[len(s)-1]]; ok {
+				unit, s = u, s[:len(s)-1]
+			}
+		}
+		cmp, err := numeric(a, s)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *file) bool {
+			// Sizes are rounded up to the next unit.
+			return cmp((f.info.Size() + unit - 1) / unit)
+		}, nil
+
+	case "-mtime", "-mmin":
+		s, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := numeric(a, s)
+		if err != nil {
+			return nil, err
+		}
+		unit := 24 * time.Hour
+		if a == "-mmin" {
+			unit = time.Minute
+		}
+		now := p.f.now
+		return func(f *file) bool {
+			return cmp(int64(now.Sub(f.info.ModTime()) / unit))
+		}, nil
+
+	case "-newer":
+		n, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := os.Stat(n)
+		if err != nil {
+			return nil, err
+		}
+		t := ref.ModTime()
+		return func(f *file) bool { return f.info.ModTime().After(t) }, nil
+
+	case "-empty":
+		return func(f *file) bool {
+			switch {
+			case f.info.Mode().IsRegular():
+				return f.info.Size() == 0
+			case f.info.IsDir():
+				d, err := os.Open(f.path)
+				if err != nil {
+					return false
+				}
+				defer d.Close()
+				names, _ := d.Readdirnames(1)
+				return len(names) == 0
+			}
+			return false
+		}, nil
+
+	case "-maxdepth", "-mindepth":
+		s, err := p.arg(a)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid argument `%s' to `%s'", s, a)
+		}
+		if a == "-maxdepth" {
+			p.f.maxDepth = n
+		} else {
+			p.f.minDepth = n
+		}
+		return func(*file) bool { return true }, nil
+
+	case "-depth":
+		p.f.depthFirst = true
+		return func(*file) bool { return true }, nil
+
+	case "-prune":
+		return func(*file) bool {
+			p.f.prune = true
+			return true
+		}, nil
+
+	case "-print":
+		p.action = true
+		return p.f.print("\n"), nil
+
+	case "-print0":
+		p.action = true
+		return p.f.print("\x00"), nil
+
+	case "-ls":
+		p.action = true
+		return func(f *file) bool {
+			ff := &find.File{Name: f.path, FileInfo: f.info}
+			fmt.Fprintf(p.f.stdout, "%s\n", ff)
+			return true
+		}, nil
+
+	case "-exec":
+		p.action = true
+		return p.exec()
+	}
+	return nil, fmt.Errorf("unknown predicate `%s'", a)
+}
+
+// exec parses the arguments of -exec, which end with ";" or with "{} +".
+func (p *parser) exec() (expr, error) {
+	var args []string
+	for {
+		if len(p.args) == 0 {
+			return nil, fmt.Errorf("missing argument to `-exec'")
+		}
+		a := p.next()
+		if a == ";" {
+			break
+		}
+		if a == "+" && len(args) > 0 && args[len(args)-1] == "{}" {
+			b := &batch{f: p.f, args: args[:len(args)-1]}
+			p.f.batches = append(p.f.batches, b)
+			return b.add, nil
+		}
+		args = append(args, a)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing argument to `-exec'")
+	}
+	return func(f *file) bool {
+		c := make([]string, len(args))
+		for i, a := range args {
+			c[i] = strings.Replace(a, "{}", f.path, -1)
+		}
+		return p.f.run(c) == nil
+	}, nil
+}
+
+// maxBatch limits the total length of the file names passed to one
+// command by -exec ... {} +.
+const maxBatch = 128 * 1024
+
+// batch collects the files for -exec ... {} + and runs the command once
+// enough of them were collected.
+type batch struct {
+	f     *finder
+	args  []string
+	files []string
+	size  int
+}
+
+func (b *batch) add(f *file) bool {
+	if b.size+len(f.path) > maxBatch {
+		b.flush()
+	}
+	b.files = append(b.files, f.path)
+	b.size += len(f.path) + 1
+	return true
+}
+
+// flush runs the command for the collected files. A failure only
+// changes the exit status of find.
+func (b *batch) flush() {
+	if len(b.files) == 0 {
+		return
+	}
+	if err := b.f.run(append(append([]string{}, b.args...), b.files...)); err != nil {
+		b.f.status = 1
+	}
+	b.files, b.size = nil, 0
+}
+
+// run runs a command for -exec with find's standard input and output.
+func (f *finder) run(args []string) error {
+	f.stdout.Flush()
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = f.stdin, f.rawStdout, f.stderr
+	err := c.Run()
+	if err != nil && c.ProcessState == nil {
+		f.error(err)
+	}
+	return err
+}
+
+func (f *finder) print(end string) expr {
+	return func(file *file) bool {
+		fmt.Fprintf(f.stdout, "%s%s", file.path, end)
+		return true
+	}
+}
+
+// unixPerm returns the permission bits of m as in chmod(2), including the
+// setuid, setgid and sticky bits.
+func unixPerm(m os.FileMode) uint32 {
+	p := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		p |= 04000
+	}
+	if m&os.ModeSetgid != 0 {
+		p |= 02000
+	}
+	if m&os.ModeSticky != 0 {
+		p |= 01000
+	}
+	return p
+}
@@ -2,98 +2,245 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Find finds files. It is similar to the Unix command. It uses REs, not globs,
-// for matching.
+// Find finds files. It is similar to the Unix command.
 //
-// OPTIONS:
-//     -d: enable debugging in the find package
-//     -mode integer-arg: match against mode, e.g. -mode 0755
-//     -type: match against a file type, e.g. -type f will match files
-//     -name: glob to match against file
-//     -l: long listing. It's not very good, yet, but it's useful enough.
+// Synopsis:
+//     find [PATH...] [EXPRESSION]
+//
+// Description:
+//     Walk each PATH, "." by default, and evaluate EXPRESSION on every file
+//     found. Symlinks are not followed. If EXPRESSION contains no action,
+//     the files it matches are printed.
+//
+//     EXPRESSION is made of the primaries below, combined with
+//     ( EXPR ), ! EXPR, EXPR [-a] EXPR and EXPR -o EXPR, in order of
+//     decreasing precedence. -not, -and and -or are accepted as well.
+//
+// Tests:
+//     -name PATTERN, -iname PATTERN: glob to match against the base name
+//     -path PATTERN, -ipath PATTERN: glob to match against the whole path
+//     -regex RE: regular expression to match against the whole path
+//     -type [fdlpscb]: match against a file type, e.g. -type f will match
+//         files; several types may be separated by commas
+//     -perm [-/]MODE: octal permissions are exactly MODE, include all of
+//         MODE (-) or include any of MODE (/)
+//     -size [+-]N[cwbkMG]: size in units, rounded up, is more than (+),
+//         less than (-) or exactly N; units default to 512 byte blocks
+//     -mtime [+-]N, -mmin [+-]N: last modified N days or minutes ago
+//     -newer FILE: modified more recently than FILE
+//     -empty: empty file or directory
+//     -true, -false: always or never match
+//
+// Options:
+//     -maxdepth N: descend at most N levels below the PATHs
+//     -mindepth N: do not evaluate EXPRESSION less than N levels deep
+//     -depth: evaluate a directory's contents before the directory
+//
+// Actions:
+//     -print, -print0: print the path followed by a newline or NUL
+//     -ls: long listing. It's not very good, yet, but it's useful enough.
+//     -prune: do not descend into the directory
+//     -exec COMMAND ;: run COMMAND with each {} replaced by the path
+//     -exec COMMAND {} +: run COMMAND with as many paths as possible
+//         appended
+//
+// Compatibility:
+//     The old form, find [-mode MODE] [-type TYPE] [-name PATTERN] [-l] [-d]
+//     PATH, is still accepted: -mode MODE is -perm MODE, with MODE in octal
+//     if it starts with 0, -type may also be file or directory, -l is -ls
+//     and -d is ignored.
 package main
 
 import (
-	"context"
-	"flag"
+	"bufio"
 	"fmt"
-	"log"
+	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
-
-	"github.com/u-root/u-root/pkg/find"
+	"time"
 )
 
-const cmd = "find [opts] starting-at-path"
+type finder struct {
+	stdin     io.Reader
+	stdout    *bufio.Writer
+	rawStdout io.Writer
+	stderr    io.Writer
+	now       time.Time
 
-var (
-	perm      = flag.Int("mode", -1, "Permissions")
-	fileType  = flag.String("type", "", "File type")
-	name      = flag.String("name", "", "glob for name")
-	long      = flag.Bool("l", false, "long listing")
-	debug     = flag.Bool("d", false, "Enable debugging in the find package")
-	fileTypes = map[string]os.FileMode{
-		"f":         0,
-		"file":      0,
-		"d":         os.ModeDir,
-		"directory": os.ModeDir,
-	}
-)
+	expr       expr
+	maxDepth   int
+	minDepth   int
+	depthFirst bool
+	batches    []*batch
 
-func init() {
-	defUsage := flag.Usage
-	flag.Usage = func() {
-		os.Args[0] = cmd
-		defUsage()
-		os.Exit(1)
-	}
+	// prune is set by -prune while evaluating a file.
+	prune bool
+	// status is the exit status.
+	status int
 }
 
-func main() {
-	flag.Parse()
-	a := flag.Args()
-	if len(a) != 1 {
-		flag.Usage()
+func newFinder(stdin io.Reader, stdout, stderr io.Writer) *finder {
+	return &finder{
+		stdin:     stdin,
+		stdout:    bufio.NewWriter(stdout),
+		rawStdout: stdout,
+		stderr:    stderr,
+		now:       time.Now(),
+		maxDepth:  -1,
 	}
-	root := a[0]
+}
 
-	var mask, mode os.FileMode
-	if *perm != -1 {
-		mask = os.ModePerm
-		mode = os.FileMode(*perm)
+func (f *finder) error(err error) {
+	f.stdout.Flush()
+	fmt.Fprintf(f.stderr, "find: %v\n", err)
+	f.status = 1
+}
+
+// split splits the command line into the paths and the expression, which
+// starts with the first argument that begins with '-' or is '(' or '!'.
+func split(args []string) ([]string, []string) {
+	for i, a := range args {
+		if strings.HasPrefix(a, "-") || a == "(" || a == "!" {
+			return args[:i], args[i:]
+		}
 	}
-	if *fileType != "" {
-		intType, ok := fileTypes[*fileType]
-		if !ok {
-			var keys []string
-			for key := range fileTypes {
-				keys = append(keys, key)
+	return args, nil
+}
+
+// compat rewrites the old form of the command line,
+// find [-mode MODE] [-type TYPE] [-name PATTERN] [-l] [-d] PATH, as an
+// expression. Other command lines are returned as they are.
+func compat(args []string) []string {
+	var e []string
+	i := 0
+	for ; i < len(args)-1; i++ {
+		a := strings.TrimPrefix(args[i], "-")
+		if a == args[i] {
+			break
+		}
+		var v string
+		hasValue := false
+		if j := strings.IndexByte(a, '='); j >= 0 {
+			a, v, hasValue = a[:j], a[j+1:], true
+		}
+		switch a {
+		case "l", "-l":
+			e = append(e, "-ls")
+			continue
+		case "d", "-d":
+			continue
+		case "mode", "-mode", "type", "-type", "name", "-name":
+		default:
+			return args
+		}
+		if !hasValue {
+			if i+2 >= len(args) {
+				return args
 			}
-			log.Fatalf("%v is not a valid file type\n valid types are %v", *fileType, strings.Join(keys, ","))
+			i++
+			v = args[i]
+		}
+		switch strings.TrimPrefix(a, "-") {
+		case "mode":
+			n, err := strconv.ParseInt(v, 0, 32)
+			if err != nil {
+				return args
+			}
+			e = append(e, "-perm", strconv.FormatInt(n, 8))
+		case "type":
+			switch v {
+			case "file":
+				v = "f"
+			case "directory":
+				v = "d"
+			}
+			e = append(e, "-type", v)
+		case "name":
+			e = append(e, "-name", v)
 		}
-		mode |= intType
-		mask |= os.ModeType
 	}
-
-	debugLog := func(string, ...interface{}) {}
-	if *debug {
-		debugLog = log.Printf
+	// Only the PATH may be left, and the new form never has a PATH after
+	// an expression.
+	if i != len(args)-1 || len(e) == 0 || strings.HasPrefix(args[i], "-") || args[i] == "(" || args[i] == "!" {
+		return args
 	}
-	names := find.Find(context.Background(),
-		find.WithRoot(root),
-		find.WithModeMatch(mode, mask),
-		find.WithFilenameMatch(*name),
-		find.WithDebugLog(debugLog),
-	)
-	for l := range names {
-		if l.Err != nil {
-			fmt.Fprintf(os.Stderr, "%v: %v\n", l.Name, l.Err)
+	// -ls must come last, as it is an action.
+	var tests, actions []string
+	for j := 0; j < len(e); j++ {
+		if e[j] == "-ls" {
+			actions = append(actions, e[j])
 			continue
 		}
-		if *long {
-			fmt.Printf("%s\n", l)
+		tests = append(tests, e[j], e[j+1])
+		j++
+	}
+	return append(append([]string{args[i]}, tests...), actions...)
+}
+
+// eval evaluates the expression on a file and reports whether to prune it.
+func (f *finder) eval(file *file) bool {
+	f.prune = false
+	if file.depth >= f.minDepth {
+		f.expr(file)
+	}
+	return f.prune
+}
+
+func (f *finder) visit(path string, fi os.FileInfo, depth int) {
+	file := &file{path: path, info: fi, depth: depth}
+	prune := false
+	if !f.depthFirst {
+		prune = f.eval(file)
+	}
+	if fi.IsDir() && !prune && (f.maxDepth < 0 || depth < f.maxDepth) {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			f.error(err)
+		}
+		dir := path
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		for _, e := range entries {
+			f.visit(dir+e.Name(), e, depth+1)
+		}
+	}
+	if f.depthFirst {
+		f.eval(file)
+	}
+}
+
+// find walks all paths and returns the exit status.
+func (f *finder) find(args []string) int {
+	paths, e := split(compat(args))
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	p := &parser{args: e, f: f}
+	var err error
+	if f.expr, err = p.parse(); err != nil {
+		f.error(err)
+		return f.status
+	}
+
+	for _, path := range paths {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			f.error(err)
 			continue
 		}
-		fmt.Printf("%s\n", l.Name)
+		f.visit(path, fi, 0)
+	}
+	for _, b := range f.batches {
+		b.flush()
 	}
+	f.stdout.Flush()
+	return f.status
+}
+
+func main() {
+	f := newFinder(os.Stdin, os.Stdout, os.Stderr)
+	os.Exit(f.find(os.Args[1:]))
 }
@@ -0,0 +1,147 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// setup creates this tree:
+//
+//   a/
+//   a/b/
+//   a/b/c.txt  (10 bytes, 0644, modified 3 days ago)
+//   a/b/D.TXT  (0 bytes, 0755)
+//   a/empty/
+//   a/l -> b
+//   x.go       (1000 bytes, 0600)
+func setup(t *testing.T) string {
+	d, err := ioutil.TempDir("", "find")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Umask(syscall.Umask(0))
+	for _, n := range []string{"a/b", "a/empty"} {
+		if err := os.MkdirAll(filepath.Join(d, n), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		size int
+		perm os.FileMode
+	}{
+		{"a/b/c.txt", 10, 0644},
+		{"a/b/D.TXT", 0, 0755},
+		{"x.go", 1000, 0600},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(d, f.name), make([]byte, f.size), f.perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-3*24*time.Hour - time.Hour)
+	if err := os.Chtimes(filepath.Join(d, "a/b/c.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("b", filepath.Join(d, "a/l")); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestFind(t *testing.T) {
+	d := setup(t)
+	defer os.RemoveAll(d)
+	if err := os.Chdir(d); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		args   []string
+		out    string
+		status int
+	}{
+		{args: []string{"a/b"}, out: "a/b\na/b/D.TXT\na/b/c.txt\n"},
+		{args: nil, out: ".\n./a\n./a/b\n./a/b/D.TXT\n./a/b/c.txt\n./a/empty\n./a/l\n./x.go\n"},
+		{args: []string{"-name", "*.txt"}, out: "./a/b/c.txt\n"},
+		{args: []string{".", "-iname", "*.txt"}, out: "./a/b/D.TXT\n./a/b/c.txt\n"},
+		{args: []string{"a", "-path", "a/*/c*"}, out: "a/b/c.txt\n"},
+		{args: []string{"a", "-regex", `a/b/[a-z]\..*`}, out: "a/b/c.txt\n"},
+		{args: []string{"a", "-type", "d"}, out: "a\na/b\na/empty\n"},
+		{args: []string{"a", "-type", "l,d", "-name", "[el]*"}, out: "a/empty\na/l\n"},
+		{args: []string{"-type", "f", "-perm", "0644"}, out: "./a/b/c.txt\n"},
+		{args: []string{"-type", "f", "-perm", "-0600"}, out: "./a/b/D.TXT\n./a/b/c.txt\n./x.go\n"},
+		{args: []string{"-type", "f", "-perm", "/0011"}, out: "./a/b/D.TXT\n"},
+		{args: []string{"-type", "f", "-size", "+1"}, out: "./x.go\n"},
+		{args: []string{"-type", "f", "-size", "-11c"}, out: "./a/b/D.TXT\n./a/b/c.txt\n"},
+		// Like GNU find, sizes are rounded up, so 10 bytes are 1k.
+		{args: []string{"-type", "f", "-size", "1k"}, out: "./a/b/c.txt\n./x.go\n"},
+		{args: []string{"-type", "f", "-mtime", "+2"}, out: "./a/b/c.txt\n"},
+		{args: []string{"-type", "f", "-mtime", "-1"}, out: "./a/b/D.TXT\n./x.go\n"},
+		{args: []string{"-type", "f", "-newer", "a/b/c.txt"}, out: "./a/b/D.TXT\n./x.go\n"},
+		{args: []string{"a", "-empty"}, out: "a/b/D.TXT\na/empty\n"},
+		{args: []string{"-maxdepth", "1"}, out: ".\n./a\n./x.go\n"},
+		{args: []string{"-mindepth", "2", "-maxdepth", "2"}, out: "./a/b\n./a/empty\n./a/l\n"},
+		{args: []string{"a", "-depth"}, out: "a/b/D.TXT\na/b/c.txt\na/b\na/empty\na/l\na\n"},
+		{args: []string{"-name", "b", "-prune", "-o", "-type", "f", "-print"}, out: "./x.go\n"},
+		{args: []string{"-type", "f", "!", "-name", "*.txt"}, out: "./a/b/D.TXT\n./x.go\n"},
+		{args: []string{"-type", "f", "-not", "(", "-name", "*.go", "-or", "-name", "c*", ")"}, out: "./a/b/D.TXT\n"},
+		{args: []string{"-name", "*.go", "-o", "-name", "*.txt", "-a", "-size", "-1"}, out: "./x.go\n"},
+		{args: []string{"-type", "f", "-false", "-print", "-o", "-name", "x*", "-print0"}, out: "./x.go\x00"},
+		{args: []string{"a/b", "-type", "f", "-print0"}, out: "a/b/D.TXT\x00a/b/c.txt\x00"},
+		{args: []string{"a/b", "-type", "f", "-exec", "echo", "<{}>", ";"}, out: "<a/b/D.TXT>\n<a/b/c.txt>\n"},
+		{args: []string{"a/b", "-type", "f", "-exec", "echo", "x", "{}", "+"}, out: "x a/b/D.TXT a/b/c.txt\n"},
+		{args: []string{"a/b", "-type", "f", "-exec", "false", ";", "-o", "-print"}, out: "a/b\na/b/D.TXT\na/b/c.txt\n"},
+		{args: []string{"a/b", "-type", "f", "-exec", "false", "{}", "+"}, status: 1},
+		{args: []string{"nonexistent", "x.go"}, out: "x.go\n", status: 1},
+		{args: []string{"-foo"}, status: 1},
+		{args: []string{"-name"}, status: 1},
+		{args: []string{"(", "-true"}, status: 1},
+		{args: []string{"-type", "q"}, status: 1},
+		{args: []string{"-exec", "echo"}, status: 1},
+		// The old form.
+		{args: []string{"-name", "*.txt", "a"}, out: "a/b/c.txt\n"},
+		{args: []string{"-mode", "0644", "-type", "file", "."}, out: "./a/b/c.txt\n"},
+		{args: []string{"-d", "-type=directory", "-mode=493", "a"}, out: "a\na/b\na/empty\n"},
+	} {
+		var out, stderr bytes.Buffer
+		f := newFinder(strings.NewReader(""), &out, &stderr)
+		if status := f.find(tt.args); status != tt.status {
+			t.Errorf("find %q: got status %d, want %d (stderr %q)", tt.args, status, tt.status, stderr.String())
+		}
+		if out.String() != tt.out {
+			t.Errorf("find %q: got %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestCompat(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-l", "-name", "x*", "-d", "/"}, []string{"/", "-name", "x*", "-ls"}},
+		{[]string{"-mode", "0755", "-type", "directory", "/"}, []string{"/", "-perm", "755", "-type", "d"}},
+		{[]string{"--mode=420", "/"}, []string{"/", "-perm", "644"}},
+		// New forms are left alone.
+		{[]string{"-name", "x*"}, []string{"-name", "x*"}},
+		{[]string{"/", "-type", "f"}, []string{"/", "-type", "f"}},
+		{[]string{"-name", "x", "-print"}, []string{"-name", "x", "-print"}},
+		{[]string{"-mode", "rwx", "/"}, []string{"-mode", "rwx", "/"}},
+		{[]string{"-type", "f", "-prune", "/"}, []string{"-type", "f", "-prune", "/"}},
+	} {
+		if got := compat(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compat(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -5,18 +5,22 @@
 // free reports usage information for physical memory and swap space.
 //
 // Synopsis:
-//     free [-k] [-m] [-g] [-t] [-h] [-json]
+//     free [-b] [-k] [-m] [-g] [-t] [-h] [-w] [-s SECONDS [-c COUNT]] [-json]
 //
 // Description:
 //     Read memory information from /proc/meminfo and display a summary for
 //     physical memory and swap space. The unit options use powers of 1024.
 //
 // Options:
-//     -k: display the values in kibibytes
+//     -b: display the values in bytes
+//     -k: display the values in kibibytes (default)
 //     -m: display the values in mebibytes
 //     -g: display the values in gibibytes
 //     -t: display the values in tebibytes
 //     -h: display the values in human-readable form
+//     -w: wide output, with separate buffers and cache columns
+//     -s: repeat every SECONDS, which may be fractional
+//     -c: with -s, repeat COUNT times rather than until interrupted
 //     -json: use JSON output
 package main
 
@@ -24,7 +28,10 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"time"
 )
 
 var (
@@ -35,6 +42,9 @@ var (
 	inGB        = flag.Bool("g", false, "Express the values in gibibytes")
 	inTB        = flag.Bool("t", false, "Express the values in tebibytes")
 	toJSON      = flag.Bool("json", false, "Use JSON for output")
+	wide        = flag.Bool("w", false, "Wide output: show buffers and cache in separate columns")
+	seconds     = flag.Float64("s", 0, "Repeat every `SECONDS`")
+	count       = flag.Int("c", 0, "With -s, repeat `COUNT` times")
 )
 
 type unit uint
@@ -59,6 +69,7 @@ type FreeConfig struct {
 	Unit        unit
 	HumanOutput bool
 	ToJSON      bool
+	Wide        bool
 }
 
 // the following types are used for JSON serialization
@@ -147,7 +158,7 @@ func formatValueByConfig(value uint64, config *FreeConfig) string {
 func Free(config *FreeConfig) error {
 	m, err := meminfo()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	mmi, err := getMainMemInfo(m, config)
 	if err != nil {
@@ -157,16 +168,36 @@ func Free(config *FreeConfig) error {
 	if err != nil {
 		return err
 	}
-	mi := MemInfo{Mem: *mmi, Swap: *si}
+	return printMemInfo(os.Stdout, &MemInfo{Mem: *mmi, Swap: *si}, config)
+}
+
+// printMemInfo writes the memory information to w in the format selected by
+// config.
+func printMemInfo(w io.Writer, mi *MemInfo, config *FreeConfig) error {
 	if config.ToJSON {
 		jsonData, err := json.Marshal(mi)
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(jsonData))
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	}
+	mmi, si := &mi.Mem, &mi.Swap
+	if config.Wide {
+		fmt.Fprintf(w, "              total        used        free      shared     buffers       cache   available\n")
+		fmt.Fprintf(w, "%-7s %11v %11v %11v %11v %11v %11v %11v\n",
+			"Mem:",
+			formatValueByConfig(mmi.Total, config),
+			formatValueByConfig(mmi.Used, config),
+			formatValueByConfig(mmi.Free, config),
+			formatValueByConfig(mmi.Shared, config),
+			formatValueByConfig(mmi.Buffers, config),
+			formatValueByConfig(mmi.Cached, config),
+			formatValueByConfig(mmi.Available, config),
+		)
 	} else {
-		fmt.Printf("              total        used        free      shared  buff/cache   available\n")
-		fmt.Printf("%-7s %11v %11v %11v %11v %11v %11v\n",
+		fmt.Fprintf(w, "              total        used        free      shared  buff/cache   available\n")
+		fmt.Fprintf(w, "%-7s %11v %11v %11v %11v %11v %11v\n",
 			"Mem:",
 			formatValueByConfig(mmi.Total, config),
 			formatValueByConfig(mmi.Used, config),
@@ -175,14 +206,14 @@ func Free(config *FreeConfig) error {
 			formatValueByConfig(mmi.Buffers+mmi.Cached, config),
 			formatValueByConfig(mmi.Available, config),
 		)
-		fmt.Printf("%-7s %11v %11v %11v\n",
-			"Swap:",
-			formatValueByConfig(si.Total, config),
-			formatValueByConfig(si.Used, config),
-			formatValueByConfig(si.Free, config),
-		)
 	}
-	return nil
+	_, err := fmt.Fprintf(w, "%-7s %11v %11v %11v\n",
+		"Swap:",
+		formatValueByConfig(si.Total, config),
+		formatValueByConfig(si.Used, config),
+		formatValueByConfig(si.Free, config),
+	)
+	return err
 }
 
 // validateUnits checks that only one option of -b, -k, -m, -g, -t or -h has been
@@ -218,7 +249,10 @@ func main() {
 	if !validateUnits() {
 		log.Fatal("Options -k, -m, -g, -t and -h are mutually exclusive")
 	}
-	config := FreeConfig{ToJSON: *toJSON}
+	if *seconds < 0 || *count < 0 {
+		log.Fatal("Options -s and -c must not be negative")
+	}
+	config := FreeConfig{Unit: KB, ToJSON: *toJSON, Wide: *wide}
 	if *humanOutput {
 		config.HumanOutput = true
 	} else {
@@ -236,7 +270,14 @@ func main() {
 		}
 	}
 
-	if err := Free(&config); err != nil {
-		log.Fatal(err)
+	for i := 1; ; i++ {
+		if err := Free(&config); err != nil {
+			log.Fatal(err)
+		}
+		if *seconds == 0 || i == *count {
+			break
+		}
+		fmt.Println()
+		time.Sleep(time.Duration(*seconds * float64(time.Second)))
 	}
 }
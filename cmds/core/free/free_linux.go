@@ -49,14 +49,12 @@ func meminfoFromBytes(buf []byte) (meminfomap, error) {
 // getMainMemInfo prints the physical memory information in the specified units. Only
 // the relevant fields will be used from the input map.
 func getMainMemInfo(m meminfomap, config *FreeConfig) (*mainMemInfo, error) {
+	// Shmem, SReclaimable and MemAvailable are missing on old kernels.
 	fields := []string{
 		"MemTotal",
 		"MemFree",
 		"Buffers",
 		"Cached",
-		"Shmem",
-		"SReclaimable",
-		"MemAvailable",
 	}
 	if missingRequiredFields(m, fields) {
 		return nil, fmt.Errorf("missing required fields from meminfo")
@@ -68,8 +66,21 @@ func getMainMemInfo(m meminfomap, config *FreeConfig) (*mainMemInfo, error) {
 	memShared := m["Shmem"] << KB
 	memCached := (m["Cached"] + m["SReclaimable"]) << KB
 	memBuffers := (m["Buffers"]) << KB
-	memUsed := memTotal - memFree - memCached - memBuffers
-	memAvailable := m["MemAvailable"] << KB
+	var memUsed uint64
+	if memTotal > memFree+memCached+memBuffers {
+		memUsed = memTotal - memFree - memCached - memBuffers
+	}
+	memAvailable, ok := m["MemAvailable"]
+	if ok {
+		memAvailable <<= KB
+	} else {
+		// Without the kernel's estimate, assume that all of the
+		// buffers and cache can be reclaimed.
+		memAvailable = memFree + memCached + memBuffers
+		if memAvailable > memTotal {
+			memAvailable = memTotal
+		}
+	}
 
 	mmi := mainMemInfo{
 		Total:     memTotal,
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -150,3 +151,80 @@ SReclaimable:     179852 kB`)
 		t.Fatal("printMem: got no error when expecting one")
 	}
 }
+
+func TestPrintMemNoAvailable(t *testing.T) {
+	input := []byte(`MemTotal:        8052976 kB
+MemFree:          721716 kB
+Buffers:          244880 kB
+Cached:          3462124 kB
+SwapTotal:       8265724 kB
+SwapFree:        8264956 kB`)
+	m, err := meminfoFromBytes(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmi, err := getMainMemInfo(m, &FreeConfig{Unit: KB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(721716+244880+3462124) << KB; mmi.Available != want {
+		t.Fatalf("MainMem.Available: got %v, want %v", mmi.Available, want)
+	}
+	if mmi.Shared != 0 {
+		t.Fatalf("MainMem.Shared: got %v, want 0", mmi.Shared)
+	}
+}
+
+func TestPrintMemInfo(t *testing.T) {
+	mi := &MemInfo{
+		Mem: mainMemInfo{
+			Total:     8 << GB,
+			Used:      2 << GB,
+			Free:      1 << GB,
+			Shared:    512 << MB,
+			Cached:    4 << GB,
+			Buffers:   1 << GB,
+			Available: 5 << GB,
+		},
+		Swap: swapInfo{
+			Total: 1 << GB,
+			Used:  0,
+			Free:  1 << GB,
+		},
+	}
+	for _, tt := range []struct {
+		config FreeConfig
+		want   string
+	}{
+		{
+			config: FreeConfig{Unit: MB},
+			want: "              total        used        free      shared  buff/cache   available\n" +
+				"Mem:           8192        2048        1024         512        5120        5120\n" +
+				"Swap:          1024           0        1024\n",
+		},
+		{
+			config: FreeConfig{Unit: GB, Wide: true},
+			want: "              total        used        free      shared     buffers       cache   available\n" +
+				"Mem:              8           2           1           0           1           4           5\n" +
+				"Swap:             1           0           1\n",
+		},
+		{
+			config: FreeConfig{HumanOutput: true},
+			want: "              total        used        free      shared  buff/cache   available\n" +
+				"Mem:           8.0G        2.0G        1.0G      512.0M        5.0G        5.0G\n" +
+				"Swap:          1.0G        0.0B        1.0G\n",
+		},
+		{
+			config: FreeConfig{ToJSON: true},
+			want:   `{"mem":{"total":8589934592,"used":2147483648,"free":1073741824,"shared":536870912,"cached":4294967296,"buffers":1073741824,"available":5368709120},"swap":{"total":1073741824,"used":0,"free":1073741824}}` + "\n",
+		},
+	} {
+		var b bytes.Buffer
+		if err := printMemInfo(&b, mi, &tt.config); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("printMemInfo(%+v): got\n%s\nwant\n%s", tt.config, b.String(), tt.want)
+		}
+	}
+}
@@ -3,6 +3,22 @@
 // license that can be found in the LICENSE file.
 
 // gzip compresses files using gzip compression.
+//
+// Synopsis:
+//     gzip [-cdfhkqtv] [-1..-9] [-b BLOCKSIZE] [-p THREADS] [-S SUFFIX] [FILE]...
+//
+// Description:
+//     Compress each FILE to FILE.gz, or stdin to stdout. gunzip and gzcat
+//     decompress, like -d and -d -c.
+//
+//     Like pigz, the input is split into blocks of BLOCKSIZE KiB, which
+//     up to THREADS goroutines compress concurrently. The blocks end at
+//     flush boundaries, so the output is a single standard gzip stream
+//     that any gunzip can decompress.
+//
+// Options:
+//     -b: compression block size in KiB (default 128)
+//     -p: number of compression threads (default: number of CPUs)
 package main
 
 import (
@@ -5,32 +5,206 @@
 // hostname prints or changes the system's hostname.
 //
 // Synopsis:
-//     hostname [HOSTNAME]
+//     hostname [-f|-d|-s|-i|-I]
+//     hostname [-p] [HOSTNAME | -F FILE]
+//
+// Description:
+//     Without arguments, print the hostname. Otherwise, set it to
+//     HOSTNAME, or the first line of FILE, which requires root. Hostnames
+//     are dot-separated labels of letters, digits and hyphens.
+//
+// Options:
+//     -f: print the fully qualified domain name
+//     -d: print the DNS domain name
+//     -s: print the hostname up to the first dot
+//     -i: print the addresses the hostname resolves to
+//     -I: print the addresses of all interfaces, except loopback and
+//         IPv6 link-local addresses
+//     -F: set the hostname to the first line of FILE
+//     -p: also write the new hostname to /etc/hostname
 //
 // Author:
 //     Beletti <rhiguita@gmail.com>
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
+	"strings"
 )
 
-func main() {
-	a := os.Args
-	switch len(a) {
-	case 2:
-		if err := Sethostname(a[1]); err != nil {
-			log.Fatalf("could not set hostname: %v", err)
-		}
-	case 1:
-		hostname, err := os.Hostname()
+var (
+	fqdn     = flag.Bool("f", false, "Print the fully qualified domain name")
+	domain   = flag.Bool("d", false, "Print the DNS domain name")
+	short    = flag.Bool("s", false, "Print the hostname up to the first dot")
+	resolved = flag.Bool("i", false, "Print the addresses the hostname resolves to")
+	allAddrs = flag.Bool("I", false, "Print the addresses of all interfaces, except loopback and IPv6 link-local addresses")
+	file     = flag.String("F", "", "Set the hostname to the first line of this file")
+	persist  = flag.Bool("p", false, "Also write the new hostname to /etc/hostname")
+)
+
+const usage = "usage: hostname [-f|-d|-s|-i|-I] | [-p] [HOSTNAME | -F FILE]"
+
+// maxLen is HOST_NAME_MAX on Linux.
+const maxLen = 64
+
+// Overridden in tests.
+var (
+	hostnameFile   = "/etc/hostname"
+	hostname       = os.Hostname
+	lookupHost     = net.LookupHost
+	lookupAddr     = net.LookupAddr
+	interfaceAddrs = net.InterfaceAddrs
+)
+
+// validate returns an error if name is not a valid hostname: dot-separated
+// labels of at most 63 letters, digits and hyphens, which do not start or
+// end with a hyphen.
+func validate(name string) error {
+	if name == "" || len(name) > maxLen {
+		return fmt.Errorf("hostname %q must be 1 to %d characters long", name, maxLen)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("hostname %q has an empty or too long label", name)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("label %q of hostname %q starts or ends with a hyphen", label, name)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("hostname %q contains the invalid character %q", name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// setHostname validates name, sets it, and writes it to hostnameFile if
+// persist is set.
+func setHostname(name string, persist bool) error {
+	if err := validate(name); err != nil {
+		return err
+	}
+	if err := Sethostname(name); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("could not set hostname: %v (are you root?)", err)
+		}
+		return fmt.Errorf("could not set hostname: %v", err)
+	}
+	if persist {
+		return ioutil.WriteFile(hostnameFile, []byte(name+"\n"), 0644)
+	}
+	return nil
+}
+
+// readHostname returns the first line of the file path.
+func readHostname(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0]), nil
+}
+
+// fullName returns the fully qualified domain name of name: the name the
+// first address of name resolves back to.
+func fullName(name string) (string, error) {
+	addrs, err := lookupHost(name)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range addrs {
+		names, err := lookupAddr(a)
+		if err == nil && len(names) > 0 {
+			return strings.TrimSuffix(names[0], "."), nil
+		}
+	}
+	// Without a reverse record, name is the best guess.
+	return name, nil
+}
+
+// interfaceIPs returns the addresses of all interfaces, except loopback and
+// IPv6 link-local addresses.
+func interfaceIPs() ([]string, error) {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || n.IP.IsLoopback() || (n.IP.To4() == nil && n.IP.IsLinkLocalUnicast()) {
+			continue
+		}
+		ips = append(ips, n.IP.String())
+	}
+	return ips, nil
+}
+
+func run(args []string, stdout io.Writer) error {
+	if *file != "" || len(args) > 0 {
+		name := ""
+		switch {
+		case *file != "" && len(args) == 0:
+			var err error
+			if name, err = readHostname(*file); err != nil {
+				return err
+			}
+		case *file == "" && len(args) == 1:
+			name = args[0]
+		default:
+			return errors.New(usage)
+		}
+		return setHostname(name, *persist)
+	}
+
+	h, err := hostname()
+	if err != nil {
+		return fmt.Errorf("could not obtain hostname: %v", err)
+	}
+	switch {
+	case *fqdn, *domain:
+		full, err := fullName(h)
+		if err != nil {
+			return fmt.Errorf("could not resolve %q: %v", h, err)
+		}
+		if *fqdn {
+			fmt.Fprintln(stdout, full)
+		} else if i := strings.Index(full, "."); i >= 0 {
+			fmt.Fprintln(stdout, full[i+1:])
+		} else {
+			fmt.Fprintln(stdout)
+		}
+	case *short:
+		fmt.Fprintln(stdout, strings.SplitN(h, ".", 2)[0])
+	case *resolved:
+		addrs, err := lookupHost(h)
 		if err != nil {
-			log.Fatalf("could not obtain hostname: %v", err)
+			return fmt.Errorf("could not resolve %q: %v", h, err)
 		}
-		fmt.Println(hostname)
+		fmt.Fprintln(stdout, strings.Join(addrs, " "))
+	case *allAddrs:
+		ips, err := interfaceIPs()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, strings.Join(ips, " "))
 	default:
-		log.Fatalf("usage: hostname [HOSTNAME]")
+		fmt.Fprintln(stdout, h)
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
 	}
 }
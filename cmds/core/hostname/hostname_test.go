@@ -0,0 +1,149 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ok   bool
+	}{
+		{"localhost", true},
+		{"node-1.example.com", true},
+		{"1a", true},
+		{strings.Repeat("a", 63), true},
+		{"", false},
+		{strings.Repeat("a", 64), false},
+		{strings.Repeat("a.", 32) + "a", false},
+		{"-node", false},
+		{"node-", false},
+		{"a..b", false},
+		{"node_1", false},
+		{"node 1", false},
+		{"nöde", false},
+	} {
+		if err := validate(tt.name); (err == nil) != tt.ok {
+			t.Errorf("validate(%q) = %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestReadHostname(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "hostname")
+	if err := ioutil.WriteFile(p, []byte("  node1 \nignored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readHostname(p); err != nil || got != "node1" {
+		t.Errorf("readHostname() = %q, %v, want node1", got, err)
+	}
+	if _, err := readHostname(filepath.Join(dir, "missing")); err == nil {
+		t.Error("readHostname() of a missing file = nil, want error")
+	}
+}
+
+func TestFullName(t *testing.T) {
+	defer func(h, a func(string) ([]string, error)) { lookupHost, lookupAddr = h, a }(lookupHost, lookupAddr)
+	lookupHost = func(name string) ([]string, error) {
+		switch name {
+		case "node1", "node2":
+			return []string{"192.0.2.1", "192.0.2.2"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupAddr = func(addr string) ([]string, error) {
+		if addr == "192.0.2.2" {
+			return []string{"node1.example.com."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	if got, err := fullName("node1"); err != nil || got != "node1.example.com" {
+		t.Errorf("fullName(node1) = %q, %v, want node1.example.com", got, err)
+	}
+
+	lookupAddr = func(string) ([]string, error) { return nil, errors.New("no such host") }
+	if got, err := fullName("node2"); err != nil || got != "node2" {
+		t.Errorf("fullName(node2) = %q, %v, want node2", got, err)
+	}
+	if _, err := fullName("node3"); err == nil {
+		t.Error("fullName(node3) = nil, want error")
+	}
+}
+
+func TestInterfaceIPs(t *testing.T) {
+	defer func(f func() ([]net.Addr, error)) { interfaceAddrs = f }(interfaceAddrs)
+	interfaceAddrs = func() ([]net.Addr, error) {
+		var addrs []net.Addr
+		for _, s := range []string{"127.0.0.1/8", "192.0.2.2/24", "::1/128", "fe80::1/64", "2001:db8::2/64", "169.254.1.1/16"} {
+			ip, n, err := net.ParseCIDR(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n.IP = ip
+			addrs = append(addrs, n)
+		}
+		return addrs, nil
+	}
+	got, err := interfaceIPs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"192.0.2.2", "2001:db8::2", "169.254.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("interfaceIPs() = %v, want %v", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	defer func(h func() (string, error), l, a func(string) ([]string, error)) {
+		hostname, lookupHost, lookupAddr = h, l, a
+	}(hostname, lookupHost, lookupAddr)
+	hostname = func() (string, error) { return "node1.lab", nil }
+	lookupHost = func(string) ([]string, error) { return []string{"192.0.2.1"}, nil }
+	lookupAddr = func(string) ([]string, error) { return []string{"node1.lab.example.com."}, nil }
+
+	for _, tt := range []struct {
+		flag *bool
+		want string
+	}{
+		{nil, "node1.lab\n"},
+		{fqdn, "node1.lab.example.com\n"},
+		{domain, "lab.example.com\n"},
+		{short, "node1\n"},
+		{resolved, "192.0.2.1\n"},
+	} {
+		if tt.flag != nil {
+			*tt.flag = true
+		}
+		var b bytes.Buffer
+		err := run(nil, &b)
+		if tt.flag != nil {
+			*tt.flag = false
+		}
+		if err != nil || b.String() != tt.want {
+			t.Errorf("run() = %q, %v, want %q", b.String(), err, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{{"a", "b"}, {"bad_name"}} {
+		if err := run(args, ioutil.Discard); err == nil {
+			t.Errorf("run(%q) = nil, want error", args)
+		}
+	}
+}
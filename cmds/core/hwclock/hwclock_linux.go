@@ -2,50 +2,153 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// hwclock reads or changes the hardware clock (RTC) in UTC format.
+// hwclock reads or changes the hardware clock (RTC).
 //
 // Synopsis:
-//     hwclock [-w]
+//     hwclock [-r|-w|-s] [-u|-l] [-f DEVICE]
 //
 // Description:
-//     It prints the current hwclock time in UTC if called without any flags.
-//     It sets the hwclock to the system clock in UTC if called with -w.
+//     It prints the current hwclock time if called without any flags.
+//     With -w (--systohc), it sets the hwclock to the system clock, and with
+//     -s (--hctosys), the system clock to the hwclock.
+//
+//     The hwclock keeps UTC, unless -l is given, or the third line of
+//     /etc/adjtime is LOCAL and -u is not given.
 //
 // Options:
-//     -w: set hwclock to system clock in UTC
+//     -r, --show: print the hwclock time
+//     -w, --systohc: set hwclock to system clock
+//     -s, --hctosys: set system clock to hwclock
+//     -u, --utc: the hwclock keeps UTC
+//     -l, --localtime: the hwclock keeps local time
+//     -f, --rtc: RTC device, the first of /dev/rtc, /dev/rtc0 and
+//         /dev/misc/rtc0 by default
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/u-root/u-root/pkg/rtc"
+	"golang.org/x/sys/unix"
 )
 
-var write = flag.Bool("w", false, "Set hwclock from system clock in UTC")
+var (
+	show      bool
+	write     bool
+	hctosys   bool
+	utc       bool
+	localtime bool
+	device    string
+)
 
-func main() {
-	flag.Parse()
+func init() {
+	for _, name := range []string{"r", "show"} {
+		flag.BoolVar(&show, name, false, "Print the hwclock time")
+	}
+	for _, name := range []string{"w", "systohc"} {
+		flag.BoolVar(&write, name, false, "Set hwclock from system clock")
+	}
+	for _, name := range []string{"s", "hctosys"} {
+		flag.BoolVar(&hctosys, name, false, "Set system clock from hwclock")
+	}
+	for _, name := range []string{"u", "utc"} {
+		flag.BoolVar(&utc, name, false, "The hwclock keeps UTC")
+	}
+	for _, name := range []string{"l", "localtime"} {
+		flag.BoolVar(&localtime, name, false, "The hwclock keeps local time")
+	}
+	for _, name := range []string{"f", "rtc"} {
+		flag.StringVar(&device, name, "", "RTC device")
+	}
+}
+
+// adjtime records whether the hwclock keeps UTC or local time.
+var adjtime = "/etc/adjtime"
 
-	r, err := rtc.OpenRTC()
+// adjtimeLocal returns whether the third line of an adjtime file is LOCAL.
+func adjtimeLocal(r io.Reader) bool {
+	s := bufio.NewScanner(r)
+	for i := 0; s.Scan(); i++ {
+		if i == 2 {
+			return strings.TrimSpace(s.Text()) == "LOCAL"
+		}
+	}
+	return false
+}
+
+// location returns the location of the time the hwclock keeps.
+func location(utc, localtime bool) (*time.Location, error) {
+	switch {
+	case utc && localtime:
+		return nil, errors.New("-u and -l are mutually exclusive")
+	case utc:
+		return time.UTC, nil
+	case localtime:
+		return time.Local, nil
+	}
+	f, err := os.Open(adjtime)
 	if err != nil {
-		log.Fatal(err)
+		return time.UTC, nil
+	}
+	defer f.Close()
+	if adjtimeLocal(f) {
+		return time.Local, nil
 	}
+	return time.UTC, nil
+}
 
-	if *write {
-		tu := time.Now().UTC()
-		if err := r.Set(tu); err != nil {
-			log.Fatal(err)
-		}
+func run() error {
+	if write && hctosys {
+		return errors.New("-w and -s are mutually exclusive")
+	}
+	loc, err := location(utc, localtime)
+	if err != nil {
+		return err
 	}
 
-	t, err := r.Read()
+	var r *rtc.RTC
+	if device != "" {
+		r, err = rtc.Open(device)
+	} else {
+		r, err = rtc.OpenRTC()
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	defer r.Close()
+
+	if write {
+		return r.SetIn(time.Now(), loc)
+	}
+
+	t, err := r.ReadIn(loc)
+	if err != nil {
+		return err
+	}
+	if hctosys {
+		tv := unix.NsecToTimeval(t.UnixNano())
+		if err := unix.Settimeofday(&tv); err != nil {
+			return fmt.Errorf("setting the system clock: %v", err)
+		}
+		return nil
 	}
 
 	// Print local time. Match the format of util-linux' hwclock.
 	fmt.Println(t.Local().Format("Mon 2 Jan 2006 15:04:05 AM MST"))
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,71 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdjtimeLocal(t *testing.T) {
+	for _, tt := range []struct {
+		adjtime string
+		local   bool
+	}{
+		{"0.0 0 0.0\n0\nLOCAL\n", true},
+		{"0.0 0 0.0\n0\nUTC\n", false},
+		{"0.0 0 0.0\n0\n", false},
+		{"", false},
+	} {
+		if got := adjtimeLocal(strings.NewReader(tt.adjtime)); got != tt.local {
+			t.Errorf("adjtimeLocal(%q) = %t, want %t", tt.adjtime, got, tt.local)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hwclock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(a string) { adjtime = a }(adjtime)
+	adjtime = filepath.Join(dir, "adjtime")
+
+	for _, tt := range []struct {
+		name      string
+		adjtime   string
+		utc       bool
+		localtime bool
+		want      *time.Location
+		err       bool
+	}{
+		{name: "no adjtime", want: time.UTC},
+		{name: "adjtime", adjtime: "0.0 0 0.0\n0\nLOCAL\n", want: time.Local},
+		{name: "-u", adjtime: "0.0 0 0.0\n0\nLOCAL\n", utc: true, want: time.UTC},
+		{name: "-l", localtime: true, want: time.Local},
+		{name: "-u and -l", utc: true, localtime: true, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(adjtime)
+			if tt.adjtime != "" {
+				if err := ioutil.WriteFile(adjtime, []byte(tt.adjtime), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := location(tt.utc, tt.localtime)
+			if (err != nil) != tt.err {
+				t.Fatalf("location() = %v, want error %t", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("location() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,32 +7,35 @@
 // id displays the user id, group id, and groups of the calling process.
 //
 // Synopsis:
-//      id [-gGnu]
+//      id [-gGnu] [-r] [USER]
 //
 // Description:
-//      id displays the uid, gid and groups of the calling process
+//      id displays the uid, gid and groups of the calling process, or of
+//      the user USER. Names are looked up in /etc/passwd and /etc/group, and
+//      IDs with no name are displayed as numbers.
 //
 // Options:
 //	-g, --group     print only the effective group ID
 //	-G, --groups    print all group IDs
 //	-n, --name      print a name instead of a number, for -ugG
 //	-u, --user      print only the effective user ID
-//	-r, --user      print real ID instead of effective ID
+//	-r, --real      print real ID instead of effective ID
 package main
 
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"strconv"
 	"strings"
 	"syscall"
+
+	"github.com/u-root/u-root/pkg/getent"
 )
 
 var (
-	GroupFile  = "/etc/group"
-	PasswdFile = "/etc/passwd"
-
 	flags struct {
 		group  bool
 		groups bool
@@ -60,132 +63,132 @@ func init() {
 	flag.BoolVar(&flags.real, "r", false, "print real ID instead of effective ID")
 }
 
+// ID is a user or group ID, and its name, which is empty if the ID has
+// none.
+type ID struct {
+	id   int
+	name string
+}
+
+// Name returns the name of the ID, or the ID as a number if it has none.
+func (i ID) Name() string {
+	if i.name == "" {
+		return strconv.Itoa(i.id)
+	}
+	return i.name
+}
+
+// String returns the ID as id displays it by default.
+func (i ID) String() string {
+	if i.name == "" {
+		return strconv.Itoa(i.id)
+	}
+	return fmt.Sprintf("%d(%s)", i.id, i.name)
+}
+
 type User struct {
-	name   string
-	uid    int
-	gid    int
-	groups map[int]string
+	user   ID
+	group  ID
+	groups []ID
 }
 
 func (u *User) UID() int {
-	return u.uid
+	return u.user.id
 }
 
 func (u *User) GID() int {
-	return u.gid
+	return u.group.id
 }
 
 func (u *User) Name() string {
-	return u.name
+	return u.user.Name()
 }
 
-func (u *User) Groups() map[int]string {
-	return u.groups
+func (u *User) GIDName() string {
+	return u.group.Name()
 }
 
-func (u *User) GIDName() string {
-	val := u.Groups()[u.UID()]
-	return val
+// Groups returns the primary group, followed by the supplementary groups.
+func (u *User) Groups() []ID {
+	return u.groups
 }
 
 // NewUser is a factory method for the User type.
 func NewUser(username string, users *Users, groups *Groups) (*User, error) {
-	var groupsNumbers []int
-
-	u := &User{groups: make(map[int]string)}
+	var uid, gid int
+	var gids []int
 	if len(username) == 0 { // no username provided, get current
 		if flags.real {
-			u.uid = syscall.Geteuid()
-			u.gid = syscall.Getegid()
-		} else {
-			u.uid = syscall.Getuid()
-			u.gid = syscall.Getgid()
-		}
-		groupsNumbers, _ = syscall.Getgroups()
-		if v, err := users.GetUser(u.uid); err == nil {
-			u.name = v
+			uid = syscall.Getuid()
+			gid = syscall.Getgid()
 		} else {
-			u.name = strconv.Itoa(u.uid)
+			uid = syscall.Geteuid()
+			gid = syscall.Getegid()
 		}
+		gids, _ = syscall.Getgroups()
 	} else {
-		if v, err := users.GetUID(username); err == nil { // user is username
-			u.name = username
-			u.uid = v
-		} else {
-			if uid, err := strconv.Atoi(username); err == nil { // user is valid int
-				if v, err := users.GetUser(uid); err == nil { // user is valid uid
-					u.name = v
-					u.uid = uid
-				}
-			} else {
-				return nil, fmt.Errorf("id: no such user or uid: %s", username)
+		var err error
+		if uid, err = users.GetUID(username); err != nil {
+			// username may be a uid, if it is in the database.
+			n, nerr := strconv.Atoi(username)
+			if nerr != nil {
+				return nil, fmt.Errorf("no such user or uid: %s", username)
 			}
+			name, err := users.GetUser(n)
+			if err != nil {
+				return nil, fmt.Errorf("no such user or uid: %s", username)
+			}
+			uid, username = n, name
 		}
-		u.gid, _ = users.GetGID(u.uid)
-		groupsNumbers = append([]int{u.gid}, groups.UserGetGIDs(u.name)...)
-		// FIXME: not yet implemented group listing lookups
+		gid, _ = users.GetGID(uid)
+		gids = groups.UserGetGIDs(username)
 	}
 
-	for _, groupNum := range groupsNumbers {
-		if groupName, err := groups.GetGroup(groupNum); err == nil {
-			u.groups[groupNum] = groupName
-		} else {
-			u.groups[groupNum] = strconv.Itoa(groupNum)
+	u := &User{user: ID{id: uid}, group: ID{id: gid}}
+	u.user.name, _ = users.GetUser(uid)
+	u.group.name, _ = groups.GetGroup(gid)
+	u.groups = append(u.groups, u.group)
+	for _, g := range gids {
+		dup := false
+		for _, id := range u.groups {
+			dup = dup || id.id == g
+		}
+		if !dup {
+			name, _ := groups.GetGroup(g)
+			u.groups = append(u.groups, ID{id: g, name: name})
 		}
 	}
-
 	return u, nil
 }
 
 // IDCommand runs the "id" with the current user's information.
-func IDCommand(u User) {
-	if !flags.groups {
-		if flags.user {
-			if flags.name {
-				fmt.Println(u.Name())
-				return
-			}
-			fmt.Println(u.UID())
-			return
-		} else if flags.group {
-			if flags.name {
-				fmt.Println(u.GIDName())
-				return
-			}
-			fmt.Println(u.GID())
-			return
-		}
-
-		fmt.Printf("uid=%d(%s) ", u.UID(), u.Name())
-		fmt.Printf("gid=%d(%s) ", u.GID(), u.GIDName())
-	}
-
-	if !flags.groups {
-		fmt.Print("groups=")
-	}
-
-	var groupOutput []string
-
-	for gid, name := range u.Groups() {
-
-		if !flags.groups {
-			groupOutput = append(groupOutput, fmt.Sprintf("%d(%s)", gid, name))
-
-		} else {
+func IDCommand(w io.Writer, u User) {
+	switch {
+	case flags.user && flags.name:
+		fmt.Fprintln(w, u.Name())
+	case flags.user:
+		fmt.Fprintln(w, u.UID())
+	case flags.group && flags.name:
+		fmt.Fprintln(w, u.GIDName())
+	case flags.group:
+		fmt.Fprintln(w, u.GID())
+	case flags.groups:
+		var s []string
+		for _, g := range u.Groups() {
 			if flags.name {
-				groupOutput = append(groupOutput, fmt.Sprintf("%s ", name))
+				s = append(s, g.Name())
 			} else {
-				groupOutput = append(groupOutput, fmt.Sprintf("%d ", gid))
+				s = append(s, strconv.Itoa(g.id))
 			}
 		}
+		fmt.Fprintln(w, strings.Join(s, " "))
+	default:
+		var s []string
+		for _, g := range u.Groups() {
+			s = append(s, g.String())
+		}
+		fmt.Fprintf(w, "uid=%v gid=%v groups=%s\n", u.user, u.group, strings.Join(s, ","))
 	}
-
-	sep := ","
-	if flags.groups {
-		sep = ""
-	}
-
-	fmt.Println(strings.Join(groupOutput, sep))
 }
 
 func main() {
@@ -201,13 +204,13 @@ func main() {
 		log.Fatalf("id: cannot print only names or real IDs in default format")
 	}
 
-	users, err := NewUsers(PasswdFile)
+	users, err := NewUsers(getent.PasswdFile)
 	if err != nil {
-		log.Printf("id: unable to read %s: %v", PasswdFile, err)
+		log.Printf("id: unable to read %s: %v", getent.PasswdFile, err)
 	}
-	groups, err := NewGroups(GroupFile)
+	groups, err := NewGroups(getent.GroupFile)
 	if err != nil {
-		log.Printf("id: unable to read %s: %v", PasswdFile, err)
+		log.Printf("id: unable to read %s: %v", getent.GroupFile, err)
 	}
 
 	user, err := NewUser(flag.Arg(0), users, groups)
@@ -215,5 +218,5 @@ func main() {
 		log.Fatalf("id: %s", err)
 	}
 
-	IDCommand(*user)
+	IDCommand(os.Stdout, *user)
 }
@@ -286,6 +286,49 @@ func TestGroups(t *testing.T) {
 	}
 }
 
+func TestIDCommand(t *testing.T) {
+	users, err := NewUsers("testdata/passwd-simple.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := NewGroups("testdata/group-simple.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f struct{ group, groups, name, user, real bool }) { flags = f }(flags)
+	for _, tt := range []struct {
+		user                     string
+		group, groups, name, usr bool
+		out                      string
+	}{
+		{user: "curly", out: "uid=1001(curly) gid=1001 groups=1001,29(rpcuser),10(wheel)\n"},
+		{user: "1", out: "uid=1(bin) gid=1 groups=1\n"},
+		{user: "curly", usr: true, out: "1001\n"},
+		{user: "curly", usr: true, name: true, out: "curly\n"},
+		{user: "curly", group: true, out: "1001\n"},
+		{user: "curly", group: true, name: true, out: "1001\n"},
+		{user: "curly", groups: true, out: "1001 29 10\n"},
+		{user: "curly", groups: true, name: true, out: "1001 rpcuser wheel\n"},
+	} {
+		flags.group, flags.groups, flags.name, flags.user = tt.group, tt.groups, tt.name, tt.usr
+		u, err := NewUser(tt.user, users, groups)
+		if err != nil {
+			t.Errorf("NewUser(%q) = %v", tt.user, err)
+			continue
+		}
+		var out bytes.Buffer
+		IDCommand(&out, *u)
+		if out.String() != tt.out {
+			t.Errorf("id %+v = %q, want %q", tt, out.String(), tt.out)
+		}
+	}
+	for _, name := range []string{"nobody", "4242"} {
+		if _, err := NewUser(name, users, groups); err == nil {
+			t.Errorf("NewUser(%q) succeeded, want an error", name)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
@@ -7,80 +7,48 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strconv"
-	"strings"
+
+	"github.com/u-root/u-root/pkg/getent"
 )
 
 // Users manages a user database, typically loaded from /etc/passwd
 type Users struct {
-	uidToUser map[int]string
-	userToUID map[string]int
-	userToGID map[int]int
+	ents []getent.Passwd
 }
 
 // GetUID returns the UID of a username
 func (u *Users) GetUID(name string) (int, error) {
-	if v, ok := u.userToUID[name]; ok {
-		return v, nil
+	for _, p := range u.ents {
+		if p.Name == name {
+			return p.UID, nil
+		}
 	}
 	return -1, fmt.Errorf("unknown user name: %s", name)
 }
 
 // GetGID returns the primary GID of a UID
 func (u *Users) GetGID(uid int) (int, error) {
-	if v, ok := u.userToGID[uid]; ok {
-		return v, nil
+	for _, p := range u.ents {
+		if p.UID == uid {
+			return p.GID, nil
+		}
 	}
 	return -1, fmt.Errorf("unknown uid: %d", uid)
 }
 
 // GetUser returns the username of a UID
 func (u *Users) GetUser(uid int) (string, error) {
-	if v, ok := u.uidToUser[uid]; ok {
-		return v, nil
+	for _, p := range u.ents {
+		if p.UID == uid {
+			return p.Name, nil
+		}
 	}
-	return "", fmt.Errorf("unkown uid: %d", uid)
+	return "", fmt.Errorf("unknown uid: %d", uid)
 }
 
 // NewUsers is a factory for Users.  file is the file to read the database from.
-func NewUsers(file string) (u *Users, e error) {
-	u = &Users{}
-	u.uidToUser = make(map[int]string)
-	u.userToUID = make(map[string]int)
-	u.userToGID = make(map[int]int)
-
-	passwdFile, err := os.Open(file)
-	if err != nil {
-		return u, err
-	}
-
-	// Read from passwdFile for the users name
-	var passwdInfo []string
-
-	passwdScanner := bufio.NewScanner(passwdFile)
-
-	for passwdScanner.Scan() {
-		txt := passwdScanner.Text()
-		if len(txt) == 0 || txt[0] == '#' { // skip empty lines and comments
-			continue
-		}
-		passwdInfo = strings.Split(txt, ":")
-		userNum, err := strconv.Atoi(passwdInfo[2])
-		if err != nil {
-			return nil, err
-		}
-		groupNum, err := strconv.Atoi(passwdInfo[3])
-		if err != nil {
-			return nil, err
-		}
-
-		u.uidToUser[userNum] = passwdInfo[0]
-		u.userToUID[passwdInfo[0]] = userNum
-		u.userToGID[userNum] = groupNum
-	}
-
-	return
+func NewUsers(file string) (*Users, error) {
+	ents, err := getent.ReadPasswd(file)
+	return &Users{ents: ents}, err
 }
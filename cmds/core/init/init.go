@@ -8,12 +8,37 @@
 // init does some basic initialization (mount file systems, turn on loopback)
 // and then tries to execute, in order, /inito, a uinit (either in /bin, /bbin,
 // or /ubin), and then a shell (/bin/defaultsh and /bin/sh).
+//
+// If /etc/uinit.services exists, init supervises the services listed in it
+// instead, see libinit.ParseServices for the format. For example:
+//
+//     dhcp  wait       /bbin/dhclient -ipv6=false
+//     sshd  always     /bbin/sshd
+//     shell on-failure /bin/sh
+//
+// With uroot.initflags=getty on the kernel command line, init also runs a
+// login loop, alongside the commands or services above, on each console
+// given by a console= parameter, e.g. console=tty1 console=ttyS0,115200n8.
+// Each gets /bin/login or a shell, with the console as its controlling
+// terminal, and is restarted on logout.
+//
+// After the modules in /lib/modules are installed, init loads the modules
+// listed in /etc/modules-load.d/*.conf, one per line, and by modules-load= on
+// the kernel command line, together with their dependencies. Modules named
+// by modprobe.blacklist= are not loaded, and module.param=value parameters
+// are passed on.
+//
+// With uroot.initflags=coldplug, init then loads the modules for the
+// modaliases of the devices in /sys/devices and writes add to their uevent
+// files, so that devices present before init started are bound to drivers
+// and announced, see the coldplug command.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 
 	"github.com/u-root/u-root/pkg/libinit"
@@ -23,6 +48,10 @@ import (
 // the init process after some initial setup.
 type initCmds struct {
 	cmds []*exec.Cmd
+	// services are supervised instead of running cmds, if there are any.
+	services []libinit.Service
+	// gettys are login loops, supervised alongside cmds or services.
+	gettys []libinit.Service
 }
 
 var (
@@ -67,10 +96,7 @@ func main() {
 		go startBgBuild()
 	}
 
-	cmdCount := libinit.RunCommands(debug, ic.cmds...)
-	if cmdCount == 0 {
-		log.Printf("No suitable executable found in %v", ic.cmds)
-	}
+	run(ic)
 
 	// We need to reap all children before exiting.
 	log.Printf("Waiting for orphaned children")
@@ -82,3 +108,53 @@ func main() {
 	}
 	log.Printf("Exiting...")
 }
+
+// run runs the commands of ic, or its services instead, and its login
+// loops alongside either.
+func run(ic *initCmds) {
+	if len(ic.services) > 0 {
+		supervise(append(ic.services, ic.gettys...))
+		return
+	}
+	if len(ic.gettys) == 0 {
+		if cmdCount := libinit.RunCommands(debug, ic.cmds...); cmdCount == 0 {
+			log.Printf("No suitable executable found in %v", ic.cmds)
+		}
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		supervise(ic.gettys)
+		close(done)
+	}()
+	if cmdCount := runCommands(ic.cmds); cmdCount == 0 {
+		log.Printf("No suitable executable found in %v", ic.cmds)
+	}
+	<-done
+}
+
+// runCommands runs the commands that exist in sequence, like
+// libinit.RunCommands, and returns how many there were. It only waits for
+// them; libinit.RunCommands waits for any child, and would take the exits
+// of the login loops from their supervisor.
+func runCommands(cmds []*exec.Cmd) int {
+	var cmdCount int
+	for _, cmd := range cmds {
+		if _, err := os.Stat(cmd.Path); os.IsNotExist(err) {
+			debug("%v", err)
+			continue
+		}
+
+		cmdCount++
+		debug("Trying to run %v", cmd)
+		if err := cmd.Start(); err != nil {
+			log.Printf("Error starting %v: %v", cmd, err)
+			continue
+		}
+		if err := cmd.Wait(); err != nil {
+			debug("%v exited: %v", cmd, err)
+		}
+	}
+	return cmdCount
+}
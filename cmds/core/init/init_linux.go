@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"syscall"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/u-root/u-root/pkg/libinit"
 	"github.com/u-root/u-root/pkg/uflag"
 	"github.com/u-root/u-root/pkg/ulog"
+	"github.com/u-root/u-root/pkg/upath"
 )
 
 func quiet() {
@@ -33,12 +35,18 @@ func osInitGo() *initCmds {
 
 	// Install modules before exec-ing into user mode below
 	libinit.InstallAllModules()
+	libinit.LoadConfiguredModules()
 
 	// systemd is "special". If we are supposed to run systemd, we're
 	// going to exec, and if we're going to exec, we're done here.
 	// systemd uber alles.
 	initFlags := cmdline.GetInitFlagMap()
 
+	// Bring up the devices the kernel found before we started.
+	if coldplug, err := strconv.ParseBool(initFlags["coldplug"]); err == nil && coldplug {
+		libinit.Coldplug()
+	}
+
 	// systemd gets upset when it discovers it isn't really process 1, so
 	// we can't start it in its own namespace. I just love systemd.
 	systemd, present := initFlags["systemd"]
@@ -61,7 +69,18 @@ func osInitGo() *initCmds {
 	}
 	uinitArgs := libinit.WithArguments(args...)
 
+	services, err := libinit.ReadServices("/etc/uinit.services")
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Not supervising services: %v", err)
+	}
+	var loginLoops []libinit.Service
+	if getty, err := strconv.ParseBool(initFlags["getty"]); err == nil && getty {
+		loginLoops = gettys(cmdline.Consoles())
+	}
+
 	return &initCmds{
+		services: services,
+		gettys:   loginLoops,
 		cmds: []*exec.Cmd{
 			// inito is (optionally) created by the u-root command when the
 			// u-root initramfs is merged with an existing initramfs that
@@ -80,3 +99,44 @@ func osInitGo() *initCmds {
 	}
 
 }
+
+// loginCmds are tried in order for the login loop on each console.
+var loginCmds = []string{"/bin/login", "/bin/defaultsh", "/bin/sh"}
+
+// gettys returns a service per console that runs a login program or shell
+// on it, and restarts it on logout.
+func gettys(consoles []cmdline.Console) []libinit.Service {
+	var login string
+	for _, c := range loginCmds {
+		if _, err := os.Stat(upath.UrootPath(c)); err == nil {
+			login = c
+			break
+		}
+	}
+	if login == "" {
+		log.Printf("No login program or shell found in %v", loginCmds)
+		return nil
+	}
+	if len(consoles) == 0 {
+		log.Printf("No console= parameter, not starting gettys")
+	}
+	var services []libinit.Service
+	for _, c := range consoles {
+		if _, err := os.Stat(filepath.Join("/dev", c.Name)); err != nil {
+			log.Printf("Not starting getty on %s: %v", c.Name, err)
+			continue
+		}
+		services = append(services, libinit.Service{
+			Name:    "getty-" + c.Name,
+			Args:    []string{login},
+			Restart: libinit.RestartAlways,
+			TTY:     c.Name,
+			Baud:    c.Baud(),
+		})
+	}
+	return services
+}
+
+func supervise(services []libinit.Service) {
+	libinit.Supervise(services)
+}
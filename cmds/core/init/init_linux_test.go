@@ -0,0 +1,49 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/libinit"
+)
+
+func TestRunWithGettys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "init")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	sh := func(script string) []string {
+		return []string{"/bin/sh", "-c", script}
+	}
+
+	// The getty outlives uinit, so that uinit exits while it is
+	// supervised.
+	run(&initCmds{
+		cmds: []*exec.Cmd{
+			exec.Command(filepath.Join(dir, "missing")),
+			exec.Command("/bin/sh", "-c", "echo uinit >> "+log+"; exit 1"),
+			exec.Command("/bin/sh", "-c", "echo shell >> "+log),
+		},
+		gettys: []libinit.Service{
+			{Name: "getty-test", Args: sh("sleep 0.5; echo getty >> " + log)},
+		},
+	})
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Fields(string(b)), []string{"uinit", "shell", "getty"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("init ran %q, want %q", got, want)
+	}
+}
@@ -7,6 +7,7 @@
 package main
 
 import (
+	"log"
 	"os/exec"
 
 	"github.com/u-root/u-root/pkg/libinit"
@@ -34,3 +35,7 @@ func osInitGo() *initCmds {
 	}
 
 }
+
+func supervise([]libinit.Service) {
+	log.Printf("Service supervision is not supported on Plan 9")
+}
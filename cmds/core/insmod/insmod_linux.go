@@ -8,7 +8,8 @@
 //	insmod [filename] [module options...]
 //
 // Description:
-//	insmod is a clone of insmod(8)
+//	insmod is a clone of insmod(8). Modules compressed with gzip, xz or
+//	zstd (.ko.gz, .ko.xz, .ko.zst) are decompressed before loading.
 package main
 
 import (
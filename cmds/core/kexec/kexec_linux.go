@@ -5,23 +5,39 @@
 // kexec executes a new kernel over the running kernel (u-root).
 //
 // Synopsis:
-//     kexec [--initrd=FILE] [--command-line=STRING] [-l] [-e] [KERNELIMAGE]
+//     kexec [--initrd=FILE] [--command-line=STRING] [-t TYPE] [-l] [-e] [KERNELIMAGE]
 //
 // Description:
-//		 Loads a kernel for later execution.
+//		 Loads a kernel for later execution. Without -l and -e, the kernel
+//		 is loaded and then executed.
+//
+//		 Linux kernels, e.g. bzImage on x86, are loaded with
+//		 kexec_file_load, which lets the running kernel verify their
+//		 signature. If the running kernel does not support it, the new
+//		 kernel is an ELF file, and there is no initrd or command line,
+//		 which only the Linux boot protocol knows of, its segments are
+//		 loaded with kexec_load and its entry point is jumped to. -s turns
+//		 this fallback off.
 //
 // Options:
 //     --cmdline=STRING or -c=STRING: Set the kernel command line
-//     --reuse-commandline:           Use the kernel command line from running system
+//     --reuse-cmdline:               Use the kernel command line from running system,
+//                                    followed by --cmdline if given
 //     --i=FILE or --initrd=FILE:     Use file as the kernel's initial ramdisk
 //     -l or --load:                  Load the new kernel into the current kernel
 //     -e or --exec:                  Execute a currently loaded kernel
+//     -t or --type:                  Kernel type: linux (or bzImage, Image), multiboot,
+//                                    or elf; guessed by default
+//     -s or --kexec-file-syscall:    Fail rather than fall back to kexec_load for
+//                                    Linux kernels
 package main
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 
@@ -39,6 +55,8 @@ type options struct {
 	load         bool
 	exec         bool
 	debug        bool
+	kernelType   string
+	fileLoadOnly bool
 	modules      []string
 }
 
@@ -46,16 +64,97 @@ func registerFlags() *options {
 	o := &options{}
 	flag.StringVarP(&o.cmdline, "cmdline", "c", "", "Append to the kernel command line")
 	flag.StringVar(&o.cmdline, "append", "", "Append to the kernel command line")
+	flag.StringVar(&o.cmdline, "command-line", "", "Append to the kernel command line")
 	flag.BoolVar(&o.reuseCmdline, "reuse-cmdline", false, "Use the kernel command line from running system")
 	flag.StringVarP(&o.initramfs, "initrd", "i", "", "Use file as the kernel's initial ramdisk")
 	flag.StringVar(&o.initramfs, "initramfs", "", "Use file as the kernel's initial ramdisk")
 	flag.BoolVarP(&o.load, "load", "l", false, "Load the new kernel into the current kernel")
 	flag.BoolVarP(&o.exec, "exec", "e", false, "Execute a currently loaded kernel")
 	flag.BoolVarP(&o.debug, "debug", "d", false, "Print debug info")
+	flag.StringVarP(&o.kernelType, "type", "t", "", "Kernel type: linux (or bzImage, Image), multiboot, or elf")
+	flag.BoolVarP(&o.fileLoadOnly, "kexec-file-syscall", "s", false, "Fail rather than fall back to kexec_load for Linux kernels")
 	flag.StringArrayVar(&o.modules, "module", nil, `Load multiboot module with command line args (e.g --module="mod arg1")`)
 	return o
 }
 
+// Kernel types of -t.
+const (
+	typeLinux     = "linux"
+	typeMultiboot = "multiboot"
+	typeELF       = "elf"
+)
+
+// parseType returns the kernel type of -t, or "" to guess it.
+func parseType(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "linux", "bzimage", "image":
+		return typeLinux, nil
+	case "multiboot":
+		return typeMultiboot, nil
+	case "elf":
+		return typeELF, nil
+	}
+	return "", fmt.Errorf("unknown kernel type %q, want linux, bzImage, Image, multiboot or elf", s)
+}
+
+// newCmdline returns the command line of the new kernel.
+func newCmdline(o *options, procCmdline func() (string, error)) (string, error) {
+	if !o.reuseCmdline {
+		return o.cmdline, nil
+	}
+	c, err := procCmdline()
+	if err != nil {
+		return "", fmt.Errorf("couldn't read /proc/cmdline: %v", err)
+	}
+	c = strings.TrimSpace(c)
+	if o.cmdline != "" {
+		c = strings.TrimSpace(c + " " + o.cmdline)
+	}
+	return c, nil
+}
+
+func load(o *options, kernelpath, cmdline string) error {
+	kernelType, err := parseType(o.kernelType)
+	if err != nil {
+		return err
+	}
+	kernel, err := os.Open(kernelpath)
+	if err != nil {
+		return err
+	}
+	defer kernel.Close()
+
+	if kernelType == "" && multiboot.Probe(kernel) == nil {
+		kernelType = typeMultiboot
+	}
+	switch kernelType {
+	case typeMultiboot:
+		image := &boot.MultibootImage{
+			Modules: multiboot.LazyOpenModules(o.modules),
+			Kernel:  kernel,
+			Cmdline: cmdline,
+		}
+		return image.Load(o.debug)
+
+	case typeELF:
+		return kexec.LoadELF(kernel)
+	}
+
+	var i io.ReaderAt
+	if o.initramfs != "" {
+		i = uio.NewLazyFile(o.initramfs)
+	}
+	image := &boot.LinuxImage{
+		Kernel:       uio.NewLazyFile(kernelpath),
+		Initrd:       i,
+		Cmdline:      cmdline,
+		FileLoadOnly: o.fileLoadOnly,
+	}
+	return image.Load(o.debug)
+}
+
 func main() {
 	opts := registerFlags()
 	flag.Parse()
@@ -65,52 +164,20 @@ func main() {
 		log.Fatalf("usage: kexec [flags] kernelname OR kexec -e")
 	}
 
-	if opts.cmdline != "" && opts.reuseCmdline {
-		flag.PrintDefaults()
-		log.Fatalf("--reuse-cmdline and other command line options are mutually exclusive")
-	}
-
 	if !opts.load && !opts.exec {
 		opts.load = true
 		opts.exec = true
 	}
 
-	newCmdline := opts.cmdline
-	if opts.reuseCmdline {
-		procCmdLine := cmdline.NewCmdLine()
-		if procCmdLine.Err != nil {
-			log.Fatal("Couldn't read /proc/cmdline")
-		} else {
-			newCmdline = procCmdLine.Raw
-		}
-	}
-
 	if opts.load {
-		kernelpath := flag.Arg(0)
-		mbkernel, err := os.Open(kernelpath)
+		c, err := newCmdline(opts, func() (string, error) {
+			c := cmdline.NewCmdLine()
+			return c.Raw, c.Err
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer mbkernel.Close()
-		var image boot.OSImage
-		if err := multiboot.Probe(mbkernel); err == nil {
-			image = &boot.MultibootImage{
-				Modules: multiboot.LazyOpenModules(opts.modules),
-				Kernel:  mbkernel,
-				Cmdline: newCmdline,
-			}
-		} else {
-			var i io.ReaderAt
-			if opts.initramfs != "" {
-				i = uio.NewLazyFile(opts.initramfs)
-			}
-			image = &boot.LinuxImage{
-				Kernel:  uio.NewLazyFile(kernelpath),
-				Initrd:  i,
-				Cmdline: newCmdline,
-			}
-		}
-		if err := image.Load(opts.debug); err != nil {
+		if err := load(opts, flag.Arg(0), c); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -0,0 +1,48 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	for in, want := range map[string]string{
+		"":          "",
+		"bzImage":   typeLinux,
+		"Image":     typeLinux,
+		"linux":     typeLinux,
+		"multiboot": typeMultiboot,
+		"ELF":       typeELF,
+	} {
+		if got, err := parseType(in); err != nil || got != want {
+			t.Errorf("parseType(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseType("uImage"); err == nil {
+		t.Error("parseType(uImage) = nil, want error")
+	}
+}
+
+func TestNewCmdline(t *testing.T) {
+	proc := func() (string, error) { return "console=ttyS0 root=/dev/sda1\n", nil }
+	for _, tt := range []struct {
+		o    options
+		want string
+	}{
+		{options{cmdline: "quiet"}, "quiet"},
+		{options{reuseCmdline: true}, "console=ttyS0 root=/dev/sda1"},
+		{options{reuseCmdline: true, cmdline: "quiet"}, "console=ttyS0 root=/dev/sda1 quiet"},
+	} {
+		if got, err := newCmdline(&tt.o, proc); err != nil || got != tt.want {
+			t.Errorf("newCmdline(%+v) = %q, %v, want %q", tt.o, got, err, tt.want)
+		}
+	}
+	o := options{reuseCmdline: true}
+	if _, err := newCmdline(&o, func() (string, error) { return "", errors.New("no /proc") }); err == nil {
+		t.Error("newCmdline() without /proc/cmdline = nil, want error")
+	}
+}
@@ -5,21 +5,28 @@
 // Kill kills processes.
 //
 // Synopsis:
-//     kill -l
-//     kill [<-s | --signal | -> <isgname|signum>] pid [pid...]
+//     kill -l [signame|signum]...
+//     kill -L
+//     kill [<-s | --signal | -> <signame|signum>] pid [pid...]
 //
 // Options:
-//     -l:                       list the signal names
+//     -l:                       list the signal names, or convert the given
+//                               signal names to numbers and numbers to names
+//     -L:                       list the signal numbers and names
 //     -name, --signal name, -s: name is the message to send. On some systems
 //                               this is a string, on others a number. It is
 //                               optional and an OS-dependent value will be
 //                               used if it is not set. pid is a list of at
-//                               least one pid.
+//                               least one pid. Signal names may be given
+//                               with or without the SIG prefix, e.g. -TERM,
+//                               -SIGTERM or -15.
 package main
 
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const eUsage = "Usage: kill -l | kill [<-s | --signal | -> <signame|signum>] pid [pid...]"
@@ -34,10 +41,51 @@ func die(msg string, args ...interface{}) {
 	os.Exit(1)
 }
 
+// lookup returns the signal for a number or a name with or without the
+// SIG prefix.
+func lookup(name string) (os.Signal, bool) {
+	if s, ok := signums[name]; ok {
+		return s, true
+	}
+	n := strings.ToUpper(name)
+	if !strings.HasPrefix(n, "SIG") {
+		n = "SIG" + n
+	}
+	s, ok := signums[n]
+	return s, ok
+}
+
+// list implements kill -l. Numbers are converted to names and names to
+// numbers.
+func list(args []string) {
+	if len(args) == 0 {
+		fmt.Print(siglist())
+		return
+	}
+	for _, a := range args {
+		if n, err := strconv.Atoi(a); err == nil {
+			name, ok := signame(n)
+			if !ok {
+				die("%v is not a valid signal", a)
+			}
+			fmt.Println(name)
+			continue
+		}
+		s, ok := lookup(a)
+		if !ok {
+			die("%v is not a valid signal", a)
+		}
+		fmt.Printf("%d\n", s)
+	}
+}
+
 func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
 	op := os.Args[1]
 	pids := os.Args[2:]
-	if op[0] != '-' {
+	if len(op) < 2 || op[0] != '-' {
 		op = defaultSignal
 		pids = os.Args[1:]
 	}
@@ -51,12 +99,16 @@ func main() {
 	// Also, note, the -l has no meaning on Plan 9 or Harvey
 	// since signals on those systems are arbitrary strings.
 
-	if op[0:2] == "-l" {
-		if len(os.Args) > 2 {
+	switch op {
+	case "-l", "--list":
+		list(pids)
+		return
+	case "-L", "--table":
+		if len(pids) > 0 {
 			usage()
 		}
-		fmt.Print(siglist())
-		os.Exit(1)
+		fmt.Print(sigtable())
+		return
 	}
 
 	// N.B. Be careful if you want to change this. It has to continue to work if
@@ -73,7 +125,7 @@ func main() {
 		op = op[1:]
 	}
 
-	s, ok := signums[op]
+	s, ok := lookup(op)
 	if !ok {
 		die("%v is not a valid signal", op)
 	}
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -71,6 +72,8 @@ func TestBadInvocations(t *testing.T) {
 			{a: []string{"--signal"}, err: eUsage + "\n"},
 			{a: []string{"--signal", "a"}, err: "a is not a valid signal\n"},
 			{a: []string{"-1", "a"}, err: "Some processes could not be killed: [a: arguments must be process or job IDS]\n"},
+			{a: []string{}, err: eUsage + "\n"},
+			{a: []string{"-KILL"}, err: eUsage + "\n"},
 		}
 	)
 
@@ -91,6 +94,53 @@ func TestBadInvocations(t *testing.T) {
 	}
 }
 
+func TestSignalNames(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start test process: %v", err)
+	}
+	if _, _, err := run(testutil.Command(t, "-term", fmt.Sprintf("%d", cmd.Process.Pid))); err != nil {
+		t.Errorf("kill -term: got %v, want nil", err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Errorf("Test process succeeded, but expected to fail")
+	}
+}
+
+func TestList(t *testing.T) {
+	for _, tt := range []struct {
+		a   []string
+		out string
+	}{
+		{a: []string{"-l", "9", "TERM", "SIGHUP", "137"}, out: "KILL\n15\n1\nKILL\n"},
+		{a: []string{"-l", "64"}, out: "RTMAX\n"},
+	} {
+		o, e, err := run(testutil.Command(t, tt.a...))
+		if err != nil || o != tt.out {
+			t.Errorf("kill %v: got (%q, %q, %v), want (%q, \"\", nil)", tt.a, o, e, err, tt.out)
+		}
+	}
+
+	o, _, err := run(testutil.Command(t, "-l"))
+	if err != nil {
+		t.Fatalf("kill -l: got %v, want nil", err)
+	}
+	if !strings.HasPrefix(o, "HUP\nINT\nQUIT\n") {
+		t.Errorf("kill -l: got %q, want it to start with HUP, INT, QUIT", o)
+	}
+	o, _, err = run(testutil.Command(t, "-L"))
+	if err != nil {
+		t.Fatalf("kill -L: got %v, want nil", err)
+	}
+	if !strings.Contains(o, " 9 KILL\n") || !strings.Contains(o, "34 RTMIN\n") {
+		t.Errorf("kill -L: got %q, want it to contain 9 KILL and 34 RTMIN", o)
+	}
+
+	if _, _, err := run(testutil.Command(t, "-l", "NOSUCH")); err == nil {
+		t.Errorf("kill -l NOSUCH: got nil, want err")
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
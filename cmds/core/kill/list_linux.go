@@ -7,6 +7,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
 )
 
@@ -203,9 +204,33 @@ var (
 	}
 )
 
+// siglist returns the signal names without their SIG prefix, one per line.
 func siglist() (s string) {
-	for i, sig := range signames {
-		s = s + fmt.Sprintf("%d: %v\n", i, sig)
+	for _, sig := range signames {
+		s = s + fmt.Sprintf("%s\n", strings.TrimPrefix(sig, "SIG"))
 	}
 	return
 }
+
+// sigtable returns the signal numbers and names, one per line.
+func sigtable() (s string) {
+	for _, sig := range signames {
+		s = s + fmt.Sprintf("%2d %s\n", signums[sig], strings.TrimPrefix(sig, "SIG"))
+	}
+	return
+}
+
+// signame returns the name, without SIG prefix, of the signal numbered n.
+// Like the shell does for exit statuses, numbers above 128 stand for
+// n-128.
+func signame(n int) (string, bool) {
+	if n > 128 {
+		n -= 128
+	}
+	for _, sig := range signames {
+		if signums[sig] == syscall.Signal(n) {
+			return strings.TrimPrefix(sig, "SIG"), true
+		}
+	}
+	return "", false
+}
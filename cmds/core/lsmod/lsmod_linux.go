@@ -17,33 +17,41 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
 )
 
-func main() {
-	file, err := os.Open("/proc/modules")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
+// lsmod prints the modules listed in r, which is in /proc/modules format:
+// name, size, reference count, users, state and address.
+func lsmod(w io.Writer, r io.Reader) error {
+	fmt.Fprintln(w, "Module                  Size  Used by")
 
-	fmt.Println("Module                  Size  Used by")
-
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		s := strings.Split(scanner.Text(), " ")
+		s := strings.Fields(scanner.Text())
+		if len(s) < 4 {
+			return fmt.Errorf("invalid line %q", scanner.Text())
+		}
 		name, size, used, usedBy := s[0], s[1], s[2], s[3]
 		final := fmt.Sprintf("%-19s %8s  %s", name, size, used)
 		if usedBy != "-" {
-			usedBy = usedBy[:len(usedBy)-1]
-			final += fmt.Sprintf(" %s", usedBy)
+			final += " " + strings.TrimSuffix(usedBy, ",")
 		}
-		fmt.Println(final)
+		fmt.Fprintln(w, final)
 	}
+	return scanner.Err()
+}
+
+func main() {
+	file, err := os.Open("/proc/modules")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
 
-	if err := scanner.Err(); err != nil {
+	if err := lsmod(os.Stdout, file); err != nil {
 		log.Fatal(err)
 	}
 }
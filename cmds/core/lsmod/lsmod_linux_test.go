@@ -0,0 +1,35 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLsmod(t *testing.T) {
+	in := `snd_hda_intel 53248 3 - Live 0x0000000000000000
+snd_hda_codec 151552 2 snd_hda_intel,snd_hda_codec_generic, Live 0x0000000000000000
+virtio_net 57344 0 - Live 0x0000000000000000 (E)
+ipv6 557056 [permanent] - Live 0x0000000000000000
+`
+	want := `Module                  Size  Used by
+snd_hda_intel          53248  3
+snd_hda_codec         151552  2 snd_hda_intel,snd_hda_codec_generic
+virtio_net             57344  0
+ipv6                  557056  [permanent]
+`
+	var b bytes.Buffer
+	if err := lsmod(&b, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("lsmod: got\n%s\nwant\n%s", b.String(), want)
+	}
+	if err := lsmod(&b, strings.NewReader("bogus 1\n")); err == nil {
+		t.Errorf("lsmod(bogus): got nil, want error")
+	}
+}
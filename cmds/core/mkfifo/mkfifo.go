@@ -8,26 +8,29 @@
 //     mkfifo [OPTIONS] NAME...
 //
 // Options:
-//     -m: mode (default 0600)
+//     -m: octal mode, not affected by the umask (default 0660)
 //
 package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"golang.org/x/sys/unix"
 )
 
 const (
-	defaultMode = 0660 | unix.S_IFIFO
-	cmd         = "mkfifo [-m] NAME..."
+	defaultMode = 0660
+	cmd         = "mkfifo [-m MODE] NAME..."
 )
 
-var mode = flag.Int("mode", defaultMode, "Mode to create fifo")
+var mode = flag.String("m", "", "octal mode of the fifo, not affected by the umask")
 
 func init() {
+	flag.StringVar(mode, "mode", "", "same as -m")
 	defUsage := flag.Usage
 	flag.Usage = func() {
 		os.Args[0] = cmd
@@ -35,6 +38,26 @@ func init() {
 	}
 }
 
+// parseMode parses an octal file mode.
+func parseMode(s string) (uint32, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return uint32(m), nil
+}
+
+func mkfifo(path string, perm uint32, exact bool) error {
+	if err := unix.Mkfifo(path, perm); err != nil {
+		return err
+	}
+	// The umask applies to mkfifo(2), but not to an explicit mode.
+	if exact {
+		return unix.Chmod(path, perm)
+	}
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
@@ -42,9 +65,20 @@ func main() {
 		log.Fatal("please provide a path, or multiple, to create a fifo")
 	}
 
+	perm := uint32(defaultMode)
+	if *mode != "" {
+		var err error
+		if perm, err = parseMode(*mode); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	status := 0
 	for _, path := range flag.Args() {
-		if err := unix.Mkfifo(path, uint32(*mode)); err != nil {
-			log.Fatalf("Error while creating fifo, %v", err)
+		if err := mkfifo(path, perm, *mode != ""); err != nil {
+			log.Printf("Error while creating fifo, %v", err)
+			status = 1
 		}
 	}
+	os.Exit(status)
 }
@@ -78,6 +78,43 @@ func TestMkfifo(t *testing.T) {
 	}
 }
 
+func TestMkfifoMode(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "mkfifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, tt := range []struct {
+		flags []string
+		mode  os.FileMode
+		err   bool
+	}{
+		{flags: []string{"-m", "0604"}, mode: 0604},
+		{flags: []string{"-m", "777"}, mode: 0777},
+		{flags: []string{"-mode", "0640"}, mode: 0640},
+		{flags: []string{"-m", "8"}, err: true},
+		{flags: []string{"-m", "17777"}, err: true},
+	} {
+		path := filepath.Join(tmpDir, "fifo")
+		err := testutil.Command(t, append(tt.flags, path)...).Run()
+		if (err != nil) != tt.err {
+			t.Errorf("mkfifo %q: got %v, want error %v", tt.flags, err, tt.err)
+		}
+		if tt.err {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.Mode(); got != os.ModeNamedPipe|tt.mode {
+			t.Errorf("mkfifo %q: mode is %v, want %v", tt.flags, got, os.ModeNamedPipe|tt.mode)
+		}
+		os.Remove(path)
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
@@ -2,15 +2,22 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Unmount a filesystem at the specified path.
+// mknod creates a special file.
 //
 // Synopsis:
-//     mknod PATH TYPE [MAJOR MINOR]
+//     mknod [-m MODE] PATH TYPE [MAJOR MINOR]
 //
 // Description:
 //     Creates a special file at PATH of the given TYPE. If TYPE is b, c or u,
 //     the MAJOR and MINOR number must be specified. If the TYPE is p, they
 //     must not be specified.
+//
+//     MAJOR may be at most 4095 and MINOR at most 1048575. They may be
+//     given in octal with a leading 0 or hexadecimal with a leading 0x.
+//     Creating block and character devices requires root.
+//
+// Options:
+//     -m: octal permissions, not affected by the umask (default 0660)
 package main
 
 import "log"
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
 	"strconv"
 
 	"golang.org/x/sys/unix"
@@ -15,54 +16,83 @@ import (
 
 const defaultPerms = 0660
 
+var mode = flag.String("m", "", "permissions of the node, in octal, not affected by umask")
+
+// parseMode parses an octal file mode.
+func parseMode(s string) (uint32, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return uint32(m), nil
+}
+
+// parseNumber parses a major or minor number of at most bits bits. Like
+// for GNU mknod, it may be given in octal or hexadecimal as well.
+func parseNumber(what, s string, bits int) (uint32, error) {
+	n, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s number %q", what, s)
+	}
+	if n >= 1<<uint(bits) {
+		return 0, fmt.Errorf("%s number %d out of range (0-%d)", what, n, 1<<uint(bits)-1)
+	}
+	return uint32(n), nil
+}
+
 func parseDevices(args []string, devtype string) (int, error) {
 	if len(args) != 4 {
 		return 0, fmt.Errorf("device type %v requires a major and minor number", devtype)
 	}
-	major, err := strconv.ParseUint(args[2], 10, 12)
+	major, err := parseNumber("major", args[2], 12)
 	if err != nil {
 		return 0, err
 	}
-	minor, err := strconv.ParseUint(args[3], 10, 20)
+	minor, err := parseNumber("minor", args[3], 20)
 	if err != nil {
 		return 0, err
 	}
-	return int(unix.Mkdev(uint32(major), uint32(minor))), nil
+	return int(unix.Mkdev(major, minor)), nil
 }
 
 func mknod() error {
 	flag.Parse()
 	a := flag.Args()
 	if len(a) != 2 && len(a) != 4 {
-		return errors.New("usage: mknod path type [major minor]")
+		return errors.New("usage: mknod [-m mode] path type [major minor]")
 	}
 	path := a[0]
 	devtype := a[1]
 
 	var err error
-	var mode uint32
+	perm := uint32(defaultPerms)
+	if *mode != "" {
+		if perm, err = parseMode(*mode); err != nil {
+			return err
+		}
+	}
 
-	mode = defaultPerms
+	var typ uint32
 	var dev int
 
 	switch devtype {
 	case "b":
 		// This is a block device. A major/minor number is needed.
-		mode |= unix.S_IFBLK
+		typ = unix.S_IFBLK
 		dev, err = parseDevices(a, devtype)
 		if err != nil {
 			return err
 		}
 	case "c", "u":
 		// This is a character/unbuffered device. A major/minor number is needed.
-		mode |= unix.S_IFCHR
+		typ = unix.S_IFCHR
 		dev, err = parseDevices(a, devtype)
 		if err != nil {
 			return err
 		}
 	case "p":
 		// This is a pipe. A major and minor number must not be supplied
-		mode |= unix.S_IFIFO
+		typ = unix.S_IFIFO
 		if len(a) != 2 {
 			return fmt.Errorf("device type %v requires no other arguments", devtype)
 		}
@@ -70,8 +100,17 @@ func mknod() error {
 		return fmt.Errorf("device type not recognized: %v", devtype)
 	}
 
-	if err := unix.Mknod(path, mode, dev); err != nil {
-		return fmt.Errorf("%q: mode %x: %v", path, mode, err)
+	if typ != unix.S_IFIFO && os.Geteuid() != 0 {
+		return fmt.Errorf("%q: creating device nodes requires root", path)
+	}
+	if err := unix.Mknod(path, typ|perm, dev); err != nil {
+		return fmt.Errorf("%q: mode %x: %v", path, typ|perm, err)
+	}
+	// The umask applies to mknod(2), but not to an explicit mode.
+	if *mode != "" {
+		if err := unix.Chmod(path, perm); err != nil {
+			return fmt.Errorf("%q: %v", path, err)
+		}
 	}
 	return nil
 }
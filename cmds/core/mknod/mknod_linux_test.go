@@ -52,3 +52,40 @@ func TestLargeDevNumber(t *testing.T) {
 		t.Fatalf("expected the device number to be 0x12345678, got %#x", s.Rdev)
 	}
 }
+
+func TestCharDevice(t *testing.T) {
+	if uid := os.Getuid(); uid != 0 {
+		// Without root, creating devices is refused.
+		tmpDir, err := ioutil.TempDir("", "mknod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmpDir)
+		if err := testutil.Command(t, filepath.Join(tmpDir, "null"), "c", "1", "3").Run(); err == nil {
+			t.Errorf("mknod of a character device as uid %d succeeded, want error", uid)
+		}
+		return
+	}
+
+	tmpDir, err := ioutil.TempDir("", "mknod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "null")
+
+	// Run "mknod -m 0666 null c 1 3".
+	if err := testutil.Command(t, "-m", "0666", file, "c", "1", "3").Run(); err != nil {
+		t.Fatal(err)
+	}
+	var s unix.Stat_t
+	if err := unix.Stat(file, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Mode != unix.S_IFCHR|0666 {
+		t.Errorf("expected mode %#o, got %#o", unix.S_IFCHR|0666, s.Mode)
+	}
+	if s.Rdev != unix.Mkdev(1, 3) {
+		t.Errorf("expected the device number to be %#x, got %#x", unix.Mkdev(1, 3), s.Rdev)
+	}
+}
@@ -50,6 +50,27 @@ func TestMknodFifo(t *testing.T) {
 	}
 }
 
+func TestMknodMode(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "mknod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// The mode is set exactly, regardless of the umask.
+	pipepath := filepath.Join(tmpDir, "testpipe")
+	if err := testutil.Command(t, "-m", "0606", pipepath, "p").Run(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(pipepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fi.Mode(); got != os.ModeNamedPipe|0606 {
+		t.Errorf("mode of %s is %v, want %v", pipepath, got, os.ModeNamedPipe|0606)
+	}
+}
+
 func TestInvocationErrors(t *testing.T) {
 	tmpDir, err := ioutil.TempDir("", "ls")
 	if err != nil {
@@ -59,12 +80,17 @@ func TestInvocationErrors(t *testing.T) {
 
 	devpath := filepath.Join(tmpDir, "testdev")
 	var tests = []test{
-		{args: []string{devpath}, expects: "mknod: usage: mknod path type [major minor]\n"},
-		{args: []string{""}, expects: "mknod: usage: mknod path type [major minor]\n"},
+		{args: []string{devpath}, expects: "mknod: usage: mknod [-m mode] path type [major minor]\n"},
+		{args: []string{""}, expects: "mknod: usage: mknod [-m mode] path type [major minor]\n"},
 		{args: []string{devpath, "p", "254", "3"}, expects: "mknod: device type p requires no other arguments\n"},
-		{args: []string{devpath, "b", "254"}, expects: "mknod: usage: mknod path type [major minor]\n"},
+		{args: []string{devpath, "b", "254"}, expects: "mknod: usage: mknod [-m mode] path type [major minor]\n"},
 		{args: []string{devpath, "b"}, expects: "mknod: device type b requires a major and minor number\n"},
 		{args: []string{devpath, "k"}, expects: "mknod: device type not recognized: k\n"},
+		{args: []string{devpath, "b", "4096", "0"}, expects: "mknod: major number 4096 out of range (0-4095)\n"},
+		{args: []string{devpath, "c", "1", "0x100000"}, expects: "mknod: minor number 1048576 out of range (0-1048575)\n"},
+		{args: []string{devpath, "c", "1", "-1"}, expects: "mknod: invalid minor number \"-1\"\n"},
+		{args: []string{"-m", "0999", devpath, "p"}, expects: "mknod: invalid mode \"0999\"\n"},
+		{args: []string{"-m", "10000", devpath, "p"}, expects: "mknod: invalid mode \"10000\"\n"},
 	}
 
 	for _, v := range tests {
@@ -22,26 +22,37 @@
 //       --suffix=SUFF
 //              append SUFF to TEMPLATE; SUFF must not contain a slash.  This option is implied if TEMPLATE does not end in X
 //
-//       -p DIR, --tmpdir[=DIR]
+//       -p DIR, --tmpdir=DIR
 //              interpret TEMPLATE relative to DIR; if DIR is not specified, use $TMPDIR if set, else /tmp.  With this option, TEMPLATE must not be an absolute name; unlike with -t, TEMPLATE may contain  slashes,  but  mktemp  creates
 //              only the final component
+//
+//       -t     interpret TEMPLATE as a single file name component, relative to a directory: $TMPDIR, if set; else the directory specified via -p; else /tmp
 package main
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/rand"
+)
+
+const (
+	defaultTemplate = "tmp.XXXXXXXXXX"
+	// Characters used to replace the X's in a template.
+	letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// Number of names tried before giving up.
+	attempts = 10000
 )
 
 type mktempflags struct {
 	d      bool
 	u      bool
 	q      bool
-	v      bool
+	t      bool
 	prefix string
 	suffix string
 	dir    string
@@ -54,9 +65,10 @@ var (
 func init() {
 	flag.BoolVarP(&flags.d, "directory", "d", false, "Make a directory")
 	flag.BoolVarP(&flags.u, "dry-run", "u", false, "Do everything save the actual create")
-	flag.BoolVarP(&flags.v, "quiet", "q", false, "Quiet: show no errors")
+	flag.BoolVarP(&flags.q, "quiet", "q", false, "Quiet: show no errors")
+	flag.BoolVarP(&flags.t, "t", "t", false, "Interpret TEMPLATE as a single file name component relative to TMPDIR, the -p directory or /tmp")
 	flag.StringVarP(&flags.prefix, "prefix", "s", "", "add a prefix -- the s flag is for compatibility with GNU mktemp")
-	flag.StringVarP(&flags.suffix, "suffix", "", "", "add a suffix to the prefix (rather than the end of the mktemp file)")
+	flag.StringVarP(&flags.suffix, "suffix", "", "", "append SUFF to TEMPLATE; SUFF must not contain a slash")
 	flag.StringVarP(&flags.dir, "tmpdir", "p", "", "Tmp directory to use. If this is not set, TMPDIR is used, else /tmp")
 }
 
@@ -64,45 +76,149 @@ func usage() {
 	log.Fatalf("Usage: mktemp [options] [template]\n%v", flag.CommandLine.FlagUsages())
 }
 
-func mktemp() (string, error) {
-	if flags.dir == "" {
-		flags.dir = os.Getenv("TMPDIR")
+// tmpdir returns $TMPDIR if set, else /tmp.
+func tmpdir() string {
+	if d := os.Getenv("TMPDIR"); d != "" {
+		return d
 	}
+	return "/tmp"
+}
 
-	if flags.u {
-		if !flags.q {
-			log.Printf("Not doing anything but dry-run is an inherently unsafe concept")
-		}
-		return "", nil
+// split splits template into the part before the last run of X's, the
+// number of X's in that run, and the part after it.
+func split(template string) (string, int, string, error) {
+	end := strings.LastIndexByte(template, 'X') + 1
+	start := end
+	for start > 0 && template[start-1] == 'X' {
+		start--
 	}
-
-	if flags.d {
-		d, err := ioutil.TempDir(flags.dir, flags.prefix)
-		return d, err
+	n := end - start
+	if n < 3 {
+		return "", 0, "", fmt.Errorf("too few X's in template %q", template)
 	}
-	f, err := ioutil.TempFile(flags.dir, flags.prefix)
-	return f.Name(), err
+	if strings.Contains(template[end:], "/") {
+		return "", 0, "", fmt.Errorf("invalid suffix %q, contains directory separator", template[end:])
+	}
+	return template[:start], n, template[end:], nil
 }
 
-func main() {
-	flag.Parse()
-
-	args := flag.Args()
-
+// path returns the template to use, with the directory it should be
+// interpreted relative to prepended, based on the flags and args.
+func path(args []string) (string, error) {
+	template := defaultTemplate
+	useDir := flags.dir != "" || flags.t
 	switch len(args) {
-	case 1:
-		// To make this work, we strip the trailing X's, since the Go runtime doesn't work
-		// as old school mktemp(3) does. Just split on the first X.
-		// If they also specified a suffix, well, add that to the prefix I guess.
-		flags.prefix = flags.prefix + strings.Split(args[0], "X")[0] + flags.suffix
 	case 0:
+		useDir = true
+	case 1:
+		template = args[0]
 	default:
 		usage()
 	}
+	template = flags.prefix + template
+
+	if flags.suffix != "" {
+		if !strings.HasSuffix(template, "X") {
+			return "", fmt.Errorf("with --suffix, template %q must end in X", template)
+		}
+		if strings.Contains(flags.suffix, "/") {
+			return "", fmt.Errorf("invalid suffix %q, contains directory separator", flags.suffix)
+		}
+		template += flags.suffix
+	}
+
+	if !useDir {
+		return template, nil
+	}
+
+	dir := flags.dir
+	switch {
+	case flags.t:
+		if strings.Contains(template, "/") {
+			return "", fmt.Errorf("invalid template %q, contains directory separator", template)
+		}
+		if d := os.Getenv("TMPDIR"); d != "" {
+			dir = d
+		}
+	case filepath.IsAbs(template):
+		return "", fmt.Errorf("invalid template %q, template must not be absolute", template)
+	}
+	if dir == "" {
+		dir = tmpdir()
+	}
+	return filepath.Join(dir, template), nil
+}
 
-	fileName, err := mktemp()
-	if err != nil && !flags.q {
+// randomName replaces the n X's between prefix and suffix with random
+// letters and digits.
+func randomName(prefix string, n int, suffix string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = letters[int(b[i])%len(letters)]
+	}
+	return prefix + string(b) + suffix, nil
+}
+
+// create creates the file or directory, or with dry run, only checks that
+// the name is not yet taken.
+func create(name string) error {
+	switch {
+	case flags.u:
+		if _, err := os.Lstat(name); err == nil {
+			return os.ErrExist
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case flags.d:
+		return os.Mkdir(name, 0700)
+	default:
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+}
+
+func mktemp(template string) (string, error) {
+	prefix, n, suffix, err := split(template)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < attempts; i++ {
+		name, err := randomName(prefix, n, suffix)
+		if err != nil {
+			return "", err
+		}
+		err = create(name)
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("failed to create file via template %q: %v", template, os.ErrExist)
+}
+
+func main() {
+	flag.Parse()
+
+	template, err := path(flag.Args())
+	if err != nil {
 		log.Fatalf("%v", err)
 	}
+
+	fileName, err := mktemp(template)
+	if err != nil {
+		if !flags.q {
+			log.Printf("%v", err)
+		}
+		os.Exit(1)
+	}
 	fmt.Println(fileName)
 }
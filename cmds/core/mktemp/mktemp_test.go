@@ -7,6 +7,8 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
@@ -36,23 +38,29 @@ func TestMkTemp(t *testing.T) {
 			exitStatus: 0,
 		},
 		{
-			flags:      []string{"foofoo.XXXX"},
-			out:        "/tmp/foofoo",
+			flags:      []string{"-t", "foofoo.XXXX"},
+			out:        "/tmp/foofoo.",
 			stdErr:     "",
 			exitStatus: 0,
 		},
 		{
-			flags:      []string{"foo.XXXXX", "--suffix", "baz"},
-			out:        "/tmp/foo.baz",
+			flags:      []string{"-p", "/tmp", "foo.XXXXX", "--suffix", "baz"},
+			out:        "/tmp/foo.",
 			stdErr:     "",
 			exitStatus: 0,
 		},
 		{
 			flags:      []string{"-u", "-q"},
-			out:        "",
+			out:        "/tmp/tmp.",
 			stdErr:     "",
 			exitStatus: 0,
 		},
+		{
+			flags:      []string{"-q", "-t", "foo.XX"},
+			out:        "",
+			stdErr:     "",
+			exitStatus: 1,
+		},
 	}
 
 	// Table-driven testing
@@ -75,10 +83,67 @@ func TestMkTemp(t *testing.T) {
 			if tt.exitStatus == 0 && err != nil {
 				t.Errorf("expected to exit with %d, but exited with err %s", tt.exitStatus, err)
 			}
+			if err := testutil.IsExitCode(err, tt.exitStatus); tt.exitStatus != 0 && err != nil {
+				t.Error(err)
+			}
 		})
 	}
 }
 
+func TestSplit(t *testing.T) {
+	for _, tt := range []struct {
+		template string
+		prefix   string
+		n        int
+		suffix   string
+		err      bool
+	}{
+		{template: "tmp.XXXXXXXXXX", prefix: "tmp.", n: 10},
+		{template: "XXX", n: 3},
+		{template: "a/XXXb.XXXX.c", prefix: "a/XXXb.", n: 4, suffix: ".c"},
+		{template: "fooXX", err: true},
+		{template: "foo", err: true},
+		{template: "XXX/foo", err: true},
+	} {
+		prefix, n, suffix, err := split(tt.template)
+		if (err != nil) != tt.err {
+			t.Errorf("split(%q): got err %v, want err %v", tt.template, err, tt.err)
+			continue
+		}
+		if prefix != tt.prefix || n != tt.n || suffix != tt.suffix {
+			t.Errorf("split(%q) = (%q, %d, %q), want (%q, %d, %q)", tt.template, prefix, n, suffix, tt.prefix, tt.n, tt.suffix)
+		}
+	}
+}
+
+func TestMktempPerm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mktemp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		flags []string
+		perm  os.FileMode
+	}{
+		{flags: []string{"-p", dir}, perm: 0600},
+		{flags: []string{"-d", "-p", dir}, perm: 0700 | os.ModeDir},
+	} {
+		out, err := testutil.Command(t, tt.flags...).Output()
+		if err != nil {
+			t.Fatalf("mktemp %v: %v", tt.flags, err)
+		}
+		fi, err := os.Stat(strings.TrimSpace(string(out)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode() != tt.perm {
+			t.Errorf("mktemp %v: got mode %v, want %v", tt.flags, fi.Mode(), tt.perm)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
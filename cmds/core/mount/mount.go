@@ -8,12 +8,24 @@
 //
 // Synopsis:
 //     mount [-r] [-o options] [-t FSTYPE] DEV PATH
+//     mount -a [-r] [-o options] [-T FSTAB] [-e]
+//
+// Description:
+//     -a mounts the filesystems of fstab in order, except those with the
+//     noauto option, swap, and those already mounted. The device may be
+//     given as UUID=, LABEL=, PARTUUID= or PARTLABEL=. Failures are
+//     reported and the other entries are still mounted, unless -e is
+//     given. Failures of entries with the nofail option are ignored.
 //
 // Options:
 //     -r: read only
+//     -a: mount all filesystems of fstab
+//     -T: fstab file, /etc/fstab by default
+//     -e: with -a, stop at the first entry that fails
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -22,6 +34,7 @@ import (
 	"strings"
 
 	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/block"
 	"github.com/u-root/u-root/pkg/mount/loop"
 	"golang.org/x/sys/unix"
 )
@@ -40,11 +53,23 @@ func (o *mountOptions) Set(value string) error {
 }
 
 var (
-	ro      = flag.Bool("r", false, "Read only mount")
-	fsType  = flag.String("t", "", "File system type")
-	options mountOptions
+	ro       = flag.Bool("r", false, "Read only mount")
+	fsType   = flag.String("t", "", "File system type")
+	all      = flag.Bool("a", false, "Mount all filesystems of fstab, except noauto ones")
+	fstab    = flag.String("T", "/etc/fstab", "fstab file of -a")
+	failFast = flag.Bool("e", false, "With -a, stop at the first entry that fails")
+	options  mountOptions
+)
+
+// Overridden in tests.
+var (
+	mountsFile  = "/proc/self/mounts"
+	mountFunc   = mountOne
+	resolveSpec = resolveSource
 )
 
+var errMountAll = errors.New("some filesystems could not be mounted")
+
 func init() {
 	flag.Var(&options, "o", "Comma separated list of mount options")
 }
@@ -95,35 +120,25 @@ func informIfUnknownFS(originFS string) {
 	}
 }
 
-func main() {
-	if len(os.Args) == 1 {
-		n := []string{"/proc/self/mounts", "/proc/mounts", "/etc/mtab"}
-		for _, p := range n {
-			if b, err := ioutil.ReadFile(p); err == nil {
-				fmt.Print(string(b))
-				os.Exit(0)
-			}
-		}
-		log.Fatalf("Could not read %s to get namespace", n)
-	}
-	flag.Parse()
-	if len(flag.Args()) < 2 {
-		flag.Usage()
-		os.Exit(1)
-	}
-	a := flag.Args()
-	dev := a[0]
-	path := a[1]
+// parseOptions returns the mount flags, the filesystem specific data, and
+// whether to set up a loop device of the options.
+func parseOptions(options []string) (uintptr, []string, bool) {
 	var flags uintptr
 	var data []string
-	var err error
+	var useLoop bool
 	for _, option := range options {
-		switch option {
-		case "loop":
-			dev, err = loopSetup(dev)
-			if err != nil {
-				log.Fatal("Error setting loop device:", err)
-			}
+		switch {
+		case option == "loop":
+			useLoop = true
+		case option == "ro":
+			flags |= unix.MS_RDONLY
+		case option == "defaults", option == "rw":
+		// Options of fstab entries, which are not for the kernel.
+		case option == "auto", option == "noauto", option == "nofail",
+			option == "user", option == "nouser", option == "users",
+			option == "owner", option == "group", option == "_netdev",
+			strings.HasPrefix(option, "x-"), strings.HasPrefix(option, "X-"),
+			strings.HasPrefix(option, "comment="):
 		default:
 			if f, ok := opts[option]; ok {
 				flags |= f
@@ -132,18 +147,153 @@ func main() {
 			}
 		}
 	}
+	return flags, data, useLoop
+}
+
+// mountOne mounts dev on path, guessing the filesystem type if fsType is
+// empty or auto.
+func mountOne(dev, path, fsType string, options []string) error {
+	flags, data, useLoop := parseOptions(options)
+	if useLoop {
+		var err error
+		if dev, err = loopSetup(dev); err != nil {
+			return fmt.Errorf("Error setting loop device: %v", err)
+		}
+	}
 	if *ro {
 		flags |= unix.MS_RDONLY
 	}
-	if *fsType == "" {
-		if _, err := mount.TryMount(dev, path, strings.Join(data, ","), flags); err != nil {
-			log.Fatalf("%v", err)
+	if fsType == "" || fsType == "auto" {
+		_, err := mount.TryMount(dev, path, strings.Join(data, ","), flags)
+		return err
+	}
+	if _, err := mount.Mount(dev, path, fsType, strings.Join(data, ","), flags); err != nil {
+		informIfUnknownFS(fsType)
+		return err
+	}
+	return nil
+}
+
+// resolveSource returns the device of an fstab spec, which may be
+// UUID=FSUUID, LABEL=FSLABEL, PARTUUID=GUID or PARTLABEL=LABEL.
+func resolveSource(spec string) (string, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) == 1 {
+		return spec, nil
+	}
+	devices, err := block.GetBlockDevices()
+	if err != nil {
+		return "", err
+	}
+	var matches block.BlockDevices
+	switch key, value := strings.ToUpper(kv[0]), kv[1]; key {
+	case "UUID":
+		matches = devices.FilterFSUUID(strings.ToLower(value))
+	case "LABEL":
+		matches = devices.FilterFSLabel(value)
+	case "PARTUUID":
+		matches = devices.FilterPartID(value)
+	case "PARTLABEL":
+		if matches, err = devices.FilterPartLabel(value); err != nil {
+			return "", err
 		}
-	} else {
-		if _, err := mount.Mount(dev, path, *fsType, strings.Join(data, ","), flags); err != nil {
-			log.Printf("%v", err)
-			informIfUnknownFS(*fsType)
+	default:
+		// Not a tag, e.g. a network filesystem.
+		return spec, nil
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no block device matches %q", spec)
+	case 1:
+		return matches[0].DevicePath(), nil
+	}
+	return "", fmt.Errorf("%d block devices match %q: %v", len(matches), spec, matches)
+}
+
+// mountAll mounts the entries, except noauto and swap ones and those whose
+// mount point is in mounted. Failures are logged, and the first one stops
+// it if failFast is set.
+func mountAll(entries []mount.Entry, mounted map[string]bool, options []string, failFast bool) error {
+	var failed bool
+	for _, e := range entries {
+		if e.HasOption("noauto") || e.FSType == "swap" || e.Target == "none" || mounted[e.Target] {
+			continue
+		}
+		dev, err := resolveSpec(e.Source)
+		if err == nil {
+			err = mountFunc(dev, e.Target, e.FSType, append(append([]string{}, e.Options...), options...))
+		}
+		if err == nil {
+			mounted[e.Target] = true
+			continue
+		}
+		log.Printf("Mounting %s on %s failed: %v", e.Source, e.Target, err)
+		if e.HasOption("nofail") {
+			continue
+		}
+		if failFast {
+			return errMountAll
+		}
+		failed = true
+	}
+	if failed {
+		return errMountAll
+	}
+	return nil
+}
+
+func runAll() error {
+	f, err := os.Open(*fstab)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := mount.ParseTable(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *fstab, err)
+	}
+	mounted := make(map[string]bool)
+	if m, err := os.Open(mountsFile); err == nil {
+		mounts, err := mount.ParseTable(m)
+		m.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", mountsFile, err)
+		}
+		for _, e := range mounts {
+			mounted[e.Target] = true
+		}
+	}
+	return mountAll(entries, mounted, options, *failFast)
+}
+
+func main() {
+	if len(os.Args) == 1 {
+		n := []string{"/proc/self/mounts", "/proc/mounts", "/etc/mtab"}
+		for _, p := range n {
+			if b, err := ioutil.ReadFile(p); err == nil {
+				fmt.Print(string(b))
+				os.Exit(0)
+			}
+		}
+		log.Fatalf("Could not read %s to get namespace", n)
+	}
+	flag.Parse()
+	if *all {
+		if flag.NArg() != 0 {
+			flag.Usage()
 			os.Exit(1)
 		}
+		if err := runAll(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(flag.Args()) < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	a := flag.Args()
+	if err := mountOne(a[0], a[1], *fsType, options); err != nil {
+		log.Fatal(err)
 	}
 }
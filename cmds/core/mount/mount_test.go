@@ -0,0 +1,93 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !plan9
+// +build !plan9
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+func TestParseOptions(t *testing.T) {
+	flags, data, useLoop := parseOptions([]string{"defaults", "ro", "noatime", "nofail", "x-systemd.automount", "loop", "uid=1000"})
+	if want := uintptr(opts["rdonly"] | opts["noatime"]); flags != want {
+		t.Errorf("flags = %#x, want %#x", flags, want)
+	}
+	if !reflect.DeepEqual(data, []string{"uid=1000"}) {
+		t.Errorf("data = %q, want [uid=1000]", data)
+	}
+	if !useLoop {
+		t.Error("useLoop = false, want true")
+	}
+}
+
+type mountCall struct {
+	dev, path, fsType string
+	options           []string
+}
+
+func TestMountAll(t *testing.T) {
+	defer func(m func(string, string, string, []string) error, r func(string) (string, error)) {
+		mountFunc, resolveSpec = m, r
+	}(mountFunc, resolveSpec)
+
+	var calls []mountCall
+	mountFunc = func(dev, path, fsType string, options []string) error {
+		calls = append(calls, mountCall{dev, path, fsType, options})
+		if dev == "/dev/bad" {
+			return errors.New("bad superblock")
+		}
+		return nil
+	}
+	resolveSpec = func(spec string) (string, error) {
+		switch spec {
+		case "LABEL=root":
+			return "/dev/sda1", nil
+		case "LABEL=gone":
+			return "", errors.New("no block device matches")
+		}
+		return spec, nil
+	}
+	entries := []mount.Entry{
+		{Source: "LABEL=root", Target: "/", FSType: "ext4", Options: []string{"ro"}},
+		{Source: "/dev/sdb1", Target: "/boot", FSType: "vfat", Options: []string{"noauto"}},
+		{Source: "/dev/sdb2", Target: "none", FSType: "swap", Options: []string{"sw"}},
+		{Source: "proc", Target: "/proc", FSType: "proc"},
+		{Source: "/dev/bad", Target: "/data", FSType: "xfs"},
+		{Source: "LABEL=gone", Target: "/opt", FSType: "auto", Options: []string{"nofail"}},
+		{Source: "tmpfs", Target: "/tmp", FSType: "tmpfs"},
+	}
+
+	err := mountAll(entries, map[string]bool{"/proc": true}, []string{"noatime"}, false)
+	if err != errMountAll {
+		t.Errorf("mountAll() = %v, want %v", err, errMountAll)
+	}
+	want := []mountCall{
+		{"/dev/sda1", "/", "ext4", []string{"ro", "noatime"}},
+		{"/dev/bad", "/data", "xfs", []string{"noatime"}},
+		{"tmpfs", "/tmp", "tmpfs", []string{"noatime"}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("mountAll() mounted %v, want %v", calls, want)
+	}
+
+	calls = nil
+	if err := mountAll(entries, map[string]bool{}, nil, true); err != errMountAll {
+		t.Errorf("mountAll() with failFast = %v, want %v", err, errMountAll)
+	}
+	if len(calls) != 3 || calls[2].path != "/data" {
+		t.Errorf("mountAll() with failFast mounted %v, want to stop at /data", calls)
+	}
+
+	calls = nil
+	if err := mountAll(entries[5:], map[string]bool{}, nil, false); err != nil {
+		t.Errorf("mountAll() of nofail entries = %v, want nil", err)
+	}
+}
@@ -3,24 +3,56 @@
 // license that can be found in the LICENSE file.
 
 // netcat creates arbitrary TCP and UDP connections and listens and sends arbitrary data.
+//
+// Synopsis:
+//     netcat [-u] [-l [-k]] [-e COMMAND] [-w SECONDS] [-net NETWORK] [-v] ADDRESS
+//
+// Description:
+//     Connect to, or with -l listen on, the go-style network ADDRESS, e.g.
+//     localhost:8080 or :8080, and copy standard input to the connection and
+//     the connection to standard output. When standard input ends, the write
+//     side of TCP and unix connections is shut down, and netcat exits once
+//     the peer closes the connection.
+//
+//     A UDP listener serves the first peer that sends it a datagram.
+//
+// Options:
+//     -u:   use UDP, same as -net udp
+//     -l:   listen for a connection
+//     -k:   with -l, keep listening for connections after the first one
+//     -e:   run COMMAND with its standard input and output connected to the
+//           connection, instead of copying standard input and output
+//     -w:   time out connecting and idle connections after SECONDS
+//     -net: network type, e.g. tcp, tcp6, udp, unix
+//     -v:   verbose output
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/u-root/u-root/pkg/shlex"
 	"github.com/u-root/u-root/pkg/uroot/util"
 )
 
-const usage = "netcat [go-style network address]"
+const usage = "netcat [-u] [-l [-k]] [-e command] [-w seconds] [-net network] [-v] [go-style network address]"
 
 var (
 	netType = flag.String("net", "tcp", "What net type to use, e.g. tcp, unix, etc.")
+	udp     = flag.Bool("u", false, "Use UDP, same as -net udp.")
 	listen  = flag.Bool("l", false, "Listen for connections.")
+	keep    = flag.Bool("k", false, "With -l, keep listening for connections after the first one.")
+	command = flag.String("e", "", "Run this command with its standard input and output connected to the connection.")
+	timeout = flag.Int("w", 0, "Time out connecting and idle connections after this many seconds.")
 	verbose = flag.Bool("v", false, "Verbose output.")
 )
 
@@ -28,47 +60,243 @@ func init() {
 	util.Usage(usage)
 }
 
-func main() {
-	var c net.Conn
-	var err error
-	if flag.Parse(); len(flag.Args()) != 1 {
-		flag.Usage()
-		os.Exit(1)
+type params struct {
+	network string
+	listen  bool
+	keep    bool
+	command []string
+	timeout time.Duration
+	verbose bool
+}
+
+func isUDP(network string) bool {
+	return strings.HasPrefix(network, "udp")
+}
+
+// closeWriter is implemented by connections that can be half-closed, e.g.
+// *net.TCPConn and *net.UnixConn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// idleConn times out reads and writes that do not complete within timeout.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	if err := c.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *idleConn) CloseWrite() error {
+	if cw, ok := c.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
 	}
+	return nil
+}
+
+// udpConn is the connection of a UDP listener to the first peer that sent it
+// a datagram. Datagrams from other peers are dropped.
+type udpConn struct {
+	*net.UDPConn
+	peer    net.Addr
+	pending []byte
+}
+
+// acceptUDP waits for the first datagram on pc.
+func acceptUDP(pc *net.UDPConn) (*udpConn, error) {
+	b := make([]byte, 64*1024)
+	n, peer, err := pc.ReadFrom(b)
+	if err != nil {
+		return nil, err
+	}
+	return &udpConn{UDPConn: pc, peer: peer, pending: b[:n]}, nil
+}
 
-	addr := flag.Args()[0]
+func (c *udpConn) Read(b []byte) (int, error) {
+	if c.pending != nil {
+		n := copy(b, c.pending)
+		c.pending = nil
+		return n, nil
+	}
+	for {
+		n, addr, err := c.ReadFrom(b)
+		if err != nil || addr.String() == c.peer.String() {
+			return n, err
+		}
+	}
+}
 
-	if *listen {
-		ln, err := net.Listen(*netType, addr)
+func (c *udpConn) Write(b []byte) (int, error) {
+	return c.WriteTo(b, c.peer)
+}
+
+func (c *udpConn) RemoteAddr() net.Addr {
+	return c.peer
+}
+
+// copyConn copies stdin to c and c to stdout. When stdin ends, the write side
+// of c is shut down if possible. It returns when c ends.
+func copyConn(c net.Conn, stdin io.Reader, stdout io.Writer) error {
+	go func() {
+		if _, err := io.Copy(c, stdin); err != nil {
+			log.Print(err)
+		}
+		if cw, ok := c.(closeWriter); ok {
+			cw.CloseWrite()
+		}
+	}()
+	_, err := io.Copy(stdout, c)
+	return err
+}
+
+// execConn runs argv with its standard input and output connected to c.
+func execConn(c net.Conn, argv []string, stderr io.Writer) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout, cmd.Stderr = c, stderr
+	// With a pipe, Wait does not wait for the peer to send more data
+	// after the command exited.
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		io.Copy(in, c)
+		in.Close()
+	}()
+	return cmd.Wait()
+}
+
+func (p params) serve(c net.Conn, stdin io.Reader, stdout, stderr io.Writer) error {
+	defer c.Close()
+	if p.verbose {
+		fmt.Fprintln(stderr, "Connected to", c.RemoteAddr())
+	}
+	if p.timeout > 0 {
+		c = &idleConn{Conn: c, timeout: p.timeout}
+	}
+	var err error
+	if len(p.command) > 0 {
+		err = execConn(c, p.command, stderr)
+	} else {
+		err = copyConn(c, stdin, stdout)
+	}
+	if p.verbose {
+		fmt.Fprintln(stderr, "Disconnected")
+	}
+	return err
+}
+
+func (p params) listenAndServe(addr string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if isUDP(p.network) {
+		if p.keep {
+			return errors.New("-k is not supported with UDP")
+		}
+		pc, err := net.ListenPacket(p.network, addr)
 		if err != nil {
-			log.Fatalln(err)
+			return err
+		}
+		if p.verbose {
+			fmt.Fprintln(stderr, "Listening on", pc.LocalAddr())
 		}
-		if *verbose {
-			fmt.Fprintln(os.Stderr, "Listening on", ln.Addr())
+		c, err := acceptUDP(pc.(*net.UDPConn))
+		if err != nil {
+			pc.Close()
+			return err
 		}
+		return p.serve(c, stdin, stdout, stderr)
+	}
 
-		c, err = ln.Accept()
+	ln, err := net.Listen(p.network, addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	if p.verbose {
+		fmt.Fprintln(stderr, "Listening on", ln.Addr())
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		c, err := ln.Accept()
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
-	} else {
-		if c, err = net.Dial(*netType, addr); err != nil {
-			log.Fatalln(err)
+		switch {
+		case !p.keep:
+			return p.serve(c, stdin, stdout, stderr)
+		case len(p.command) > 0:
+			// Each connection gets its own command.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := p.serve(c, stdin, stdout, stderr); err != nil {
+					log.Print(err)
+				}
+			}()
+		default:
+			// Connections share standard input and output, one at a
+			// time.
+			if err := p.serve(c, stdin, stdout, stderr); err != nil {
+				log.Print(err)
+			}
 		}
 	}
-	if *verbose {
-		fmt.Fprintln(os.Stderr, "Connected to", c.RemoteAddr())
+}
+
+func (p params) run(addr string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if p.keep && !p.listen {
+		return errors.New("-k requires -l")
 	}
+	if p.listen {
+		return p.listenAndServe(addr, stdin, stdout, stderr)
+	}
+	var d net.Dialer
+	d.Timeout = p.timeout
+	c, err := d.Dial(p.network, addr)
+	if err != nil {
+		return err
+	}
+	return p.serve(c, stdin, stdout, stderr)
+}
 
-	go func() {
-		if _, err := io.Copy(c, os.Stdin); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+func main() {
+	if flag.Parse(); len(flag.Args()) != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	p := params{
+		network: *netType,
+		listen:  *listen,
+		keep:    *keep,
+		command: shlex.Argv(*command),
+		timeout: time.Duration(*timeout) * time.Second,
+		verbose: *verbose,
+	}
+	if *udp {
+		if *netType != "tcp" && !isUDP(*netType) {
+			log.Fatalf("-u conflicts with -net %s", *netType)
+		}
+		if !isUDP(*netType) {
+			p.network = "udp"
 		}
-	}()
-	if _, err = io.Copy(os.Stdout, c); err != nil {
-		fmt.Fprintln(os.Stderr, err)
 	}
-	if *verbose {
-		fmt.Fprintln(os.Stderr, "Disconnected")
+	if err := p.run(flag.Args()[0], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		log.Fatalln(err)
 	}
 }
@@ -0,0 +1,144 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyConnHalfClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The server answers only after the client shut down its write side.
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		b, _ := ioutil.ReadAll(c)
+		c.Write(bytes.ToUpper(b))
+	}()
+
+	var out bytes.Buffer
+	p := params{network: "tcp", timeout: 5 * time.Second}
+	if err := p.run(ln.Addr().String(), strings.NewReader("hello"), &out, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "HELLO" {
+		t.Errorf("output = %q, want %q", got, "HELLO")
+	}
+}
+
+func TestUDPListen(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	peer, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	if _, err := peer.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := acceptUDP(pc.(*net.UDPConn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoteAddr().String() != peer.LocalAddr().String() {
+		t.Errorf("RemoteAddr() = %v, want %v", c.RemoteAddr(), peer.LocalAddr())
+	}
+	b := make([]byte, 16)
+	n, err := c.Read(b)
+	if err != nil || string(b[:n]) != "ping" {
+		t.Errorf("Read() = %q, %v, want %q", b[:n], err, "ping")
+	}
+	if _, err := c.Write([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err = peer.Read(b)
+	if err != nil || string(b[:n]) != "pong" {
+		t.Errorf("peer Read() = %q, %v, want %q", b[:n], err, "pong")
+	}
+}
+
+func TestExecConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		p := params{network: "tcp", listen: true, command: []string{"echo", "hello"}}
+		c, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			errs <- err
+			return
+		}
+		errs <- p.serve(c, nil, nil, ioutil.Discard)
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// The command exits without waiting for input.
+	b, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello\n" {
+		t.Errorf("output = %q, want %q", b, "hello\n")
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("serve() = %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// The server never sends anything nor closes.
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		time.Sleep(5 * time.Second)
+		c.Close()
+	}()
+
+	p := params{network: "tcp", timeout: 50 * time.Millisecond}
+	err = p.run(ln.Addr().String(), strings.NewReader(""), ioutil.Discard, ioutil.Discard)
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("run() = %v, want a timeout", err)
+	}
+}
+
+func TestKeepRequiresListen(t *testing.T) {
+	p := params{network: "tcp", keep: true}
+	if err := p.run("127.0.0.1:0", nil, nil, nil); err == nil {
+		t.Error("run() with -k and without -l = nil, want error")
+	}
+}
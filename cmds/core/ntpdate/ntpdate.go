@@ -3,12 +3,27 @@
 // license that can be found in the LICENSE file.
 
 // ntpdate uses NTP to adjust the system clock.
+//
+// Synopsis:
+//     ntpdate [-q] [-config FILE] [-timeout DURATION] [-verbose] [SERVER...]
+//
+// Description:
+//     Query each SERVER, or the servers of the config file, with SNTP
+//     (RFC 4330) and step the system clock by the offset of the best
+//     server: the valid reply with the smallest root distance.
+//
+// Options:
+//     -q: only print the offset of each server, do not set the clock
+//     -config: NTP config file with "server" lines, used without SERVERs
+//     -timeout: time to wait for each server
+//     -verbose: verbose output
 package main
 
 import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -19,15 +34,20 @@ import (
 )
 
 var (
-	config  = flag.String("config", "/etc/ntp.conf", "NTP config file.")
-	verbose = flag.Bool("verbose", false, "Verbose output")
-	debug   = func(string, ...interface{}) {}
+	config    = flag.String("config", "/etc/ntp.conf", "NTP config file.")
+	verbose   = flag.Bool("verbose", false, "Verbose output")
+	queryOnly = flag.Bool("q", false, "Only print the offset of each server, do not set the clock")
+	timeout   = flag.Duration("timeout", 5*time.Second, "Time to wait for each server")
+	debug     = func(string, ...interface{}) {}
 )
 
 const (
 	fallback = "time.google.com"
 )
 
+// query is ntp.QueryWithOptions, overridden in tests.
+var query = ntp.QueryWithOptions
+
 func parseServers(r *bufio.Reader) []string {
 	var uri []string
 	var l string
@@ -48,47 +68,116 @@ func parseServers(r *bufio.Reader) []string {
 	return uri
 }
 
-func getTime(servers []string) (t time.Time, err error) {
-	for _, s := range servers {
-		debug("Getting time from %v", s)
-		if t, err = ntp.Time(s); err == nil {
-			// Right now we return on the first valid time.
-			// We can implement better heuristics here.
-			debug("Got time %v", t)
-			return
+// result is the reply of a server.
+type result struct {
+	server string
+	resp   *ntp.Response
+	err    error
+}
+
+func (r *result) String() string {
+	if r.err != nil {
+		return fmt.Sprintf("server %s: %v", r.server, r.err)
+	}
+	return fmt.Sprintf("server %s, stratum %d, offset %+.6f, delay %.5f", r.server, r.resp.Stratum, r.resp.ClockOffset.Seconds(), r.resp.RTT.Seconds())
+}
+
+// queryAll queries all servers at once and returns their replies in the
+// order of servers.
+func queryAll(servers []string) []*result {
+	results := make([]*result, len(servers))
+	done := make(chan struct{})
+	for i, s := range servers {
+		go func(i int, s string) {
+			debug("Getting time from %v", s)
+			r := &result{server: s}
+			r.resp, r.err = query(s, ntp.QueryOptions{Timeout: *timeout})
+			if r.err == nil {
+				r.err = r.resp.Validate()
+			}
+			results[i] = r
+			done <- struct{}{}
+		}(i, s)
+	}
+	for range servers {
+		<-done
+	}
+	return results
+}
+
+// best returns the valid result with the smallest root distance.
+func best(results []*result) *result {
+	var b *result
+	for _, r := range results {
+		if r.err == nil && (b == nil || r.resp.RootDistance < b.resp.RootDistance) {
+			b = r
 		}
-		debug("Error getting time: %v", err)
 	}
+	return b
+}
 
-	err = fmt.Errorf("unable to get any time from servers %v", servers)
-	return
+// getTime returns the reply of the best of servers.
+func getTime(servers []string) (*result, error) {
+	results := queryAll(servers)
+	for _, r := range results {
+		debug("%v", r)
+	}
+	if b := best(results); b != nil {
+		return b, nil
+	}
+	return nil, fmt.Errorf("unable to get any time from servers %v", servers)
 }
 
-func main() {
-	var servers []string
-	flag.Parse()
-	if *verbose {
-		debug = log.Printf
+func run(args []string, stdout io.Writer) error {
+	servers := args
+	if len(servers) == 0 {
+		debug("Reading NTP servers from config file: %v", *config)
+		f, err := os.Open(*config)
+		if err == nil {
+			defer f.Close()
+			servers = parseServers(bufio.NewReader(f))
+			debug("Found %v servers", len(servers))
+		} else {
+			log.Printf("Unable to open config file: %v\nFalling back to : %v", err, fallback)
+			servers = []string{fallback}
+		}
 	}
 
-	debug("Reading NTP servers from config file: %v", *config)
-	f, err := os.Open(*config)
-	if err == nil {
-		defer f.Close()
-		servers = parseServers(bufio.NewReader(f))
-		debug("Found %v servers", len(servers))
-	} else {
-		log.Printf("Unable to open config file: %v\nFalling back to : %v", err, fallback)
-		servers = []string{fallback}
+	if *queryOnly {
+		results := queryAll(servers)
+		for _, r := range results {
+			fmt.Fprintln(stdout, r)
+		}
+		if best(results) == nil {
+			return fmt.Errorf("unable to get any time from servers %v", servers)
+		}
+		return nil
 	}
 
-	t, err := getTime(servers)
+	r, err := getTime(servers)
 	if err != nil {
-		log.Fatalf("Unable to get time: %v", err)
+		return fmt.Errorf("unable to get time: %v", err)
 	}
-
-	tv := syscall.NsecToTimeval(t.UnixNano())
+	// The offset was measured against the clock, so apply it to the
+	// clock, rather than setting the server's time from before the reply
+	// traveled back.
+	tv := syscall.NsecToTimeval(time.Now().Add(r.resp.ClockOffset).UnixNano())
 	if err = syscall.Settimeofday(&tv); err != nil {
-		log.Fatalf("Unable to set system time: %v", err)
+		if os.IsPermission(err) {
+			return fmt.Errorf("unable to set system time: %v (are you root?)", err)
+		}
+		return fmt.Errorf("unable to set system time: %v", err)
+	}
+	fmt.Fprintf(stdout, "step time server %s offset %+.6f sec\n", r.server, r.resp.ClockOffset.Seconds())
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if *verbose {
+		debug = log.Printf
+	}
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
 	}
 }
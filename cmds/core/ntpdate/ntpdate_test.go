@@ -6,9 +6,13 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/beevik/ntp"
 )
 
 var configFileTests = []struct {
@@ -95,3 +99,67 @@ func TestGetNoTime(t *testing.T) {
 		}
 	}
 }
+
+// fakeQuery replies as the servers of responses do, and fails for others.
+func fakeQuery(responses map[string]*ntp.Response) func(string, ntp.QueryOptions) (*ntp.Response, error) {
+	return func(s string, _ ntp.QueryOptions) (*ntp.Response, error) {
+		if r, ok := responses[s]; ok {
+			return r, nil
+		}
+		return nil, errors.New("no reply")
+	}
+}
+
+func response(stratum uint8, offset, distance time.Duration) *ntp.Response {
+	now := time.Now()
+	return &ntp.Response{
+		Time:          now,
+		ReferenceTime: now.Add(-time.Minute),
+		Stratum:       stratum,
+		ClockOffset:   offset,
+		RTT:           10 * time.Millisecond,
+		RootDistance:  distance,
+	}
+}
+
+func TestGetTimeBest(t *testing.T) {
+	defer func(q func(string, ntp.QueryOptions) (*ntp.Response, error)) { query = q }(query)
+	query = fakeQuery(map[string]*ntp.Response{
+		"far":  response(3, time.Second, 100*time.Millisecond),
+		"near": response(1, 2*time.Second, 10*time.Millisecond),
+		// A kiss of death is invalid, however near.
+		"kod": response(0, 3*time.Second, time.Millisecond),
+	})
+
+	r, err := getTime([]string{"down", "far", "kod", "near"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.server != "near" {
+		t.Errorf("getTime() picked %s, want near", r.server)
+	}
+	if _, err := getTime([]string{"down", "kod"}); err == nil {
+		t.Error("getTime() without valid replies = nil, want error")
+	}
+}
+
+func TestQueryOnly(t *testing.T) {
+	defer func(q func(string, ntp.QueryOptions) (*ntp.Response, error)) { query = q }(query)
+	query = fakeQuery(map[string]*ntp.Response{
+		"a": response(2, 1500*time.Microsecond, time.Millisecond),
+	})
+	*queryOnly = true
+	defer func() { *queryOnly = false }()
+
+	var b bytes.Buffer
+	if err := run([]string{"a", "b"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	want := "server a, stratum 2, offset +0.001500, delay 0.01000\nserver b: no reply\n"
+	if b.String() != want {
+		t.Errorf("run(-q) = %q, want %q", b.String(), want)
+	}
+	if err := run([]string{"b"}, &b); err == nil {
+		t.Error("run(-q) without replies = nil, want error")
+	}
+}
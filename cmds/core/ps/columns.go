@@ -0,0 +1,254 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// column is a column that can be selected with -o and used with --sort.
+type column struct {
+	header string
+	// right aligns the column to the right, as is done for numbers.
+	right bool
+	value func(p *Process) string
+	// key, if set, is used for sorting instead of value.
+	key func(p *Process) int64
+}
+
+func atoi(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// etime formats an elapsed time as [[dd-]hh:]mm:ss.
+func etime(secs int64) string {
+	if secs < 0 {
+		return "-"
+	}
+	d, h, m, s := secs/86400, secs/3600%24, secs/60%60, secs%60
+	switch {
+	case d > 0:
+		return fmt.Sprintf("%d-%02d:%02d:%02d", d, h, m, s)
+	case h > 0:
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// userName returns the name of uid, or the number if it has no name.
+func userName(uid int) string {
+	id := strconv.Itoa(uid)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+var (
+	pidColumn   = column{header: "PID", right: true, value: func(p *Process) string { return p.Pid }, key: func(p *Process) int64 { return int64(p.Pidno) }}
+	ppidColumn  = column{header: "PPID", right: true, value: func(p *Process) string { return p.Ppid }, key: func(p *Process) int64 { return atoi(p.Ppid) }}
+	pgrpColumn  = column{header: "PGRP", right: true, value: func(p *Process) string { return p.Pgrp }, key: func(p *Process) int64 { return atoi(p.Pgrp) }}
+	sidColumn   = column{header: "SID", right: true, value: func(p *Process) string { return p.Sid }, key: func(p *Process) int64 { return atoi(p.Sid) }}
+	uidColumn   = column{header: "UID", right: true, value: func(p *Process) string { return strconv.Itoa(p.uid) }, key: func(p *Process) int64 { return int64(p.uid) }}
+	userColumn  = column{header: "USER", value: func(p *Process) string { return userName(p.uid) }}
+	rssColumn   = column{header: "RSS", right: true, value: func(p *Process) string { return strconv.FormatInt(p.rss, 10) }, key: func(p *Process) int64 { return p.rss }}
+	vszColumn   = column{header: "VSZ", right: true, value: func(p *Process) string { return strconv.FormatInt(p.vsz, 10) }, key: func(p *Process) int64 { return p.vsz }}
+	statColumn  = column{header: "STAT", value: func(p *Process) string { return p.State }}
+	commColumn  = column{header: "COMMAND", value: func(p *Process) string { return p.comm }}
+	argsColumn  = column{header: "COMMAND", value: func(p *Process) string { return p.args() }}
+	etimeColumn = column{header: "ELAPSED", right: true, value: func(p *Process) string { return etime(p.etime) }, key: func(p *Process) int64 { return p.etime }}
+	timeColumn  = column{header: "TIME", right: true, value: func(p *Process) string { return p.Time }, key: func(p *Process) int64 { return atoi(p.Utime) + atoi(p.Stime) }}
+	ttyColumn   = column{header: "TT", value: func(p *Process) string { return p.Ctty }}
+)
+
+// columns maps the names accepted by -o and --sort to columns. Aliases
+// are the ones procps uses.
+var columns = map[string]column{
+	"pid":     pidColumn,
+	"ppid":    ppidColumn,
+	"pgrp":    pgrpColumn,
+	"pgid":    pgrpColumn,
+	"sid":     sidColumn,
+	"sess":    sidColumn,
+	"uid":     uidColumn,
+	"user":    userColumn,
+	"uname":   userColumn,
+	"rss":     rssColumn,
+	"rssize":  rssColumn,
+	"vsz":     vszColumn,
+	"vsize":   vszColumn,
+	"stat":    statColumn,
+	"state":   statColumn,
+	"s":       statColumn,
+	"comm":    commColumn,
+	"ucomm":   commColumn,
+	"args":    argsColumn,
+	"cmd":     argsColumn,
+	"command": argsColumn,
+	"etime":   etimeColumn,
+	"time":    timeColumn,
+	"cputime": timeColumn,
+	"tty":     ttyColumn,
+	"tt":      ttyColumn,
+}
+
+// parseColumns parses -o arguments. Each is a list of column names
+// separated by commas or blanks. A name may be followed by =HEADER to
+// rename the column, in which case the rest of the argument is the header.
+func parseColumns(specs []string) ([]column, error) {
+	var cols []column
+	for _, spec := range specs {
+		for spec != "" {
+			var name string
+			name, spec = nextField(spec)
+			if name == "" {
+				continue
+			}
+			header := ""
+			i := strings.IndexByte(name, '=')
+			if i >= 0 {
+				name, header = name[:i], name[i+1:]
+			}
+			c, ok := columns[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown column %q", name)
+			}
+			if i >= 0 {
+				c.header = header
+			}
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
+// nextField splits off the first comma or blank separated field of s, up
+// to an '=' which ends the list.
+func nextField(s string) (string, string) {
+	i := strings.IndexAny(s, ", \t")
+	if i < 0 || strings.IndexByte(s[:i], '=') >= 0 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// sortKey is a --sort key.
+type sortKey struct {
+	column
+	reverse bool
+}
+
+// parseSort parses a --sort argument, a comma separated list of column
+// names, each optionally prefixed with + (ascending, the default) or -
+// (descending).
+func parseSort(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, f := range strings.Split(spec, ",") {
+		if f == "" {
+			continue
+		}
+		var k sortKey
+		switch f[0] {
+		case '-':
+			k.reverse = true
+			f = f[1:]
+		case '+':
+			f = f[1:]
+		}
+		c, ok := columns[strings.ToLower(f)]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort key %q", f)
+		}
+		k.column = c
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// sortTable sorts the process table by keys. Processes that compare
+// equal keep their order.
+func sortTable(table []*Process, keys []sortKey) {
+	sort.SliceStable(table, func(i, j int) bool {
+		a, b := table[i], table[j]
+		for _, k := range keys {
+			var c int
+			if k.key != nil {
+				x, y := k.key(a), k.key(b)
+				switch {
+				case x < y:
+					c = -1
+				case x > y:
+					c = 1
+				}
+			} else {
+				c = strings.Compare(k.value(a), k.value(b))
+			}
+			if k.reverse {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// printColumns prints the selected processes in aligned columns. Numeric
+// columns are right aligned. The last column is not padded, so long
+// command lines are printed in full.
+func printColumns(w io.Writer, cols []column, procs []*Process) {
+	rows := make([][]string, 0, len(procs)+1)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.header
+	}
+	rows = append(rows, header)
+	for _, p := range procs {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			row[i] = c.value(p)
+		}
+		rows = append(rows, row)
+	}
+
+	width := make([]int, len(cols))
+	for _, row := range rows {
+		for i, v := range row {
+			if len(v) > width[i] {
+				width[i] = len(v)
+			}
+		}
+	}
+	// Headers that are all blank are omitted, like procps does when
+	// every column is renamed to the empty string.
+	skip := strings.TrimSpace(strings.Join(header, "")) == ""
+	for n, row := range rows {
+		if n == 0 && skip {
+			continue
+		}
+		var b strings.Builder
+		for i, v := range row {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			switch {
+			case cols[i].right:
+				fmt.Fprintf(&b, "%*s", width[i], v)
+			case i == len(row)-1:
+				b.WriteString(v)
+			default:
+				fmt.Fprintf(&b, "%-*s", width[i], v)
+			}
+		}
+		fmt.Fprintln(w, b.String())
+	}
+}
@@ -5,7 +5,7 @@
 // Print process information.
 //
 // Synopsis:
-//     ps [-Aaex] [aux]
+//     ps [-Aaex] [-o COLUMNS] [--sort KEYS] [aux]
 //
 // Description:
 //     ps reads the /proc filesystem and prints nice things about what it
@@ -17,6 +17,13 @@
 //     -e: select all processes. Identical to -A.
 //     -x: BSD-Like style, with STAT Column and long CommandLine
 //     -a: print all process except whose are session leaders or unlinked with terminal
+//     -o: print the comma separated COLUMNS, which may be given more
+//         than once. A column may be renamed with COLUMN=HEADER, which
+//         takes the rest of the argument. Known columns are pid, ppid,
+//         pgrp, sid, uid, user, rss, vsz (in KiB), stat, comm, args,
+//         etime, time and tty.
+//     --sort: sort by the comma separated KEYS, which are column names
+//         optionally prefixed with + (ascending) or - (descending)
 //    aux: see every process on the system using BSD syntax
 package main
 
@@ -35,8 +42,10 @@ var (
 		nSidTty bool
 		x       bool
 		aux     bool
+		format  []string
+		sort    string
 	}
-	cmd  = "ps [-Aaex] [aux]"
+	cmd  = "ps [-Aaex] [-o COLUMNS] [--sort KEYS] [aux]"
 	eUID = os.Geteuid()
 )
 
@@ -50,6 +59,8 @@ func init() {
 	flag.BoolVarP(&flags.all, "every", "e", false, "Select all processes.  Identical to -A.")
 	flag.BoolVarP(&flags.x, "bsd", "x", false, "BSD-Like style, with STAT Column and long CommandLine")
 	flag.BoolVarP(&flags.nSidTty, "nSIDTTY", "a", false, "Print all process except whose are session leaders or unlinked with terminal")
+	flag.StringArrayVarP(&flags.format, "format", "o", nil, "Comma separated columns to print")
+	flag.StringVar(&flags.sort, "sort", "", "Comma separated columns to sort by, prefixed with - for descending order")
 }
 
 // ProcessTable holds all the information needed for ps
@@ -164,6 +175,28 @@ func ps(pT *ProcessTable, w io.Writer) error {
 	}
 	// sorting ProcessTable by PID
 	sort.Sort(pT)
+	if flags.sort != "" {
+		keys, err := parseSort(flags.sort)
+		if err != nil {
+			return err
+		}
+		sortTable(pT.table, keys)
+	}
+
+	if len(flags.format) > 0 {
+		cols, err := parseColumns(flags.format)
+		if err != nil {
+			return err
+		}
+		var procs []*Process
+		for _, p := range pT.table {
+			if pT.selected(p) {
+				procs = append(procs, p)
+			}
+		}
+		printColumns(w, cols, procs)
+		return nil
+	}
 
 	switch {
 	case flags.aux:
@@ -180,35 +213,35 @@ func ps(pT *ProcessTable, w io.Writer) error {
 	pT.PrepareString()
 	pT.PrintHeader(w)
 	for index, p := range pT.table {
-		switch {
-		case flags.nSidTty:
-			// no session leaders and no unlinked terminals
-			if p.Sid == p.Pid || p.Ctty == "?" {
-				continue
-			}
+		if pT.selected(p) {
+			pT.PrintProcess(index, w)
+		}
+	}
 
-		case flags.x:
-			// print only process with same eUID of caller
-			if eUID != p.uid {
-				continue
-			}
+	return nil
 
-		case flags.all:
-			// pass, print all
+}
 
-		default:
-			// default for no flags only same session
-			// and same uid process
-			if p.Sid != pT.mProc.Sid || eUID != p.uid {
-				continue
-			}
-		}
+// selected reports whether the flags select a process for printing.
+func (pT ProcessTable) selected(p *Process) bool {
+	switch {
+	case flags.nSidTty:
+		// no session leaders and no unlinked terminals
+		return p.Sid != p.Pid && p.Ctty != "?"
 
-		pT.PrintProcess(index, w)
-	}
+	case flags.x:
+		// print only process with same eUID of caller
+		return eUID == p.uid
 
-	return nil
+	case flags.all:
+		// pass, print all
+		return true
 
+	default:
+		// default for no flags only same session
+		// and same uid process
+		return pT.mProc != nil && p.Sid == pT.mProc.Sid && eUID == p.uid
+	}
 }
 
 func usage() {
@@ -235,7 +268,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	err := ps(pT, os.Stderr)
+	err := ps(pT, os.Stdout)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -33,8 +33,13 @@ type Process struct {
 	status  string
 	cmdline string
 	stat    string
+	statm   string
 	Pidno   int // process id #
 	uid     int
+	comm    string // name of the executable, even if Cmd is the command line
+	rss     int64  // resident set size in KiB
+	vsz     int64  // virtual memory size in KiB
+	etime   int64  // seconds since the process started, -1 if unknown
 }
 
 // table content of stat file defined by:
@@ -111,6 +116,8 @@ func (p *Process) readStat(s string) error {
 	p.Time = p.getTime()
 	p.Ctty = p.getCtty()
 	p.Cmd = strings.TrimSuffix(strings.TrimPrefix(p.Cmd, "("), ")")
+	p.comm = p.Cmd
+	p.rss, p.vsz = p.getMemory()
 	if flags.x && p.cmdline != "" {
 		p.Cmd = p.cmdline
 	}
@@ -144,6 +151,29 @@ func (p process) getCtty() string {
 	return "?"
 }
 
+// getMemory returns the resident and virtual memory sizes in KiB, from
+// statm if available, else from stat.
+func (p *Process) getMemory() (int64, int64) {
+	page := int64(os.Getpagesize())
+	var size, resident int64
+	if _, err := fmt.Sscan(p.statm, &size, &resident); err == nil {
+		return resident * page / 1024, size * page / 1024
+	}
+	rss, _ := strconv.ParseInt(p.Rss, 10, 64)
+	vsz, _ := strconv.ParseInt(p.Vsize, 10, 64)
+	return rss * page / 1024, vsz / 1024
+}
+
+// args returns the command line, or the name in brackets for processes
+// without one, like kernel threads.
+func (p *Process) args() string {
+	a := strings.TrimSuffix(p.cmdline, "\x00")
+	if a == "" {
+		return "[" + p.comm + "]"
+	}
+	return strings.Replace(a, "\x00", " ", -1)
+}
+
 // Get a named field of stat type
 // e.g.: p.getField("Pid") => '1'
 func (p *process) getField(field string) string {
@@ -222,8 +252,22 @@ func file(s string) (string, error) {
 	return string(b), err
 }
 
+// readUptime returns the system uptime in seconds, or -1 if unknown.
+func readUptime() int64 {
+	var up float64
+	s, err := file(filepath.Join(procdir, "uptime"))
+	if err != nil {
+		return -1
+	}
+	if _, err := fmt.Sscan(s, &up); err != nil {
+		return -1
+	}
+	return int64(up)
+}
+
 func (pT *ProcessTable) doTable(statFileNames []string) error {
 	var err error
+	uptime := readUptime()
 	for _, stat := range statFileNames {
 		p := &Process{}
 
@@ -253,7 +297,12 @@ func (pT *ProcessTable) doTable(statFileNames []string) error {
 			if err != nil {
 				continue
 			}
+		} else {
+			// Only needed for some -o columns, so it is fine if
+			// it is missing.
+			p.cmdline, _ = file(filepath.Join(d, "cmdline"))
 		}
+		p.statm, _ = file(filepath.Join(d, "statm"))
 		// if filepath.Base is *not* proc, then use it, else
 		// it's just the directory containing the pid.
 		proot := filepath.Dir(d)
@@ -266,6 +315,10 @@ func (pT *ProcessTable) doTable(statFileNames []string) error {
 			return err
 		}
 		p.Pid = pid
+		p.etime = -1
+		if start, err := strconv.ParseInt(p.StartTime, 10, 64); err == nil && uptime >= 0 {
+			p.etime = uptime - start/userHZ
+		}
 		//log.Printf("stat is %v p is %v", stat,p)
 		if p.Pidno == os.Getpid() {
 			pT.mProc = p
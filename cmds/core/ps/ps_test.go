@@ -215,3 +215,106 @@ nonvoluntary_ctxt_switches:	3746
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
+
+func TestEtime(t *testing.T) {
+	for _, tt := range []struct {
+		secs int64
+		want string
+	}{
+		{-1, "-"},
+		{0, "00:00"},
+		{61, "01:01"},
+		{3600, "01:00:00"},
+		{86400 + 3*3600 + 4*60 + 5, "1-03:04:05"},
+	} {
+		if got := etime(tt.secs); got != tt.want {
+			t.Errorf("etime(%d) = %q, want %q", tt.secs, got, tt.want)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	d, err := ioutil.TempDir("", "ps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	page := os.Getpagesize() / 1024
+	vsz := len(fmt.Sprint(500 * page))
+	files := map[string]string{
+		"uptime":     "1000.50 2000.00\n",
+		"1/stat":     "1 (init) S 0 1 1 0 -1 4194560 82923 51272244 88 3457 153 671 103226 39563 20 0 1 0 2 230821888 2325 18446744073709551615 1 1 0 0 0 0 671173123 4096 1260 0 0 0 17 1 0 0 69 0 0 0 0 0 0 0 0 0 0",
+		"1/status":   "Name:\tinit\nUid:\t0\t0\t0\t0\n",
+		"1/cmdline":  "/init\x00",
+		"1/statm":    "100 10 0 0 0 0 0\n",
+		"2/stat":     "2 (kthreadd) S 0 0 0 0 -1 2129984 0 0 0 0 0 0 0 0 20 0 1 0 50000 0 0 18446744073709551615 0 0 0 0 0 0 0 2147483647 0 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0",
+		"2/status":   "Name:\tkthreadd\nUid:\t0\t0\t0\t0\n",
+		"2/statm":    "0 0 0 0 0 0 0\n",
+		"30/stat":    "30 (sh) R 1 30 30 0 -1 4194560 0 0 0 0 5 5 0 0 20 0 1 0 90000 0 0 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0 0 0 0 0 0 0 0 0",
+		"30/status":  "Name:\tsh\nUid:\t0\t0\t0\t0\n",
+		"30/cmdline": "/bin/sh\x00-c\x00sleep 1\x00",
+		"30/statm":   "500 20 0 0 0 0 0\n",
+	}
+	for n, f := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(d, n)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, n), []byte(f), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{
+			args: []string{"-e", "-o", "pid,ppid,stat,etime,comm"},
+			want: "PID PPID STAT ELAPSED COMMAND\n" +
+				"  1    0 S      16:40 init\n" +
+				"  2    0 S      08:20 kthreadd\n" +
+				" 30    1 R      01:40 sh\n",
+		},
+		{
+			args: []string{"-e", "-o", "pid", "-o", "rss,vsz,args", "--sort", "-rss"},
+			want: fmt.Sprintf("PID RSS %*s COMMAND\n", vsz, "VSZ") +
+				fmt.Sprintf(" 30 %3d %*d /bin/sh -c sleep 1\n", 20*page, vsz, 500*page) +
+				fmt.Sprintf("  1 %3d %*d /init\n", 10*page, vsz, 100*page) +
+				fmt.Sprintf("  2   0 %*d [kthreadd]\n", vsz, 0),
+		},
+		{
+			args: []string{"-e", "-o", "pid,comm=NAME OF COMMAND", "--sort", "ppid,-pid"},
+			want: "PID NAME OF COMMAND\n" +
+				"  2 kthreadd\n" +
+				"  1 init\n" +
+				" 30 sh\n",
+		},
+		{
+			args: []string{"-e", "-o", "pid=", "--sort", "+etime"},
+			want: "30\n 2\n 1\n",
+		},
+	} {
+		c := testutil.Command(t, tt.args...)
+		c.Env = append(c.Env, "UROOT_PSPATH="+d)
+		o, err := c.CombinedOutput()
+		if err != nil {
+			t.Errorf("ps %q: %v: %s", tt.args, err, o)
+			continue
+		}
+		if string(o) != tt.want {
+			t.Errorf("ps %q: got %q, want %q", tt.args, o, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-o", "nosuchcolumn"},
+		{"--sort", "nosuchkey"},
+	} {
+		c := testutil.Command(t, args...)
+		c.Env = append(c.Env, "UROOT_PSPATH="+d)
+		if err := c.Run(); err == nil {
+			t.Errorf("ps %q: got nil, want error", args)
+		}
+	}
+}
@@ -7,8 +7,18 @@
 // Synopsis:
 //     readlink [OPTIONS] FILE
 //
+// Description:
+//     Without -f, -e or -m, readlink prints the target of the symbolic link
+//     FILE. With them, it prints the absolute path of FILE with all
+//     symbolic links resolved, one component at a time, and relative link
+//     targets resolved from the directory of the link. Chains of more than
+//     40 links are reported as loops. If more than one is given, -e wins
+//     over -m, and -m over -f.
+//
 // Options:
-//     -f: follow
+//     -f: canonicalize; all but the last component must exist
+//     -e: canonicalize; all components must exist
+//     -m: canonicalize; no components need exist
 //     -n: nonewline
 //     -v: verbose
 package main
@@ -17,14 +27,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
+
+	"github.com/u-root/u-root/pkg/upath"
 )
 
-const cmd = "readlink [-fnv] FILE"
+const cmd = "readlink [-femnv] FILE"
 
 var (
 	delimiter = "\n"
-	follow    = flag.Bool("f", false, "follow recursively")
+	follow    = flag.Bool("f", false, "canonicalize; all but the last component must exist")
+	existing  = flag.Bool("e", false, "canonicalize; all components must exist")
+	missing   = flag.Bool("m", false, "canonicalize; no components need exist")
 	nonewline = flag.Bool("n", false, "do not output trailing newline")
 	verbose   = flag.Bool("v", false, "report error messages")
 )
@@ -38,21 +51,28 @@ func init() {
 }
 
 func readLink(file string) error {
-	path, err := os.Readlink(file)
+	var path string
+	var err error
+	switch {
+	case *existing:
+		path, err = upath.Canonicalize(file, upath.Existing, true)
+	case *missing:
+		path, err = upath.Canonicalize(file, upath.Missing, true)
+	case *follow:
+		path, err = upath.Canonicalize(file, upath.AllButLast, true)
+	default:
+		path, err = os.Readlink(file)
+	}
 	if err != nil {
 		return err
 	}
 
-	if *follow {
-		path, err = filepath.EvalSymlinks(file)
-	}
-
 	if *nonewline {
 		delimiter = ""
 	}
 
 	fmt.Printf("%s%s", path, delimiter)
-	return err
+	return nil
 }
 
 func main() {
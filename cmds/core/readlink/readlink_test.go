@@ -52,9 +52,9 @@ func TestReadlink(t *testing.T) {
 			exitStatus: 1,
 		}, {
 			flags:      []string{"-f", "f2"},
-			out:        "",
+			out:        filepath.Join(testDir, "f2") + "\n",
 			stdErr:     "",
-			exitStatus: 1,
+			exitStatus: 0,
 		},
 		{
 			flags:      []string{"f1symlink"},
@@ -86,6 +86,42 @@ func TestReadlink(t *testing.T) {
 			stdErr:     fmt.Sprintf("readlink foo.bar: no such file or directory\n"),
 			exitStatus: 1,
 		},
+		{
+			flags:      []string{"-f", "multilinks", "sub/up", "foo.bar"},
+			out:        fmt.Sprintf("%s/f1\n%s/f1\n%s/foo.bar\n", testDir, testDir, testDir),
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-v", "-f", "foo.bar/baz"},
+			out:        "",
+			stdErr:     fmt.Sprintf("lstat %s/foo.bar: no such file or directory\n", testDir),
+			exitStatus: 1,
+		},
+		{
+			flags:      []string{"-v", "-e", "sub/up", "dangling"},
+			out:        fmt.Sprintf("%s/f1\n", testDir),
+			stdErr:     fmt.Sprintf("lstat %s/foo.bar: no such file or directory\n", testDir),
+			exitStatus: 1,
+		},
+		{
+			flags:      []string{"-m", "dangling/baz", "sub/../f2/x"},
+			out:        fmt.Sprintf("%s/foo.bar/baz\n%s/f2/x\n", testDir, testDir),
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-n", "-f", "f1symlink"},
+			out:        filepath.Join(testDir, "f1"),
+			stdErr:     "",
+			exitStatus: 0,
+		},
+		{
+			flags:      []string{"-v", "-f", "loop1"},
+			out:        "",
+			stdErr:     fmt.Sprintf("canonicalize %s/loop1: too many levels of symbolic links\n", testDir),
+			exitStatus: 1,
+		},
 	}
 	// Createfiles.
 	_, err = os.Create("f1")
@@ -113,6 +149,21 @@ func TestReadlink(t *testing.T) {
 		t.Error(err)
 	}
 
+	// Relative links are resolved from the directory of the link.
+	if err := os.Mkdir("sub", 0700); err != nil {
+		t.Error(err)
+	}
+	for link, target := range map[string]string{
+		"sub/up":   "../f1symlink",
+		"dangling": "foo.bar",
+		"loop1":    "loop2",
+		"loop2":    "loop1",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Error(err)
+		}
+	}
+
 	// Table-driven testing
 	for _, tt := range tests {
 		var out, stdErr bytes.Buffer
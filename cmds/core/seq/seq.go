@@ -14,6 +14,10 @@
 //    2 3 4
 //    % seq -s=' ' 3 2 7
 //    3 5 7
+//    % seq -s=' ' 3 -1 1
+//    3 2 1
+//    % seq -s=' ' 0 0.25 1
+//    0.00 0.25 0.50 0.75 1.00
 //
 // Options:
 //     -f: use printf style floating-point FORMAT (default: %v)
@@ -28,6 +32,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -51,58 +56,107 @@ func init() {
 	flag.BoolVar(&flags.widthEqual, "w", false, "equalize width by padding with leading zeroes")
 }
 
+// parse parses a number argument and returns it along with the number of
+// digits after its decimal point, taking an exponent into account.
+func parse(s string) (float64, int, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid floating point argument: %q", s)
+	}
+	mant, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mant = s[:i]
+		if exp, err = strconv.Atoi(s[i+1:]); err != nil {
+			return f, 0, nil
+		}
+	}
+	prec := 0
+	if i := strings.IndexByte(mant, '.'); i >= 0 {
+		prec = len(mant) - i - 1
+	}
+	if prec -= exp; prec < 0 {
+		prec = 0
+	}
+	return f, prec, nil
+}
+
 func seq(w io.Writer, args []string) error {
 	var (
 		stt   = 1.0
 		stp   = 1.0
 		end   float64
 		width int
+		prec  int
+		err   error
 	)
 
-	format := flags.format // I use that because I'll modify a global variable
 	argv, argc := args, len(args)
-	if argc < 1 || argc > 4 {
+	if argc < 1 || argc > 3 {
 		return fmt.Errorf("mismatch n args; got %v, wants 1 >= n args >= 3", argc)
 	}
 
+	if argc >= 2 { // cases: start + end || start + step + end
+		if stt, prec, err = parse(argv[0]); err != nil {
+			return err
+		}
+	}
 	// loading step value if args is <start> <step> <end>
 	if argc == 3 {
-		_, err := fmt.Sscanf(argv[1], "%v", &stp)
-		if stp-float64(int(stp)) > 0 && format == "%v" {
-			d := len(fmt.Sprintf("%v", stp-float64(int(stp)))) - 2 // get the nums of y.xx decimal part
-			format = fmt.Sprintf("%%.%df", d)
+		p := 0
+		if stp, p, err = parse(argv[1]); err != nil {
+			return err
 		}
 		if stp == 0.0 {
 			return errors.New("step value should be != 0")
 		}
-
-		if err != nil {
-			return err
+		if p > prec {
+			prec = p
 		}
 	}
-
-	if argc >= 2 { // cases: start + end || start + step + end
-		if _, err := fmt.Sscanf(argv[0]+" "+argv[argc-1], "%v %v", &stt, &end); err != nil {
-			return err
-		}
-	} else { // only <end>
-		if _, err := fmt.Sscanf(argv[0], "%v", &end); err != nil {
-			return err
-		}
+	if end, _, err = parse(argv[argc-1]); err != nil {
+		return err
 	}
 
+	format := flags.format // I use that because I'll modify a global variable
+	if format == "%v" {
+		// Print as many decimals as the start and step have.
+		format = fmt.Sprintf("%%.%df", prec)
+	}
 	format = strings.Replace(format, "%", "%0*", 1) // support widthEqual
 	if flags.widthEqual {
-		width = len(fmt.Sprintf(format, 0, end))
+		width = len(fmt.Sprintf(format, 0, stt))
+		if l := len(fmt.Sprintf(format, 0, end)); l > width {
+			width = l
+		}
 	}
 
-	defer fmt.Fprint(w, "\n") // last char is always '\n'
-	for stt <= end {
-		fmt.Fprintf(w, format, width, stt)
-		stt += stp
-		if stt <= end { // print only between the values
+	// Compute each value from the start rather than adding up steps, so
+	// that fractional steps do not accumulate rounding errors.
+	var last string
+	for i := 0; ; i++ {
+		x := stt + float64(i)*stp
+		out := fmt.Sprintf(format, width, x)
+		if (stp > 0 && x > end) || (stp < 0 && x < end) {
+			// If we overshot end only by rounding, i.e. x prints
+			// the same as end, print end rather than stopping short.
+			e := fmt.Sprintf(format, width, end)
+			if out != e || out == last {
+				break
+			}
+			out = e
+			x = end
+		}
+		if i > 0 {
 			fmt.Fprint(w, flags.separator)
 		}
+		fmt.Fprint(w, out)
+		last = out
+		if x == end {
+			break
+		}
+	}
+	if last != "" {
+		fmt.Fprint(w, "\n") // last char is always '\n'
 	}
 
 	return nil
@@ -9,7 +9,6 @@ package main
 import (
 	"bytes"
 	"io"
-	"reflect"
 	"testing"
 )
 
@@ -35,7 +34,7 @@ func testseq(tests []test, t *testing.T) {
 		got := b.Bytes()
 		want := []byte(tst.expect)
 
-		if !reflect.DeepEqual(got, want) {
+		if !bytes.Equal(got, want) {
 			t.Logf("Got: \n%v\n", string(got))
 			t.Logf("Expect: \n%v\n", tst.expect)
 			t.Error("Mismatching output")
@@ -112,3 +111,87 @@ func TestSeqSeparator(t *testing.T) {
 	testseq(tests, t)
 
 }
+
+func TestSeqFractionalStep(t *testing.T) {
+	var tests = []test{
+		{
+			[]string{"0", "0.1", "1"},
+			"0.0\n0.1\n0.2\n0.3\n0.4\n0.5\n0.6\n0.7\n0.8\n0.9\n1.0\n",
+		},
+		{
+			[]string{"0.1", "0.2", "1"},
+			"0.1\n0.3\n0.5\n0.7\n0.9\n",
+		},
+		{
+			[]string{"1", "0.25", "2"},
+			"1.00\n1.25\n1.50\n1.75\n2.00\n",
+		},
+		{
+			[]string{"1e1", "2e-1", "1.1e1"},
+			"10.0\n10.2\n10.4\n10.6\n10.8\n11.0\n",
+		},
+	}
+
+	testseq(tests, t)
+}
+
+func TestSeqDescending(t *testing.T) {
+	var tests = []test{
+		{
+			[]string{"3", "-1", "1"},
+			"3\n2\n1\n",
+		},
+		{
+			[]string{"1", "-0.5", "0"},
+			"1.0\n0.5\n0.0\n",
+		},
+		{
+			[]string{"-1", "-3"},
+			"",
+		},
+		{
+			[]string{"3", "1"},
+			"",
+		},
+		{
+			[]string{"0"},
+			"",
+		},
+		{
+			[]string{"1", "2", "1"},
+			"1\n",
+		},
+		{
+			[]string{"1", "2", "4"},
+			"1\n3\n",
+		},
+	}
+
+	testseq(tests, t)
+}
+
+func TestSeqNegativeWidthEqual(t *testing.T) {
+	flags.widthEqual = true
+	defer resetFlags()
+	var tests = []test{
+		{
+			[]string{"-3", "1"},
+			"-3\n-2\n-1\n00\n01\n",
+		},
+	}
+
+	testseq(tests, t)
+}
+
+func TestSeqErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"1", "2", "3", "4"},
+		{"a"},
+		{"1", "0", "2"},
+	} {
+		if err := seq(&bytes.Buffer{}, args); err == nil {
+			t.Errorf("seq(%q): got nil, want error", args)
+		}
+	}
+}
@@ -5,17 +5,32 @@
 // shutdown halts, suspends, or reboots at a specified time, or immediately.
 //
 // Synopsis:
-//     shutdown [<-h|-r|-s|halt|reboot|suspend> [time [message...]]]
+//     shutdown [-n] [-f] [-t SECONDS] [<-h|-P|-H|-r|-s|halt|poweroff|reboot|suspend> [time [message...]]]
+//     reboot|poweroff|halt [-n] [-f] [-t SECONDS] [time [message...]]
 //
 // Description:
-//     current operations are reboot (-r), suspend, and halt [-h].
+//     current operations are reboot (-r), suspend, halt (-h), which powers
+//     the machine off, and -H, which stops it without powering it off.
 //     If no operation is specified halt is assumed.
 //     If a time is given, an opcode is not optional.
 //
+//     Before the machine goes down, filesystems are synced, all
+//     filesystems but / and pseudo filesystems like proc are unmounted, in
+//     the reverse order they were mounted, and / is remounted read-only.
+//     Busy filesystems are unmounted lazily. Suspend only syncs.
+//
+//     Installed as reboot, poweroff or halt, shutdown does that operation;
+//     like shutdown halt, halt powers the machine off.
+//
 // Options:
 //     -r|reboot:	reboot the machine.
-//     -h|halt:		halt the machine.
+//     -h|halt:		power the machine off.
+//     -P|poweroff:	power the machine off.
+//     -H:		halt the machine without powering it off.
 //     -s|suspend:	suspend the machine.
+//     -t:		wait SECONDS before going down, added to time.
+//     -f:		do not unmount filesystems or remount / read-only.
+//     -n:		do not sync filesystems; implies -f.
 //
 // Time is specified as "now", +minutes, or RFC3339 format.
 // All other arguments past time are printed as a message.
@@ -23,65 +38,180 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/u-root/u-root/pkg/mount"
 	"golang.org/x/sys/unix"
 )
 
 var (
 	opcodes = map[string]uint{
-		"halt":    unix.LINUX_REBOOT_CMD_POWER_OFF,
-		"-h":      unix.LINUX_REBOOT_CMD_POWER_OFF,
-		"reboot":  unix.LINUX_REBOOT_CMD_RESTART,
-		"-r":      unix.LINUX_REBOOT_CMD_RESTART,
-		"suspend": unix.LINUX_REBOOT_CMD_SW_SUSPEND,
-		"-s":      unix.LINUX_REBOOT_CMD_SW_SUSPEND,
-	}
-	reboot = unix.Reboot
-	delay  = time.Sleep
+		"halt":     unix.LINUX_REBOOT_CMD_POWER_OFF,
+		"-h":       unix.LINUX_REBOOT_CMD_POWER_OFF,
+		"poweroff": unix.LINUX_REBOOT_CMD_POWER_OFF,
+		"-P":       unix.LINUX_REBOOT_CMD_POWER_OFF,
+		"-H":       unix.LINUX_REBOOT_CMD_HALT,
+		"reboot":   unix.LINUX_REBOOT_CMD_RESTART,
+		"-r":       unix.LINUX_REBOOT_CMD_RESTART,
+		"suspend":  unix.LINUX_REBOOT_CMD_SW_SUSPEND,
+		"-s":       unix.LINUX_REBOOT_CMD_SW_SUSPEND,
+	}
+	// aliases are the operations of the names shutdown may be run as.
+	aliases = map[string]uint{
+		"reboot":   unix.LINUX_REBOOT_CMD_RESTART,
+		"poweroff": unix.LINUX_REBOOT_CMD_POWER_OFF,
+		"halt":     unix.LINUX_REBOOT_CMD_POWER_OFF,
+	}
+	reboot      = unix.Reboot
+	delay       = time.Sleep
+	sync        = unix.Sync
+	unmountAll  = unmountFilesystems
+	remountRoot = func() error {
+		return unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_RDONLY, "")
+	}
+	mountsFile = "/proc/self/mounts"
 )
 
 func usage() {
-	log.Fatalf("shutdown [<-h|-r|-s|halt|reboot|suspend> [time [message...]]]")
+	log.Fatalf("shutdown [-n] [-f] [-t SECONDS] [<-h|-P|-H|-r|-s|halt|poweroff|reboot|suspend> [time [message...]]]")
+}
+
+// mountPoints returns the mount points of the mount table r to unmount,
+// latest mounts first.
+func mountPoints(r io.Reader) ([]string, error) {
+	mounts, err := mount.ParseTable(r)
+	if err != nil {
+		return nil, err
+	}
+	var m []string
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if e := mounts[i]; e.Target != "/" && !mount.PseudoFS[e.FSType] {
+			m = append(m, e.Target)
+		}
+	}
+	return m, nil
+}
+
+// unmountFilesystems unmounts all filesystems but / and pseudo ones. Busy
+// filesystems are detached, so that they go away once they are not used.
+func unmountFilesystems() error {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return err
+	}
+	m, err := mountPoints(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	var failed bool
+	for _, p := range m {
+		err := unix.Unmount(p, 0)
+		if err == unix.EBUSY {
+			err = unix.Unmount(p, unix.MNT_DETACH)
+		}
+		if err != nil {
+			log.Printf("Unmounting %s failed: %v", p, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some filesystems could not be unmounted")
+	}
+	return nil
+}
+
+// prepare gets the filesystems ready for op.
+func prepare(op uint, noSync, noUnmount bool) {
+	if noSync {
+		return
+	}
+	sync()
+	if noUnmount || op == unix.LINUX_REBOOT_CMD_SW_SUSPEND {
+		return
+	}
+	if err := unmountAll(); err != nil {
+		log.Print(err)
+	}
+	if err := remountRoot(); err != nil {
+		log.Printf("Remounting / read-only failed: %v", err)
+	}
+	// Whatever is left, e.g. in lazily unmounted filesystems.
+	sync()
 }
 
 func main() {
-	a := os.Args
-	if len(a) == 1 {
-		a = append(a, "halt")
+	a := os.Args[1:]
+	op, aliased := aliases[filepath.Base(os.Args[0])]
+	var noSync, noUnmount bool
+	var seconds int
+	// Options come before the operation.
+options:
+	for len(a) > 0 {
+		switch a[0] {
+		case "-n":
+			noSync = true
+		case "-f":
+			noUnmount = true
+		case "-t":
+			if len(a) < 2 {
+				usage()
+			}
+			s, err := strconv.Atoi(a[1])
+			if err != nil || s < 0 {
+				log.Fatalf("invalid delay %q", a[1])
+			}
+			seconds = s
+			a = a[1:]
+		default:
+			break options
+		}
+		a = a[1:]
 	}
-	op, ok := opcodes[a[1]]
-	if !ok {
-		usage()
+	if !aliased {
+		if len(a) == 0 {
+			a = append(a, "halt")
+		}
+		var ok bool
+		if op, ok = opcodes[a[0]]; !ok {
+			usage()
+		}
+		a = a[1:]
 	}
-	if len(a) < 3 {
+	if len(a) < 1 {
 		a = append(a, "now")
 	}
 	when := time.Now()
 	switch {
-	case a[2] == "now":
-	case a[2][0] == '+':
-		m, err := time.ParseDuration(a[2][1:] + "m")
+	case a[0] == "now":
+	case a[0][0] == '+':
+		m, err := time.ParseDuration(a[0][1:] + "m")
 		if err != nil {
 			log.Fatal(err)
 		}
 		when = when.Add(m)
 	default:
-		t, err := time.Parse(time.RFC3339, a[2])
+		t, err := time.Parse(time.RFC3339, a[0])
 		if err != nil {
 			log.Fatal(err)
 		}
 		when = t
 	}
+	when = when.Add(time.Duration(seconds) * time.Second)
 
 	delay(when.Sub(time.Now()))
-	if len(a) > 2 {
-		fmt.Println(strings.Join(a[3:], " "))
+	if len(a) > 1 {
+		fmt.Println(strings.Join(a[1:], " "))
 	}
+	prepare(op, noSync, noUnmount)
 	if err := reboot(int(op)); err != nil {
 		log.Fatal(err)
 	}
@@ -5,8 +5,11 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"os/exec"
+	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -15,38 +18,53 @@ import (
 )
 
 var tests = []struct {
+	name    string
 	args    []string
 	retCode int
 }{
-	{[]string{"halt"}, 2},
-	{[]string{"-h"}, 2},
+	{"shutdown", []string{"halt"}, 2},
+	{"shutdown", []string{"-h"}, 2},
 	// No args means halt.
-	{[]string{}, 2},
-	{[]string{"reboot"}, 3},
-	{[]string{"-r"}, 3},
-	{[]string{"suspend"}, 4},
-	{[]string{"-s"}, 4},
+	{"shutdown", []string{}, 2},
+	{"shutdown", []string{"reboot"}, 3},
+	{"shutdown", []string{"-r"}, 3},
+	{"shutdown", []string{"suspend"}, 4},
+	{"shutdown", []string{"-s"}, 4},
 	// good times, bad times
-	{[]string{"halt", "police"}, 1},
+	{"shutdown", []string{"halt", "police"}, 1},
 	// Yep, it's legal.
 	// We can't put any non-zero times in these tests, it causes
 	// the integration tests to fail ...
-	{[]string{"halt", "+-0"}, 2},
-	{[]string{"halt", "+0"}, 2},
-	{[]string{"halt", "+2"}, 2},
-	{[]string{"halt", "now"}, 2},
-	{[]string{"halt", "2006-01-02T15:04:05Z"}, 2},
-	{[]string{"halt", "2006-01-02T15:04:05Z07:00"}, 1},
-	{[]string{"halt", "2006-o1-02T15:04:05Z07:00"}, 1},
+	{"shutdown", []string{"halt", "+-0"}, 2},
+	{"shutdown", []string{"halt", "+0"}, 2},
+	{"shutdown", []string{"halt", "+2"}, 2},
+	{"shutdown", []string{"halt", "now"}, 2},
+	{"shutdown", []string{"halt", "2006-01-02T15:04:05Z"}, 2},
+	{"shutdown", []string{"halt", "2006-01-02T15:04:05Z07:00"}, 1},
+	{"shutdown", []string{"halt", "2006-o1-02T15:04:05Z07:00"}, 1},
 	// Get the message out
-	{[]string{"halt", "now", "is", "the", "time"}, 2},
+	{"shutdown", []string{"halt", "now", "is", "the", "time"}, 2},
+	{"shutdown", []string{"poweroff"}, 2},
+	{"shutdown", []string{"-P"}, 2},
+	{"shutdown", []string{"-H"}, 5},
+	// Options come first.
+	{"shutdown", []string{"-n", "-f", "-t", "0", "-r", "now"}, 3},
+	{"shutdown", []string{"-t"}, 1},
+	{"shutdown", []string{"-t", "soon", "-r"}, 1},
+	{"shutdown", []string{"-r", "-n"}, 1},
+	// Installed under other names.
+	{"reboot", []string{}, 3},
+	{"reboot", []string{"-f", "now", "bye"}, 3},
+	{"poweroff", []string{}, 2},
+	{"halt", []string{"-n"}, 2},
+	{"halt", []string{"-r"}, 1},
 }
 
 func TestShutdown(t *testing.T) {
 	for i, tt := range tests {
 		var retCode int
 		c := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--"}, tt.args...)...)
-		c.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		c.Env = []string{"GO_WANT_HELPER_PROCESS=1", "SHUTDOWN_NAME=" + tt.name}
 		o, err := c.CombinedOutput()
 		t.Logf("out %s", o)
 		if err != nil {
@@ -78,6 +96,8 @@ func TestHelperProcess(t *testing.T) {
 			xval = 3
 		case unix.LINUX_REBOOT_CMD_SW_SUSPEND:
 			xval = 4
+		case unix.LINUX_REBOOT_CMD_HALT:
+			xval = 5
 		}
 
 		t.Logf("Exit with %#x", i)
@@ -86,6 +106,59 @@ func TestHelperProcess(t *testing.T) {
 	}
 
 	delay = func(_ time.Duration) {}
-	os.Args = append([]string{"shutdown"}, os.Args[3:]...)
+	sync = func() {}
+	unmountAll = func() error { return nil }
+	remountRoot = func() error { return nil }
+	os.Args = append([]string{os.Getenv("SHUTDOWN_NAME")}, os.Args[3:]...)
 	main()
 }
+
+func TestMountPoints(t *testing.T) {
+	table := `rootfs / rootfs rw 0 0
+proc /proc proc rw 0 0
+/dev/sda1 /mnt ext4 rw 0 0
+tmpfs /mnt/my\040tmp tmpfs rw 0 0
+sysfs /sys sysfs rw 0 0
+mqueue /dev/mqueue mqueue rw 0 0
+/dev/sdb1 /data xfs rw 0 0
+`
+	got, err := mountPoints(strings.NewReader(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/data", "/mnt/my tmp", "/mnt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mountPoints() = %q, want %q", got, want)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	defer func(s func(), u, r func() error) { sync, unmountAll, remountRoot = s, u, r }(sync, unmountAll, remountRoot)
+	var steps []string
+	sync = func() { steps = append(steps, "sync") }
+	unmountAll = func() error {
+		steps = append(steps, "unmount")
+		return errors.New("busy")
+	}
+	remountRoot = func() error {
+		steps = append(steps, "remount")
+		return nil
+	}
+
+	for _, tt := range []struct {
+		op        uint
+		noSync    bool
+		noUnmount bool
+		want      []string
+	}{
+		{op: unix.LINUX_REBOOT_CMD_RESTART, want: []string{"sync", "unmount", "remount", "sync"}},
+		{op: unix.LINUX_REBOOT_CMD_POWER_OFF, noUnmount: true, want: []string{"sync"}},
+		{op: unix.LINUX_REBOOT_CMD_HALT, noSync: true},
+		{op: unix.LINUX_REBOOT_CMD_SW_SUSPEND, want: []string{"sync"}},
+	} {
+		steps = nil
+		prepare(tt.op, tt.noSync, tt.noUnmount)
+		if !reflect.DeepEqual(steps, tt.want) {
+			t.Errorf("prepare(%#x, %t, %t) did %q, want %q", tt.op, tt.noSync, tt.noUnmount, steps, tt.want)
+		}
+	}
+}
@@ -4,6 +4,18 @@
 
 // +build linux
 
+// switch_root moves from the initramfs to the real root file system.
+//
+// Synopsis:
+//     switch_root [-h] [-V] NEWROOT INIT [ARGS...]
+//
+// Description:
+//     Move /dev, /proc, /sys and /run to NEWROOT, make NEWROOT the root
+//     directory, delete everything in the initramfs and exec INIT with
+//     ARGS in place of switch_root, so that it keeps the PID, e.g. 1.
+//
+//     The current root has to be a tmpfs or ramfs, NEWROOT a mount point
+//     and INIT has to exist in NEWROOT.
 package main
 
 import (
@@ -21,7 +33,7 @@ var (
 )
 
 func usage() string {
-	return "switch_root [-h] [-V]\nswitch_root newroot init"
+	return "switch_root [-h] [-V]\nswitch_root newroot init [args...]"
 }
 
 func main() {
@@ -42,10 +54,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if len(flag.Args()) < 2 {
+		fmt.Fprintln(os.Stderr, usage())
+		os.Exit(1)
+	}
 	newRoot := flag.Args()[0]
 	init := flag.Args()[1]
 
-	if err := mount.SwitchRoot(newRoot, init); err != nil {
+	if err := mount.SwitchRoot(newRoot, init, flag.Args()[2:]...); err != nil {
 		log.Fatalf("switch_root failed %v\n", err)
 	}
 }
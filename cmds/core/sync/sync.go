@@ -5,7 +5,19 @@
 // sync command in Go.
 //
 // Synopsis:
-//		sync [-df] [FILE]
+//		sync [-df] [FILE]...
+//
+// Description:
+//		Without FILE, commit all file system caches to disk. Otherwise sync
+//		only each FILE with fsync(2), or with -d its data with fdatasync(2),
+//		or with -f the whole file system it is on with syncfs(2).
+//
+//		Errors are reported for each FILE; sync exits with 1 if there
+//		were any.
+//
+// Options:
+//		-d, -data:        sync only file data, no unneeded metadata
+//		-f, -file-system: sync the file systems that contain the files
 //
 
 package main
@@ -22,12 +34,13 @@ import (
 
 var (
 	data       = flag.Bool("data", false, "sync file data, no metadata")
-	filesystem = flag.Bool("filesystem", false, "commit filesystem caches to disk")
+	filesystem = flag.Bool("file-system", false, "commit filesystem caches to disk")
 )
 
 func init() {
 	flag.BoolVar(data, "d", false, "")
 	flag.BoolVar(filesystem, "f", false, "")
+	flag.BoolVar(filesystem, "filesystem", false, "")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [OPTION] [FILE]...\n", os.Args[0])
@@ -35,29 +48,59 @@ func init() {
 	}
 }
 
-func doSyscall(syscallNum uintptr) {
-	for _, fileName := range flag.Args() {
-		f, err := os.OpenFile(fileName, syscall.O_RDONLY|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0644)
-		if err != nil {
-			log.Fatal(err)
-		}
-		_, _, err = syscall.Syscall(syscallNum, uintptr(f.Fd()), 0, 0)
-		if err != nil {
-			log.Fatal(err)
-		}
-		f.Close()
+// open opens name for syncing. Files that can not be read, but written,
+// are opened for writing instead.
+func open(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_NOCTTY, 0)
+	if os.IsPermission(err) {
+		f, err = os.OpenFile(name, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_NOCTTY, 0)
+	}
+	return f, err
+}
+
+// syncFile syncs the file name with fn, which is one of unix.Fsync,
+// unix.Fdatasync and unix.Syncfs.
+func syncFile(name string, fn func(int) error) error {
+	f, err := open(name)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	if err := fn(int(f.Fd())); err != nil {
+		return &os.PathError{Op: "sync", Path: name, Err: err}
+	}
+	return nil
 }
 
 func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sync: ")
 	flag.Parse()
+
+	fn := unix.Fsync
 	switch {
+	case *data && *filesystem:
+		log.Fatal("cannot specify both -data and -file-system")
 	case *data:
-		doSyscall(unix.SYS_FDATASYNC)
+		fn = unix.Fdatasync
 	case *filesystem:
-		doSyscall(unix.SYS_SYNCFS)
-	default:
+		fn = unix.Syncfs
+	}
+
+	if flag.NArg() == 0 {
+		if *data {
+			log.Fatal("-data needs at least one FILE")
+		}
 		syscall.Sync()
 		os.Exit(0)
 	}
+
+	status := 0
+	for _, name := range flag.Args() {
+		if err := syncFile(name, fn); err != nil {
+			log.Print(err)
+			status = 1
+		}
+	}
+	os.Exit(status)
 }
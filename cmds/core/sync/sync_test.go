@@ -0,0 +1,53 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestSync(t *testing.T) {
+	d, err := ioutil.TempDir("", "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	f := filepath.Join(d, "f")
+	if err := ioutil.WriteFile(f, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		args   []string
+		status int
+	}{
+		{args: nil},
+		{args: []string{f}},
+		{args: []string{"-d", f}},
+		{args: []string{"-f", f, d}},
+		{args: []string{"-file-system", f}},
+		{args: []string{filepath.Join(d, "nosuchfile"), f}, status: 1},
+		{args: []string{"-d"}, status: 1},
+		{args: []string{"-d", "-f", f}, status: 1},
+	} {
+		err := testutil.Command(t, tt.args...).Run()
+		if tt.status == 0 && err != nil {
+			t.Errorf("sync %q: got %v, want nil", tt.args, err)
+		} else if tt.status != 0 {
+			if err := testutil.IsExitCode(err, tt.status); err != nil {
+				t.Errorf("sync %q: %v", tt.args, err)
+			}
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
@@ -6,14 +6,23 @@
 // the end of the file as it grows.
 //
 // Synopsis:
-//     tail [-f] [-n lines_to_show] [FILE]
+//     tail [-f] [-q | -v] [-n [+]K | -c [+]K] [FILE]...
 //
 // Description:
-//     If no files are specified, read from stdin.
+//     If no files are specified, or a file is -, read from stdin. If more
+//     than one file is given, the output of each is preceded by a header,
+//     ==> FILE <==.
+//
+//     K is a number, optionally followed by a multiplier: b (512), K or KiB
+//     (1024), KB (1000), M or MiB, MB, G or GiB, or GB. With a leading +,
+//     output starts at line or byte K instead.
 //
 // Options:
 //     -f: follow the end of the file as it grows
 //     -n: specify the number of lines to show (default: 10)
+//     -c: specify the number of bytes to show
+//     -q: never print headers
+//     -v: always print headers
 
 // Missing features:
 // - follow-mode (i.e. tail -f)
@@ -21,18 +30,26 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
 var (
 	flagFollow   = flag.Bool("f", false, "follow the end of the file")
-	flagNumLines = flag.Int("n", 10, "specify the number of lines to show")
+	flagNumLines = flag.String("n", "10", "specify the number of lines to show, or with +K, start at line K")
+	flagNumBytes = flag.String("c", "", "specify the number of bytes to show, or with +K, start at byte K")
+	flagQuiet    = flag.Bool("q", false, "never print headers")
+	flagVerbose  = flag.Bool("v", false, "always print headers")
 )
 
 type ReadAtSeeker interface {
@@ -47,6 +64,54 @@ type TailConfig struct {
 
 	// specifies the number of lines to print (-n)
 	numLines uint
+
+	// counts bytes instead of lines (-c), numLines is then a byte count
+	bytes bool
+
+	// prints from line or byte numLines on instead (+K)
+	fromStart bool
+}
+
+// multipliers are the suffixes of counts.
+var multipliers = map[string]uint64{
+	"":    1,
+	"b":   512,
+	"K":   1 << 10,
+	"KiB": 1 << 10,
+	"KB":  1000,
+	"M":   1 << 20,
+	"MiB": 1 << 20,
+	"MB":  1000 * 1000,
+	"G":   1 << 30,
+	"GiB": 1 << 30,
+	"GB":  1000 * 1000 * 1000,
+}
+
+// parseCount parses the count s of -n or -c, [+]K with an optional
+// multiplier. It returns whether K is preceded by a +.
+func parseCount(s string) (uint, bool, error) {
+	var fromStart bool
+	num := s
+	switch {
+	case strings.HasPrefix(num, "+"):
+		fromStart = true
+		num = num[1:]
+	case strings.HasPrefix(num, "-"):
+		num = num[1:]
+	}
+	i := strings.IndexFunc(num, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(num)
+	}
+	m, ok := multipliers[num[i:]]
+	if !ok || i == 0 {
+		return 0, false, fmt.Errorf("invalid count %q", s)
+	}
+	n, err := strconv.ParseUint(num[:i], 10, 64)
+	if err != nil || n > uint64(^uint(0)>>1)/m {
+		return 0, false, fmt.Errorf("invalid count %q", s)
+	}
+	return uint(n * m), fromStart, nil
 }
 
 // getBlockSize returns the number of bytes to read for each ReadAt call. This
@@ -62,6 +127,9 @@ func getBlockSize(numLines uint) int64 {
 // containing only the last `n` lines. If less lines are found, the input slice
 // is returned unmodified.
 func lastNLines(buf []byte, n uint) []byte {
+	if n == 0 {
+		return nil
+	}
 	slice := buf
 	// `data` contains up to `n` lines of the file
 	var data []byte
@@ -85,7 +153,7 @@ func lastNLines(buf []byte, n uint) []byte {
 				break
 			}
 			foundLines++
-			slice = slice[:idx-1]
+			slice = slice[:idx]
 		}
 		if idx == -1 {
 			// if there are less than `numLines` lines, use all what we have read
@@ -190,6 +258,81 @@ func readLastLinesFromBeginning(input io.ReadSeeker, writer io.Writer, numLines
 	return nil
 }
 
+// readLastBytes writes the last n bytes of input to writer. It seeks to
+// them if input is seekable, and otherwise keeps the last n bytes read.
+func readLastBytes(input io.ReadSeeker, writer io.Writer, n uint) error {
+	if size, err := input.Seek(0, io.SeekEnd); err == nil {
+		pos := size - int64(n)
+		if pos < 0 {
+			pos = 0
+		}
+		if _, err := input.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, input)
+		return err
+	} else if pathErr, ok := err.(*os.PathError); !ok || pathErr.Err != syscall.ESPIPE {
+		return err
+	}
+
+	// Not seekable: read it all, and only keep the last n bytes, which
+	// may be less than a block.
+	var last []byte
+	buf := make([]byte, 32*1024)
+	for {
+		k, err := input.Read(buf)
+		last = append(last, buf[:k]...)
+		if uint(len(last)) > 2*n+uint(len(buf)) {
+			last = append(last[:0], last[uint(len(last))-n:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if uint(len(last)) > n {
+		last = last[uint(len(last))-n:]
+	}
+	_, err := writer.Write(last)
+	return err
+}
+
+// readFrom writes input to writer from the n-th line or byte on, counting
+// from 1.
+func readFrom(input io.Reader, writer io.Writer, n uint, countBytes bool) error {
+	if n > 0 {
+		n--
+	}
+	if countBytes {
+		if _, err := io.CopyN(ioutil.Discard, input, int64(n)); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		_, err := io.Copy(writer, input)
+		return err
+	}
+	r := bufio.NewReader(input)
+	for ; n > 0; n-- {
+		if _, err := r.ReadSlice('\n'); err != nil {
+			if err == bufio.ErrBufferFull {
+				// The line goes on.
+				n++
+				continue
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	_, err := r.WriteTo(writer)
+	return err
+}
+
 // Tail reads the last N lines from the input File and writes them to the Writer.
 // The TailConfig object allows to specify the precise behaviour.
 func Tail(inFile *os.File, writer io.Writer, config TailConfig) error {
@@ -199,6 +342,12 @@ func Tail(inFile *os.File, writer io.Writer, config TailConfig) error {
 	if inFile == nil {
 		return fmt.Errorf("no input file specified")
 	}
+	if config.fromStart {
+		return readFrom(inFile, writer, config.numLines, config.bytes)
+	}
+	if config.bytes {
+		return readLastBytes(inFile, writer, config.numLines)
+	}
 	// try reading from the end of the file
 	retryFromBeginning := false
 	err := readLastLinesBackwards(inFile, writer, config.numLines)
@@ -221,32 +370,70 @@ func Tail(inFile *os.File, writer io.Writer, config TailConfig) error {
 	return nil
 }
 
+// run tails each file in names, or stdin if there are none, preceded by a
+// header if there are several files or verbose is set. It keeps going if a
+// file fails.
+func run(names []string, writer io.Writer, config TailConfig, quiet, verbose bool) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	if config.follow && len(names) > 1 {
+		return errors.New("can only follow one file at a time")
+	}
+	headers := !quiet && (verbose || len(names) > 1)
+	var failed bool
+	for i, name := range names {
+		inFile := os.Stdin
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				log.Printf("tail: %v", err)
+				failed = true
+				continue
+			}
+			defer f.Close()
+			inFile = f
+		}
+		if headers {
+			if i > 0 {
+				fmt.Fprintln(writer)
+			}
+			title := name
+			if name == "-" {
+				title = "standard input"
+			}
+			fmt.Fprintf(writer, "==> %s <==\n", title)
+		}
+		if err := Tail(inFile, writer, config); err != nil {
+			log.Printf("tail: %s: %v", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some files could not be read")
+	}
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
-	var (
-		inFile *os.File
-		writer = os.Stdout
-		err    error
-	)
-	switch nArgs := len(flag.Args()); nArgs {
-	case 0:
-		inFile = os.Stdin
-	case 1:
-		inFile, err = os.Open(flag.Args()[0])
-		if err != nil {
-			log.Fatal(err)
-		}
-	default:
-		// TODO support multiple files
-		log.Fatal("tail: can only read one file at a time")
+	count, countBytes := *flagNumLines, false
+	if *flagNumBytes != "" {
+		count, countBytes = *flagNumBytes, true
 	}
+	n, fromStart, err := parseCount(count)
+	if err != nil {
+		log.Fatalf("tail: %v", err)
+	}
+	config := TailConfig{follow: *flagFollow, numLines: n, bytes: countBytes, fromStart: fromStart}
 
-	if *flagNumLines < 0 {
-		log.Fatalf("The number of lines cannot be negative")
+	w := bufio.NewWriter(os.Stdout)
+	err = run(flag.Args(), w, config, *flagQuiet, *flagVerbose)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
 	}
-	config := TailConfig{follow: *flagFollow, numLines: uint(*flagNumLines)}
-	if err := Tail(inFile, writer, config); err != nil {
+	if err != nil {
 		log.Fatalf("tail: %v", err)
 	}
 }
@@ -7,7 +7,9 @@ package main
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -66,3 +68,141 @@ func TestTailReadFromBeginning(t *testing.T) {
 		t.Fatalf("Expected EOF, got another error instead: %v", err)
 	}
 }
+
+func TestParseCount(t *testing.T) {
+	for _, tt := range []struct {
+		in        string
+		n         uint
+		fromStart bool
+		err       bool
+	}{
+		{in: "10", n: 10},
+		{in: "-3", n: 3},
+		{in: "+3", n: 3, fromStart: true},
+		{in: "0", n: 0},
+		{in: "2b", n: 1024},
+		{in: "1K", n: 1024},
+		{in: "1KiB", n: 1024},
+		{in: "2KB", n: 2000},
+		{in: "+1M", n: 1 << 20, fromStart: true},
+		{in: "1MB", n: 1000000},
+		{in: "", err: true},
+		{in: "+", err: true},
+		{in: "K", err: true},
+		{in: "1X", err: true},
+		{in: "1.5K", err: true},
+		{in: "99999999999999999999", err: true},
+	} {
+		n, fromStart, err := parseCount(tt.in)
+		if (err != nil) != tt.err || n != tt.n || fromStart != tt.fromStart {
+			t.Errorf("parseCount(%q) = %d, %t, %v, want %d, %t, error %t", tt.in, n, fromStart, err, tt.n, tt.fromStart, tt.err)
+		}
+	}
+}
+
+func TestLastNLines(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		n    uint
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\n\n\nb\n", 3, "\n\nb\n"},
+		{"\nb\n", 2, "\nb\n"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"a\nb\n", 0, ""},
+	} {
+		if got := string(lastNLines([]byte(tt.in), tt.n)); got != tt.want {
+			t.Errorf("lastNLines(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTailModes(t *testing.T) {
+	const in = "one\ntwo\nthree\nfour\n"
+	for _, tt := range []struct {
+		name   string
+		config TailConfig
+		want   string
+	}{
+		{"last lines", TailConfig{numLines: 2}, "three\nfour\n"},
+		{"from line", TailConfig{numLines: 3, fromStart: true}, "three\nfour\n"},
+		{"from line 0", TailConfig{numLines: 0, fromStart: true}, in},
+		{"from line past end", TailConfig{numLines: 9, fromStart: true}, ""},
+		{"last bytes", TailConfig{numLines: 5, bytes: true}, "four\n"},
+		{"all bytes", TailConfig{numLines: 100, bytes: true}, in},
+		{"from byte", TailConfig{numLines: 15, bytes: true, fromStart: true}, "four\n"},
+		{"from byte past end", TailConfig{numLines: 100, bytes: true, fromStart: true}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Once from a file, and once from a pipe, which can not
+			// seek.
+			f, err := ioutil.TempFile("", "tail")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+			if _, err := f.WriteString(in); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := Tail(f, &out, tt.config); err != nil || out.String() != tt.want {
+				t.Errorf("Tail(file) = %q, %v, want %q", out.String(), err, tt.want)
+			}
+
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			go func() {
+				io.WriteString(w, in)
+				w.Close()
+			}()
+			out.Reset()
+			if err := Tail(r, &out, tt.config); err != nil || out.String() != tt.want {
+				t.Errorf("Tail(pipe) = %q, %v, want %q", out.String(), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("a1\na2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("b1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := TailConfig{numLines: 1}
+	for _, tt := range []struct {
+		names   []string
+		quiet   bool
+		verbose bool
+		want    string
+		err     bool
+	}{
+		{names: []string{a}, want: "a2\n"},
+		{names: []string{a}, verbose: true, want: "==> " + a + " <==\na2\n"},
+		{names: []string{a, b}, want: "==> " + a + " <==\na2\n\n==> " + b + " <==\nb1\n"},
+		{names: []string{a, b}, quiet: true, want: "a2\nb1\n"},
+		{names: []string{a, filepath.Join(dir, "missing"), b}, quiet: true, want: "a2\nb1\n", err: true},
+	} {
+		var out bytes.Buffer
+		err := run(tt.names, &out, config, tt.quiet, tt.verbose)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("run(%v, quiet %t, verbose %t) = %q, %v, want %q, error %t", tt.names, tt.quiet, tt.verbose, out.String(), err, tt.want, tt.err)
+		}
+	}
+}
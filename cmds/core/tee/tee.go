@@ -6,19 +6,35 @@
 // in the files.
 //
 // Synopsis:
-//     tee [-ai] FILES...
+//     tee [-aip] [--output-error[=MODE]] FILES...
+//
+// Description:
+//     If a file can not be opened or written, tee reports it, keeps writing
+//     the other outputs and exits with status 1. How write errors are
+//     handled is set by MODE:
+//
+//     warn:        report errors writing to any output
+//     warn-nopipe: report errors writing to outputs other than pipes
+//     exit:        exit on an error writing to any output
+//     exit-nopipe: exit on an error writing to outputs other than pipes
+//
+//     Without --output-error, tee exits on errors writing to pipes and
+//     reports the others. -p is --output-error=warn-nopipe.
 //
 // Options:
 //     -a, --append: append the output to the files rather than rewriting them
 //     -i, --ignore-interrupts: ignore the SIGINT signal
+//     -p, --output-error[=MODE]: set the behavior on write errors
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 
 	flag "github.com/spf13/pflag"
 )
@@ -26,50 +42,130 @@ import (
 const name = "tee"
 
 var (
-	cat    = flag.BoolP("append", "a", false, "append the output to the files rather than rewriting them")
-	ignore = flag.BoolP("ignore-interrupts", "i", false, "ignore the SIGINT signal")
+	cat         = flag.BoolP("append", "a", false, "append the output to the files rather than rewriting them")
+	ignore      = flag.BoolP("ignore-interrupts", "i", false, "ignore the SIGINT signal")
+	outputError = flag.StringP("output-error", "p", "", "set the behavior on write errors: warn, warn-nopipe, exit or exit-nopipe")
 )
 
+func init() {
+	flag.Lookup("output-error").NoOptDefVal = "warn-nopipe"
+}
+
+// errStop stops copying after write errors, which multiWriter reports.
+var errStop = errors.New("stop writing")
+
+// output is a destination of tee, and the first error writing to it.
+type output struct {
+	name string
+	w    io.Writer
+	err  error
+}
+
+// multiWriter writes to all outputs, like io.MultiWriter, but keeps writing
+// to the other outputs if one fails, unless mode says to exit.
+type multiWriter struct {
+	outputs []*output
+	mode    string
+	failed  bool
+}
+
+// Write writes p to the outputs that have not failed. It only returns an
+// error, errStop, when mode asks to exit on the error, or when all outputs
+// failed.
+func (m *multiWriter) Write(p []byte) (int, error) {
+	live := 0
+	for _, o := range m.outputs {
+		if o.err != nil {
+			continue
+		}
+		n, err := o.w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			live++
+			continue
+		}
+		o.err = err
+		pipe := errors.Is(err, syscall.EPIPE)
+		if pipe && (m.mode == "warn-nopipe" || m.mode == "exit-nopipe") {
+			continue
+		}
+		m.failed = true
+		log.Printf("%s: error writing %s: %v", name, o.name, err)
+		if m.mode == "exit" || m.mode == "exit-nopipe" || (m.mode == "" && pipe) {
+			return 0, errStop
+		}
+	}
+	if live == 0 {
+		return 0, errStop
+	}
+	return len(p), nil
+}
+
 // handeFlags parses all the flags and sets variables accordingly
-func handleFlags() int {
+func handleFlags() (int, error) {
 	flag.Parse()
 
 	oflags := os.O_WRONLY | os.O_CREATE
 
 	if *cat {
 		oflags |= os.O_APPEND
+	} else {
+		oflags |= os.O_TRUNC
 	}
 
 	if *ignore {
 		signal.Ignore(os.Interrupt)
 	}
 
-	return oflags
+	switch *outputError {
+	case "":
+	case "warn", "warn-nopipe", "exit", "exit-nopipe":
+		// Get EPIPE instead of being killed by SIGPIPE writing to
+		// stdout.
+		signal.Ignore(syscall.SIGPIPE)
+	default:
+		return 0, fmt.Errorf("invalid --output-error mode %q", *outputError)
+	}
+
+	return oflags, nil
 }
 
 func main() {
-	oflags := handleFlags()
+	log.SetFlags(0)
+	oflags, err := handleFlags()
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
 
+	var failed bool
 	files := make([]*os.File, 0, flag.NArg())
-	writers := make([]io.Writer, 0, flag.NArg()+1)
+	mw := &multiWriter{mode: *outputError}
 	for _, fname := range flag.Args() {
 		f, err := os.OpenFile(fname, oflags, 0666)
 		if err != nil {
-			log.Fatalf("%s: error opening %s: %v", name, fname, err)
+			log.Printf("%s: error opening %s: %v", name, fname, err)
+			failed = true
+			continue
 		}
 		files = append(files, f)
-		writers = append(writers, f)
+		mw.outputs = append(mw.outputs, &output{name: fname, w: f})
 	}
-	writers = append(writers, os.Stdout)
+	mw.outputs = append(mw.outputs, &output{name: "standard output", w: os.Stdout})
 
-	mw := io.MultiWriter(writers...)
-	if _, err := io.Copy(mw, os.Stdin); err != nil {
-		log.Fatalf("%s: error: %v", name, err)
+	if _, err := io.Copy(mw, os.Stdin); err != nil && err != errStop {
+		log.Printf("%s: error: %v", name, err)
+		failed = true
 	}
 
 	for _, f := range files {
 		if err := f.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "%s: error closing file %q: %v\n", name, f.Name(), err)
+			failed = true
 		}
 	}
+	if failed || mw.failed {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,79 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type failWriter struct {
+	err error
+}
+
+func (f failWriter) Write([]byte) (int, error) {
+	return 0, f.err
+}
+
+func TestMultiWriter(t *testing.T) {
+	ioErr := &os.PathError{Op: "write", Path: "f", Err: syscall.EIO}
+	pipeErr := &os.PathError{Op: "write", Path: "p", Err: syscall.EPIPE}
+	for _, tt := range []struct {
+		name   string
+		mode   string
+		bad    error
+		err    error
+		failed bool
+	}{
+		{name: "default, file error", mode: "", bad: ioErr, failed: true},
+		{name: "default, pipe error", mode: "", bad: pipeErr, err: errStop, failed: true},
+		{name: "warn, pipe error", mode: "warn", bad: pipeErr, failed: true},
+		{name: "warn-nopipe, pipe error", mode: "warn-nopipe", bad: pipeErr},
+		{name: "warn-nopipe, file error", mode: "warn-nopipe", bad: ioErr, failed: true},
+		{name: "exit, file error", mode: "exit", bad: ioErr, err: errStop, failed: true},
+		{name: "exit-nopipe, pipe error", mode: "exit-nopipe", bad: pipeErr},
+		{name: "exit-nopipe, file error", mode: "exit-nopipe", bad: ioErr, err: errStop, failed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var a, b bytes.Buffer
+			bad := &output{name: "bad", w: failWriter{tt.bad}}
+			mw := &multiWriter{
+				mode:    tt.mode,
+				outputs: []*output{{name: "a", w: &a}, bad, {name: "b", w: &b}},
+			}
+			for _, s := range []string{"hello ", "world"} {
+				_, err := io.WriteString(mw, s)
+				if err != tt.err {
+					t.Fatalf("Write(%q) = %v, want %v", s, err, tt.err)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if tt.err == nil {
+				if a.String() != "hello world" || b.String() != "hello world" {
+					t.Errorf("outputs = %q, %q, want hello world", a.String(), b.String())
+				}
+			}
+			if !errors.Is(bad.err, tt.bad) {
+				t.Errorf("output error = %v, want %v", bad.err, tt.bad)
+			}
+			if mw.failed != tt.failed {
+				t.Errorf("failed = %t, want %t", mw.failed, tt.failed)
+			}
+		})
+	}
+}
+
+func TestMultiWriterAllFailed(t *testing.T) {
+	mw := &multiWriter{mode: "warn", outputs: []*output{{name: "a", w: failWriter{syscall.EIO}}}}
+	if _, err := mw.Write([]byte("x")); err != errStop {
+		t.Errorf("Write() = %v, want %v", err, errStop)
+	}
+}
@@ -2,36 +2,45 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// tr - translate or delete characters
-
+// tr translates, squeezes or deletes characters.
+//
 // Synopsis:
-//     tr [OPTION]... SET1 [SET2]
-
+//     tr [-c] SET1 SET2
+//     tr [-c] -s SET1 [SET2]
+//     tr [-c] -d SET1
+//     tr [-c] -d -s SET1 SET2
+//
 // Description:
-//     Translate, squeeze, and/or delete characters from standard input, writing
-//     to standard output.
+//     tr copies stdin to stdout, translating the characters of SET1 to the
+//     characters at the same position in SET2. If SET2 is shorter than
+//     SET1, its last character is repeated.
 //
-//     -d, --delete: delete characters in SET1, do not translate
+//     SETs are strings of characters, most of which represent themselves.
+//     The others are:
 //
-// SETs  are  specified  as  strings of characters. Most represent themselves.
-// Interpreted sequences are:
-//     \\        backslash
-//     \a        audible BEL
-//     \b        backspace
-//     \f        form feed
-//     \n        new line
-//     \r        return
-//     \t        horizontal tab
-//     \v        vertical tab
-//     [:alnum:] all letters and digits
-//     [:alpha:] all letters
-//     [:digit:] all digits
-//     [:graph:] all printable characters
-//     [:cntrl:] all control characters
-//     [:lower:] all lower case letters
-//     [:upper:] all upper case letters
-//     [:space:] all whitespaces
-
+//     \\, \a, \b, \f, \n, \r, \t, \v: the escaped characters
+//     \NNN:       the character with octal value NNN
+//     C1-C2:      the characters from C1 to C2
+//     [:CLASS:]:  the characters of CLASS, one of alnum, alpha, blank,
+//                 cntrl, digit, graph, lower, print, punct, space, upper
+//                 and xdigit
+//     [C*N]:      in SET2, C repeated N times, or enough times to make
+//                 SET2 as long as SET1 without N
+//
+//     The only classes allowed in SET2 are [:lower:] and [:upper:], at the
+//     same position as [:upper:] or [:lower:] in SET1, to convert case.
+//
+//     Characters are UTF-8 encoded runes, and classes include all of
+//     Unicode. To pair a class with the characters of SET2, its ASCII
+//     members are used in order; a class translated to a single character
+//     translates all of its members. Invalid UTF-8 is copied as is.
+//
+// Options:
+//     -c: use the complement of SET1; when translating, all the characters
+//         not in SET1 are translated to the last character of SET2
+//     -d: delete the characters in SET1 instead of translating them
+//     -s: replace a sequence of the same character in the last SET given
+//         with a single one, after translating or deleting
 package main
 
 import (
@@ -40,14 +49,19 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	flag "github.com/spf13/pflag"
 )
 
-var delete = flag.BoolP("delete", "d", false, "delete characters in SET1, do not translate")
-
-const name = "tr"
+var (
+	delete     = flag.BoolP("delete", "d", false, "delete characters in SET1, do not translate")
+	squeeze    = flag.BoolP("squeeze-repeats", "s", false, "replace a sequence of the same character in the last SET with a single one")
+	complement = flag.BoolP("complement", "c", false, "use the complement of SET1")
+)
 
 var escapeChars = map[rune]rune{
 	'\\': '\\',
@@ -63,208 +77,428 @@ var escapeChars = map[rune]rune{
 type Set string
 
 const (
-	ALPHA Set = "[:alpha:]"
-	DIGIT Set = "[:digit:]"
-	GRAPH Set = "[:graph:]"
-	CNTRL Set = "[:cntrl:]"
-	PUNCT Set = "[:punct:]"
-	SPACE Set = "[:space:]"
-	ALNUM Set = "[:alnum:]"
-	LOWER Set = "[:lower:]"
-	UPPER Set = "[:upper:]"
+	ALPHA  Set = "[:alpha:]"
+	DIGIT  Set = "[:digit:]"
+	GRAPH  Set = "[:graph:]"
+	CNTRL  Set = "[:cntrl:]"
+	PUNCT  Set = "[:punct:]"
+	SPACE  Set = "[:space:]"
+	ALNUM  Set = "[:alnum:]"
+	LOWER  Set = "[:lower:]"
+	UPPER  Set = "[:upper:]"
+	BLANK  Set = "[:blank:]"
+	PRINT  Set = "[:print:]"
+	XDIGIT Set = "[:xdigit:]"
 )
 
 var sets = map[Set]func(r rune) bool{
 	ALNUM: func(r rune) bool {
 		return unicode.IsLetter(r) || unicode.IsDigit(r)
 	},
+	GRAPH: func(r rune) bool {
+		return unicode.IsGraphic(r) && !unicode.IsSpace(r)
+	},
+	BLANK: func(r rune) bool {
+		return r == ' ' || r == '\t'
+	},
+	XDIGIT: func(r rune) bool {
+		return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+	},
 
 	ALPHA: unicode.IsLetter,
 	DIGIT: unicode.IsDigit,
-	GRAPH: unicode.IsGraphic,
 	CNTRL: unicode.IsControl,
 	PUNCT: unicode.IsPunct,
 	SPACE: unicode.IsSpace,
 	LOWER: unicode.IsLower,
 	UPPER: unicode.IsUpper,
+	PRINT: unicode.IsPrint,
 }
 
-type transformer struct {
-	transform func(r rune) rune
+// element is a part of a SET: the characters from lo to hi, a class, or in
+// SET2, lo repeated n times, where n is 0 to fill SET2.
+type element struct {
+	lo, hi rune
+	class  Set
+	repeat bool
+	n      int
 }
 
-func setToRune(s Set, outRune rune) *transformer {
-	check := sets[s]
-	return &transformer{
-		transform: func(r rune) rune {
-			if check(r) {
-				return outRune
-			}
-			return r
-		},
+// readChar reads a character, which may be escaped, from the start of s,
+// and returns it and its length.
+func readChar(s string) (rune, int, error) {
+	if s[0] != '\\' || len(s) == 1 {
+		r, n := utf8.DecodeRuneInString(s)
+		return r, n, nil
 	}
-}
-
-func lowerToUpper() *transformer {
-	return &transformer{
-		transform: func(r rune) rune {
-			return unicode.ToUpper(r)
-		},
+	if s[1] >= '0' && s[1] <= '7' {
+		n := 1
+		for n < 4 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+			n++
+		}
+		v, _ := strconv.ParseUint(s[1:n], 8, 8)
+		return rune(v), n, nil
 	}
+	r, n := utf8.DecodeRuneInString(s[1:])
+	v, ok := escapeChars[r]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown escape sequence '\\%c'", r)
+	}
+	return v, n + 1, nil
 }
 
-func upperToLower() *transformer {
-	return &transformer{
-		transform: func(r rune) rune {
-			return unicode.ToLower(r)
-		},
+// parseSet parses SET1, or SET2 if second is set.
+func parseSet(s string, second bool) ([]element, error) {
+	var elems []element
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "[:") {
+			if j := strings.Index(s[i+2:], ":]"); j >= 0 {
+				class := Set(s[i : i+j+4])
+				if _, ok := sets[class]; !ok {
+					return nil, fmt.Errorf("invalid character class %q", class)
+				}
+				elems = append(elems, element{class: class})
+				i += j + 4
+				continue
+			}
+		}
+		if second && s[i] == '[' && i+1 < len(s) {
+			if e, n, ok, err := parseRepeat(s[i+1:]); err != nil {
+				return nil, err
+			} else if ok {
+				elems = append(elems, e)
+				i += n + 1
+				continue
+			}
+		}
+		lo, n, err := readChar(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		hi := lo
+		// A - at the start or end is itself.
+		if i+1 < len(s) && s[i] == '-' {
+			if hi, n, err = readChar(s[i+1:]); err != nil {
+				return nil, err
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("range-endpoints of '%s' are in reverse collating sequence order", s[i-1:i+1+n])
+			}
+			i += n + 1
+		}
+		elems = append(elems, element{lo: lo, hi: hi})
 	}
+	return elems, nil
 }
 
-func runesToRunes(in []rune, out ...rune) *transformer {
-	convs := make(map[rune]rune)
-	l := len(out)
-	for i, r := range in {
-		ind := i
-		if i > l-1 {
-			ind = l - 1
+// parseRepeat parses C*N], after [, and returns its length and whether s
+// is one.
+func parseRepeat(s string) (element, int, bool, error) {
+	c, n, err := readChar(s)
+	if err != nil || n >= len(s) || s[n] != '*' {
+		return element{}, 0, false, nil
+	}
+	end := strings.IndexByte(s[n:], ']')
+	if end < 0 {
+		return element{}, 0, false, nil
+	}
+	count := s[n+1 : n+end]
+	e := element{lo: c, hi: c, repeat: true}
+	if count != "" {
+		// Like in C, a leading 0 means octal.
+		base := 10
+		if count[0] == '0' {
+			base = 8
 		}
-		convs[r] = out[ind]
+		v, err := strconv.ParseUint(count, base, 31)
+		if err != nil {
+			return element{}, 0, false, fmt.Errorf("invalid repeat count %q in [c*n] construct", count)
+		}
+		e.n = int(v)
 	}
-	return &transformer{
-		transform: func(r rune) rune {
-			if outRune, ok := convs[r]; ok {
-				return outRune
+	return e, n + end + 1, true, nil
+}
+
+// contains returns whether a character is in the set, or not in it if
+// complement is set.
+func contains(set []element, complement bool) func(r rune) bool {
+	return func(r rune) bool {
+		for _, e := range set {
+			if e.class != "" && sets[e.class](r) || e.class == "" && r >= e.lo && r <= e.hi {
+				return !complement
 			}
-			return r
-		},
+		}
+		return complement
 	}
 }
 
-func (t *transformer) run(r io.Reader, w io.Writer) error {
-	in := bufio.NewReader(r)
-	out := bufio.NewWriter(w)
-
-	defer out.Flush()
-
-	for {
-		inRune, size, err := in.ReadRune()
-		if inRune == unicode.ReplacementChar {
-			// can skip error handling here, because
-			// previous operation was in.ReadRune()
-			in.UnreadRune()
+// segment is where a class is in an expanded set.
+type segment struct {
+	class      Set
+	start, end int
+}
 
-			b, err := in.ReadByte()
-			if err != nil {
-				return fmt.Errorf("read error: %v", err)
+// expand returns the characters of set in order, with classes expanded to
+// their ASCII members. A repeat without a count fills the set up to size.
+func expand(set []element, size int) ([]rune, []segment, error) {
+	fill := -1
+	var runes []rune
+	var segs []segment
+	for i, e := range set {
+		switch {
+		case e.class != "":
+			start := len(runes)
+			for r := rune(0); r < utf8.RuneSelf; r++ {
+				if sets[e.class](r) {
+					runes = append(runes, r)
+				}
 			}
-
-			if err := out.WriteByte(b); err != nil {
-				return fmt.Errorf("write error: %v", err)
+			segs = append(segs, segment{class: e.class, start: start, end: len(runes)})
+		case e.repeat && e.n == 0:
+			if fill >= 0 {
+				return nil, nil, fmt.Errorf("only one [c*] repeat construct may appear in SET2")
 			}
-		} else if size > 0 {
-			if outRune := t.transform(inRune); outRune != unicode.ReplacementChar {
-				if _, err := out.WriteRune(outRune); err != nil {
-					return fmt.Errorf("write error: %v", err)
-				}
+			fill = i
+		case e.repeat:
+			for j := 0; j < e.n; j++ {
+				runes = append(runes, e.lo)
 			}
-		}
-
-		if err != nil {
-			if err == io.EOF {
-				return nil
+		default:
+			for r := e.lo; r <= e.hi; r++ {
+				runes = append(runes, r)
 			}
-			return err
 		}
 	}
+	if fill < 0 {
+		return runes, segs, nil
+	}
+	// Expand again, now that the length of the fill is known.
+	n := size - len(runes)
+	if n < 0 {
+		n = 0
+	}
+	filled := append([]element{}, set...)
+	filled[fill].n = n
+	if n == 0 {
+		filled = append(filled[:fill], filled[fill+1:]...)
+	}
+	return expand(filled, size)
 }
 
-func parse() (*transformer, error) {
-	flag.Parse()
-
-	narg := flag.NArg()
-	args := flag.Args()
-	switch {
-	case narg == 0 || (narg == 1 && !*delete):
-		return nil, fmt.Errorf("missing operand")
-	case narg > 1 && *delete:
-		return nil, fmt.Errorf("extra operand after %q", args[0])
-	case narg > 2:
-		return nil, fmt.Errorf("extra operand after %q", args[1])
+// mapping returns the translation of SET1 to SET2.
+func mapping(set1, set2 []element, complement bool) (func(r rune) rune, error) {
+	for _, e := range set2 {
+		if e.class != "" && e.class != LOWER && e.class != UPPER {
+			return nil, fmt.Errorf("the only character classes that may appear in SET2 are 'upper' and 'lower'")
+		}
 	}
-
-	set1 := Set(args[0])
-	arg1, err := unescape(set1)
+	from, segs1, err := expand(set1, 0)
 	if err != nil {
 		return nil, err
 	}
-
-	var set2 Set
-	if *delete {
-		set2 = Set(unicode.ReplacementChar)
-	} else {
-		set2 = Set(args[1])
+	to, segs2, err := expand(set2, len(from))
+	if err != nil {
+		return nil, err
 	}
-
-	if set1 == LOWER && set2 == UPPER {
-		return lowerToUpper(), nil
+	if len(to) == 0 {
+		return nil, fmt.Errorf("SET2 must be non-empty")
 	}
-	if set1 == UPPER && set2 == LOWER {
-		return upperToLower(), nil
+	last := to[len(to)-1]
+	if complement {
+		if len(segs2) > 0 {
+			return nil, fmt.Errorf("when translating with complemented character classes, SET2 must map all characters to one")
+		}
+		in := contains(set1, false)
+		return func(r rune) rune {
+			if in(r) {
+				return r
+			}
+			return last
+		}, nil
+	}
+	for len(to) < len(from) {
+		to = append(to, last)
 	}
 
-	if (set2 == LOWER || set2 == UPPER) && (set1 != LOWER && set1 != UPPER) ||
-		(set1 == LOWER && set2 == LOWER) || (set1 == UPPER && set2 == UPPER) {
-		return nil, fmt.Errorf("misaligned [:upper:] and/or [:lower:] construct")
+	// Classes are translated by rules, which cover all of Unicode, if
+	// they convert case or translate to a single character.
+	type rule struct {
+		in func(r rune) bool
+		to func(r rune) rune
+	}
+	var rules []rule
+	done := make([]bool, len(from))
+	for _, s2 := range segs2 {
+		var paired bool
+		for _, s1 := range segs1 {
+			if s1.start == s2.start && s1.end == s2.end && s1.class != s2.class && (s1.class == LOWER || s1.class == UPPER) {
+				paired = true
+			}
+		}
+		if !paired {
+			return nil, fmt.Errorf("misaligned [:upper:] and/or [:lower:] construct")
+		}
+	}
+	for _, s1 := range segs1 {
+		var conv func(r rune) rune
+		for _, s2 := range segs2 {
+			if s2.start == s1.start {
+				conv = unicode.ToUpper
+				if s2.class == LOWER {
+					conv = unicode.ToLower
+				}
+			}
+		}
+		if conv == nil {
+			c := to[s1.start]
+			for _, r := range to[s1.start:s1.end] {
+				if r != c {
+					c = -1
+				}
+			}
+			if c < 0 {
+				continue
+			}
+			conv = func(rune) rune { return c }
+		}
+		rules = append(rules, rule{in: sets[s1.class], to: conv})
+		for i := s1.start; i < s1.end; i++ {
+			done[i] = true
+		}
 	}
 
-	if _, ok := sets[set2]; ok {
-		return nil, fmt.Errorf(`the only character classes that may appear in SET2 are 'upper' and 'lower'`)
+	m := make(map[rune]rune)
+	for i, r := range from {
+		if !done[i] {
+			m[r] = to[i]
+		}
 	}
+	return func(r rune) rune {
+		if t, ok := m[r]; ok {
+			return t
+		}
+		for _, ru := range rules {
+			if ru.in(r) {
+				return ru.to(r)
+			}
+		}
+		return r
+	}, nil
+}
 
-	arg2, err := unescape(set2)
+// transformer deletes, translates and squeezes characters, in that order.
+type transformer struct {
+	deletes   func(r rune) bool
+	translate func(r rune) rune
+	squeezes  func(r rune) bool
+}
+
+func newTransformer(set1, set2 string, nsets int, delete, squeeze, complement bool) (*transformer, error) {
+	s1, err := parseSet(set1, false)
 	if err != nil {
 		return nil, err
 	}
-	if len(arg2) == 0 {
-		return nil, fmt.Errorf("SET2 must be non-empty")
+	s2, err := parseSet(set2, true)
+	if err != nil {
+		return nil, err
+	}
+	t := &transformer{}
+	switch {
+	case delete:
+		t.deletes = contains(s1, complement)
+	case nsets == 1:
+		t.squeezes = contains(s1, complement)
+		return t, nil
+	default:
+		if t.translate, err = mapping(s1, s2, complement); err != nil {
+			return nil, err
+		}
 	}
-	if _, ok := sets[set1]; ok {
-		return setToRune(set1, arg2[0]), nil
+	if squeeze {
+		t.squeezes = contains(s2, false)
 	}
-	return runesToRunes(arg1, arg2...), nil
+	return t, nil
 }
 
-func unescape(s Set) ([]rune, error) {
-	var out []rune
-	var escape bool
-	for _, r := range s {
-		if escape {
-			v, ok := escapeChars[r]
-			if !ok {
-				return nil, fmt.Errorf("unknown escape sequence '\\%c'", r)
+func (t *transformer) run(r io.Reader, w io.Writer) error {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	defer out.Flush()
+
+	last := rune(-1)
+	for {
+		inRune, size, err := in.ReadRune()
+		if inRune == unicode.ReplacementChar && size == 1 {
+			// Copy invalid UTF-8 as is.
+			in.UnreadRune()
+			b, err := in.ReadByte()
+			if err != nil {
+				return fmt.Errorf("read error: %v", err)
 			}
-			out = append(out, v)
-			escape = false
+			if err := out.WriteByte(b); err != nil {
+				return fmt.Errorf("write error: %v", err)
+			}
+			last = -1
 			continue
 		}
+		if err == io.EOF {
+			return out.Flush()
+		}
+		if err != nil {
+			return fmt.Errorf("read error: %v", err)
+		}
 
-		if r == '\\' {
-			escape = true
+		if t.deletes != nil && t.deletes(inRune) {
+			continue
+		}
+		if t.translate != nil {
+			inRune = t.translate(inRune)
+		}
+		if t.squeezes != nil && inRune == last && t.squeezes(inRune) {
 			continue
 		}
+		last = inRune
+		if _, err := out.WriteRune(inRune); err != nil {
+			return fmt.Errorf("write error: %v", err)
+		}
+	}
+}
 
-		out = append(out, r)
+func parse() (*transformer, error) {
+	flag.Parse()
+
+	narg := flag.NArg()
+	args := flag.Args()
+	want := 2
+	if *delete && !*squeeze {
+		want = 1
+	}
+	switch {
+	case narg == 0:
+		return nil, fmt.Errorf("missing operand")
+	case narg == 1 && *squeeze && !*delete:
+	case narg < want:
+		return nil, fmt.Errorf("missing operand after %q", args[narg-1])
+	case narg > want:
+		return nil, fmt.Errorf("extra operand %q", args[want])
+	}
+	var set2 string
+	if narg == 2 {
+		set2 = args[1]
 	}
-	return out, nil
+	return newTransformer(args[0], set2, narg, *delete, *squeeze, *complement)
 }
 
 func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tr: ")
 	t, err := parse()
 	if err != nil {
-		log.Fatalf("%s: %v\n", name, err)
+		log.Fatal(err)
 	}
 	if err := t.run(os.Stdin, os.Stdout); err != nil {
-		log.Fatalf("%s: %v\n", name, err)
+		log.Fatal(err)
 	}
 }
@@ -7,114 +7,249 @@ package main
 import (
 	"bytes"
 	"testing"
-	"unicode"
 
 	"github.com/u-root/u-root/pkg/testutil"
 )
 
-func TestUnescape(t *testing.T) {
-	if _, err := unescape("a\\tb\\nc\\d"); err == nil {
-		t.Errorf("unescape() expected error, got nil")
+func TestReadChar(t *testing.T) {
+	if _, _, err := readChar("\\d"); err == nil {
+		t.Errorf("readChar() expected error, got nil")
 	}
-	got, err := unescape("a\\tb\\nc\\\\")
-	if err != nil {
-		t.Fatalf("unescape() error: %v", err)
+	for _, tt := range []struct {
+		in   string
+		want rune
+		n    int
+	}{
+		{"a", 'a', 1},
+		{"\\t", '\t', 2},
+		{"\\n", '\n', 2},
+		{"\\\\", '\\', 2},
+		{"\\", '\\', 1},
+		{"\\101x", 'A', 4},
+		{"\\0", 0, 2},
+		{"é", 'é', 2},
+	} {
+		got, n, err := readChar(tt.in)
+		if err != nil || got != tt.want || n != tt.n {
+			t.Errorf("readChar(%q) = %q, %d, %v, want %q, %d, nil", tt.in, got, n, err, tt.want, tt.n)
+		}
 	}
-	want := "a\tb\nc\\"
-	if string(got) != want {
-		t.Errorf("unescape() want %q, got %q", want, got)
+}
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		set    string
+		second bool
+		size   int
+		want   string
+	}{
+		{set: "a-e", want: "abcde"},
+		{set: "-a-c-", want: "-abc-"},
+		{set: "[:digit:]x", want: "0123456789x"},
+		{set: "[:xdigit:]", want: "0123456789ABCDEFabcdef"},
+		{set: "[:blank:]", want: "\t "},
+		{set: "[x*3]y", second: true, want: "xxxy"},
+		{set: "a[x*]b", second: true, size: 5, want: "axxxb"},
+		{set: "a[x*]b", second: true, size: 1, want: "ab"},
+		{set: "[x*010]", second: true, want: "xxxxxxxx"},
+		{set: "[x*3]", want: "[x*3]"},
+		{set: "[ab]", second: true, want: "[ab]"},
+	} {
+		elems, err := parseSet(tt.set, tt.second)
+		if err != nil {
+			t.Errorf("parseSet(%q) = %v", tt.set, err)
+			continue
+		}
+		got, _, err := expand(elems, tt.size)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("expand(%q) = %q, %v, want %q", tt.set, string(got), err, tt.want)
+		}
+	}
+}
+
+func TestParseSetErrors(t *testing.T) {
+	for _, set := range []string{"z-a", "[:foo:]", "a\\d", "[x*9a]"} {
+		if _, err := parseSet(set, true); err == nil {
+			t.Errorf("parseSet(%q) succeeded, want an error", set)
+		}
 	}
 }
 
 func TestTR(t *testing.T) {
 	for _, test := range []struct {
-		name   string
-		input  string
-		output string
-		t      *transformer
+		name       string
+		input      string
+		output     string
+		set1, set2 string
+		nsets      int
+		delete     bool
+		squeeze    bool
+		complement bool
 	}{
 		{
 			name:   "alnum",
 			input:  "0123456789!&?defgh",
 			output: "zzzzzzzzzz!&?zzzzz",
-			t:      setToRune(ALNUM, 'z'),
+			set1:   "[:alnum:]", set2: "z",
 		},
 		{
 			name:   "alpha",
 			input:  "0123456789abcdefgh",
 			output: "0123456789zzzzzzzz",
-			t:      setToRune(ALPHA, 'z'),
+			set1:   "[:alpha:]", set2: "z",
 		},
 		{
 			name:   "digit",
 			input:  "0123456789abcdefgh",
 			output: "zzzzzzzzzzabcdefgh",
-			t:      setToRune(DIGIT, 'z'),
+			set1:   "[:digit:]", set2: "z",
 		},
 		{
 			name:   "lower",
 			input:  "0123456789abcdEFGH",
 			output: "0123456789zzzzEFGH",
-			t:      setToRune(LOWER, 'z'),
+			set1:   "[:lower:]", set2: "z",
 		},
 		{
 			name:   "upper",
 			input:  "0123456789abcdEFGH",
 			output: "0123456789abcdzzzz",
-			t:      setToRune(UPPER, 'z'),
+			set1:   "[:upper:]", set2: "z",
 		},
 		{
 			name:   "punct",
 			input:  "012345*{}[]!.?&()def",
 			output: "012345zzzzzzzzzzzdef",
-			t:      setToRune(PUNCT, 'z'),
+			set1:   "[:punct:]", set2: "z",
 		},
 		{
 			name:   "space",
 			input:  "0123456789\t\ncdef",
 			output: "0123456789zzcdef",
-			t:      setToRune(SPACE, 'z'),
+			set1:   "[:space:]", set2: "z",
 		},
 		{
 			name:   "graph",
 			input:  "\f\t🔫123456789abcdEFG",
 			output: "\f\tzzzzzzzzzzzzzzzzz",
-			t:      setToRune(GRAPH, 'z'),
+			set1:   "[:graph:]", set2: "z",
 		},
 		{
 			name:   "lower_to_upper",
-			input:  "0123456789abcdEFGH",
-			output: "0123456789ABCDEFGH",
-			t:      lowerToUpper(),
+			input:  "0123456789abcdEFGHé",
+			output: "0123456789ABCDEFGHÉ",
+			set1:   "[:lower:]", set2: "[:upper:]",
 		},
 		{
 			name:   "upper_to_lower",
 			input:  "0123456789abcdEFGH",
 			output: "0123456789abcdefgh",
-			t:      upperToLower(),
+			set1:   "[:upper:]", set2: "[:lower:]",
 		},
 		{
 			name:   "runes_to_runes",
 			input:  "0123456789abcdEFGH",
 			output: "012x45678yabcdzFGH",
-			t:      runesToRunes([]rune("39E"), 'x', 'y', 'z'),
+			set1:   "39E", set2: "xyz",
 		},
 		{
 			name:   "runes_to_runes_truncated",
 			input:  "0123456789abcdEFGH",
 			output: "012x45678yabcdyFGH",
-			t:      runesToRunes([]rune("39E"), 'x', 'y'),
+			set1:   "39E", set2: "xy",
 		},
 		{
 			name:   "delete_alnum",
 			input:  "0123456789abcdEFGH",
 			output: "",
-			t:      setToRune(ALNUM, unicode.ReplacementChar),
+			set1:   "[:alnum:]", nsets: 1, delete: true,
+		},
+		{
+			name:   "ranges",
+			input:  "hello, world",
+			output: "uryyb, jbeyq",
+			set1:   "a-zA-Z", set2: "n-za-mN-ZA-M",
+		},
+		{
+			name:   "class_to_range",
+			input:  "a1b2c9",
+			output: "aBbCcJ",
+			set1:   "[:digit:]", set2: "A-J",
+		},
+		{
+			name:   "repeat",
+			input:  "abcdef",
+			output: "xxxxxf",
+			set1:   "a-e", set2: "[x*]",
+		},
+		{
+			name:   "delete",
+			input:  "hello, world\n",
+			output: "he, wd\n",
+			set1:   "lor", nsets: 1, delete: true,
+		},
+		{
+			name:   "delete_complement",
+			input:  "a1b2 c3\n",
+			output: "123",
+			set1:   "[:digit:]", nsets: 1, delete: true, complement: true,
+		},
+		{
+			name:   "squeeze",
+			input:  "aaabbbccc  dd\n",
+			output: "abccc dd\n",
+			set1:   "ab ", nsets: 1, squeeze: true,
+		},
+		{
+			name:   "squeeze_complement",
+			input:  "aa..bb,,\n",
+			output: "aa.bb,\n",
+			set1:   "[:alpha:]\n", nsets: 1, squeeze: true, complement: true,
+		},
+		{
+			name:   "translate_squeeze",
+			input:  "one  two\t\tthree\n",
+			output: "one\ntwo\nthree\n",
+			set1:   "[:space:]", set2: "\n", squeeze: true,
+		},
+		{
+			name:   "complement_translate",
+			input:  "ab,cd;e\n",
+			output: "ab\ncd\ne\n",
+			set1:   "a-z", set2: "\n", complement: true,
+		},
+		{
+			name:   "delete_squeeze",
+			input:  "a1b22bb\n",
+			output: "ab\n",
+			set1:   "[:digit:]", set2: "b", delete: true, squeeze: true,
+		},
+		{
+			name:   "octal",
+			input:  "a:b",
+			output: "a\x00b",
+			set1:   "\\072", set2: "\\0",
+		},
+		{
+			name:   "invalid_utf8",
+			input:  "a\xffa",
+			output: "b\xffb",
+			set1:   "a", set2: "b",
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
+			nsets := test.nsets
+			if nsets == 0 {
+				nsets = 2
+			}
+			tr, err := newTransformer(test.set1, test.set2, nsets, test.delete, test.squeeze, test.complement)
+			if err != nil {
+				t.Fatalf("newTransformer() = %v", err)
+			}
 			out := &bytes.Buffer{}
-			test.t.run(bytes.NewBufferString(test.input), out)
+			if err := tr.run(bytes.NewBufferString(test.input), out); err != nil {
+				t.Fatal(err)
+			}
 			res := out.String()
 			if test.output != res {
 				t.Errorf("run() want %q, got %q", test.output, res)
@@ -123,6 +258,22 @@ func TestTR(t *testing.T) {
 	}
 }
 
+func TestTransformerErrors(t *testing.T) {
+	for _, test := range []struct {
+		set1, set2 string
+	}{
+		{"abc", ""},
+		{"a-z", "[:digit:]"},
+		{"a[:lower:]", "[:upper:]"},
+		{"[:lower:]", "[:lower:]"},
+		{"abc", "[x*][y*]"},
+	} {
+		if _, err := newTransformer(test.set1, test.set2, 2, false, false, false); err == nil {
+			t.Errorf("newTransformer(%q, %q) succeeded, want an error", test.set1, test.set2)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
@@ -5,11 +5,20 @@
 // Unmount a filesystem at the specified path.
 //
 // Synopsis:
-//     umount [-f | -l] PATH
+//     umount [-f | -l] [-R] PATH...
+//     umount [-f | -l] -a
+//
+// Description:
+//     The mount table, /proc/self/mounts, is read to unmount filesystems
+//     in the reverse order they were mounted. -a skips / and pseudo
+//     filesystems like proc and sysfs. Failures are reported and the
+//     other filesystems are still unmounted.
 //
 // Options:
 //     -f: force unmount
 //     -l: lazy unmount
+//     -a: unmount all filesystems
+//     -R: unmount PATH and all filesystems mounted below it
 package main
 
 import "log"
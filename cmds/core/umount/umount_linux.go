@@ -7,21 +7,103 @@ package main
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/u-root/u-root/pkg/mount"
 )
 
 var (
-	force = flag.Bool("f", false, "Force unmount")
-	lazy  = flag.Bool("l", false, "Lazy unmount")
+	force     = flag.Bool("f", false, "Force unmount")
+	lazy      = flag.Bool("l", false, "Lazy unmount")
+	all       = flag.Bool("a", false, "Unmount all filesystems, except / and pseudo filesystems")
+	recursive = flag.Bool("R", false, "Unmount the filesystems mounted below the paths too")
 )
 
+// Overridden in tests.
+var (
+	mountsFile = "/proc/self/mounts"
+	unmount    = mount.Unmount
+)
+
+// below returns whether path is dir or below it.
+func below(path, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// targets returns the mount points to unmount, in order: those of all
+// filesystems if all is set, else paths and, if recursive is set, the
+// mount points below them, latest mounts first.
+func targets(mounts []mount.Entry, paths []string, all, recursive bool) []string {
+	var t []string
+	if !all && !recursive {
+		return paths
+	}
+	for i := len(mounts) - 1; i >= 0; i-- {
+		m := mounts[i]
+		if all {
+			if m.Target != "/" && !mount.PseudoFS[m.FSType] {
+				t = append(t, m.Target)
+			}
+			continue
+		}
+		for _, p := range paths {
+			if below(m.Target, p) {
+				t = append(t, m.Target)
+				break
+			}
+		}
+	}
+	return t
+}
+
 func umount() error {
 	flag.Parse()
 	a := flag.Args()
-	if len(a) != 1 {
-		return errors.New("usage: umount [-f | -l] path")
+	if *all == (len(a) > 0) {
+		return errors.New("usage: umount [-f | -l] [-R] PATH... | umount [-f | -l] -a")
+	}
+	var paths []string
+	for _, p := range a {
+		// Mount points in the table are absolute and clean.
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		paths = append(paths, p)
+	}
+
+	var mounts []mount.Entry
+	if *all || *recursive {
+		f, err := os.Open(mountsFile)
+		if err != nil {
+			return err
+		}
+		mounts, err = mount.ParseTable(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", mountsFile, err)
+		}
+	}
+	t := targets(mounts, paths, *all, *recursive)
+	if len(t) == 0 && *recursive {
+		return fmt.Errorf("%v: not mounted", a)
+	}
+
+	if len(t) == 1 {
+		return unmount(t[0], *force, *lazy)
+	}
+	var failed bool
+	for _, p := range t {
+		if err := unmount(p, *force, *lazy); err != nil {
+			log.Print(err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some filesystems could not be unmounted")
 	}
-	path := a[0]
-	return mount.Unmount(path, *force, *lazy)
+	return nil
 }
@@ -0,0 +1,64 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/mount"
+)
+
+const mountTable = `/dev/root / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+devtmpfs /dev devtmpfs rw,nosuid 0 0
+/dev/sda1 /mnt ext4 rw 0 0
+/dev/sda2 /mnt/my\040data vfat rw 0 0
+tmpfs /mnt/my\040data/tmp tmpfs rw 0 0
+/dev/sdb1 /mnt2 xfs rw 0 0
+tmpfs /mnt tmpfs rw 0 0
+`
+
+func TestTargets(t *testing.T) {
+	m, err := mount.ParseTable(strings.NewReader(mountTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		paths     []string
+		all       bool
+		recursive bool
+		want      []string
+	}{
+		{
+			paths: []string{"/mnt"},
+			want:  []string{"/mnt"},
+		},
+		{
+			all:  true,
+			want: []string{"/mnt", "/mnt2", "/mnt/my data/tmp", "/mnt/my data", "/mnt"},
+		},
+		{
+			paths:     []string{"/mnt"},
+			recursive: true,
+			want:      []string{"/mnt", "/mnt/my data/tmp", "/mnt/my data", "/mnt"},
+		},
+		{
+			paths:     []string{"/mnt/my data", "/mnt2"},
+			recursive: true,
+			want:      []string{"/mnt2", "/mnt/my data/tmp", "/mnt/my data"},
+		},
+		{
+			paths:     []string{"/mn"},
+			recursive: true,
+		},
+	} {
+		if got := targets(m, tt.paths, tt.all, tt.recursive); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("targets(%q, all %t, recursive %t) = %q, want %q", tt.paths, tt.all, tt.recursive, got, tt.want)
+		}
+	}
+}
@@ -5,7 +5,7 @@
 // Uniq removes repeated lines.
 //
 // Synopsis:
-//     uniq [OPTIONS...] [FILES]...
+//     uniq [-c] [-d | -u] [-i] [-f num] [-s num] [FILES]...
 //
 // Description:
 //     Uniq copies the input file, or the standard input, to the standard
@@ -13,15 +13,18 @@
 //     succeeding copies of repeated lines are removed. Repeated lines must be
 //     adjacent in order to be found.
 //
+//     The -f, -s and -i options only change how lines are compared; the
+//     first line of each group of repeated lines is printed as it is.
+//
 // Options:
 //     –u:      Print unique lines.
 //     –d:      Print (one copy of) duplicated lines.
 //     –c:      Prefix a repetition count and a tab to each output line.
-//              Implies –u and –d.
+//     -i:      Ignore case when comparing lines.
 //     –f num:  The first num fields together with any blanks before each are
 //              ignored. A field is defined as a string of non–space, non–tab
 //              characters separated by tabs and spaces from its neighbors.
-//     -cn num: The first num characters are ignored. Fields are skipped before
+//     -s num:  The first num characters are ignored. Fields are skipped before
 //              characters.
 package main
 
@@ -39,60 +42,94 @@ import (
 	"os"
 )
 
-var uniques = flag.Bool("u", false, "print unique lines")
-var duplicates = flag.Bool("d", false, "print one copy of duplicated lines")
-var count = flag.Bool("c", false, "prefix a repetition count and a tab for each output line")
+var (
+	uniques    = flag.Bool("u", false, "print unique lines")
+	duplicates = flag.Bool("d", false, "print one copy of duplicated lines")
+	count      = flag.Bool("c", false, "prefix a repetition count and a tab for each output line")
+	ignoreCase = flag.Bool("i", false, "ignore case when comparing lines")
+	fields     = flag.Int("f", 0, "ignore num fields from beginning of line")
+	chars      = flag.Int("s", 0, "ignore num characters from beginning of line")
+)
 
-//var fnum = flag.Int("f", 0, "ignore num fields from beginning of line")
-//var cnum = flag.Int("cn", 0, "ignore num characters from beginning of line")
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
 
-func uniq(f *os.File) {
-	br := bufio.NewReader(f)
+// key returns the part of line that is compared: line without its newline,
+// the first fields fields and then the first chars characters.
+func key(line []byte, fields, chars int) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	for ; fields > 0; fields-- {
+		i := 0
+		for i < len(line) && isBlank(line[i]) {
+			i++
+		}
+		for i < len(line) && !isBlank(line[i]) {
+			i++
+		}
+		line = line[i:]
+	}
+	if chars > len(line) {
+		chars = len(line)
+	}
+	return line[chars:]
+}
 
-	var err error
-	var oline, line []byte
-	cnt := 1
-	isLast := false
-	for {
-		line, err = br.ReadBytes('\n')
-		if err == io.EOF {
-			isLast = true
-		} else if err != nil {
-			log.Printf("Can't read the %v line of %v file: %v", line, f, err)
+// equal returns whether the keys a and b are the same.
+func equal(a, b []byte) bool {
+	if *ignoreCase {
+		return bytes.EqualFold(a, b)
+	}
+	return bytes.Equal(a, b)
+}
+
+// uniq copies r to w, printing each group of adjacent repeated lines once,
+// or not at all, depending on the flags.
+func uniq(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var group []byte
+	cnt := 0
+	flush := func() error {
+		if cnt == 0 || (cnt > 1 && *uniques) || (cnt == 1 && *duplicates) {
+			return nil
 		}
-		if oline == nil {
-			oline = line
-			continue
+		if *count {
+			fmt.Fprintf(bw, "%d\t", cnt)
 		}
-		if !bytes.Equal(line, oline) {
-			if *count {
-				fmt.Printf("%d\t%s", cnt, oline)
-				goto skip
-			}
-			if cnt > 1 && *uniques {
-				goto skip
-			}
-			if cnt == 1 && *duplicates {
-				goto skip
+		if _, err := bw.Write(group); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(group, []byte("\n")) {
+			return bw.WriteByte('\n')
+		}
+		return nil
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if cnt > 0 && equal(key(group, *fields, *chars), key(line, *fields, *chars)) {
+				cnt++
+			} else {
+				if err := flush(); err != nil {
+					return err
+				}
+				group, cnt = line, 1
 			}
-			fmt.Printf("%s", oline)
-		skip:
-			oline = line
-			cnt = 1
-		} else {
-			cnt++
 		}
-		if isLast {
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return err
+		}
 	}
-	if cnt > 1 && *uniques {
-		return
-	}
-	if cnt == 1 && *duplicates {
-		return
+	if err := flush(); err != nil {
+		return err
 	}
-	fmt.Printf("%s", line)
+	return bw.Flush()
 }
 
 func main() {
@@ -105,10 +142,13 @@ func main() {
 				log.Printf("open %s: %v\n", fn, err)
 				os.Exit(1)
 			}
-			uniq(f)
+			err = uniq(f, os.Stdout)
 			f.Close()
+			if err != nil {
+				log.Fatalf("%s: %v", fn, err)
+			}
 		}
-	} else {
-		uniq(os.Stdin)
+	} else if err := uniq(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
 	}
 }
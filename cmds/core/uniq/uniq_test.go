@@ -17,6 +17,7 @@ func TestUniq(t *testing.T) {
 	var (
 		input1 = "test\ntest\ngo\ngo\ngo\ncoool\ncoool\ncool\nlegaal\ntest\n"
 		input2 = "u-root\nuniq\nron\nron\nteam\nbinaries\ntest\n\n\n\n\n\n"
+		input3 = "a 1 x\nB 1 x\nc 1 X\nd 2 y\n"
 		tab    = []struct {
 			i string
 			o string
@@ -31,6 +32,17 @@ func TestUniq(t *testing.T) {
 			{input2, "1\tu-root\n1\tuniq\n2\tron\n1\tteam\n1\tbinaries\n1\ttest\n5\t\n", 0, []string{"-c"}},
 			{input2, "u-root\nuniq\nteam\nbinaries\ntest\n", 0, []string{"-u"}},
 			{input2, "ron\n\n", 0, []string{"-d"}},
+			{input3, input3, 0, nil},
+			{input3, "a 1 x\nc 1 X\nd 2 y\n", 0, []string{"-f", "1"}},
+			{input3, "2\ta 1 x\n1\tc 1 X\n1\td 2 y\n", 0, []string{"-c", "-f", "1"}},
+			{input3, "a 1 x\nc 1 X\nd 2 y\n", 0, []string{"-s", "2"}},
+			{input3, "a 1 x\nc 1 X\nd 2 y\n", 0, []string{"-f", "2"}},
+			{input3, "a 1 x\nd 2 y\n", 0, []string{"-i", "-f", "1"}},
+			{input3, "3\ta 1 x\n1\td 2 y\n", 0, []string{"-c", "-i", "-f", "1", "-s", "3"}},
+			{input3, "c 1 X\nd 2 y\n", 0, []string{"-u", "-f", "1"}},
+			{input3, "a 1 x\n", 0, []string{"-d", "-s", "2"}},
+			{input3, "a 1 x\n", 0, []string{"-d", "-i", "-f", "2"}},
+			{"a\nA\nb", "a\nb\n", 0, []string{"-i"}},
 		}
 	)
 
@@ -55,6 +67,27 @@ func TestUniq(t *testing.T) {
 	}
 }
 
+func TestKey(t *testing.T) {
+	for _, tt := range []struct {
+		line   string
+		fields int
+		chars  int
+		want   string
+	}{
+		{"a b c\n", 0, 0, "a b c"},
+		{"a b c\n", 1, 0, " b c"},
+		{"  a\tb c", 2, 0, " c"},
+		{"a b c\n", 1, 1, "b c"},
+		{"a b c\n", 0, 2, "b c"},
+		{"a b c\n", 5, 0, ""},
+		{"a b c\n", 1, 10, ""},
+	} {
+		if got := string(key([]byte(tt.line), tt.fields, tt.chars)); got != tt.want {
+			t.Errorf("key(%q, %d, %d) = %q, want %q", tt.line, tt.fields, tt.chars, got, tt.want)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
@@ -20,69 +20,164 @@
 //     it is not possible to use `syscall.Unshare` from Go with any reasonable
 //     expectation of success.
 //
+//     The namespaces are created when PROGRAM is started, so PROGRAM is
+//     always run in a new process, as if with -fork, and is PID 1 of a new
+//     pid namespace.
+//
+//     In a new user namespace, -map-user and -map-group map the current
+//     user and group IDs to USER and GROUP, which are names or numeric IDs.
+//     -map-root-user maps them to root. Mapping the group disables
+//     setgroups(2) in the namespace, as the kernel requires of unprivileged
+//     users.
+//
 //     If PROGRAM is not specified, unshare defaults to /ubin/elvish.
 //
 // Options:
-//     -ipc:           Unshare the IPC namespace
-//     -mount:         Unshare the mount namespace
-//     -pid:           Unshare the pid namespace
-//     -net:           Unshare the net namespace
-//     -uts:           Unshare the uts namespace
-//     -user:          Unshare the user namespace
-//     -map-root-user: Map current uid to root. Not working
+//     -ipc:             Unshare the IPC namespace
+//     -mount:           Unshare the mount namespace
+//     -pid:             Unshare the pid namespace
+//     -net:             Unshare the net namespace
+//     -uts:             Unshare the uts namespace
+//     -user:            Unshare the user namespace
+//     -fork:            Run PROGRAM in a new process; always the case
+//     -map-root-user:   Map the current user and group IDs to root; implies
+//                       -user
+//     -map-user USER:   Map the current user ID to USER; implies -user
+//     -map-group GROUP: Map the current group ID to GROUP; implies -user
 package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"strconv"
 	"syscall"
+
+	"github.com/u-root/u-root/pkg/getent"
 )
 
 var (
-	ipc   = flag.Bool("ipc", false, "Unshare the IPC namespace")
-	mount = flag.Bool("mount", false, "Unshare the mount namespace")
-	pid   = flag.Bool("pid", false, "Unshare the pid namespace")
-	net   = flag.Bool("net", false, "Unshare the net namespace")
-	uts   = flag.Bool("uts", false, "Unshare the uts namespace")
-	user  = flag.Bool("user", false, "Unshare the user namespace")
+	ipc      = flag.Bool("ipc", false, "Unshare the IPC namespace")
+	mount    = flag.Bool("mount", false, "Unshare the mount namespace")
+	pid      = flag.Bool("pid", false, "Unshare the pid namespace")
+	net      = flag.Bool("net", false, "Unshare the net namespace")
+	uts      = flag.Bool("uts", false, "Unshare the uts namespace")
+	user     = flag.Bool("user", false, "Unshare the user namespace")
+	_        = flag.Bool("fork", true, "Run PROGRAM in a new process; always the case")
+	mapRoot  = flag.Bool("map-root-user", false, "Map the current user and group IDs to root; implies -user")
+	mapUser  = flag.String("map-user", "", "Map the current user ID to USER; implies -user")
+	mapGroup = flag.String("map-group", "", "Map the current group ID to GROUP; implies -user")
 )
 
-func main() {
-	flag.Parse()
+// options are the namespaces to unshare and the IDs to map in a new user
+// namespace, which are -1 if they are not mapped.
+type options struct {
+	cloneflags uintptr
+	uid, gid   int
+}
 
-	a := flag.Args()
-	if len(a) == 0 {
-		a = []string{"/ubin/elvish", "elvish"}
+// lookupID returns the ID of a user or group name, or of a numeric ID, in
+// the entries of a database.
+func lookupID(s string, find func(string) (int, bool)) (int, error) {
+	if id, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return int(id), nil
+	}
+	if id, ok := find(s); ok {
+		return id, nil
 	}
+	return 0, fmt.Errorf("%q not found", s)
+}
 
-	c := exec.Command(a[0], a[1:]...)
-	c.SysProcAttr = &syscall.SysProcAttr{}
-	if *mount {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
+func findUser(name string) (int, bool) {
+	pw, _ := getent.ReadPasswd(getent.PasswdFile)
+	p, ok := getent.FindPasswd(pw, name)
+	return p.UID, ok
+}
+
+func findGroup(name string) (int, bool) {
+	gr, _ := getent.ReadGroup(getent.GroupFile)
+	g, ok := getent.FindGroup(gr, name)
+	return g.GID, ok
+}
+
+// sysProcAttr returns the attributes of a process started in the
+// namespaces of o. The current user and group IDs, uid and gid, are mapped
+// as o says.
+func sysProcAttr(o options, uid, gid int) *syscall.SysProcAttr {
+	a := &syscall.SysProcAttr{Cloneflags: o.cloneflags}
+	if o.uid != -1 {
+		a.UidMappings = []syscall.SysProcIDMap{{ContainerID: o.uid, HostID: uid, Size: 1}}
 	}
-	if *uts {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUTS
+	if o.gid != -1 {
+		a.GidMappings = []syscall.SysProcIDMap{{ContainerID: o.gid, HostID: gid, Size: 1}}
+		a.GidMappingsEnableSetgroups = false
 	}
-	if *ipc {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWIPC
+	if a.UidMappings != nil || a.GidMappings != nil {
+		a.Cloneflags |= syscall.CLONE_NEWUSER
 	}
-	if *net {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	return a
+}
+
+func parseOptions() (options, error) {
+	o := options{uid: -1, gid: -1}
+	for _, ns := range []struct {
+		set  bool
+		flag uintptr
+	}{
+		{*mount, syscall.CLONE_NEWNS},
+		{*uts, syscall.CLONE_NEWUTS},
+		{*ipc, syscall.CLONE_NEWIPC},
+		{*net, syscall.CLONE_NEWNET},
+		{*pid, syscall.CLONE_NEWPID},
+		{*user, syscall.CLONE_NEWUSER},
+	} {
+		if ns.set {
+			o.cloneflags |= ns.flag
+		}
+	}
+	if *mapRoot {
+		o.uid, o.gid = 0, 0
+	}
+	var err error
+	if *mapUser != "" {
+		if o.uid, err = lookupID(*mapUser, findUser); err != nil {
+			return o, fmt.Errorf("map-user: %v", err)
+		}
 	}
-	if *pid {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWPID
+	if *mapGroup != "" {
+		if o.gid, err = lookupID(*mapGroup, findGroup); err != nil {
+			return o, fmt.Errorf("map-group: %v", err)
+		}
+	}
+	return o, nil
+}
+
+func main() {
+	flag.Parse()
+
+	o, err := parseOptions()
+	if err != nil {
+		log.Fatal(err)
 	}
-	if *user {
-		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+
+	a := flag.Args()
+	if len(a) == 0 {
+		a = []string{"/ubin/elvish", "elvish"}
 	}
 
+	c := exec.Command(a[0], a[1:]...)
+	c.SysProcAttr = sysProcAttr(o, os.Getuid(), os.Getgid())
+
 	c.Stdin = os.Stdin
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
 
 	if err := c.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() >= 0 {
+			os.Exit(ee.ExitCode())
+		}
 		log.Fatalf("%v", err)
 	}
 }
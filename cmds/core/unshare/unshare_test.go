@@ -0,0 +1,71 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestSysProcAttr(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		o    options
+		want *syscall.SysProcAttr
+	}{
+		{
+			name: "no user namespace",
+			o:    options{cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID, uid: -1, gid: -1},
+			want: &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID},
+		},
+		{
+			name: "user namespace without maps",
+			o:    options{cloneflags: syscall.CLONE_NEWUSER, uid: -1, gid: -1},
+			want: &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWUSER},
+		},
+		{
+			name: "root",
+			o:    options{cloneflags: syscall.CLONE_NEWNET, uid: 0, gid: 0},
+			want: &syscall.SysProcAttr{
+				Cloneflags:  syscall.CLONE_NEWNET | syscall.CLONE_NEWUSER,
+				UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 1000, Size: 1}},
+				GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: 100, Size: 1}},
+			},
+		},
+		{
+			name: "user only",
+			o:    options{uid: 42, gid: -1},
+			want: &syscall.SysProcAttr{
+				Cloneflags:  syscall.CLONE_NEWUSER,
+				UidMappings: []syscall.SysProcIDMap{{ContainerID: 42, HostID: 1000, Size: 1}},
+			},
+		},
+	} {
+		if got := sysProcAttr(tt.o, 1000, 100); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: sysProcAttr() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLookupID(t *testing.T) {
+	find := func(name string) (int, bool) { return 7, name == "wheel" }
+	for _, tt := range []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1000", 1000, true},
+		{"wheel", 7, true},
+		{"nobody", 0, false},
+		{"-1", 0, false},
+	} {
+		got, err := lookupID(tt.in, find)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("lookupID(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -4,11 +4,21 @@
 
 // Get the time the machine has been up
 // Synopsis:
-//     uptime
+//     uptime [-p] [-s]
+//
+// Description:
+//     Print the current time, how long the system has been up, the number
+//     of users and the load averages of the last 1, 5 and 15 minutes.
+//     u-root does not record logins, so there are always 0 users.
+//
+// Options:
+//     -p: only print how long the system has been up, in a pretty form
+//     -s: only print when the system was booted, as yyyy-mm-dd HH:MM:SS
 package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -16,13 +26,18 @@ import (
 	"time"
 )
 
+var (
+	pretty = flag.Bool("p", false, "Only print the uptime, in a pretty form")
+	since  = flag.Bool("s", false, "Only print when the system was booted")
+)
+
 // loadavg takes in the contents of proc/loadavg,it then extracts and returns the three load averages as a string
 func loadavg(contents string) (loadaverage string, err error) {
 	loadavg := strings.Fields(contents)
 	if len(loadavg) < 3 {
 		return "", fmt.Errorf("error:invalid contents:the contents of proc/loadavg we are trying to process contain less than the required 3 loadavgs")
 	}
-	return loadavg[0] + "," + loadavg[1] + "," + loadavg[2], nil
+	return loadavg[0] + ", " + loadavg[1] + ", " + loadavg[2], nil
 }
 
 // uptime takes in the contents of proc/uptime it then extracts and returns the uptime in the format Days , Hours , Minutes ,Seconds
@@ -40,7 +55,62 @@ func uptime(contents string) (*time.Time, error) {
 	return &uptime, nil
 }
 
+func plural(n int, s string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, s)
+	}
+	return fmt.Sprintf("%d %ss", n, s)
+}
+
+// classic returns the traditional uptime line, e.g.
+//  10:15:02 up 14 days,  5:35,  0 users,  load average: 0.60, 0.70, 0.74
+func classic(now time.Time, up time.Duration, users int, load string) string {
+	s := " " + now.Format("15:04:05") + " up "
+	mins := int(up / time.Minute)
+	days, hours, mins := mins/(24*60), mins/60%24, mins%60
+	if days > 0 {
+		s += plural(days, "day") + ", "
+	}
+	if hours > 0 {
+		s += fmt.Sprintf("%2d:%02d, ", hours, mins)
+	} else {
+		s += fmt.Sprintf("%d min, ", mins)
+	}
+	s += fmt.Sprintf("%2d user", users)
+	if users != 1 {
+		s += "s"
+	}
+	return s + ",  load average: " + load
+}
+
+// prettyUptime returns the uptime like "up 2 weeks, 1 day, 5 minutes",
+// leaving out units that are 0.
+func prettyUptime(up time.Duration) string {
+	mins := int(up / time.Minute)
+	var parts []string
+	for _, u := range []struct {
+		name string
+		mins int
+	}{
+		{"year", 365 * 24 * 60},
+		{"week", 7 * 24 * 60},
+		{"day", 24 * 60},
+		{"hour", 60},
+		{"minute", 1},
+	} {
+		if n := mins / u.mins; n > 0 {
+			parts = append(parts, plural(n, u.name))
+			mins %= u.mins
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"0 minutes"}
+	}
+	return "up " + strings.Join(parts, ", ")
+}
+
 func main() {
+	flag.Parse()
 	procUptimeOutput, err := ioutil.ReadFile("/proc/uptime")
 	if err != nil {
 		log.Fatalf("error reading /proc/uptime: %v \n", err)
@@ -49,6 +119,16 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	up := uptimeTime.Sub(time.Time{})
+	now := time.Now()
+	switch {
+	case *since:
+		fmt.Println(now.Add(-up).Format("2006-01-02 15:04:05"))
+		return
+	case *pretty:
+		fmt.Println(prettyUptime(up))
+		return
+	}
 	procLoadAvgOutput, err := ioutil.ReadFile("/proc/loadavg")
 	if err != nil {
 		log.Fatalf("error reading /proc/loadavg: %v \n", err)
@@ -57,6 +137,5 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	//Subtracted one from time.Day() because time.Add(Duration) starts counting at 1 day instead of zero days.
-	fmt.Printf(" %s up %d days, %d hours , %d min ,loadaverage: %s \n", time.Now().Format("15:04:05"), (uptimeTime.Day() - 1), uptimeTime.Hour(), uptimeTime.Minute(), loadAverage)
+	fmt.Println(classic(now, up, 0, loadAverage))
 }
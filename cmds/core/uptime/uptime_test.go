@@ -71,7 +71,7 @@ func TestLoadAverage(t *testing.T) {
 		loadAverage string
 		err         string
 	}{
-		{"goodInput", "0.60 0.70 0.74", "0.60,0.70,0.74", ""},
+		{"goodInput", "0.60 0.70 0.74", "0.60, 0.70, 0.74", ""},
 		{"badDataInput", "1.00 2.00", "", "error:invalid contents:the contents of proc/loadavg we are trying to process contain less than the required 3 loadavgs"},
 	}
 
@@ -93,3 +93,38 @@ func TestLoadAverage(t *testing.T) {
 		})
 	}
 }
+
+func TestClassic(t *testing.T) {
+	now := time.Date(2021, 5, 1, 10, 15, 2, 0, time.UTC)
+	for _, tt := range []struct {
+		up    time.Duration
+		users int
+		want  string
+	}{
+		{42 * time.Second, 0, " 10:15:02 up 0 min,  0 users,  load average: 0.60, 0.70, 0.74"},
+		{5*time.Hour + 35*time.Minute, 1, " 10:15:02 up  5:35,  1 user,  load average: 0.60, 0.70, 0.74"},
+		{24*time.Hour + 7*time.Minute, 0, " 10:15:02 up 1 day, 7 min,  0 users,  load average: 0.60, 0.70, 0.74"},
+		{14*24*time.Hour + 12*time.Hour, 0, " 10:15:02 up 14 days, 12:00,  0 users,  load average: 0.60, 0.70, 0.74"},
+	} {
+		if got := classic(now, tt.up, tt.users, "0.60, 0.70, 0.74"); got != tt.want {
+			t.Errorf("classic(%v, %d) = %q, want %q", tt.up, tt.users, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyUptime(t *testing.T) {
+	for _, tt := range []struct {
+		up   time.Duration
+		want string
+	}{
+		{30 * time.Second, "up 0 minutes"},
+		{time.Minute, "up 1 minute"},
+		{5*time.Hour + 35*time.Minute, "up 5 hours, 35 minutes"},
+		{15*24*time.Hour + 2*time.Minute, "up 2 weeks, 1 day, 2 minutes"},
+		{400 * 24 * time.Hour, "up 1 year, 5 weeks"},
+	} {
+		if got := prettyUptime(tt.up); got != tt.want {
+			t.Errorf("prettyUptime(%v) = %q, want %q", tt.up, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@
 //
 // Synopsis:
 //     wc [OPTIONS...] [FILES]...
+//     wc [OPTIONS...] --files0-from=FILE
 //
 // Description:
 //     Wc counts lines, words, runes, syntactically–invalid UTF codes and bytes
@@ -16,12 +17,17 @@
 //     codes or bytes) are selected by the letters l, w, r, b, or c. Otherwise,
 //     lines, words and bytes (–lwc) are reported.
 //
+//     A file named - is the standard input. With more than one file, a total
+//     is reported last, and the counts are aligned in columns.
+//
 // Options:
 //     –l: count lines
 //     –w: count words
 //     –r: count runes
 //     –b: count broken UTF codes
 //     -c: count bytes
+//     --files0-from=FILE: count the files named in FILE, separated by NUL
+//         characters, as find -print0 prints them; - is the standard input
 //
 // Bugs:
 //     This wc differs from Plan 9's wc somewhat in word count (BSD's wc differs
@@ -45,9 +51,12 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
 	"os"
 	"strings"
 	"unicode/utf8"
@@ -58,6 +67,7 @@ var words = flag.Bool("w", false, "count words")
 var runes = flag.Bool("r", false, "count runes")
 var broken = flag.Bool("b", false, "count broken")
 var chars = flag.Bool("c", false, "count bytes (include partial UTF)")
+var files0From = flag.String("files0-from", "", "count the files named in `FILE`, separated by NUL characters")
 
 type cnt struct {
 	nline, nword, nrune, nbadr, nchar int64
@@ -108,60 +118,158 @@ func count(in io.Reader, fname string) (c cnt) {
 	return
 }
 
-func report(c cnt, fname string) {
-	fields := []string{}
+func (c *cnt) add(o cnt) {
+	c.nline += o.nline
+	c.nword += o.nword
+	c.nrune += o.nrune
+	c.nbadr += o.nbadr
+	c.nchar += o.nchar
+}
+
+// selected returns the counts selected by the flags.
+func (c cnt) selected() []int64 {
+	var v []int64
 	if *lines {
-		fields = append(fields, fmt.Sprintf("%d", c.nline))
+		v = append(v, c.nline)
 	}
 	if *words {
-		fields = append(fields, fmt.Sprintf("%d", c.nword))
+		v = append(v, c.nword)
 	}
 	if *runes {
-		fields = append(fields, fmt.Sprintf("%d", c.nrune))
+		v = append(v, c.nrune)
 	}
 	if *broken {
-		fields = append(fields, fmt.Sprintf("%d", c.nbadr))
+		v = append(v, c.nbadr)
 	}
 	if *chars {
-		fields = append(fields, fmt.Sprintf("%d", c.nchar))
+		v = append(v, c.nchar)
+	}
+	return v
+}
+
+// width returns the width of the widest count of c, so that the counts of
+// the files, which are at most the total c, line up.
+func width(c cnt) int {
+	w := 1
+	for _, v := range c.selected() {
+		if n := len(fmt.Sprint(v)); n > w {
+			w = n
+		}
+	}
+	return w
+}
+
+func report(w io.Writer, c cnt, fname string, width int) {
+	fields := []string{}
+	for _, v := range c.selected() {
+		fields = append(fields, fmt.Sprintf("%*d", width, v))
 	}
 	if fname != "" {
 		fields = append(fields, fname)
 	}
 
-	fmt.Println(strings.Join(fields, " "))
+	fmt.Fprintln(w, strings.Join(fields, " "))
 }
 
-func main() {
-	var totals cnt
+// readFiles0 returns the file names in r, which are separated by NUL
+// characters.
+func readFiles0(r io.Reader) ([]string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	names := strings.Split(strings.TrimSuffix(string(b), "\x00"), "\x00")
+	for i, n := range names {
+		if n == "" {
+			return nil, fmt.Errorf("invalid zero-length file name at position %d", i+1)
+		}
+	}
+	return names, nil
+}
+
+type result struct {
+	c     cnt
+	fname string
+}
 
+// run counts the files in names, or stdin if there are none, and reports
+// the counts and their total to stdout.
+func run(names []string, stdin io.Reader, stdout io.Writer) error {
+	if len(names) == 0 {
+		report(stdout, count(stdin, ""), "", 1)
+		return nil
+	}
+
+	var (
+		totals  cnt
+		results []result
+		failed  bool
+	)
+	for _, v := range names {
+		var c cnt
+		if v == "-" {
+			c = count(stdin, v)
+		} else {
+			f, err := os.Open(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error opening %s: %v\n", v, err)
+				failed = true
+				continue
+			}
+			c = count(f, v)
+			f.Close()
+		}
+		totals.add(c)
+		results = append(results, result{c, v})
+	}
+
+	wid := width(totals)
+	for _, r := range results {
+		report(stdout, r.c, r.fname, wid)
+	}
+	if len(names) > 1 {
+		report(stdout, totals, "total", wid)
+	}
+	if failed {
+		return errors.New("some files could not be counted")
+	}
+	return nil
+}
+
+func main() {
 	flag.Parse()
 
 	if !(*lines || *words || *runes || *broken || *chars) {
 		*lines, *words, *chars = true, true, true
 	}
 
-	if flag.NArg() == 0 {
-		cnt := count(os.Stdin, "")
-		report(cnt, "")
-		return
+	names := flag.Args()
+	if *files0From != "" {
+		if len(names) > 0 {
+			log.Fatalf("wc: file operands can not be combined with --files0-from")
+		}
+		var in io.Reader = os.Stdin
+		if *files0From != "-" {
+			f, err := os.Open(*files0From)
+			if err != nil {
+				log.Fatalf("wc: %v", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		var err error
+		if names, err = readFiles0(in); err != nil {
+			log.Fatalf("wc: %s: %v", *files0From, err)
+		}
+		if len(names) == 0 {
+			return
+		}
 	}
 
-	for _, v := range flag.Args() {
-		f, err := os.Open(v)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error opening %s: %v\n", v, err)
-			os.Exit(1)
-		}
-		cnt := count(f, v)
-		totals.nline += cnt.nline
-		totals.nword += cnt.nword
-		totals.nrune += cnt.nrune
-		totals.nbadr += cnt.nbadr
-		totals.nchar += cnt.nchar
-		report(cnt, v)
-	}
-	if flag.NArg() > 1 {
-		report(totals, "total")
+	if err := run(names, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("wc: %v", err)
 	}
 }
@@ -8,6 +8,9 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/u-root/u-root/pkg/testutil"
@@ -46,6 +49,64 @@ func TestWc(t *testing.T) {
 	}
 }
 
+func TestReadFiles0(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{in: "", want: nil},
+		{in: "a\x00b c\x00", want: []string{"a", "b c"}},
+		{in: "a\x00-", want: []string{"a", "-"}},
+		{in: "a\nb\x00", want: []string{"a\nb"}},
+		{in: "a\x00\x00b\x00", err: true},
+	} {
+		got, err := readFiles0(strings.NewReader(tt.in))
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readFiles0(%q) = %q, %v, want %q, error %t", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestWc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("one two\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte(strings.Repeat("x\n", 50)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	*lines, *words, *chars = true, true, true
+	defer func() { *lines, *words, *chars = false, false, false }()
+
+	for _, tt := range []struct {
+		names []string
+		stdin string
+		want  string
+		err   bool
+	}{
+		{names: nil, stdin: "a b\n", want: "1 2 4\n"},
+		{names: []string{a}, want: " 2  3 14 " + a + "\n"},
+		{names: []string{a, "-", b}, stdin: "s\n", want: "" +
+			"  2   3  14 " + a + "\n" +
+			"  1   1   2 -\n" +
+			" 50  50 100 " + b + "\n" +
+			" 53  54 116 total\n"},
+		{names: []string{a, filepath.Join(dir, "missing")}, want: " 2  3 14 " + a + "\n 2  3 14 total\n", err: true},
+	} {
+		var out bytes.Buffer
+		err := run(tt.names, strings.NewReader(tt.stdin), &out)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("run(%q) = %q, %v, want %q, error %t", tt.names, out.String(), err, tt.want, tt.err)
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	testutil.Run(m, main)
 }
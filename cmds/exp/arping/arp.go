@@ -0,0 +1,96 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ARP operations.
+const (
+	arpRequest = 1
+	arpReply   = 2
+)
+
+// arpLen is the length of an ARP packet for Ethernet and IPv4.
+const arpLen = 28
+
+var broadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// arpPacket is an ARP packet for Ethernet and IPv4 addresses.
+type arpPacket struct {
+	Op  uint16
+	SHA net.HardwareAddr
+	SPA net.IP
+	THA net.HardwareAddr
+	TPA net.IP
+}
+
+func (p *arpPacket) marshal() []byte {
+	b := make([]byte, 8, arpLen)
+	binary.BigEndian.PutUint16(b[0:], 1)      // Ethernet
+	binary.BigEndian.PutUint16(b[2:], 0x0800) // IPv4
+	b[4], b[5] = 6, 4
+	binary.BigEndian.PutUint16(b[6:], p.Op)
+	b = append(b, p.SHA...)
+	b = append(b, p.SPA.To4()...)
+	b = append(b, p.THA...)
+	return append(b, p.TPA.To4()...)
+}
+
+func parseARP(b []byte) (*arpPacket, error) {
+	if len(b) < arpLen {
+		return nil, errors.New("short ARP packet")
+	}
+	if binary.BigEndian.Uint16(b[0:]) != 1 || binary.BigEndian.Uint16(b[2:]) != 0x0800 || b[4] != 6 || b[5] != 4 {
+		return nil, errors.New("not an Ethernet and IPv4 ARP packet")
+	}
+	return &arpPacket{
+		Op:  binary.BigEndian.Uint16(b[6:]),
+		SHA: net.HardwareAddr(append([]byte(nil), b[8:14]...)),
+		SPA: net.IP(append([]byte(nil), b[14:18]...)),
+		THA: net.HardwareAddr(append([]byte(nil), b[18:24]...)),
+		TPA: net.IP(append([]byte(nil), b[24:28]...)),
+	}, nil
+}
+
+// request returns an ARP request from srcMAC and srcIP for target. In
+// duplicate address detection, srcIP is 0.0.0.0.
+func request(srcMAC net.HardwareAddr, srcIP, target net.IP) *arpPacket {
+	return &arpPacket{
+		Op:  arpRequest,
+		SHA: srcMAC,
+		SPA: srcIP,
+		THA: make(net.HardwareAddr, 6),
+		TPA: target,
+	}
+}
+
+// isAnswer returns whether p shows that target is in use. In duplicate
+// address detection, another host probing for target counts too.
+func isAnswer(p *arpPacket, target net.IP, dad bool) bool {
+	if p.Op == arpReply && p.SPA.Equal(target) {
+		return true
+	}
+	return dad && p.Op == arpRequest && p.TPA.Equal(target) && p.SPA.Equal(net.IPv4zero)
+}
+
+// stats counts probes and replies.
+type stats struct {
+	sent       int
+	broadcasts int
+	received   int
+}
+
+func (s *stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sent %d probes (%d broadcast(s))\n", s.sent, s.broadcasts)
+	fmt.Fprintf(&b, "Received %d response(s)\n", s.received)
+	return b.String()
+}
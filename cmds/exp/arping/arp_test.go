@@ -0,0 +1,82 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+var (
+	macA = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x0a}
+	macB = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x0b}
+)
+
+func TestMarshalParse(t *testing.T) {
+	p := request(macA, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"))
+	b := p.marshal()
+	want := []byte{
+		0, 1, 8, 0, 6, 4, 0, 1,
+		2, 0, 0, 0, 0, 0x0a, 10, 0, 0, 1,
+		0, 0, 0, 0, 0, 0, 10, 0, 0, 2,
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("marshal() = % x, want % x", b, want)
+	}
+	got, err := parseARP(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SPA, p.TPA = p.SPA.To4(), p.TPA.To4()
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("parseARP() = %+v, want %+v", got, p)
+	}
+
+	if _, err := parseARP(b[:20]); err == nil {
+		t.Error("parseARP() of a short packet = nil, want error")
+	}
+	b[2] = 0x86
+	if _, err := parseARP(b); err == nil {
+		t.Error("parseARP() of a non-IPv4 packet = nil, want error")
+	}
+}
+
+func TestIsAnswer(t *testing.T) {
+	target := net.ParseIP("10.0.0.2")
+	reply := &arpPacket{Op: arpReply, SHA: macB, SPA: target, THA: macA, TPA: net.ParseIP("10.0.0.1")}
+	otherReply := &arpPacket{Op: arpReply, SHA: macB, SPA: net.ParseIP("10.0.0.3"), THA: macA, TPA: net.ParseIP("10.0.0.1")}
+	probe := request(macB, net.IPv4zero, target)
+	query := request(macB, net.ParseIP("10.0.0.3"), target)
+
+	for _, tt := range []struct {
+		name string
+		p    *arpPacket
+		dad  bool
+		want bool
+	}{
+		{name: "reply", p: reply, want: true},
+		{name: "reply in DAD", p: reply, dad: true, want: true},
+		{name: "reply from another address", p: otherReply},
+		{name: "probe", p: probe},
+		{name: "probe in DAD", p: probe, dad: true, want: true},
+		{name: "query in DAD", p: query, dad: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAnswer(tt.p, target, tt.dad); got != tt.want {
+				t.Errorf("isAnswer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := stats{sent: 3, broadcasts: 1, received: 2}
+	want := "Sent 3 probes (1 broadcast(s))\nReceived 2 response(s)\n"
+	if got := s.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// arping sends ARP requests to a neighbour and reports its replies.
+//
+// Synopsis:
+//     arping -I INTERFACE [-c COUNT] [-w DEADLINE] [-s SOURCE] [-D] [-f] TARGET
+//
+// Description:
+//     Send an ARP request for the IPv4 address TARGET on INTERFACE every
+//     second, and print the MAC address and round trip time of each reply.
+//     Requests are broadcast until a reply arrives, then sent to the MAC
+//     address that replied.
+//
+//     With -D, do duplicate address detection: probe with the source address
+//     0.0.0.0 and stop at the first reply. arping exits with status 0 if
+//     TARGET is free, and 1 if it is in use.
+//
+//     Otherwise, arping exits with status 0 if any reply arrived, and 1 if
+//     none did.
+//
+// Options:
+//     -I: interface to send requests on
+//     -c: stop after sending COUNT requests, 0 for no limit
+//     -w: stop after DEADLINE seconds, 0 for no limit
+//     -s: source IPv4 address, the first address of INTERFACE by default
+//     -D: duplicate address detection
+//     -f: stop at the first reply
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const usage = "usage: arping -I INTERFACE [-c COUNT] [-w DEADLINE] [-s SOURCE] [-D] [-f] TARGET"
+
+var (
+	ifName   = flag.String("I", "", "Interface to send requests on")
+	count    = flag.Int("c", 0, "Stop after sending this many requests, 0 for no limit")
+	deadline = flag.Int("w", 0, "Stop after this many seconds, 0 for no limit")
+	source   = flag.String("s", "", "Source IPv4 address, the first address of the interface by default")
+	dad      = flag.Bool("D", false, "Duplicate address detection")
+	first    = flag.Bool("f", false, "Stop at the first reply")
+)
+
+// interval is the time between requests.
+var interval = time.Second
+
+// run returns these errors for the exit status, they are not printed.
+var (
+	errNoReply = errors.New("no reply")
+	errInUse   = errors.New("address in use")
+)
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// sourceIP returns the first IPv4 address of ifc.
+func sourceIP(ifc *net.Interface) (net.IP, error) {
+	addrs, err := ifc.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil {
+			return n.IP.To4(), nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no IPv4 address, use -s", ifc.Name)
+}
+
+type reply struct {
+	pkt *arpPacket
+	at  time.Time
+}
+
+// receive sends the ARP packets that arrive on fd, and are not sent by us,
+// to replies.
+func receive(fd int, self net.HardwareAddr, replies chan<- reply) {
+	b := make([]byte, 1500)
+	for {
+		n, from, err := unix.Recvfrom(fd, b, 0)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		if ll, ok := from.(*unix.SockaddrLinklayer); ok && ll.Pkttype == unix.PACKET_OUTGOING {
+			continue
+		}
+		p, err := parseARP(b[:n])
+		if err != nil || p.SHA.String() == self.String() {
+			continue
+		}
+		replies <- reply{pkt: p, at: time.Now()}
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) != 1 || *ifName == "" {
+		return errors.New(usage)
+	}
+	target := net.ParseIP(args[0]).To4()
+	if target == nil {
+		return fmt.Errorf("%q is not an IPv4 address", args[0])
+	}
+	ifc, err := net.InterfaceByName(*ifName)
+	if err != nil {
+		return err
+	}
+	if len(ifc.HardwareAddr) != 6 {
+		return fmt.Errorf("%s is not an Ethernet interface", ifc.Name)
+	}
+	src := net.IPv4zero.To4()
+	switch {
+	case *dad:
+	case *source != "":
+		if src = net.ParseIP(*source).To4(); src == nil {
+			return fmt.Errorf("%q is not an IPv4 address", *source)
+		}
+	default:
+		if src, err = sourceIP(ifc); err != nil {
+			return err
+		}
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return fmt.Errorf("opening packet socket: %v", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: ifc.Index}); err != nil {
+		return fmt.Errorf("binding packet socket: %v", err)
+	}
+	replies := make(chan reply)
+	go receive(fd, ifc.HardwareAddr, replies)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	var timeout <-chan time.Time
+	if *deadline > 0 {
+		timeout = time.After(time.Duration(*deadline) * time.Second)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	fmt.Fprintf(stdout, "ARPING %s from %s %s\n", target, src, ifc.Name)
+	var (
+		s      stats
+		dst    = broadcast
+		sentAt time.Time
+		// last fires one interval after the last request.
+		last <-chan time.Time
+	)
+	send := func() error {
+		pkt := request(ifc.HardwareAddr, src, target).marshal()
+		to := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: ifc.Index, Halen: 6}
+		copy(to.Addr[:], dst)
+		if err := unix.Sendto(fd, pkt, 0, to); err != nil {
+			return err
+		}
+		sentAt = time.Now()
+		s.sent++
+		if dst.String() == broadcast.String() {
+			s.broadcasts++
+		}
+		if *count > 0 && s.sent >= *count {
+			last = time.After(interval)
+		}
+		return nil
+	}
+	if err := send(); err != nil {
+		return err
+	}
+loop:
+	for {
+		select {
+		case r := <-replies:
+			if !isAnswer(r.pkt, target, *dad) {
+				continue
+			}
+			s.received++
+			kind := "Unicast"
+			if r.pkt.Op == arpRequest {
+				kind = "Probe"
+			} else if r.pkt.THA.String() == broadcast.String() {
+				kind = "Broadcast"
+			}
+			fmt.Fprintf(stdout, "%s reply from %s [%s]  %.3fms\n", kind, r.pkt.SPA, r.pkt.SHA, float64(r.at.Sub(sentAt))/float64(time.Millisecond))
+			if *dad || *first {
+				break loop
+			}
+			dst = r.pkt.SHA
+		case <-tick.C:
+			if last != nil {
+				continue
+			}
+			if err := send(); err != nil {
+				return err
+			}
+		case <-last:
+			break loop
+		case <-timeout:
+			break loop
+		case <-sig:
+			break loop
+		}
+	}
+	fmt.Fprint(stdout, s.String())
+
+	switch {
+	case *dad && s.received > 0:
+		return errInUse
+	case !*dad && s.received == 0:
+		return errNoReply
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("arping: ")
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		if err != errNoReply && err != errInUse {
+			log.Print(err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// awk processes the fields of lines, with a subset of the awk language.
+//
+// Synopsis:
+//     awk [-F FS] [-v VAR=VALUE]... PROGRAM [FILE | VAR=VALUE]...
+//     awk [-F FS] [-v VAR=VALUE]... -f PROGFILE... [FILE | VAR=VALUE]...
+//
+// Description:
+//     awk reads the FILEs, or stdin, line by line and splits each line into
+//     the fields $1 to $NF; $0 is the whole line. The program is a list of
+//     rules:
+//
+//     BEGIN { ACTION }:   run before reading input
+//     END { ACTION }:     run after reading input
+//     PATTERN { ACTION }: run for lines where PATTERN is true
+//     PATTERN:            print lines where PATTERN is true
+//     { ACTION }:         run for all lines
+//     P1, P2 { ACTION }:  run for the lines from P1 to P2
+//
+//     A pattern is an expression; /RE/ alone is $0 ~ /RE/. Actions are
+//     statements, separated by newlines or semicolons:
+//
+//     print [EXPR, ...], printf FORMAT[, EXPR...], EXPR, { ... },
+//     if (EXPR) STMT [else STMT], while (EXPR) STMT,
+//     do STMT while (EXPR), for (EXPR; EXPR; EXPR) STMT,
+//     break, continue, next, exit [EXPR]
+//
+//     Expressions are numbers, "strings", /RE/, variables, $EXPR, and
+//     from the lowest precedence: = += -= *= /= %= ^=, ?:, ||, &&, ~ !~,
+//     < <= == != >= >, concatenation, + -, * / %, unary ! + -, ^, ++ --.
+//     A comparison is numeric if both sides are numbers or input that
+//     looks like a number, and is a string comparison otherwise.
+//
+//     The variables NF, NR, FNR, FS, OFS, ORS, FILENAME, CONVFMT, OFMT,
+//     RSTART and RLENGTH are special. The functions are length, substr,
+//     index, match, sub, gsub, sprintf, tolower, toupper, int, sqrt, exp
+//     and log. REs are those of Go's regexp package.
+//
+//     Arrays, user defined functions, getline, output redirection and
+//     pipes, and record separators other than newline are not supported.
+//
+// Options:
+//     -F: the field separator FS, a single character or an RE; the
+//         default splits on blanks, and t is a tab
+//     -f: read the program from PROGFILE
+//     -v: set VAR to VALUE before running the program
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	fieldSep  = flag.StringP("field-separator", "F", "", "the field separator FS")
+	assigns   = flag.StringArrayP("assign", "v", nil, "set VAR to VALUE before running the program")
+	progFiles = flag.StringArrayP("file", "f", nil, "read the program from PROGFILE")
+)
+
+// stdin is the input when there are no files, or a file is -. Overridden in
+// tests.
+var stdin io.Reader = os.Stdin
+
+var assignRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// assignment sets a variable from VAR=VALUE, and returns whether arg is
+// one.
+func (in *interp) assignment(arg string) (bool, error) {
+	if !assignRE.MatchString(arg) {
+		return false, nil
+	}
+	i := strings.IndexByte(arg, '=')
+	name := arg[:i]
+	if keywords[name] || builtins[name] {
+		return false, fmt.Errorf("cannot assign to %s", name)
+	}
+	s, _, err := lexString(arg[i+1:], false)
+	if err != nil {
+		return false, err
+	}
+	in.setVar(name, strnum(s))
+	return true, nil
+}
+
+// run runs the program on the files and returns the exit status.
+func run(program string, fs string, vars, args []string, w io.Writer) (int, error) {
+	rules, err := parseProgram(program)
+	if err != nil {
+		return 2, err
+	}
+	in := newInterp(rules, w)
+	defer in.out.Flush()
+	if fs != "" {
+		if fs == "t" {
+			fs = "\t"
+		}
+		if fs, _, err = lexString(fs, false); err != nil {
+			return 2, err
+		}
+		in.vars["FS"] = str(fs)
+	}
+	for _, v := range vars {
+		ok, err := in.assignment(v)
+		if err != nil {
+			return 2, err
+		}
+		if !ok {
+			return 2, fmt.Errorf("invalid -v argument %q, expected VAR=VALUE", v)
+		}
+	}
+
+	c, err := in.runSpecial(true)
+	if err != nil {
+		return 2, err
+	}
+	if c != ctrlExit && in.needsInput() {
+		files := 0
+		for _, a := range args {
+			if !assignRE.MatchString(a) {
+				files++
+			}
+		}
+		if files == 0 {
+			args = append(args, "-")
+		}
+		for _, a := range args {
+			if ok, err := in.assignment(a); err != nil {
+				return 2, err
+			} else if ok {
+				continue
+			}
+			in.vars["FILENAME"] = str(a)
+			in.fnr = 0
+			var exit bool
+			if a == "-" {
+				exit, err = in.readRecords(stdin)
+			} else {
+				var f *os.File
+				if f, err = os.Open(a); err != nil {
+					return 2, err
+				}
+				exit, err = in.readRecords(f)
+				f.Close()
+			}
+			if err != nil {
+				return 2, err
+			}
+			if exit {
+				break
+			}
+		}
+	}
+	// END actions run after exit too, except in END.
+	if _, err := in.runSpecial(false); err != nil {
+		return 2, err
+	}
+	return in.exitCode, in.out.Flush()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("awk: ")
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+
+	args := flag.Args()
+	var program string
+	if len(*progFiles) > 0 {
+		var b strings.Builder
+		for _, name := range *progFiles {
+			p, err := ioutil.ReadFile(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			b.Write(p)
+			b.WriteByte('\n')
+		}
+		program = b.String()
+	} else {
+		if len(args) == 0 {
+			log.Fatal("usage: awk [-F FS] [-v VAR=VALUE]... PROGRAM [FILE | VAR=VALUE]...")
+		}
+		program, args = args[0], args[1:]
+	}
+	code, err := run(program, *fieldSep, *assigns, args, os.Stdout)
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const people = `alice 30 paris
+bob 25 berlin
+carol 35 rome
+`
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		program string
+		fs      string
+		vars    []string
+		args    []string
+		input   string
+		want    string
+		code    int
+	}{
+		{name: "print", program: "{ print }", input: people, want: people},
+		{name: "fields", program: "{ print $3, $1 }", input: people, want: "paris alice\nberlin bob\nrome carol\n"},
+		{name: "last field", program: "{ print $NF; print $(NF-1) }", input: "a b c\n", want: "c\nb\n"},
+		{name: "numeric compare", program: "$2 > 28", input: people, want: "alice 30 paris\ncarol 35 rome\n"},
+		{name: "string compare", program: `$3 < "m"`, input: people, want: "bob 25 berlin\n"},
+		{name: "field vs string", program: `$1 == "10"`, input: "10\n10.0\n", want: "10\n"},
+		{name: "fields are numbers", program: `$1 == 10`, input: "10\n10.0\nx\n", want: "10\n10.0\n"},
+		{name: "regex", program: "/^b/", input: people, want: "bob 25 berlin\n"},
+		{name: "not match", program: `$3 !~ /i/ { print $1 }`, input: people, want: "carol\n"},
+		{name: "dynamic regex", program: `$0 ~ "o.*r"`, input: people, want: "bob 25 berlin\ncarol 35 rome\n"},
+		{name: "range", program: "/bob/,/carol/ { print NR }", input: people, want: "2\n3\n"},
+		{name: "begin end", program: "BEGIN { print \"start\" } { n += $2 } END { print n, NR }", input: people, want: "start\n90 3\n"},
+		{name: "begin only", program: `BEGIN { print 1 + 2 * 3, 2 ^ 3 ^ 2, -2 ^ 2, 7 % 3, 7 / 2 }`, want: "7 512 -4 1 3.5\n"},
+		{name: "concatenation", program: `BEGIN { x = 1 " " 2 + 3; print x; print 1 2 }`, want: "1 5\n12\n"},
+		{name: "uninitialized", program: `BEGIN { print x + 0, "[" x "]", length(x) }`, want: "0 [] 0\n"},
+		{name: "logic", program: `BEGIN { print 1 && 0, 1 || 0, !0, !"", !"a", (1 < 2) ? "y" : "n" }`, want: "0 1 1 1 0 y\n"},
+		{name: "increment", program: `BEGIN { i = 5; print i++, i, ++i, i--, --i }`, want: "5 6 7 7 5\n"},
+		{name: "assign ops", program: `BEGIN { x = 10; x += 5; x -= 3; x *= 2; x /= 4; x %= 4; x ^= 3; print x }`, want: "8\n"},
+		{name: "number format", program: `BEGIN { print 0.1 + 0.2, 1e6, 1/3; OFMT = "%.2f"; print 1/3; x = 1/3 ""; print x }`, want: "0.3 1000000 0.333333\n0.33\n0.333333\n"},
+		{name: "field separator", program: "{ print $2 }", fs: ":", input: "a:b:c\n::\n", want: "b\n\n"},
+		{name: "regex separator", program: "{ print $2 }", fs: "[,;]+", input: "a,;b;c\n", want: "b\n"},
+		{name: "tab separator", program: "{ print $2 }", fs: "t", input: "a b\tc\n", want: "c\n"},
+		{name: "FS in BEGIN", program: `BEGIN { FS = "," } { print $2 }`, input: "a,b\n", want: "b\n"},
+		{name: "OFS", program: `BEGIN { OFS = "-" } { $1 = $1; print; print $1, $2 }`, input: "a  b c\n", want: "a-b-c\na-b\n"},
+		{name: "assign field", program: `{ $2 = "X"; print; print NF }`, input: "a b c\n", want: "a X c\n3\n"},
+		{name: "assign past NF", program: `{ $5 = "e"; print; print NF }`, input: "a b\n", want: "a b   e\n5\n"},
+		{name: "assign NF", program: `{ NF = 2; print }`, input: "a b c\n", want: "a b\n"},
+		{name: "assign $0", program: `{ $0 = "x y"; print $2, NF }`, input: "a b c\n", want: "y 2\n"},
+		{name: "missing field", program: `{ print "[" $7 "]" }`, input: "a\n", want: "[]\n"},
+		{name: "vars", program: `BEGIN { print x, y }`, vars: []string{"x=1", `y=a\tb`}, want: "1 a\tb\n"},
+		{name: "args assign", program: `{ print x, $0 }`, args: []string{"x=1", "-", "x=2"}, input: "a\n", want: "1 a\n"},
+		{name: "if else", program: `{ if ($2 >= 30) print $1, "old"; else if ($2 < 26) print $1, "young"
+else print $1 }`, input: people, want: "alice old\nbob young\ncarol old\n"},
+		{name: "while", program: `BEGIN { i = 0; while (i < 3) { printf "%d", i; i++ }; print "" }`, want: "012\n"},
+		{name: "do while", program: `BEGIN { do { i++ } while (i < 0); print i }`, want: "1\n"},
+		{name: "for", program: `{ for (i = NF; i > 0; i--) printf "%s%s", $i, (i > 1 ? " " : "\n") }`, input: "a b c\n", want: "c b a\n"},
+		{name: "break continue", program: `BEGIN { for (i = 0; ; i++) { if (i == 1) continue; if (i > 3) break; print i } }`, want: "0\n2\n3\n"},
+		{name: "next", program: `/bob/ { next } { print $1 }`, input: people, want: "alice\ncarol\n"},
+		{name: "exit", program: `{ print $1; exit 3 } END { print "end" }`, input: people, want: "alice\nend\n", code: 3},
+		{name: "exit in BEGIN", program: `BEGIN { exit } { print } END { print "end", NR }`, input: people, want: "end 0\n"},
+		{name: "printf", program: `BEGIN { printf "%5.2f|%-4s|%03d|%x|%c|%c|%e|%%|%*d\n", 3.14159, "ab", 7, 255, 65, "hi", 1234.5, 3, 1 }`, want: " 3.14|ab  |007|ff|A|h|1.234500e+03|%|  1\n"},
+		{name: "print parens", program: `BEGIN { print("a", "b"); print ("a")("b") }`, want: "a b\nab\n"},
+		{name: "print comparison", program: `BEGIN { print (2 > 1) }`, want: "1\n"},
+		{name: "length", program: `{ print length, length($1), length() }`, input: "héllo world\n", want: "11 5 11\n"},
+		{name: "substr", program: `BEGIN { s = "hello"; print substr(s, 2, 3), substr(s, 4), substr(s, 0, 2), substr(s, -1), substr(s, 9) "." }`, want: "ell lo h hello .\n"},
+		{name: "index match", program: `BEGIN { print index("foobar", "bar"), index("foo", "x"); print match("foobar", /o+/), RSTART, RLENGTH }`, want: "4 0\n2 2 2\n"},
+		{name: "sub gsub", program: `{ n = gsub(/o/, "[&]"); sub(/l+/, "L", $2); print n, $0; x = "a.b"; gsub(/\./, "\\&", x); print x }`, input: "foo lol\n", want: "3 f[o][o] L[o]l\na&b\n"},
+		{name: "case int", program: `BEGIN { print toupper("ab"), tolower("CD"), int(-3.7), int("4x"), sqrt(16) }`, want: "AB cd -3 4 4\n"},
+		{name: "sprintf", program: `BEGIN { s = sprintf("%s-%d", "a", 1.9); print s }`, want: "a-1\n"},
+		{name: "comments and newlines", program: "# comment\n{\n\tprint $1 # first\n}\n", input: "a b\n", want: "a\n"},
+		{name: "semicolons", program: `{ print $1 }; END { print NR };`, input: "a\nb\n", want: "a\nb\n2\n"},
+		{name: "continuation", program: "BEGIN { x = 1 + \\\n2; print x }", want: "3\n"},
+		{name: "strnum from string", program: `BEGIN { print "3x" + 1, " 12 " * 2, "1e3" + 0 }`, want: "4 24 1000\n"},
+		{name: "no trailing newline", program: "{ print NR \": \" $0 }", input: "a\nb", want: "1: a\n2: b\n"},
+		{name: "empty action", program: "/a/ {}", input: "a\n", want: ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdin = strings.NewReader(tt.input)
+			var out bytes.Buffer
+			code, err := run(tt.program, tt.fs, tt.vars, tt.args, &out)
+			if err != nil {
+				t.Fatalf("run() = %v", err)
+			}
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "awk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("1\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := run(`{ print FILENAME == b, NR, FNR, $1 }`, "", []string{"b=" + b}, []string{a, b}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 1 1 1\n0 2 2 2\n1 3 1 3\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if _, err := run(`{ print }`, "", nil, []string{filepath.Join(dir, "missing")}, &out); err == nil {
+		t.Errorf("run() on a missing file succeeded")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, program := range []string{
+		"{ print ",
+		"{ print $1 > \"file\" }",
+		"{ a[1] = 2 }",
+		"function f() {}",
+		"{ getline }",
+		"{ x = \"abc }",
+		"{ 1 = 2 }",
+		"{ break }",
+		"{ substr(1) }",
+		"/(/",
+		"BEGIN { x = 1 / 0 }",
+		"BEGIN { next }",
+		"BEGIN { printf \"%d\" }",
+	} {
+		stdin = strings.NewReader("")
+		var out bytes.Buffer
+		if code, err := run(program, "", nil, nil, &out); err == nil || code != 2 {
+			t.Errorf("run(%q) = %d, %v, want status 2 and an error", program, code, err)
+		}
+	}
+}
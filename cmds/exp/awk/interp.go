@@ -0,0 +1,947 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type valueKind int
+
+const (
+	kUninit valueKind = iota
+	kNum
+	kStr
+	// kStrNum is input that looks like a number, such as a field, which
+	// compares as a number.
+	kStrNum
+)
+
+// value is an awk value, which is a number, a string or both.
+type value struct {
+	kind valueKind
+	s    string
+	n    float64
+}
+
+func num(n float64) value {
+	return value{kind: kNum, n: n}
+}
+
+func str(s string) value {
+	return value{kind: kStr, s: s}
+}
+
+func boolean(b bool) value {
+	if b {
+		return num(1)
+	}
+	return num(0)
+}
+
+// strnum returns the value of the input s, which is a number if s looks
+// like one.
+func strnum(s string) value {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return str(s)
+	}
+	sign := 0
+	if t[0] == '-' || t[0] == '+' {
+		sign = 1
+	}
+	n, l := numberPrefix(t[sign:])
+	if l == 0 || sign+l != len(t) {
+		return str(s)
+	}
+	if t[0] == '-' {
+		n = -n
+	}
+	return value{kind: kStrNum, s: s, n: n}
+}
+
+// numberPrefix returns the unsigned decimal number at the start of s, and
+// its length, which is 0 if there is none.
+func numberPrefix(s string) (float64, int) {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	n := digits()
+	if i < len(s) && s[i] == '.' {
+		i++
+		n += digits()
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		mant := i
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		if digits() == 0 {
+			i = mant
+		}
+	}
+	f, _ := strconv.ParseFloat(s[:i], 64)
+	return f, i
+}
+
+// toNum converts a string to the number at its start, after blanks.
+func toNum(v value) float64 {
+	if v.kind != kStr {
+		return v.n
+	}
+	s := strings.TrimLeft(v.s, " \t\n")
+	sign := 1.0
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	n, _ := numberPrefix(s)
+	return sign * n
+}
+
+func isTrue(v value) bool {
+	switch v.kind {
+	case kNum, kStrNum:
+		return v.n != 0
+	case kStr:
+		return v.s != ""
+	}
+	return false
+}
+
+func formatNum(n float64, format string) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e16 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return fmt.Sprintf(format, n)
+}
+
+type ctrl int
+
+const (
+	ctrlNone ctrl = iota
+	ctrlNext
+	ctrlExit
+	ctrlBreak
+	ctrlContinue
+)
+
+// interp runs an awk program.
+type interp struct {
+	rules []*rule
+	vars  map[string]value
+	// record is $0 and fields are $1 to $NF.
+	record   string
+	fields   []string
+	nr, fnr  int
+	out      *bufio.Writer
+	regexps  map[string]*regexp.Regexp
+	exitCode int
+}
+
+func newInterp(rules []*rule, w io.Writer) *interp {
+	return &interp{
+		rules: rules,
+		vars: map[string]value{
+			"FS":      str(" "),
+			"OFS":     str(" "),
+			"ORS":     str("\n"),
+			"CONVFMT": str("%.6g"),
+			"OFMT":    str("%.6g"),
+			"RSTART":  num(0),
+			"RLENGTH": num(-1),
+		},
+		out:     bufio.NewWriter(w),
+		regexps: map[string]*regexp.Regexp{},
+	}
+}
+
+func (in *interp) toStr(v value) string {
+	if v.kind == kNum {
+		return formatNum(v.n, in.toStr(in.vars["CONVFMT"]))
+	}
+	return v.s
+}
+
+// outputStr is like toStr, but uses OFMT, for print.
+func (in *interp) outputStr(v value) string {
+	if v.kind == kNum {
+		return formatNum(v.n, in.toStr(in.vars["OFMT"]))
+	}
+	return v.s
+}
+
+// regex compiles a dynamic regex, the string value of an expression.
+func (in *interp) regex(s string) (*regexp.Regexp, error) {
+	if re, ok := in.regexps[s]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	in.regexps[s] = re
+	return re, nil
+}
+
+// regexArg returns the regex of the operand e of ~, which is a regex
+// literal or any expression.
+func (in *interp) regexArg(e expr) (*regexp.Regexp, error) {
+	if r, ok := e.(*regexLit); ok {
+		return r.re, nil
+	}
+	v, err := in.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	return in.regex(in.toStr(v))
+}
+
+// setRecord sets $0 and splits it into fields with FS.
+func (in *interp) setRecord(s string) error {
+	in.record = s
+	fs := in.toStr(in.vars["FS"])
+	switch {
+	case fs == " ":
+		in.fields = strings.FieldsFunc(s, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == '\n'
+		})
+	case s == "":
+		in.fields = nil
+	case utf8.RuneCountInString(fs) == 1:
+		in.fields = strings.Split(s, fs)
+	default:
+		re, err := in.regex(fs)
+		if err != nil {
+			return err
+		}
+		in.fields = re.Split(s, -1)
+	}
+	return nil
+}
+
+// rebuild joins the fields with OFS into $0.
+func (in *interp) rebuild() {
+	in.record = strings.Join(in.fields, in.toStr(in.vars["OFS"]))
+}
+
+func (in *interp) setNF(n int) {
+	for len(in.fields) < n {
+		in.fields = append(in.fields, "")
+	}
+	in.fields = in.fields[:n]
+	in.rebuild()
+}
+
+func (in *interp) fieldIndex(e *fieldRef) (int, error) {
+	v, err := in.eval(e.index)
+	if err != nil {
+		return 0, err
+	}
+	i := int(toNum(v))
+	if i < 0 {
+		return 0, fmt.Errorf("trying to access out of range field %d", i)
+	}
+	return i, nil
+}
+
+func (in *interp) getVar(name string) value {
+	switch name {
+	case "NF":
+		return num(float64(len(in.fields)))
+	case "NR":
+		return num(float64(in.nr))
+	case "FNR":
+		return num(float64(in.fnr))
+	}
+	return in.vars[name]
+}
+
+func (in *interp) setVar(name string, v value) {
+	switch name {
+	case "NF":
+		in.setNF(int(toNum(v)))
+	case "NR":
+		in.nr = int(toNum(v))
+	case "FNR":
+		in.fnr = int(toNum(v))
+	default:
+		in.vars[name] = v
+	}
+}
+
+func (in *interp) set(lv expr, v value) error {
+	switch lv := lv.(type) {
+	case *varRef:
+		in.setVar(lv.name, v)
+	case *fieldRef:
+		i, err := in.fieldIndex(lv)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			return in.setRecord(in.toStr(v))
+		}
+		if i > len(in.fields) {
+			in.setNF(i)
+		}
+		in.fields[i-1] = in.toStr(v)
+		in.rebuild()
+	}
+	return nil
+}
+
+func arith(op string, l, r float64) (float64, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero in %%")
+		}
+		return math.Mod(l, r), nil
+	case "^":
+		return math.Pow(l, r), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
+
+// compare compares numerically if both values are numbers, and as strings
+// otherwise.
+func (in *interp) compare(l, r value) int {
+	if l.kind != kStr && r.kind != kStr {
+		switch {
+		case l.n < r.n:
+			return -1
+		case l.n > r.n:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(in.toStr(l), in.toStr(r))
+}
+
+func (in *interp) eval(e expr) (value, error) {
+	switch e := e.(type) {
+	case *numLit:
+		return num(e.n), nil
+	case *strLit:
+		return str(e.s), nil
+	case *regexLit:
+		return boolean(e.re.MatchString(in.record)), nil
+	case *varRef:
+		return in.getVar(e.name), nil
+	case *fieldRef:
+		i, err := in.fieldIndex(e)
+		if err != nil {
+			return value{}, err
+		}
+		if i == 0 {
+			return strnum(in.record), nil
+		}
+		if i > len(in.fields) {
+			return value{}, nil
+		}
+		return strnum(in.fields[i-1]), nil
+	case *assign:
+		v, err := in.eval(e.rhs)
+		if err != nil {
+			return value{}, err
+		}
+		if e.op != "=" {
+			old, err := in.eval(e.lhs)
+			if err != nil {
+				return value{}, err
+			}
+			n, err := arith(e.op[:1], toNum(old), toNum(v))
+			if err != nil {
+				return value{}, err
+			}
+			v = num(n)
+		}
+		return v, in.set(e.lhs, v)
+	case *condExpr:
+		c, err := in.eval(e.cond)
+		if err != nil {
+			return value{}, err
+		}
+		if isTrue(c) {
+			return in.eval(e.yes)
+		}
+		return in.eval(e.no)
+	case *binary:
+		return in.binary(e)
+	case *unary:
+		v, err := in.eval(e.e)
+		if err != nil {
+			return value{}, err
+		}
+		switch e.op {
+		case "!":
+			return boolean(!isTrue(v)), nil
+		case "-":
+			return num(-toNum(v)), nil
+		}
+		return num(toNum(v)), nil
+	case *incDec:
+		old, err := in.eval(e.lv)
+		if err != nil {
+			return value{}, err
+		}
+		n := toNum(old)
+		v := num(n + 1)
+		if e.op == "--" {
+			v = num(n - 1)
+		}
+		if err := in.set(e.lv, v); err != nil {
+			return value{}, err
+		}
+		if e.pre {
+			return v, nil
+		}
+		return num(n), nil
+	case *call:
+		return in.call(e)
+	}
+	return value{}, fmt.Errorf("unknown expression %T", e)
+}
+
+func (in *interp) binary(e *binary) (value, error) {
+	switch e.op {
+	case "&&", "||":
+		l, err := in.eval(e.l)
+		if err != nil {
+			return value{}, err
+		}
+		if isTrue(l) == (e.op == "||") {
+			return boolean(isTrue(l)), nil
+		}
+		r, err := in.eval(e.r)
+		if err != nil {
+			return value{}, err
+		}
+		return boolean(isTrue(r)), nil
+	case "~", "!~":
+		l, err := in.eval(e.l)
+		if err != nil {
+			return value{}, err
+		}
+		re, err := in.regexArg(e.r)
+		if err != nil {
+			return value{}, err
+		}
+		return boolean(re.MatchString(in.toStr(l)) == (e.op == "~")), nil
+	}
+	l, err := in.eval(e.l)
+	if err != nil {
+		return value{}, err
+	}
+	r, err := in.eval(e.r)
+	if err != nil {
+		return value{}, err
+	}
+	switch e.op {
+	case " ":
+		return str(in.toStr(l) + in.toStr(r)), nil
+	case "<":
+		return boolean(in.compare(l, r) < 0), nil
+	case "<=":
+		return boolean(in.compare(l, r) <= 0), nil
+	case "==":
+		return boolean(in.compare(l, r) == 0), nil
+	case "!=":
+		return boolean(in.compare(l, r) != 0), nil
+	case ">=":
+		return boolean(in.compare(l, r) >= 0), nil
+	case ">":
+		return boolean(in.compare(l, r) > 0), nil
+	}
+	n, err := arith(e.op, toNum(l), toNum(r))
+	return num(n), err
+}
+
+func (in *interp) evalArgs(args []expr) ([]value, error) {
+	var vals []value
+	for _, a := range args {
+		v, err := in.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+func (in *interp) call(c *call) (value, error) {
+	switch c.name {
+	case "sub", "gsub":
+		return in.substitute(c)
+	case "match":
+		s, err := in.eval(c.args[0])
+		if err != nil {
+			return value{}, err
+		}
+		re, err := in.regexArg(c.args[1])
+		if err != nil {
+			return value{}, err
+		}
+		start, length := 0, -1
+		str := in.toStr(s)
+		if loc := re.FindStringIndex(str); loc != nil {
+			start = utf8.RuneCountInString(str[:loc[0]]) + 1
+			length = utf8.RuneCountInString(str[loc[0]:loc[1]])
+		}
+		in.vars["RSTART"] = num(float64(start))
+		in.vars["RLENGTH"] = num(float64(length))
+		return num(float64(start)), nil
+	case "length":
+		if len(c.args) == 0 {
+			return num(float64(utf8.RuneCountInString(in.record))), nil
+		}
+	}
+
+	args, err := in.evalArgs(c.args)
+	if err != nil {
+		return value{}, err
+	}
+	switch c.name {
+	case "length":
+		return num(float64(utf8.RuneCountInString(in.toStr(args[0])))), nil
+	case "substr":
+		return str(in.substr(args)), nil
+	case "index":
+		s, t := in.toStr(args[0]), in.toStr(args[1])
+		i := strings.Index(s, t)
+		if i < 0 {
+			return num(0), nil
+		}
+		return num(float64(utf8.RuneCountInString(s[:i]) + 1)), nil
+	case "sprintf":
+		s, err := in.sprintf(in.toStr(args[0]), args[1:])
+		return str(s), err
+	case "tolower":
+		return str(strings.ToLower(in.toStr(args[0]))), nil
+	case "toupper":
+		return str(strings.ToUpper(in.toStr(args[0]))), nil
+	case "int":
+		return num(math.Trunc(toNum(args[0]))), nil
+	case "sqrt":
+		return num(math.Sqrt(toNum(args[0]))), nil
+	case "exp":
+		return num(math.Exp(toNum(args[0]))), nil
+	case "log":
+		return num(math.Log(toNum(args[0]))), nil
+	}
+	return value{}, fmt.Errorf("unknown function %s", c.name)
+}
+
+// substr returns the characters of args[0] from position args[1], which
+// starts at 1, and at most args[2] of them.
+func (in *interp) substr(args []value) string {
+	r := []rune(in.toStr(args[0]))
+	start := math.Round(toNum(args[1]))
+	end := float64(len(r) + 1)
+	if len(args) == 3 {
+		end = start + math.Round(toNum(args[2]))
+	}
+	start = math.Max(start, 1)
+	end = math.Min(end, float64(len(r)+1))
+	if math.IsNaN(start) || math.IsNaN(end) || end <= start {
+		return ""
+	}
+	return string(r[int(start)-1 : int(end)-1])
+}
+
+// substitute runs sub or gsub, which replace the first or all matches of
+// the regex in the target, $0 by default. & in the replacement is the
+// match, and \& a literal &.
+func (in *interp) substitute(c *call) (value, error) {
+	re, err := in.regexArg(c.args[0])
+	if err != nil {
+		return value{}, err
+	}
+	repl, err := in.eval(c.args[1])
+	if err != nil {
+		return value{}, err
+	}
+	var target expr = &fieldRef{index: &numLit{n: 0}}
+	if len(c.args) == 3 {
+		target = c.args[2]
+	}
+	tv, err := in.eval(target)
+	if err != nil {
+		return value{}, err
+	}
+	s, r := in.toStr(tv), in.toStr(repl)
+
+	var b strings.Builder
+	n, last := 0, 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		if c.name == "sub" && n == 1 {
+			break
+		}
+		n++
+		b.WriteString(s[last:loc[0]])
+		for i := 0; i < len(r); i++ {
+			switch {
+			case r[i] == '\\' && i+1 < len(r) && (r[i+1] == '&' || r[i+1] == '\\'):
+				i++
+				b.WriteByte(r[i])
+			case r[i] == '&':
+				b.WriteString(s[loc[0]:loc[1]])
+			default:
+				b.WriteByte(r[i])
+			}
+		}
+		last = loc[1]
+	}
+	if n == 0 {
+		return num(0), nil
+	}
+	b.WriteString(s[last:])
+	return num(float64(n)), in.set(target, str(b.String()))
+}
+
+// sprintf formats args like printf(3). The conversions are c, d, i, o, u,
+// x, X, e, E, f, F, g, G and s.
+func (in *interp) sprintf(format string, args []value) (string, error) {
+	var b strings.Builder
+	nextArg := func() (value, error) {
+		if len(args) == 0 {
+			return value{}, fmt.Errorf("not enough arguments for format %q", format)
+		}
+		v := args[0]
+		args = args[1:]
+		return v, nil
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+		// Copy the flags, width and precision, replacing * by an argument.
+		spec := "%"
+		j := i + 1
+		for ; j < len(format) && strings.IndexByte("-+ #0123456789.*", format[j]) >= 0; j++ {
+			if format[j] != '*' {
+				spec += format[j : j+1]
+				continue
+			}
+			v, err := nextArg()
+			if err != nil {
+				return "", err
+			}
+			spec += strconv.Itoa(int(toNum(v)))
+		}
+		if j == len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+		verb := format[j]
+		if strings.IndexByte("cdiouxXeEfFgGs", verb) < 0 {
+			b.WriteString(format[i : j+1])
+			i = j
+			continue
+		}
+		v, err := nextArg()
+		if err != nil {
+			return "", err
+		}
+		switch verb {
+		case 'c':
+			s := in.toStr(v)
+			if v.kind == kNum {
+				s = string(rune(int(v.n)))
+			} else if s != "" {
+				_, l := utf8.DecodeRuneInString(s)
+				s = s[:l]
+			}
+			fmt.Fprintf(&b, spec+"s", s)
+		case 'd', 'i':
+			fmt.Fprintf(&b, spec+"d", int64(toNum(v)))
+		case 'o', 'x', 'X':
+			fmt.Fprintf(&b, spec+string(verb), uint64(int64(toNum(v))))
+		case 'u':
+			fmt.Fprintf(&b, spec+"d", uint64(int64(toNum(v))))
+		case 'F':
+			fmt.Fprintf(&b, spec+"f", toNum(v))
+		case 'e', 'E', 'f', 'g', 'G':
+			fmt.Fprintf(&b, spec+string(verb), toNum(v))
+		case 's':
+			fmt.Fprintf(&b, spec+"s", in.toStr(v))
+		}
+		i = j
+	}
+	return b.String(), nil
+}
+
+func (in *interp) exec(s stmt) (ctrl, error) {
+	switch s := s.(type) {
+	case block:
+		for _, st := range s {
+			if c, err := in.exec(st); c != ctrlNone || err != nil {
+				return c, err
+			}
+		}
+	case *exprStmt:
+		_, err := in.eval(s.e)
+		return ctrlNone, err
+	case *printStmt:
+		return ctrlNone, in.print(s)
+	case *ifStmt:
+		c, err := in.eval(s.cond)
+		if err != nil {
+			return ctrlNone, err
+		}
+		if isTrue(c) {
+			return in.exec(s.then)
+		}
+		if s.el != nil {
+			return in.exec(s.el)
+		}
+	case *whileStmt:
+		for first := true; ; first = false {
+			if !s.do || !first {
+				c, err := in.eval(s.cond)
+				if err != nil {
+					return ctrlNone, err
+				}
+				if !isTrue(c) {
+					break
+				}
+			}
+			c, err := in.exec(s.body)
+			if err != nil || c == ctrlNext || c == ctrlExit {
+				return c, err
+			}
+			if c == ctrlBreak {
+				break
+			}
+		}
+	case *forStmt:
+		if s.init != nil {
+			if _, err := in.eval(s.init); err != nil {
+				return ctrlNone, err
+			}
+		}
+		for {
+			if s.cond != nil {
+				c, err := in.eval(s.cond)
+				if err != nil {
+					return ctrlNone, err
+				}
+				if !isTrue(c) {
+					break
+				}
+			}
+			c, err := in.exec(s.body)
+			if err != nil || c == ctrlNext || c == ctrlExit {
+				return c, err
+			}
+			if c == ctrlBreak {
+				break
+			}
+			if s.post != nil {
+				if _, err := in.eval(s.post); err != nil {
+					return ctrlNone, err
+				}
+			}
+		}
+	case *nextStmt:
+		return ctrlNext, nil
+	case *exitStmt:
+		if s.code != nil {
+			v, err := in.eval(s.code)
+			if err != nil {
+				return ctrlNone, err
+			}
+			in.exitCode = int(toNum(v))
+		}
+		return ctrlExit, nil
+	case *breakStmt:
+		return ctrlBreak, nil
+	case *continueStmt:
+		return ctrlContinue, nil
+	default:
+		return ctrlNone, fmt.Errorf("unknown statement %T", s)
+	}
+	return ctrlNone, nil
+}
+
+func (in *interp) print(s *printStmt) error {
+	args, err := in.evalArgs(s.args)
+	if err != nil {
+		return err
+	}
+	if s.format {
+		out, err := in.sprintf(in.toStr(args[0]), args[1:])
+		if err != nil {
+			return err
+		}
+		_, err = in.out.WriteString(out)
+		return err
+	}
+	if len(args) == 0 {
+		args = []value{str(in.record)}
+	}
+	for i, a := range args {
+		if i > 0 {
+			in.out.WriteString(in.toStr(in.vars["OFS"]))
+		}
+		in.out.WriteString(in.outputStr(a))
+	}
+	_, err = in.out.WriteString(in.toStr(in.vars["ORS"]))
+	return err
+}
+
+// matches returns whether the pattern of the main rule r matches the
+// current record.
+func (in *interp) matches(r *rule) (bool, error) {
+	if r.pattern == nil {
+		return true, nil
+	}
+	if r.pattern2 == nil {
+		v, err := in.eval(r.pattern)
+		return isTrue(v), err
+	}
+	if !r.inRange {
+		v, err := in.eval(r.pattern)
+		if err != nil || !isTrue(v) {
+			return false, err
+		}
+		r.inRange = true
+	}
+	v, err := in.eval(r.pattern2)
+	if err != nil {
+		return false, err
+	}
+	if isTrue(v) {
+		r.inRange = false
+	}
+	return true, nil
+}
+
+// runSpecial runs the BEGIN actions, or the END actions.
+func (in *interp) runSpecial(begin bool) (ctrl, error) {
+	for _, r := range in.rules {
+		if begin && !r.begin || !begin && !r.end {
+			continue
+		}
+		c, err := in.exec(r.action)
+		if err != nil {
+			return c, err
+		}
+		switch c {
+		case ctrlNext:
+			return c, fmt.Errorf("next used in BEGIN or END action")
+		case ctrlExit:
+			return c, nil
+		}
+	}
+	return ctrlNone, nil
+}
+
+// runRecord runs the main rules on a record, and returns whether to exit.
+func (in *interp) runRecord(line string) (bool, error) {
+	in.nr++
+	in.fnr++
+	if err := in.setRecord(line); err != nil {
+		return false, err
+	}
+	for _, r := range in.rules {
+		if r.begin || r.end {
+			continue
+		}
+		ok, err := in.matches(r)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		if r.action == nil {
+			if err := in.print(&printStmt{}); err != nil {
+				return false, err
+			}
+			continue
+		}
+		c, err := in.exec(r.action)
+		if err != nil {
+			return false, err
+		}
+		if c == ctrlNext {
+			break
+		}
+		if c == ctrlExit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readRecords runs the main rules on the lines of r, and returns whether
+// to exit.
+func (in *interp) readRecords(r io.Reader) (bool, error) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		if line == "" {
+			return false, nil
+		}
+		exit, rerr := in.runRecord(strings.TrimSuffix(line, "\n"))
+		if rerr != nil || exit {
+			return exit, rerr
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+	}
+}
+
+// needsInput returns whether the program has main rules or END actions,
+// without which no input is read.
+func (in *interp) needsInput() bool {
+	for _, r := range in.rules {
+		if !r.begin {
+			return true
+		}
+	}
+	return false
+}
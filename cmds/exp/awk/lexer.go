@@ -0,0 +1,273 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tNewline
+	tNumber
+	tString
+	tRegex
+	tName
+	tBuiltin
+	tKeyword
+	tPunct
+)
+
+type token struct {
+	kind tokenKind
+	// text is the operator, name or keyword, or the value of a string or
+	// regex.
+	text string
+	num  float64
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tEOF:
+		return "end of program"
+	case tNewline:
+		return "newline"
+	case tString:
+		return fmt.Sprintf("%q", t.text)
+	case tRegex:
+		return "/" + t.text + "/"
+	}
+	return t.text
+}
+
+var keywords = map[string]bool{
+	"BEGIN":    true,
+	"END":      true,
+	"if":       true,
+	"else":     true,
+	"while":    true,
+	"for":      true,
+	"do":       true,
+	"break":    true,
+	"continue": true,
+	"next":     true,
+	"exit":     true,
+	"print":    true,
+	"printf":   true,
+	// Not supported, but reserved so that they are reported.
+	"function": true,
+	"getline":  true,
+	"delete":   true,
+	"in":       true,
+	"return":   true,
+}
+
+var builtins = map[string]bool{
+	"length":  true,
+	"substr":  true,
+	"index":   true,
+	"match":   true,
+	"sub":     true,
+	"gsub":    true,
+	"sprintf": true,
+	"tolower": true,
+	"toupper": true,
+	"int":     true,
+	"sqrt":    true,
+	"exp":     true,
+	"log":     true,
+}
+
+// operators are the punctuation tokens, longest first.
+var operators = []string{
+	"+=", "-=", "*=", "/=", "%=", "^=", "**",
+	"==", "<=", ">=", "!=", "++", "--", "&&", "||", "!~", ">>",
+	"{", "}", "(", ")", "[", "]", ";", ",", "+", "-", "*", "/", "%", "^",
+	"!", ">", "<", "|", "?", ":", "~", "$", "=",
+}
+
+// lex splits the program src into tokens.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	// regexAllowed is whether a / starts a regex rather than being a
+	// division, which depends on the previous token.
+	regexAllowed := func() bool {
+		if len(toks) == 0 {
+			return true
+		}
+		t := toks[len(toks)-1]
+		switch t.kind {
+		case tNumber, tString, tRegex, tName, tBuiltin:
+			return false
+		case tPunct:
+			return t.text != ")" && t.text != "]" && t.text != "$" && t.text != "++" && t.text != "--"
+		}
+		return true
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			line++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\n':
+			toks = append(toks, token{kind: tNewline, text: "\n", line: line})
+			line++
+			i++
+		case c == '"':
+			s, n, err := lexString(src[i+1:], true)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			toks = append(toks, token{kind: tString, text: s, line: line})
+			i += n + 1
+		case c == '/' && regexAllowed():
+			re, n, err := lexRegex(src[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			toks = append(toks, token{kind: tRegex, text: re, line: line})
+			i += n + 1
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			n, l := numberPrefix(src[i:])
+			toks = append(toks, token{kind: tNumber, num: n, text: src[i : i+l], line: line})
+			i += l
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			kind := tName
+			if keywords[src[i:j]] {
+				kind = tKeyword
+			} else if builtins[src[i:j]] {
+				kind = tBuiltin
+			}
+			toks = append(toks, token{kind: kind, text: src[i:j], line: line})
+			i = j
+		default:
+			var op string
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			i += len(op)
+			if op == "**" {
+				op = "^"
+			}
+			toks = append(toks, token{kind: tPunct, text: op, line: line})
+		}
+	}
+	return append(toks, token{kind: tEOF, line: line}), nil
+}
+
+// lexString returns the value of the string literal at the start of s,
+// after the opening quote, and its length including the closing quote. If
+// quoted is false, all of s is the string, with its escape sequences.
+func lexString(s string, quoted bool) (string, int, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && quoted:
+			return b.String(), i + 1, nil
+		case c == '\n' && quoted:
+			return "", 0, fmt.Errorf("newline in string")
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'a':
+				b.WriteByte('\a')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'v':
+				b.WriteByte('\v')
+			case '\n':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := 0
+				j := i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					n = n*8 + int(s[j]-'0')
+				}
+				b.WriteByte(byte(n))
+				i = j - 1
+			case '"', '\\', '/':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if !quoted {
+		return b.String(), len(s), nil
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexRegex returns the regex at the start of s, after the opening slash,
+// and its length including the closing slash.
+func lexRegex(s string) (string, int, error) {
+	var b strings.Builder
+	inBracket := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\n':
+			return "", 0, fmt.Errorf("newline in regex")
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] != '/' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[i])
+		case c == '[' && !inBracket:
+			inBracket = true
+			b.WriteByte(c)
+			// A ] right after [ or [^ is part of the set.
+			if i+1 < len(s) && s[i+1] == '^' {
+				i++
+				b.WriteByte('^')
+			}
+			if i+1 < len(s) && s[i+1] == ']' {
+				i++
+				b.WriteString(`\]`)
+			}
+		case c == ']' && inBracket:
+			inBracket = false
+			b.WriteByte(c)
+		case c == '/' && !inBracket:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated regex")
+}
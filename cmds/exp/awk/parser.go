@@ -0,0 +1,801 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Expressions.
+type (
+	expr interface{}
+
+	numLit struct{ n float64 }
+	strLit struct{ s string }
+	// regexLit matches $0, unless it is an operand of ~, !~ or a builtin
+	// that takes a regex.
+	regexLit struct{ re *regexp.Regexp }
+	varRef   struct{ name string }
+	fieldRef struct{ index expr }
+	// assign is =, or an operator followed by =.
+	assign struct {
+		op       string
+		lhs, rhs expr
+	}
+	condExpr struct{ cond, yes, no expr }
+	// binary is an operator, or " " for concatenation.
+	binary struct {
+		op   string
+		l, r expr
+	}
+	unary struct {
+		op string
+		e  expr
+	}
+	incDec struct {
+		op  string
+		pre bool
+		lv  expr
+	}
+	call struct {
+		name string
+		args []expr
+	}
+)
+
+// Statements.
+type (
+	stmt interface{}
+
+	block     []stmt
+	exprStmt  struct{ e expr }
+	printStmt struct {
+		args   []expr
+		format bool
+	}
+	ifStmt struct {
+		cond     expr
+		then, el stmt
+	}
+	whileStmt struct {
+		cond expr
+		body stmt
+		// do runs body before testing cond.
+		do bool
+	}
+	forStmt struct {
+		init, cond, post expr
+		body             stmt
+	}
+	nextStmt     struct{}
+	exitStmt     struct{ code expr }
+	breakStmt    struct{}
+	continueStmt struct{}
+)
+
+// rule is a pattern and its action. A nil action prints the record.
+type rule struct {
+	begin, end bool
+	// pattern is nil to match every record. pattern2 ends a range.
+	pattern, pattern2 expr
+	action            block
+
+	inRange bool
+}
+
+// parser parses an awk program.
+type parser struct {
+	toks []token
+	pos  int
+	// noGT is set while parsing the arguments of print and printf, where
+	// > outside of parentheses is an output redirection.
+	noGT  bool
+	loops int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns whether the next token is the operator or keyword s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.kind == tPunct || t.kind == tKeyword) && t.text == s
+}
+
+// accept skips the next token if it is s.
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.peek().line, fmt.Sprintf(format, a...))
+}
+
+func (p *parser) unexpected() error {
+	return p.errorf("syntax error at %v", p.peek())
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tNewline {
+		p.pos++
+	}
+}
+
+// skipTerminators skips newlines and semicolons.
+func (p *parser) skipTerminators() {
+	for p.peek().kind == tNewline || p.is(";") {
+		p.pos++
+	}
+}
+
+// parseProgram parses the awk program src.
+func parseProgram(src string) ([]*rule, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	var rules []*rule
+	for {
+		p.skipTerminators()
+		if p.peek().kind == tEOF {
+			return rules, nil
+		}
+		r, err := p.rule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+		// A rule without an action ends at a newline or semicolon.
+		if t := p.peek(); r.action == nil && t.kind != tNewline && t.kind != tEOF && !p.is(";") {
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *parser) rule() (*rule, error) {
+	r := &rule{}
+	var err error
+	switch {
+	case p.accept("BEGIN"):
+		r.begin = true
+	case p.accept("END"):
+		r.end = true
+	case p.is("function"):
+		return nil, p.errorf("functions are not supported")
+	case !p.is("{"):
+		if r.pattern, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if p.accept(",") {
+			p.skipNewlines()
+			if r.pattern2, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+		if !p.is("{") {
+			return r, nil
+		}
+	}
+	if !p.is("{") {
+		return nil, p.unexpected()
+	}
+	if r.action, err = p.block(); err != nil {
+		return nil, err
+	}
+	if r.action == nil {
+		// An empty action does nothing, rather than print.
+		r.action = block{}
+	}
+	return r, nil
+}
+
+func (p *parser) block() (block, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var b block
+	for {
+		p.skipTerminators()
+		if p.accept("}") {
+			return b, nil
+		}
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, s)
+	}
+}
+
+// endSimple checks that a simple statement is terminated, and skips the
+// terminator unless it is }.
+func (p *parser) endSimple() error {
+	switch {
+	case p.peek().kind == tNewline, p.is(";"):
+		p.pos++
+	case p.is("}"), p.peek().kind == tEOF:
+	default:
+		return p.unexpected()
+	}
+	return nil
+}
+
+// body parses the statement of if, while and for.
+func (p *parser) body() (stmt, error) {
+	p.skipNewlines()
+	if p.accept(";") {
+		return block{}, nil
+	}
+	return p.stmt()
+}
+
+func (p *parser) loopBody() (stmt, error) {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.body()
+}
+
+func (p *parser) stmt() (stmt, error) {
+	t := p.peek()
+	if t.kind == tKeyword {
+		switch t.text {
+		case "if":
+			return p.ifStmt()
+		case "while":
+			p.next()
+			cond, err := p.paren()
+			if err != nil {
+				return nil, err
+			}
+			body, err := p.loopBody()
+			if err != nil {
+				return nil, err
+			}
+			return &whileStmt{cond: cond, body: body}, nil
+		case "do":
+			p.next()
+			body, err := p.loopBody()
+			if err != nil {
+				return nil, err
+			}
+			p.skipTerminators()
+			if err := p.expect("while"); err != nil {
+				return nil, err
+			}
+			cond, err := p.paren()
+			if err != nil {
+				return nil, err
+			}
+			return &whileStmt{cond: cond, body: body, do: true}, p.endSimple()
+		case "for":
+			return p.forStmt()
+		}
+	}
+	if p.is("{") {
+		return p.block()
+	}
+	s, err := p.simpleStmt()
+	if err != nil {
+		return nil, err
+	}
+	return s, p.endSimple()
+}
+
+func (p *parser) paren() (expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return e, p.expect(")")
+}
+
+func (p *parser) ifStmt() (stmt, error) {
+	p.next()
+	cond, err := p.paren()
+	if err != nil {
+		return nil, err
+	}
+	then, err := p.body()
+	if err != nil {
+		return nil, err
+	}
+	s := &ifStmt{cond: cond, then: then}
+	// else may follow a newline or semicolon.
+	save := p.pos
+	p.skipTerminators()
+	if !p.accept("else") {
+		p.pos = save
+		return s, nil
+	}
+	if s.el, err = p.body(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) forStmt() (stmt, error) {
+	p.next()
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	s := &forStmt{}
+	var err error
+	if !p.is(";") {
+		if s.init, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if p.is("in") {
+			return nil, p.errorf("arrays are not supported")
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if !p.is(";") {
+		if s.cond, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if !p.is(")") {
+		if s.post, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if s.body, err = p.loopBody(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) simpleStmt() (stmt, error) {
+	t := p.peek()
+	if t.kind == tKeyword {
+		switch t.text {
+		case "print", "printf":
+			return p.printStmt()
+		case "next":
+			p.next()
+			return &nextStmt{}, nil
+		case "exit":
+			p.next()
+			s := &exitStmt{}
+			if t := p.peek(); t.kind != tNewline && t.kind != tEOF && !p.is(";") && !p.is("}") {
+				var err error
+				if s.code, err = p.expr(); err != nil {
+					return nil, err
+				}
+			}
+			return s, nil
+		case "break", "continue":
+			if p.loops == 0 {
+				return nil, p.errorf("%s outside of a loop", t.text)
+			}
+			p.next()
+			if t.text == "break" {
+				return &breakStmt{}, nil
+			}
+			return &continueStmt{}, nil
+		case "getline":
+			return nil, p.errorf("getline is not supported")
+		case "delete":
+			return nil, p.errorf("arrays are not supported")
+		case "return":
+			return nil, p.errorf("functions are not supported")
+		}
+	}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &exprStmt{e: e}, nil
+}
+
+func (p *parser) printStmt() (stmt, error) {
+	s := &printStmt{format: p.next().text == "printf"}
+	p.noGT = true
+	defer func() { p.noGT = false }()
+
+	// print (a, b) prints a and b, but print (a) b prints the concatenation.
+	if p.is("(") {
+		save := p.pos
+		p.next()
+		args, err := p.exprList()
+		if err == nil && p.accept(")") && p.endsPrint() {
+			s.args = args
+		} else {
+			p.pos = save
+		}
+	}
+	if s.args == nil && !p.endsPrint() {
+		var err error
+		if s.args, err = p.exprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(">") || p.is(">>") || p.is("|") {
+		return nil, p.errorf("output redirection is not supported")
+	}
+	if s.format && len(s.args) == 0 {
+		return nil, p.errorf("printf: no format")
+	}
+	return s, nil
+}
+
+// endsPrint returns whether the next token ends the arguments of print.
+func (p *parser) endsPrint() bool {
+	t := p.peek()
+	return t.kind == tNewline || t.kind == tEOF || p.is(";") || p.is("}") || p.is(">") || p.is(">>") || p.is("|")
+}
+
+func (p *parser) exprList() ([]expr, error) {
+	var list []expr
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.accept(",") {
+			return list, nil
+		}
+		p.skipNewlines()
+	}
+}
+
+func isLvalue(e expr) bool {
+	switch e.(type) {
+	case *varRef, *fieldRef:
+		return true
+	}
+	return false
+}
+
+// expr parses an expression. From the lowest precedence to the highest,
+// the operators are: assignment, ?:, ||, &&, ~ and !~, comparison,
+// concatenation, + and -, * / and %, unary ! + and -, ^, ++ and --, $ and
+// grouping.
+func (p *parser) expr() (expr, error) {
+	l, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tPunct && isLvalue(l) {
+		switch t.text {
+		case "=", "+=", "-=", "*=", "/=", "%=", "^=":
+			p.next()
+			p.skipNewlines()
+			r, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return &assign{op: t.text, lhs: l, rhs: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) ternary() (expr, error) {
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return c, nil
+	}
+	p.skipNewlines()
+	yes, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	no, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &condExpr{cond: c, yes: yes, no: no}, nil
+}
+
+// binaryLevel parses operands with next, joined by any of ops from left to
+// right.
+func (p *parser) binaryLevel(next func() (expr, error), ops ...string) (expr, error) {
+	l, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		for _, o := range ops {
+			if p.is(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return l, nil
+		}
+		p.next()
+		if op == "&&" || op == "||" {
+			p.skipNewlines()
+		}
+		r, err := next()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) or() (expr, error) {
+	return p.binaryLevel(p.and, "||")
+}
+
+func (p *parser) and() (expr, error) {
+	return p.binaryLevel(p.match, "&&")
+}
+
+func (p *parser) match() (expr, error) {
+	if p.is("in") {
+		return nil, p.errorf("arrays are not supported")
+	}
+	return p.binaryLevel(p.comparison, "~", "!~")
+}
+
+// comparison parses a comparison, which does not associate.
+func (p *parser) comparison() (expr, error) {
+	l, err := p.concat()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"<", "<=", "==", "!=", ">=", ">"} {
+		if op == ">" && p.noGT {
+			continue
+		}
+		if p.accept(op) {
+			r, err := p.concat()
+			if err != nil {
+				return nil, err
+			}
+			return &binary{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+// startsConcat returns whether the next token starts an operand of
+// concatenation. + and - are binary operators rather than signs there.
+func (p *parser) startsConcat() bool {
+	switch t := p.peek(); t.kind {
+	case tNumber, tString, tRegex, tName, tBuiltin:
+		return true
+	case tPunct:
+		return t.text == "$" || t.text == "(" || t.text == "!" || t.text == "++" || t.text == "--"
+	}
+	return false
+}
+
+func (p *parser) concat() (expr, error) {
+	l, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for p.startsConcat() {
+		r, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		l = &binary{op: " ", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) additive() (expr, error) {
+	return p.binaryLevel(p.multiplicative, "+", "-")
+}
+
+func (p *parser) multiplicative() (expr, error) {
+	return p.binaryLevel(p.unary, "*", "/", "%")
+}
+
+func (p *parser) unary() (expr, error) {
+	for _, op := range []string{"!", "-", "+"} {
+		if p.accept(op) {
+			e, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unary{op: op, e: e}, nil
+		}
+	}
+	return p.power()
+}
+
+// power parses ^, which associates to the right and binds tighter than a
+// sign on its left but not on its right: -2^2 is -4 and 2^-1 is 0.5.
+func (p *parser) power() (expr, error) {
+	l, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("^") {
+		return l, nil
+	}
+	var r expr
+	if p.is("-") || p.is("+") || p.is("!") {
+		r, err = p.unary()
+	} else {
+		r, err = p.power()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &binary{op: "^", l: l, r: r}, nil
+}
+
+func (p *parser) postfix() (expr, error) {
+	if p.is("++") || p.is("--") {
+		op := p.next().text
+		lv, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		if !isLvalue(lv) {
+			return nil, p.errorf("%s applied to a non-variable", op)
+		}
+		return &incDec{op: op, pre: true, lv: lv}, nil
+	}
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if isLvalue(e) && (p.is("++") || p.is("--")) {
+		return &incDec{op: p.next().text, lv: e}, nil
+	}
+	return e, nil
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tNumber:
+		return &numLit{n: t.num}, nil
+	case tString:
+		return &strLit{s: t.text}, nil
+	case tRegex:
+		re, err := regexp.Compile(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", t.line, err)
+		}
+		return &regexLit{re: re}, nil
+	case tName:
+		if p.is("[") {
+			return nil, p.errorf("arrays are not supported")
+		}
+		if p.is("(") {
+			return nil, p.errorf("functions are not supported")
+		}
+		return &varRef{name: t.text}, nil
+	case tBuiltin:
+		return p.call(t.text)
+	case tPunct:
+		switch t.text {
+		case "$":
+			var e expr
+			var err error
+			if p.is("++") || p.is("--") || p.is("-") || p.is("!") {
+				e, err = p.unary()
+			} else {
+				e, err = p.primary()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return &fieldRef{index: e}, nil
+		case "(":
+			noGT := p.noGT
+			p.noGT = false
+			defer func() { p.noGT = noGT }()
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if p.is(",") {
+				return nil, p.errorf("arrays are not supported")
+			}
+			return e, p.expect(")")
+		}
+	case tKeyword:
+		if t.text == "getline" {
+			return nil, p.errorf("getline is not supported")
+		}
+	}
+	p.pos--
+	return nil, p.unexpected()
+}
+
+// arity is the minimum and maximum number of arguments of the builtins.
+var arity = map[string][2]int{
+	"length":  {0, 1},
+	"substr":  {2, 3},
+	"index":   {2, 2},
+	"match":   {2, 2},
+	"sub":     {2, 3},
+	"gsub":    {2, 3},
+	"sprintf": {1, -1},
+	"tolower": {1, 1},
+	"toupper": {1, 1},
+	"int":     {1, 1},
+	"sqrt":    {1, 1},
+	"exp":     {1, 1},
+	"log":     {1, 1},
+}
+
+func (p *parser) call(name string) (expr, error) {
+	c := &call{name: name}
+	if !p.accept("(") {
+		// length without parentheses is the length of $0.
+		if name == "length" {
+			return c, nil
+		}
+		return nil, p.unexpected()
+	}
+	noGT := p.noGT
+	p.noGT = false
+	defer func() { p.noGT = noGT }()
+	if !p.accept(")") {
+		var err error
+		if c.args, err = p.exprList(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	a := arity[name]
+	if len(c.args) < a[0] || a[1] >= 0 && len(c.args) > a[1] {
+		return nil, p.errorf("wrong number of arguments to %s", name)
+	}
+	if (name == "sub" || name == "gsub") && len(c.args) == 3 && !isLvalue(c.args[2]) {
+		return nil, p.errorf("%s: third argument is not a variable", name)
+	}
+	return c, nil
+}
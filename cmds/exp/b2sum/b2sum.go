@@ -0,0 +1,76 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// b2sum prints or checks BLAKE2b hashes.
+//
+// Synopsis:
+//     b2sum [-l BITS] [FILE]...
+//     b2sum -c [-l BITS] [FILE]...
+//
+// Description:
+//     b2sum prints the BLAKE2b hash of each FILE, or stdin, followed by two
+//     spaces and the name of the FILE. A FILE of - is stdin.
+//
+//     With -c, FILEs contain lines in this format, and b2sum checks that
+//     the files they name have their hashes. The length of a hash is that
+//     of -l, or else of the hash in the line.
+//
+// Options:
+//     -c: check the hashes in FILEs
+//     -l: hash length in bits, a multiple of 8 up to 512, which is the
+//         default
+package main
+
+import (
+	"errors"
+	"hash"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/hashsum"
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	check  = flag.BoolP("check", "c", false, "check the hashes in FILEs")
+	length = flag.IntP("length", "l", 0, "hash length in bits, a multiple of 8 up to 512")
+)
+
+var errLength = errors.New("invalid hash length")
+
+// newHash returns a BLAKE2b hash of bits bits.
+func newHash(bits int) (hash.Hash, error) {
+	if bits <= 0 || bits > 512 || bits%8 != 0 {
+		return nil, errLength
+	}
+	return blake2b.New(bits/8, nil)
+}
+
+// hashFor returns the hash for a line to check, of bits bits, or of the
+// length of its hash if bits is 0.
+func hashFor(bits int) func(sum string) (hash.Hash, error) {
+	return func(sum string) (hash.Hash, error) {
+		if bits == 0 {
+			return newHash(len(sum) * 4)
+		}
+		return newHash(bits)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("b2sum: ")
+	flag.Parse()
+	bits := *length
+	if bits == 0 && !*check {
+		bits = 512
+	}
+	if _, err := newHash(bits); bits != 0 && err != nil {
+		log.Fatalf("%v: %d", err, bits)
+	}
+	if !hashsum.Run(os.Stdout, os.Stdin, flag.Args(), *check, hashFor(bits)) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashFor(t *testing.T) {
+	for _, tt := range []struct {
+		bits int
+		sum  string
+		want string
+	}{
+		{512, "", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{128, "", "cf4ab791c62b8d2b2109c90275287816"},
+		{0, "00112233445566778899aabbccddeeff", "cf4ab791c62b8d2b2109c90275287816"},
+		{8, "00112233445566778899aabbccddeeff", "6b"},
+	} {
+		h, err := hashFor(tt.bits)(tt.sum)
+		if err != nil {
+			t.Errorf("hashFor(%d)(%q) = %v", tt.bits, tt.sum, err)
+			continue
+		}
+		h.Write([]byte("abc"))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("hashFor(%d)(%q) sum = %s, want %s", tt.bits, tt.sum, got, tt.want)
+		}
+	}
+	for _, bits := range []int{-8, 7, 520} {
+		if _, err := newHash(bits); err != errLength {
+			t.Errorf("newHash(%d) = %v, want %v", bits, err, errLength)
+		}
+	}
+}
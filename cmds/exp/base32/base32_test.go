@@ -0,0 +1,35 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		decode bool
+		ignore bool
+		wrap   int
+		want   string
+		err    bool
+	}{
+		{in: "hello, world\n", wrap: 76, want: "NBSWY3DPFQQHO33SNRSAU===\n"},
+		{in: "hello, world\n", wrap: 8, want: "NBSWY3DP\nFQQHO33S\nNRSAU===\n"},
+		{in: "hello, world\n", want: "NBSWY3DPFQQHO33SNRSAU==="},
+		{in: "NBSWY3DP\nFQQHO33S\nNRSAU===\n", decode: true, want: "hello, world\n"},
+		{in: "NBSWY:3DPFQ======", decode: true, err: true, want: ""},
+		{in: "NBSWY:3DPFQ======", decode: true, ignore: true, want: "hello,"},
+	} {
+		var out bytes.Buffer
+		err := run(&out, strings.NewReader(tt.in), tt.decode, tt.ignore, tt.wrap)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("run(%q, %v, %v, %d) = %q, %v, want %q", tt.in, tt.decode, tt.ignore, tt.wrap, out.String(), err, tt.want)
+		}
+	}
+}
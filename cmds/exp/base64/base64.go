@@ -0,0 +1,71 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// base64 encodes or decodes base64.
+//
+// Synopsis:
+//     base64 [-d] [-i] [-w COLS] [FILE]
+//
+// Description:
+//     base64 encodes FILE, or stdin, to base64, in lines of COLS
+//     characters. With -d, it decodes FILE instead. A FILE of - is stdin.
+//
+// Options:
+//     -d: decode
+//     -i: when decoding, ignore characters that are not base64
+//     -w: wrap encoded lines after COLS characters, 76 by default, or
+//         never if COLS is 0
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/basenc"
+)
+
+var (
+	decode        = flag.BoolP("decode", "d", false, "decode")
+	ignoreGarbage = flag.BoolP("ignore-garbage", "i", false, "when decoding, ignore characters that are not base64")
+	wrap          = flag.IntP("wrap", "w", 76, "wrap encoded lines after COLS characters, or never if 0")
+)
+
+func run(w io.Writer, r io.Reader, decode, ignoreGarbage bool, wrap int) error {
+	if decode {
+		return basenc.Base64.Decode(w, r, ignoreGarbage)
+	}
+	return basenc.Base64.Encode(w, r, wrap)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("base64: ")
+	flag.Parse()
+	if flag.NArg() > 1 {
+		log.Fatal("usage: base64 [-d] [-i] [-w COLS] [FILE]")
+	}
+	if *wrap < 0 {
+		log.Fatalf("invalid wrap size %d", *wrap)
+	}
+	var r io.Reader = os.Stdin
+	if name := flag.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	w := bufio.NewWriter(os.Stdout)
+	err := run(w, r, *decode, *ignoreGarbage, *wrap)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		decode bool
+		ignore bool
+		wrap   int
+		want   string
+		err    bool
+	}{
+		{in: "hello, world\n", wrap: 76, want: "aGVsbG8sIHdvcmxkCg==\n"},
+		{in: "hello, world\n", wrap: 8, want: "aGVsbG8s\nIHdvcmxk\nCg==\n"},
+		{in: "hello, world\n", want: "aGVsbG8sIHdvcmxkCg=="},
+		{in: "aGVsbG8s\nIHdvcmxk\nCg==\n", decode: true, want: "hello, world\n"},
+		{in: "aGVsb:G8s", decode: true, err: true, want: "hel"},
+		{in: "aGVsb:G8s", decode: true, ignore: true, want: "hello,"},
+	} {
+		var out bytes.Buffer
+		err := run(&out, strings.NewReader(tt.in), tt.decode, tt.ignore, tt.wrap)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("run(%q, %v, %v, %d) = %q, %v, want %q", tt.in, tt.decode, tt.ignore, tt.wrap, out.String(), err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// blkdiscard discards sectors of a block device.
+//
+// Synopsis:
+//     blkdiscard [-fsvz] [-o OFFSET] [-l LENGTH] [-p STEP] DEVICE
+//
+// Description:
+//     Tell DEVICE that the data in a byte range is no longer needed, which
+//     for SSDs is a TRIM. The range starts at OFFSET and is LENGTH bytes
+//     long, by default the whole device. Both must be multiples of the
+//     device's discard granularity, or of its sector size for -z.
+//
+//     Discarding destroys the data, so blkdiscard asks for confirmation
+//     unless -f is given.
+//
+//     Sizes may have a K, M, G or T suffix for powers of 1024.
+//
+// Options:
+//     -f, --force:   do not ask for confirmation
+//     -l, --length:  number of bytes to discard
+//     -o, --offset:  byte offset to start at
+//     -p, --step:    discard this many bytes per request
+//     -s, --secure:  securely discard, so that the data can not be recovered
+//     -v, --verbose: print each discarded range
+//     -z, --zeroout: zero the range instead of discarding it
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+// ioctls from linux/fs.h, which are missing in x/sys/unix.
+const (
+	blkDiscard    = 0x1277 // _IO(0x12, 119)
+	blkSecDiscard = 0x127d // _IO(0x12, 125)
+	blkZeroOut    = 0x127f // _IO(0x12, 127)
+)
+
+var (
+	force   = flag.BoolP("force", "f", false, "do not ask for confirmation")
+	length  = flag.StringP("length", "l", "", "number of bytes to discard")
+	offset  = flag.StringP("offset", "o", "0", "byte offset to start at")
+	step    = flag.StringP("step", "p", "", "discard this many bytes per request")
+	secure  = flag.BoolP("secure", "s", false, "securely discard")
+	verbose = flag.BoolP("verbose", "v", false, "print each discarded range")
+	zeroout = flag.BoolP("zeroout", "z", false, "zero the range instead of discarding it")
+
+	// sysBlock is a variable so it can be changed in tests.
+	sysBlock = "/sys/class/block"
+)
+
+// parseSize parses a size with an optional K, M, G or T suffix.
+func parseSize(s string) (uint64, error) {
+	num, shift := s, uint(0)
+	if i := strings.IndexAny(s, "KMGTkmgt"); i >= 0 && i == len(s)-1 {
+		shift = 10 * uint(strings.IndexByte("KMGT", s[i]&^0x20)+1)
+		num = s[:i]
+	}
+	n, err := strconv.ParseUint(num, 0, 64)
+	if err != nil || n > ^uint64(0)>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
+
+// queueValue reads a value from the request queue of the block device
+// named dev in sysfs. Partitions share the queue of their disk.
+func queueValue(dev, name string) (uint64, error) {
+	dir, err := filepath.EvalSymlinks(filepath.Join(sysBlock, dev))
+	if err != nil {
+		return 0, err
+	}
+	var b []byte
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		if b, err = ioutil.ReadFile(filepath.Join(d, "queue", name)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// checkRange checks that [off, off+n) is within size bytes and aligned to
+// align bytes.
+func checkRange(off, n, size, align uint64) error {
+	if off > size || n > size-off {
+		return fmt.Errorf("range %d+%d exceeds the device size of %d bytes", off, n, size)
+	}
+	if align == 0 {
+		return nil
+	}
+	if off%align != 0 {
+		return fmt.Errorf("offset %d is not aligned to %d bytes", off, align)
+	}
+	if n%align != 0 {
+		return fmt.Errorf("length %d is not aligned to %d bytes", n, align)
+	}
+	return nil
+}
+
+// confirm asks whether to go ahead on w and reads the answer from r.
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func ioctlRange(fd int, req uintptr, off, n uint64) error {
+	r := [2]uint64{off, n}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(&r))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func blkdiscard(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|unix.O_EXCL, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return fmt.Errorf("%s is not a block device", path)
+	}
+	dev := fmt.Sprintf("%d:%d", unix.Major(st.Rdev), unix.Minor(st.Rdev))
+	name, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", dev))
+	if err != nil {
+		return err
+	}
+	name = filepath.Base(name)
+
+	var size uint64
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.BLKGETSIZE64, uintptr(unsafe.Pointer(&size))); errno != 0 {
+		return os.NewSyscallError("ioctl(BLKGETSIZE64)", errno)
+	}
+	sector, err := unix.IoctlGetInt(fd, unix.BLKSSZGET)
+	if err != nil {
+		return os.NewSyscallError("ioctl(BLKSSZGET)", err)
+	}
+
+	req, op := uintptr(blkDiscard), "discard"
+	align := uint64(sector)
+	switch {
+	case *zeroout:
+		req, op = blkZeroOut, "zero"
+	case *secure:
+		req, op = blkSecDiscard, "securely discard"
+		fallthrough
+	default:
+		g, err := queueValue(name, "discard_granularity")
+		if err != nil {
+			return fmt.Errorf("reading discard granularity: %v", err)
+		}
+		if g == 0 {
+			return fmt.Errorf("%s does not support discard", path)
+		}
+		if g > align {
+			align = g
+		}
+	}
+
+	off, err := parseSize(*offset)
+	if err != nil {
+		return err
+	}
+	n := size - off
+	if off > size {
+		n = 0
+	}
+	if *length != "" {
+		if n, err = parseSize(*length); err != nil {
+			return err
+		}
+	}
+	if err := checkRange(off, n, size, align); err != nil {
+		return err
+	}
+	stepSize := n
+	if *step != "" {
+		if stepSize, err = parseSize(*step); err != nil {
+			return err
+		}
+		if stepSize == 0 || stepSize%align != 0 {
+			return fmt.Errorf("step %d is not a multiple of %d bytes", stepSize, align)
+		}
+	}
+
+	if !*force && !confirm(os.Stdin, os.Stderr, fmt.Sprintf("%s %d bytes of %s at offset %d?", op, n, path, off)) {
+		return errors.New("aborted")
+	}
+
+	for end := off + n; off < end; off += stepSize {
+		if stepSize > end-off {
+			stepSize = end - off
+		}
+		if err := ioctlRange(fd, req, off, stepSize); err != nil {
+			return fmt.Errorf("%s: %s %d+%d: %v", path, op, off, stepSize, err)
+		}
+		if *verbose {
+			fmt.Printf("%s: %s %d bytes from offset %d\n", path, op, stepSize, off)
+		}
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("blkdiscard: ")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: blkdiscard [-fsvz] [-o OFFSET] [-l LENGTH] [-p STEP] DEVICE")
+	}
+	if err := blkdiscard(flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
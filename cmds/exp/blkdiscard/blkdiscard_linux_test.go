@@ -0,0 +1,111 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want uint64
+		err  bool
+	}{
+		{in: "0", want: 0},
+		{in: "4096", want: 4096},
+		{in: "0x1000", want: 4096},
+		{in: "4K", want: 4096},
+		{in: "1m", want: 1 << 20},
+		{in: "2G", want: 2 << 30},
+		{in: "1T", want: 1 << 40},
+		{in: "K", err: true},
+		{in: "1KB", err: true},
+		{in: "-1", err: true},
+		{in: "16777216T", err: true},
+	} {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseSize(%q) = (%d, %v), want (%d, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCheckRange(t *testing.T) {
+	for _, tt := range []struct {
+		off, n, size, align uint64
+		err                 string
+	}{
+		{off: 0, n: 1 << 20, size: 1 << 20, align: 4096},
+		{off: 4096, n: 8192, size: 1 << 20, align: 4096},
+		{off: 512, n: 512, size: 1 << 20, align: 0},
+		{off: 512, n: 4096, size: 1 << 20, align: 4096, err: "offset 512 is not aligned"},
+		{off: 4096, n: 512, size: 1 << 20, align: 4096, err: "length 512 is not aligned"},
+		{off: 4096, n: 1 << 20, size: 1 << 20, align: 4096, err: "exceeds the device size"},
+		{off: 2 << 20, n: 0, size: 1 << 20, align: 4096, err: "exceeds the device size"},
+	} {
+		err := checkRange(tt.off, tt.n, tt.size, tt.align)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("checkRange(%d, %d, %d, %d) = %v, want %q", tt.off, tt.n, tt.size, tt.align, err, tt.err)
+		}
+	}
+}
+
+func TestQueueValue(t *testing.T) {
+	d, err := ioutil.TempDir("", "blkdiscard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	if err := os.MkdirAll(filepath.Join(d, "sda/sda1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(d, "sda/queue"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(d, "sda/queue/discard_granularity"), []byte("4096\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sda/sda1", filepath.Join(d, "sda1")); err != nil {
+		t.Fatal(err)
+	}
+
+	sysBlock = d
+	defer func() { sysBlock = "/sys/class/block" }()
+	for _, dev := range []string{"sda", "sda1"} {
+		if g, err := queueValue(dev, "discard_granularity"); err != nil || g != 4096 {
+			t.Errorf("queueValue(%q) = (%d, %v), want (4096, nil)", dev, g, err)
+		}
+	}
+	if _, err := queueValue("sdb", "discard_granularity"); err == nil {
+		t.Errorf("queueValue(sdb) succeeded, want error")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want bool
+	}{
+		{in: "y\n", want: true},
+		{in: "YES\n", want: true},
+		{in: "n\n"},
+		{in: "\n"},
+		{in: ""},
+	} {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(tt.in), &out, "discard?"); got != tt.want {
+			t.Errorf("confirm(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if out.String() != "discard? [y/N] " {
+			t.Errorf("confirm printed %q, want %q", out.String(), "discard? [y/N] ")
+		}
+	}
+}
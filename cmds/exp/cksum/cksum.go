@@ -2,41 +2,85 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// cksum prints the POSIX checksum and size of files.
+//
+// Synopsis:
+//     cksum [FILE]...
+//
+// Description:
+//     cksum prints the CRC of each FILE, or stdin, as POSIX specifies it,
+//     followed by its size in bytes and its name. A FILE of - is stdin,
+//     and no name is printed for stdin without FILEs.
 package main
 
 import (
 	"fmt"
-	"hash/crc32"
-	"io/ioutil"
+	"io"
+	"log"
 	"os"
 
 	"github.com/spf13/pflag"
 )
 
-func reverse(x uint32) uint32 {
-	x = (((x & 0xaaaaaaaa) >> 1) | ((x & 0x55555555) << 1))
-	x = (((x & 0xcccccccc) >> 2) | ((x & 0x33333333) << 2))
-	x = (((x & 0xf0f0f0f0) >> 4) | ((x & 0x0f0f0f0f) << 4))
-	x = (((x & 0xff00ff00) >> 8) | ((x & 0x00ff00ff) << 8))
-	return ((x >> 16) | (x << 16))
+// polynomial is the CRC polynomial of POSIX cksum, which is not reflected,
+// unlike that of hash/crc32.
+const polynomial = 0x04c11db7
+
+var table = func() (t [256]uint32) {
+	for i := range t {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ polynomial
+			} else {
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return t
+}()
+
+func update(crc uint32, p []byte) uint32 {
+	for _, b := range p {
+		crc = crc<<8 ^ table[byte(crc>>24)^b]
+	}
+	return crc
 }
 
-func reverseByte(b byte) byte {
-	b = (b&0xF0)>>4 | (b&0x0F)<<4
-	b = (b&0xCC)>>2 | (b&0x33)<<2
-	b = (b&0xAA)>>1 | (b&0x55)<<1
-	return b
+// digest is the POSIX checksum, a hash.Hash32. The CRC is of the data
+// followed by its length, in as few bytes as it takes, least significant
+// first.
+type digest struct {
+	crc uint32
+	n   uint64
 }
 
-func reverseBytes(b []byte) []byte {
-	for i := range b {
-		b[i] = reverseByte(b[i])
+func (d *digest) Write(p []byte) (int, error) {
+	d.crc = update(d.crc, p)
+	d.n += uint64(len(p))
+	return len(p), nil
+}
+
+func (d *digest) Sum32() uint32 {
+	crc := d.crc
+	for n := d.n; n > 0; n >>= 8 {
+		crc = update(crc, []byte{byte(n)})
 	}
-	return b
+	return ^crc
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	s := d.Sum32()
+	return append(b, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
 }
 
+func (d *digest) Reset()         { *d = digest{} }
+func (d *digest) Size() int      { return 4 }
+func (d *digest) BlockSize() int { return 1 }
+
 func helpPrinter() {
-	fmt.Printf("Usage:\ncksum <File Name>\n")
+	fmt.Printf("Usage:\ncksum [File Name]...\n")
 	pflag.PrintDefaults()
 	os.Exit(0)
 }
@@ -46,24 +90,52 @@ func versionPrinter() {
 	os.Exit(0)
 }
 
-func getInput(fileName string) (input []byte, err error) {
-	if fileName != "" {
-		return ioutil.ReadFile(fileName)
+// cksum prints the checksum of r, with name unless it is empty.
+func cksum(w io.Writer, r io.Reader, name string) error {
+	d := &digest{}
+	if _, err := io.Copy(d, r); err != nil {
+		return err
 	}
-	return ioutil.ReadAll(os.Stdin)
-}
-
-func appendLengthToData(data []byte) []byte {
-	length := len(data)
-	for length > 0 {
-		data = append(data, byte(length))
-		length = (length >> 8)
+	if name == "" {
+		_, err := fmt.Fprintf(w, "%d %d\n", d.Sum32(), d.n)
+		return err
 	}
-	return data
+	_, err := fmt.Fprintf(w, "%d %d %s\n", d.Sum32(), d.n, name)
+	return err
 }
 
-func calculateCksum(data []byte) uint32 {
-	return reverse(crc32.Update(0xffffffff, crc32.MakeTable(0xEDB88320), reverseBytes(appendLengthToData(data))))
+// run prints the checksums of files, or of stdin if there are none, and
+// returns whether all could be read.
+func run(w io.Writer, stdin io.Reader, names []string) bool {
+	if len(names) == 0 {
+		if err := cksum(w, stdin, ""); err != nil {
+			log.Print(err)
+			return false
+		}
+		return true
+	}
+	ok := true
+	for _, name := range names {
+		if name == "-" {
+			if err := cksum(w, stdin, name); err != nil {
+				log.Print(err)
+				ok = false
+			}
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			log.Print(err)
+			ok = false
+			continue
+		}
+		if err := cksum(w, f, name); err != nil {
+			log.Printf("%s: %v", name, err)
+			ok = false
+		}
+		f.Close()
+	}
+	return ok
 }
 
 func main() {
@@ -71,7 +143,8 @@ func main() {
 		help    bool
 		version bool
 	)
-	cliArgs := ""
+	log.SetFlags(0)
+	log.SetPrefix("cksum: ")
 	pflag.BoolVarP(&help, "help", "h", false, "Show this help and exit")
 	pflag.BoolVarP(&version, "version", "v", false, "Print Version")
 	pflag.Parse()
@@ -83,12 +156,7 @@ func main() {
 	if version {
 		versionPrinter()
 	}
-	if len(os.Args) >= 2 {
-		cliArgs = os.Args[1]
-	}
-	input, err := getInput(cliArgs)
-	if err != nil {
-		return
+	if !run(os.Stdout, os.Stdin, pflag.Args()) {
+		os.Exit(1)
 	}
-	fmt.Println(calculateCksum(input), len(input), cliArgs)
 }
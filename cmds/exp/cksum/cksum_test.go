@@ -5,6 +5,11 @@
 package main
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/u-root/u-root/pkg/testutil"
@@ -25,8 +30,37 @@ func TestCksum(t *testing.T) {
 	}
 
 	for _, testData := range testMatrix {
-		if testData.cksum != calculateCksum(testData.data) {
-			t.Errorf("Cksum verification failed. (Expected: %d, Received: %d)", testData.cksum, calculateCksum(testData.data))
+		d := &digest{}
+		d.Write(testData.data)
+		if testData.cksum != d.Sum32() {
+			t.Errorf("Cksum verification failed. (Expected: %d, Received: %d)", testData.cksum, d.Sum32())
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "f")
+	if err := ioutil.WriteFile(f, []byte("pqra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		names []string
+		want  string
+		ok    bool
+	}{
+		{nil, "3512391007 7\n", true},
+		{[]string{"-"}, "3512391007 7 -\n", true},
+		{[]string{f, filepath.Join(dir, "missing"), f}, "1063566492 5 " + f + "\n1063566492 5 " + f + "\n", false},
+	} {
+		var out bytes.Buffer
+		ok := run(&out, strings.NewReader("abcdef\n"), tt.names)
+		if out.String() != tt.want || ok != tt.ok {
+			t.Errorf("run(%q) = %q, %v, want %q, %v", tt.names, out.String(), ok, tt.want, tt.ok)
 		}
 	}
 }
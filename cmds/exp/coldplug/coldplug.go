@@ -0,0 +1,113 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// coldplug brings up the devices that appeared before userspace started.
+//
+// Synopsis:
+//     coldplug [-Mnv] [-a ACTION] [-d DIR]
+//
+// Description:
+//     The kernel sends a uevent for each device it finds, but those sent
+//     before userspace started listening are lost. coldplug replays them,
+//     like udevadm trigger does: it loads the modules matching the
+//     modaliases of the devices, so that drivers bind to them, and writes
+//     ACTION to the uevent file of every device, which makes the kernel
+//     send the uevent again.
+//
+//     Modules are found through modules.dep and modules.alias, and
+//     blacklisted modules are not loaded. Drivers may request firmware as
+//     they bind; run fwload to serve requests the kernel can not.
+//
+// Options:
+//     -a, --action:     uevent action to trigger (default add)
+//     -d, --dir:        sysfs devices directory (default /sys/devices)
+//     -M, --no-modules: do not load modules
+//     -n, --dry-run:    print what would be done
+//     -v, --verbose:    print each module loaded and device triggered
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+	"github.com/u-root/u-root/pkg/libinit"
+)
+
+var (
+	action    = flag.StringP("action", "a", "add", "uevent action to trigger")
+	dir       = flag.StringP("dir", "d", libinit.SysDevices, "sysfs devices directory")
+	noModules = flag.BoolP("no-modules", "M", false, "do not load modules")
+	dryRun    = flag.BoolP("dry-run", "n", false, "print what would be done")
+	verbose   = flag.BoolP("verbose", "v", false, "print each module loaded and device triggered")
+)
+
+// actions are the actions the kernel accepts in uevent files.
+var actions = map[string]bool{
+	"add":     true,
+	"remove":  true,
+	"change":  true,
+	"move":    true,
+	"online":  true,
+	"offline": true,
+	"bind":    true,
+	"unbind":  true,
+}
+
+func run() error {
+	if !actions[*action] {
+		return fmt.Errorf("invalid action %q", *action)
+	}
+	aliases, err := libinit.Modaliases(*dir)
+	if err != nil {
+		return err
+	}
+
+	if !*noModules {
+		if *dryRun {
+			for _, a := range aliases {
+				if p, err := kmodule.Path(a, kmodule.ProbeOpts{UseBlacklist: true}); err == nil {
+					fmt.Printf("load %s for %s\n", p, a)
+				}
+			}
+		} else {
+			for _, p := range libinit.LoadModaliasModules(aliases) {
+				if *verbose {
+					log.Printf("loaded %s", p)
+				}
+			}
+		}
+	}
+
+	if *dryRun {
+		files, err := libinit.UeventFiles(*dir)
+		for _, f := range files {
+			fmt.Printf("write %s to %s\n", *action, f)
+		}
+		return err
+	}
+	written, err := libinit.TriggerUevents(*dir, *action)
+	if *verbose {
+		for _, p := range written {
+			log.Printf("triggered %s", p)
+		}
+	}
+	return err
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("coldplug: ")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
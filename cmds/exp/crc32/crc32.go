@@ -0,0 +1,45 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// crc32 prints or checks CRC-32 checksums.
+//
+// Synopsis:
+//     crc32 [-c] [FILE]...
+//
+// Description:
+//     crc32 prints the IEEE CRC-32 of each FILE, or stdin, as 8 hex digits,
+//     followed by two spaces and the name of the FILE. A FILE of - is
+//     stdin. This is the CRC of gzip and zip files.
+//
+//     With -c, FILEs contain lines in this format, and crc32 checks that
+//     the files they name have their checksums.
+//
+// Options:
+//     -c: check the checksums in FILEs
+package main
+
+import (
+	"hash"
+	"hash/crc32"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/hashsum"
+)
+
+var check = flag.BoolP("check", "c", false, "check the checksums in FILEs")
+
+func newHash(string) (hash.Hash, error) {
+	return crc32.NewIEEE(), nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("crc32: ")
+	flag.Parse()
+	if !hashsum.Run(os.Stdout, os.Stdin, flag.Args(), *check, newHash) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// cryptsetup opens and closes LUKS2 encrypted volumes.
+//
+// Synopsis:
+//     cryptsetup open [-r] [--allow-discards] [-d FILE] [--test-passphrase] DEVICE [NAME]
+//     cryptsetup close NAME
+//     cryptsetup luksDump DEVICE
+//     cryptsetup isLuks DEVICE
+//
+// Description:
+//     open:     unlock a keyslot of DEVICE with a passphrase and map its
+//               data to /dev/mapper/NAME with dm-crypt
+//     close:    remove the mapping NAME
+//     luksDump: print the LUKS2 header of DEVICE
+//     isLuks:   exit with 0 if DEVICE has a LUKS2 header, 1 otherwise
+//
+//     The passphrase is read from FILE, or from the terminal. If stdin is
+//     not a terminal, its first line is the passphrase. With -d -, all of
+//     stdin is the key, so a key unsealed by the TPM can be piped in:
+//
+//     tpmseal -nv 0x1500001 unseal | cryptsetup open -d - /dev/sda2 root
+//
+//     luksOpen and luksClose are aliases of open and close.
+//
+// Options:
+//     -d, --key-file:        read the key from FILE, - for stdin
+//     -r, --readonly:        map the volume read-only
+//     --allow-discards:      pass discards through to DEVICE
+//     --test-passphrase:     only check the passphrase, do not map DEVICE
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/dm"
+	"github.com/u-root/u-root/pkg/luks"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const usage = `usage: cryptsetup open [-r] [--allow-discards] [-d FILE] [--test-passphrase] DEVICE [NAME]
+       cryptsetup close NAME
+       cryptsetup luksDump DEVICE
+       cryptsetup isLuks DEVICE`
+
+var (
+	keyFile        = flag.StringP("key-file", "d", "", "read the key from `FILE`, - for stdin")
+	readOnly       = flag.BoolP("readonly", "r", false, "map the volume read-only")
+	allowDiscards  = flag.Bool("allow-discards", false, "pass discards through to the device")
+	testPassphrase = flag.Bool("test-passphrase", false, "only check the passphrase")
+)
+
+// readPassphrase reads the passphrase of device.
+func readPassphrase(device string, stdin *os.File) ([]byte, error) {
+	switch {
+	case *keyFile == "-":
+		return ioutil.ReadAll(stdin)
+	case *keyFile != "":
+		return ioutil.ReadFile(*keyFile)
+	case terminal.IsTerminal(int(stdin.Fd())):
+		fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", device)
+		p, err := terminal.ReadPassword(int(stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return p, err
+	}
+	return readLine(stdin)
+}
+
+// readLine reads the first line of r without its newline.
+func readLine(r io.Reader) ([]byte, error) {
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("no passphrase given")
+	}
+	return []byte(strings.TrimSuffix(string(line), "\n")), nil
+}
+
+// sortIDs sorts ids in numerical order.
+func sortIDs(ids []string) []string {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	return ids
+}
+
+func orNone(s, none string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
+
+// dump prints h like cryptsetup luksDump.
+func dump(w io.Writer, h *luks.Header) {
+	fmt.Fprintf(w, "LUKS header information\n")
+	fmt.Fprintf(w, "Version:       \t%d\n", h.Version)
+	fmt.Fprintf(w, "Epoch:         \t%d\n", h.SeqID)
+	fmt.Fprintf(w, "Metadata area: \t%d [bytes]\n", h.Size)
+	fmt.Fprintf(w, "Keyslots area: \t%d [bytes]\n", h.Config.KeyslotsSize)
+	fmt.Fprintf(w, "UUID:          \t%s\n", h.UUID)
+	fmt.Fprintf(w, "Label:         \t%s\n", orNone(h.Label, "(no label)"))
+	fmt.Fprintf(w, "Subsystem:     \t%s\n", orNone(h.Subsystem, "(no subsystem)"))
+	fmt.Fprintf(w, "Flags:         \t%s\n", orNone(strings.Join(h.Config.Flags, " "), "(no flags)"))
+
+	var segments, keyslots, digests []string
+	for id := range h.Segments {
+		segments = append(segments, id)
+	}
+	for id := range h.Keyslots {
+		keyslots = append(keyslots, id)
+	}
+	for id := range h.Digests {
+		digests = append(digests, id)
+	}
+
+	fmt.Fprintf(w, "\nData segments:\n")
+	for _, id := range sortIDs(segments) {
+		s := h.Segments[id]
+		fmt.Fprintf(w, "  %s: %s\n", id, s.Type)
+		fmt.Fprintf(w, "\toffset: %d [bytes]\n", s.Offset)
+		if s.Size == "dynamic" {
+			fmt.Fprintf(w, "\tlength: (whole device)\n")
+		} else {
+			fmt.Fprintf(w, "\tlength: %s [bytes]\n", s.Size)
+		}
+		fmt.Fprintf(w, "\tcipher: %s\n", s.Encryption)
+		fmt.Fprintf(w, "\tsector: %d [bytes]\n", s.SectorSize)
+	}
+
+	fmt.Fprintf(w, "\nKeyslots:\n")
+	for _, id := range sortIDs(keyslots) {
+		k := h.Keyslots[id]
+		fmt.Fprintf(w, "  %s: %s\n", id, k.Type)
+		fmt.Fprintf(w, "\tKey:        %d bits\n", k.KeySize*8)
+		fmt.Fprintf(w, "\tCipher:     %s\n", k.Area.Encryption)
+		fmt.Fprintf(w, "\tPBKDF:      %s\n", k.KDF.Type)
+		if k.KDF.Type == "pbkdf2" {
+			fmt.Fprintf(w, "\tHash:       %s\n", k.KDF.Hash)
+			fmt.Fprintf(w, "\tIterations: %d\n", k.KDF.Iterations)
+		} else {
+			fmt.Fprintf(w, "\tTime cost:  %d\n", k.KDF.Time)
+			fmt.Fprintf(w, "\tMemory:     %d\n", k.KDF.Memory)
+			fmt.Fprintf(w, "\tThreads:    %d\n", k.KDF.CPUs)
+		}
+		fmt.Fprintf(w, "\tAF stripes: %d\n", k.AF.Stripes)
+		fmt.Fprintf(w, "\tAF hash:    %s\n", k.AF.Hash)
+		fmt.Fprintf(w, "\tArea offset:%d [bytes]\n", k.Area.Offset)
+		fmt.Fprintf(w, "\tArea length:%d [bytes]\n", k.Area.Size)
+	}
+
+	fmt.Fprintf(w, "Digests:\n")
+	for _, id := range sortIDs(digests) {
+		d := h.Digests[id]
+		fmt.Fprintf(w, "  %s: %s\n", id, d.Type)
+		fmt.Fprintf(w, "\tHash:       %s\n", d.Hash)
+		fmt.Fprintf(w, "\tIterations: %d\n", d.Iterations)
+	}
+}
+
+func readHeader(device string) (*os.File, *luks.Header, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := luks.ReadHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s is not a valid LUKS2 device: %v", device, err)
+	}
+	return f, h, nil
+}
+
+func open(device, name string) error {
+	f, h, err := readHeader(device)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	passphrase, err := readPassphrase(device, os.Stdin)
+	if err != nil {
+		return err
+	}
+	key, keyslot, err := h.Unlock(f, passphrase)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	log.Printf("Key slot %s unlocked.", keyslot)
+	if *testPassphrase {
+		return nil
+	}
+
+	s, err := h.Segment(keyslot)
+	if err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	sectors, err := s.Sectors(size)
+	if err != nil {
+		return err
+	}
+	var flags uint32
+	if *readOnly {
+		flags |= dm.ReadOnly
+	}
+	_, err = dm.Create(name, flags, []dm.Target{{
+		Length: sectors,
+		Type:   "crypt",
+		Params: s.CryptParams(key, device, *allowDiscards),
+	}})
+	return err
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	cmd, args := args[0], args[1:]
+	switch {
+	case (cmd == "open" || cmd == "luksOpen") && len(args) == 2:
+		return open(args[0], args[1])
+	case (cmd == "open" || cmd == "luksOpen") && len(args) == 1 && *testPassphrase:
+		return open(args[0], "")
+	case (cmd == "close" || cmd == "luksClose") && len(args) == 1:
+		return dm.Remove(args[0])
+	case cmd == "luksDump" && len(args) == 1:
+		f, h, err := readHeader(args[0])
+		if err != nil {
+			return err
+		}
+		f.Close()
+		dump(stdout, h)
+		return nil
+	case cmd == "isLuks" && len(args) == 1:
+		f, _, err := readHeader(args[0])
+		if err != nil {
+			os.Exit(1)
+		}
+		f.Close()
+		return nil
+	}
+	return errors.New(usage)
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/luks"
+)
+
+func TestReadLine(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "secret\n", want: "secret"},
+		{in: "secret", want: "secret"},
+		{in: "two words\nnext\n", want: "two words"},
+		{in: "\n", want: ""},
+		{in: "", err: true},
+	} {
+		got, err := readLine(strings.NewReader(tt.in))
+		if (err != nil) != tt.err || string(got) != tt.want {
+			t.Errorf("readLine(%q) = (%q, %v), want (%q, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	h := &luks.Header{
+		Version: 2,
+		SeqID:   3,
+		UUID:    "5f5fa7b6-1b8b-4c3a-8f6c-0f4e3c6a9d21",
+		Size:    16384,
+		Metadata: luks.Metadata{
+			Keyslots: map[string]luks.Keyslot{
+				"10": {Type: "luks2", KeySize: 64, KDF: luks.KDF{Type: "pbkdf2", Hash: "sha256", Iterations: 1000}},
+				"2":  {Type: "luks2", KeySize: 64, KDF: luks.KDF{Type: "argon2id", Time: 4, Memory: 1024, CPUs: 2}},
+			},
+			Segments: map[string]luks.Segment{
+				"0": {Type: "crypt", Offset: 16777216, Size: "dynamic", Encryption: "aes-xts-plain64", SectorSize: 512},
+			},
+			Digests: map[string]luks.Digest{
+				"0": {Type: "pbkdf2", Hash: "sha256", Iterations: 1000},
+			},
+		},
+	}
+	var b bytes.Buffer
+	dump(&b, h)
+	out := b.String()
+	for _, want := range []string{
+		"Version:       \t2\n",
+		"Label:         \t(no label)\n",
+		"\toffset: 16777216 [bytes]\n\tlength: (whole device)\n",
+		"\tPBKDF:      argon2id\n\tTime cost:  4\n",
+		"\tPBKDF:      pbkdf2\n\tHash:       sha256\n\tIterations: 1000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump output does not contain %q:\n%s", want, out)
+		}
+	}
+	// Keyslots are in numerical order.
+	if strings.Index(out, "  2: luks2") > strings.Index(out, "  10: luks2") {
+		t.Errorf("keyslot 10 is printed before keyslot 2:\n%s", out)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"foo"},
+		{"open", "/dev/null"},
+		{"open", "/nonexistent", "root"},
+		{"close"},
+		{"luksDump", "/dev/null"},
+	} {
+		if err := run(args, ioutil.Discard); err == nil {
+			t.Errorf("run(%q) succeeded, want error", args)
+		}
+	}
+}
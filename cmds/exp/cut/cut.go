@@ -0,0 +1,301 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// cut prints selected parts of lines.
+//
+// Synopsis:
+//     cut -b LIST [--complement] [--output-delimiter=STRING] [FILE]...
+//     cut -c LIST [--complement] [--output-delimiter=STRING] [FILE]...
+//     cut -f LIST [-d DELIM] [-s] [--complement] [--output-delimiter=STRING] [FILE]...
+//
+// Description:
+//     cut prints the bytes, characters or fields of each line of the FILEs,
+//     or stdin, that LIST selects. A FILE named - is stdin. LIST is a comma
+//     separated list of ranges, which are one of
+//
+//     N:   the Nth byte, character or field, counted from 1
+//     N-M: from the Nth to the Mth
+//     N-:  from the Nth to the end of the line
+//     -M:  from the first to the Mth
+//
+//     Selected parts are printed once and in the order of the line, whatever
+//     the order of LIST. Characters are UTF-8 encoded runes.
+//
+//     Fields are separated by DELIM, a tab by default. Lines without DELIM
+//     are printed whole, unless -s is given.
+//
+// Options:
+//     -b: select bytes
+//     -c: select characters
+//     -f: select fields
+//     -d: use DELIM, a single character, to separate fields
+//     -s: do not print lines without delimiters
+//     --complement: select the parts that LIST does not select
+//     --output-delimiter: print STRING between fields, or between the
+//         ranges of bytes or characters, instead of DELIM or nothing
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	bytesList   = flag.StringP("bytes", "b", "", "select only these bytes")
+	charsList   = flag.StringP("characters", "c", "", "select only these characters")
+	fieldsList  = flag.StringP("fields", "f", "", "select only these fields")
+	delimiter   = flag.StringP("delimiter", "d", "\t", "use DELIM instead of tab to separate fields")
+	onlyDelim   = flag.BoolP("only-delimited", "s", false, "do not print lines without delimiters")
+	complement  = flag.Bool("complement", false, "select the parts that LIST does not select")
+	outputDelim = flag.String("output-delimiter", "", "print STRING between the selected parts")
+)
+
+// span is a range of positions, counted from 1.
+type span struct {
+	lo, hi int
+}
+
+// toEnd is the hi of a range to the end of the line.
+const toEnd = 1<<31 - 1
+
+// parseList parses a list of ranges, and returns them sorted and merged.
+func parseList(list string) ([]span, error) {
+	var spans []span
+	for _, item := range strings.Split(list, ",") {
+		lo, hi := item, item
+		dash := strings.IndexByte(item, '-')
+		if dash >= 0 {
+			lo, hi = item[:dash], item[dash+1:]
+		}
+		if lo == "" && hi == "" {
+			return nil, fmt.Errorf("invalid range with no endpoint: %q", item)
+		}
+		s := span{lo: 1, hi: toEnd}
+		var err error
+		if lo != "" {
+			if s.lo, err = position(lo); err != nil {
+				return nil, err
+			}
+		}
+		if hi != "" {
+			if s.hi, err = position(hi); err != nil {
+				return nil, err
+			}
+		}
+		if s.hi < s.lo {
+			return nil, fmt.Errorf("invalid decreasing range: %q", item)
+		}
+		spans = append(spans, s)
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].lo < spans[j].lo })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.lo > last.hi+1 {
+			merged = append(merged, s)
+		} else if s.hi > last.hi {
+			last.hi = s.hi
+		}
+	}
+	return merged, nil
+}
+
+func position(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil || strings.HasPrefix(s, "+"):
+		return 0, fmt.Errorf("invalid position: %q", s)
+	case n == 0:
+		return 0, errors.New("positions are numbered from 1")
+	}
+	return n, nil
+}
+
+// selector says which positions are selected.
+type selector struct {
+	spans      []span
+	complement bool
+}
+
+func (s selector) selected(pos int) bool {
+	for _, sp := range s.spans {
+		if pos < sp.lo {
+			break
+		}
+		if pos <= sp.hi {
+			return !s.complement
+		}
+	}
+	return s.complement
+}
+
+type mode int
+
+const (
+	byBytes mode = iota
+	byChars
+	byFields
+)
+
+// cutter cuts lines.
+type cutter struct {
+	mode mode
+	sel  selector
+	// delim separates fields, and outDelim selected fields or ranges.
+	delim, outDelim string
+	onlyDelimited   bool
+}
+
+// cutLine returns the selected parts of line, and whether to print it.
+func (c *cutter) cutLine(line string) (string, bool) {
+	var parts []string
+	switch c.mode {
+	case byFields:
+		if !strings.Contains(line, c.delim) {
+			return line, !c.onlyDelimited
+		}
+		parts = strings.Split(line, c.delim)
+	case byChars:
+		for len(line) > 0 {
+			_, n := utf8.DecodeRuneInString(line)
+			parts = append(parts, line[:n])
+			line = line[n:]
+		}
+	default:
+		for i := 0; i < len(line); i++ {
+			parts = append(parts, line[i:i+1])
+		}
+	}
+
+	var b strings.Builder
+	// Fields are joined by the output delimiter, but bytes and characters
+	// only when a range starts.
+	prev, first := false, true
+	for i, p := range parts {
+		sel := c.sel.selected(i + 1)
+		if sel {
+			if !first && (c.mode == byFields || !prev) {
+				b.WriteString(c.outDelim)
+			}
+			b.WriteString(p)
+			first = false
+		}
+		prev = sel
+	}
+	return b.String(), true
+}
+
+// cut cuts the lines of r to w.
+func (c *cutter) cut(r io.Reader, w *bufio.Writer) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == "" {
+			return nil
+		}
+		if out, ok := c.cutLine(strings.TrimSuffix(line, "\n")); ok {
+			w.WriteString(out)
+			w.WriteByte('\n')
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func run(c *cutter, names []string, stdin io.Reader, stdout io.Writer) error {
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	var failed bool
+	for _, name := range names {
+		if name == "-" {
+			if err := c.cut(stdin, w); err != nil {
+				log.Printf("-: %v", err)
+				failed = true
+			}
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			log.Print(err)
+			failed = true
+			continue
+		}
+		if err := c.cut(f, w); err != nil {
+			log.Printf("%s: %v", name, err)
+			failed = true
+		}
+		f.Close()
+	}
+	if failed {
+		return errors.New("some files could not be read")
+	}
+	return w.Flush()
+}
+
+// newCutter returns a cutter for the flags.
+func newCutter() (*cutter, error) {
+	c := &cutter{}
+	var list string
+	n := 0
+	for m, l := range map[mode]string{byBytes: *bytesList, byChars: *charsList, byFields: *fieldsList} {
+		if l != "" {
+			c.mode, list = m, l
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("you must specify a list of bytes, characters, or fields")
+	}
+	if c.mode != byFields && (flag.CommandLine.Changed("delimiter") || *onlyDelim) {
+		return nil, errors.New("-d and -s only make sense when operating on fields")
+	}
+	spans, err := parseList(list)
+	if err != nil {
+		return nil, err
+	}
+	c.sel = selector{spans: spans, complement: *complement}
+	c.delim = *delimiter
+	if utf8.RuneCountInString(c.delim) != 1 {
+		return nil, errors.New("the delimiter must be a single character")
+	}
+	c.onlyDelimited = *onlyDelim
+	if c.mode == byFields {
+		c.outDelim = c.delim
+	}
+	if flag.CommandLine.Changed("output-delimiter") {
+		c.outDelim = *outputDelim
+	}
+	return c, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cut: ")
+	flag.Parse()
+	c, err := newCutter()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := run(c, flag.Args(), os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tt := range []struct {
+		list string
+		want []span
+	}{
+		{"1", []span{{1, 1}}},
+		{"1,3-5,7-", []span{{1, 1}, {3, 5}, {7, toEnd}}},
+		{"-3", []span{{1, 3}}},
+		{"3-", []span{{3, toEnd}}},
+		{"5,1", []span{{1, 1}, {5, 5}}},
+		{"1-3,2-6", []span{{1, 6}}},
+		{"1-2,3-4", []span{{1, 4}}},
+		{"2-4,3", []span{{2, 4}}},
+		{"4-,1-5", []span{{1, toEnd}}},
+		{"3-3", []span{{3, 3}}},
+	} {
+		got, err := parseList(tt.list)
+		if err != nil {
+			t.Errorf("parseList(%q) = %v", tt.list, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseList(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestParseListErrors(t *testing.T) {
+	for _, list := range []string{"", "-", "0", "0-2", "3-1", "a", "1,", ",1", "1-2-3", "+1", "1--2", "1,,2"} {
+		if got, err := parseList(list); err == nil {
+			t.Errorf("parseList(%q) = %v, want an error", list, got)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		c     cutter
+		list  string
+		input string
+		want  string
+	}{
+		{
+			name:  "fields",
+			c:     cutter{mode: byFields, delim: ":", outDelim: ":"},
+			list:  "1,3-",
+			input: "root:x:0:0:root:/root:/bin/sh\n",
+			want:  "root:0:0:root:/root:/bin/sh\n",
+		},
+		{
+			name:  "fields out of order",
+			c:     cutter{mode: byFields, delim: "\t", outDelim: "\t"},
+			list:  "3,1",
+			input: "a\tb\tc\n",
+			want:  "a\tc\n",
+		},
+		{
+			name:  "no delimiter",
+			c:     cutter{mode: byFields, delim: ",", outDelim: ","},
+			list:  "2",
+			input: "a,b\nnone\n",
+			want:  "b\nnone\n",
+		},
+		{
+			name:  "only delimited",
+			c:     cutter{mode: byFields, delim: ",", outDelim: ",", onlyDelimited: true},
+			list:  "2",
+			input: "a,b\nnone\n",
+			want:  "b\n",
+		},
+		{
+			name:  "missing fields",
+			c:     cutter{mode: byFields, delim: ",", outDelim: ","},
+			list:  "2,5",
+			input: "a,b,c\na,\n",
+			want:  "b\n\n",
+		},
+		{
+			name:  "output delimiter",
+			c:     cutter{mode: byFields, delim: " ", outDelim: "|"},
+			list:  "1-2",
+			input: "a b c\n",
+			want:  "a|b\n",
+		},
+		{
+			name:  "field complement",
+			c:     cutter{mode: byFields, delim: ":", outDelim: ":", sel: selector{complement: true}},
+			list:  "2",
+			input: "a:b:c\n",
+			want:  "a:c\n",
+		},
+		{
+			name:  "bytes",
+			c:     cutter{mode: byBytes},
+			list:  "1-3,5",
+			input: "abcdefg\nab\n",
+			want:  "abce\nab\n",
+		},
+		{
+			name:  "bytes complement",
+			c:     cutter{mode: byBytes, sel: selector{complement: true}},
+			list:  "2-3",
+			input: "abcde\n",
+			want:  "ade\n",
+		},
+		{
+			name:  "byte ranges delimited",
+			c:     cutter{mode: byBytes, outDelim: ":"},
+			list:  "1-2,4,5-",
+			input: "abcdefg\n",
+			want:  "ab:defg\n",
+		},
+		{
+			name:  "chars",
+			c:     cutter{mode: byChars},
+			list:  "2-3",
+			input: "héllo\n",
+			want:  "él\n",
+		},
+		{
+			name:  "bytes of runes",
+			c:     cutter{mode: byBytes},
+			list:  "1-2",
+			input: "héllo\n",
+			want:  "h\xc3\n",
+		},
+		{
+			name:  "no trailing newline",
+			c:     cutter{mode: byChars},
+			list:  "1",
+			input: "ab\ncd",
+			want:  "a\nc\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, err := parseList(tt.list)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := tt.c
+			c.sel.spans = spans
+			var out bytes.Buffer
+			if err := run(&c, nil, strings.NewReader(tt.input), &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("cut = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingFile(t *testing.T) {
+	c := &cutter{mode: byBytes, sel: selector{spans: []span{{1, 1}}}}
+	var out bytes.Buffer
+	if err := run(c, []string{"/does/not/exist", "-"}, strings.NewReader("xy\n"), &out); err == nil {
+		t.Errorf("run() succeeded, want an error")
+	}
+	if out.String() != "x\n" {
+		t.Errorf("cut = %q, want %q", out.String(), "x\n")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// diff compares files line by line.
+//
+// Synopsis:
+//     diff [-qr] [-u | -U NUM] [--label LABEL]... FILE1 FILE2
+//
+// Description:
+//     diff prints the changes that turn FILE1 into FILE2, in the normal
+//     format by default, or in the unified format with -u. A FILE of - is
+//     stdin.
+//
+//     If one FILE is a directory, the file in it with the name of the other
+//     FILE is compared. If both are directories, the files with the same
+//     names in them are compared, and the subdirectories too with -r.
+//
+//     Files that contain a NUL byte are binary and are only reported to
+//     differ.
+//
+//     The exit status is 0 if the files are the same, 1 if they differ and
+//     2 if there was trouble.
+//
+// Options:
+//     -q: only report whether files differ
+//     -r: compare subdirectories recursively
+//     -u: print 3 lines of unified context
+//     -U: print NUM lines of unified context
+//     --label: use LABEL instead of the file name in the unified header;
+//         the first one is for FILE1 and the second for FILE2
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	brief     = flag.BoolP("brief", "q", false, "only report whether files differ")
+	recursive = flag.BoolP("recursive", "r", false, "compare subdirectories recursively")
+	unified   = flag.BoolP("unified", "u", false, "print 3 lines of unified context")
+	context   = flag.IntP("unified-context", "U", 3, "print NUM lines of unified context")
+	labels    = flag.StringArray("label", nil, "use LABEL instead of the file name in the unified header")
+)
+
+// binaryCheckSize is how much of a file is searched for a NUL byte.
+const binaryCheckSize = 8192
+
+// differ compares files and directories.
+type differ struct {
+	w     io.Writer
+	stdin io.Reader
+
+	unified   bool
+	context   int
+	brief     bool
+	recursive bool
+	labels    []string
+	// command is printed before the differences of files in directories.
+	command string
+
+	trouble bool
+}
+
+func (d *differ) errorf(format string, a ...interface{}) {
+	log.Printf(format, a...)
+	d.trouble = true
+}
+
+// file is the contents of a file to compare.
+type file struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+func (d *differ) readFile(name string) (*file, error) {
+	if name == "-" {
+		data, err := ioutil.ReadAll(d.stdin)
+		return &file{name: name, data: data, modTime: time.Now()}, err
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(name)
+	return &file{name: name, data: data, modTime: fi.ModTime()}, err
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > binaryCheckSize {
+		data = data[:binaryCheckSize]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// header returns the unified header of the nth file.
+func (d *differ) header(f *file, n int) string {
+	if n < len(d.labels) {
+		return d.labels[n]
+	}
+	return f.name + "\t" + f.modTime.Format("2006-01-02 15:04:05.000000000 -0700")
+}
+
+// diffFiles compares two files, and returns whether they differ. inDir is
+// set when comparing the files of directories.
+func (d *differ) diffFiles(name1, name2 string, inDir bool) bool {
+	f1, err := d.readFile(name1)
+	if err != nil {
+		d.errorf("%v", err)
+		return false
+	}
+	f2, err := d.readFile(name2)
+	if err != nil {
+		d.errorf("%v", err)
+		return false
+	}
+	if bytes.Equal(f1.data, f2.data) {
+		return false
+	}
+
+	switch {
+	case d.brief:
+		fmt.Fprintf(d.w, "Files %s and %s differ\n", name1, name2)
+		return true
+	case isBinary(f1.data) || isBinary(f2.data):
+		fmt.Fprintf(d.w, "Binary files %s and %s differ\n", name1, name2)
+		return true
+	}
+
+	if inDir {
+		fmt.Fprintf(d.w, "%s %s %s\n", d.command, name1, name2)
+	}
+	edits := diffLines(splitLines(string(f1.data)), splitLines(string(f2.data)))
+	if d.unified {
+		fmt.Fprintf(d.w, "--- %s\n+++ %s\n", d.header(f1, 0), d.header(f2, 1))
+		writeUnified(d.w, edits, d.context)
+	} else {
+		writeNormal(d.w, edits)
+	}
+	return true
+}
+
+// diffDirs compares the entries of two directories, and returns whether
+// they differ.
+func (d *differ) diffDirs(dir1, dir2 string) bool {
+	entries := map[string][2]os.FileInfo{}
+	for i, dir := range []string{dir1, dir2} {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			d.errorf("%v", err)
+			return false
+		}
+		for _, fi := range fis {
+			e := entries[fi.Name()]
+			e[i] = fi
+			entries[fi.Name()] = e
+		}
+	}
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var differ bool
+	for _, name := range names {
+		e := entries[name]
+		p1, p2 := filepath.Join(dir1, name), filepath.Join(dir2, name)
+		switch {
+		case e[1] == nil:
+			fmt.Fprintf(d.w, "Only in %s: %s\n", dir1, name)
+			differ = true
+		case e[0] == nil:
+			fmt.Fprintf(d.w, "Only in %s: %s\n", dir2, name)
+			differ = true
+		case e[0].IsDir() && e[1].IsDir():
+			if d.recursive {
+				differ = d.diffDirs(p1, p2) || differ
+			} else {
+				fmt.Fprintf(d.w, "Common subdirectories: %s and %s\n", p1, p2)
+			}
+		case e[0].IsDir() || e[1].IsDir():
+			kind := func(fi os.FileInfo) string {
+				if fi.IsDir() {
+					return "directory"
+				}
+				return "regular file"
+			}
+			fmt.Fprintf(d.w, "File %s is a %s while file %s is a %s\n", p1, kind(e[0]), p2, kind(e[1]))
+			differ = true
+		default:
+			differ = d.diffFiles(p1, p2, true) || differ
+		}
+	}
+	return differ
+}
+
+func isDir(name string) bool {
+	if name == "-" {
+		return false
+	}
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// diff compares two files or directories, and returns whether they differ.
+func (d *differ) diff(name1, name2 string) bool {
+	dir1, dir2 := isDir(name1), isDir(name2)
+	switch {
+	case dir1 && dir2:
+		return d.diffDirs(name1, name2)
+	case dir1:
+		name1 = filepath.Join(name1, filepath.Base(name2))
+	case dir2:
+		name2 = filepath.Join(name2, filepath.Base(name1))
+	}
+	return d.diffFiles(name1, name2, false)
+}
+
+// run compares two files or directories and returns the exit status.
+func run(d *differ, name1, name2 string) int {
+	differ := d.diff(name1, name2)
+	switch {
+	case d.trouble:
+		return 2
+	case differ:
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("diff: ")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		log.Print("usage: diff [-qr] [-u | -U NUM] [--label LABEL]... FILE1 FILE2")
+		os.Exit(2)
+	}
+	if *context < 0 {
+		log.Printf("invalid context length %d", *context)
+		os.Exit(2)
+	}
+	if len(*labels) > 2 {
+		log.Print("too many file label options")
+		os.Exit(2)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	d := &differ{
+		w:         w,
+		stdin:     os.Stdin,
+		unified:   *unified || flag.CommandLine.Changed("unified-context"),
+		context:   *context,
+		brief:     *brief,
+		recursive: *recursive,
+		labels:    *labels,
+	}
+	opts := []string{"diff"}
+	if d.unified {
+		opts = append(opts, fmt.Sprintf("-U%d", d.context))
+	}
+	if d.recursive {
+		opts = append(opts, "-r")
+	}
+	d.command = strings.Join(opts, " ")
+	code := run(d, flag.Arg(0), flag.Arg(1))
+	w.Flush()
+	os.Exit(code)
+}
@@ -0,0 +1,292 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lcsLength returns the length of a longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	l := make([][]int, len(a)+1)
+	for i := range l {
+		l[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] > l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+	return l[0][0]
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(40))
+		for i := range lines {
+			lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		edits := diffLines(a, b)
+		var gotA, gotB []string
+		common := 0
+		for _, e := range edits {
+			if e.kind != '+' {
+				gotA = append(gotA, e.line)
+			}
+			if e.kind != '-' {
+				gotB = append(gotB, e.line)
+			}
+			if e.kind == ' ' {
+				common++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("diffLines(%q, %q) = %q, which does not turn one into the other", a, b, edits)
+		}
+		if want := lcsLength(a, b); common != want {
+			t.Fatalf("diffLines(%q, %q) has %d common lines, want %d", a, b, common, want)
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	got := splitLines("a\n\nb")
+	if want := []string{"a\n", "\n", "b"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitLines() = %q, want %q", got, want)
+	}
+	if got := splitLines(""); len(got) != 0 {
+		t.Errorf("splitLines(\"\") = %q, want none", got)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	ten := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	for _, tt := range []struct {
+		name    string
+		a, b    string
+		unified bool
+		context int
+		want    string
+	}{
+		{
+			name: "normal",
+			a:    "a\nb\nc\nd\n",
+			b:    "a\nB\nc\nd\ne\n",
+			want: "2c2\n< b\n---\n> B\n4a5\n> e\n",
+		},
+		{
+			name: "normal delete",
+			a:    "a\nb\nc\nd\n",
+			b:    "a\nd\n",
+			want: "2,3d1\n< b\n< c\n",
+		},
+		{
+			name: "normal insert at start",
+			a:    "b\n",
+			b:    "a\nb\n",
+			want: "0a1\n> a\n",
+		},
+		{
+			name: "normal no newline",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: "2c2\n< b\n\\ No newline at end of file\n---\n> b\n",
+		},
+		{
+			name:    "unified",
+			a:       "a\nb\nc\nd\n",
+			b:       "a\nB\nc\nd\ne\n",
+			unified: true,
+			context: 3,
+			want:    "@@ -1,4 +1,5 @@\n a\n-b\n+B\n c\n d\n+e\n",
+		},
+		{
+			name:    "unified hunks",
+			a:       ten,
+			b:       strings.Replace(strings.Replace(ten, "2\n", "two\n", 1), "9\n", "nine\n", 1),
+			unified: true,
+			context: 1,
+			want:    "@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n@@ -8,3 +8,3 @@\n 8\n-9\n+nine\n 10\n",
+		},
+		{
+			name:    "unified merged hunk",
+			a:       ten,
+			b:       strings.Replace(strings.Replace(ten, "2\n", "two\n", 1), "5\n", "five\n", 1),
+			unified: true,
+			context: 1,
+			want:    "@@ -1,6 +1,6 @@\n 1\n-2\n+two\n 3\n 4\n-5\n+five\n 6\n",
+		},
+		{
+			name:    "unified empty",
+			a:       "",
+			b:       "a\n",
+			unified: true,
+			context: 3,
+			want:    "@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:    "unified deletion no context",
+			a:       "a\nb\nc\n",
+			b:       "a\nc\n",
+			unified: true,
+			context: 0,
+			want:    "@@ -2 +1,0 @@\n-b\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			edits := diffLines(splitLines(tt.a), splitLines(tt.b))
+			if tt.unified {
+				writeUnified(&out, edits, tt.context)
+			} else {
+				writeNormal(&out, edits)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"a/same":       "x\n",
+		"b/same":       "x\n",
+		"a/changed":    "x\n",
+		"b/changed":    "y\n",
+		"a/onlya":      "",
+		"b/onlyb":      "",
+		"a/sub/f":      "1\n",
+		"b/sub/f":      "2\n",
+		"a/bin":        "a\x00b",
+		"b/bin":        "a\x00c",
+		"a/kind/x":     "",
+		"b/kind":       "",
+		"file":         "x\n",
+		"other/file":   "y\n",
+		"other/binary": "\x00",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for _, tt := range []struct {
+		name       string
+		d          differ
+		args       [2]string
+		stdin      string
+		want       string
+		wantStatus int
+	}{
+		{
+			name:       "same",
+			args:       [2]string{filepath.Join(a, "same"), filepath.Join(b, "same")},
+			wantStatus: 0,
+		},
+		{
+			name:       "labels",
+			d:          differ{unified: true, context: 3, labels: []string{"old", "new"}},
+			args:       [2]string{filepath.Join(a, "changed"), filepath.Join(b, "changed")},
+			want:       "--- old\n+++ new\n@@ -1 +1 @@\n-x\n+y\n",
+			wantStatus: 1,
+		},
+		{
+			name:       "brief",
+			d:          differ{brief: true},
+			args:       [2]string{filepath.Join(a, "changed"), filepath.Join(b, "changed")},
+			want:       "Files " + a + "/changed and " + b + "/changed differ\n",
+			wantStatus: 1,
+		},
+		{
+			name:       "binary",
+			args:       [2]string{filepath.Join(a, "bin"), filepath.Join(b, "bin")},
+			want:       "Binary files " + a + "/bin and " + b + "/bin differ\n",
+			wantStatus: 1,
+		},
+		{
+			name:       "stdin",
+			args:       [2]string{"-", filepath.Join(dir, "file")},
+			stdin:      "y\n",
+			want:       "1c1\n< y\n---\n> x\n",
+			wantStatus: 1,
+		},
+		{
+			name:       "file and directory",
+			args:       [2]string{filepath.Join(dir, "file"), filepath.Join(dir, "other")},
+			want:       "1c1\n< x\n---\n> y\n",
+			wantStatus: 1,
+		},
+		{
+			name: "directories",
+			d:    differ{command: "diff"},
+			args: [2]string{a, b},
+			want: "Binary files " + a + "/bin and " + b + "/bin differ\n" +
+				"diff " + a + "/changed " + b + "/changed\n1c1\n< x\n---\n> y\n" +
+				"File " + a + "/kind is a directory while file " + b + "/kind is a regular file\n" +
+				"Only in " + a + ": onlya\n" +
+				"Only in " + b + ": onlyb\n" +
+				"Common subdirectories: " + a + "/sub and " + b + "/sub\n",
+			wantStatus: 1,
+		},
+		{
+			name: "recursive",
+			d:    differ{recursive: true, brief: true},
+			args: [2]string{a, b},
+			want: "Files " + a + "/bin and " + b + "/bin differ\n" +
+				"Files " + a + "/changed and " + b + "/changed differ\n" +
+				"File " + a + "/kind is a directory while file " + b + "/kind is a regular file\n" +
+				"Only in " + a + ": onlya\n" +
+				"Only in " + b + ": onlyb\n" +
+				"Files " + a + "/sub/f and " + b + "/sub/f differ\n",
+			wantStatus: 1,
+		},
+		{
+			name:       "missing",
+			args:       [2]string{filepath.Join(dir, "missing"), filepath.Join(dir, "file")},
+			wantStatus: 2,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			d := tt.d
+			d.w = &out
+			d.stdin = strings.NewReader(tt.stdin)
+			if got := run(&d, tt.args[0], tt.args[1]); got != tt.wantStatus {
+				t.Errorf("run() = %d, want %d", got, tt.wantStatus)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
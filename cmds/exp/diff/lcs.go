@@ -0,0 +1,295 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// edit is a line of an edit script: a line of both files, a line deleted
+// from the first or a line inserted from the second.
+type edit struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// splitLines splits data into lines, which keep their newline.
+func splitLines(data string) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := strings.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines = append(lines, data[:i])
+		data = data[i:]
+	}
+	return lines
+}
+
+// diffLines returns an edit script from a to b, with a longest common
+// subsequence of lines, found with Myers' O(ND) algorithm.
+func diffLines(a, b []string) []edit {
+	// Lines are compared as numbers.
+	ids := map[string]int{}
+	id := func(lines []string) []int {
+		n := make([]int, len(lines))
+		for i, l := range lines {
+			v, ok := ids[l]
+			if !ok {
+				v = len(ids)
+				ids[l] = v
+			}
+			n[i] = v
+		}
+		return n
+	}
+	var edits, adds []edit
+	for _, s := range myers(id(a), id(b)) {
+		switch s.kind {
+		case '+':
+			adds = append(adds, edit{s.kind, b[s.i]})
+		case '-':
+			edits = append(edits, edit{s.kind, a[s.i]})
+		default:
+			// Deletions come first in a change.
+			edits = append(append(edits, adds...), edit{s.kind, a[s.i]})
+			adds = adds[:0]
+		}
+	}
+	return append(edits, adds...)
+}
+
+// step is an edit of line i of a, or of b for an insertion.
+type step struct {
+	kind byte
+	i    int
+}
+
+// myers returns the shortest edit script from a to b, using the linear
+// space variant of the algorithm, which splits the problem at the middle
+// snake of an optimal path.
+func myers(a, b []int) []step {
+	var steps []step
+	var diff func(a, b []int, ai, bi int)
+	diff = func(a, b []int, ai, bi int) {
+		for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+			steps = append(steps, step{' ', ai})
+			a, b, ai, bi = a[1:], b[1:], ai+1, bi+1
+		}
+		suf := 0
+		for suf < len(a) && suf < len(b) && a[len(a)-1-suf] == b[len(b)-1-suf] {
+			suf++
+		}
+		a, b = a[:len(a)-suf], b[:len(b)-suf]
+		switch {
+		case len(a) == 0:
+			for i := range b {
+				steps = append(steps, step{'+', bi + i})
+			}
+		case len(b) == 0:
+			for i := range a {
+				steps = append(steps, step{'-', ai + i})
+			}
+		default:
+			x, y, u, v := middleSnake(a, b)
+			diff(a[:x], b[:y], ai, bi)
+			for i := x; i < u; i++ {
+				steps = append(steps, step{' ', ai + i})
+			}
+			diff(a[u:], b[v:], ai+u, bi+v)
+		}
+		for i := len(a); i < len(a)+suf; i++ {
+			steps = append(steps, step{' ', ai + i})
+		}
+	}
+	diff(a, b, 0, 0)
+	return steps
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the
+// middle of a shortest path from a to b, found by searching from both ends.
+func middleSnake(a, b []int) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	max := (n+m+1)/2 + 1
+	// vf[off+k] is the furthest x reached forward on diagonal k = x - y,
+	// and vb[off+k] the furthest reached backward, from the ends of a and
+	// b, on diagonal k of the reversed sequences, which is diagonal
+	// delta-k.
+	off := max + 1
+	vf := make([]int, 2*max+3)
+	vb := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || k != d && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			vf[off+k] = u
+			if kb := delta - k; delta%2 != 0 && kb >= -(d-1) && kb <= d-1 && u+vb[off+kb] >= n {
+				return x, y, u, v
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var rx int
+			if k == -d || k != d && vb[off+k-1] < vb[off+k+1] {
+				rx = vb[off+k+1]
+			} else {
+				rx = vb[off+k-1] + 1
+			}
+			ry := rx - k
+			rx0, ry0 := rx, ry
+			for rx < n && ry < m && a[n-1-rx] == b[m-1-ry] {
+				rx++
+				ry++
+			}
+			vb[off+k] = rx
+			if kf := delta - k; delta%2 == 0 && kf >= -d && kf <= d && vf[off+kf]+rx >= n {
+				return n - rx, m - ry, n - rx0, m - ry0
+			}
+		}
+	}
+	panic("no middle snake")
+}
+
+// writeLine writes a line with its prefix, and notes a missing newline.
+func writeLine(w io.Writer, prefix, line string) {
+	io.WriteString(w, prefix+line)
+	if !strings.HasSuffix(line, "\n") {
+		io.WriteString(w, "\n\\ No newline at end of file\n")
+	}
+}
+
+// lineRange formats the lines from start to end, counted from 1, of normal
+// output. An empty range is the line before it.
+func lineRange(start, end int) string {
+	switch {
+	case end <= start:
+		return fmt.Sprint(start)
+	case end == start+1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, end)
+}
+
+// writeNormal writes edits in the normal format: commands to add, change or
+// delete lines, followed by the lines of the first file prefixed with <
+// and the lines of the second prefixed with >.
+func writeNormal(w io.Writer, edits []edit) {
+	ai, bi := 0, 0
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			ai++
+			bi++
+			i++
+			continue
+		}
+		var del, add []string
+		for ; i < len(edits) && edits[i].kind != ' '; i++ {
+			if edits[i].kind == '-' {
+				del = append(del, edits[i].line)
+			} else {
+				add = append(add, edits[i].line)
+			}
+		}
+		cmd := "c"
+		switch {
+		case len(del) == 0:
+			cmd = "a"
+		case len(add) == 0:
+			cmd = "d"
+		}
+		fmt.Fprintf(w, "%s%s%s\n", lineRange(ai, ai+len(del)), cmd, lineRange(bi, bi+len(add)))
+		for _, l := range del {
+			writeLine(w, "< ", l)
+		}
+		if len(del) > 0 && len(add) > 0 {
+			io.WriteString(w, "---\n")
+		}
+		for _, l := range add {
+			writeLine(w, "> ", l)
+		}
+		ai += len(del)
+		bi += len(add)
+	}
+}
+
+// hunkRange formats the range of a unified hunk, which starts at line
+// start, counted from 1, or is empty after line start-1.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// writeUnified writes the hunks of edits in the unified format, with
+// context lines of context around changes.
+func writeUnified(w io.Writer, edits []edit, context int) {
+	// apos[i] and bpos[i] are the lines of a and b before edits[i].
+	apos := make([]int, len(edits)+1)
+	bpos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		apos[i+1], bpos[i+1] = apos[i], bpos[i]
+		if e.kind != '+' {
+			apos[i+1]++
+		}
+		if e.kind != '-' {
+			bpos[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk over changes separated by at most 2*context
+		// common lines.
+		end := i
+		for end < len(edits) {
+			for end < len(edits) && edits[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].kind == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n",
+			hunkRange(apos[start]+1, apos[end]-apos[start]),
+			hunkRange(bpos[start]+1, bpos[end]-bpos[start]))
+		for _, e := range edits[start:end] {
+			writeLine(w, string(e.kind), e.line)
+		}
+		i = end
+	}
+}
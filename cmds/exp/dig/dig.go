@@ -0,0 +1,277 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// dig queries DNS servers.
+//
+// Synopsis:
+//     dig [-x ADDRESS] [-p PORT] [-w TIMEOUT] [@SERVER] [NAME] [TYPE] [+tcp] [+short] [+norecurse]
+//
+// Description:
+//     Send a query for the TYPE records of NAME to SERVER, or to the
+//     nameservers of /etc/resolv.conf in turn, and print the reply. TYPE is
+//     one of A, AAAA, CNAME, MX, NS, PTR, SOA, SRV, TXT, ANY or TYPEnn, and
+//     A by default.
+//
+//     With -x, look up the PTR record of the IPv4 or IPv6 ADDRESS.
+//
+//     Queries are sent over UDP, and again over TCP if the reply is
+//     truncated. Options come before the other arguments.
+//
+// Options:
+//     -x: reverse lookup of ADDRESS
+//     -p: port of the nameserver
+//     -w: seconds to wait for a reply
+//     +tcp: send queries over TCP
+//     +short: only print the data of the answers
+//     +norecurse: do not ask the nameserver to resolve the query recursively
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	reverse = flag.String("x", "", "Reverse lookup of this address")
+	port    = flag.Int("p", 53, "Port of the nameserver")
+	timeout = flag.Float64("w", 5, "Seconds to wait for a reply")
+)
+
+// resolvConf lists the default nameservers.
+var resolvConf = "/etc/resolv.conf"
+
+// config is the query dig sends and how.
+type config struct {
+	server string
+	name   string
+	qtype  uint16
+	tcp    bool
+	short  bool
+	rd     bool
+}
+
+// parseArgs parses the arguments after the options, in any order. An
+// argument that is a record type is the type, as in dig.
+func parseArgs(args []string, c *config) error {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "@"):
+			c.server = a[1:]
+		case a == "+tcp" || a == "+vc":
+			c.tcp = true
+		case a == "+notcp" || a == "+novc":
+			c.tcp = false
+		case a == "+short":
+			c.short = true
+		case a == "+noshort":
+			c.short = false
+		case a == "+recurse":
+			c.rd = true
+		case a == "+norecurse":
+			c.rd = false
+		case strings.HasPrefix(a, "+"):
+			return fmt.Errorf("unknown option %q", a)
+		default:
+			if t, ok := parseType(a); ok {
+				c.qtype = t
+				continue
+			}
+			if c.name != "" {
+				return fmt.Errorf("unexpected argument %q", a)
+			}
+			c.name = a
+		}
+	}
+	return nil
+}
+
+// nameservers returns the nameservers of a resolv.conf.
+func nameservers(r io.Reader) []string {
+	var ns []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) >= 2 && f[0] == "nameserver" {
+			ns = append(ns, f[1])
+		}
+	}
+	return ns
+}
+
+// exchange sends query to server and returns the reply.
+func exchange(server string, query []byte, tcp bool, wait time.Duration) ([]byte, error) {
+	network := "udp"
+	if tcp {
+		network = "tcp"
+	}
+	c, err := net.DialTimeout(network, server, wait)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if err := c.SetDeadline(time.Now().Add(wait)); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(query)
+
+	if tcp {
+		// Messages are prefixed with their length over TCP.
+		if _, err := c.Write(append([]byte{byte(len(query) >> 8), byte(len(query))}, query...)); err != nil {
+			return nil, err
+		}
+		var l [2]byte
+		if _, err := io.ReadFull(c, l[:]); err != nil {
+			return nil, err
+		}
+		reply := make([]byte, binary.BigEndian.Uint16(l[:]))
+		if _, err := io.ReadFull(c, reply); err != nil {
+			return nil, err
+		}
+		return reply, nil
+	}
+
+	if _, err := c.Write(query); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 65535)
+	for {
+		n, err := c.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray replies to other queries.
+		if n >= headerLen && binary.BigEndian.Uint16(b) == id {
+			return b[:n], nil
+		}
+	}
+}
+
+// query sends the query of c to server, over TCP if the UDP reply is
+// truncated.
+func query(server string, c *config, wait time.Duration) (*message, error) {
+	q, err := buildQuery(uint16(rand.Intn(1<<16)), c.name, c.qtype, c.rd)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := exchange(server, q, c.tcp, wait)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseMessage(reply)
+	if err != nil {
+		return nil, err
+	}
+	if m.flags&flagTC != 0 && !c.tcp {
+		if reply, err = exchange(server, q, true, wait); err != nil {
+			return nil, err
+		}
+		return parseMessage(reply)
+	}
+	return m, nil
+}
+
+func printMessage(w io.Writer, m *message, short bool) {
+	if short {
+		for _, r := range m.answers {
+			fmt.Fprintln(w, r.data)
+		}
+		return
+	}
+	fmt.Fprintf(w, ";; ->>HEADER<<- opcode: QUERY, status: %s, id: %d\n", rcodeString(m.rcode), m.id)
+	fmt.Fprintf(w, ";; flags: %s; QUERY: %d, ANSWER: %d, AUTHORITY: %d, ADDITIONAL: %d\n",
+		m.flagString(), len(m.questions), len(m.answers), len(m.authority), len(m.additional))
+	fmt.Fprintln(w, "\n;; QUESTION SECTION:")
+	for _, q := range m.questions {
+		fmt.Fprintln(w, q)
+	}
+	for _, s := range []struct {
+		name string
+		rrs  []resource
+	}{{"ANSWER", m.answers}, {"AUTHORITY", m.authority}, {"ADDITIONAL", m.additional}} {
+		if len(s.rrs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n;; %s SECTION:\n", s.name)
+		for _, r := range s.rrs {
+			fmt.Fprintln(w, r)
+		}
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	c := &config{qtype: typeA, rd: true}
+	if *reverse != "" {
+		ip := net.ParseIP(*reverse)
+		if ip == nil {
+			return fmt.Errorf("%q is not an IP address", *reverse)
+		}
+		name, err := reverseName(ip)
+		if err != nil {
+			return err
+		}
+		c.name, c.qtype = name, typePTR
+	}
+	if err := parseArgs(args, c); err != nil {
+		return err
+	}
+	if c.name == "" {
+		c.name, c.qtype = ".", typeNS
+	}
+	c.name = fqdn(c.name)
+
+	var servers []string
+	if c.server != "" {
+		servers = []string{c.server}
+	} else {
+		f, err := os.Open(resolvConf)
+		if err != nil {
+			return err
+		}
+		servers = nameservers(f)
+		f.Close()
+		if len(servers) == 0 {
+			return fmt.Errorf("no nameservers in %s", resolvConf)
+		}
+	}
+
+	wait := time.Duration(*timeout * float64(time.Second))
+	var errs []string
+	for _, s := range servers {
+		server := net.JoinHostPort(s, strconv.Itoa(*port))
+		start := time.Now()
+		m, err := query(server, c, wait)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", server, err))
+			continue
+		}
+		printMessage(stdout, m, c.short)
+		if !c.short {
+			fmt.Fprintf(stdout, "\n;; Query time: %d msec\n", time.Since(start).Milliseconds())
+			fmt.Fprintf(stdout, ";; SERVER: %s\n", server)
+		}
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("dig: ")
+	flag.Parse()
+	rand.Seed(time.Now().UnixNano())
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want config
+		err  bool
+	}{
+		{
+			args: []string{"example.com"},
+			want: config{name: "example.com", qtype: typeA, rd: true},
+		},
+		{
+			args: []string{"@192.0.2.53", "mx", "example.com", "+tcp", "+short", "+norecurse"},
+			want: config{server: "192.0.2.53", name: "example.com", qtype: typeMX, tcp: true, short: true},
+		},
+		{
+			args: []string{"example.com", "AAAA", "+short", "+noshort"},
+			want: config{name: "example.com", qtype: typeAAAA, rd: true},
+		},
+		{args: []string{"example.com", "+bogus"}, err: true},
+		{args: []string{"example.com", "example.org"}, err: true},
+	} {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			c := config{qtype: typeA, rd: true}
+			err := parseArgs(tt.args, &c)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseArgs() = %v, want error %t", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("parseArgs() = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameservers(t *testing.T) {
+	conf := "# comment\ndomain example.com\nnameserver 192.0.2.53\nnameserver 2001:db8::53\nsearch example.com\n"
+	if got, want := nameservers(strings.NewReader(conf)), []string{"192.0.2.53", "2001:db8::53"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nameservers() = %v, want %v", got, want)
+	}
+}
+
+// serve answers queries for example.com on UDP and TCP. Replies over UDP
+// are truncated if truncate is set.
+func serve(t *testing.T, truncate bool) (string, func()) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Skipf("TCP port of %v is taken: %v", pc.LocalAddr(), err)
+	}
+	answer := func(q []byte) []byte {
+		return reply(q, flagQR|flagRD|flagRA, rr(ptr, typeA, 60, []byte{192, 0, 2, 1}))
+	}
+	go func() {
+		b := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			r := answer(b[:n])
+			if truncate {
+				r = reply(b[:n], flagQR|flagTC)
+			}
+			pc.WriteTo(r, addr)
+		}
+	}()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			var n uint16
+			if err := binary.Read(c, binary.BigEndian, &n); err == nil {
+				q := make([]byte, n)
+				if _, err := io.ReadFull(c, q); err == nil {
+					r := answer(q)
+					c.Write(append([]byte{byte(len(r) >> 8), byte(len(r))}, r...))
+				}
+			}
+			c.Close()
+		}
+	}()
+	return pc.LocalAddr().String(), func() {
+		pc.Close()
+		l.Close()
+	}
+}
+
+func TestQuery(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		tcp      bool
+		truncate bool
+	}{
+		{name: "UDP"},
+		{name: "TCP", tcp: true},
+		{name: "truncated", truncate: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, stop := serve(t, tt.truncate)
+			defer stop()
+			m, err := query(server, &config{name: "example.com.", qtype: typeA, tcp: tt.tcp, rd: true}, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.flags&flagTC != 0 || len(m.answers) != 1 || m.answers[0].data != "192.0.2.1" {
+				t.Errorf("query() = %+v, want one untruncated answer", m)
+			}
+		})
+	}
+}
+
+func TestPrintMessage(t *testing.T) {
+	m := &message{
+		id:        7,
+		flags:     flagQR | flagRD,
+		rcode:     3,
+		questions: []question{{"example.com.", typeA, classINET}},
+		authority: []resource{{"com.", typeSOA, classINET, 900, "a.gtld-servers.net. nstld.verisign-grs.com. 1 2 3 4 5"}},
+	}
+	var b bytes.Buffer
+	printMessage(&b, m, false)
+	want := `;; ->>HEADER<<- opcode: QUERY, status: NXDOMAIN, id: 7
+;; flags: qr rd; QUERY: 1, ANSWER: 0, AUTHORITY: 1, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;example.com.		IN	A
+
+;; AUTHORITY SECTION:
+com.	900	IN	SOA	a.gtld-servers.net. nstld.verisign-grs.com. 1 2 3 4 5
+`
+	if b.String() != want {
+		t.Errorf("printMessage() = %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	m.answers = []resource{{"example.com.", typeA, classINET, 60, "192.0.2.1"}}
+	printMessage(&b, m, true)
+	if got, want := b.String(), "192.0.2.1\n"; got != want {
+		t.Errorf("printMessage(short) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Resource record types.
+const (
+	typeA     = 1
+	typeNS    = 2
+	typeCNAME = 5
+	typeSOA   = 6
+	typePTR   = 12
+	typeMX    = 15
+	typeTXT   = 16
+	typeAAAA  = 28
+	typeSRV   = 33
+	typeANY   = 255
+)
+
+const classINET = 1
+
+var typeNames = map[uint16]string{
+	typeA:     "A",
+	typeNS:    "NS",
+	typeCNAME: "CNAME",
+	typeSOA:   "SOA",
+	typePTR:   "PTR",
+	typeMX:    "MX",
+	typeTXT:   "TXT",
+	typeAAAA:  "AAAA",
+	typeSRV:   "SRV",
+	typeANY:   "ANY",
+}
+
+var rcodeNames = []string{"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED"}
+
+// Header flags.
+const (
+	flagQR = 1 << 15
+	flagAA = 1 << 10
+	flagTC = 1 << 9
+	flagRD = 1 << 8
+	flagRA = 1 << 7
+)
+
+const headerLen = 12
+
+func typeString(t uint16) string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// parseType parses a record type name, e.g. "MX", or "TYPE15".
+func parseType(s string) (uint16, bool) {
+	s = strings.ToUpper(s)
+	for t, n := range typeNames {
+		if n == s {
+			return t, true
+		}
+	}
+	if strings.HasPrefix(s, "TYPE") {
+		if t, err := strconv.ParseUint(s[4:], 10, 16); err == nil {
+			return uint16(t), true
+		}
+	}
+	return 0, false
+}
+
+func rcodeString(rcode int) string {
+	if rcode < len(rcodeNames) {
+		return rcodeNames[rcode]
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// fqdn returns name with a trailing dot.
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// appendName appends the wire format of name to b.
+func appendName(b []byte, name string) ([]byte, error) {
+	name = fqdn(name)
+	if len(name) > 254 {
+		return nil, fmt.Errorf("name %q is too long", name)
+	}
+	if name == "." {
+		return append(b, 0), nil
+	}
+	for _, label := range strings.Split(name[:len(name)-1], ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid label %q in %q", label, name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// buildQuery returns a query for the records of type qtype of name. The
+// server resolves it recursively if rd is set.
+func buildQuery(id uint16, name string, qtype uint16, rd bool) ([]byte, error) {
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b[0:], id)
+	if rd {
+		binary.BigEndian.PutUint16(b[2:], flagRD)
+	}
+	binary.BigEndian.PutUint16(b[4:], 1) // QDCOUNT
+	b, err := appendName(b, name)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, byte(qtype>>8), byte(qtype), 0, classINET), nil
+}
+
+type question struct {
+	name  string
+	typ   uint16
+	class uint16
+}
+
+func (q question) String() string {
+	return fmt.Sprintf(";%s\t\tIN\t%s", q.name, typeString(q.typ))
+}
+
+// resource is a resource record. data is its presentation format.
+type resource struct {
+	name  string
+	typ   uint16
+	class uint16
+	ttl   uint32
+	data  string
+}
+
+func (r resource) String() string {
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", r.name, r.ttl, typeString(r.typ), r.data)
+}
+
+// message is a parsed DNS message.
+type message struct {
+	id         uint16
+	flags      uint16
+	rcode      int
+	questions  []question
+	answers    []resource
+	authority  []resource
+	additional []resource
+}
+
+func (m *message) flagString() string {
+	var f []string
+	for _, n := range []struct {
+		flag uint16
+		name string
+	}{{flagQR, "qr"}, {flagAA, "aa"}, {flagTC, "tc"}, {flagRD, "rd"}, {flagRA, "ra"}} {
+		if m.flags&n.flag != 0 {
+			f = append(f, n.name)
+		}
+	}
+	return strings.Join(f, " ")
+}
+
+var errShort = errors.New("short DNS message")
+
+// parser reads a DNS message.
+type parser struct {
+	msg []byte
+	off int
+}
+
+func (p *parser) uint8() (byte, error) {
+	if p.off+1 > len(p.msg) {
+		return 0, errShort
+	}
+	p.off++
+	return p.msg[p.off-1], nil
+}
+
+func (p *parser) uint16() (uint16, error) {
+	if p.off+2 > len(p.msg) {
+		return 0, errShort
+	}
+	p.off += 2
+	return binary.BigEndian.Uint16(p.msg[p.off-2:]), nil
+}
+
+func (p *parser) uint32() (uint32, error) {
+	if p.off+4 > len(p.msg) {
+		return 0, errShort
+	}
+	p.off += 4
+	return binary.BigEndian.Uint32(p.msg[p.off-4:]), nil
+}
+
+// name reads a possibly compressed name.
+func (p *parser) name() (string, error) {
+	var labels []string
+	off := p.off
+	// end is the offset after the name, before the first pointer.
+	end := -1
+	for hops := 0; ; {
+		if off >= len(p.msg) {
+			return "", errShort
+		}
+		l := int(p.msg[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			p.off = end
+			return strings.Join(labels, ".") + ".", nil
+		case l&0xc0 == 0xc0:
+			if off+2 > len(p.msg) {
+				return "", errShort
+			}
+			if hops++; hops > 64 {
+				return "", errors.New("too many compression pointers")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(p.msg[off:]) & 0x3fff)
+		case l&0xc0 != 0:
+			return "", fmt.Errorf("invalid label length %#x", l)
+		default:
+			if off+1+l > len(p.msg) {
+				return "", errShort
+			}
+			labels = append(labels, string(p.msg[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+func (p *parser) question() (question, error) {
+	var q question
+	var err error
+	if q.name, err = p.name(); err != nil {
+		return q, err
+	}
+	if q.typ, err = p.uint16(); err != nil {
+		return q, err
+	}
+	q.class, err = p.uint16()
+	return q, err
+}
+
+func (p *parser) resource() (resource, error) {
+	var r resource
+	var err error
+	if r.name, err = p.name(); err != nil {
+		return r, err
+	}
+	if r.typ, err = p.uint16(); err != nil {
+		return r, err
+	}
+	if r.class, err = p.uint16(); err != nil {
+		return r, err
+	}
+	if r.ttl, err = p.uint32(); err != nil {
+		return r, err
+	}
+	l, err := p.uint16()
+	if err != nil {
+		return r, err
+	}
+	if p.off+int(l) > len(p.msg) {
+		return r, errShort
+	}
+	// Names in the data may point anywhere in the message, so parse it
+	// with its own parser.
+	rd := &parser{msg: p.msg[:p.off+int(l)], off: p.off}
+	p.off += int(l)
+	r.data, err = rd.rdata(r.typ)
+	if err != nil {
+		return r, fmt.Errorf("%s %s record: %v", r.name, typeString(r.typ), err)
+	}
+	return r, nil
+}
+
+// rdata returns the presentation format of the data of a record of type
+// typ, which extends to the end of p.msg.
+func (p *parser) rdata(typ uint16) (string, error) {
+	data := p.msg[p.off:]
+	switch typ {
+	case typeA:
+		if len(data) != net.IPv4len {
+			return "", errors.New("invalid length")
+		}
+		return net.IP(data).String(), nil
+	case typeAAAA:
+		if len(data) != net.IPv6len {
+			return "", errors.New("invalid length")
+		}
+		return net.IP(data).String(), nil
+	case typeNS, typeCNAME, typePTR:
+		return p.name()
+	case typeMX:
+		pref, err := p.uint16()
+		if err != nil {
+			return "", err
+		}
+		host, err := p.name()
+		return fmt.Sprintf("%d %s", pref, host), err
+	case typeSRV:
+		var v [3]uint16
+		for i := range v {
+			var err error
+			if v[i], err = p.uint16(); err != nil {
+				return "", err
+			}
+		}
+		target, err := p.name()
+		return fmt.Sprintf("%d %d %d %s", v[0], v[1], v[2], target), err
+	case typeSOA:
+		mname, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		rname, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		s := []string{mname, rname}
+		for i := 0; i < 5; i++ {
+			v, err := p.uint32()
+			if err != nil {
+				return "", err
+			}
+			s = append(s, strconv.FormatUint(uint64(v), 10))
+		}
+		return strings.Join(s, " "), nil
+	case typeTXT:
+		var s []string
+		for p.off < len(p.msg) {
+			l, err := p.uint8()
+			if err != nil {
+				return "", err
+			}
+			if p.off+int(l) > len(p.msg) {
+				return "", errShort
+			}
+			s = append(s, strconv.Quote(string(p.msg[p.off:p.off+int(l)])))
+			p.off += int(l)
+		}
+		return strings.Join(s, " "), nil
+	}
+	// Unknown types are printed as in RFC 3597.
+	return fmt.Sprintf("\\# %d %s", len(data), hex.EncodeToString(data)), nil
+}
+
+// parseMessage parses the DNS message b.
+func parseMessage(b []byte) (*message, error) {
+	p := &parser{msg: b}
+	m := &message{}
+	var counts [4]uint16
+	var err error
+	if m.id, err = p.uint16(); err != nil {
+		return nil, err
+	}
+	if m.flags, err = p.uint16(); err != nil {
+		return nil, err
+	}
+	m.rcode = int(m.flags & 0xf)
+	for i := range counts {
+		if counts[i], err = p.uint16(); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < int(counts[0]); i++ {
+		q, err := p.question()
+		if err != nil {
+			return nil, err
+		}
+		m.questions = append(m.questions, q)
+	}
+	for s, rrs := range []*[]resource{&m.answers, &m.authority, &m.additional} {
+		for i := 0; i < int(counts[s+1]); i++ {
+			r, err := p.resource()
+			if err != nil {
+				return nil, err
+			}
+			*rrs = append(*rrs, r)
+		}
+	}
+	return m, nil
+}
+
+// reverseName returns the name of the PTR record of ip.
+func reverseName(ip net.IP) (string, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0]), nil
+	}
+	if len(ip) != net.IPv6len {
+		return "", fmt.Errorf("invalid IP address %v", ip)
+	}
+	var b strings.Builder
+	const digits = "0123456789abcdef"
+	for i := len(ip) - 1; i >= 0; i-- {
+		b.WriteByte(digits[ip[i]&0xf])
+		b.WriteByte('.')
+		b.WriteByte(digits[ip[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
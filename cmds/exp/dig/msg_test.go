@@ -0,0 +1,190 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// rr returns the wire format of a record of name, which is a name or a
+// compression pointer.
+func rr(name []byte, typ uint16, ttl uint32, data []byte) []byte {
+	b := append([]byte(nil), name...)
+	b = append(b, byte(typ>>8), byte(typ), 0, classINET)
+	b = append(b, byte(ttl>>24), byte(ttl>>16), byte(ttl>>8), byte(ttl))
+	b = append(b, byte(len(data)>>8), byte(len(data)))
+	return append(b, data...)
+}
+
+func wireName(t *testing.T, name string) []byte {
+	b, err := appendName(nil, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// reply returns a reply to query with answers and no other records.
+func reply(query []byte, flags uint16, answers ...[]byte) []byte {
+	b := append([]byte(nil), query...)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[6:], uint16(len(answers)))
+	for _, a := range answers {
+		b = append(b, a...)
+	}
+	return b
+}
+
+// ptr points at the name of the question.
+var ptr = []byte{0xc0, headerLen}
+
+func TestBuildQuery(t *testing.T) {
+	q, err := buildQuery(0x1234, "example.com", typeMX, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0, typeMX, 0, classINET,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("buildQuery() = %x, want %x", q, want)
+	}
+
+	for _, name := range []string{"a..b", string(make([]byte, 64)) + ".com", "x" + string(make([]byte, 260))} {
+		if _, err := buildQuery(1, name, typeA, true); err == nil {
+			t.Errorf("buildQuery(%q) = nil, want error", name)
+		}
+	}
+	if q, err := buildQuery(1, ".", typeNS, false); err != nil || len(q) != headerLen+5 || q[2] != 0 {
+		t.Errorf("buildQuery(.) = %x, %v", q, err)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	q, err := buildQuery(7, "example.com", typeANY, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mx := append([]byte{0, 10}, wireName(t, "mail")...)
+	mx = mx[:len(mx)-1]
+	mx = append(mx, ptr...)
+	srv := append([]byte{0, 1, 0, 2, 0x1f, 0x90}, wireName(t, "sip.example.com")...)
+	soa := append(wireName(t, "ns.example.com"), ptr...)
+	soa = append(soa, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5)
+	msg := reply(q, flagQR|flagRD|flagRA,
+		rr(ptr, typeA, 300, []byte{192, 0, 2, 1}),
+		rr(ptr, typeAAAA, 300, net.ParseIP("2001:db8::1")),
+		rr(ptr, typeCNAME, 60, ptr),
+		rr(ptr, typeMX, 60, mx),
+		rr(ptr, typeTXT, 60, []byte{5, 'h', 'e', 'l', 'l', 'o', 3, 'a', '"', 'b'}),
+		rr(ptr, typeSRV, 60, srv),
+		rr(ptr, typeSOA, 60, soa),
+		rr(ptr, 99, 60, []byte{0xca, 0xfe}),
+	)
+	m, err := parseMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &message{
+		id:        7,
+		flags:     flagQR | flagRD | flagRA,
+		questions: []question{{"example.com.", typeANY, classINET}},
+		answers: []resource{
+			{"example.com.", typeA, classINET, 300, "192.0.2.1"},
+			{"example.com.", typeAAAA, classINET, 300, "2001:db8::1"},
+			{"example.com.", typeCNAME, classINET, 60, "example.com."},
+			{"example.com.", typeMX, classINET, 60, "10 mail.example.com."},
+			{"example.com.", typeTXT, classINET, 60, `"hello" "a\"b"`},
+			{"example.com.", typeSRV, classINET, 60, "1 2 8080 sip.example.com."},
+			{"example.com.", typeSOA, classINET, 60, "ns.example.com. example.com. 1 2 3 4 5"},
+			{"example.com.", 99, classINET, 60, `\# 2 cafe`},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("parseMessage() = %+v, want %+v", m, want)
+	}
+	if got, want := m.flagString(), "qr rd ra"; got != want {
+		t.Errorf("flagString() = %q, want %q", got, want)
+	}
+	if got, want := m.answers[3].String(), "example.com.\t60\tIN\tMX\t10 mail.example.com."; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	q, err := buildQuery(7, "example.com", typeA, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		msg  []byte
+	}{
+		{"short header", q[:10]},
+		{"short question", q[:len(q)-1]},
+		{"missing answer", reply(q, flagQR, nil)},
+		{"bad A length", reply(q, flagQR, rr(ptr, typeA, 1, []byte{1, 2, 3}))},
+		{"short rdata", reply(q, flagQR, rr(ptr, typeA, 1, []byte{1, 2, 3, 4}))[:len(q)+14]},
+		{"pointer loop", reply(q, flagQR, rr([]byte{0xc0, byte(len(q))}, typeA, 1, []byte{1, 2, 3, 4}))},
+		{"bad label", reply(q, flagQR, rr([]byte{0x80}, typeA, 1, []byte{1, 2, 3, 4}))},
+		{"short MX", reply(q, flagQR, rr(ptr, typeMX, 1, []byte{0}))},
+		{"short TXT", reply(q, flagQR, rr(ptr, typeTXT, 1, []byte{4, 'a'}))},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := parseMessage(tt.msg); err == nil {
+				t.Errorf("parseMessage() = %+v, want error", m)
+			}
+		})
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	for _, tt := range []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.1", "1.2.0.192.in-addr.arpa."},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	} {
+		got, err := reverseName(net.ParseIP(tt.ip))
+		if err != nil || got != tt.want {
+			t.Errorf("reverseName(%s) = %q, %v, want %q", tt.ip, got, err, tt.want)
+		}
+	}
+	if _, err := reverseName(net.IP{1, 2}); err == nil {
+		t.Error("reverseName of an invalid address = nil, want error")
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want uint16
+		ok   bool
+	}{
+		{"A", typeA, true},
+		{"aaaa", typeAAAA, true},
+		{"Mx", typeMX, true},
+		{"TYPE99", 99, true},
+		{"TYPE99999", 0, false},
+		{"example.com", 0, false},
+	} {
+		got, ok := parseType(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseType(%q) = %d, %t, want %d, %t", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got := typeString(99); got != "TYPE99" {
+		t.Errorf("typeString(99) = %q, want TYPE99", got)
+	}
+	if got := rcodeString(3); got != "NXDOMAIN" {
+		t.Errorf("rcodeString(3) = %q, want NXDOMAIN", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// dmsetup creates and removes device-mapper devices.
+//
+// Synopsis:
+//     dmsetup create [-r] NAME [--table TABLE | TABLEFILE]
+//     dmsetup remove NAME
+//
+// Description:
+//     create: create the device /dev/mapper/NAME from TABLE, TABLEFILE or
+//             stdin
+//     remove: remove the device NAME
+//
+//     A table has one target per line:
+//
+//     START LENGTH TYPE [PARAMS...]
+//
+//     START and LENGTH are in 512 byte sectors. For example, to map the
+//     first MiB of /dev/sda:
+//
+//     dmsetup create part --table "0 2048 linear /dev/sda 0"
+//
+// Options:
+//     -r, --readonly: create a read-only device
+//     --table:        the table, instead of reading it from a file
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/dm"
+)
+
+const usage = `usage: dmsetup create [-r] NAME [--table TABLE | TABLEFILE]
+       dmsetup remove NAME`
+
+var (
+	readOnly = flag.BoolP("readonly", "r", false, "create a read-only device")
+	table    = flag.String("table", "", "the table, instead of reading it from a file")
+)
+
+// readTable returns the --table, or the contents of the file args[0], or
+// of stdin.
+func readTable(args []string, stdin io.Reader) (string, error) {
+	switch {
+	case *table != "" && len(args) > 0:
+		return "", errors.New(usage)
+	case *table != "":
+		return *table, nil
+	case len(args) == 1:
+		b, err := ioutil.ReadFile(args[0])
+		return string(b), err
+	case len(args) == 0:
+		b, err := ioutil.ReadAll(stdin)
+		return string(b), err
+	}
+	return "", errors.New(usage)
+}
+
+func run(args []string) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "create":
+		t, err := readTable(args[2:], os.Stdin)
+		if err != nil {
+			return err
+		}
+		targets, err := dm.ParseTable(t)
+		if err != nil {
+			return err
+		}
+		var flags uint32
+		if *readOnly {
+			flags |= dm.ReadOnly
+		}
+		node, err := dm.Create(args[1], flags, targets)
+		if err != nil {
+			return err
+		}
+		fmt.Println(node)
+		return nil
+	case "remove":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		return dm.Remove(args[1])
+	}
+	return errors.New(usage)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("dmsetup: ")
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
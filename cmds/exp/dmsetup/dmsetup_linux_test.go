@@ -0,0 +1,66 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dmsetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "table")
+	if err := ioutil.WriteFile(file, []byte("0 8 zero\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		table string
+		args  []string
+		want  string
+		err   bool
+	}{
+		{table: "0 8 error", want: "0 8 error"},
+		{args: []string{file}, want: "0 8 zero\n"},
+		{want: "0 8 linear /dev/sda 0\n"},
+		{table: "0 8 error", args: []string{file}, err: true},
+		{args: []string{file, file}, err: true},
+		{args: []string{"/nonexistent"}, err: true},
+	} {
+		*table = tt.table
+		got, err := readTable(tt.args, strings.NewReader("0 8 linear /dev/sda 0\n"))
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("readTable(%q) with --table %q = (%q, %v), want (%q, err %v)", tt.args, tt.table, got, err, tt.want, tt.err)
+		}
+	}
+	*table = ""
+}
+
+func TestRunErrors(t *testing.T) {
+	*table = ""
+	for _, args := range [][]string{
+		nil,
+		{"create"},
+		{"foo", "bar"},
+		{"remove", "a", "b"},
+		{"create", "x", "/nonexistent"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("run(%q) succeeded, want error", args)
+		}
+	}
+	*table = "0 8"
+	if err := run([]string{"create", "x"}); err == nil {
+		t.Errorf("run with invalid table succeeded, want error")
+	}
+	*table = ""
+}
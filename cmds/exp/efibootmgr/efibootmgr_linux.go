@@ -0,0 +1,381 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// efibootmgr manages UEFI boot entries.
+//
+// Synopsis:
+//     efibootmgr [-v] [-q]
+//     efibootmgr -c [-b XXXX] [-d DISK] [-p PART] [--part-uuid UUID] -l LOADER [-L LABEL]
+//     efibootmgr -b XXXX -B|-a|-A
+//     efibootmgr -n XXXX | -N
+//     efibootmgr -o XXXX,YYYY,...
+//
+// Description:
+//     Without options, efibootmgr lists BootNext, BootCurrent, Timeout,
+//     BootOrder and the BootXXXX entries, in the format of Linux's
+//     efibootmgr. Active entries are marked with a *. After a change, the
+//     new state is listed, unless -q is given.
+//
+//     -c creates an entry for the file LOADER on a GPT partition, given
+//     either by its PARTUUID or as partition PART of DISK, and puts it
+//     first in BootOrder:
+//
+//     efibootmgr -c --part-uuid 0f5e1d0c-... -l '\EFI\BOOT\BOOTX64.EFI' -L u-root
+//
+// Options:
+//     -c, --create:          create a new boot entry
+//     -b, --bootnum:         the boot entry to create or change, in hex
+//     -B, --delete-bootnum:  delete boot entry -b, and remove it from BootOrder
+//     -a, --active:          make boot entry -b active
+//     -A, --inactive:        make boot entry -b inactive
+//     -d, --disk:            the disk of the partition of a new entry
+//     -p, --part:            the partition number on -d (default 1)
+//     --part-uuid:           the PARTUUID of the partition of a new entry
+//     -l, --loader:          the file path of a new entry on its partition
+//     -L, --label:           the description of a new entry (default Linux)
+//     -n, --bootnext:        boot entry XXXX on the next boot only
+//     -N, --delete-bootnext: delete BootNext
+//     -o, --bootorder:       set BootOrder to a comma-separated list of entries
+//     -q, --quiet:           do not list the boot entries
+//     -v, --verbose:         also list the device path of each entry
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/efivarfs"
+	"github.com/u-root/u-root/pkg/mount/block"
+	"github.com/u-root/u-root/pkg/uefivars"
+	"github.com/u-root/u-root/pkg/uefivars/boot"
+)
+
+var (
+	create         = flag.BoolP("create", "c", false, "create a new boot entry")
+	bootNum        = flag.StringP("bootnum", "b", "", "the boot entry to create or change, in hex")
+	deleteBootNum  = flag.BoolP("delete-bootnum", "B", false, "delete boot entry -b")
+	active         = flag.BoolP("active", "a", false, "make boot entry -b active")
+	inactive       = flag.BoolP("inactive", "A", false, "make boot entry -b inactive")
+	disk           = flag.StringP("disk", "d", "", "the disk of the partition of a new entry")
+	part           = flag.IntP("part", "p", 1, "the partition number on -d")
+	partUUID       = flag.String("part-uuid", "", "the PARTUUID of the partition of a new entry")
+	loader         = flag.StringP("loader", "l", "", "the file path of a new entry on its partition")
+	label          = flag.StringP("label", "L", "Linux", "the description of a new entry")
+	bootNext       = flag.StringP("bootnext", "n", "", "boot entry `XXXX` on the next boot only")
+	deleteBootNext = flag.BoolP("delete-bootnext", "N", false, "delete BootNext")
+	bootOrder      = flag.StringP("bootorder", "o", "", "set BootOrder to a comma-separated list of entries")
+	quiet          = flag.BoolP("quiet", "q", false, "do not list the boot entries")
+	verbose        = flag.BoolP("verbose", "v", false, "also list the device path of each entry")
+)
+
+// attrs are the attributes of the boot variables efibootmgr writes.
+const attrs = efivarfs.AttributeNonVolatile | efivarfs.AttributeBootserviceAccess | efivarfs.AttributeRuntimeAccess
+
+func globalVar(name string) efivarfs.Var {
+	return efivarfs.Var{Name: name, GUID: efivarfs.GlobalVariable}
+}
+
+func entryVar(num uint16) efivarfs.Var {
+	return globalVar(fmt.Sprintf("Boot%04X", num))
+}
+
+func parseBootNum(s string) (uint16, error) {
+	n, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid boot entry %q, want 4 hex digits", s)
+	}
+	return uint16(n), nil
+}
+
+func parseBootOrder(s string) ([]uint16, error) {
+	var order []uint16
+	for _, f := range strings.Split(s, ",") {
+		n, err := parseBootNum(f)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, n)
+	}
+	return order, nil
+}
+
+func formatBootOrder(order []uint16) string {
+	s := make([]string, len(order))
+	for i, n := range order {
+		s[i] = fmt.Sprintf("%04X", n)
+	}
+	return strings.Join(s, ",")
+}
+
+// readUint16 reads a uint16 variable, e.g. BootNext. ok is false if it
+// does not exist.
+func readUint16(name string) (n uint16, ok bool, err error) {
+	_, data, err := efivarfs.Get(globalVar(name))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if len(data) != 2 {
+		return 0, false, fmt.Errorf("%s is %d bytes, want 2", name, len(data))
+	}
+	return uint16(data[0]) | uint16(data[1])<<8, true, nil
+}
+
+// readBootOrder returns BootOrder, or nothing if it does not exist.
+func readBootOrder() ([]uint16, error) {
+	order, err := boot.BootOrder()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return order, err
+}
+
+func list(w io.Writer, verbose bool) error {
+	for _, name := range []string{"BootNext", "BootCurrent"} {
+		n, ok, err := readUint16(name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fmt.Fprintf(w, "%s: %04X\n", name, n)
+		}
+	}
+	timeout, ok, err := readUint16("Timeout")
+	if err != nil {
+		return err
+	}
+	if ok {
+		fmt.Fprintf(w, "Timeout: %d seconds\n", timeout)
+	}
+	order, err := readBootOrder()
+	if err != nil {
+		return err
+	}
+	if order != nil {
+		fmt.Fprintf(w, "BootOrder: %s\n", formatBootOrder(order))
+	}
+	entries, err := boot.LoadOptions()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		mark := " "
+		if e.Attributes&boot.LoadOptionActive != 0 {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "Boot%04X%s %s", e.Number, mark, e.Description)
+		if verbose {
+			fmt.Fprintf(w, "\t%s", e.FilePathList)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// partition is a GPT partition.
+type partition struct {
+	num         uint32
+	start, size uint64
+	guid        uefivars.MixedGUID
+}
+
+// findPartition finds partition part of disk, or the partition with
+// PARTUUID uuid if disk is empty.
+func findPartition(disk string, part int, uuid string) (*partition, error) {
+	var devs block.BlockDevices
+	if disk != "" {
+		d, err := block.Device(disk)
+		if err != nil {
+			return nil, err
+		}
+		devs = block.BlockDevices{d}
+	} else {
+		var err error
+		if devs, err = block.GetBlockDevices(); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range devs {
+		table, err := d.GPTTable()
+		if err != nil {
+			if disk != "" {
+				return nil, fmt.Errorf("%s: no GPT: %v", disk, err)
+			}
+			continue
+		}
+		for i, p := range table.Partitions {
+			if p.IsEmpty() {
+				continue
+			}
+			if (disk != "" && i+1 == part) || (disk == "" && strings.EqualFold(p.Id.String(), uuid)) {
+				return &partition{
+					num:   uint32(i + 1),
+					start: p.FirstLBA,
+					size:  p.LastLBA - p.FirstLBA + 1,
+					guid:  uefivars.MixedGUID(p.Id),
+				}, nil
+			}
+		}
+	}
+	if disk != "" {
+		return nil, fmt.Errorf("%s has no partition %d", disk, part)
+	}
+	return nil, fmt.Errorf("no partition with PARTUUID %s", uuid)
+}
+
+// createEntry creates a boot entry for loader on p, numbered num or, if num
+// is nil, the lowest free number. The entry is put first in BootOrder.
+func createEntry(num *uint16, p *partition, loader, label string) (uint16, error) {
+	var n uint16
+	if num != nil {
+		n = *num
+		if _, _, err := efivarfs.Get(entryVar(n)); err == nil {
+			return 0, fmt.Errorf("Boot%04X already exists", n)
+		}
+	} else {
+		for ; ; n++ {
+			if _, _, err := efivarfs.Get(entryVar(n)); os.IsNotExist(err) {
+				break
+			}
+			if n == 0xffff {
+				return 0, errors.New("no free boot entry")
+			}
+		}
+	}
+	fpl := boot.GPTFilePathList(p.num, p.start, p.size, p.guid, loader)
+	if err := efivarfs.Set(entryVar(n), attrs, boot.EncodeLoadOption(boot.LoadOptionActive, label, fpl, nil)); err != nil {
+		return 0, err
+	}
+	order, err := readBootOrder()
+	if err != nil {
+		return 0, err
+	}
+	return n, boot.SetBootOrder(append([]uint16{n}, order...))
+}
+
+// deleteEntry deletes a boot entry and removes it from BootOrder.
+func deleteEntry(num uint16) error {
+	if err := efivarfs.Delete(entryVar(num)); err != nil {
+		return err
+	}
+	order, err := readBootOrder()
+	if err != nil || order == nil {
+		return err
+	}
+	var newOrder []uint16
+	for _, n := range order {
+		if n != num {
+			newOrder = append(newOrder, n)
+		}
+	}
+	if len(newOrder) == len(order) {
+		return nil
+	}
+	return efivarfs.Set(globalVar("BootOrder"), attrs, boot.EncodeBootOrder(newOrder))
+}
+
+// setActive sets or clears the active flag of a boot entry.
+func setActive(num uint16, active bool) error {
+	a, data, err := efivarfs.Get(entryVar(num))
+	if err != nil {
+		return err
+	}
+	if len(data) < 4 {
+		return fmt.Errorf("Boot%04X is too short", num)
+	}
+	if active {
+		data[0] |= boot.LoadOptionActive
+	} else {
+		data[0] &^= boot.LoadOptionActive
+	}
+	return efivarfs.Set(entryVar(num), a, data)
+}
+
+func run(stdout io.Writer) error {
+	if flag.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %q", flag.Args())
+	}
+	var num *uint16
+	if *bootNum != "" {
+		n, err := parseBootNum(*bootNum)
+		if err != nil {
+			return err
+		}
+		num = &n
+	}
+	if (*deleteBootNum || *active || *inactive) && num == nil {
+		return errors.New("-B, -a and -A need a boot entry, given with -b")
+	}
+
+	switch {
+	case *create:
+		if *loader == "" {
+			return errors.New("-c needs a loader, given with -l")
+		}
+		if (*disk == "") == (*partUUID == "") {
+			return errors.New("-c needs either -d or --part-uuid")
+		}
+		p, err := findPartition(*disk, *part, *partUUID)
+		if err != nil {
+			return err
+		}
+		if _, err := createEntry(num, p, *loader, *label); err != nil {
+			return err
+		}
+	case *deleteBootNum:
+		if err := deleteEntry(*num); err != nil {
+			return err
+		}
+	case *active, *inactive:
+		if err := setActive(*num, *active); err != nil {
+			return err
+		}
+	}
+
+	if *bootNext != "" {
+		n, err := parseBootNum(*bootNext)
+		if err != nil {
+			return err
+		}
+		if err := boot.SetBootNext(n); err != nil {
+			return err
+		}
+	}
+	if *deleteBootNext {
+		if err := efivarfs.Delete(globalVar("BootNext")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if *bootOrder != "" {
+		order, err := parseBootOrder(*bootOrder)
+		if err != nil {
+			return err
+		}
+		if err := boot.SetBootOrder(order); err != nil {
+			return err
+		}
+	}
+
+	if *quiet {
+		return nil
+	}
+	return list(stdout, *verbose)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("efibootmgr: ")
+	flag.Parse()
+	if err := run(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/efivarfs"
+)
+
+func TestParseBootOrder(t *testing.T) {
+	order, err := parseBootOrder("0003,1,00aF")
+	if want := []uint16{3, 1, 0xaf}; err != nil || !reflect.DeepEqual(order, want) {
+		t.Errorf("parseBootOrder = %v, %v, want %v", order, err, want)
+	}
+	if s := formatBootOrder(order); s != "0003,0001,00AF" {
+		t.Errorf("formatBootOrder(%v) = %q, want 0003,0001,00AF", order, s)
+	}
+	for _, s := range []string{"", "1,", "10000", "xyz"} {
+		if _, err := parseBootOrder(s); err == nil {
+			t.Errorf("parseBootOrder(%q) succeeded", s)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	d, err := ioutil.TempDir("", "efivarfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	defer func(old string) { efivarfs.Dir = old }(efivarfs.Dir)
+	efivarfs.Dir = d
+
+	check := func(want string) {
+		t.Helper()
+		var b bytes.Buffer
+		if err := list(&b, false); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Errorf("list:\n%s\nwant:\n%s", b.String(), want)
+		}
+	}
+
+	if err := efivarfs.Set(globalVar("BootCurrent"), attrs, []byte{0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	p := &partition{num: 1, start: 2048, size: 4096}
+	for _, label := range []string{"first", "second"} {
+		if _, err := createEntry(nil, p, `\EFI\BOOT\BOOTX64.EFI`, label); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := uint16(0x10)
+	if _, err := createEntry(&n, p, "/EFI/u-root.efi", "third"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createEntry(&n, p, "/EFI/u-root.efi", "again"); err == nil {
+		t.Errorf("creating Boot0010 twice succeeded")
+	}
+	check(`BootCurrent: 0000
+BootOrder: 0010,0001,0000
+Boot0000* first
+Boot0001* second
+Boot0010* third
+`)
+
+	var b bytes.Buffer
+	if err := list(&b, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Boot0010* third\tHD(1,GPT,00000000-0000-0000-0000-000000000000,0x800,0x1000)/File(/EFI/u-root.efi)\n"; !bytes.HasSuffix(b.Bytes(), []byte(want)) {
+		t.Errorf("verbose list:\n%s\nwant it to end in:\n%s", b.String(), want)
+	}
+
+	if err := setActive(1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteEntry(0); err != nil {
+		t.Fatal(err)
+	}
+	check(`BootCurrent: 0000
+BootOrder: 0010,0001
+Boot0001  second
+Boot0010* third
+`)
+}
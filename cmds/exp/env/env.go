@@ -0,0 +1,164 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// env runs a program in a modified environment.
+//
+// Synopsis:
+//     env [OPTIONS] [-] [NAME=VALUE]... [COMMAND [ARG]...]
+//
+// Description:
+//     Set each NAME to VALUE in the environment and run COMMAND. Assignments
+//     end at the first argument that does not contain an '='. Without
+//     COMMAND, print the resulting environment.
+//
+//     env exits with 125 if it fails itself, 126 if COMMAND cannot be run
+//     and 127 if COMMAND cannot be found.
+//
+// Options:
+//     -i, --ignore-environment: start with an empty environment; a lone "-" implies -i
+//     -u, --unset=NAME:         remove NAME from the environment
+//     -0, --null:               end each output line with NUL, not newline
+//     -C, --chdir=DIR:          change working directory to DIR before running COMMAND
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	exitFailure  = 125
+	exitCannot   = 126
+	exitNotFound = 127
+)
+
+var (
+	ignore = flag.BoolP("ignore-environment", "i", false, "start with an empty environment")
+	unset  = flag.StringArrayP("unset", "u", nil, "remove variable from the environment")
+	null   = flag.BoolP("null", "0", false, "end each output line with NUL, not newline")
+	chdir  = flag.StringP("chdir", "C", "", "change working directory to DIR")
+)
+
+// modify returns environ with the names in unset removed and the leading
+// NAME=VALUE assignments in args applied. It also returns the remaining
+// args, which form the command to run.
+func modify(environ, unset, args []string) ([]string, []string) {
+	var e []string
+	for _, v := range environ {
+		name := strings.SplitN(v, "=", 2)[0]
+		keep := true
+		for _, u := range unset {
+			if name == u {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			e = append(e, v)
+		}
+	}
+
+	for len(args) > 0 {
+		i := strings.IndexByte(args[0], '=')
+		if i <= 0 {
+			break
+		}
+		e = set(e, args[0][:i], args[0])
+		args = args[1:]
+	}
+	return e, args
+}
+
+// set replaces the value of name in e with kv, or appends kv if name is not
+// in e.
+func set(e []string, name, kv string) []string {
+	for i, v := range e {
+		if strings.HasPrefix(v, name+"=") {
+			e[i] = kv
+			return e
+		}
+	}
+	return append(e, kv)
+}
+
+func printenv(w io.Writer, e []string, end string) {
+	for _, v := range e {
+		fmt.Fprintf(w, "%s%s", v, end)
+	}
+}
+
+// run execs args in environment e. It only returns on failure, with the
+// exit status to use.
+func run(e []string, args []string) int {
+	// Apply the environment first, so that COMMAND is looked up in the
+	// new PATH.
+	os.Clearenv()
+	for _, v := range e {
+		kv := strings.SplitN(v, "=", 2)
+		os.Setenv(kv[0], kv[1])
+	}
+
+	p, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Printf("%v", err)
+		return exitNotFound
+	}
+	err = syscall.Exec(p, args, e)
+	log.Printf("%s: %v", args[0], err)
+	if os.IsNotExist(err) {
+		return exitNotFound
+	}
+	return exitCannot
+}
+
+func main() {
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("env: ")
+
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "-" {
+		*ignore = true
+		args = args[1:]
+	}
+
+	var environ []string
+	if !*ignore {
+		environ = os.Environ()
+	}
+	e, args := modify(environ, *unset, args)
+
+	if len(args) == 0 {
+		if *chdir != "" {
+			log.Print("must specify command with --chdir")
+			os.Exit(exitFailure)
+		}
+		end := "\n"
+		if *null {
+			end = "\x00"
+		}
+		printenv(os.Stdout, e, end)
+		return
+	}
+	if *null {
+		log.Print("cannot specify --null with command")
+		os.Exit(exitFailure)
+	}
+
+	if *chdir != "" {
+		if err := os.Chdir(*chdir); err != nil {
+			log.Printf("%v", err)
+			os.Exit(exitFailure)
+		}
+	}
+	os.Exit(run(e, args))
+}
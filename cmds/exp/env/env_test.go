@@ -0,0 +1,79 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestModify(t *testing.T) {
+	for _, tt := range []struct {
+		environ []string
+		unset   []string
+		args    []string
+		env     []string
+		rest    []string
+	}{
+		{environ: []string{"A=1", "B=2"}, env: []string{"A=1", "B=2"}},
+		{environ: []string{"A=1", "B=2"}, unset: []string{"A", "C"}, env: []string{"B=2"}},
+		{environ: []string{"A=1", "AB=2"}, args: []string{"A=3", "C=a=b"}, env: []string{"A=3", "AB=2", "C=a=b"}, rest: []string{}},
+		{environ: []string{"A=1"}, args: []string{"B=", "cmd", "C=1"}, env: []string{"A=1", "B="}, rest: []string{"cmd", "C=1"}},
+		{args: []string{"=x", "B=1"}, rest: []string{"=x", "B=1"}},
+		{args: []string{"cmd", "-i"}, rest: []string{"cmd", "-i"}},
+		{args: []string{"A=1"}, env: []string{"A=1"}, rest: []string{}},
+	} {
+		env, rest := modify(tt.environ, tt.unset, tt.args)
+		if !reflect.DeepEqual(env, tt.env) || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("modify(%q, %q, %q) = (%q, %q), want (%q, %q)", tt.environ, tt.unset, tt.args, env, rest, tt.env, tt.rest)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	const sh = "/bin/sh"
+	if _, err := os.Stat(sh); err != nil {
+		t.Skipf("test needs %s: %v", sh, err)
+	}
+	dir, err := ioutil.TempDir("", "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{args: []string{"-i", "A=1", "B=2"}, out: "A=1\nB=2\n"},
+		{args: []string{"-", "A=1"}, out: "A=1\n"},
+		{args: []string{"-i", "-0", "A=1", "B=2"}, out: "A=1\x00B=2\x00"},
+		{args: []string{"-i", "A=1", sh, "-c", "echo $A $0", "B=2"}, out: "1 B=2\n"},
+		{args: []string{"-i", "-C", dir, sh, "-c", "pwd"}, out: dir + "\n"},
+		{args: []string{"-i", "-C", dir}, code: 125},
+		{args: []string{"-i", "PATH=" + dir, "sh"}, code: 127},
+	} {
+		c := testutil.Command(t, tt.args...)
+		var out bytes.Buffer
+		c.Stdout = &out
+		err := c.Run()
+		if err := testutil.IsExitCode(err, tt.code); err != nil {
+			t.Errorf("env %q: %v", tt.args, err)
+		}
+		if out.String() != tt.out {
+			t.Errorf("env %q: got %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// fwload serves firmware requests from the kernel's fallback loader.
+//
+// Synopsis:
+//     fwload [-1v] [-i INTERVAL] [-p PATH] [-t SECONDS]
+//
+// Description:
+//     Drivers request firmware from the kernel, which looks for it in
+//     /lib/firmware itself. If the kernel is built with
+//     CONFIG_FW_LOADER_USER_HELPER, requests it can not satisfy appear in
+//     /sys/class/firmware, and fwload answers them: it writes 1 to the
+//     request's loading file, the firmware to its data file and 0 to
+//     loading when done.
+//
+//     Firmware is searched for in PATH, or like the kernel does in
+//     /lib/firmware/updates/$(uname -r), /lib/firmware/updates,
+//     /lib/firmware/$(uname -r) and /lib/firmware. If it is not found,
+//     the request is aborted by writing -1 to loading, so the driver does
+//     not have to wait for the request to time out.
+//
+//     fwload runs until killed, checking for requests every INTERVAL.
+//
+// Options:
+//     -1, --once:     serve pending requests and exit
+//     -i, --interval: how often to check for requests
+//     -p, --path:     colon separated list of firmware directories
+//     -t, --timeout:  set the kernel's request timeout in seconds
+//     -v, --verbose:  print each firmware that is loaded
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	once     = flag.BoolP("once", "1", false, "serve pending requests and exit")
+	interval = flag.DurationP("interval", "i", 100*time.Millisecond, "how often to check for requests")
+	fwPath   = flag.StringP("path", "p", "", "colon separated list of firmware directories")
+	timeout  = flag.IntP("timeout", "t", 0, "set the kernel's request timeout in seconds")
+	verbose  = flag.BoolP("verbose", "v", false, "print each firmware that is loaded")
+
+	// These are variables so they can be changed in tests.
+	sysFirmware = "/sys/class/firmware"
+	osRelease   = "/proc/sys/kernel/osrelease"
+)
+
+// request is a pending firmware request.
+type request struct {
+	dir      string
+	firmware string
+}
+
+// searchPath returns the kernel's firmware search path for kernel
+// version kver.
+func searchPath(kver string) []string {
+	if kver == "" {
+		return []string{"/lib/firmware/updates", "/lib/firmware"}
+	}
+	return []string{
+		"/lib/firmware/updates/" + kver,
+		"/lib/firmware/updates",
+		"/lib/firmware/" + kver,
+		"/lib/firmware",
+	}
+}
+
+// firmwareName returns the name of the firmware requested in dir, which
+// the kernel puts in the request's uevent.
+func firmwareName(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := strings.TrimPrefix(scanner.Text(), "FIRMWARE="); name != scanner.Text() {
+			return name, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no FIRMWARE in uevent", dir)
+}
+
+// pending returns the requests in sysFirmware.
+func pending() ([]request, error) {
+	entries, err := ioutil.ReadDir(sysFirmware)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []request
+	for _, e := range entries {
+		dir := filepath.Join(sysFirmware, e.Name())
+		// Requests have a loading file, the timeout file does not.
+		if _, err := os.Stat(filepath.Join(dir, "loading")); err != nil {
+			continue
+		}
+		name, err := firmwareName(dir)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		reqs = append(reqs, request{dir: dir, firmware: name})
+	}
+	return reqs, nil
+}
+
+// find returns the first file called name in the directories of path.
+func find(name string, path []string) (string, error) {
+	// Firmware names are relative to the search path and must not leave
+	// it.
+	if filepath.IsAbs(name) || strings.HasPrefix(filepath.Clean(name), "..") {
+		return "", fmt.Errorf("invalid firmware name %q", name)
+	}
+	for _, d := range path {
+		p := filepath.Join(d, name)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("firmware %q not found in %s", name, strings.Join(path, ":"))
+}
+
+func writeSys(dir, name, value string) error {
+	return ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0)
+}
+
+// load writes file to the request in dir.
+func load(dir, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := writeSys(dir, "loading", "1"); err != nil {
+		return err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, "data"), os.O_WRONLY, 0)
+	if err == nil {
+		_, err = io.Copy(data, f)
+		if cerr := data.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		// The request may be gone if it timed out, so ignore errors
+		// aborting it.
+		writeSys(dir, "loading", "-1")
+		return err
+	}
+	return writeSys(dir, "loading", "0")
+}
+
+// serve answers a request with firmware from path, or aborts it if there
+// is none.
+func serve(r request, path []string) error {
+	file, err := find(r.firmware, path)
+	if err != nil {
+		if aerr := writeSys(r.dir, "loading", "-1"); aerr != nil {
+			return fmt.Errorf("%v; aborting request: %v", err, aerr)
+		}
+		return err
+	}
+	if err := load(r.dir, file); err != nil {
+		return fmt.Errorf("loading %s: %v", file, err)
+	}
+	if *verbose {
+		log.Printf("loaded %s for %s", file, filepath.Base(r.dir))
+	}
+	return nil
+}
+
+// run serves requests until done, or forever if once is false. Each
+// request is only served once, even if it is still there the next time.
+func run(path []string) error {
+	served := make(map[string]bool)
+	for {
+		reqs, err := pending()
+		if err != nil {
+			return err
+		}
+		current := make(map[string]bool)
+		for _, r := range reqs {
+			current[r.dir] = true
+			if served[r.dir] {
+				continue
+			}
+			served[r.dir] = true
+			if err := serve(r, path); err != nil {
+				log.Print(err)
+			}
+		}
+		for d := range served {
+			if !current[d] {
+				delete(served, d)
+			}
+		}
+		if *once {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("fwload: ")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var path []string
+	if *fwPath != "" {
+		path = filepath.SplitList(*fwPath)
+	} else {
+		kver, _ := ioutil.ReadFile(osRelease)
+		path = searchPath(strings.TrimSpace(string(kver)))
+	}
+	if *timeout > 0 {
+		if err := writeSys(sysFirmware, "timeout", strconv.Itoa(*timeout)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := run(path); err != nil {
+		log.Fatal(err)
+	}
+}
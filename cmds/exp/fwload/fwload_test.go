@@ -0,0 +1,97 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchPath(t *testing.T) {
+	want := []string{"/lib/firmware/updates/5.10.0", "/lib/firmware/updates", "/lib/firmware/5.10.0", "/lib/firmware"}
+	if got := searchPath("5.10.0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("searchPath = %q, want %q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	d, err := ioutil.TempDir("", "fwload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	// Firmware is found in the first directory that has it.
+	fw1, fw2 := filepath.Join(d, "fw1"), filepath.Join(d, "fw2")
+	for name, data := range map[string]string{
+		filepath.Join(fw1, "iwlwifi-9000-pu-b0-jf-b0-46.ucode"): "iwlwifi",
+		filepath.Join(fw2, "iwlwifi-9000-pu-b0-jf-b0-46.ucode"): "old",
+		filepath.Join(fw2, "rtl_nic/rtl8168g-2.fw"):             "rtl8168g",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sysFirmware = filepath.Join(d, "sys")
+	if err := os.MkdirAll(sysFirmware, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sysFirmware, "timeout"), []byte("60\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reqs := []struct {
+		dev, firmware string
+		loading, data string
+	}{
+		{dev: "0000:00:14.3", firmware: "iwlwifi-9000-pu-b0-jf-b0-46.ucode", loading: "0", data: "iwlwifi"},
+		{dev: "0000:02:00.0", firmware: "rtl_nic/rtl8168g-2.fw", loading: "0", data: "rtl8168g"},
+		{dev: "0000:03:00.0", firmware: "nosuch.bin", loading: "-1"},
+		{dev: "0000:04:00.0", firmware: "../../etc/passwd", loading: "-1"},
+	}
+	for _, r := range reqs {
+		dir := filepath.Join(sysFirmware, r.dev)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range map[string]string{
+			"loading": "0\n",
+			"data":    "",
+			"uevent":  "ACTION=add\nDEVPATH=/devices/virtual/firmware/" + r.dev + "\nFIRMWARE=" + r.firmware + "\nTIMEOUT=60\nASYNC=0\n",
+		} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	*once = true
+	if err := run([]string{fw1, fw2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range reqs {
+		dir := filepath.Join(sysFirmware, r.dev)
+		loading, err := ioutil.ReadFile(filepath.Join(dir, "loading"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(loading) != r.loading {
+			t.Errorf("%s: loading is %q, want %q", r.firmware, loading, r.loading)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, "data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != r.data {
+			t.Errorf("%s: data is %q, want %q", r.firmware, data, r.data)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// getent prints entries of the passwd, group and hosts databases.
+//
+// Synopsis:
+//     getent DATABASE [KEY]...
+//
+// Description:
+//     getent prints the entries of DATABASE with each KEY, or all of its
+//     entries if there is no KEY. DATABASE is one of:
+//
+//     passwd: /etc/passwd, keyed by user name or UID
+//     group:  /etc/group, keyed by group name or GID
+//     hosts:  /etc/hosts, keyed by host name or address
+//
+//     A hosts KEY that is not in /etc/hosts is looked up with DNS.
+//
+//     getent exits with 1 if its arguments are wrong or a database cannot
+//     be read, and with 2 if a KEY is not found.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+// Overridden in tests.
+var (
+	lookupHost = net.LookupHost
+	lookupAddr = net.LookupAddr
+)
+
+// errNotFound is returned when a key is not in a database.
+var errNotFound = errors.New("not found")
+
+// databases print all entries of a database, or those with keys.
+var databases = map[string]func(w io.Writer, keys []string) error{
+	"passwd": passwd,
+	"group":  group,
+	"hosts":  hosts,
+}
+
+func passwd(w io.Writer, keys []string) error {
+	ents, err := getent.ReadPasswd(getent.PasswdFile)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		for _, p := range ents {
+			fmt.Fprintln(w, p)
+		}
+		return nil
+	}
+	err = nil
+	for _, k := range keys {
+		if p, ok := getent.FindPasswd(ents, k); ok {
+			fmt.Fprintln(w, p)
+		} else {
+			err = errNotFound
+		}
+	}
+	return err
+}
+
+func group(w io.Writer, keys []string) error {
+	ents, err := getent.ReadGroup(getent.GroupFile)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		for _, g := range ents {
+			fmt.Fprintln(w, g)
+		}
+		return nil
+	}
+	err = nil
+	for _, k := range keys {
+		if g, ok := getent.FindGroup(ents, k); ok {
+			fmt.Fprintln(w, g)
+		} else {
+			err = errNotFound
+		}
+	}
+	return err
+}
+
+// resolve looks up a host with DNS.
+func resolve(key string) ([]getent.Host, error) {
+	if addr := net.ParseIP(key); addr != nil {
+		names, err := lookupAddr(key)
+		if err != nil || len(names) == 0 {
+			return nil, errNotFound
+		}
+		for i, n := range names {
+			names[i] = strings.TrimSuffix(n, ".")
+		}
+		return []getent.Host{{Addr: addr, Names: names}}, nil
+	}
+	addrs, err := lookupHost(key)
+	if err != nil || len(addrs) == 0 {
+		return nil, errNotFound
+	}
+	var hs []getent.Host
+	for _, a := range addrs {
+		if addr := net.ParseIP(a); addr != nil {
+			hs = append(hs, getent.Host{Addr: addr, Names: []string{key}})
+		}
+	}
+	return hs, nil
+}
+
+func hosts(w io.Writer, keys []string) error {
+	// Without /etc/hosts, every key is looked up with DNS.
+	ents, err := getent.ReadHosts(getent.HostsFile)
+	if err != nil && (len(keys) == 0 || !os.IsNotExist(err)) {
+		return err
+	}
+	if len(keys) == 0 {
+		for _, h := range ents {
+			fmt.Fprintln(w, h)
+		}
+		return nil
+	}
+	err = nil
+	for _, k := range keys {
+		if h, ok := getent.FindHost(ents, k); ok {
+			fmt.Fprintln(w, h)
+			continue
+		}
+		hs, rerr := resolve(k)
+		if rerr != nil {
+			err = rerr
+			continue
+		}
+		for _, h := range hs {
+			fmt.Fprintln(w, h)
+		}
+	}
+	return err
+}
+
+// run prints the entries of a database and returns the exit status.
+func run(w io.Writer, args []string) int {
+	if len(args) == 0 {
+		log.Print("wrong number of arguments")
+		return 1
+	}
+	db, ok := databases[args[0]]
+	if !ok {
+		log.Printf("unknown database %q", args[0])
+		return 1
+	}
+	switch err := db(w, args[1:]); err {
+	case nil:
+		return 0
+	case errNotFound:
+		return 2
+	default:
+		log.Print(err)
+		return 1
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("getent: ")
+	os.Exit(run(os.Stdout, os.Args[1:]))
+}
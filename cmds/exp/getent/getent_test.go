@@ -0,0 +1,80 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "getent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range map[string]string{
+		"passwd": "root:x:0:0:root:/root:/bin/sh\nuser:x:1000:100::/home/user:/bin/sh\n",
+		"group":  "root:x:0:\nusers:x:100:user\n",
+		"hosts":  "127.0.0.1 localhost\n10.0.0.1 router gw\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(p, g, h string) { getent.PasswdFile, getent.GroupFile, getent.HostsFile = p, g, h }(getent.PasswdFile, getent.GroupFile, getent.HostsFile)
+	getent.PasswdFile = filepath.Join(dir, "passwd")
+	getent.GroupFile = filepath.Join(dir, "group")
+	getent.HostsFile = filepath.Join(dir, "hosts")
+
+	defer func(h, a func(string) ([]string, error)) { lookupHost, lookupAddr = h, a }(lookupHost, lookupAddr)
+	lookupHost = func(name string) ([]string, error) {
+		if name == "example.com" {
+			return []string{"192.0.2.1", "2001:db8::1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupAddr = func(addr string) ([]string, error) {
+		if addr == "192.0.2.1" {
+			return []string{"example.com."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+		code int
+	}{
+		{[]string{"passwd"}, "root:x:0:0:root:/root:/bin/sh\nuser:x:1000:100::/home/user:/bin/sh\n", 0},
+		{[]string{"passwd", "user", "0"}, "user:x:1000:100::/home/user:/bin/sh\nroot:x:0:0:root:/root:/bin/sh\n", 0},
+		{[]string{"passwd", "nobody", "root"}, "root:x:0:0:root:/root:/bin/sh\n", 2},
+		{[]string{"group"}, "root:x:0:\nusers:x:100:user\n", 0},
+		{[]string{"group", "100"}, "users:x:100:user\n", 0},
+		{[]string{"hosts"}, "127.0.0.1       localhost\n10.0.0.1        router gw\n", 0},
+		{[]string{"hosts", "gw", "10.0.0.1"}, "10.0.0.1        router gw\n10.0.0.1        router gw\n", 0},
+		{[]string{"hosts", "example.com"}, "192.0.2.1       example.com\n2001:db8::1     example.com\n", 0},
+		{[]string{"hosts", "192.0.2.1"}, "192.0.2.1       example.com\n", 0},
+		{[]string{"hosts", "nowhere", "localhost"}, "127.0.0.1       localhost\n", 2},
+		{nil, "", 1},
+		{[]string{"shadow"}, "", 1},
+	} {
+		var out bytes.Buffer
+		if code := run(&out, tt.args); code != tt.code || out.String() != tt.want {
+			t.Errorf("run(%q) = %q, %d, want %q, %d", tt.args, out.String(), code, tt.want, tt.code)
+		}
+	}
+
+	getent.PasswdFile = filepath.Join(dir, "nonexistent")
+	if code := run(ioutil.Discard, []string{"passwd", "root"}); code != 1 {
+		t.Errorf("run() with no passwd file = %d, want 1", code)
+	}
+}
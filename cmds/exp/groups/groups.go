@@ -0,0 +1,84 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// groups prints the groups of the calling process or of users.
+//
+// Synopsis:
+//     groups [USER]...
+//
+// Description:
+//     groups prints the names of the groups of the calling process, or of
+//     each USER after "USER : ". The primary group comes first. Names are
+//     looked up in /etc/passwd and /etc/group, and groups with no name are
+//     printed as numbers.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+// Overridden in tests.
+var (
+	getegid   = os.Getegid
+	getgroups = os.Getgroups
+)
+
+// names returns the names of gids, without duplicates, separated by spaces.
+func names(ents []getent.Group, gids []int) string {
+	var s []string
+	seen := map[int]bool{}
+	for _, gid := range gids {
+		if !seen[gid] {
+			seen[gid] = true
+			s = append(s, getent.GroupName(ents, gid))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// run prints the groups of the process if there are no users, or else of
+// each user. Errors are logged, and run returns whether there were none.
+func run(w io.Writer, users []string) bool {
+	// Without the files, users are not found and groups have no names.
+	pw, _ := getent.ReadPasswd(getent.PasswdFile)
+	gr, _ := getent.ReadGroup(getent.GroupFile)
+	if len(users) == 0 {
+		gids, err := getgroups()
+		if err != nil {
+			log.Print(err)
+			return false
+		}
+		fmt.Fprintln(w, names(gr, append([]int{getegid()}, gids...)))
+		return true
+	}
+	ok := true
+	for _, user := range users {
+		p, found := getent.FindPasswd(pw, user)
+		if !found {
+			log.Printf("%q: no such user", user)
+			ok = false
+			continue
+		}
+		gids := []int{p.GID}
+		for _, g := range getent.MemberOf(gr, p.Name) {
+			gids = append(gids, g.GID)
+		}
+		fmt.Fprintf(w, "%s : %s\n", user, names(gr, gids))
+	}
+	return ok
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("groups: ")
+	if !run(os.Stdout, os.Args[1:]) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, data := range map[string]string{
+		"passwd": "root:x:0:0:root:/root:/bin/sh\nuser:x:1000:100::/home/user:/bin/sh\n",
+		"group":  "root:x:0:\nwheel:x:10:root,user\nusers:x:100:user\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(p, g string) { getent.PasswdFile, getent.GroupFile = p, g }(getent.PasswdFile, getent.GroupFile)
+	getent.PasswdFile = filepath.Join(dir, "passwd")
+	getent.GroupFile = filepath.Join(dir, "group")
+
+	defer func(e func() int, g func() ([]int, error)) { getegid, getgroups = e, g }(getegid, getgroups)
+	getegid = func() int { return 100 }
+	getgroups = func() ([]int, error) { return []int{10, 100, 4242}, nil }
+
+	for _, tt := range []struct {
+		users []string
+		want  string
+		ok    bool
+	}{
+		{nil, "users wheel 4242\n", true},
+		{[]string{"user", "root"}, "user : users wheel\nroot : root wheel\n", true},
+		{[]string{"nobody", "root"}, "root : root wheel\n", false},
+	} {
+		var out bytes.Buffer
+		if ok := run(&out, tt.users); ok != tt.ok || out.String() != tt.want {
+			t.Errorf("run(%q) = %q, %v, want %q, %v", tt.users, out.String(), ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -17,7 +17,12 @@
 // arguments for commands requiring a password.
 //
 // Synopsis:
-//     hdparm [--i] [--security-unlock[=password]] [--user-master|--timeout] [device ...]
+//     hdparm [--i|--I] [--security-unlock[=password]] [--user-master|--timeout] [device ...]
+//
+// Options:
+//     -i: print the drive's identify information as JSON
+//     -I: print the drive's identify information, including
+//         supported features, in human readable form
 //
 package main
 
@@ -25,6 +30,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/u-root/u-root/pkg/mount/scuzz"
@@ -40,9 +46,10 @@ var (
 	debug           = func(string, ...interface{}) {}
 	unlock          = flag.String("security-unlock", "", "Unlock the drive with a password")
 	identify        = flag.Bool("i", false, "Get drive identifying information")
+	report          = flag.Bool("I", false, "Print drive identifying information and features")
 	admin           = flag.Bool("user-master", false, "Unlock admin (true) or user (false)")
 	timeoutDuration = flag.String("timeout", "15s", "Timeout for operations expressed as a Go duration (e.g. 15s)")
-	verbs           = []string{"security-unlock", "i"}
+	verbs           = []string{"security-unlock", "i", "I"}
 )
 
 // The hdparm switches can conflict. This function returns nil if there is no conflict, and a (hopefully)
@@ -60,6 +67,10 @@ func checkVerbs() (op, error) {
 		verb = identifyop
 		v = append(v, "i")
 	}
+	if *report {
+		verb = reportop
+		v = append(v, "I")
+	}
 
 	if len(v) > 1 {
 		return nil, fmt.Errorf("%v verbs were invoked and only one is allowed", v)
@@ -82,6 +93,91 @@ func identifyop(d scuzz.Disk) (string, error) {
 	return i.String(), nil
 }
 
+func reportop(d scuzz.Disk) (string, error) {
+	i, err := d.Identify()
+	if err != nil {
+		return "", err
+	}
+	return identifyReport(i), nil
+}
+
+// identifyReport formats identify information like hdparm -I does.
+func identifyReport(i *scuzz.Info) string {
+	var b strings.Builder
+	p := func(name string, format string, args ...interface{}) {
+		fmt.Fprintf(&b, "\t%-24s%s\n", name+":", fmt.Sprintf(format, args...))
+	}
+	b.WriteString("ATA device:\n")
+	p("Model Number", "%s", i.Model)
+	p("Serial Number", "%s", i.Serial)
+	p("Firmware Revision", "%s", i.FirmwareRevision)
+	if i.MajorVersion != "" {
+		p("Standard", "%s", i.MajorVersion)
+	}
+	size := i.NumberSectors * uint64(i.LogicalSectorSize)
+	p("Sectors", "%d", i.NumberSectors)
+	p("Size", "%d MiB, %d MB", size>>20, size/1000/1000)
+	p("Sector size", "%d bytes logical, %d bytes physical", i.LogicalSectorSize, i.PhysicalSectorSize)
+	switch i.RotationRate {
+	case 0:
+	case 1:
+		p("Rotation Rate", "Solid State Device")
+	default:
+		p("Rotation Rate", "%d rpm", i.RotationRate)
+	}
+	if i.WWN != 0 {
+		p("WWN", "%016x", i.WWN)
+	}
+	if len(i.Features.SATASpeeds) > 0 {
+		p("SATA speeds", "%s", strings.Join(i.Features.SATASpeeds, ", "))
+	}
+
+	// Like hdparm, enabled features are marked with a *.
+	b.WriteString("Features:\n")
+	f := i.Features
+	for _, feat := range []struct {
+		name               string
+		supported, enabled bool
+	}{
+		{"SMART feature set", f.SMART, f.SMARTEnabled},
+		{"Security Mode feature set", f.Security, f.SecurityEnabled},
+		{"Write cache", f.WriteCache, f.WriteCacheEnabled},
+		{"Look-ahead", f.ReadLookAhead, f.ReadLookAheadEnabled},
+		{"48-bit Address feature set", f.LBA48, f.LBA48},
+		{"Data Set Management TRIM supported", f.TRIM, f.TRIM},
+		{fmt.Sprintf("Native Command Queueing (NCQ), depth %d", f.NCQDepth), f.NCQ, f.NCQ},
+	} {
+		if !feat.supported {
+			continue
+		}
+		mark := " "
+		if feat.enabled {
+			mark = "*"
+		}
+		fmt.Fprintf(&b, "\t   %s\t%s\n", mark, feat.name)
+	}
+
+	if st := i.SecurityStatus; st.SecuritySupported() {
+		b.WriteString("Security:\n")
+		for _, s := range []struct {
+			name string
+			set  bool
+		}{
+			{"enabled", st.SecurityEnabled()},
+			{"locked", st.SecurityLocked()},
+			{"frozen", st.SecurityFrozen()},
+			{"expired: security count", st.SecurityCountExpired()},
+		} {
+			not := "not\t"
+			if s.set {
+				not = "\t"
+			}
+			fmt.Fprintf(&b, "\t%s%s\n", not, s.name)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
 func main() {
 	flag.Parse()
 
@@ -0,0 +1,63 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/u-root/u-root/pkg/mount/scuzz"
+)
+
+func TestIdentifyReport(t *testing.T) {
+	i := &scuzz.Info{
+		Model:              "Samsung SSD 860 EVO 2TB",
+		Serial:             "S3YHNX0K123456",
+		FirmwareRevision:   "RVT04B6Q",
+		MajorVersion:       "ACS-3",
+		NumberSectors:      3907029168,
+		LogicalSectorSize:  512,
+		PhysicalSectorSize: 512,
+		RotationRate:       1,
+		WWN:                0x5002538e40501234,
+		SecurityStatus:     0x19,
+		Features: scuzz.Features{
+			SMART:        true,
+			SMARTEnabled: true,
+			Security:     true,
+			WriteCache:   true,
+			LBA48:        true,
+			TRIM:         true,
+			NCQ:          true,
+			NCQDepth:     32,
+			SATASpeeds:   []string{"1.5 Gb/s", "3.0 Gb/s", "6.0 Gb/s"},
+		},
+	}
+	want := `ATA device:
+	Model Number:           Samsung SSD 860 EVO 2TB
+	Serial Number:          S3YHNX0K123456
+	Firmware Revision:      RVT04B6Q
+	Standard:               ACS-3
+	Sectors:                3907029168
+	Size:                   1907729 MiB, 2000398 MB
+	Sector size:            512 bytes logical, 512 bytes physical
+	Rotation Rate:          Solid State Device
+	WWN:                    5002538e40501234
+	SATA speeds:            1.5 Gb/s, 3.0 Gb/s, 6.0 Gb/s
+Features:
+	   *	SMART feature set
+	    	Security Mode feature set
+	    	Write cache
+	   *	48-bit Address feature set
+	   *	Data Set Management TRIM supported
+	   *	Native Command Queueing (NCQ), depth 32
+Security:
+	not	enabled
+	not	locked
+		frozen
+		expired: security count`
+	if got := identifyReport(i); got != want {
+		t.Errorf("identifyReport:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// head prints the first 10 lines of files.
+//
+// Synopsis:
+//     head [-q | -v] [-n [-]K | -c [-]K] [FILE]...
+//
+// Description:
+//     If no files are specified, or a file is -, read from stdin. If more
+//     than one file is given, the output of each is preceded by a header,
+//     ==> FILE <==.
+//
+//     K is a number, optionally followed by a multiplier: b (512), K or KiB
+//     (1024), KB (1000), M or MiB, MB, G or GiB, or GB. With a leading -,
+//     all but the last K lines or bytes are printed.
+//
+// Options:
+//     -n: number of lines to print (default: 10)
+//     -c: number of bytes to print
+//     -q: never print headers
+//     -v: always print headers
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	numLines = flag.String("n", "10", "number of lines to print, or with -K, all but the last K")
+	numBytes = flag.String("c", "", "number of bytes to print, or with -K, all but the last K")
+	quiet    = flag.Bool("q", false, "never print headers")
+	verbose  = flag.Bool("v", false, "always print headers")
+)
+
+// config is what head prints of each file.
+type config struct {
+	// count is the number of lines or bytes.
+	count uint
+	// bytes counts bytes instead of lines.
+	bytes bool
+	// allBut prints all but the last count lines or bytes.
+	allBut bool
+}
+
+// multipliers are the suffixes of counts.
+var multipliers = map[string]uint64{
+	"":    1,
+	"b":   512,
+	"K":   1 << 10,
+	"KiB": 1 << 10,
+	"KB":  1000,
+	"M":   1 << 20,
+	"MiB": 1 << 20,
+	"MB":  1000 * 1000,
+	"G":   1 << 30,
+	"GiB": 1 << 30,
+	"GB":  1000 * 1000 * 1000,
+}
+
+// parseCount parses the count s of -n or -c, [-]K with an optional
+// multiplier. It returns whether K is preceded by a -.
+func parseCount(s string) (uint, bool, error) {
+	num := strings.TrimPrefix(s, "-")
+	allBut := num != s
+	i := strings.IndexFunc(num, func(r rune) bool { return r < '0' || r > '9' })
+	if i < 0 {
+		i = len(num)
+	}
+	m, ok := multipliers[num[i:]]
+	if !ok || i == 0 {
+		return 0, false, fmt.Errorf("invalid count %q", s)
+	}
+	n, err := strconv.ParseUint(num[:i], 10, 64)
+	if err != nil || n > uint64(^uint(0)>>1)/m {
+		return 0, false, fmt.Errorf("invalid count %q", s)
+	}
+	return uint(n * m), allBut, nil
+}
+
+// head writes the part of r given by c to w.
+func head(r io.Reader, w io.Writer, c config) error {
+	switch {
+	case c.bytes && !c.allBut:
+		_, err := io.CopyN(w, r, int64(c.count))
+		if err == io.EOF {
+			return nil
+		}
+		return err
+
+	case c.bytes:
+		// Hold back the last count bytes read.
+		br := bufio.NewReader(r)
+		var held []byte
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := br.Read(buf)
+			held = append(held, buf[:n]...)
+			if extra := uint(len(held)); extra > c.count {
+				if _, err := w.Write(held[:extra-c.count]); err != nil {
+					return err
+				}
+				held = append(held[:0], held[extra-c.count:]...)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	br := bufio.NewReader(r)
+	// held are the last count lines read, which are not printed yet.
+	var held [][]byte
+	for printed := uint(0); c.allBut || printed < c.count; {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if c.allBut {
+				held = append(held, line)
+				if uint(len(held)) <= c.count {
+					line = nil
+				} else {
+					line, held = held[0], held[1:]
+				}
+			}
+			if line != nil {
+				if _, err := w.Write(line); err != nil {
+					return err
+				}
+				printed++
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run prints the head of each file in names, or stdin if there are none,
+// preceded by a header if there are several files or verbose is set. It
+// keeps going if a file fails.
+func run(names []string, w io.Writer, c config, quiet, verbose bool) error {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	headers := !quiet && (verbose || len(names) > 1)
+	var failed bool
+	for i, name := range names {
+		var r io.Reader = os.Stdin
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				log.Print(err)
+				failed = true
+				continue
+			}
+			defer f.Close()
+			r = f
+		}
+		if headers {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			title := name
+			if name == "-" {
+				title = "standard input"
+			}
+			fmt.Fprintf(w, "==> %s <==\n", title)
+		}
+		if err := head(r, w, c); err != nil {
+			log.Printf("%s: %v", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some files could not be read")
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("head: ")
+	flag.Parse()
+
+	count, countBytes := *numLines, false
+	if *numBytes != "" {
+		count, countBytes = *numBytes, true
+	}
+	n, allBut, err := parseCount(count)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = run(flag.Args(), w, config{count: n, bytes: countBytes, allBut: allBut}, *quiet, *verbose)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCount(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		n      uint
+		allBut bool
+		err    bool
+	}{
+		{in: "10", n: 10},
+		{in: "-3", n: 3, allBut: true},
+		{in: "0", n: 0},
+		{in: "2b", n: 1024},
+		{in: "-1K", n: 1024, allBut: true},
+		{in: "2KB", n: 2000},
+		{in: "1MiB", n: 1 << 20},
+		{in: "", err: true},
+		{in: "-", err: true},
+		{in: "+3", err: true},
+		{in: "1X", err: true},
+		{in: "99999999999999999999", err: true},
+	} {
+		n, allBut, err := parseCount(tt.in)
+		if (err != nil) != tt.err || n != tt.n || allBut != tt.allBut {
+			t.Errorf("parseCount(%q) = %d, %t, %v, want %d, %t, error %t", tt.in, n, allBut, err, tt.n, tt.allBut, tt.err)
+		}
+	}
+}
+
+func TestHead(t *testing.T) {
+	const in = "one\ntwo\nthree\nfour"
+	for _, tt := range []struct {
+		name string
+		c    config
+		want string
+	}{
+		{"lines", config{count: 2}, "one\ntwo\n"},
+		{"no lines", config{count: 0}, ""},
+		{"more lines than input", config{count: 10}, in},
+		{"all but last lines", config{count: 1, allBut: true}, "one\ntwo\nthree\n"},
+		{"all but zero lines", config{count: 0, allBut: true}, in},
+		{"all but too many lines", config{count: 10, allBut: true}, ""},
+		{"bytes", config{count: 5, bytes: true}, "one\nt"},
+		{"more bytes than input", config{count: 100, bytes: true}, in},
+		{"all but last bytes", config{count: 4, bytes: true, allBut: true}, "one\ntwo\nthree\n"},
+		{"all but too many bytes", config{count: 100, bytes: true, allBut: true}, ""},
+	} {
+		var out bytes.Buffer
+		if err := head(strings.NewReader(in), &out, tt.c); err != nil || out.String() != tt.want {
+			t.Errorf("%s: head() = %q, %v, want %q", tt.name, out.String(), err, tt.want)
+		}
+	}
+}
+
+func TestHeadAllButBytesLarge(t *testing.T) {
+	in := strings.Repeat("0123456789", 10000)
+	var out bytes.Buffer
+	if err := head(strings.NewReader(in), &out, config{count: 5, bytes: true, allBut: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != in[:len(in)-5] {
+		t.Errorf("head() returned %d bytes, want %d", out.Len(), len(in)-5)
+	}
+}
+
+func TestRunHeaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "head")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("a1\na2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("b1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := config{count: 1}
+	for _, tt := range []struct {
+		names   []string
+		quiet   bool
+		verbose bool
+		want    string
+		err     bool
+	}{
+		{names: []string{a}, want: "a1\n"},
+		{names: []string{a}, verbose: true, want: "==> " + a + " <==\na1\n"},
+		{names: []string{a, b}, want: "==> " + a + " <==\na1\n\n==> " + b + " <==\nb1\n"},
+		{names: []string{a, b}, quiet: true, want: "a1\nb1\n"},
+		{names: []string{a, filepath.Join(dir, "missing"), b}, quiet: true, want: "a1\nb1\n", err: true},
+	} {
+		var out bytes.Buffer
+		err := run(tt.names, &out, c, tt.quiet, tt.verbose)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("run(%v, quiet %t, verbose %t) = %q, %v, want %q, error %t", tt.names, tt.quiet, tt.verbose, out.String(), err, tt.want, tt.err)
+		}
+	}
+}
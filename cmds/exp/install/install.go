@@ -0,0 +1,319 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// install copies files and sets their attributes.
+//
+// Synopsis:
+//     install [-Dpsv] [-m MODE] [-o OWNER] [-g GROUP] SOURCE DEST
+//     install [-Dpsv] [-m MODE] [-o OWNER] [-g GROUP] SOURCE... DIRECTORY
+//     install [-Dpsv] [-m MODE] [-o OWNER] [-g GROUP] -t DIRECTORY SOURCE...
+//     install -d [-v] [-m MODE] [-o OWNER] [-g GROUP] DIRECTORY...
+//
+// Description:
+//     install copies SOURCE to DEST, or each SOURCE into DIRECTORY, and
+//     sets the mode, owner and group of the copies. A copy is written to a
+//     temporary file next to its destination, which is renamed over the
+//     destination once its attributes are set, so the destination is never
+//     seen half written.
+//
+//     With -d, install creates each DIRECTORY and the missing directories
+//     above it, and sets the attributes of DIRECTORY.
+//
+//     MODE is octal. OWNER and GROUP are names or numeric IDs.
+//
+// Options:
+//     -d: create directories
+//     -D: create the missing directories above DEST, or DIRECTORY of -t
+//     -g: set the group of the copies to GROUP
+//     -m: set the mode of the copies to MODE, 0755 by default
+//     -o: set the owner of the copies to OWNER
+//     -p: set the access and modification times of the copies to those of
+//         the sources
+//     -s: strip the copies with the strip program
+//     -t: copy each SOURCE into DIRECTORY
+//     -v: print the name of each file and directory installed
+//     --strip-program: strip with PROGRAM instead of strip
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	directory = flag.BoolP("directory", "d", false, "create directories")
+	parents   = flag.BoolP("parents", "D", false, "create the missing directories above DEST, or DIRECTORY of -t")
+	group     = flag.StringP("group", "g", "", "set the group of the copies to GROUP")
+	mode      = flag.StringP("mode", "m", "0755", "set the mode of the copies to MODE")
+	owner     = flag.StringP("owner", "o", "", "set the owner of the copies to OWNER")
+	preserve  = flag.BoolP("preserve-timestamps", "p", false, "set the times of the copies to those of the sources")
+	strip     = flag.BoolP("strip", "s", false, "strip the copies with the strip program")
+	target    = flag.StringP("target-directory", "t", "", "copy each SOURCE into DIRECTORY")
+	verbose   = flag.BoolP("verbose", "v", false, "print the name of each file and directory installed")
+	stripProg = flag.String("strip-program", "strip", "strip with PROGRAM instead of strip")
+)
+
+// Overridden in tests.
+var (
+	lookupUser  = user.Lookup
+	lookupGroup = user.LookupGroup
+)
+
+// installer installs files and directories.
+type installer struct {
+	w io.Writer
+
+	mode os.FileMode
+	// uid and gid are -1 to leave them as they are.
+	uid, gid  int
+	parents   bool
+	preserve  bool
+	strip     bool
+	stripProg string
+	verbose   bool
+}
+
+// parseMode parses an octal MODE.
+func parseMode(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 07777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	m := os.FileMode(n & 0777)
+	for bit, fm := range map[uint64]os.FileMode{04000: os.ModeSetuid, 02000: os.ModeSetgid, 01000: os.ModeSticky} {
+		if n&bit != 0 {
+			m |= fm
+		}
+	}
+	return m, nil
+}
+
+// parseID returns the ID of a user or group name, or of a numeric ID, or
+// -1 if s is empty.
+func parseID(s string, lookup func(string) (string, error)) (int, error) {
+	if s == "" {
+		return -1, nil
+	}
+	if id, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return int(id), nil
+	}
+	id, err := lookup(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q of %q", id, s)
+	}
+	return n, nil
+}
+
+func uidOf(name string) (string, error) {
+	u, err := lookupUser(name)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid, nil
+}
+
+func gidOf(name string) (string, error) {
+	g, err := lookupGroup(name)
+	if err != nil {
+		return "", err
+	}
+	return g.Gid, nil
+}
+
+// setAttrs sets the owner, group and mode of a file. The mode is set last,
+// as changing the owner clears the set-user-ID and set-group-ID bits.
+func (in *installer) setAttrs(name string) error {
+	if in.uid != -1 || in.gid != -1 {
+		if err := os.Chown(name, in.uid, in.gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(name, in.mode)
+}
+
+// installDir creates a directory and sets its attributes.
+func (in *installer) installDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if in.verbose {
+		fmt.Fprintf(in.w, "install: creating directory '%s'\n", dir)
+	}
+	return in.setAttrs(dir)
+}
+
+// atime returns the access time of a file.
+func atime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atim.Unix())
+	}
+	return fi.ModTime()
+}
+
+// installFile copies src to dst.
+func (in *installer) installFile(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("omitting directory %q", src)
+	}
+	dir := filepath.Dir(dst)
+	if in.parents {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(dst)+".")
+	if err != nil {
+		return err
+	}
+	// Once it is renamed, the temporary file is gone.
+	defer os.Remove(tmp.Name())
+
+	r, err := os.Open(src)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	r.Close()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if in.strip {
+		cmd := exec.Command(in.stripProg, tmp.Name())
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %v", in.stripProg, src, err)
+		}
+	}
+	if err := in.setAttrs(tmp.Name()); err != nil {
+		return err
+	}
+	if in.preserve {
+		if err := os.Chtimes(tmp.Name(), atime(fi), fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	if in.verbose {
+		fmt.Fprintf(in.w, "'%s' -> '%s'\n", src, dst)
+	}
+	return nil
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// run installs args, which are directories with -d, or sources followed
+// by a destination unless target is set. Errors are logged, and run
+// returns whether there were none.
+func run(in *installer, args []string, dirs bool, target string) bool {
+	if dirs {
+		if len(args) == 0 {
+			log.Print("missing directory")
+			return false
+		}
+		ok := true
+		for _, dir := range args {
+			if err := in.installDir(dir); err != nil {
+				log.Print(err)
+				ok = false
+			}
+		}
+		return ok
+	}
+	if target == "" {
+		if len(args) < 2 {
+			log.Print("missing destination")
+			return false
+		}
+		target = args[len(args)-1]
+		args = args[:len(args)-1]
+		if len(args) == 1 && !isDir(target) {
+			if err := in.installFile(args[0], target); err != nil {
+				log.Print(err)
+				return false
+			}
+			return true
+		}
+	} else if in.parents {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			log.Print(err)
+			return false
+		}
+	}
+	if len(args) == 0 {
+		log.Print("missing source")
+		return false
+	}
+	if !isDir(target) {
+		log.Printf("target %q is not a directory", target)
+		return false
+	}
+	ok := true
+	for _, src := range args {
+		if err := in.installFile(src, filepath.Join(target, filepath.Base(src))); err != nil {
+			log.Print(err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("install: ")
+	flag.Parse()
+	m, err := parseMode(*mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	uid, err := parseID(*owner, uidOf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gid, err := parseID(*group, gidOf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	in := &installer{
+		w:         os.Stdout,
+		mode:      m,
+		uid:       uid,
+		gid:       gid,
+		parents:   *parents,
+		preserve:  *preserve,
+		strip:     *strip,
+		stripProg: *stripProg,
+		verbose:   *verbose,
+	}
+	if !run(in, flag.Args(), *directory, *target) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want os.FileMode
+	}{
+		{"755", 0755},
+		{"0644", 0644},
+		{"4755", 0755 | os.ModeSetuid},
+		{"3770", 0770 | os.ModeSetgid | os.ModeSticky},
+	} {
+		if got, err := parseMode(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseMode(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"u+x", "999", "17777"} {
+		if _, err := parseMode(in); err == nil {
+			t.Errorf("parseMode(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseID(t *testing.T) {
+	defer func(f func(string) (*user.User, error)) { lookupUser = f }(lookupUser)
+	lookupUser = func(name string) (*user.User, error) {
+		if name == "bin" {
+			return &user.User{Uid: "2"}, nil
+		}
+		return nil, user.UnknownUserError(name)
+	}
+	for _, tt := range []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"", -1, true},
+		{"0", 0, true},
+		{"1000", 1000, true},
+		{"bin", 2, true},
+		{"nobody-here", 0, false},
+	} {
+		got, err := parseID(tt.in, uidOf)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseID(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	strip := filepath.Join(dir, "strip")
+	if err := ioutil.WriteFile(strip, []byte("#!/bin/sh\necho stripped > \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := func(name string) string { return filepath.Join(dir, name) }
+	if err := os.Mkdir(p("d0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		in     installer
+		args   []string
+		dirs   bool
+		target string
+		ok     bool
+		// files are the files that should exist, with their data, or "" for
+		// a directory.
+		files map[string]string
+		mode  os.FileMode
+		out   string
+	}{
+		{
+			name:  "file",
+			in:    installer{mode: 0755},
+			args:  []string{src, p("a")},
+			ok:    true,
+			files: map[string]string{"a": "data"},
+			mode:  0755,
+		},
+		{
+			name:  "into directory",
+			in:    installer{mode: 0644},
+			args:  []string{src, p("d0")},
+			ok:    true,
+			files: map[string]string{"d0/src": "data"},
+			mode:  0644,
+		},
+		{
+			name: "not a directory",
+			in:   installer{mode: 0644},
+			args: []string{src, strip, p("d1")},
+			ok:   false,
+		},
+		{
+			name:  "parents",
+			in:    installer{mode: 0640, parents: true, verbose: true},
+			args:  []string{src, p("d2/e/f")},
+			ok:    true,
+			files: map[string]string{"d2/e/f": "data"},
+			mode:  0640,
+			out:   fmt.Sprintf("'%s' -> '%s'\n", src, p("d2/e/f")),
+		},
+		{
+			name:   "target",
+			in:     installer{mode: 0600, parents: true},
+			args:   []string{src, strip},
+			target: p("d3"),
+			ok:     true,
+			files:  map[string]string{"d3/src": "data", "d3/strip": "#!/bin/sh\necho stripped > \"$1\"\n"},
+			mode:   0600,
+		},
+		{
+			name:  "directories",
+			in:    installer{mode: 0700, verbose: true},
+			args:  []string{p("d4/a"), p("d4/b")},
+			dirs:  true,
+			ok:    true,
+			files: map[string]string{"d4/a": "", "d4/b": ""},
+			mode:  os.ModeDir | 0700,
+			out:   fmt.Sprintf("install: creating directory '%s'\ninstall: creating directory '%s'\n", p("d4/a"), p("d4/b")),
+		},
+		{
+			name:  "strip",
+			in:    installer{mode: 0755, strip: true, stripProg: strip},
+			args:  []string{src, p("stripped")},
+			ok:    true,
+			files: map[string]string{"stripped": "stripped\n"},
+			mode:  0755,
+		},
+		{
+			name:  "missing source",
+			in:    installer{mode: 0755},
+			args:  []string{p("nonexistent"), src, p("d0")},
+			ok:    false,
+			files: map[string]string{"d0/src": "data"},
+			mode:  0755,
+		},
+		{
+			name: "missing destination",
+			in:   installer{mode: 0755},
+			args: []string{src},
+			ok:   false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			in := tt.in
+			in.w = &out
+			in.uid, in.gid = -1, -1
+			if ok := run(&in, tt.args, tt.dirs, tt.target); ok != tt.ok {
+				t.Errorf("run() = %v, want %v", ok, tt.ok)
+			}
+			if out.String() != tt.out {
+				t.Errorf("run() printed %q, want %q", out.String(), tt.out)
+			}
+			for name, want := range tt.files {
+				fi, err := os.Stat(p(name))
+				if err != nil {
+					t.Errorf("%v", err)
+					continue
+				}
+				if fi.Mode() != tt.mode {
+					t.Errorf("%s has mode %v, want %v", name, fi.Mode(), tt.mode)
+				}
+				if want == "" {
+					continue
+				}
+				if got, err := ioutil.ReadFile(p(name)); err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(src, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	mtime := time.Date(2002, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := os.Chtimes(src, atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	// Changing to one's own IDs is allowed without privileges.
+	in := &installer{mode: 0751, uid: os.Getuid(), gid: os.Getgid(), preserve: true}
+	if !run(in, []string{src, dst}, false, "") {
+		t.Fatal("run() failed")
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	if fi.Mode() != 0751 || int(st.Uid) != os.Getuid() || int(st.Gid) != os.Getgid() {
+		t.Errorf("dst has mode %v, uid %d and gid %d, want %v, %d and %d", fi.Mode(), st.Uid, st.Gid, os.FileMode(0751), os.Getuid(), os.Getgid())
+	}
+	if !fi.ModTime().Equal(mtime) || !time.Unix(st.Atim.Unix()).Equal(atime) {
+		t.Errorf("dst has times %v and %v, want %v and %v", time.Unix(st.Atim.Unix()), fi.ModTime(), atime, mtime)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, ".dst.*"))
+	if err != nil || len(files) != 0 {
+		t.Errorf("temporary files %q were left", files)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// ionice gets or sets the I/O scheduling class and priority of a process.
+//
+// Synopsis:
+//     ionice [-c CLASS] [-n LEVEL] [-t] -p PID...
+//     ionice [-c CLASS] [-n LEVEL] [-t] COMMAND [ARG...]
+//
+// Description:
+//     Print the I/O scheduling class and priority of the processes PID, or
+//     set them if -c or -n is given, or run COMMAND with them.
+//
+//     CLASS is 0 or none, 1 or realtime, 2 or best-effort, 3 or idle.
+//     Realtime and best-effort have levels from 0, the highest priority,
+//     to 7. Only root may use realtime. Processes of class none get a
+//     best-effort level derived from their niceness.
+//
+// Options:
+//     -c: the scheduling class, best-effort if only -n is given
+//     -n: the level within the class, 4 if only -c is given
+//     -p: operate on the existing processes PID
+//     -t: ignore failures to set the class and level
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	class  = flag.StringP("class", "c", "", "scheduling `CLASS`: none, realtime, best-effort or idle, or 0 to 3")
+	level  = flag.IntP("classdata", "n", 4, "`LEVEL` within the class, 0 to 7")
+	pids   = flag.BoolP("pid", "p", false, "operate on the existing processes PID")
+	ignore = flag.BoolP("ignore", "t", false, "ignore failures to set the class and level")
+)
+
+// I/O scheduling classes, from include/uapi/linux/ioprio.h.
+const (
+	classNone = iota
+	classRealtime
+	classBestEffort
+	classIdle
+)
+
+var classNames = []string{"none", "realtime", "best-effort", "idle"}
+
+const (
+	// whoProcess is IOPRIO_WHO_PROCESS.
+	whoProcess = 1
+	// classShift is IOPRIO_CLASS_SHIFT.
+	classShift = 13
+	// maxLevel is the highest level of realtime and best-effort.
+	maxLevel = 7
+)
+
+// prioValue packs a class and level like IOPRIO_PRIO_VALUE.
+func prioValue(class, level int) int {
+	return class<<classShift | level
+}
+
+// prioClass and prioLevel unpack a value like IOPRIO_PRIO_CLASS and
+// IOPRIO_PRIO_DATA.
+func prioClass(v int) int { return v >> classShift }
+func prioLevel(v int) int { return v & (1<<classShift - 1) }
+
+func ioprioGet(pid int) (int, error) {
+	v, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, whoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(v), nil
+}
+
+func ioprioSet(pid, v int) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, whoProcess, uintptr(pid), uintptr(v)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Overridden in tests.
+var (
+	getPrio = ioprioGet
+	setPrio = ioprioSet
+)
+
+// parseClass parses a class name or number.
+func parseClass(s string) (int, error) {
+	for i, n := range classNames {
+		if strings.EqualFold(s, n) || s == strconv.Itoa(i) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scheduling class %q", s)
+}
+
+// formatPrio formats a value like "best-effort: prio 4".
+func formatPrio(v int) string {
+	c, l := prioClass(v), prioLevel(v)
+	switch {
+	case c == classIdle:
+		return classNames[c]
+	case c < len(classNames):
+		return fmt.Sprintf("%s: prio %d", classNames[c], l)
+	}
+	return fmt.Sprintf("unknown: prio %d", l)
+}
+
+// newPrio returns the value to set, or -1 if neither -c nor -n is given.
+func newPrio(class string, classSet bool, level int, levelSet bool) (int, error) {
+	if !classSet && !levelSet {
+		return -1, nil
+	}
+	c := classBestEffort
+	if classSet {
+		var err error
+		if c, err = parseClass(class); err != nil {
+			return 0, err
+		}
+	}
+	switch c {
+	case classNone, classIdle:
+		if levelSet {
+			log.Printf("ignoring the level of class %s", classNames[c])
+		}
+		level = 0
+	default:
+		if level < 0 || level > maxLevel {
+			return 0, fmt.Errorf("level %d is out of range [0,%d]", level, maxLevel)
+		}
+	}
+	return prioValue(c, level), nil
+}
+
+// runPIDs prints or sets the I/O priority of the processes args.
+func runPIDs(w io.Writer, args []string, v int, ignore bool) error {
+	if len(args) == 0 {
+		return errors.New("no PID given")
+	}
+	var failed bool
+	for _, a := range args {
+		pid, err := strconv.Atoi(a)
+		if err != nil || pid < 0 {
+			return fmt.Errorf("invalid PID %q", a)
+		}
+		if v < 0 {
+			cur, err := getPrio(pid)
+			if err != nil {
+				log.Printf("failed to get the I/O priority of pid %d: %v", pid, err)
+				failed = true
+				continue
+			}
+			if len(args) > 1 {
+				fmt.Fprintf(w, "%d: ", pid)
+			}
+			fmt.Fprintln(w, formatPrio(cur))
+			continue
+		}
+		if err := setPrio(pid, v); err != nil && !ignore {
+			log.Printf("failed to set the I/O priority of pid %d: %v", pid, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some I/O priorities could not be changed")
+	}
+	return nil
+}
+
+// runCommand runs args with the I/O priority v, or prints the priority of
+// ionice itself if there are no args.
+func runCommand(w io.Writer, args []string, v int, ignore bool) error {
+	if len(args) == 0 {
+		if v >= 0 {
+			return errors.New("no command given")
+		}
+		return runPIDs(w, []string{"0"}, v, ignore)
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	// Like the CPU affinity, the I/O priority is per thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if v >= 0 {
+		if err := setPrio(0, v); err != nil && !ignore {
+			return fmt.Errorf("failed to set the I/O priority: %v", err)
+		}
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ionice: ")
+	// Options after COMMAND belong to it.
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+
+	v, err := newPrio(*class, flag.CommandLine.Changed("class"), *level, flag.CommandLine.Changed("classdata"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *pids {
+		err = runPIDs(os.Stdout, flag.Args(), v, *ignore)
+	} else {
+		err = runCommand(os.Stdout, flag.Args(), v, *ignore)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestPrioValue(t *testing.T) {
+	for _, tt := range []struct {
+		class, level, v int
+	}{
+		{classNone, 0, 0},
+		{classRealtime, 0, 0x2000},
+		{classBestEffort, 4, 0x4004},
+		{classIdle, 0, 0x6000},
+	} {
+		v := prioValue(tt.class, tt.level)
+		if v != tt.v {
+			t.Errorf("prioValue(%d, %d) = %#x, want %#x", tt.class, tt.level, v, tt.v)
+		}
+		if prioClass(v) != tt.class || prioLevel(v) != tt.level {
+			t.Errorf("prioClass, prioLevel(%#x) = %d, %d, want %d, %d", v, prioClass(v), prioLevel(v), tt.class, tt.level)
+		}
+	}
+}
+
+func TestNewPrio(t *testing.T) {
+	for _, tt := range []struct {
+		class    string
+		classSet bool
+		level    int
+		levelSet bool
+		want     int
+		err      bool
+	}{
+		{level: 4, want: -1},
+		{class: "idle", classSet: true, level: 4, want: 0x6000},
+		{class: "3", classSet: true, level: 2, levelSet: true, want: 0x6000},
+		{class: "Realtime", classSet: true, level: 4, want: 0x2004},
+		{class: "2", classSet: true, level: 7, levelSet: true, want: 0x4007},
+		{level: 0, levelSet: true, want: 0x4000},
+		{class: "none", classSet: true, level: 4, want: 0},
+		{class: "4", classSet: true, err: true},
+		{class: "fast", classSet: true, err: true},
+		{class: "1", classSet: true, level: 8, levelSet: true, err: true},
+		{level: -1, levelSet: true, err: true},
+	} {
+		got, err := newPrio(tt.class, tt.classSet, tt.level, tt.levelSet)
+		if (err != nil) != tt.err || (err == nil && got != tt.want) {
+			t.Errorf("newPrio(%q, %t, %d, %t) = %#x, %v, want %#x, error %t", tt.class, tt.classSet, tt.level, tt.levelSet, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRunPIDs(t *testing.T) {
+	defer func(g func(int) (int, error), s func(int, int) error) { getPrio, setPrio = g, s }(getPrio, setPrio)
+	prios := map[int]int{1: 0, 2: 0x4003, 3: 0x6000}
+	getPrio = func(pid int) (int, error) {
+		v, ok := prios[pid]
+		if !ok {
+			return 0, unix.ESRCH
+		}
+		return v, nil
+	}
+	setPrio = func(pid, v int) error {
+		if _, ok := prios[pid]; !ok {
+			return unix.ESRCH
+		}
+		prios[pid] = v
+		return nil
+	}
+
+	var b bytes.Buffer
+	if err := runPIDs(&b, []string{"2"}, -1, false); err != nil || b.String() != "best-effort: prio 3\n" {
+		t.Errorf("runPIDs(2) = %q, %v", b.String(), err)
+	}
+	b.Reset()
+	if err := runPIDs(&b, []string{"1", "3"}, -1, false); err != nil || b.String() != "1: none: prio 0\n3: idle\n" {
+		t.Errorf("runPIDs(1, 3) = %q, %v", b.String(), err)
+	}
+	if err := runPIDs(&b, []string{"1", "4"}, 0x2001, false); err == nil {
+		t.Error("runPIDs(1, 4) = nil, want error for the missing process")
+	}
+	if prios[1] != 0x2001 {
+		t.Errorf("priority of pid 1 = %#x, want %#x", prios[1], 0x2001)
+	}
+	if err := runPIDs(&b, []string{"4"}, 0x2001, true); err != nil {
+		t.Errorf("runPIDs(4) with -t = %v, want nil", err)
+	}
+	for _, args := range [][]string{nil, {"x"}} {
+		if err := runPIDs(&b, args, -1, false); err == nil {
+			t.Errorf("runPIDs(%q) = nil, want error", args)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// lsblk lists block devices.
+//
+// Synopsis:
+//     lsblk [-abfJ] [DEVICE...]
+//
+// Description:
+//     List the block devices in /sys/block, or only DEVICE, as a tree with
+//     partitions under their disk. Devices of size 0, like unused loop
+//     devices, are left out.
+//
+// Options:
+//     -a, --all:   include devices of size 0
+//     -b, --bytes: print sizes in bytes rather than in human readable form
+//     -f, --fs:    print file system type, label and UUID instead of the
+//                  device columns. File systems are found by reading
+//                  their superblock.
+//     -J, --json:  use JSON output
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/mount"
+	"github.com/u-root/u-root/pkg/mount/block"
+)
+
+var (
+	all     = flag.BoolP("all", "a", false, "include devices of size 0")
+	inBytes = flag.BoolP("bytes", "b", false, "print sizes in bytes")
+	fs      = flag.BoolP("fs", "f", false, "print file system information")
+	toJSON  = flag.BoolP("json", "J", false, "use JSON output")
+
+	// These are variables so they can be changed in tests.
+	sysBlock   = "/sys/block"
+	devDir     = "/dev"
+	mountsPath = "/proc/mounts"
+)
+
+// device is a block device.
+type device struct {
+	name       string
+	majMin     string
+	removable  bool
+	readOnly   bool
+	size       uint64
+	typ        string
+	mountpoint string
+	fs         block.FSInfo
+	children   []*device
+}
+
+// readSys returns the trimmed contents of a sysfs file, or "" on error.
+func readSys(elem ...string) string {
+	b, err := ioutil.ReadFile(filepath.Join(elem...))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// newDevice reads a device from its sysfs directory. Its superblock is
+// only read with -f, which needs access to the device.
+func newDevice(dir, name, typ string, mounts map[string]string) *device {
+	sectors, _ := strconv.ParseUint(readSys(dir, "size"), 10, 64)
+	d := &device{
+		name:       name,
+		majMin:     readSys(dir, "dev"),
+		removable:  readSys(dir, "removable") == "1",
+		readOnly:   readSys(dir, "ro") == "1",
+		size:       sectors * 512,
+		typ:        typ,
+		mountpoint: mounts[filepath.Join(devDir, name)],
+	}
+	if !*fs {
+		return d
+	}
+	if f, err := os.Open(filepath.Join(devDir, name)); err == nil {
+		d.fs, _ = block.ProbeFS(f)
+		f.Close()
+	}
+	return d
+}
+
+// diskType returns the type of a whole device.
+func diskType(dir, name string) string {
+	switch {
+	case strings.HasPrefix(name, "loop"):
+		return "loop"
+	case strings.HasPrefix(name, "sr"), readSys(dir, "device", "type") == "5":
+		return "rom"
+	}
+	return "disk"
+}
+
+// devices returns the devices in sysBlock, with their partitions, sorted
+// by name. If names is not empty, only those devices are returned.
+func devices(names []string) ([]*device, error) {
+	mounts, err := readMounts()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(sysBlock)
+	if err != nil {
+		return nil, err
+	}
+	want, found := map[string]bool{}, map[string]bool{}
+	for _, n := range names {
+		want[filepath.Base(n)] = true
+	}
+
+	var devs []*device
+	for _, e := range entries {
+		name := e.Name()
+		if len(names) > 0 && !want[name] {
+			continue
+		}
+		found[name] = true
+		dir := filepath.Join(sysBlock, name)
+		d := newDevice(dir, name, diskType(dir, name), mounts)
+		if d.size == 0 && !*all && len(names) == 0 {
+			continue
+		}
+		parts, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			pdir := filepath.Join(dir, p.Name())
+			if _, err := os.Stat(filepath.Join(pdir, "partition")); err != nil {
+				continue
+			}
+			d.children = append(d.children, newDevice(pdir, p.Name(), "part", mounts))
+		}
+		sort.Slice(d.children, func(i, j int) bool {
+			pi, _ := strconv.Atoi(readSys(dir, d.children[i].name, "partition"))
+			pj, _ := strconv.Atoi(readSys(dir, d.children[j].name, "partition"))
+			return pi < pj
+		})
+		devs = append(devs, d)
+	}
+	for _, n := range names {
+		if !found[filepath.Base(n)] {
+			return nil, fmt.Errorf("%s: not a block device", n)
+		}
+	}
+	return devs, nil
+}
+
+// readMounts returns the first mountpoint of each device in mountsPath.
+func readMounts() (map[string]string, error) {
+	m := map[string]string{}
+	f, err := os.Open(mountsPath)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "/") {
+			continue
+		}
+		dev := mount.Unescape(fields[0])
+		if p, err := filepath.EvalSymlinks(dev); err == nil && strings.HasPrefix(dev, "/dev/") {
+			dev = filepath.Join(devDir, filepath.Base(p))
+		}
+		if _, ok := m[dev]; !ok {
+			m[dev] = mount.Unescape(fields[1])
+		}
+	}
+	return m, s.Err()
+}
+
+// humanSize formats a size like lsblk, e.g. 512M or 465.8G.
+func humanSize(n uint64) string {
+	const units = "BKMGTPE"
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	s = strings.TrimSuffix(s, ".0")
+	return s + units[i:i+1]
+}
+
+// column is an output column. value returns a string, bool, uint64 or
+// nil for a missing value.
+type column struct {
+	header string
+	key    string
+	right  bool
+	value  func(d *device) interface{}
+}
+
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func columns(fs, inBytes bool) []column {
+	name := column{"NAME", "name", false, func(d *device) interface{} { return d.name }}
+	mountpoint := column{"MOUNTPOINT", "mountpoint", false, func(d *device) interface{} { return optional(d.mountpoint) }}
+	if fs {
+		return []column{
+			name,
+			{"FSTYPE", "fstype", false, func(d *device) interface{} { return optional(d.fs.Type) }},
+			{"LABEL", "label", false, func(d *device) interface{} { return optional(d.fs.Label) }},
+			{"UUID", "uuid", false, func(d *device) interface{} { return optional(d.fs.UUID) }},
+			mountpoint,
+		}
+	}
+	return []column{
+		name,
+		{"MAJ:MIN", "maj:min", true, func(d *device) interface{} { return d.majMin }},
+		{"RM", "rm", true, func(d *device) interface{} { return d.removable }},
+		{"SIZE", "size", true, func(d *device) interface{} {
+			if inBytes {
+				return d.size
+			}
+			return humanSize(d.size)
+		}},
+		{"RO", "ro", true, func(d *device) interface{} { return d.readOnly }},
+		{"TYPE", "type", false, func(d *device) interface{} { return d.typ }},
+		mountpoint,
+	}
+}
+
+// text formats a column value for the table.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+// rows flattens the device tree into table rows, drawing the tree in the
+// first column. indent is what is drawn before the branches of devs.
+func rows(cols []column, devs []*device, indent string, top bool) [][]string {
+	var r [][]string
+	for i, d := range devs {
+		row := make([]string, len(cols))
+		for j, c := range cols {
+			row[j] = text(c.value(d))
+		}
+		next := ""
+		if !top {
+			branch, cont := "├─", "│ "
+			if i == len(devs)-1 {
+				branch, cont = "└─", "  "
+			}
+			row[0] = indent + branch + row[0]
+			next = indent + cont
+		}
+		r = append(r, row)
+		r = append(r, rows(cols, d.children, next, false)...)
+	}
+	return r
+}
+
+// printTable prints the devices as an aligned table. The last column is
+// not padded.
+func printTable(w io.Writer, cols []column, devs []*device) {
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.header
+	}
+	all := append([][]string{header}, rows(cols, devs, "", true)...)
+	width := make([]int, len(cols))
+	for _, row := range all {
+		for i, v := range row {
+			if n := len([]rune(v)); n > width[i] {
+				width[i] = n
+			}
+		}
+	}
+	for _, row := range all {
+		var b strings.Builder
+		for i, v := range row {
+			pad := strings.Repeat(" ", width[i]-len([]rune(v)))
+			switch {
+			case cols[i].right:
+				b.WriteString(pad + v)
+			case i < len(row)-1:
+				b.WriteString(v + pad)
+			default:
+				b.WriteString(v)
+			}
+			if i < len(row)-1 {
+				b.WriteByte(' ')
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// object is a JSON object that keeps the order of its keys.
+type object struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(kb)
+		b.WriteByte(':')
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+func objects(cols []column, devs []*device) []*object {
+	var objs []*object
+	for _, d := range devs {
+		o := &object{}
+		for _, c := range cols {
+			o.keys = append(o.keys, c.key)
+			o.values = append(o.values, c.value(d))
+		}
+		if len(d.children) > 0 {
+			o.keys = append(o.keys, "children")
+			o.values = append(o.values, objects(cols, d.children))
+		}
+		objs = append(objs, o)
+	}
+	return objs
+}
+
+// printJSON prints the devices like lsblk -J.
+func printJSON(w io.Writer, cols []column, devs []*device) error {
+	b, err := json.MarshalIndent(map[string]interface{}{"blockdevices": objects(cols, devs)}, "", "   ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func main() {
+	flag.Parse()
+	devs, err := devices(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cols := columns(*fs, *inBytes)
+	if *toJSON {
+		if err := printJSON(os.Stdout, cols, devs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printTable(os.Stdout, cols, devs)
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+)
+
+// ext4 returns an ext4 superblock with a journal and extents.
+func ext4(label string) []byte {
+	b := make([]byte, 4096)
+	s := b[1024:]
+	binary.LittleEndian.PutUint16(s[0x38:], 0xEF53)
+	binary.LittleEndian.PutUint32(s[0x5c:], 0x4)
+	binary.LittleEndian.PutUint32(s[0x60:], 0x40)
+	for i := 0; i < 16; i++ {
+		s[0x68+i] = byte(i)
+	}
+	copy(s[0x78:], label)
+	return b
+}
+
+func fat32(label string) []byte {
+	b := make([]byte, 512)
+	copy(b[0x52:], "FAT32   ")
+	copy(b[0x43:], []byte{0xef, 0xbe, 0xad, 0xde})
+	copy(b[0x47:], label)
+	return b
+}
+
+func TestHumanSize(t *testing.T) {
+	for _, tt := range []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1024, "1K"},
+		{512 << 20, "512M"},
+		{500107862016, "465.8G"},
+		{8 << 40, "8T"},
+	} {
+		if got := humanSize(tt.n); got != tt.want {
+			t.Errorf("humanSize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// setup creates a fake sysfs, /dev and /proc/mounts with a disk sda with
+// two partitions, a read only removable disk sdb and an unused loop0.
+func setup(t *testing.T) string {
+	d, err := ioutil.TempDir("", "lsblk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"sys/sda/dev":             "8:0\n",
+		"sys/sda/size":            "16777216\n",
+		"sys/sda/removable":       "0\n",
+		"sys/sda/ro":              "0\n",
+		"sys/sda/sda2/dev":        "8:2\n",
+		"sys/sda/sda2/size":       "15728640\n",
+		"sys/sda/sda2/partition":  "2\n",
+		"sys/sda/sda10/dev":       "8:10\n",
+		"sys/sda/sda10/size":      "1048576\n",
+		"sys/sda/sda10/partition": "10\n",
+		"sys/sda/queue/x":         "",
+		"sys/sdb/dev":             "8:16\n",
+		"sys/sdb/size":            "2048\n",
+		"sys/sdb/removable":       "1\n",
+		"sys/sdb/ro":              "1\n",
+		"sys/loop0/dev":           "7:0\n",
+		"sys/loop0/size":          "0\n",
+		"dev/sda2":                string(ext4("root")),
+		"dev/sda10":               string(fat32("EFI        ")),
+		"mounts":                  "/dev/sda2 / ext4 rw 0 0\nproc /proc proc rw 0 0\n/dev/sda10 /boot/my\\040efi vfat rw 0 0\n",
+	}
+	for n, c := range files {
+		p := filepath.Join(d, n)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sysBlock = filepath.Join(d, "sys")
+	devDir = filepath.Join(d, "dev")
+	mountsPath = filepath.Join(d, "mounts")
+	return d
+}
+
+func TestLsblk(t *testing.T) {
+	d := setup(t)
+	defer os.RemoveAll(d)
+	// The mounts refer to /dev, so make them refer to the fake one.
+	b, err := ioutil.ReadFile(mountsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = bytes.Replace(b, []byte("/dev/"), []byte(devDir+"/"), -1)
+	if err := ioutil.WriteFile(mountsPath, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		all     bool
+		fs      bool
+		inBytes bool
+		json    bool
+		args    []string
+		want    string
+	}{
+		{
+			name: "default",
+			want: `NAME    MAJ:MIN RM SIZE RO TYPE MOUNTPOINT
+sda         8:0  0   8G  0 disk
+├─sda2      8:2  0 7.5G  0 part /
+└─sda10    8:10  0 512M  0 part /boot/my efi
+sdb        8:16  1   1M  1 disk
+`,
+		},
+		{
+			name:    "all bytes",
+			all:     true,
+			inBytes: true,
+			args:    []string{"/dev/loop0", "sdb"},
+			want: `NAME  MAJ:MIN RM    SIZE RO TYPE MOUNTPOINT
+loop0     7:0  0       0  0 loop
+sdb      8:16  1 1048576  1 disk
+`,
+		},
+		{
+			name: "fs",
+			fs:   true,
+			args: []string{"sda"},
+			want: `NAME    FSTYPE LABEL UUID                                 MOUNTPOINT
+sda
+├─sda2  ext4   root  00010203-0405-0607-0809-0a0b0c0d0e0f /
+└─sda10 vfat   EFI   dead-beef                            /boot/my efi
+`,
+		},
+		{
+			name:    "json",
+			inBytes: true,
+			json:    true,
+			args:    []string{"sdb"},
+			want: `{
+   "blockdevices": [
+      {
+         "name": "sdb",
+         "maj:min": "8:16",
+         "rm": true,
+         "size": 1048576,
+         "ro": true,
+         "type": "disk",
+         "mountpoint": null
+      }
+   ]
+}
+`,
+		},
+	} {
+		*all, *fs = tt.all, tt.fs
+		devs, err := devices(tt.args)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var out bytes.Buffer
+		cols := columns(tt.fs, tt.inBytes)
+		if tt.json {
+			if err := printJSON(&out, cols, devs); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			printTable(&out, cols, devs)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, out.String(), tt.want)
+		}
+	}
+	*all, *fs = false, false
+
+	// Without -f, the devices are not read.
+	devs, err := devices([]string{"sda"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := devs[0].children[0].fs; got != (block.FSInfo{}) {
+		t.Errorf("devices(sda) without -f read %s: %+v", devs[0].children[0].name, got)
+	}
+
+	if _, err := devices([]string{"sdz"}); err == nil {
+		t.Errorf("devices(sdz): got nil, want error")
+	}
+}
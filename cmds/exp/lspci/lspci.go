@@ -0,0 +1,252 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// lspci lists PCI devices.
+//
+// Synopsis:
+//     lspci [-Dnv] [-i FILE] [-s [[[[DOMAIN]:]BUS]:][SLOT][.[FUNC]]]
+//
+// Description:
+//     List the devices in /sys/bus/pci with their class, vendor and device
+//     names. Names come from the database built into pkg/pci, which has
+//     no subsystems, unless a pci.ids file is given with -i.
+//
+// Options:
+//     -D: always show the PCI domain
+//     -i: read vendor and device names from FILE, in pci.ids format
+//     -n: show numeric IDs rather than names; twice to show both
+//     -s: only show devices in the given domain, bus, slot and function,
+//         all in hex. Parts left out or given as * match any device.
+//     -v: also show the subsystem, IRQ and kernel driver
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/pci"
+)
+
+var (
+	domain  = flag.BoolP("domain", "D", false, "always show the PCI domain")
+	idsFile = flag.StringP("ids", "i", "", "read names from `FILE` in pci.ids format")
+	numeric = flag.CountP("numeric", "n", "show numeric IDs; twice to show names and IDs")
+	sel     = flag.StringP("select", "s", "", "only show devices at [[[[domain]:]bus]:][slot][.[func]]")
+	verbose = flag.BoolP("verbose", "v", false, "show subsystem, IRQ and kernel driver")
+)
+
+// options control how devices are printed.
+type options struct {
+	domain  bool
+	numeric int
+	verbose bool
+	ids     *pci.IDs
+}
+
+// attr returns a sysfs attribute of a device without the 0x prefix, or ""
+// if it can not be read.
+func attr(p *pci.PCI, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(p.FullPath, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(b)), "0x")
+}
+
+func hex16(s string) uint16 {
+	n, _ := strconv.ParseUint(s, 16, 16)
+	return uint16(n)
+}
+
+// name formats a vendor and device like lspci does.
+func (o *options) name(vendor, device string) string {
+	ids := vendor + ":" + device
+	if o.numeric == 1 {
+		return ids
+	}
+	v, ok := o.ids.LookupVendor(hex16(vendor))
+	if !ok {
+		return "Device " + ids
+	}
+	d, ok := o.ids.LookupDevice(hex16(vendor), hex16(device))
+	if !ok {
+		d = "Device " + device
+	}
+	s := v + " " + d
+	if o.numeric > 1 {
+		s += " [" + ids + "]"
+	}
+	return s
+}
+
+// subsystem formats the subsystem of a device.
+func (o *options) subsystem(vendor, device, subvendor, subdevice string) string {
+	ids := subvendor + ":" + subdevice
+	if o.numeric == 1 {
+		return ids
+	}
+	v, ok := o.ids.LookupVendor(hex16(subvendor))
+	if !ok {
+		return "Device " + ids
+	}
+	d, ok := o.ids.LookupSubsystem(hex16(vendor), hex16(device), hex16(subvendor), hex16(subdevice))
+	if !ok {
+		d = "Device " + subdevice
+	}
+	s := v + " " + d
+	if o.numeric > 1 {
+		s += " [" + ids + "]"
+	}
+	return s
+}
+
+// class formats a class like lspci does.
+func (o *options) class(class string) string {
+	code := class
+	if len(code) > 4 {
+		code = code[:4]
+	}
+	if o.numeric == 1 {
+		return code
+	}
+	n, err := strconv.ParseUint(class, 16, 32)
+	name, ok := o.ids.LookupClass(uint32(n))
+	if err != nil || !ok {
+		name = "Class " + code
+	}
+	if o.numeric > 1 {
+		name += " [" + code + "]"
+	}
+	return name
+}
+
+// progIf formats the programming interface of a class.
+func (o *options) progIf(class string) string {
+	pi := class[4:]
+	if o.numeric == 1 {
+		return pi
+	}
+	n, _ := strconv.ParseUint(class, 16, 32)
+	if name, ok := o.ids.LookupProgIf(uint32(n)); ok {
+		return pi + " [" + name + "]"
+	}
+	return pi
+}
+
+// print prints one device.
+func (o *options) print(w io.Writer, p *pci.PCI) {
+	addr := p.Addr
+	if !o.domain {
+		addr = strings.TrimPrefix(addr, "0000:")
+	}
+	class := attr(p, "class")
+	fmt.Fprintf(w, "%s %s: %s", addr, o.class(class), o.name(p.Vendor, p.Device))
+	if rev := attr(p, "revision"); rev != "" && rev != "00" {
+		fmt.Fprintf(w, " (rev %s)", rev)
+	}
+	if o.verbose && len(class) == 6 && class[4:] != "00" {
+		fmt.Fprintf(w, " (prog-if %s)", o.progIf(class))
+	}
+	fmt.Fprintln(w)
+	if !o.verbose {
+		return
+	}
+	if sv, sd := attr(p, "subsystem_vendor"), attr(p, "subsystem_device"); sv != "" && sv != "0000" {
+		fmt.Fprintf(w, "\tSubsystem: %s\n", o.subsystem(p.Vendor, p.Device, sv, sd))
+	}
+	if irq := attr(p, "irq"); irq != "" && irq != "0" {
+		fmt.Fprintf(w, "\tIRQ: %s\n", irq)
+	}
+	if drv, err := os.Readlink(filepath.Join(p.FullPath, "driver")); err == nil {
+		fmt.Fprintf(w, "\tKernel driver in use: %s\n", filepath.Base(drv))
+	}
+	fmt.Fprintln(w)
+}
+
+// selector returns a filter for a -s argument.
+func selector(s string) (pci.Filter, error) {
+	var want [4]int64 // domain, bus, slot, function; -1 matches any
+	for i := range want {
+		want[i] = -1
+	}
+	parse := func(i int, s string) error {
+		if s == "" || s == "*" {
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid selector %q", s)
+		}
+		want[i] = n
+		return nil
+	}
+	dev := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		dev = s[:i]
+		if err := parse(3, s[i+1:]); err != nil {
+			return nil, err
+		}
+	}
+	parts := strings.Split(dev, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid selector %q", s)
+	}
+	for i, part := range parts {
+		if err := parse(3-len(parts)+i, part); err != nil {
+			return nil, err
+		}
+	}
+	return func(p *pci.PCI) bool {
+		var got [4]int64
+		if _, err := fmt.Sscanf(p.Addr, "%x:%x:%x.%x", &got[0], &got[1], &got[2], &got[3]); err != nil {
+			return false
+		}
+		for i := range want {
+			if want[i] >= 0 && want[i] != got[i] {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	ids, err := pci.LoadIDs(*idsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o := &options{domain: *domain, numeric: *numeric, verbose: *verbose, ids: ids}
+	var filters []pci.Filter
+	if *sel != "" {
+		f, err := selector(*sel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		filters = append(filters, f)
+	}
+
+	r, err := pci.NewBusReader()
+	if err != nil {
+		log.Fatal(err)
+	}
+	devs, err := r.Read(filters...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range devs {
+		o.print(os.Stdout, p)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/pci"
+)
+
+const testIDs = `1af4  Red Hat, Inc.
+	1000  Virtio network device
+		1af4 0001  Virtio network device
+8086  Intel Corporation
+C 01  Mass storage controller
+	06  SATA controller
+		01  AHCI 1.0
+C 02  Network controller
+	00  Ethernet controller
+`
+
+// fakeDevice creates a sysfs directory for a device.
+func fakeDevice(t *testing.T, dir, addr string, attrs map[string]string) *pci.PCI {
+	d := filepath.Join(dir, addr)
+	if err := os.MkdirAll(d, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for n, v := range attrs {
+		if n == "driver" {
+			if err := os.Symlink(filepath.Join("../../bus/pci/drivers", v), filepath.Join(d, n)); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, n), []byte(v+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &pci.PCI{
+		Addr:     addr,
+		Vendor:   strings.TrimPrefix(attrs["vendor"], "0x"),
+		Device:   strings.TrimPrefix(attrs["device"], "0x"),
+		FullPath: d,
+	}
+}
+
+func TestPrint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lspci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ids, err := pci.ReadIDs(strings.NewReader(testIDs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devs := pci.Devices{
+		fakeDevice(t, dir, "0000:00:04.0", map[string]string{
+			"vendor":           "0x1af4",
+			"device":           "0x1000",
+			"class":            "0x020000",
+			"revision":         "0x01",
+			"subsystem_vendor": "0x1af4",
+			"subsystem_device": "0x0001",
+			"irq":              "11",
+			"driver":           "virtio-pci",
+		}),
+		fakeDevice(t, dir, "0001:02:1f.2", map[string]string{
+			"vendor":   "0x8086",
+			"device":   "0x2922",
+			"class":    "0x010601",
+			"revision": "0x00",
+		}),
+		fakeDevice(t, dir, "0000:00:05.0", map[string]string{
+			"vendor": "0x1234",
+			"device": "0x1111",
+			"class":  "0x7f0000",
+		}),
+	}
+
+	for _, tt := range []struct {
+		name string
+		o    options
+		want string
+	}{
+		{
+			name: "names",
+			o:    options{ids: ids},
+			want: `00:04.0 Ethernet controller: Red Hat, Inc. Virtio network device (rev 01)
+0001:02:1f.2 SATA controller: Intel Corporation Device 2922
+00:05.0 Class 7f00: Device 1234:1111
+`,
+		},
+		{
+			name: "numeric",
+			o:    options{ids: ids, numeric: 1, domain: true},
+			want: `0000:00:04.0 0200: 1af4:1000 (rev 01)
+0001:02:1f.2 0106: 8086:2922
+0000:00:05.0 7f00: 1234:1111
+`,
+		},
+		{
+			name: "both verbose",
+			o:    options{ids: ids, numeric: 2, verbose: true},
+			want: `00:04.0 Ethernet controller [0200]: Red Hat, Inc. Virtio network device [1af4:1000] (rev 01)
+	Subsystem: Red Hat, Inc. Virtio network device [1af4:0001]
+	IRQ: 11
+	Kernel driver in use: virtio-pci
+
+0001:02:1f.2 SATA controller [0106]: Intel Corporation Device 2922 [8086:2922] (prog-if 01 [AHCI 1.0])
+
+00:05.0 Class 7f00 [7f00]: Device 1234:1111
+
+`,
+		},
+	} {
+		var b bytes.Buffer
+		for _, p := range devs {
+			tt.o.print(&b, p)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+func TestSelector(t *testing.T) {
+	for _, tt := range []struct {
+		sel  string
+		addr string
+		want bool
+	}{
+		{"", "0000:00:1f.2", true},
+		{"1f", "0000:00:1f.2", true},
+		{"1f", "0000:00:1e.2", false},
+		{"00:1f.2", "0000:00:1f.2", true},
+		{"01:", "0000:01:00.0", true},
+		{"01:", "0000:00:01.0", false},
+		{".3", "0000:00:1f.2", false},
+		{"1:*:*.*", "0001:05:00.1", true},
+		{"1::", "0000:05:00.1", false},
+		{"*:1f", "0000:03:1f.7", true},
+	} {
+		f, err := selector(tt.sel)
+		if err != nil {
+			t.Errorf("selector(%q): %v", tt.sel, err)
+			continue
+		}
+		if got := f(&pci.PCI{Addr: tt.addr}); got != tt.want {
+			t.Errorf("selector(%q)(%s) = %v, want %v", tt.sel, tt.addr, got, tt.want)
+		}
+	}
+	for _, s := range []string{"zz", "1:2:3:4", "0.g"} {
+		if _, err := selector(s); err == nil {
+			t.Errorf("selector(%q): got nil, want error", s)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// modinfo prints information about Linux kernel modules.
+//
+// Synopsis:
+//     modinfo [-0] [-F FIELD] [-b BASEDIR] [-k KERNEL] MODULE...
+//
+// Description:
+//     Print the fields of the .modinfo section of each MODULE, which is
+//     either a path to a module file or the name of a module, looked up
+//     in modules.dep. Module parameters are printed with their type.
+//
+// Options:
+//     -0: end each field with a NUL rather than a newline
+//     -F: only print the values of FIELD
+//     -b: root directory for modules
+//     -k: kernel version to look up modules for, rather than the running one
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+var (
+	null    = flag.Bool("0", false, "End each field with a NUL")
+	field   = flag.String("F", "", "Only print the values of `FIELD`")
+	rootDir = flag.String("b", "/", "Root directory for modules")
+	kver    = flag.String("k", "", "Set kernel version instead of using uname")
+)
+
+// fields merges the parm and parmtype fields, which modinfo prints as one,
+// and adds the file name.
+func fields(path string, info []kmodule.Info) []kmodule.Info {
+	types := map[string]string{}
+	described := map[string]bool{}
+	for _, i := range info {
+		kv := strings.SplitN(i.Value, ":", 2)
+		switch {
+		case i.Key == "parmtype" && len(kv) == 2:
+			types[kv[0]] = kv[1]
+		case i.Key == "parm":
+			described[kv[0]] = true
+		}
+	}
+	f := []kmodule.Info{{Key: "filename", Value: path}}
+	for _, i := range info {
+		name := strings.SplitN(i.Value, ":", 2)[0]
+		switch i.Key {
+		case "parmtype":
+			// Parameters without a description are only in parmtype.
+			if !described[name] {
+				f = append(f, kmodule.Info{Key: "parm", Value: fmt.Sprintf("%s:(%s)", name, types[name])})
+			}
+		case "parm":
+			if t, ok := types[name]; ok {
+				i.Value += " (" + t + ")"
+			}
+			f = append(f, i)
+		default:
+			f = append(f, i)
+		}
+	}
+	return f
+}
+
+// printInfo prints the fields, or only the values of one field if name is
+// not empty.
+func printInfo(w io.Writer, info []kmodule.Info, name string, end string) {
+	for _, i := range info {
+		switch {
+		case name == "":
+			fmt.Fprintf(w, "%-16s%s%s", i.Key+":", i.Value, end)
+		case i.Key == name:
+			fmt.Fprintf(w, "%s%s", i.Value, end)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: modinfo [-0] [-F FIELD] [-b BASEDIR] [-k KERNEL] MODULE...")
+	}
+	end := "\n"
+	if *null {
+		end = "\x00"
+	}
+	status := 0
+	for _, m := range flag.Args() {
+		path := m
+		if _, err := os.Stat(m); err != nil {
+			if path, err = kmodule.Path(m, kmodule.ProbeOpts{RootDir: *rootDir, KVer: *kver, IgnoreProcMods: true}); err != nil {
+				log.Printf("%s: %v", m, err)
+				status = 1
+				continue
+			}
+		}
+		info, err := kmodule.FileInfo(path)
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			status = 1
+			continue
+		}
+		printInfo(os.Stdout, fields(path, info), *field, end)
+	}
+	os.Exit(status)
+}
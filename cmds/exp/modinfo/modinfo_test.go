@@ -0,0 +1,46 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+func TestPrintInfo(t *testing.T) {
+	info := []kmodule.Info{
+		{Key: "parmtype", Value: "debug:int"},
+		{Key: "license", Value: "GPL"},
+		{Key: "parm", Value: "debug:Enable debugging"},
+		{Key: "parmtype", Value: "quiet:bool"},
+		{Key: "depends", Value: "mii"},
+		{Key: "vermagic", Value: "5.10.0 SMP mod_unload "},
+	}
+	f := fields("/lib/modules/5.10.0/e1000.ko", info)
+	for _, tt := range []struct {
+		field, end, want string
+	}{
+		{
+			end: "\n",
+			want: "filename:       /lib/modules/5.10.0/e1000.ko\n" +
+				"license:        GPL\n" +
+				"parm:           debug:Enable debugging (int)\n" +
+				"parm:           quiet:(bool)\n" +
+				"depends:        mii\n" +
+				"vermagic:       5.10.0 SMP mod_unload \n",
+		},
+		{field: "parm", end: "\n", want: "debug:Enable debugging (int)\nquiet:(bool)\n"},
+		{field: "vermagic", end: "\x00", want: "5.10.0 SMP mod_unload \x00"},
+		{field: "author", end: "\n", want: ""},
+	} {
+		var b bytes.Buffer
+		printInfo(&b, f, tt.field, tt.end)
+		if b.String() != tt.want {
+			t.Errorf("printInfo(%q): got %q, want %q", tt.field, b.String(), tt.want)
+		}
+	}
+}
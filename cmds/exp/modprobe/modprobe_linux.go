@@ -5,8 +5,25 @@
 // modprobe - Add and remove modules from the Linux Kernel
 //
 // Synopsis:
-//     modprobe [-n] modulename [parameters...]
-//     modprobe [-n] -a modulename...
+//     modprobe [-bn] modulename [parameters...]
+//     modprobe [-bn] -a modulename...
+//
+// Description:
+//     modprobe loads a module and the modules it depends on, as listed in
+//     modules.dep. modulename may also be an alias from modules.alias or
+//     modprobe.d, e.g. a device's modalias such as pci:v00008086d...
+//
+//     Parameters are taken from "options" lines in modprobe.d, then from
+//     modulename.param=value on the kernel command line, then from the
+//     command line. Modules blacklisted in modprobe.d or with
+//     modprobe.blacklist= are not loaded through aliases.
+//
+// Options:
+//     -a: insert all module names on the command line
+//     -b: also apply the blacklist to modules given by name
+//     -d: root directory for modules and modprobe.d
+//     -n: dry run, print the modules that would be loaded
+//     -S: kernel version instead of the running one
 //
 // Author:
 //     Roland Kammerer <dev.rck@gmail.com>
@@ -21,7 +38,7 @@ import (
 	"github.com/u-root/u-root/pkg/kmodule"
 )
 
-const cmd = "modprobe [-an] modulename[s] [parameters...]"
+const cmd = "modprobe [-abn] modulename[s] [parameters...]"
 
 var (
 	dryRun     = flag.Bool("n", false, "Dry run")
@@ -29,6 +46,7 @@ var (
 	verboseAll = flag.Bool("va", false, "Insert all module names on the command line.")
 	rootDir    = flag.String("d", "/", "Root directory for modules")
 	kernelVer  = flag.String("S", "", "Set kernel version instead of using uname")
+	blacklist  = flag.Bool("b", false, "Apply the blacklist to module names, not only aliases")
 )
 
 func init() {
@@ -49,8 +67,9 @@ func main() {
 	}
 
 	opts := kmodule.ProbeOpts{
-		RootDir: *rootDir,
-		KVer:    *kernelVer,
+		RootDir:      *rootDir,
+		KVer:         *kernelVer,
+		UseBlacklist: *blacklist,
 	}
 	if *dryRun {
 		log.Println("Unique dependencies in load order, already loaded ones get skipped:")
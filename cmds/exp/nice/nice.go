@@ -0,0 +1,110 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nice runs a program with a modified scheduling priority.
+//
+// Synopsis:
+//     nice [-n ADJUSTMENT] [COMMAND [ARG...]]
+//     nice -ADJUSTMENT COMMAND [ARG...]
+//
+// Description:
+//     Run COMMAND with its niceness increased by ADJUSTMENT, 10 by default.
+//     Nicenesses range from -20, the highest priority, to 19, the lowest,
+//     and only root may lower them. Without COMMAND, print the niceness.
+//
+// Options:
+//     -n: add ADJUSTMENT to the niceness
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var adjustment = flag.Int("n", 10, "Add `ADJUSTMENT` to the niceness")
+
+// Bounds of the niceness.
+const (
+	minNice = -20
+	maxNice = 19
+)
+
+// getNice returns the niceness of the calling process. The raw system call
+// returns 20 - niceness, so that it is never negative.
+func getNice() (int, error) {
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0, err
+	}
+	return 20 - prio, nil
+}
+
+// clamp limits n to the range of nicenesses.
+func clamp(n int) int {
+	if n < minNice {
+		return minNice
+	}
+	if n > maxNice {
+		return maxNice
+	}
+	return n
+}
+
+// legacyArgs converts the obsolete -ADJUSTMENT form to -n ADJUSTMENT.
+func legacyArgs(args []string) []string {
+	if len(args) == 0 || len(args[0]) < 2 || args[0][0] != '-' {
+		return args
+	}
+	if _, err := strconv.Atoi(args[0][1:]); err != nil {
+		return args
+	}
+	return append([]string{"-n", args[0][1:]}, args[1:]...)
+}
+
+func run(args []string, stdout io.Writer, adjusted bool) error {
+	cur, err := getNice()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		if adjusted {
+			return fmt.Errorf("a command must be given with an adjustment")
+		}
+		fmt.Fprintln(stdout, cur)
+		return nil
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	if err := unix.Setpriority(unix.PRIO_PROCESS, 0, clamp(cur+*adjustment)); err != nil {
+		if err == unix.EACCES || err == unix.EPERM {
+			// Like GNU nice, run the command anyway.
+			log.Printf("cannot set niceness: %v", err)
+		} else {
+			return fmt.Errorf("cannot set niceness: %v", err)
+		}
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("nice: ")
+	flag.CommandLine.Parse(legacyArgs(os.Args[1:]))
+	adjusted := false
+	flag.Visit(func(f *flag.Flag) { adjusted = adjusted || f.Name == "n" })
+	if err := run(flag.Args(), os.Stdout, adjusted); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLegacyArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in   []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"ls"}, []string{"ls"}},
+		{[]string{"-5", "ls", "-l"}, []string{"-n", "5", "ls", "-l"}},
+		{[]string{"--5", "ls"}, []string{"-n", "-5", "ls"}},
+		{[]string{"-n", "5", "ls"}, []string{"-n", "5", "ls"}},
+		{[]string{"-", "ls"}, []string{"-", "ls"}},
+	} {
+		if got := legacyArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("legacyArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	for in, want := range map[int]int{-30: -20, -20: -20, 0: 0, 19: 19, 25: 19} {
+		if got := clamp(in); got != want {
+			t.Errorf("clamp(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nohup runs a command immune to hangups.
+//
+// Synopsis:
+//     nohup COMMAND [ARG...]
+//
+// Description:
+//     Run COMMAND with SIGHUP ignored, so that it keeps running when the
+//     terminal goes away. If stdin is a terminal, it is replaced by
+//     /dev/null. If stdout is a terminal, output is appended to nohup.out,
+//     or $HOME/nohup.out if nohup.out cannot be written. If stderr is a
+//     terminal, it goes where stdout goes.
+//
+//     The exit status is 127 if COMMAND is not found and 126 if it cannot
+//     be run; otherwise, nohup becomes COMMAND.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+// Exit statuses of POSIX nohup.
+const (
+	exitCannotRun = 126
+	exitNotFound  = 127
+)
+
+// outName is the file output is appended to.
+const outName = "nohup.out"
+
+func isTerminal(fd int) bool {
+	_, err := termios.GetTermios(uintptr(fd))
+	return err == nil
+}
+
+// openOutput opens outName in the current directory, or in $HOME if that
+// fails.
+func openOutput() (*os.File, string, error) {
+	f, err := os.OpenFile(outName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err == nil {
+		return f, outName, nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return nil, "", err
+	}
+	name := filepath.Join(home, outName)
+	f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, name, nil
+}
+
+// redirect replaces the terminals on stdin, stdout and stderr, and returns
+// a message saying what it did, or "" if nothing.
+func redirect() (string, error) {
+	var msg string
+	if isTerminal(0) {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if err := unix.Dup2(int(f.Fd()), 0); err != nil {
+			return "", err
+		}
+		msg = "ignoring input"
+	}
+
+	outIsTerminal := isTerminal(1)
+	if outIsTerminal {
+		f, name, err := openOutput()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %q: %v", outName, err)
+		}
+		defer f.Close()
+		if err := unix.Dup2(int(f.Fd()), 1); err != nil {
+			return "", err
+		}
+		if msg != "" {
+			msg += " and "
+		}
+		msg += fmt.Sprintf("appending output to %q", name)
+	}
+
+	if isTerminal(2) {
+		if err := unix.Dup2(1, 2); err != nil {
+			return "", err
+		}
+		if !outIsTerminal {
+			if msg != "" {
+				msg += " and "
+			}
+			msg += "redirecting stderr to stdout"
+		}
+	}
+	return msg, nil
+}
+
+func run(args []string) (int, error) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return exitNotFound, fmt.Errorf("usage: nohup COMMAND [ARG...]")
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return exitNotFound, err
+	}
+	// Write the message before stderr is redirected.
+	stderr, err := syscall.Dup(2)
+	if err != nil {
+		return exitCannotRun, err
+	}
+	msg, err := redirect()
+	if err != nil {
+		return exitCannotRun, err
+	}
+	f := os.NewFile(uintptr(stderr), "stderr")
+	if msg != "" {
+		fmt.Fprintf(f, "nohup: %s\n", msg)
+	}
+	f.Close()
+
+	// An ignored signal stays ignored across exec.
+	signal.Ignore(syscall.SIGHUP)
+	return exitCannotRun, syscall.Exec(path, args, os.Environ())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("nohup: ")
+	status, err := run(os.Args[1:])
+	log.Print(err)
+	os.Exit(status)
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nohup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+
+	home := filepath.Join(dir, "home")
+	if err := os.Mkdir(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("HOME", home)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	f, name, err := openOutput()
+	if err != nil || name != outName {
+		t.Fatalf("openOutput() = %q, %v, want %q", name, err, outName)
+	}
+	f.Close()
+
+	// A nohup.out directory cannot be written, so $HOME/nohup.out is used.
+	if err := os.Remove(outName); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(outName, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, name, err = openOutput()
+	if want := filepath.Join(home, outName); err != nil || name != want {
+		t.Fatalf("openOutput() = %q, %v, want %q", name, err, want)
+	}
+	f.Close()
+
+	os.Setenv("HOME", "")
+	if _, _, err := openOutput(); err == nil {
+		t.Error("openOutput() without $HOME = nil, want error")
+	}
+}
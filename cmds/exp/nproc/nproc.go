@@ -0,0 +1,141 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nproc prints the number of processing units available.
+//
+// Synopsis:
+//     nproc [--all] [--ignore N]
+//
+// Description:
+//     Print the number of processors the current process may run on,
+//     which is less than the number online if its CPU affinity is
+//     restricted. Like GNU nproc, the OMP_NUM_THREADS and
+//     OMP_THREAD_LIMIT environment variables override and limit the
+//     result.
+//
+// Options:
+//     --all:    print the number of configured processors, ignoring
+//               affinity and the environment
+//     --ignore: exclude N processors, leaving at least 1
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	all    = flag.Bool("all", false, "print the number of configured processors")
+	ignore = flag.Int("ignore", 0, "exclude `N` processors if possible")
+
+	// sysCPU is where processors are looked up.
+	sysCPU = "/sys/devices/system/cpu"
+)
+
+// configured returns the number of processors the kernel knows of,
+// whether online or not.
+func configured() int {
+	m, err := filepath.Glob(filepath.Join(sysCPU, "cpu[0-9]*"))
+	if err != nil || len(m) == 0 {
+		return runtime.NumCPU()
+	}
+	return len(m)
+}
+
+// parseCPUList returns the number of processors in a list like "0-3,6".
+func parseCPUList(s string) (int, error) {
+	n := 0
+	for _, r := range strings.Split(strings.TrimSpace(s), ",") {
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		l, err := strconv.Atoi(lo)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU list %q", s)
+		}
+		h, err := strconv.Atoi(hi)
+		if err != nil || h < l {
+			return 0, fmt.Errorf("invalid CPU list %q", s)
+		}
+		n += h - l + 1
+	}
+	return n, nil
+}
+
+// online returns the number of online processors.
+func online() int {
+	b, err := ioutil.ReadFile(filepath.Join(sysCPU, "online"))
+	if err != nil {
+		return configured()
+	}
+	n, err := parseCPUList(string(b))
+	if err != nil {
+		return configured()
+	}
+	return n
+}
+
+// available returns the number of processors this process may run on.
+func available() int {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil || set.Count() == 0 {
+		return online()
+	}
+	return set.Count()
+}
+
+// ompValue returns the first value of an OpenMP variable, which may be a
+// comma separated list, or 0 if it is not a positive number.
+func ompValue(s string) int {
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// nproc returns the number of processors to print. getenv looks up the
+// OpenMP variables.
+func nproc(all bool, ignore int, getenv func(string) string) int {
+	var n int
+	if all {
+		n = configured()
+	} else {
+		n = ompValue(getenv("OMP_NUM_THREADS"))
+		if n == 0 {
+			n = available()
+		}
+		if limit := ompValue(getenv("OMP_THREAD_LIMIT")); limit > 0 && n > limit {
+			n = limit
+		}
+	}
+	if ignore > 0 {
+		n -= ignore
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 0 {
+		log.Fatalf("usage: nproc [--all] [--ignore N]")
+	}
+	fmt.Println(nproc(*all, *ignore, os.Getenv))
+}
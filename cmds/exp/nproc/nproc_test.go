@@ -0,0 +1,97 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{in: "0\n", want: 1},
+		{in: "0-3", want: 4},
+		{in: "0-3,6,8-9\n", want: 7},
+		{in: "", err: true},
+		{in: "3-1", err: true},
+		{in: "a-b", err: true},
+	} {
+		got, err := parseCPUList(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseCPUList(%q) = (%d, %v), want (%d, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNproc(t *testing.T) {
+	d, err := ioutil.TempDir("", "nproc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	for _, c := range []string{"cpu0", "cpu1", "cpu2", "cpu3", "cpufreq"} {
+		if err := os.Mkdir(filepath.Join(d, c), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(d, "online"), []byte("0-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sysCPU = d
+	defer func() { sysCPU = "/sys/devices/system/cpu" }()
+
+	if got := online(); got != 2 {
+		t.Errorf("online() = %d, want 2", got)
+	}
+	avail := available()
+	for _, tt := range []struct {
+		all    bool
+		ignore int
+		env    map[string]string
+		want   int
+	}{
+		{all: true, want: 4},
+		{all: true, ignore: 1, want: 3},
+		{all: true, ignore: 10, want: 1},
+		{all: true, env: map[string]string{"OMP_NUM_THREADS": "16"}, want: 4},
+		{want: avail},
+		{env: map[string]string{"OMP_NUM_THREADS": "16"}, want: 16},
+		{env: map[string]string{"OMP_NUM_THREADS": "16,2"}, ignore: 2, want: 14},
+		{env: map[string]string{"OMP_NUM_THREADS": "16", "OMP_THREAD_LIMIT": "5"}, want: 5},
+		{env: map[string]string{"OMP_NUM_THREADS": "junk"}, want: avail},
+	} {
+		getenv := func(k string) string { return tt.env[k] }
+		if got := nproc(tt.all, tt.ignore, getenv); got != tt.want {
+			t.Errorf("nproc(%v, %d, %v) = %d, want %d", tt.all, tt.ignore, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestNprocCommand(t *testing.T) {
+	c := testutil.Command(t)
+	c.Env = append(c.Env, "OMP_NUM_THREADS=3")
+	out, err := c.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "3\n" {
+		t.Errorf("nproc: got %q, want %q", out, "3\n")
+	}
+	if err := testutil.Command(t, "extra").Run(); err == nil {
+		t.Errorf("nproc extra: got nil, want error")
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nsenter runs a command in the namespaces of another process.
+//
+// Synopsis:
+//     nsenter [-t PID] [-a] [-CimnpuU] [--NAMESPACE=FILE]... [COMMAND [ARG...]]
+//
+// Description:
+//     nsenter enters the namespaces of process PID that are given as
+//     options, or of the files given to them, such as a bind mount of
+//     /proc/PID/ns/net, and runs COMMAND in them, or $SHELL, or /bin/sh.
+//     The namespaces are entered with setns(2), and COMMAND is run as a
+//     child, so that it is also in a pid namespace that was entered.
+//
+//     A process can only enter a user namespace when it has just one
+//     thread, which a Go program never has, so -U fails.
+//
+// Options:
+//     -a, --all:    enter all namespaces of PID that differ from ours
+//     -t, --target: enter the namespaces of PID
+//     -C, --cgroup: enter the cgroup namespace
+//     -i, --ipc:    enter the IPC namespace
+//     -m, --mount:  enter the mount namespace
+//     -n, --net:    enter the network namespace
+//     -p, --pid:    enter the pid namespace
+//     -u, --uts:    enter the UTS namespace
+//     -U, --user:   enter the user namespace
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	all    = flag.BoolP("all", "a", false, "enter all namespaces of PID that differ from ours")
+	target = flag.IntP("target", "t", 0, "enter the namespaces of PID")
+)
+
+// namespace is a kind of namespace, in the order they are entered. The
+// user namespace comes first, as it may grant the rights to enter the
+// others, and the mount namespace last, as it changes where files are.
+type namespace struct {
+	long, short string
+	// file is its name in /proc/PID/ns.
+	file   string
+	nstype int
+	// path is the value of the option; the FILE given to it, or
+	// /proc/PID/ns/file if none was.
+	path *string
+}
+
+var namespaces = []namespace{
+	{long: "user", short: "U", file: "user", nstype: unix.CLONE_NEWUSER},
+	{long: "cgroup", short: "C", file: "cgroup", nstype: unix.CLONE_NEWCGROUP},
+	{long: "ipc", short: "i", file: "ipc", nstype: unix.CLONE_NEWIPC},
+	{long: "uts", short: "u", file: "uts", nstype: unix.CLONE_NEWUTS},
+	{long: "net", short: "n", file: "net", nstype: unix.CLONE_NEWNET},
+	{long: "pid", short: "p", file: "pid", nstype: unix.CLONE_NEWPID},
+	{long: "mount", short: "m", file: "mnt", nstype: unix.CLONE_NEWNS},
+}
+
+func init() {
+	for i := range namespaces {
+		ns := &namespaces[i]
+		ns.path = flag.StringP(ns.long, ns.short, "", fmt.Sprintf("enter the %s namespace, of PID or of FILE", ns.long))
+		flag.Lookup(ns.long).NoOptDefVal = ns.procPath("PID")
+	}
+}
+
+// procPath returns the file of the namespace of process pid in /proc.
+func (ns namespace) procPath(pid string) string {
+	return filepath.Join("/proc", pid, "ns", ns.file)
+}
+
+// nsFile is a namespace file to enter.
+type nsFile struct {
+	path   string
+	nstype int
+}
+
+// sameFile returns whether two files are the same, and so two namespace
+// files are of the same namespace.
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	return err == nil && os.SameFile(fa, fb)
+}
+
+// files returns the namespace files to enter. pid is 0 if there is no
+// target. Without a FILE, an option enters the namespace of pid. With all,
+// the namespaces of pid that differ from ours are entered too.
+func files(nss []namespace, pid int, all bool) ([]nsFile, error) {
+	if all && pid == 0 {
+		return nil, errors.New("--all needs a target PID")
+	}
+	var fs []nsFile
+	for _, ns := range nss {
+		path := *ns.path
+		switch {
+		case path == ns.procPath("PID"):
+			if pid == 0 {
+				return nil, fmt.Errorf("--%s needs a FILE or a target PID", ns.long)
+			}
+			path = ns.procPath(fmt.Sprint(pid))
+		case path == "" && all:
+			path = ns.procPath(fmt.Sprint(pid))
+			if sameFile(path, ns.procPath("self")) {
+				continue
+			}
+		case path == "":
+			continue
+		}
+		fs = append(fs, nsFile{path: path, nstype: ns.nstype})
+	}
+	return fs, nil
+}
+
+// enter enters the namespaces of fs on this thread, which must be locked
+// to the calling goroutine. They are all opened first, as entering the
+// mount namespace changes where the files are.
+func enter(fs []nsFile) error {
+	var fds []int
+	defer func() {
+		for _, fd := range fds {
+			unix.Close(fd)
+		}
+	}()
+	for _, f := range fs {
+		fd, err := unix.Open(f.path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: f.path, Err: err}
+		}
+		fds = append(fds, fd)
+	}
+	for i, f := range fs {
+		if f.nstype == unix.CLONE_NEWNS {
+			// The threads of a process share their root and working
+			// directories, unless they are unshared, and a thread that
+			// shares them may not change its mount namespace.
+			if err := unix.Unshare(unix.CLONE_FS); err != nil {
+				return fmt.Errorf("unshare: %v", err)
+			}
+		}
+		if err := unix.Setns(fds[i], f.nstype); err != nil {
+			if f.nstype == unix.CLONE_NEWUSER && err == unix.EINVAL {
+				return fmt.Errorf("setns %s: a process with several threads cannot enter a user namespace", f.path)
+			}
+			return fmt.Errorf("setns %s: %v", f.path, err)
+		}
+	}
+	return nil
+}
+
+// run runs args in the namespaces of fs and returns its exit status.
+func run(fs []nsFile, args []string) (int, error) {
+	if len(args) == 0 {
+		sh := os.Getenv("SHELL")
+		if sh == "" {
+			sh = "/bin/sh"
+		}
+		args = []string{sh}
+	}
+
+	// The namespaces are entered by this thread only, which must also be
+	// the one to start the command. It is not unlocked, so that the Go
+	// runtime does not use it for anything else.
+	runtime.LockOSThread()
+	if err := enter(fs); err != nil {
+		return 1, err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				return 128 + int(ws.Signal()), nil
+			}
+			return ee.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("nsenter: ")
+	// Options after COMMAND are its own.
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+
+	fs, err := files(namespaces, *target, *all)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := run(fs, flag.Args())
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(code)
+}
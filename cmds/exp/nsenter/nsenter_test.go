@@ -0,0 +1,117 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testNamespaces returns the namespaces with options set to paths.
+func testNamespaces(paths map[string]string) []namespace {
+	var nss []namespace
+	for _, ns := range namespaces {
+		p := paths[ns.long]
+		if p == "target" {
+			p = ns.procPath("PID")
+		}
+		ns.path = &p
+		nss = append(nss, ns)
+	}
+	return nss
+}
+
+func TestFiles(t *testing.T) {
+	self := os.Getpid()
+	for _, tt := range []struct {
+		name  string
+		paths map[string]string
+		pid   int
+		all   bool
+		want  []nsFile
+		ok    bool
+	}{
+		{
+			name:  "target",
+			paths: map[string]string{"mount": "target", "net": "target", "user": "target"},
+			pid:   42,
+			want: []nsFile{
+				{"/proc/42/ns/user", unix.CLONE_NEWUSER},
+				{"/proc/42/ns/net", unix.CLONE_NEWNET},
+				{"/proc/42/ns/mnt", unix.CLONE_NEWNS},
+			},
+			ok: true,
+		},
+		{
+			name:  "files",
+			paths: map[string]string{"uts": "/run/ns/uts", "pid": "target"},
+			pid:   42,
+			want: []nsFile{
+				{"/run/ns/uts", unix.CLONE_NEWUTS},
+				{"/proc/42/ns/pid", unix.CLONE_NEWPID},
+			},
+			ok: true,
+		},
+		{
+			name:  "files without target",
+			paths: map[string]string{"ipc": "/run/ns/ipc"},
+			want:  []nsFile{{"/run/ns/ipc", unix.CLONE_NEWIPC}},
+			ok:    true,
+		},
+		{
+			name:  "all of ourselves",
+			paths: map[string]string{"net": "/run/ns/net"},
+			pid:   self,
+			all:   true,
+			want:  []nsFile{{"/run/ns/net", unix.CLONE_NEWNET}},
+			ok:    true,
+		},
+		{
+			name:  "no target",
+			paths: map[string]string{"uts": "target"},
+		},
+		{
+			name: "all without target",
+			all:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := files(testNamespaces(tt.paths), tt.pid, tt.all)
+			if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("entering namespaces needs CAP_SYS_ADMIN")
+	}
+	// Our own namespaces can be entered again.
+	fs := []nsFile{
+		{fmt.Sprintf("/proc/%d/ns/uts", os.Getpid()), unix.CLONE_NEWUTS},
+		{"/proc/self/ns/mnt", unix.CLONE_NEWNS},
+	}
+	for _, tt := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"sh", "-c", "exit 0"}, 0},
+		{[]string{"sh", "-c", "exit 3"}, 3},
+		{[]string{"sh", "-c", "kill -9 $$"}, 128 + 9},
+	} {
+		if code, err := run(fs, tt.args); code != tt.code || err != nil {
+			t.Errorf("run(%q) = %d, %v, want %d", tt.args, code, err, tt.code)
+		}
+	}
+	if code, err := run([]nsFile{{"/proc/self/ns/nonexistent", unix.CLONE_NEWNET}}, []string{"true"}); code != 1 || err == nil {
+		t.Errorf("run() with a missing file = %d, %v, want 1 and an error", code, err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// nvme lists NVMe devices and prints their identify data and health.
+//
+// Synopsis:
+//     nvme list
+//     nvme id-ctrl DEVICE
+//     nvme id-ns [-n NSID] DEVICE
+//     nvme smart-log DEVICE
+//
+// Description:
+//     list:      list all NVMe namespaces with their controller's model,
+//                serial number and firmware, their usage and LBA format
+//     id-ctrl:   print the controller's Identify Controller data
+//     id-ns:     print a namespace's Identify Namespace data. The
+//                namespace is NSID, or the one DEVICE refers to, e.g.
+//                /dev/nvme0n1
+//     smart-log: print the controller's SMART / Health Information log,
+//                with the critical warnings and the temperature
+//
+//     DEVICE is a controller, e.g. /dev/nvme0, or a namespace, e.g.
+//     /dev/nvme0n1.
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sizes of the data structures returned by the admin commands.
+const (
+	identifySize = 4096
+	smartLogSize = 512
+)
+
+// controller is the part of the Identify Controller data structure that
+// nvme prints.
+type controller struct {
+	vendorID          uint16
+	subsystemVendorID uint16
+	serial            string
+	model             string
+	firmware          string
+	version           uint32
+	// totalCapacity is in bytes. It is 0 if the controller does not
+	// support namespace management.
+	totalCapacity uint64
+	namespaces    uint32
+}
+
+// lbaFormat is an LBA format of a namespace.
+type lbaFormat struct {
+	metadataSize uint16
+	// dataSize is in bytes.
+	dataSize uint64
+	// relativePerformance is 0 for the best performance up to 3 for the
+	// worst.
+	relativePerformance uint8
+}
+
+// namespace is the part of the Identify Namespace data structure that
+// nvme prints. Sizes are in logical blocks.
+type namespace struct {
+	size        uint64
+	capacity    uint64
+	utilization uint64
+	formats     []lbaFormat
+	format      int
+}
+
+// smartLog is the SMART / Health Information log page. Counters are 128
+// bit in the log; only the low 64 bits are kept.
+type smartLog struct {
+	criticalWarning uint8
+	// temperature is in Kelvin.
+	temperature      uint16
+	availableSpare   uint8
+	spareThreshold   uint8
+	percentUsed      uint8
+	dataUnitsRead    uint64
+	dataUnitsWritten uint64
+	powerCycles      uint64
+	powerOnHours     uint64
+	unsafeShutdowns  uint64
+	mediaErrors      uint64
+	errorLogEntries  uint64
+}
+
+// idString trims the space padding of an identify string.
+func idString(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
+
+func parseController(b []byte) (*controller, error) {
+	if len(b) < identifySize {
+		return nil, fmt.Errorf("identify controller data is %d bytes, want %d", len(b), identifySize)
+	}
+	le := binary.LittleEndian
+	return &controller{
+		vendorID:          le.Uint16(b[0:2]),
+		subsystemVendorID: le.Uint16(b[2:4]),
+		serial:            idString(b[4:24]),
+		model:             idString(b[24:64]),
+		firmware:          idString(b[64:72]),
+		version:           le.Uint32(b[80:84]),
+		totalCapacity:     le.Uint64(b[280:288]),
+		namespaces:        le.Uint32(b[516:520]),
+	}, nil
+}
+
+func parseNamespace(b []byte) (*namespace, error) {
+	if len(b) < identifySize {
+		return nil, fmt.Errorf("identify namespace data is %d bytes, want %d", len(b), identifySize)
+	}
+	le := binary.LittleEndian
+	ns := &namespace{
+		size:        le.Uint64(b[0:8]),
+		capacity:    le.Uint64(b[8:16]),
+		utilization: le.Uint64(b[16:24]),
+		format:      int(b[26] & 0xf),
+	}
+	// The number of formats is 0's based.
+	for i := 0; i <= int(b[25]) && i < 16; i++ {
+		f := b[128+4*i:]
+		ns.formats = append(ns.formats, lbaFormat{
+			metadataSize:        le.Uint16(f[0:2]),
+			dataSize:            1 << f[2],
+			relativePerformance: f[3] & 3,
+		})
+	}
+	if ns.format >= len(ns.formats) {
+		return nil, fmt.Errorf("formatted LBA size %d is not one of the %d formats", ns.format, len(ns.formats))
+	}
+	return ns, nil
+}
+
+// blockSize returns the size of the namespace's logical blocks.
+func (ns *namespace) blockSize() uint64 {
+	return ns.formats[ns.format].dataSize
+}
+
+func parseSMARTLog(b []byte) (*smartLog, error) {
+	if len(b) < smartLogSize {
+		return nil, fmt.Errorf("SMART log is %d bytes, want %d", len(b), smartLogSize)
+	}
+	le := binary.LittleEndian
+	return &smartLog{
+		criticalWarning:  b[0],
+		temperature:      le.Uint16(b[1:3]),
+		availableSpare:   b[3],
+		spareThreshold:   b[4],
+		percentUsed:      b[5],
+		dataUnitsRead:    le.Uint64(b[32:40]),
+		dataUnitsWritten: le.Uint64(b[48:56]),
+		powerCycles:      le.Uint64(b[112:120]),
+		powerOnHours:     le.Uint64(b[128:136]),
+		unsafeShutdowns:  le.Uint64(b[144:152]),
+		mediaErrors:      le.Uint64(b[160:168]),
+		errorLogEntries:  le.Uint64(b[176:184]),
+	}, nil
+}
+
+// criticalWarnings are the bits of the critical warning field.
+var criticalWarnings = []string{
+	"available spare is below threshold",
+	"temperature is out of range",
+	"reliability is degraded",
+	"media is read only",
+	"volatile memory backup failed",
+	"persistent memory region is read only",
+}
+
+// warnings returns the critical warnings that are set.
+func (l *smartLog) warnings() []string {
+	var w []string
+	for i, s := range criticalWarnings {
+		if l.criticalWarning&(1<<uint(i)) != 0 {
+			w = append(w, s)
+		}
+	}
+	return w
+}
+
+// versionString formats an NVMe version, which is 0 before NVMe 1.2.
+func versionString(v uint32) string {
+	if v == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", v>>16, v>>8&0xff, v&0xff)
+}
+
+// decimal formats a size with decimal units, like drive vendors do.
+func decimal(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1000 {
+		return fmt.Sprintf("%d B", n)
+	}
+	f := float64(n)
+	i := -1
+	for f >= 1000 && i < len(units)-1 {
+		f /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.2f %cB", f, units[i])
+}
+
+// listEntry is a namespace in nvme list.
+type listEntry struct {
+	node string
+	nsid uint32
+	ctrl *controller
+	ns   *namespace
+}
+
+func printList(w io.Writer, entries []listEntry) {
+	const format = "%-16s %-20s %-40s %-9s %-26s %-16s %s\n"
+	fmt.Fprintf(w, format, "Node", "SN", "Model", "Namespace", "Usage", "Format", "FW Rev")
+	fmt.Fprintf(w, format, strings.Repeat("-", 16), strings.Repeat("-", 20), strings.Repeat("-", 40),
+		strings.Repeat("-", 9), strings.Repeat("-", 26), strings.Repeat("-", 16), strings.Repeat("-", 8))
+	for _, e := range entries {
+		bs := e.ns.blockSize()
+		usage := fmt.Sprintf("%s / %s", decimal(e.ns.utilization*bs), decimal(e.ns.size*bs))
+		lbaf := fmt.Sprintf("%d B + %d B", bs, e.ns.formats[e.ns.format].metadataSize)
+		fmt.Fprintf(w, format, e.node, e.ctrl.serial, e.ctrl.model, fmt.Sprint(e.nsid), usage, lbaf, e.ctrl.firmware)
+	}
+}
+
+func printController(w io.Writer, c *controller) {
+	fmt.Fprintf(w, "vid       : %#04x\n", c.vendorID)
+	fmt.Fprintf(w, "ssvid     : %#04x\n", c.subsystemVendorID)
+	fmt.Fprintf(w, "sn        : %s\n", c.serial)
+	fmt.Fprintf(w, "mn        : %s\n", c.model)
+	fmt.Fprintf(w, "fr        : %s\n", c.firmware)
+	fmt.Fprintf(w, "ver       : %s\n", versionString(c.version))
+	fmt.Fprintf(w, "tnvmcap   : %d\n", c.totalCapacity)
+	fmt.Fprintf(w, "nn        : %d\n", c.namespaces)
+}
+
+func printNamespace(w io.Writer, nsid uint32, ns *namespace) {
+	fmt.Fprintf(w, "NVME Identify Namespace %d:\n", nsid)
+	fmt.Fprintf(w, "nsze    : %#x\n", ns.size)
+	fmt.Fprintf(w, "ncap    : %#x\n", ns.capacity)
+	fmt.Fprintf(w, "nuse    : %#x\n", ns.utilization)
+	fmt.Fprintf(w, "nlbaf   : %d\n", len(ns.formats)-1)
+	fmt.Fprintf(w, "flbas   : %#x\n", ns.format)
+	for i, f := range ns.formats {
+		inUse := ""
+		if i == ns.format {
+			inUse = " (in use)"
+		}
+		fmt.Fprintf(w, "lbaf %2d : ms:%-3d lbads:%-2d rp:%#x%s\n", i, f.metadataSize, log2(f.dataSize), f.relativePerformance, inUse)
+	}
+}
+
+func log2(n uint64) int {
+	i := 0
+	for n > 1 {
+		n >>= 1
+		i++
+	}
+	return i
+}
+
+func printSMARTLog(w io.Writer, l *smartLog) {
+	p := func(name, format string, args ...interface{}) {
+		fmt.Fprintf(w, "%-36s: %s\n", name, fmt.Sprintf(format, args...))
+	}
+	p("critical_warning", "%#x", l.criticalWarning)
+	for _, s := range l.warnings() {
+		fmt.Fprintf(w, "  %s\n", s)
+	}
+	p("temperature", "%d C", int(l.temperature)-273)
+	p("available_spare", "%d%%", l.availableSpare)
+	p("available_spare_threshold", "%d%%", l.spareThreshold)
+	p("percentage_used", "%d%%", l.percentUsed)
+	// Data units are thousands of 512 byte blocks.
+	p("data_units_read", "%d (%s)", l.dataUnitsRead, decimal(l.dataUnitsRead*512000))
+	p("data_units_written", "%d (%s)", l.dataUnitsWritten, decimal(l.dataUnitsWritten*512000))
+	p("power_cycles", "%d", l.powerCycles)
+	p("power_on_hours", "%d", l.powerOnHours)
+	p("unsafe_shutdowns", "%d", l.unsafeShutdowns)
+	p("media_errors", "%d", l.mediaErrors)
+	p("num_err_log_entries", "%d", l.errorLogEntries)
+}
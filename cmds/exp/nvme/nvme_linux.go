@@ -0,0 +1,292 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioctls from linux/nvme_ioctl.h.
+const (
+	nvmeIoctlID       = 0x4e40     // _IO('N', 0x40)
+	nvmeIoctlAdminCmd = 0xc0484e41 // _IOWR('N', 0x41, struct nvme_admin_cmd)
+)
+
+// Admin commands and their arguments.
+const (
+	adminGetLogPage = 0x02
+	adminIdentify   = 0x06
+
+	cnsNamespace  = 0x00
+	cnsController = 0x01
+
+	logSMART = 0x02
+
+	// nsidAll is the namespace ID for the controller as a whole.
+	nsidAll = 0xffffffff
+)
+
+// passthruCmd is struct nvme_passthru_cmd.
+type passthruCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+var (
+	// devDir is a variable so it can be changed in tests.
+	devDir = "/dev"
+
+	nsRE = regexp.MustCompile(`^nvme[0-9]+n([0-9]+)$`)
+)
+
+// adminCmd runs cmd on f, reading into data.
+func adminCmd(f *os.File, cmd *passthruCmd, data []byte) error {
+	cmd.addr = uint64(uintptr(unsafe.Pointer(&data[0])))
+	cmd.dataLen = uint32(len(data))
+	status, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(cmd)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return &os.PathError{Op: "ioctl NVME_IOCTL_ADMIN_CMD", Path: f.Name(), Err: errno}
+	}
+	// A positive return value is the NVMe status of the command.
+	if status != 0 {
+		return fmt.Errorf("%s: admin command %#x failed with NVMe status %#x", f.Name(), cmd.opcode, status)
+	}
+	return nil
+}
+
+func identify(f *os.File, cns, nsid uint32) ([]byte, error) {
+	data := make([]byte, identifySize)
+	err := adminCmd(f, &passthruCmd{opcode: adminIdentify, nsid: nsid, cdw10: cns}, data)
+	return data, err
+}
+
+func readSMARTLog(f *os.File) (*smartLog, error) {
+	data := make([]byte, smartLogSize)
+	// The number of dwords to read is 0's based.
+	numd := uint32(len(data)/4 - 1)
+	if err := adminCmd(f, &passthruCmd{opcode: adminGetLogPage, nsid: nsidAll, cdw10: logSMART | numd<<16}, data); err != nil {
+		return nil, err
+	}
+	return parseSMARTLog(data)
+}
+
+func readController(f *os.File) (*controller, error) {
+	b, err := identify(f, cnsController, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseController(b)
+}
+
+func readNamespace(f *os.File, nsid uint32) (*namespace, error) {
+	b, err := identify(f, cnsNamespace, nsid)
+	if err != nil {
+		return nil, err
+	}
+	return parseNamespace(b)
+}
+
+// namespaceID returns the namespace ID of a namespace device, or 0 for a
+// controller.
+func namespaceID(f *os.File) (uint32, error) {
+	if !nsRE.MatchString(filepath.Base(f.Name())) {
+		return 0, nil
+	}
+	id, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlID, 0)
+	if errno != 0 {
+		return 0, &os.PathError{Op: "ioctl NVME_IOCTL_ID", Path: f.Name(), Err: errno}
+	}
+	return uint32(id), nil
+}
+
+// namespaces returns the namespace devices in devDir, in order.
+func namespaces() ([]string, error) {
+	entries, err := filepath.Glob(filepath.Join(devDir, "nvme*n*"))
+	if err != nil {
+		return nil, err
+	}
+	var ns []string
+	for _, e := range entries {
+		if nsRE.MatchString(filepath.Base(e)) {
+			ns = append(ns, e)
+		}
+	}
+	// Sort nvme0n2 before nvme0n10.
+	key := func(s string) (string, int) {
+		m := nsRE.FindStringSubmatchIndex(filepath.Base(s))
+		n, _ := strconv.Atoi(filepath.Base(s)[m[2]:m[3]])
+		return filepath.Base(s)[:m[2]], n
+	}
+	sort.Slice(ns, func(i, j int) bool {
+		a, an := key(ns[i])
+		b, bn := key(ns[j])
+		if a != b {
+			return a < b
+		}
+		return an < bn
+	})
+	return ns, nil
+}
+
+func list() error {
+	devs, err := namespaces()
+	if err != nil {
+		return err
+	}
+	var entries []listEntry
+	for _, d := range devs {
+		e, err := listNamespace(d)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	printList(os.Stdout, entries)
+	return nil
+}
+
+func listNamespace(dev string) (*listEntry, error) {
+	f, err := os.Open(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	e := &listEntry{node: dev}
+	if e.nsid, err = namespaceID(f); err != nil {
+		return nil, err
+	}
+	if e.ctrl, err = readController(f); err != nil {
+		return nil, err
+	}
+	if e.ns, err = readNamespace(f, e.nsid); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func idCtrl(dev string) error {
+	f, err := os.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c, err := readController(f)
+	if err != nil {
+		return err
+	}
+	printController(os.Stdout, c)
+	return nil
+}
+
+func idNS(dev string, nsid uint32) error {
+	f, err := os.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if nsid == 0 {
+		if nsid, err = namespaceID(f); err != nil {
+			return err
+		}
+		if nsid == 0 {
+			return fmt.Errorf("%s is a controller, use -n to select a namespace", dev)
+		}
+	}
+	ns, err := readNamespace(f, nsid)
+	if err != nil {
+		return err
+	}
+	printNamespace(os.Stdout, nsid, ns)
+	return nil
+}
+
+func smartLogCmd(dev string) error {
+	f, err := os.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	l, err := readSMARTLog(f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Smart Log for NVME device:%s namespace-id:%x\n", filepath.Base(dev), uint32(nsidAll))
+	printSMARTLog(os.Stdout, l)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: nvme list | id-ctrl DEVICE | id-ns [-n NSID] DEVICE | smart-log DEVICE\n")
+	os.Exit(1)
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+	}
+	fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+	nsid := fs.Uint("n", 0, "namespace ID")
+	fs.Parse(args[1:])
+
+	switch args[0] {
+	case "list":
+		if fs.NArg() != 0 {
+			usage()
+		}
+		return list()
+	case "id-ctrl", "id-ns", "smart-log":
+	default:
+		usage()
+	}
+	if fs.NArg() != 1 {
+		usage()
+	}
+	dev := fs.Arg(0)
+	switch args[0] {
+	case "id-ctrl":
+		return idCtrl(dev)
+	case "id-ns":
+		return idNS(dev, uint32(*nsid))
+	default:
+		return smartLogCmd(dev)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("nvme: ")
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
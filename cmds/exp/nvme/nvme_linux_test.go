@@ -0,0 +1,40 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNamespaces(t *testing.T) {
+	d, err := ioutil.TempDir("", "nvme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	for _, n := range []string{"nvme0", "nvme0n10", "nvme0n2", "nvme0n2p1", "nvme1", "nvme1n1", "nvme-fabrics", "nvme0n1"} {
+		if err := ioutil.WriteFile(filepath.Join(d, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	devDir = d
+	defer func() { devDir = "/dev" }()
+
+	got, err := namespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, n := range []string{"nvme0n1", "nvme0n2", "nvme0n10", "nvme1n1"} {
+		want = append(want, filepath.Join(d, n))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("namespaces() = %q, want %q", got, want)
+	}
+}
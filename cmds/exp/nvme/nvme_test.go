@@ -0,0 +1,182 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func testController() []byte {
+	b := make([]byte, identifySize)
+	le := binary.LittleEndian
+	le.PutUint16(b[0:], 0x144d)
+	le.PutUint16(b[2:], 0x144d)
+	copy(b[4:24], "S4EWNX0R123456      ")
+	copy(b[24:64], "Samsung SSD 970 EVO Plus 1TB            ")
+	copy(b[64:72], "2B2QEXM7")
+	le.PutUint32(b[80:], 0x10300)
+	le.PutUint64(b[280:], 1000204886016)
+	le.PutUint32(b[516:], 1)
+	return b
+}
+
+func testNamespace() []byte {
+	b := make([]byte, identifySize)
+	le := binary.LittleEndian
+	le.PutUint64(b[0:], 1953525168)
+	le.PutUint64(b[8:], 1953525168)
+	le.PutUint64(b[16:], 551609288)
+	b[25] = 1
+	b[26] = 0
+	b[128+2] = 9
+	b[128+3] = 2
+	b[132+2] = 12
+	b[132+3] = 1
+	return b
+}
+
+func TestParse(t *testing.T) {
+	c, err := parseController(testController())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantC := &controller{
+		vendorID:          0x144d,
+		subsystemVendorID: 0x144d,
+		serial:            "S4EWNX0R123456",
+		model:             "Samsung SSD 970 EVO Plus 1TB",
+		firmware:          "2B2QEXM7",
+		version:           0x10300,
+		totalCapacity:     1000204886016,
+		namespaces:        1,
+	}
+	if !reflect.DeepEqual(c, wantC) {
+		t.Errorf("parseController = %+v, want %+v", c, wantC)
+	}
+
+	ns, err := parseNamespace(testNamespace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNS := &namespace{
+		size:        1953525168,
+		capacity:    1953525168,
+		utilization: 551609288,
+		formats: []lbaFormat{
+			{dataSize: 512, relativePerformance: 2},
+			{dataSize: 4096, relativePerformance: 1},
+		},
+	}
+	if !reflect.DeepEqual(ns, wantNS) {
+		t.Errorf("parseNamespace = %+v, want %+v", ns, wantNS)
+	}
+
+	bad := testNamespace()
+	bad[26] = 2
+	if _, err := parseNamespace(bad); err == nil {
+		t.Errorf("parseNamespace with format 2 of 2 succeeded, want error")
+	}
+	if _, err := parseController(make([]byte, 512)); err == nil {
+		t.Errorf("parseController of a short buffer succeeded, want error")
+	}
+}
+
+func TestPrintList(t *testing.T) {
+	c, err := parseController(testController())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns, err := parseNamespace(testNamespace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	printList(&b, []listEntry{{node: "/dev/nvme0n1", nsid: 1, ctrl: c, ns: ns}})
+	want := `Node             SN                   Model                                    Namespace Usage                      Format           FW Rev
+---------------- -------------------- ---------------------------------------- --------- -------------------------- ---------------- --------
+/dev/nvme0n1     S4EWNX0R123456       Samsung SSD 970 EVO Plus 1TB             1         282.42 GB / 1.00 TB        512 B + 0 B      2B2QEXM7
+`
+	if b.String() != want {
+		t.Errorf("printList:\ngot\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestPrintNamespace(t *testing.T) {
+	ns, err := parseNamespace(testNamespace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	printNamespace(&b, 1, ns)
+	want := `NVME Identify Namespace 1:
+nsze    : 0x74706db0
+ncap    : 0x74706db0
+nuse    : 0x20e0e3c8
+nlbaf   : 1
+flbas   : 0x0
+lbaf  0 : ms:0   lbads:9  rp:0x2 (in use)
+lbaf  1 : ms:0   lbads:12 rp:0x1
+`
+	if b.String() != want {
+		t.Errorf("printNamespace:\ngot\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestSMARTLog(t *testing.T) {
+	b := make([]byte, smartLogSize)
+	le := binary.LittleEndian
+	b[0] = 0x5
+	le.PutUint16(b[1:], 308)
+	b[3], b[4], b[5] = 100, 10, 3
+	le.PutUint64(b[32:], 17539113)
+	le.PutUint64(b[48:], 25049421)
+	le.PutUint64(b[112:], 1143)
+	le.PutUint64(b[128:], 3771)
+	le.PutUint64(b[144:], 61)
+	l, err := parseSMARTLog(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printSMARTLog(&out, l)
+	want := `critical_warning                    : 0x5
+  available spare is below threshold
+  reliability is degraded
+temperature                         : 35 C
+available_spare                     : 100%
+available_spare_threshold           : 10%
+percentage_used                     : 3%
+data_units_read                     : 17539113 (8.98 TB)
+data_units_written                  : 25049421 (12.83 TB)
+power_cycles                        : 1143
+power_on_hours                      : 3771
+unsafe_shutdowns                    : 61
+media_errors                        : 0
+num_err_log_entries                 : 0
+`
+	if out.String() != want {
+		t.Errorf("printSMARTLog:\ngot\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	for _, tt := range []struct {
+		in   uint64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.00 KB"},
+		{1000204886016, "1.00 TB"},
+		{^uint64(0), "18.45 EB"},
+	} {
+		if got := decimal(tt.in); got != tt.want {
+			t.Errorf("decimal(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
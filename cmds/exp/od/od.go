@@ -0,0 +1,484 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// od dumps files in octal and other formats.
+//
+// Synopsis:
+//     od [-bcdfilosxv] [-A RADIX] [-t TYPE]... [-j BYTES] [-N BYTES] [-w BYTES] [FILE]...
+//
+// Description:
+//     od prints the FILEs, or stdin, concatenated, in lines of BYTES bytes,
+//     16 by default. Each line starts with the offset of its first byte,
+//     followed by the bytes in each TYPE, on a line of its own. A FILE of -
+//     is stdin. Lines that are the same as the one before are printed as a
+//     single *, unless -v is given.
+//
+//     TYPE is one or more of
+//
+//     a:      named characters, ignoring the high bit
+//     c:      printable characters, backslash escapes or octal
+//     d SIZE: signed decimal
+//     f SIZE: floating point
+//     o SIZE: octal
+//     u SIZE: unsigned decimal
+//     x SIZE: hexadecimal
+//
+//     with an optional z to print the printable characters of each line at
+//     its end. SIZE is a number of bytes, or C, S, I or L for the size of
+//     a char, short, int and long for integers, and F or D for a float and
+//     a double. It is 4 for integers and 8 for floating point by default.
+//     Numbers are in the byte order of the machine.
+//
+//     BYTES are decimal, octal with a leading 0, or hexadecimal with a
+//     leading 0x, followed by b for 512, K for 1024, or M for 1048576.
+//
+// Options:
+//     -A: print offsets in RADIX d, o or x, or n for none; o by default
+//     -t: print TYPE; o2 by default
+//     -j: skip BYTES bytes of input
+//     -N: read at most BYTES bytes of input
+//     -v: print all lines
+//     -w: print BYTES bytes per line
+//     -b: same as -t o1
+//     -c: same as -t c
+//     -d: same as -t u2
+//     -f: same as -t fF
+//     -i: same as -t dI
+//     -l: same as -t dL
+//     -o: same as -t o2
+//     -s: same as -t d2
+//     -x: same as -t x2
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/ubinary"
+)
+
+// typeList collects the types of -t and of the traditional options, in the
+// order they are given.
+type typeList struct {
+	types *[]string
+	// fixed is the type of a traditional option.
+	fixed string
+}
+
+func (l typeList) String() string { return "" }
+
+func (l typeList) Set(s string) error {
+	if l.fixed != "" {
+		s = l.fixed
+	}
+	*l.types = append(*l.types, s)
+	return nil
+}
+
+func (l typeList) Type() string {
+	if l.fixed != "" {
+		return "bool"
+	}
+	return "string"
+}
+
+var (
+	types   []string
+	radix   = flag.StringP("address-radix", "A", "o", "print offsets in RADIX d, o or x, or n for none")
+	skip    = flag.StringP("skip-bytes", "j", "0", "skip BYTES bytes of input")
+	limit   = flag.StringP("read-bytes", "N", "", "read at most BYTES bytes of input")
+	verbose = flag.BoolP("output-duplicates", "v", false, "print all lines")
+	width   = flag.StringP("width", "w", "16", "print BYTES bytes per line")
+)
+
+func init() {
+	flag.VarP(typeList{types: &types}, "format", "t", "print TYPE")
+	for _, o := range []struct {
+		name, short, fixed string
+	}{
+		{"octal-bytes", "b", "o1"},
+		{"chars", "c", "c"},
+		{"unsigned-shorts", "d", "u2"},
+		{"floats", "f", "fF"},
+		{"ints", "i", "dI"},
+		{"longs", "l", "dL"},
+		{"octal-shorts", "o", "o2"},
+		{"shorts", "s", "d2"},
+		{"hex-shorts", "x", "x2"},
+	} {
+		f := flag.CommandLine.VarPF(typeList{types: &types, fixed: o.fixed}, o.name, o.short, "same as -t "+o.fixed)
+		f.NoOptDefVal = "true"
+	}
+}
+
+// outputType is how to print the bytes of a line.
+type outputType struct {
+	kind byte // a, c, d, f, o, u or x
+	size int
+	// width is that of a field, with its leading space.
+	width int
+	// chars is set to print the characters of the line at its end.
+	chars bool
+}
+
+// intSizes are the sizes of C types on Linux.
+var intSizes = map[byte]int{'C': 1, 'S': 2, 'I': 4, 'L': 8}
+
+// intWidth returns the number of digits of the widest integer of size
+// bytes in base, with a sign if signed.
+func intWidth(base, size int, signed bool) int {
+	if signed {
+		return len(strconv.FormatInt(math.MinInt64>>(64-8*size), base))
+	}
+	return len(strconv.FormatUint(math.MaxUint64>>(64-8*size), base))
+}
+
+// parseTypes parses a TYPE string, which has one or more types.
+func parseTypes(spec string) ([]outputType, error) {
+	var ts []outputType
+	s := spec
+	for len(s) > 0 {
+		t := outputType{kind: s[0]}
+		s = s[1:]
+		switch t.kind {
+		case 'a', 'c':
+			t.size, t.width = 1, 4
+		case 'd', 'o', 'u', 'x':
+			t.size = 4
+			if len(s) > 0 && intSizes[s[0]] != 0 {
+				t.size = intSizes[s[0]]
+				s = s[1:]
+			} else if len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+				i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+				if i < 0 {
+					i = len(s)
+				}
+				n, _ := strconv.Atoi(s[:i])
+				if n != 1 && n != 2 && n != 4 && n != 8 {
+					return nil, fmt.Errorf("invalid type string %q: %d-byte integers are not supported", spec, n)
+				}
+				t.size = n
+				s = s[i:]
+			}
+			base := map[byte]int{'d': 10, 'o': 8, 'u': 10, 'x': 16}[t.kind]
+			t.width = intWidth(base, t.size, t.kind == 'd') + 1
+		case 'f':
+			t.size = 8
+			switch {
+			case strings.HasPrefix(s, "F") || strings.HasPrefix(s, "4"):
+				t.size = 4
+				s = s[1:]
+			case strings.HasPrefix(s, "D") || strings.HasPrefix(s, "8"):
+				s = s[1:]
+			case len(s) > 0 && (s[0] == 'L' || s[0] >= '0' && s[0] <= '9'):
+				return nil, fmt.Errorf("invalid type string %q: only float and double are supported", spec)
+			}
+			t.width = 16
+			if t.size == 8 {
+				t.width = 25
+			}
+		default:
+			return nil, fmt.Errorf("invalid character %q in type string", t.kind)
+		}
+		if len(s) > 0 && s[0] == 'z' {
+			t.chars = true
+			s = s[1:]
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// parseBytes parses a number of BYTES.
+func parseBytes(s string) (int64, error) {
+	mult := int64(1)
+	for suffix, m := range map[string]int64{"b": 512, "k": 1024, "K": 1024, "m": 1 << 20, "M": 1 << 20} {
+		if strings.HasSuffix(s, suffix) && !strings.HasPrefix(s, "0x") {
+			s, mult = strings.TrimSuffix(s, suffix), m
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number of bytes %q", s)
+	}
+	return n * mult, nil
+}
+
+var charNames = [...]string{
+	"nul", "soh", "stx", "etx", "eot", "enq", "ack", "bel",
+	"bs", "ht", "nl", "vt", "ff", "cr", "so", "si",
+	"dle", "dc1", "dc2", "dc3", "dc4", "nak", "syn", "etb",
+	"can", "em", "sub", "esc", "fs", "gs", "rs", "us",
+	"sp",
+}
+
+var charEscapes = map[byte]string{
+	0: `\0`, '\a': `\a`, '\b': `\b`, '\f': `\f`, '\n': `\n`, '\r': `\r`, '\t': `\t`, '\v': `\v`,
+}
+
+// formatFloat formats f with the fewest digits that read back as f.
+func formatFloat(f float64, bits int) string {
+	for prec := 1; ; prec++ {
+		s := strconv.FormatFloat(f, 'g', prec, bits)
+		if g, err := strconv.ParseFloat(s, bits); err != nil || g == f || prec >= 17 || math.IsNaN(f) {
+			return s
+		}
+	}
+}
+
+// format formats the field of b, which is t.size bytes.
+func (t outputType) format(b []byte) string {
+	var u uint64
+	switch t.size {
+	case 1:
+		u = uint64(b[0])
+	case 2:
+		u = uint64(ubinary.NativeEndian.Uint16(b))
+	case 4:
+		u = uint64(ubinary.NativeEndian.Uint32(b))
+	case 8:
+		u = ubinary.NativeEndian.Uint64(b)
+	}
+	switch t.kind {
+	case 'a':
+		c := b[0] & 0x7f
+		switch {
+		case int(c) < len(charNames):
+			return charNames[c]
+		case c == 0x7f:
+			return "del"
+		}
+		return string(c)
+	case 'c':
+		c := b[0]
+		if s, ok := charEscapes[c]; ok {
+			return s
+		}
+		if c >= ' ' && c <= '~' {
+			return string(c)
+		}
+		return fmt.Sprintf("%03o", c)
+	case 'd':
+		// Sign extend.
+		shift := uint(64 - 8*t.size)
+		return strconv.FormatInt(int64(u<<shift)>>shift, 10)
+	case 'f':
+		if t.size == 4 {
+			return formatFloat(float64(math.Float32frombits(uint32(u))), 32)
+		}
+		return formatFloat(math.Float64frombits(u), 64)
+	case 'o':
+		return fmt.Sprintf("%0*o", t.width-1, u)
+	case 'x':
+		return fmt.Sprintf("%0*x", t.width-1, u)
+	}
+	return strconv.FormatUint(u, 10)
+}
+
+// dumper prints lines of bytes.
+type dumper struct {
+	w     io.Writer
+	types []outputType
+	// radix of addresses, or 'n' for none.
+	radix   byte
+	width   int
+	verbose bool
+
+	// lineWidth is the width of the fields of a whole line, the same for
+	// all types.
+	lineWidth int
+	prev      []byte
+	star      bool
+}
+
+func newDumper(w io.Writer, types []outputType, radix byte, width int, verbose bool) (*dumper, error) {
+	if radix != 'd' && radix != 'o' && radix != 'x' && radix != 'n' {
+		return nil, fmt.Errorf("invalid output address radix %q; it must be one character from [doxn]", radix)
+	}
+	d := &dumper{w: w, types: types, radix: radix, width: width, verbose: verbose}
+	for _, t := range types {
+		if width%t.size != 0 || width == 0 {
+			return nil, fmt.Errorf("invalid width %d: it must be a multiple of the sizes of the types", width)
+		}
+		if lw := width / t.size * t.width; lw > d.lineWidth {
+			d.lineWidth = lw
+		}
+	}
+	return d, nil
+}
+
+// address formats an address, or an empty one of the same width.
+func (d *dumper) address(addr int64, empty bool) string {
+	var s string
+	switch d.radix {
+	case 'd':
+		s = fmt.Sprintf("%07d", addr)
+	case 'o':
+		s = fmt.Sprintf("%07o", addr)
+	case 'x':
+		s = fmt.Sprintf("%06x", addr)
+	}
+	if empty {
+		return strings.Repeat(" ", len(s))
+	}
+	return s
+}
+
+// line prints the line of block, which is at most d.width bytes, at addr.
+func (d *dumper) line(addr int64, block []byte) error {
+	if !d.verbose && len(block) == d.width && bytes.Equal(block, d.prev) {
+		if d.star {
+			return nil
+		}
+		d.star = true
+		_, err := io.WriteString(d.w, "*\n")
+		return err
+	}
+	d.star = false
+	d.prev = append(d.prev[:0], block...)
+
+	padded := make([]byte, d.width)
+	copy(padded, block)
+	var b strings.Builder
+	for i, t := range d.types {
+		b.WriteString(d.address(addr, i > 0))
+		fields := d.width / t.size
+		n := (len(block) + t.size - 1) / t.size
+		pad := d.lineWidth - fields*t.width
+		// The padding is spread over the fields of a whole line, as evenly
+		// as it goes.
+		padLeft := pad
+		start := b.Len()
+		for j := 0; j < n; j++ {
+			next := pad * (fields - j - 1) / fields
+			fmt.Fprintf(&b, "%*s", padLeft-next+t.width, t.format(padded[j*t.size:]))
+			padLeft = next
+		}
+		if t.chars {
+			b.WriteString(strings.Repeat(" ", d.lineWidth-(b.Len()-start)))
+			b.WriteString("  >")
+			for _, c := range block {
+				if c < ' ' || c > '~' {
+					c = '.'
+				}
+				b.WriteByte(c)
+			}
+			b.WriteString("<")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(d.w, b.String())
+	return err
+}
+
+// dump prints r, whose first byte is at addr, and the address after it.
+func (d *dumper) dump(r io.Reader, addr int64) error {
+	buf := make([]byte, d.width)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := d.line(addr, buf[:n]); err != nil {
+				return err
+			}
+			addr += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if d.radix != 'n' {
+		_, err := fmt.Fprintln(d.w, d.address(addr, false))
+		return err
+	}
+	return nil
+}
+
+var errSkip = errors.New("cannot skip past end of combined input")
+
+// run dumps r, skipping skip bytes and reading at most limit bytes, if it
+// is not negative.
+func run(d *dumper, r io.Reader, skip, limit int64) error {
+	if n, err := io.CopyN(ioutil.Discard, r, skip); err != nil {
+		if n < skip {
+			return errSkip
+		}
+		return err
+	}
+	if limit >= 0 {
+		r = io.LimitReader(r, limit)
+	}
+	return d.dump(r, skip)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("od: ")
+	flag.Parse()
+
+	if len(types) == 0 {
+		types = []string{"o2"}
+	}
+	var ts []outputType
+	for _, s := range types {
+		t, err := parseTypes(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ts = append(ts, t...)
+	}
+	skipBytes, err := parseBytes(*skip)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limitBytes := int64(-1)
+	if *limit != "" {
+		if limitBytes, err = parseBytes(*limit); err != nil {
+			log.Fatal(err)
+		}
+	}
+	widthBytes, err := parseBytes(*width)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(*radix) != 1 {
+		log.Fatalf("invalid output address radix %q; it must be one character from [doxn]", *radix)
+	}
+	d, err := newDumper(os.Stdout, ts, (*radix)[0], int(widthBytes), *verbose)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var readers []io.Reader
+	for _, name := range flag.Args() {
+		if name == "-" {
+			readers = append(readers, os.Stdin)
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = []io.Reader{os.Stdin}
+	}
+	if err := run(d, io.MultiReader(readers...), skipBytes, limitBytes); err != nil {
+		log.Fatal(err)
+	}
+}
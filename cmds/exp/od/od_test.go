@@ -0,0 +1,195 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTypes(t *testing.T) {
+	for _, tt := range []struct {
+		spec string
+		want []outputType
+	}{
+		{"o2", []outputType{{kind: 'o', size: 2, width: 7}}},
+		{"x", []outputType{{kind: 'x', size: 4, width: 9}}},
+		{"dL", []outputType{{kind: 'd', size: 8, width: 21}}},
+		{"u1z", []outputType{{kind: 'u', size: 1, width: 4, chars: true}}},
+		{"x1cfF", []outputType{{kind: 'x', size: 1, width: 3}, {kind: 'c', size: 1, width: 4}, {kind: 'f', size: 4, width: 16}}},
+		{"f", []outputType{{kind: 'f', size: 8, width: 25}}},
+		{"aS", nil},
+	} {
+		got, err := parseTypes(tt.spec)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseTypes(%q) succeeded, want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTypes(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"q", "d3", "d16", "fL", "f2"} {
+		if _, err := parseTypes(spec); err == nil {
+			t.Errorf("parseTypes(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int64
+	}{
+		{"10", 10},
+		{"010", 8},
+		{"0x1b", 27},
+		{"2b", 1024},
+		{"1K", 1024},
+		{"1M", 1 << 20},
+	} {
+		if got, err := parseBytes(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseBytes(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "-1", "x"} {
+		if _, err := parseBytes(in); err == nil {
+			t.Errorf("parseBytes(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	const in = "hello, world\n\x00\x01\x7f\x80\xff"
+	for _, tt := range []struct {
+		name    string
+		types   string
+		radix   byte
+		width   int
+		verbose bool
+		skip    int64
+		limit   int64
+		in      string
+		want    string
+	}{
+		{
+			name:  "default",
+			types: "o2",
+			want: "0000000 062550 066154 026157 073440 071157 062154 000012 077401\n" +
+				"0000020 177600\n0000022\n",
+		},
+		{
+			name:  "hex with chars",
+			types: "x1z",
+			radix: 'x',
+			want: "000000 68 65 6c 6c 6f 2c 20 77 6f 72 6c 64 0a 00 01 7f  >hello, world....<\n" +
+				"000010 80 ff                                            >..<\n000012\n",
+		},
+		{
+			name:  "aligned types",
+			types: "x1c",
+			radix: 'd',
+			want: "0000000  68  65  6c  6c  6f  2c  20  77  6f  72  6c  64  0a  00  01  7f\n" +
+				"          h   e   l   l   o   ,       w   o   r   l   d  \\n  \\0 001 177\n" +
+				"0000016  80  ff\n        200 377\n0000018\n",
+		},
+		{
+			name:  "spread padding",
+			types: "d2d8",
+			radix: 'n',
+			in:    "\xff\xff\x01\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00",
+			want: "     -1      1      0      0      2      0      0      0\n" +
+				"                      131071                           2\n",
+		},
+		{
+			name:  "named",
+			types: "a",
+			want: "0000000   h   e   l   l   o   ,  sp   w   o   r   l   d  nl nul soh del\n" +
+				"0000020 nul del\n0000022\n",
+		},
+		{
+			name:  "floats",
+			types: "fF",
+			in:    "\x00\x00\x80\x3f\x00\x00\x00\x00\x00\x00\xf0\x3f",
+			want:  "0000000               1               0           1.875\n0000014\n",
+		},
+		{
+			name:  "skip and limit",
+			types: "x1",
+			skip:  3,
+			limit: 5,
+			want:  "0000003 6c 6f 2c 20 77\n0000010\n",
+		},
+		{
+			name:  "width",
+			types: "o1",
+			width: 4,
+			limit: 8,
+			want:  "0000000 150 145 154 154\n0000004 157 054 040 167\n0000010\n",
+		},
+		{
+			name:  "duplicates",
+			types: "o2",
+			in:    strings.Repeat("\x00", 64) + "a",
+			want:  "0000000 000000 000000 000000 000000 000000 000000 000000 000000\n*\n0000100 000141\n0000101\n",
+		},
+		{
+			name:    "verbose",
+			types:   "x2",
+			width:   2,
+			verbose: true,
+			in:      "\x00\x00\x00\x00",
+			want:    "0000000 0000\n0000002 0000\n0000004\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := parseTypes(tt.types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.radix == 0 {
+				tt.radix = 'o'
+			}
+			if tt.width == 0 {
+				tt.width = 16
+			}
+			if tt.in == "" {
+				tt.in = in
+			}
+			if tt.limit == 0 {
+				tt.limit = -1
+			}
+			var out bytes.Buffer
+			d, err := newDumper(&out, ts, tt.radix, tt.width, tt.verbose)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := run(d, strings.NewReader(tt.in), tt.skip, tt.limit); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("run() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts, _ := parseTypes("x4")
+	if _, err := newDumper(nil, ts, 'o', 6, false); err == nil {
+		t.Errorf("newDumper() with width 6 succeeded, want an error")
+	}
+	if _, err := newDumper(nil, ts, 'y', 16, false); err == nil {
+		t.Errorf("newDumper() with radix y succeeded, want an error")
+	}
+	d, _ := newDumper(&bytes.Buffer{}, ts, 'o', 16, false)
+	if err := run(d, strings.NewReader("abc"), 4, -1); err != errSkip {
+		t.Errorf("run() skipping past the end = %v, want %v", err, errSkip)
+	}
+}
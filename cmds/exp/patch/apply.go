@@ -0,0 +1,114 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// splitLines splits data into lines, which keep their newline.
+func splitLines(data string) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := strings.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines = append(lines, data[:i])
+		data = data[i:]
+	}
+	return lines
+}
+
+// result is how a hunk was applied. line is where it was applied in the
+// new file, or where it was expected in the old file if it failed,
+// counted from 1. offset is how far the hunk was moved from the line in its
+// header, and fuzz how many context lines were ignored at either end.
+type result struct {
+	failed bool
+	line   int
+	offset int
+	fuzz   int
+}
+
+// mismatch reports whether the hunk did not apply exactly as it says.
+func (r result) mismatch() bool {
+	return r.failed || r.offset != 0 || r.fuzz != 0
+}
+
+// context returns the number of context lines at the start and end of h.
+func (h *hunk) context() (lead, trail int) {
+	for lead < len(h.lines) && h.lines[lead].kind == ' ' {
+		lead++
+	}
+	for trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail].kind == ' ' {
+		trail++
+	}
+	return lead, trail
+}
+
+// match reports whether want is at lines[at:].
+func match(lines, want []string, at int) bool {
+	for i, l := range want {
+		if lines[at+i] != l {
+			return false
+		}
+	}
+	return true
+}
+
+// search looks for want in lines at or after line pos, from line near
+// outwards, and returns its index, or -1.
+func search(lines, want []string, pos, near int) int {
+	for d := 0; near+d <= len(lines) || near-d >= pos; d++ {
+		for _, at := range []int{near + d, near - d} {
+			if at >= pos && at+len(want) <= len(lines) && match(lines, want, at) {
+				return at
+			}
+		}
+	}
+	return -1
+}
+
+// apply applies hunks to lines, in order, and returns the new lines. A hunk
+// is looked for where its header says, moved by the offset of the previous
+// hunk, and then further away. If it is not found, up to maxFuzz context
+// lines at either end of it are ignored, and it is looked for again.
+func apply(lines []string, hunks []*hunk, maxFuzz int) ([]string, []result) {
+	var out []string
+	results := make([]result, len(hunks))
+	pos, offset := 0, 0
+	for i, h := range hunks {
+		old, new := h.old(), h.new()
+		lead, trail := h.context()
+		found := false
+		for fuzz := 0; fuzz <= maxFuzz && !found; fuzz++ {
+			top, bottom := min(fuzz, lead), min(fuzz, trail)
+			if fuzz > 0 && top < fuzz && bottom < fuzz {
+				// Nothing more to ignore.
+				break
+			}
+			at := search(lines, old[top:len(old)-bottom], pos, h.oldStart-1+offset+top)
+			if at < 0 {
+				continue
+			}
+			out = append(out, lines[pos:at]...)
+			offset = at - top - (h.oldStart - 1)
+			results[i] = result{line: len(out) - top + 1, offset: offset, fuzz: fuzz}
+			out = append(out, new[top:len(new)-bottom]...)
+			pos = at + len(old) - top - bottom
+			found = true
+		}
+		if !found {
+			results[i] = result{failed: true, line: h.oldStart + offset}
+		}
+	}
+	return append(out, lines[pos:]...), results
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,372 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkLine is a line of a hunk: context, deleted or added. line keeps its
+// newline, unless the file does not end with one.
+type hunkLine struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// hunk is a change to consecutive lines. oldStart is the first changed
+// line of the old file, counted from 1, or the line after which to add
+// lines if the hunk has no old lines; the same for newStart.
+type hunk struct {
+	oldStart, newStart int
+	lines              []hunkLine
+}
+
+// filePatch is the hunks of a file.
+type filePatch struct {
+	oldName, newName string
+	hunks            []*hunk
+}
+
+// old returns the lines the hunk expects, and new the lines it replaces
+// them with.
+func (h *hunk) old() []string { return h.side('+') }
+func (h *hunk) new() []string { return h.side('-') }
+
+func (h *hunk) side(skip byte) []string {
+	var lines []string
+	for _, l := range h.lines {
+		if l.kind != skip {
+			lines = append(lines, l.line)
+		}
+	}
+	return lines
+}
+
+// reverse swaps the old and new sides of the hunk.
+func (h *hunk) reverse() {
+	h.oldStart, h.newStart = h.newStart, h.oldStart
+	var dels []hunkLine
+	var lines []hunkLine
+	for _, l := range h.lines {
+		switch l.kind {
+		case '-':
+			dels = append(dels, hunkLine{'+', l.line})
+		case '+':
+			lines = append(lines, hunkLine{'-', l.line})
+		default:
+			lines = append(append(lines, dels...), l)
+			dels = nil
+		}
+	}
+	h.lines = append(lines, dels...)
+}
+
+// String formats the hunk as a unified diff hunk.
+func (h *hunk) String() string {
+	rng := func(start, n int) string {
+		switch n {
+		case 0:
+			return fmt.Sprintf("%d,0", start-1)
+		case 1:
+			return strconv.Itoa(start)
+		}
+		return fmt.Sprintf("%d,%d", start, n)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%s +%s @@\n", rng(h.oldStart, len(h.old())), rng(h.newStart, len(h.new())))
+	for _, l := range h.lines {
+		b.WriteByte(l.kind)
+		b.WriteString(l.line)
+		if !strings.HasSuffix(l.line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return b.String()
+}
+
+var (
+	unifiedHunk = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	contextOld  = regexp.MustCompile(`^\*\*\* (\d+)(?:,(\d+))? \*\*\*\*`)
+	contextNew  = regexp.MustCompile(`^--- (\d+)(?:,(\d+))? ----`)
+)
+
+// patchReader reads lines of a patch, with one line of lookahead.
+type patchReader struct {
+	br   *bufio.Reader
+	next string
+	eof  bool
+	line int
+	err  error
+}
+
+func (r *patchReader) peek() (string, bool) {
+	if r.next == "" && !r.eof {
+		r.next, r.err = r.br.ReadString('\n')
+		if r.err == io.EOF {
+			r.err = nil
+		}
+		if r.next == "" {
+			r.eof = true
+		}
+	}
+	return r.next, !r.eof
+}
+
+func (r *patchReader) read() (string, bool) {
+	l, ok := r.peek()
+	r.next = ""
+	r.line++
+	return l, ok
+}
+
+func (r *patchReader) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("malformed patch at line %d: %s", r.line, fmt.Sprintf(format, a...))
+}
+
+// fileName returns the name in a --- or +++ header line, without the
+// time stamp that may follow a tab.
+func fileName(header string) string {
+	name := strings.TrimRight(header[4:], "\r\n")
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	return strings.TrimSpace(name)
+}
+
+// parsePatch parses the unified and context diffs in r. Other lines, such
+// as the diff commands and comments around them, are skipped.
+func parsePatch(r io.Reader) ([]*filePatch, error) {
+	pr := &patchReader{br: bufio.NewReader(r)}
+	var patches []*filePatch
+	for {
+		l, ok := pr.read()
+		if !ok {
+			return patches, pr.err
+		}
+		next, _ := pr.peek()
+		switch {
+		case strings.HasPrefix(l, "--- ") && strings.HasPrefix(next, "+++ "):
+			pr.read()
+			fp := &filePatch{oldName: fileName(l), newName: fileName(next)}
+			if err := pr.unifiedHunks(fp); err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		case strings.HasPrefix(l, "*** ") && strings.HasPrefix(next, "--- "):
+			pr.read()
+			fp := &filePatch{oldName: fileName(l), newName: fileName(next)}
+			if err := pr.contextHunks(fp); err != nil {
+				return nil, err
+			}
+			patches = append(patches, fp)
+		}
+	}
+}
+
+// count returns the number in a hunk header, or def if it is missing.
+func count(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func (pr *patchReader) unifiedHunks(fp *filePatch) error {
+	for {
+		l, _ := pr.peek()
+		m := unifiedHunk.FindStringSubmatch(l)
+		if m == nil {
+			if len(fp.hunks) == 0 {
+				return pr.errorf("no hunks")
+			}
+			return nil
+		}
+		pr.read()
+		h := &hunk{oldStart: count(m[1], 0), newStart: count(m[3], 0)}
+		oldLen, newLen := count(m[2], 1), count(m[4], 1)
+		if oldLen == 0 {
+			h.oldStart++
+		}
+		if newLen == 0 {
+			h.newStart++
+		}
+		for oldLen > 0 || newLen > 0 {
+			l, ok := pr.read()
+			if !ok {
+				return pr.errorf("unexpected end of hunk")
+			}
+			kind := l[0]
+			switch {
+			case l == "\n" || l == "\r\n":
+				// Some editors strip the space of empty context lines.
+				kind, l = ' ', " "+l
+			case kind == '\\':
+				if err := pr.noNewline(h); err != nil {
+					return err
+				}
+				continue
+			}
+			switch kind {
+			case ' ':
+				oldLen--
+				newLen--
+			case '-':
+				oldLen--
+			case '+':
+				newLen--
+			default:
+				return pr.errorf("unexpected line in hunk: %q", l)
+			}
+			if oldLen < 0 || newLen < 0 {
+				return pr.errorf("hunk is longer than its header says")
+			}
+			h.lines = append(h.lines, hunkLine{kind, l[1:]})
+		}
+		if l, _ := pr.peek(); strings.HasPrefix(l, "\\") {
+			pr.read()
+			if err := pr.noNewline(h); err != nil {
+				return err
+			}
+		}
+		fp.hunks = append(fp.hunks, h)
+	}
+}
+
+// noNewline removes the newline of the last line of h, after a
+// "\ No newline at end of file" line.
+func (pr *patchReader) noNewline(h *hunk) error {
+	if len(h.lines) == 0 {
+		return pr.errorf("misplaced \\ line")
+	}
+	l := &h.lines[len(h.lines)-1]
+	l.line = strings.TrimSuffix(l.line, "\n")
+	return nil
+}
+
+// contextRange returns the start and length of the range of a context
+// hunk.
+func contextRange(m []string) (int, int) {
+	start := count(m[1], 0)
+	end := count(m[2], start)
+	if start == 0 || end < start {
+		return start + 1, 0
+	}
+	return start, end - start + 1
+}
+
+// contextSection reads the lines of one side of a context hunk, with
+// prefixes "  ", "! " and del or add.
+func (pr *patchReader) contextSection(n int, change byte) ([]hunkLine, error) {
+	var lines []hunkLine
+	for len(lines) < n {
+		l, _ := pr.peek()
+		if len(l) < 2 || l[1] != ' ' || (l[0] != ' ' && l[0] != '!' && l[0] != change) {
+			if len(lines) == 0 {
+				// The section is left out if it has no changes.
+				return nil, nil
+			}
+			return nil, pr.errorf("unexpected line in hunk: %q", l)
+		}
+		pr.read()
+		lines = append(lines, hunkLine{l[0], l[2:]})
+		if next, _ := pr.peek(); strings.HasPrefix(next, "\\") {
+			pr.read()
+			lines[len(lines)-1].line = strings.TrimSuffix(lines[len(lines)-1].line, "\n")
+		}
+	}
+	return lines, nil
+}
+
+func (pr *patchReader) contextHunks(fp *filePatch) error {
+	for {
+		if l, _ := pr.peek(); !strings.HasPrefix(l, "***************") {
+			if len(fp.hunks) == 0 {
+				return pr.errorf("no hunks")
+			}
+			return nil
+		}
+		pr.read()
+		l, _ := pr.read()
+		m := contextOld.FindStringSubmatch(l)
+		if m == nil {
+			return pr.errorf("bad hunk header %q", l)
+		}
+		h := &hunk{}
+		var oldLen, newLen int
+		h.oldStart, oldLen = contextRange(m)
+		old, err := pr.contextSection(oldLen, '-')
+		if err != nil {
+			return err
+		}
+		l, _ = pr.read()
+		if m = contextNew.FindStringSubmatch(l); m == nil {
+			return pr.errorf("bad hunk header %q", l)
+		}
+		h.newStart, newLen = contextRange(m)
+		new, err := pr.contextSection(newLen, '+')
+		if err != nil {
+			return err
+		}
+		if h.lines, err = mergeContext(old, new, oldLen, newLen); err != nil {
+			return pr.errorf("%v", err)
+		}
+		fp.hunks = append(fp.hunks, h)
+	}
+}
+
+// mergeContext merges the old and new sides of a context hunk into the
+// lines of a unified one. A side without changes is left out of a context
+// hunk, so it is the context lines of the other.
+func mergeContext(old, new []hunkLine, oldLen, newLen int) ([]hunkLine, error) {
+	if old == nil && oldLen > 0 {
+		for _, l := range new {
+			if l.kind == ' ' {
+				old = append(old, l)
+			}
+		}
+	}
+	if new == nil && newLen > 0 {
+		for _, l := range old {
+			if l.kind == ' ' {
+				new = append(new, l)
+			}
+		}
+	}
+	var lines []hunkLine
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && old[i].kind == '-':
+			lines = append(lines, hunkLine{'-', old[i].line})
+			i++
+		case j < len(new) && new[j].kind == '+':
+			lines = append(lines, hunkLine{'+', new[j].line})
+			j++
+		case i < len(old) && old[i].kind == '!' || j < len(new) && new[j].kind == '!':
+			for ; i < len(old) && old[i].kind == '!'; i++ {
+				lines = append(lines, hunkLine{'-', old[i].line})
+			}
+			for ; j < len(new) && new[j].kind == '!'; j++ {
+				lines = append(lines, hunkLine{'+', new[j].line})
+			}
+		case i < len(old) && j < len(new):
+			lines = append(lines, hunkLine{' ', old[i].line})
+			i++
+			j++
+		default:
+			return nil, fmt.Errorf("context lines of hunk do not match")
+		}
+	}
+	if len(old) != oldLen || len(new) != newLen {
+		return nil, fmt.Errorf("hunk does not have the length its header says")
+	}
+	return lines, nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// patch applies a diff to files.
+//
+// Synopsis:
+//     patch [-bR] [-p NUM] [-F NUM] [-i PATCHFILE] [--dry-run] [--no-backup-if-mismatch] [ORIGFILE [PATCHFILE]]
+//
+// Description:
+//     patch reads a unified or context diff from PATCHFILE, or stdin, and
+//     applies its hunks to the files it names, or to ORIGFILE.
+//
+//     A hunk that is not at the line it says is looked for in the rest of
+//     the file, and applied at the closest place it is found, moved by an
+//     offset. If it is not found, up to NUM context lines at its start and
+//     end are ignored, which is the fuzz. Hunks that cannot be applied are
+//     saved to FILE.rej.
+//
+//     Unless -p is given, the base name of a file name in the diff is
+//     used, or the whole name if its directory exists. The name of the old
+//     file is used if it exists, or else the name of the new one. A diff
+//     from /dev/null or an empty file creates a file, and one to /dev/null
+//     or an empty file removes it.
+//
+//     The original of a file is saved to FILE.orig with -b, or if a hunk
+//     does not apply exactly.
+//
+//     The exit status is 0 if all hunks were applied, 1 if some failed and
+//     2 if there was trouble.
+//
+// Options:
+//     -b: save the original of each file to FILE.orig
+//     -F: ignore up to NUM context lines, 2 by default
+//     -i: read the diff from PATCHFILE
+//     -p: strip NUM leading components from file names
+//     -R: apply the diff in reverse
+//     --dry-run: print what would happen, without changing any files
+//     --no-backup-if-mismatch: do not save originals when hunks do not
+//         apply exactly
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	backup     = flag.BoolP("backup", "b", false, "save the original of each file to FILE.orig")
+	fuzz       = flag.IntP("fuzz", "F", 2, "ignore up to NUM context lines")
+	input      = flag.StringP("input", "i", "", "read the diff from PATCHFILE")
+	strip      = flag.IntP("strip", "p", 0, "strip NUM leading components from file names")
+	reverse    = flag.BoolP("reverse", "R", false, "apply the diff in reverse")
+	dryRun     = flag.Bool("dry-run", false, "print what would happen, without changing any files")
+	noMismatch = flag.Bool("no-backup-if-mismatch", false, "do not save originals when hunks do not apply exactly")
+)
+
+// devNull is the name of the missing side of a diff that creates or
+// removes a file.
+const devNull = "/dev/null"
+
+// patcher applies the diffs of files.
+type patcher struct {
+	w io.Writer
+
+	// strip is -1 to use base names.
+	strip            int
+	fuzz             int
+	backup           bool
+	backupIfMismatch bool
+	reverse          bool
+	dryRun           bool
+	// orig is the file to patch instead of the files named in the diff.
+	orig string
+
+	failed  bool
+	trouble bool
+}
+
+func (p *patcher) errorf(format string, a ...interface{}) {
+	log.Printf(format, a...)
+	p.trouble = true
+}
+
+// creates reports whether fp creates a file: it is from /dev/null, or from
+// an empty file, as diff -N shows a missing one.
+func (fp *filePatch) creates() bool {
+	return fp.oldName == devNull || len(fp.hunks) == 1 && len(fp.hunks[0].old()) == 0
+}
+
+// removes reports whether fp removes a file, like creates.
+func (fp *filePatch) removes() bool {
+	return fp.newName == devNull || len(fp.hunks) == 1 && len(fp.hunks[0].new()) == 0
+}
+
+// stripName returns name as given by -p, or false if it has no components
+// left.
+func (p *patcher) stripName(name string) (string, bool) {
+	if p.strip < 0 {
+		if fi, err := os.Stat(filepath.Dir(name)); !filepath.IsAbs(name) && err == nil && fi.IsDir() {
+			return name, true
+		}
+		return filepath.Base(name), true
+	}
+	for i := 0; i < p.strip; i++ {
+		j := strings.IndexByte(name, '/')
+		if j < 0 {
+			return "", false
+		}
+		name = strings.TrimLeft(name[j+1:], "/")
+	}
+	return name, name != ""
+}
+
+// target returns the name of the file to patch.
+func (p *patcher) target(fp *filePatch) (string, error) {
+	if p.orig != "" {
+		return p.orig, nil
+	}
+	var names []string
+	for _, name := range []string{fp.oldName, fp.newName} {
+		if name == devNull {
+			continue
+		}
+		if name, ok := p.stripName(name); ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	}
+	if fp.creates() && len(names) > 0 {
+		return names[len(names)-1], nil
+	}
+	return "", fmt.Errorf("can't find file to patch: %s", fp.oldName)
+}
+
+func plural(n int, s string) string {
+	if n == 1 || n == -1 {
+		return fmt.Sprintf("%d %s", n, s)
+	}
+	return fmt.Sprintf("%d %ss", n, s)
+}
+
+// patchFile applies the hunks of a file.
+func (p *patcher) patchFile(fp *filePatch) {
+	if p.reverse {
+		fp.oldName, fp.newName = fp.newName, fp.oldName
+		for _, h := range fp.hunks {
+			h.reverse()
+		}
+	}
+	name, err := p.target(fp)
+	if err != nil {
+		log.Print(err)
+		p.failed = true
+		return
+	}
+	var exists bool
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		exists = true
+		mode = fi.Mode()
+	}
+	var data []byte
+	if exists || len(fp.hunks[0].old()) > 0 {
+		if data, err = ioutil.ReadFile(name); err != nil {
+			p.errorf("%v", err)
+			return
+		}
+	}
+
+	if p.dryRun {
+		fmt.Fprintf(p.w, "checking file %s\n", name)
+	} else {
+		fmt.Fprintf(p.w, "patching file %s\n", name)
+	}
+	lines, results := apply(splitLines(string(data)), fp.hunks, p.fuzz)
+	var failed []*hunk
+	var mismatch bool
+	for i, r := range results {
+		mismatch = mismatch || r.mismatch()
+		switch {
+		case r.failed:
+			failed = append(failed, fp.hunks[i])
+			fmt.Fprintf(p.w, "Hunk #%d FAILED at %d.\n", i+1, r.line)
+		case r.fuzz != 0 && r.offset != 0:
+			fmt.Fprintf(p.w, "Hunk #%d succeeded at %d with fuzz %d (offset %s).\n", i+1, r.line, r.fuzz, plural(r.offset, "line"))
+		case r.fuzz != 0:
+			fmt.Fprintf(p.w, "Hunk #%d succeeded at %d with fuzz %d.\n", i+1, r.line, r.fuzz)
+		case r.offset != 0:
+			fmt.Fprintf(p.w, "Hunk #%d succeeded at %d (offset %s).\n", i+1, r.line, plural(r.offset, "line"))
+		}
+	}
+	if len(failed) > 0 {
+		p.failed = true
+		fmt.Fprintf(p.w, "%d out of %s FAILED -- saving rejects to file %s.rej\n", len(failed), plural(len(fp.hunks), "hunk"), name)
+	}
+	if p.dryRun {
+		return
+	}
+
+	if exists && (p.backup || mismatch && p.backupIfMismatch) {
+		if err := ioutil.WriteFile(name+".orig", data, mode); err != nil {
+			p.errorf("%v", err)
+			return
+		}
+	}
+	if len(failed) > 0 {
+		var rej strings.Builder
+		fmt.Fprintf(&rej, "--- %s\n+++ %s\n", fp.oldName, fp.newName)
+		for _, h := range failed {
+			rej.WriteString(h.String())
+		}
+		if err := ioutil.WriteFile(name+".rej", []byte(rej.String()), 0644); err != nil {
+			p.errorf("%v", err)
+		}
+	}
+	if fp.removes() && len(lines) == 0 {
+		if err := os.Remove(name); err != nil {
+			p.errorf("%v", err)
+		}
+		return
+	}
+	if dir := filepath.Dir(name); !exists {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			p.errorf("%v", err)
+			return
+		}
+	}
+	if err := ioutil.WriteFile(name, []byte(strings.Join(lines, "")), mode); err != nil {
+		p.errorf("%v", err)
+	}
+}
+
+// run applies the diff in r and returns the exit status.
+func run(p *patcher, r io.Reader) int {
+	patches, err := parsePatch(r)
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+	if len(patches) == 0 {
+		log.Print("only garbage was found in the patch input")
+		return 2
+	}
+	for _, fp := range patches {
+		p.patchFile(fp)
+	}
+	switch {
+	case p.trouble:
+		return 2
+	case p.failed:
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("patch: ")
+	flag.Parse()
+	if flag.NArg() > 2 || flag.NArg() == 2 && *input != "" {
+		log.Print("usage: patch [-bR] [-p NUM] [-F NUM] [-i PATCHFILE] [--dry-run] [--no-backup-if-mismatch] [ORIGFILE [PATCHFILE]]")
+		os.Exit(2)
+	}
+	if *strip < 0 || *fuzz < 0 {
+		log.Print("-p and -F must not be negative")
+		os.Exit(2)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	p := &patcher{
+		w:                w,
+		strip:            *strip,
+		fuzz:             *fuzz,
+		backup:           *backup,
+		backupIfMismatch: !*noMismatch,
+		reverse:          *reverse,
+		dryRun:           *dryRun,
+		orig:             flag.Arg(0),
+	}
+	if !flag.CommandLine.Changed("strip") {
+		p.strip = -1
+	}
+	name := *input
+	if flag.NArg() == 2 {
+		name = flag.Arg(1)
+	}
+	var r io.Reader = os.Stdin
+	if name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+	code := run(p, r)
+	w.Flush()
+	os.Exit(code)
+}
@@ -0,0 +1,362 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const unifiedDiff = `--- a/f	2021-01-01 00:00:00.000000000 +0000
++++ b/f	2021-01-01 00:00:00.000000000 +0000
+@@ -1,4 +1,4 @@
+-one
++ONE
+ two
+ three
+ four
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+\ No newline at end of file
+`
+
+const contextDiff = `*** a/f	2021-01-01 00:00:00.000000000 +0000
+--- b/f	2021-01-01 00:00:00.000000000 +0000
+***************
+*** 1,4 ****
+! one
+  two
+  three
+  four
+--- 1,4 ----
+! ONE
+  two
+  three
+  four
+***************
+*** 8,10 ****
+--- 8,11 ----
+  eight
+  nine
+  ten
++ eleven
+\ No newline at end of file
+`
+
+func TestParse(t *testing.T) {
+	want := []*filePatch{{
+		oldName: "a/f",
+		newName: "b/f",
+		hunks: []*hunk{
+			{oldStart: 1, newStart: 1, lines: []hunkLine{
+				{'-', "one\n"}, {'+', "ONE\n"}, {' ', "two\n"}, {' ', "three\n"}, {' ', "four\n"},
+			}},
+			{oldStart: 8, newStart: 8, lines: []hunkLine{
+				{' ', "eight\n"}, {' ', "nine\n"}, {' ', "ten\n"}, {'+', "eleven"},
+			}},
+		},
+	}}
+	for _, tt := range []struct {
+		name, diff string
+	}{
+		{"unified", "diff -u a/f b/f\n" + unifiedDiff},
+		{"context", contextDiff},
+	} {
+		got, err := parsePatch(strings.NewReader(tt.diff))
+		if err != nil {
+			t.Errorf("%s: parsePatch() = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: parsePatch() = %+v, want %+v", tt.name, got[0].hunks, want[0].hunks)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, diff := range []string{
+		"--- a\n+++ b\n",
+		"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n",
+		"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n?b\n",
+		"*** a\n--- b\n***************\n*** 1,3 ****\n  a\n- b\n--- 1,2 ----\n  a\n",
+	} {
+		if _, err := parsePatch(strings.NewReader(diff)); err == nil {
+			t.Errorf("parsePatch(%q) succeeded, want an error", diff)
+		}
+	}
+}
+
+func TestHunk(t *testing.T) {
+	h := &hunk{oldStart: 3, newStart: 3, lines: []hunkLine{
+		{' ', "a\n"}, {'-', "b\n"}, {'+', "c\n"}, {' ', "d"},
+	}}
+	const want = "@@ -3,3 +3,3 @@\n a\n-b\n+c\n d\n\\ No newline at end of file\n"
+	if got := h.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	h.reverse()
+	const rev = "@@ -3,3 +3,3 @@\n a\n-c\n+b\n d\n\\ No newline at end of file\n"
+	if got := h.String(); got != rev {
+		t.Errorf("reversed String() = %q, want %q", got, rev)
+	}
+}
+
+func TestApply(t *testing.T) {
+	h := func(start int, lines ...string) *hunk {
+		h := &hunk{oldStart: start}
+		for _, l := range lines {
+			h.lines = append(h.lines, hunkLine{l[0], l[1:] + "\n"})
+		}
+		return h
+	}
+	lines := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return splitLines(strings.Join(strings.Split(s, ""), "\n") + "\n")
+	}
+	for _, tt := range []struct {
+		name    string
+		in      string
+		hunks   []*hunk
+		want    string
+		results []result
+	}{
+		{
+			name:    "exact",
+			in:      "abcdefgh",
+			hunks:   []*hunk{h(2, " b", " c", "-d", "+D", " e")},
+			want:    "abcDefgh",
+			results: []result{{line: 2}},
+		},
+		{
+			name:    "offset",
+			in:      "xxabcdefgh",
+			hunks:   []*hunk{h(2, " b", " c", "-d", "+D", " e"), h(7, " g", "+G", " h")},
+			want:    "xxabcDefgGh",
+			results: []result{{line: 4, offset: 2}, {line: 9, offset: 2}},
+		},
+		{
+			name:    "negative offset",
+			in:      "cdefgh",
+			hunks:   []*hunk{h(7, " g", "-h")},
+			want:    "cdefg",
+			results: []result{{line: 5, offset: -2}},
+		},
+		{
+			name:    "fuzz",
+			in:      "aBcdefGh",
+			hunks:   []*hunk{h(1, " a", " b", " c", "-d", "+D", " e", " f", " g")},
+			want:    "aBcDefGh",
+			results: []result{{line: 1, fuzz: 2}},
+		},
+		{
+			name:    "failed",
+			in:      "abcdefgh",
+			hunks:   []*hunk{h(1, " a", "-x", " c"), h(5, " e", "-f", " g")},
+			want:    "abcdegh",
+			results: []result{{failed: true, line: 1}, {line: 5}},
+		},
+		{
+			name:    "no fuzz",
+			in:      "aBcdefgh",
+			hunks:   []*hunk{h(1, " a", " b", " c", "-d", " e")},
+			want:    "aBcdefgh",
+			results: []result{{failed: true, line: 1}},
+		},
+		{
+			name:    "create",
+			in:      "",
+			hunks:   []*hunk{h(1, "+a", "+b")},
+			want:    "ab",
+			results: []result{{line: 1}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fuzz := 2
+			if tt.name == "no fuzz" {
+				fuzz = 0
+			}
+			got, results := apply(lines(tt.in), tt.hunks, fuzz)
+			if want := lines(tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("apply() = %q, want %q", got, want)
+			}
+			if !reflect.DeepEqual(results, tt.results) {
+				t.Errorf("apply() results = %+v, want %+v", results, tt.results)
+			}
+		})
+	}
+}
+
+func TestStripName(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		strip int
+		want  string
+		ok    bool
+	}{
+		{"a/b/c", 0, "a/b/c", true},
+		{"a/b/c", 1, "b/c", true},
+		{"a//b/c", 2, "c", true},
+		{"/a/b", 1, "a/b", true},
+		{"a/b", 2, "", false},
+		{"nonexistent/b", -1, "b", true},
+		{"b", -1, "b", true},
+	} {
+		got, ok := (&patcher{strip: tt.strip}).stripName(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("stripName(%q) with -p%d = %q, %v, want %q, %v", tt.name, tt.strip, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	const orig = "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	const patched = "ONE\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven"
+	const create = `--- /dev/null
++++ b/sub/new
+@@ -0,0 +1,2 @@
++hello
++world
+--- a/gone
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	for _, tt := range []struct {
+		name   string
+		files  map[string]string
+		diff   string
+		p      patcher
+		code   int
+		out    string
+		want   map[string]string
+		absent []string
+	}{
+		{
+			name:   "unified",
+			files:  map[string]string{"f": orig},
+			diff:   unifiedDiff,
+			p:      patcher{strip: 1, fuzz: 2, backupIfMismatch: true},
+			out:    "patching file f\n",
+			want:   map[string]string{"f": patched},
+			absent: []string{"f.orig"},
+		},
+		{
+			name:  "context",
+			files: map[string]string{"f": orig},
+			diff:  contextDiff,
+			p:     patcher{strip: -1, fuzz: 2, backup: true},
+			out:   "patching file f\n",
+			want:  map[string]string{"f": patched, "f.orig": orig},
+		},
+		{
+			name:  "reverse",
+			files: map[string]string{"f": patched},
+			diff:  unifiedDiff,
+			p:     patcher{strip: 1, fuzz: 2, reverse: true},
+			out:   "patching file f\n",
+			want:  map[string]string{"f": orig},
+		},
+		{
+			name:  "orig file",
+			files: map[string]string{"g": "zero\n" + orig},
+			diff:  unifiedDiff,
+			p:     patcher{strip: 1, fuzz: 2, backupIfMismatch: true, orig: "g"},
+			out:   "patching file g\nHunk #1 succeeded at 2 (offset 1 line).\nHunk #2 succeeded at 9 (offset 1 line).\n",
+			want:  map[string]string{"g": "zero\n" + patched, "g.orig": "zero\n" + orig},
+		},
+		{
+			name:   "dry run",
+			files:  map[string]string{"f": "zero\n" + orig},
+			diff:   unifiedDiff,
+			p:      patcher{strip: 1, fuzz: 2, backupIfMismatch: true, dryRun: true},
+			out:    "checking file f\nHunk #1 succeeded at 2 (offset 1 line).\nHunk #2 succeeded at 9 (offset 1 line).\n",
+			want:   map[string]string{"f": "zero\n" + orig},
+			absent: []string{"f.orig"},
+		},
+		{
+			name:  "reject",
+			files: map[string]string{"f": strings.Replace(orig, "one", "uno", 1)},
+			diff:  unifiedDiff,
+			p:     patcher{strip: 1, fuzz: 2, backupIfMismatch: true},
+			code:  1,
+			out:   "patching file f\nHunk #1 FAILED at 1.\n1 out of 2 hunks FAILED -- saving rejects to file f.rej\n",
+			want: map[string]string{
+				"f":      strings.Replace(patched, "ONE", "uno", 1),
+				"f.orig": strings.Replace(orig, "one", "uno", 1),
+				"f.rej":  "--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-one\n+ONE\n two\n three\n four\n",
+			},
+		},
+		{
+			name:   "create and remove",
+			files:  map[string]string{"gone": "bye\n"},
+			diff:   create,
+			p:      patcher{strip: 1, fuzz: 2},
+			out:    "patching file sub/new\npatching file gone\n",
+			want:   map[string]string{"sub/new": "hello\nworld\n"},
+			absent: []string{"gone"},
+		},
+		{
+			name: "missing file",
+			diff: unifiedDiff,
+			p:    patcher{strip: 1, fuzz: 2},
+			code: 1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"f", "f.orig", "f.rej", "g", "g.orig", "gone", "sub"} {
+				os.RemoveAll(name)
+			}
+			for name, data := range tt.files {
+				if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var out bytes.Buffer
+			p := tt.p
+			p.w = &out
+			if code := run(&p, strings.NewReader(tt.diff)); code != tt.code {
+				t.Errorf("run() = %d, want %d", code, tt.code)
+			}
+			if out.String() != tt.out {
+				t.Errorf("run() printed %q, want %q", out.String(), tt.out)
+			}
+			for name, want := range tt.want {
+				got, err := ioutil.ReadFile(name)
+				if err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					t.Errorf("%s exists, want it absent", name)
+				}
+			}
+		})
+	}
+}
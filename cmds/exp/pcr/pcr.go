@@ -0,0 +1,172 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pcr reads and extends TPM PCRs.
+//
+// Synopsis:
+//     pcr [-a ALG] read [INDEX...]
+//     pcr [-a ALG] extend INDEX DIGEST
+//     pcr [-a ALG] measure INDEX FILE
+//
+// Description:
+//     read:    print the PCRs of the bank of ALG, or only INDEX
+//     extend:  extend PCR INDEX with DIGEST, given in hex
+//     measure: extend PCR INDEX with the ALG hash of FILE
+//
+//     TPM 1.2 only has a SHA-1 bank.
+//
+// Options:
+//     -a: hash algorithm, sha1, sha256, sha384 or sha512 (default sha256)
+package main
+
+import (
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/tss"
+)
+
+const usage = "usage: pcr [-a ALG] read [INDEX...] | extend INDEX DIGEST | measure INDEX FILE"
+
+// numPCRs is the number of PCRs in a bank.
+const numPCRs = 24
+
+var algName = flag.String("a", "sha256", "hash algorithm: sha1, sha256, sha384 or sha512")
+
+var algs = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+func parseIndex(s string) (uint32, error) {
+	i, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || i >= numPCRs {
+		return 0, fmt.Errorf("invalid PCR index %q, must be 0-%d", s, numPCRs-1)
+	}
+	return uint32(i), nil
+}
+
+// parseDigest parses a hex digest of alg, optionally prefixed with 0x.
+func parseDigest(s string, alg crypto.Hash) ([]byte, error) {
+	d, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %v", s, err)
+	}
+	if len(d) != alg.Size() {
+		return nil, fmt.Errorf("digest is %d bytes, but %s needs %d", len(d), *algName, alg.Size())
+	}
+	return d, nil
+}
+
+// hashFile returns the alg hash of the contents of r.
+func hashFile(r io.Reader, alg crypto.Hash) ([]byte, error) {
+	h := alg.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func printPCRs(w io.Writer, name string, pcrs []tss.PCR, indices []uint32) {
+	fmt.Fprintf(w, "%s:\n", name)
+	if indices == nil {
+		for _, p := range pcrs {
+			fmt.Fprintf(w, "  %2d: %x\n", p.Index, p.Digest)
+		}
+		return
+	}
+	for _, i := range indices {
+		fmt.Fprintf(w, "  %2d: %x\n", i, pcrs[i].Digest)
+	}
+}
+
+func run(args []string) error {
+	alg, ok := algs[*algName]
+	if !ok {
+		return fmt.Errorf("unknown hash algorithm %q", *algName)
+	}
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	// Parse all arguments before talking to the TPM.
+	var indices []uint32
+	var digest []byte
+	switch args[0] {
+	case "read":
+		for _, a := range args[1:] {
+			i, err := parseIndex(a)
+			if err != nil {
+				return err
+			}
+			indices = append(indices, i)
+		}
+	case "extend", "measure":
+		if len(args) != 3 {
+			return errors.New(usage)
+		}
+		i, err := parseIndex(args[1])
+		if err != nil {
+			return err
+		}
+		indices = []uint32{i}
+		if args[0] == "extend" {
+			if digest, err = parseDigest(args[2], alg); err != nil {
+				return err
+			}
+			break
+		}
+		f, err := os.Open(args[2])
+		if err != nil {
+			return err
+		}
+		digest, err = hashFile(f, alg)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New(usage)
+	}
+
+	tpm, err := tss.NewTPM()
+	if err != nil {
+		return fmt.Errorf("no TPM found: %v", err)
+	}
+	defer tpm.Close()
+
+	if args[0] != "read" {
+		if err := tpm.ExtendBank(digest, indices[0], alg); err != nil {
+			return fmt.Errorf("extending PCR %d: %v", indices[0], err)
+		}
+	}
+	pcrs, err := tpm.ReadPCRBank(alg)
+	if err != nil {
+		return err
+	}
+	printPCRs(os.Stdout, *algName, pcrs, indices)
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pcr: ")
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
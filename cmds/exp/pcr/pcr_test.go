@@ -0,0 +1,93 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/tss"
+)
+
+func TestParseIndex(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want uint32
+		err  bool
+	}{
+		{in: "0", want: 0},
+		{in: "23", want: 23},
+		{in: "24", err: true},
+		{in: "-1", err: true},
+		{in: "x", err: true},
+	} {
+		got, err := parseIndex(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseIndex(%q) = (%d, %v), want (%d, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	sha1 := strings.Repeat("ab", 20)
+	for _, tt := range []struct {
+		in  string
+		alg crypto.Hash
+		err bool
+	}{
+		{in: sha1, alg: crypto.SHA1},
+		{in: "0x" + sha1, alg: crypto.SHA1},
+		{in: sha1, alg: crypto.SHA256, err: true},
+		{in: strings.Repeat("00", 32), alg: crypto.SHA256},
+		{in: "xyz", alg: crypto.SHA1, err: true},
+	} {
+		if _, err := parseDigest(tt.in, tt.alg); (err != nil) != tt.err {
+			t.Errorf("parseDigest(%q, %v) = %v, want error %v", tt.in, tt.alg, err, tt.err)
+		}
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	got, err := hashFile(strings.NewReader("abc"), crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; hex.EncodeToString(got) != want {
+		t.Errorf("hashFile = %x, want %s", got, want)
+	}
+}
+
+func TestPrintPCRs(t *testing.T) {
+	pcrs := []tss.PCR{
+		{Index: 0, Digest: []byte{0, 1}},
+		{Index: 1, Digest: []byte{2, 3}},
+		{Index: 2, Digest: []byte{4, 5}},
+	}
+	var b bytes.Buffer
+	printPCRs(&b, "sha1", pcrs, nil)
+	printPCRs(&b, "sha1", pcrs, []uint32{2, 0})
+	want := "sha1:\n   0: 0001\n   1: 0203\n   2: 0405\nsha1:\n   2: 0405\n   0: 0001\n"
+	if b.String() != want {
+		t.Errorf("printPCRs = %q, want %q", b.String(), want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"foo"},
+		{"read", "24"},
+		{"extend", "1"},
+		{"extend", "1", "00"},
+		{"measure", "1", "/nonexistent"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("run(%q) succeeded, want error", args)
+		}
+	}
+}
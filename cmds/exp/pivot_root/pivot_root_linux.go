@@ -0,0 +1,139 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pivot_root changes the root file system.
+//
+// Synopsis:
+//     pivot_root NEW_ROOT PUT_OLD
+//
+// Description:
+//     Move the root file system of the mount namespace to PUT_OLD and make
+//     NEW_ROOT the root file system. NEW_ROOT has to be a mount point and
+//     PUT_OLD a directory under it. Unlike switch_root, the old root is
+//     kept and can be used or unmounted later.
+//
+//     pivot_root changes the root and working directory of processes that
+//     used the old root, but a shell running pivot_root should still
+//     chroot into the new root. The usual sequence is:
+//
+//     mount --bind /newroot /newroot  # if it is not a mount point yet
+//     cd /newroot
+//     mkdir -p oldroot
+//     pivot_root . oldroot
+//     exec chroot . sh -c 'umount -l /oldroot; exec /sbin/init'
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const usage = "usage: pivot_root NEW_ROOT PUT_OLD"
+
+// mountInfo is a variable so it can be changed in tests.
+var mountInfo = "/proc/self/mountinfo"
+
+// unescape decodes the octal escapes of spaces, tabs, newlines and
+// backslashes in mountinfo.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isMountPoint returns whether path is a mount point in mountinfo r.
+func isMountPoint(r io.Reader, path string) (bool, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 5 {
+			continue
+		}
+		if unescape(f[4]) == path {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
+
+// resolve returns the absolute path of p without symlinks.
+func resolve(p string) (string, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(p)
+}
+
+// check validates the arguments of pivot_root(2), whose errors are vague.
+func check(newRoot, putOld string) error {
+	nr, err := resolve(newRoot)
+	if err != nil {
+		return err
+	}
+	po, err := resolve(putOld)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(po); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", putOld)
+	}
+	if po != nr && !strings.HasPrefix(po, nr+"/") && nr != "/" {
+		return fmt.Errorf("%s is not under %s", putOld, newRoot)
+	}
+	f, err := os.Open(mountInfo)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ok, err := isMountPoint(f, nr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s is not a mount point, bind mount it onto itself first", newRoot)
+	}
+	return nil
+}
+
+func run(args []string) error {
+	if len(args) != 2 {
+		return errors.New(usage)
+	}
+	if err := check(args[0], args[1]); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(args[0], args[1]); err != nil {
+		return fmt.Errorf("pivot_root(%q, %q): %v", args[0], args[1], err)
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pivot_root: ")
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
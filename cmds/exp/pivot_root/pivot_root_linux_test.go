@@ -0,0 +1,101 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+24 22 0:22 / /new\040root rw,relatime shared:2 - tmpfs tmpfs rw
+`
+
+func TestUnescape(t *testing.T) {
+	for in, want := range map[string]string{
+		`/new\040root`:  "/new root",
+		`/a\011b\134c`:  "/a\tb\\c",
+		`/trailing\04`:  `/trailing\04`,
+		`/not\999octal`: `/not\999octal`,
+	} {
+		if got := unescape(in); got != want {
+			t.Errorf("unescape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIsMountPoint(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/":         true,
+		"/proc":     true,
+		"/new root": true,
+		"/new":      false,
+		"/proc/1":   false,
+	} {
+		got, err := isMountPoint(strings.NewReader(testMountInfo), path)
+		if err != nil || got != want {
+			t.Errorf("isMountPoint(%q) = (%v, %v), want %v", path, got, err, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pivot_root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The temporary directory may be behind a symlink.
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	newRoot := filepath.Join(dir, "new")
+	old := filepath.Join(newRoot, "old")
+	other := filepath.Join(dir, "other")
+	file := filepath.Join(newRoot, "file")
+	for _, d := range []string{old, other} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(old string) { mountInfo = old }(mountInfo)
+	mountInfo = filepath.Join(dir, "mountinfo")
+	info := "22 1 8:1 / / rw - ext4 /dev/sda1 rw\n30 22 0:40 / " + newRoot + " rw - tmpfs tmpfs rw\n"
+	if err := ioutil.WriteFile(mountInfo, []byte(info), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		newRoot, putOld string
+		want            string
+	}{
+		{newRoot, old, ""},
+		{newRoot, newRoot, ""},
+		{newRoot, other, "is not under"},
+		{newRoot, file, "is not a directory"},
+		{newRoot, filepath.Join(newRoot, "missing"), "no such file"},
+		{other, other, "is not a mount point"},
+	} {
+		err := check(tt.newRoot, tt.putOld)
+		if tt.want == "" && err != nil {
+			t.Errorf("check(%q, %q) = %v, want nil", tt.newRoot, tt.putOld, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("check(%q, %q) = %v, want error containing %q", tt.newRoot, tt.putOld, err, tt.want)
+		}
+	}
+
+	if err := run([]string{newRoot}); err == nil || err.Error() != usage {
+		t.Errorf("run with one argument = %v, want %q", err, usage)
+	}
+}
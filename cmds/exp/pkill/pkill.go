@@ -0,0 +1,187 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pkill signals processes by name.
+//
+// Synopsis:
+//     pkill [-SIGNAL | --signal SIGNAL] [-efx] PATTERN
+//
+// Description:
+//     Send SIGNAL, SIGTERM by default, to every process whose name matches
+//     the regular expression PATTERN. Processes are found by scanning
+//     /proc. pkill never signals itself.
+//
+//     pkill exits with 0 if a process matched, 1 if none did, 2 on a usage
+//     error and 3 if a process could not be signaled.
+//
+// Options:
+//     -f, --full:   match against the full command line rather than the name
+//     -x, --exact:  PATTERN has to match the whole name or command line
+//     -e, --echo:   print the pid and name of each process signaled
+//     -s, --signal: the signal to send, by name or number
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	exitNoMatch = 1
+	exitUsage   = 2
+	exitFailed  = 3
+)
+
+var (
+	full   = flag.BoolP("full", "f", false, "match against the full command line")
+	exact  = flag.BoolP("exact", "x", false, "PATTERN has to match the whole name")
+	echo   = flag.BoolP("echo", "e", false, "print the pid and name of each process signaled")
+	signal = flag.StringP("signal", "s", "TERM", "the signal to send, by name or number")
+
+	// procdir is where processes are looked up.
+	procdir = "/proc"
+)
+
+// proc is a process found in procdir.
+type proc struct {
+	pid     int
+	name    string
+	cmdline string
+}
+
+// parseSignal returns the signal for a number or a name with or without
+// the SIG prefix.
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < 65 {
+		return syscall.Signal(n), nil
+	}
+	n := strings.ToUpper(s)
+	if !strings.HasPrefix(n, "SIG") {
+		n = "SIG" + n
+	}
+	if sig := unix.SignalNum(n); sig != 0 {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%s is not a valid signal", s)
+}
+
+// procs returns all processes in procdir. Processes that exit while being
+// read are skipped.
+func procs() ([]proc, error) {
+	entries, err := ioutil.ReadDir(procdir)
+	if err != nil {
+		return nil, err
+	}
+	var ps []proc
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		comm, err := ioutil.ReadFile(filepath.Join(procdir, e.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		p := proc{pid: pid, name: strings.TrimSuffix(string(comm), "\n")}
+		cmdline, err := ioutil.ReadFile(filepath.Join(procdir, e.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		// Arguments are NUL-terminated. Kernel threads have no command
+		// line, so use their name.
+		p.cmdline = strings.Replace(strings.TrimSuffix(string(cmdline), "\x00"), "\x00", " ", -1)
+		if p.cmdline == "" {
+			p.cmdline = p.name
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// match returns the processes, other than self, matching re.
+func match(ps []proc, re *regexp.Regexp, full bool, self int) []proc {
+	var m []proc
+	for _, p := range ps {
+		s := p.name
+		if full {
+			s = p.cmdline
+		}
+		if p.pid != self && re.MatchString(s) {
+			m = append(m, p)
+		}
+	}
+	return m
+}
+
+// signalArg handles the -SIGNAL form, which the flag package can not
+// parse, by turning it into --signal SIGNAL.
+func signalArg(args []string) []string {
+	if len(args) == 0 || len(args[0]) < 2 || args[0][0] != '-' || args[0][1] == '-' {
+		return args
+	}
+	s := args[0][1:]
+	if _, err := strconv.Atoi(s); err == nil || len(s) > 1 && strings.ToUpper(s) == s {
+		return append([]string{"--signal", s}, args[1:]...)
+	}
+	return args
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pkill: ")
+	if err := flag.CommandLine.Parse(signalArg(os.Args[1:])); err != nil {
+		os.Exit(exitUsage)
+	}
+	if flag.NArg() != 1 {
+		log.Print("usage: pkill [-SIGNAL] [-efx] PATTERN")
+		os.Exit(exitUsage)
+	}
+
+	sig, err := parseSignal(*signal)
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitUsage)
+	}
+	pat := flag.Arg(0)
+	if *exact {
+		pat = "^(?:" + pat + ")$"
+	}
+	re, err := regexp.Compile(pat)
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitUsage)
+	}
+
+	ps, err := procs()
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitFailed)
+	}
+	m := match(ps, re, *full, os.Getpid())
+	if len(m) == 0 {
+		os.Exit(exitNoMatch)
+	}
+	status := 0
+	for _, p := range m {
+		if err := syscall.Kill(p.pid, sig); err != nil {
+			log.Printf("killing pid %d failed: %v", p.pid, err)
+			status = exitFailed
+			continue
+		}
+		if *echo {
+			fmt.Printf("%s killed (pid %d)\n", p.name, p.pid)
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want syscall.Signal
+		err  bool
+	}{
+		{in: "9", want: syscall.SIGKILL},
+		{in: "KILL", want: syscall.SIGKILL},
+		{in: "SIGHUP", want: syscall.SIGHUP},
+		{in: "term", want: syscall.SIGTERM},
+		{in: "NOSUCH", err: true},
+		{in: "99", err: true},
+	} {
+		got, err := parseSignal(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseSignal(%q) = (%v, %v), want (%v, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestSignalArg(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{in: []string{"-9", "x"}, want: []string{"--signal", "9", "x"}},
+		{in: []string{"-HUP", "x"}, want: []string{"--signal", "HUP", "x"}},
+		{in: []string{"-f", "x"}, want: []string{"-f", "x"}},
+		{in: []string{"--full", "x"}, want: []string{"--full", "x"}},
+		{in: []string{"x"}, want: []string{"x"}},
+	} {
+		if got := signalArg(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("signalArg(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	d, err := ioutil.TempDir("", "pkill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	for _, p := range []struct {
+		pid, comm, cmdline string
+	}{
+		{"1", "init\n", "/init\x00"},
+		{"2", "kthreadd\n", ""},
+		{"10", "sleep\n", "sleep\x00100\x00"},
+		{"11", "dhclient\n", "/bbin/dhclient\x00-ipv6=false\x00"},
+	} {
+		if err := os.MkdirAll(filepath.Join(d, p.pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, p.pid, "comm"), []byte(p.comm), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, p.pid, "cmdline"), []byte(p.cmdline), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Not a process.
+	if err := os.MkdirAll(filepath.Join(d, "sys"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	procdir = d
+	defer func() { procdir = "/proc" }()
+	ps, err := procs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 4 {
+		t.Fatalf("procs() = %v, want 4 processes", ps)
+	}
+
+	pids := func(ps []proc) []int {
+		var p []int
+		for _, pp := range ps {
+			p = append(p, pp.pid)
+		}
+		return p
+	}
+	for _, tt := range []struct {
+		pat  string
+		full bool
+		self int
+		want []int
+	}{
+		{pat: "^s", want: []int{10}},
+		{pat: "i", want: []int{1, 11}},
+		{pat: "i", self: 11, want: []int{1}},
+		{pat: "thread", full: true, want: []int{2}},
+		{pat: "ipv6", want: nil},
+		{pat: "ipv6", full: true, want: []int{11}},
+		{pat: "^sleep 100$", full: true, want: []int{10}},
+	} {
+		if got := pids(match(ps, regexp.MustCompile(tt.pat), tt.full, tt.self)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("match(%q, %v) = %v, want %v", tt.pat, tt.full, got, tt.want)
+		}
+	}
+}
+
+func TestPkill(t *testing.T) {
+	c := exec.Command("sleep", "31.4159")
+	if err := c.Start(); err != nil {
+		t.Skipf("test needs sleep: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- c.Wait() }()
+
+	if err := testutil.IsExitCode(testutil.Command(t, "-x", "no such process").Run(), exitNoMatch); err != nil {
+		t.Errorf("pkill for no process: %v", err)
+	}
+	if err := testutil.Command(t, "-KILL", "-f", `^sleep 31\.4159$`).Run(); err != nil {
+		t.Fatalf("pkill: got %v, want nil", err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("sleep succeeded, but expected to be killed")
+		}
+	case <-time.After(5 * time.Second):
+		c.Process.Kill()
+		t.Errorf("sleep was not killed")
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
@@ -0,0 +1,426 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// printf formats and prints ARGUMENTs under control of FORMAT.
+//
+// Synopsis:
+//     printf FORMAT [ARGUMENT]...
+//
+// Description:
+//     FORMAT is printed with backslash escapes interpreted and each
+//     conversion specification replaced by the next ARGUMENT. If there are
+//     more ARGUMENTs than conversions, FORMAT is reused until all of them
+//     are consumed. Missing ARGUMENTs are treated as empty strings or zero.
+//
+//     The escapes \\, \a, \b, \c, \e, \f, \n, \r, \t, \v, \", \NNN
+//     (octal), \xHH, \uHHHH and \UHHHHHHHH are recognized. \c stops all
+//     further output.
+//
+//     Conversions are %d, %i, %o, %u, %x, %X, %f, %F, %e, %E, %g, %G, %c,
+//     %s, %b (like %s, but with backslash escapes, including \0NNN,
+//     interpreted in the ARGUMENT), %q (ARGUMENT quoted for reuse as shell
+//     input) and %%. The flags "-+ #0", a field width and a precision are
+//     supported; either of the latter may be '*' to take it from the
+//     next ARGUMENT.
+//
+//     Numeric ARGUMENTs may be decimal, octal with a leading 0, hexadecimal
+//     with a leading 0x or a character preceded by ' or ", which stands for
+//     its character code.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// errStop is returned internally when \c is seen.
+var errStop = errors.New("stop")
+
+type printer struct {
+	w    *bufio.Writer
+	args []string
+	// used counts the arguments consumed during one pass over the format.
+	used int
+	// errs holds conversion errors. They are reported, but do not stop
+	// the output.
+	errs []error
+}
+
+func (p *printer) next() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	a := p.args[0]
+	p.args = p.args[1:]
+	p.used++
+	return a
+}
+
+// escape interprets the backslash escape at the start of s. In %b
+// arguments, octal escapes may have a leading 0 that does not count
+// towards their up to 3 digits. It returns the bytes to print and the
+// number of bytes of s that were consumed.
+func escape(s string, b bool) (string, int, error) {
+	if len(s) < 2 {
+		return "\\", 1, nil
+	}
+	switch c := s[1]; c {
+	case '\\':
+		return "\\", 2, nil
+	case 'a':
+		return "\a", 2, nil
+	case 'b':
+		return "\b", 2, nil
+	case 'c':
+		return "", 2, errStop
+	case 'e':
+		return "\x1b", 2, nil
+	case 'f':
+		return "\f", 2, nil
+	case 'n':
+		return "\n", 2, nil
+	case 'r':
+		return "\r", 2, nil
+	case 't':
+		return "\t", 2, nil
+	case 'v':
+		return "\v", 2, nil
+	case '"':
+		return "\"", 2, nil
+	case 'x':
+		n := digits(s[2:], 16, 2)
+		if n == 0 {
+			return "", 2, fmt.Errorf("missing hexadecimal number in escape")
+		}
+		v, _ := strconv.ParseUint(s[2:2+n], 16, 8)
+		return string([]byte{byte(v)}), 2 + n, nil
+	case 'u', 'U':
+		l := 4
+		if c == 'U' {
+			l = 8
+		}
+		n := digits(s[2:], 16, l)
+		if n != l {
+			return "", 2 + n, fmt.Errorf("missing hexadecimal number in escape")
+		}
+		v, _ := strconv.ParseUint(s[2:2+n], 16, 32)
+		return string(rune(v)), 2 + n, nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		start := 1
+		if b && c == '0' {
+			start = 2
+		}
+		n := digits(s[start:], 8, 3)
+		v, _ := strconv.ParseUint("0"+s[start:start+n], 8, 16)
+		return string([]byte{byte(v)}), start + n, nil
+	default:
+		return s[:2], 2, nil
+	}
+}
+
+// digits returns the number of leading digits in base, up to max, in s.
+func digits(s string, base, max int) int {
+	n := 0
+	for n < len(s) && n < max {
+		if _, err := strconv.ParseUint(s[n:n+1], base, 8); err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// unescape interprets all backslash escapes in s, as done for %b.
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\\')
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		e, n, err := escape(s[i:], true)
+		b.WriteString(e)
+		if err != nil {
+			return b.String(), err
+		}
+		s = s[i+n:]
+	}
+	return b.String(), nil
+}
+
+// parseInt converts a numeric argument as described for printf(1). If a
+// is not completely converted, the value of its longest valid prefix is
+// returned along with the error.
+func parseInt(a string) (int64, error) {
+	s := strings.TrimLeft(a, " \t\n")
+	if len(s) > 0 && (s[0] == '\'' || s[0] == '"') {
+		if len(s) == 1 {
+			return 0, nil
+		}
+		r, _ := utf8.DecodeRuneInString(s[1:])
+		return int64(r), nil
+	}
+
+	if s == "" {
+		return 0, nil
+	}
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	base := 10
+	switch {
+	case len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X'):
+		base = 16
+		s = s[2:]
+	case len(s) > 0 && s[0] == '0':
+		base = 8
+	}
+
+	n := digits(s, base, len(s))
+	switch {
+	case n == 0 && base == 16:
+		// Like strtol, take "0x" without digits as 0 followed by junk.
+		return 0, fmt.Errorf("%q: value not completely converted", a)
+	case n == 0:
+		return 0, fmt.Errorf("%q: expected a numeric value", a)
+	}
+	v, err := strconv.ParseUint(s[:n], base, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %v", a, err.(*strconv.NumError).Err)
+	}
+	if n < len(s) {
+		err = fmt.Errorf("%q: value not completely converted", a)
+	}
+	if neg {
+		return -int64(v), err
+	}
+	return int64(v), err
+}
+
+func parseFloat(a string) (float64, error) {
+	s := strings.TrimSpace(a)
+	if len(s) > 0 && (s[0] == '\'' || s[0] == '"') {
+		i, err := parseInt(s)
+		return float64(i), err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return f, nil
+		}
+		return 0, fmt.Errorf("%q: expected a numeric value", a)
+	}
+	return f, nil
+}
+
+// shellQuote quotes s so that a POSIX shell reads it back as one word.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !strings.ContainsRune("@%_-+=:,./", c) && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// conversion prints one conversion specification from the start of f,
+// which begins after the '%'. It returns the number of bytes consumed.
+func (p *printer) conversion(f string) (int, error) {
+	i := 0
+	flags := ""
+	for i < len(f) && strings.IndexByte("-+ #0", f[i]) >= 0 {
+		flags += f[i : i+1]
+		i++
+	}
+
+	spec := "%" + flags
+	// Width and precision, either of which may come from an argument.
+	for _, part := range []string{"", "."} {
+		if part == "." {
+			if i >= len(f) || f[i] != '.' {
+				break
+			}
+			i++
+		}
+		spec += part
+		if i < len(f) && f[i] == '*' {
+			i++
+			a := p.next()
+			v, err := parseInt(a)
+			if err != nil {
+				p.errs = append(p.errs, err)
+			}
+			if v < 0 && part == "." {
+				// A negative precision is taken as if it were missing.
+				spec = strings.TrimSuffix(spec, ".")
+				continue
+			}
+			spec += strconv.FormatInt(v, 10)
+			continue
+		}
+		n := digits(f[i:], 10, len(f)-i)
+		spec += f[i : i+n]
+		i += n
+	}
+	// Length modifiers have no meaning here.
+	for i < len(f) && strings.IndexByte("hlLjzt", f[i]) >= 0 {
+		i++
+	}
+	if i >= len(f) {
+		return i, fmt.Errorf("%%%s: missing conversion specifier", f)
+	}
+
+	verb := f[i]
+	i++
+	switch verb {
+	case 'd', 'i':
+		v, err := parseInt(p.next())
+		if err != nil {
+			p.errs = append(p.errs, err)
+		}
+		fmt.Fprintf(p.w, spec+"d", v)
+	case 'o', 'u', 'x', 'X':
+		v, err := parseInt(p.next())
+		if err != nil {
+			p.errs = append(p.errs, err)
+		}
+		if verb == 'u' {
+			verb = 'd'
+		}
+		fmt.Fprintf(p.w, spec+string(verb), uint64(v))
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		v, err := parseFloat(p.next())
+		if err != nil {
+			p.errs = append(p.errs, err)
+		}
+		if verb == 'F' {
+			verb = 'f'
+		}
+		// Go's %g defaults to the shortest representation, C's to a
+		// precision of 6.
+		if (verb == 'g' || verb == 'G') && !strings.Contains(spec, ".") {
+			spec += ".6"
+		}
+		fmt.Fprintf(p.w, spec+string(verb), v)
+	case 'c':
+		a := p.next()
+		if len(a) > 0 {
+			a = a[:1]
+		}
+		fmt.Fprintf(p.w, withoutPrecision(spec)+"s", a)
+	case 's':
+		fmt.Fprintf(p.w, spec+"s", p.next())
+	case 'q':
+		fmt.Fprintf(p.w, spec+"s", shellQuote(p.next()))
+	case 'b':
+		s, err := unescape(p.next())
+		fmt.Fprintf(p.w, spec+"s", s)
+		if err != nil {
+			return i, err
+		}
+	default:
+		return i, fmt.Errorf("%%%s: invalid conversion specification", f[:i])
+	}
+	return i, nil
+}
+
+func withoutPrecision(spec string) string {
+	if i := strings.IndexByte(spec, '.'); i >= 0 {
+		return spec[:i]
+	}
+	return spec
+}
+
+// format makes one pass over the format string.
+func (p *printer) format(f string) error {
+	for len(f) > 0 {
+		i := strings.IndexAny(f, "\\%")
+		if i < 0 {
+			p.w.WriteString(f)
+			return nil
+		}
+		p.w.WriteString(f[:i])
+		f = f[i:]
+
+		if f[0] == '\\' {
+			e, n, err := escape(f, false)
+			p.w.WriteString(e)
+			if err != nil {
+				return err
+			}
+			f = f[n:]
+			continue
+		}
+
+		if len(f) > 1 && f[1] == '%' {
+			p.w.WriteByte('%')
+			f = f[2:]
+			continue
+		}
+		n, err := p.conversion(f[1:])
+		if err != nil {
+			return err
+		}
+		f = f[1+n:]
+	}
+	return nil
+}
+
+// printf writes format to w, reusing it as long as there are arguments
+// left. It returns the conversion errors, which do not stop the output,
+// and an error that did.
+func printf(w io.Writer, format string, args []string) ([]error, error) {
+	p := &printer{w: bufio.NewWriter(w), args: args}
+	defer p.w.Flush()
+	for {
+		p.used = 0
+		if err := p.format(format); err != nil {
+			if err == errStop {
+				err = nil
+			}
+			return p.errs, err
+		}
+		if p.used == 0 || len(p.args) == 0 {
+			return p.errs, nil
+		}
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("printf: ")
+	if len(os.Args) < 2 {
+		log.Fatal("missing operand")
+	}
+
+	errs, err := printf(os.Stdout, os.Args[1], os.Args[2:])
+	for _, e := range errs {
+		log.Print(e)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestPrintf(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		args   []string
+		out    string
+		errs   int
+		err    bool
+	}{
+		{format: `hello\n`, out: "hello\n"},
+		{format: `%d %i %o %u %x %X\n`, args: []string{"10", "-3", "8", "7", "255", "255"}, out: "10 -3 10 7 ff FF\n"},
+		{format: `%d %d %d\n`, args: []string{"0x1f", "010", "'A"}, out: "31 8 65\n"},
+		{format: `%u\n`, args: []string{"-1"}, out: "18446744073709551615\n"},
+		{format: `%5d|%-5d|%05d|%+d|% d\n`, args: []string{"42", "42", "42", "42", "42"}, out: "   42|42   |00042|+42| 42\n"},
+		{format: `%.3d|%#o|%#x\n`, args: []string{"7", "8", "255"}, out: "007|010|0xff\n"},
+		{format: `%*d|%-*d|%.*f\n`, args: []string{"4", "1", "3", "2", "2", "3.14159"}, out: "   1|2  |3.14\n"},
+		{format: `%f %.2f %e %g %g %G\n`, args: []string{"1.5", "2.345", "1234.5", "0.0001", "1234567", "1e-10"}, out: "1.500000 2.35 1.234500e+03 0.0001 1.23457e+06 1E-10\n"},
+		{format: `%c%c%c\n`, args: []string{"abc", "d", ""}, out: "ad\n"},
+		{format: `[%5s][%-5s][%.2s]\n`, args: []string{"ab", "ab", "abcd"}, out: "[   ab][ab   ][ab]\n"},
+		{format: `%s\n`, args: []string{"a", "b", "c"}, out: "a\nb\nc\n"},
+		{format: `%s=%s\n`, args: []string{"a", "1", "b"}, out: "a=1\nb=\n"},
+		{format: `%s %d|`, out: " 0|"},
+		{format: `no conversions\n`, args: []string{"a", "b"}, out: "no conversions\n"},
+		{format: `%%|%s\n`, args: []string{"x"}, out: "%|x\n"},
+		{format: `\x41\x4a\x4B\101\0\t|é\n`, out: "AJKA\x00\t|é\n"},
+		{format: `\1010`, out: "A0"},
+		{format: `%b|%s\n`, args: []string{`a\tb\0101\101`, `a\tb`}, out: "a\tbAA|a\\tb\n"},
+		{format: `%b`, args: []string{`one\ctwo`, "three"}, out: "one"},
+		{format: `a\cb`, out: "a"},
+		{format: `\q\\`, out: `\q\`},
+		{format: `%q %q %q %q\n`, args: []string{"abc", "a b", "it's", ""}, out: `abc 'a b' 'it'\''s' ''` + "\n"},
+		{format: `%d|%d\n`, args: []string{"abc", "12abc"}, out: "0|12\n", errs: 2},
+		{format: `%f\n`, args: []string{"x"}, out: "0.000000\n", errs: 1},
+		{format: `%z`, err: true},
+		{format: `%5`, err: true},
+		{format: `\x`, err: true},
+	} {
+		var out bytes.Buffer
+		errs, err := printf(&out, tt.format, tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("printf(%q, %q): got err %v, want err %v", tt.format, tt.args, err, tt.err)
+		}
+		if len(errs) != tt.errs {
+			t.Errorf("printf(%q, %q): got errs %v, want %d errors", tt.format, tt.args, errs, tt.errs)
+		}
+		if out.String() != tt.out {
+			t.Errorf("printf(%q, %q) = %q, want %q", tt.format, tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestPrintfCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{args: []string{`%s-%s\n`, "a", "b"}, out: "a-b\n"},
+		{args: []string{`%d\n`, "x"}, out: "0\n", code: 1},
+		{code: 1},
+	} {
+		c := testutil.Command(t, tt.args...)
+		var out bytes.Buffer
+		c.Stdout = &out
+		if err := testutil.IsExitCode(c.Run(), tt.code); err != nil {
+			t.Errorf("printf %q: %v", tt.args, err)
+		}
+		if out.String() != tt.out {
+			t.Errorf("printf %q: got %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
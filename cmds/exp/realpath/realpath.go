@@ -0,0 +1,113 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// realpath prints the resolved absolute path of each FILE.
+//
+// Synopsis:
+//     realpath [OPTIONS] FILE...
+//
+// Description:
+//     By default, all but the last component of each FILE must exist.
+//     Symlinks are resolved one component at a time and chains of more
+//     than 40 links are reported as loops.
+//
+// Options:
+//     -e, --canonicalize-existing: all components of the path must exist
+//     -m, --canonicalize-missing:  no path components need exist
+//     -s, --no-symlinks:           do not expand symlinks
+//     -q, --quiet:                 suppress most error messages
+//     -z, --zero:                  end each output line with NUL, not newline
+//         --relative-to=DIR:       print the resolved path relative to DIR
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/upath"
+)
+
+var (
+	existing   = flag.BoolP("canonicalize-existing", "e", false, "all components of the path must exist")
+	missing    = flag.BoolP("canonicalize-missing", "m", false, "no path components need exist")
+	noSymlinks = flag.BoolP("no-symlinks", "s", false, "do not expand symlinks")
+	quiet      = flag.BoolP("quiet", "q", false, "suppress most error messages")
+	zero       = flag.BoolP("zero", "z", false, "end each output line with NUL, not newline")
+	relativeTo = flag.String("relative-to", "", "print the resolved path relative to DIR")
+)
+
+type options struct {
+	mode       upath.Mode
+	follow     bool
+	relativeTo string
+	end        string
+}
+
+func realpath(o options, name string) (string, error) {
+	p, err := upath.Canonicalize(name, o.mode, o.follow)
+	if err != nil {
+		return "", err
+	}
+	if o.relativeTo == "" {
+		return p, nil
+	}
+	return filepath.Rel(o.relativeTo, p)
+}
+
+// run prints the resolved path of each name to w. It keeps going after an
+// error and reports whether all names were resolved.
+func run(w io.Writer, o options, names []string) bool {
+	ok := true
+	for _, n := range names {
+		p, err := realpath(o, n)
+		if err != nil {
+			if !*quiet {
+				log.Printf("%s: %v", n, err)
+			}
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "%s%s", p, o.end)
+	}
+	return ok
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("realpath: ")
+
+	if flag.NArg() == 0 {
+		log.Fatal("missing operand")
+	}
+	if *existing && *missing {
+		log.Fatal("-e and -m are mutually exclusive")
+	}
+
+	o := options{mode: upath.AllButLast, follow: !*noSymlinks, end: "\n"}
+	switch {
+	case *existing:
+		o.mode = upath.Existing
+	case *missing:
+		o.mode = upath.Missing
+	}
+	if *zero {
+		o.end = "\x00"
+	}
+	if *relativeTo != "" {
+		d, err := upath.Canonicalize(*relativeTo, o.mode, o.follow)
+		if err != nil {
+			log.Fatal(err)
+		}
+		o.relativeTo = d
+	}
+
+	if !run(os.Stdout, o, flag.Args()) {
+		os.Exit(1)
+	}
+}
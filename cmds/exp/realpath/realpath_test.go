@@ -0,0 +1,67 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func TestRealpath(t *testing.T) {
+	td, err := ioutil.TempDir("", "realpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	if td, err = filepath.EvalSymlinks(td); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(td, "a/b"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a/b", filepath.Join(td, "l")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		out  string
+		err  bool
+	}{
+		{args: []string{"l"}, out: td + "/a/b\n"},
+		{args: []string{"l", "a"}, out: td + "/a/b\n" + td + "/a\n"},
+		{args: []string{"-s", "l"}, out: td + "/l\n"},
+		{args: []string{"-z", "l"}, out: td + "/a/b\x00"},
+		{args: []string{"new"}, out: td + "/new\n"},
+		{args: []string{"-e", "new"}, err: true},
+		{args: []string{"new/x"}, err: true},
+		{args: []string{"-m", "new/x"}, out: td + "/new/x\n"},
+		{args: []string{"--relative-to=a", "l"}, out: "b\n"},
+		{args: []string{"--relative-to=l/..", "a/b/.."}, out: ".\n"},
+		{args: []string{"--relative-to=a/b", "."}, out: "../..\n"},
+		{args: []string{"-e", "-m", "l"}, err: true},
+	} {
+		c := testutil.Command(t, tt.args...)
+		c.Dir = td
+		var out bytes.Buffer
+		c.Stdout = &out
+		err := c.Run()
+		if (err != nil) != tt.err {
+			t.Errorf("realpath %v: got err %v, want err %v", tt.args, err, tt.err)
+		}
+		if out.String() != tt.out {
+			t.Errorf("realpath %v: got %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
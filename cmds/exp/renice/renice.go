@@ -0,0 +1,169 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// renice changes the scheduling priority of running processes.
+//
+// Synopsis:
+//     renice PRIORITY [[-p] PID...] [-g PGRP...] [-u USER...]
+//     renice -n INCREMENT [[-p] PID...] [-g PGRP...] [-u USER...]
+//
+// Description:
+//     Set the niceness of the processes, process groups or all processes
+//     of the users to PRIORITY, or add INCREMENT to it. Nicenesses range
+//     from -20, the highest priority, to 19, the lowest, and only root may
+//     lower them. -p, -g and -u change how the IDs that follow are read;
+//     IDs are process IDs by default.
+//
+// Options:
+//     -n: add INCREMENT to the niceness instead of setting it
+//     -p: the following IDs are process IDs
+//     -g: the following IDs are process group IDs
+//     -u: the following IDs are user names or IDs
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const usage = "usage: renice [-n] PRIORITY [[-p] PID...] [-g PGRP...] [-u USER...]"
+
+// Bounds of the niceness.
+const (
+	minNice = -20
+	maxNice = 19
+)
+
+// A target is the process, process group or user whose niceness is changed.
+type target struct {
+	which int
+	who   int
+	arg   string
+}
+
+var whichNames = map[int]string{
+	unix.PRIO_PROCESS: "process ID",
+	unix.PRIO_PGRP:    "process group ID",
+	unix.PRIO_USER:    "user ID",
+}
+
+// Overridden in tests.
+var (
+	getpriority = unix.Getpriority
+	setpriority = unix.Setpriority
+	lookupUser  = user.Lookup
+)
+
+// parseArgs returns the priority, whether it is an increment, and the
+// targets of args.
+func parseArgs(args []string) (int, bool, []target, error) {
+	relative := false
+	if len(args) > 0 && args[0] == "-n" {
+		relative = true
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return 0, false, nil, errors.New(usage)
+	}
+	prio, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, false, nil, fmt.Errorf("invalid priority %q", args[0])
+	}
+
+	which := unix.PRIO_PROCESS
+	var targets []target
+	for _, a := range args[1:] {
+		switch a {
+		case "-p":
+			which = unix.PRIO_PROCESS
+			continue
+		case "-g":
+			which = unix.PRIO_PGRP
+			continue
+		case "-u":
+			which = unix.PRIO_USER
+			continue
+		}
+		id, err := strconv.Atoi(a)
+		if err != nil && which == unix.PRIO_USER {
+			u, lerr := lookupUser(a)
+			if lerr != nil {
+				return 0, false, nil, lerr
+			}
+			id, err = strconv.Atoi(u.Uid)
+		}
+		if err != nil || id < 0 {
+			return 0, false, nil, fmt.Errorf("invalid %s %q", whichNames[which], a)
+		}
+		targets = append(targets, target{which: which, who: id, arg: a})
+	}
+	if len(targets) == 0 {
+		return 0, false, nil, errors.New(usage)
+	}
+	return prio, relative, targets, nil
+}
+
+// clamp limits n to the range of nicenesses.
+func clamp(n int) int {
+	if n < minNice {
+		return minNice
+	}
+	if n > maxNice {
+		return maxNice
+	}
+	return n
+}
+
+// renice changes the niceness of t and prints the old and new ones. The raw
+// system call returns 20 - niceness, so that it is never negative.
+func renice(w io.Writer, t target, prio int, relative bool) error {
+	raw, err := getpriority(t.which, t.who)
+	if err != nil {
+		return fmt.Errorf("failed to get priority of %s (%s): %v", t.arg, whichNames[t.which], err)
+	}
+	old := 20 - raw
+	n := prio
+	if relative {
+		n += old
+	}
+	n = clamp(n)
+	if err := setpriority(t.which, t.who, n); err != nil {
+		return fmt.Errorf("failed to set priority of %s (%s): %v", t.arg, whichNames[t.which], err)
+	}
+	fmt.Fprintf(w, "%s (%s) old priority %d, new priority %d\n", t.arg, whichNames[t.which], old, n)
+	return nil
+}
+
+func run(args []string, stdout io.Writer) error {
+	prio, relative, targets, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	var failed bool
+	for _, t := range targets {
+		if err := renice(stdout, t, prio, relative); err != nil {
+			log.Print(err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some priorities could not be changed")
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("renice: ")
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os/user"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseArgs(t *testing.T) {
+	defer func(f func(string) (*user.User, error)) { lookupUser = f }(lookupUser)
+	lookupUser = func(name string) (*user.User, error) {
+		if name == "daemon" {
+			return &user.User{Uid: "1"}, nil
+		}
+		return nil, errors.New("unknown user")
+	}
+
+	for _, tt := range []struct {
+		args     []string
+		prio     int
+		relative bool
+		targets  []target
+		err      bool
+	}{
+		{
+			args:    []string{"5", "100", "101"},
+			prio:    5,
+			targets: []target{{unix.PRIO_PROCESS, 100, "100"}, {unix.PRIO_PROCESS, 101, "101"}},
+		},
+		{
+			args:     []string{"-n", "-2", "-g", "7", "-u", "daemon", "0", "-p", "9"},
+			prio:     -2,
+			relative: true,
+			targets: []target{
+				{unix.PRIO_PGRP, 7, "7"},
+				{unix.PRIO_USER, 1, "daemon"},
+				{unix.PRIO_USER, 0, "0"},
+				{unix.PRIO_PROCESS, 9, "9"},
+			},
+		},
+		{args: nil, err: true},
+		{args: []string{"5"}, err: true},
+		{args: []string{"5", "-p"}, err: true},
+		{args: []string{"x", "100"}, err: true},
+		{args: []string{"5", "init"}, err: true},
+		{args: []string{"5", "-u", "nobody-here"}, err: true},
+		{args: []string{"5", "-1"}, err: true},
+	} {
+		prio, relative, targets, err := parseArgs(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("parseArgs(%q) = %v, want error %t", tt.args, err, tt.err)
+			continue
+		}
+		if prio != tt.prio || relative != tt.relative || !reflect.DeepEqual(targets, tt.targets) {
+			t.Errorf("parseArgs(%q) = %d, %t, %v, want %d, %t, %v", tt.args, prio, relative, targets, tt.prio, tt.relative, tt.targets)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	defer func(g func(int, int) (int, error), s func(int, int, int) error) {
+		getpriority, setpriority = g, s
+	}(getpriority, setpriority)
+	nice := map[int]int{100: 0, 101: 15}
+	getpriority = func(which, who int) (int, error) {
+		n, ok := nice[who]
+		if !ok {
+			return 0, unix.ESRCH
+		}
+		return 20 - n, nil
+	}
+	setpriority = func(which, who, prio int) error {
+		nice[who] = prio
+		return nil
+	}
+
+	var b bytes.Buffer
+	if err := run([]string{"-n", "10", "100", "101"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	want := "100 (process ID) old priority 0, new priority 10\n101 (process ID) old priority 15, new priority 19\n"
+	if b.String() != want {
+		t.Errorf("run() printed %q, want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := run([]string{"-30", "-g", "100", "102"}, &b); err == nil {
+		t.Error("run() with a missing process = nil, want error")
+	}
+	if want := "100 (process group ID) old priority 10, new priority -20\n"; b.String() != want {
+		t.Errorf("run() printed %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// address selects lines: a line number, the last line or the lines
+// matching a regular expression.
+type address struct {
+	line int
+	last bool
+	// re is nil for an empty regular expression, which is the last one
+	// used.
+	re   *regexp.Regexp
+	isRE bool
+}
+
+// replacement is a part of the replacement of s: literal text, or the
+// text matched by group, where 0 is the whole match.
+type replacement struct {
+	literal string
+	group   int
+}
+
+// command is a sed command with its addresses.
+type command struct {
+	addr1, addr2 *address
+	negate       bool
+	name         byte
+
+	// The regular expression of s, nil for the last one used.
+	re   *regexp.Regexp
+	repl []replacement
+	// global replaces all matches from the nth one on, instead of only
+	// the nth one.
+	global bool
+	nth    int
+	print  bool
+
+	// active is set while in the range addr1,addr2.
+	active bool
+}
+
+// parser parses a sed script.
+type parser struct {
+	s        string
+	i        int
+	extended bool
+}
+
+func (p *parser) eof() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("-e expression char %d: %s", p.i+1, fmt.Sprintf(format, a...))
+}
+
+// parseScript parses the commands of script, using extended regular
+// expressions if extended is set.
+func parseScript(script string, extended bool) ([]*command, error) {
+	p := &parser{s: script, extended: extended}
+	var cmds []*command
+	for {
+		for !p.eof() && strings.IndexByte(" \t\n;", p.peek()) >= 0 {
+			p.i++
+		}
+		if p.eof() {
+			return cmds, nil
+		}
+		if p.peek() == '#' {
+			for !p.eof() && p.peek() != '\n' {
+				p.i++
+			}
+			continue
+		}
+		c, err := p.command()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, c)
+	}
+}
+
+func (p *parser) command() (*command, error) {
+	c := &command{}
+	var err error
+	if c.addr1, err = p.address(); err != nil {
+		return nil, err
+	}
+	if c.addr1 != nil && p.peek() == ',' {
+		p.i++
+		p.skipSpace()
+		if c.addr2, err = p.address(); err != nil {
+			return nil, err
+		}
+		if c.addr2 == nil {
+			return nil, p.errorf("unexpected `,'")
+		}
+	}
+	p.skipSpace()
+	if p.peek() == '!' {
+		c.negate = true
+		p.i++
+		p.skipSpace()
+	}
+	if p.eof() {
+		return nil, p.errorf("missing command")
+	}
+	c.name = p.s[p.i]
+	p.i++
+	switch c.name {
+	case 'd', 'p', '=':
+	case 'q':
+		if c.addr2 != nil {
+			return nil, p.errorf("command only uses one address")
+		}
+	case 's':
+		if err := p.substitute(c); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf("unknown command: `%c'", c.name)
+	}
+	p.skipSpace()
+	if !p.eof() && strings.IndexByte(";\n#", p.peek()) < 0 {
+		return nil, p.errorf("extra characters after command")
+	}
+	return c, nil
+}
+
+// address parses an address, if there is one.
+func (p *parser) address() (*address, error) {
+	switch ch := p.peek(); {
+	case ch >= '0' && ch <= '9':
+		start := p.i
+		for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+			p.i++
+		}
+		n, err := strconv.Atoi(p.s[start:p.i])
+		if err != nil || n == 0 {
+			return nil, p.errorf("invalid usage of line address %s", p.s[start:p.i])
+		}
+		return &address{line: n}, nil
+	case ch == '$':
+		p.i++
+		return &address{last: true}, nil
+	case ch == '/' || ch == '\\':
+		if ch == '\\' {
+			p.i++
+			if p.eof() {
+				return nil, p.errorf("unexpected end of script")
+			}
+		}
+		delim := p.s[p.i]
+		p.i++
+		pat, err := p.delimited(delim)
+		if err != nil {
+			return nil, err
+		}
+		var icase bool
+		if p.peek() == 'I' {
+			icase = true
+			p.i++
+		}
+		re, err := compileRE(pat, p.extended, icase)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		return &address{re: re, isRE: true}, nil
+	}
+	return nil, nil
+}
+
+// delimited returns the text up to the unescaped delim, and skips delim.
+// An escaped delim is replaced by delim, other escapes are kept.
+func (p *parser) delimited(delim byte) (string, error) {
+	var b strings.Builder
+	for !p.eof() {
+		ch := p.s[p.i]
+		p.i++
+		switch {
+		case ch == delim:
+			return b.String(), nil
+		case ch == '\\' && !p.eof():
+			if p.s[p.i] == delim {
+				b.WriteByte(delim)
+			} else {
+				b.WriteByte('\\')
+				b.WriteByte(p.s[p.i])
+			}
+			p.i++
+		case ch == '\n' && delim != '\n':
+			return "", p.errorf("unterminated address regex")
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", p.errorf("unterminated `s' command")
+}
+
+// substitute parses the rest of an s command.
+func (p *parser) substitute(c *command) error {
+	if p.eof() || p.peek() == '\n' || p.peek() == '\\' {
+		return p.errorf("unterminated `s' command")
+	}
+	delim := p.s[p.i]
+	p.i++
+	pat, err := p.delimited(delim)
+	if err != nil {
+		return err
+	}
+	repl, err := p.delimited(delim)
+	if err != nil {
+		return err
+	}
+	c.repl = parseReplacement(repl)
+
+	var icase bool
+flags:
+	for !p.eof() {
+		switch ch := p.peek(); {
+		case ch == 'g':
+			c.global = true
+		case ch == 'p':
+			c.print = true
+		case ch == 'i' || ch == 'I':
+			icase = true
+		case ch >= '0' && ch <= '9':
+			start := p.i
+			for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+				p.i++
+			}
+			n, err := strconv.Atoi(p.s[start:p.i])
+			if err != nil || n == 0 {
+				return p.errorf("number option to `s' command may not be zero")
+			}
+			c.nth = n
+			continue
+		case strings.IndexByte(" \t\n;#", ch) >= 0:
+			break flags
+		default:
+			return p.errorf("unknown option to `s'")
+		}
+		p.i++
+	}
+	if c.nth == 0 {
+		c.nth = 1
+	}
+	if c.re, err = compileRE(pat, p.extended, icase); err != nil {
+		return p.errorf("%v", err)
+	}
+	if c.re != nil {
+		for _, r := range c.repl {
+			if r.group > c.re.NumSubexp() {
+				return p.errorf("invalid reference \\%d on `s' command's RHS", r.group)
+			}
+		}
+	}
+	return nil
+}
+
+// parseReplacement splits the replacement of s into literal text, & for
+// the whole match and \1 to \9 for groups. \n is a newline, and other
+// escaped characters stand for themselves.
+func parseReplacement(s string) []replacement {
+	var parts []replacement
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, replacement{literal: lit.String(), group: -1})
+			lit.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '&':
+			flush()
+			parts = append(parts, replacement{group: 0})
+		case ch == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; {
+			case e >= '1' && e <= '9':
+				flush()
+				parts = append(parts, replacement{group: int(e - '0')})
+			case e == 'n':
+				lit.WriteByte('\n')
+			case e == 't':
+				lit.WriteByte('\t')
+			default:
+				lit.WriteByte(e)
+			}
+		default:
+			lit.WriteByte(ch)
+		}
+	}
+	flush()
+	return parts
+}
+
+// compileRE compiles the regular expression pat, which is a POSIX basic
+// regular expression unless extended is set, in which case it uses the
+// syntax of the regexp package. It returns nil for an empty pat, which
+// stands for the last regular expression used.
+func compileRE(pat string, extended, icase bool) (*regexp.Regexp, error) {
+	if pat == "" {
+		if icase {
+			return nil, fmt.Errorf("no previous regular expression")
+		}
+		return nil, nil
+	}
+	if !extended {
+		pat = convertBRE(pat)
+	}
+	if icase {
+		pat = "(?i)" + pat
+	}
+	return regexp.Compile(pat)
+}
+
+// convertBRE converts a basic regular expression to the syntax of the
+// regexp package: \( \) \{ \} \+ \? and \| are special, and ( ) { } + ?
+// and | are not.
+func convertBRE(pat string) string {
+	var b strings.Builder
+	for i := 0; i < len(pat); i++ {
+		switch ch := pat[i]; ch {
+		case '\\':
+			if i+1 == len(pat) {
+				b.WriteString(`\\`)
+				break
+			}
+			i++
+			if strings.IndexByte("(){}+?|", pat[i]) >= 0 {
+				b.WriteByte(pat[i])
+			} else {
+				b.WriteByte('\\')
+				b.WriteByte(pat[i])
+			}
+		case '(', ')', '{', '}', '+', '?', '|':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '*':
+			// A leading * is literal.
+			if s := b.String(); s == "" || s == "^" || strings.HasSuffix(s, "(") && !strings.HasSuffix(s, `\(`) {
+				b.WriteString(`\*`)
+			} else {
+				b.WriteByte('*')
+			}
+		case '[':
+			// Copy the bracket expression, in which backslashes are
+			// literal.
+			j := i + 1
+			if j < len(pat) && pat[j] == '^' {
+				j++
+			}
+			if j < len(pat) && pat[j] == ']' {
+				j++
+			}
+			// Classes such as [:alpha:] may contain ].
+			for j < len(pat) && pat[j] != ']' {
+				if pat[j] == '[' && j+1 < len(pat) && strings.IndexByte(":.=", pat[j+1]) >= 0 {
+					if k := strings.Index(pat[j+2:], string(pat[j+1])+"]"); k >= 0 {
+						j += k + 4
+						continue
+					}
+				}
+				j++
+			}
+			if j == len(pat) {
+				b.WriteString(pat[i:])
+				return b.String()
+			}
+			// A leading ] is literal, which it is not for regexp.
+			b.WriteByte('[')
+			k := i + 1
+			if pat[k] == '^' {
+				b.WriteByte('^')
+				k++
+			}
+			if pat[k] == ']' && k < j {
+				b.WriteString(`\]`)
+				k++
+			}
+			b.WriteString(strings.Replace(pat[k:j+1], `\`, `\\`, -1))
+			i = j
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,454 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// sed is a stream editor.
+//
+// Synopsis:
+//     sed [-nE] [-i[SUFFIX]] SCRIPT [FILE]...
+//     sed [-nE] [-i[SUFFIX]] [-e SCRIPT]... [-f SCRIPTFILE]... [FILE]...
+//
+// Description:
+//     sed reads the FILEs, or stdin, line by line, runs the commands of the
+//     script on each line and prints the result. Commands are separated by
+//     newlines or semicolons, and have zero, one or two addresses:
+//
+//     N:             line N
+//     $:             the last line
+//     /RE/, \cREc:   lines matching RE, I after the address ignores case
+//     ADDR1,ADDR2:   the lines from ADDR1 to ADDR2
+//     ADDR!:         the lines not selected by ADDR
+//
+//     The commands are:
+//
+//     s/RE/REPL/FLAGS: replace the first match of RE by REPL, in which &
+//                      is the match, \1 to \9 are groups and \n is a
+//                      newline. FLAGS are g to replace all matches, N to
+//                      replace the Nth, p to print the line if it changed
+//                      and i to ignore case.
+//     d:               delete the line and start the next one
+//     p:               print the line
+//     q:               print the line and quit
+//     =:               print the line number
+//
+//     REs are POSIX basic regular expressions, or with -E, the extended
+//     ones of Go's regexp package. An empty RE is the last RE used.
+//
+// Options:
+//     -e: add SCRIPT to the commands
+//     -E: use extended regular expressions
+//     -f: add the contents of SCRIPTFILE to the commands
+//     -i: edit the files in place, saving the originals with SUFFIX if
+//         given; * in SUFFIX is replaced by the file name
+//     -n: only print lines with p, or s///p
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	quiet       = flag.BoolP("quiet", "n", false, "only print lines with p, or s///p")
+	expressions = flag.StringArrayP("expression", "e", nil, "add SCRIPT to the commands")
+	scriptFiles = flag.StringArrayP("file", "f", nil, "add the contents of SCRIPTFILE to the commands")
+	extended    = flag.BoolP("regexp-extended", "E", false, "use extended regular expressions")
+	inPlace     = flag.StringP("in-place", "i", "", "edit the files in place, saving the originals with SUFFIX if given")
+)
+
+func init() {
+	flag.Lookup("in-place").NoOptDefVal = " "
+}
+
+// noBackup is the value of -i without a SUFFIX.
+const noBackup = " "
+
+// editor runs sed commands.
+type editor struct {
+	cmds  []*command
+	quiet bool
+	// lastRE is the last regular expression used.
+	lastRE *regexp.Regexp
+	line   int
+}
+
+// lineReader reads the lines of a sequence of readers.
+type lineReader struct {
+	readers []io.Reader
+	br      *bufio.Reader
+}
+
+// read returns the next line without its newline, and whether it had one.
+func (l *lineReader) read() (string, bool, bool, error) {
+	for {
+		if l.br == nil {
+			if len(l.readers) == 0 {
+				return "", false, false, nil
+			}
+			l.br = bufio.NewReader(l.readers[0])
+			l.readers = l.readers[1:]
+		}
+		line, err := l.br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", false, false, err
+		}
+		if len(line) > 0 {
+			nl := strings.HasSuffix(line, "\n")
+			return strings.TrimSuffix(line, "\n"), nl, true, nil
+		}
+		l.br = nil
+	}
+}
+
+// reset starts a new input, with line numbers starting at 1 and no active
+// ranges.
+func (e *editor) reset() {
+	e.line = 0
+	for _, c := range e.cmds {
+		c.active = false
+	}
+}
+
+// regexp returns re, or the last regular expression used if it is nil, and
+// makes it the last one used.
+func (e *editor) regexp(re *regexp.Regexp) (*regexp.Regexp, error) {
+	if re == nil {
+		if e.lastRE == nil {
+			return nil, errors.New("no previous regular expression")
+		}
+		return e.lastRE, nil
+	}
+	e.lastRE = re
+	return re, nil
+}
+
+func (e *editor) matchAddr(a *address, ps string, last bool) (bool, error) {
+	switch {
+	case a.last:
+		return last, nil
+	case a.isRE:
+		re, err := e.regexp(a.re)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(ps), nil
+	}
+	return e.line == a.line, nil
+}
+
+// selects returns whether c applies to the pattern space ps.
+func (e *editor) selects(c *command, ps string, last bool) (bool, error) {
+	sel, err := e.selectsRange(c, ps, last)
+	return sel != c.negate, err
+}
+
+func (e *editor) selectsRange(c *command, ps string, last bool) (bool, error) {
+	if c.addr1 == nil {
+		return true, nil
+	}
+	if !c.active {
+		m, err := e.matchAddr(c.addr1, ps, last)
+		if !m || err != nil || c.addr2 == nil {
+			return m, err
+		}
+		// A line number addr2 at or before addr1 only selects one line.
+		a2 := c.addr2
+		c.active = !(a2.line > 0 && a2.line <= e.line) && !(a2.last && last)
+		return true, nil
+	}
+	m, err := e.matchAddr(c.addr2, ps, last)
+	if err != nil {
+		return false, err
+	}
+	if m || (c.addr2.line > 0 && e.line >= c.addr2.line) {
+		c.active = false
+	}
+	return true, nil
+}
+
+// substitute runs the s command c on ps, and returns the result and
+// whether anything was replaced.
+func (e *editor) substitute(c *command, ps string) (string, bool, error) {
+	re, err := e.regexp(c.re)
+	if err != nil {
+		return "", false, err
+	}
+	var b strings.Builder
+	prev := 0
+	replaced := false
+	for i, m := range re.FindAllStringSubmatchIndex(ps, -1) {
+		if n := i + 1; n < c.nth || (n > c.nth && !c.global) {
+			continue
+		}
+		b.WriteString(ps[prev:m[0]])
+		for _, r := range c.repl {
+			switch {
+			case r.group < 0:
+				b.WriteString(r.literal)
+			case 2*r.group >= len(m):
+				return "", false, fmt.Errorf("invalid reference \\%d on `s' command's RHS", r.group)
+			case m[2*r.group] >= 0:
+				b.WriteString(ps[m[2*r.group]:m[2*r.group+1]])
+			}
+		}
+		prev = m[1]
+		replaced = true
+	}
+	if !replaced {
+		return ps, false, nil
+	}
+	b.WriteString(ps[prev:])
+	return b.String(), true, nil
+}
+
+// process runs the commands on each line of r, writing the result to w.
+// It returns whether a q command was run.
+func (e *editor) process(r *lineReader, w io.Writer) (bool, error) {
+	line, nl, ok, err := r.read()
+	for ok && err == nil {
+		next, nextNL, nextOK, nextErr := r.read()
+		if nextErr != nil {
+			return false, nextErr
+		}
+		last := !nextOK
+		e.line++
+
+		ps, print, quit, err := e.cycle(line, last, w)
+		if err != nil {
+			return false, fmt.Errorf("line %d: %v", e.line, err)
+		}
+		if print && !e.quiet {
+			if _, err := io.WriteString(w, ps); err != nil {
+				return false, err
+			}
+			// Only the last line may lack a newline.
+			if nl || !last {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return false, err
+				}
+			}
+		}
+		if quit {
+			return true, nil
+		}
+		line, nl, ok = next, nextNL, nextOK
+	}
+	return false, err
+}
+
+// cycle runs the commands on a line, and returns the pattern space, whether
+// it is to be printed and whether to quit.
+func (e *editor) cycle(ps string, last bool, w io.Writer) (string, bool, bool, error) {
+	for _, c := range e.cmds {
+		sel, err := e.selects(c, ps, last)
+		if err != nil {
+			return "", false, false, err
+		}
+		if !sel {
+			continue
+		}
+		switch c.name {
+		case 'd':
+			return "", false, false, nil
+		case 'p':
+			if _, err := fmt.Fprintln(w, ps); err != nil {
+				return "", false, false, err
+			}
+		case '=':
+			if _, err := fmt.Fprintln(w, e.line); err != nil {
+				return "", false, false, err
+			}
+		case 'q':
+			return ps, true, true, nil
+		case 's':
+			var changed bool
+			if ps, changed, err = e.substitute(c, ps); err != nil {
+				return "", false, false, err
+			}
+			if changed && c.print {
+				if _, err := fmt.Fprintln(w, ps); err != nil {
+					return "", false, false, err
+				}
+			}
+		}
+	}
+	return ps, true, false, nil
+}
+
+// backupName returns the name of the backup of file for -i with suffix.
+func backupName(file, suffix string) string {
+	if !strings.Contains(suffix, "*") {
+		return file + suffix
+	}
+	b := strings.Replace(suffix, "*", filepath.Base(file), -1)
+	if strings.Contains(b, "/") {
+		return b
+	}
+	return filepath.Join(filepath.Dir(file), b)
+}
+
+// editFile edits file in place, keeping the original as a backup if suffix
+// is not noBackup. It returns whether a q command was run.
+func (e *editor) editFile(file, suffix string) (bool, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	if !fi.Mode().IsRegular() {
+		return false, fmt.Errorf("couldn't edit %s: not a regular file", file)
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	out, err := ioutil.TempFile(filepath.Dir(file), "sed")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	bw := bufio.NewWriter(out)
+	e.reset()
+	quit, err := e.process(&lineReader{readers: []io.Reader{in}}, bw)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return false, err
+	}
+	if err := out.Chmod(fi.Mode()); err != nil {
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		return false, err
+	}
+	if suffix != noBackup {
+		if err := os.Rename(file, backupName(file, suffix)); err != nil {
+			return false, err
+		}
+	}
+	return quit, os.Rename(out.Name(), file)
+}
+
+// script returns the script of the -e and -f flags, or the first of args,
+// and the remaining args.
+func script(args []string) (string, []string, error) {
+	var parts []string
+	for _, e := range *expressions {
+		parts = append(parts, e)
+	}
+	for _, f := range *scriptFiles {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, strings.TrimSuffix(string(b), "\n"))
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "\n"), args, nil
+	}
+	if len(args) == 0 {
+		return "", nil, errors.New("usage: sed [-nE] [-i[SUFFIX]] [-e SCRIPT]... [-f SCRIPTFILE]... [SCRIPT] [FILE]...")
+	}
+	return args[0], args[1:], nil
+}
+
+// inPlaceArgs rewrites -iSUFFIX, which pflag does not parse for a flag with
+// an optional value, to --in-place=SUFFIX.
+func inPlaceArgs(args []string) []string {
+	var out []string
+	for i, a := range args {
+		if a == "--" {
+			return append(out, args[i:]...)
+		}
+		if strings.HasPrefix(a, "-i") && len(a) > 2 && a[2] != '=' {
+			a = "--in-place=" + a[2:]
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	s, files, err := script(args)
+	if err != nil {
+		return err
+	}
+	cmds, err := parseScript(s, *extended)
+	if err != nil {
+		return err
+	}
+	e := &editor{cmds: cmds, quiet: *quiet}
+
+	if *inPlace != "" {
+		if len(files) == 0 {
+			return errors.New("no input files")
+		}
+		for _, f := range files {
+			quit, err := e.editFile(f, *inPlace)
+			if err != nil {
+				return err
+			}
+			if quit {
+				break
+			}
+		}
+		return nil
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var readers []io.Reader
+	var failed bool
+	for _, f := range files {
+		if f == "-" {
+			readers = append(readers, stdin)
+			continue
+		}
+		fd, err := os.Open(f)
+		if err != nil {
+			log.Printf("can't read %s: %v", f, err)
+			failed = true
+			continue
+		}
+		defer fd.Close()
+		readers = append(readers, fd)
+	}
+	bw := bufio.NewWriter(stdout)
+	if _, err := e.process(&lineReader{readers: readers}, bw); err != nil {
+		bw.Flush()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if failed {
+		return errors.New("some files could not be read")
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sed: ")
+	if err := flag.CommandLine.Parse(inPlaceArgs(os.Args[1:])); err != nil {
+		log.Fatal(err)
+	}
+	if err := run(flag.Args(), os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
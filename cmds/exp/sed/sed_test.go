@@ -0,0 +1,230 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertBRE(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{`a\(b\)*`, `a(b)*`},
+		{`(a)+?|{`, `\(a\)\+\?\|\{`},
+		{`a\{2,3\}`, `a{2,3}`},
+		{`*a`, `\*a`},
+		{`^*a`, `^\*a`},
+		{`\(*a\)`, `(\*a)`},
+		{`a\.b`, `a\.b`},
+		{`[\n]`, `[\\n]`},
+		{`[]a]`, `[\]a]`},
+		{`[^]a]`, `[^\]a]`},
+		{`[[:alpha:]]+`, `[[:alpha:]]\+`},
+		{`[abc`, `[abc`},
+	} {
+		if got := convertBRE(tt.in); got != tt.want {
+			t.Errorf("convertBRE(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseReplacement(t *testing.T) {
+	got := parseReplacement(`<&>\1\n\&\\x`)
+	want := []replacement{
+		{literal: "<", group: -1},
+		{group: 0},
+		{literal: ">", group: -1},
+		{group: 1},
+		{literal: "\n&\\x", group: -1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseReplacement() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	for _, s := range []string{
+		"x",
+		"s/a/b",
+		"s/a/b/z",
+		"s/a/b/0",
+		`s/a/\1/`,
+		"1,q",
+		"1,2q",
+		"0p",
+		"/a",
+		"p x",
+		"1,",
+	} {
+		if _, err := parseScript(s, false); err == nil {
+			t.Errorf("parseScript(%q) = nil, want error", s)
+		}
+	}
+}
+
+const input = "one\ntwo\nthree\nfour\nfive\n"
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		args  []string
+		quiet bool
+		ext   bool
+		in    string
+		want  string
+		err   bool
+	}{
+		{args: []string{"s/o/0/"}, in: "foo\nbar\n", want: "f0o\nbar\n"},
+		{args: []string{"s/o/0/g"}, in: "foo\n", want: "f00\n"},
+		{args: []string{"s/o/0/2"}, in: "fooo\n", want: "fo0o\n"},
+		{args: []string{"s/o/0/2g"}, in: "fooo\n", want: "fo00\n"},
+		{args: []string{"s/O/0/i"}, in: "foo\n", want: "f0o\n"},
+		{args: []string{"s/o*/<&>/g"}, in: "foo\n", want: "<>f<oo>\n"},
+		{args: []string{`s/\([a-z]*\) \([a-z]*\)/\2 \1/`}, in: "hello world\n", want: "world hello\n"},
+		{args: []string{`s/(a+)/[\1]/`}, ext: true, in: "baad\n", want: "b[aa]d\n"},
+		{args: []string{`s/a+/X/`}, in: "aa+\n", want: "aX\n"},
+		{args: []string{`s|/usr|/opt|`}, in: "/usr/bin\n", want: "/opt/bin\n"},
+		{args: []string{`s/\//:/g`}, in: "a/b/c\n", want: "a:b:c\n"},
+		{args: []string{`s/ /\n/`}, in: "a b\n", want: "a\nb\n"},
+		{args: []string{"2d"}, in: input, want: "one\nthree\nfour\nfive\n"},
+		{args: []string{"2,4d"}, in: input, want: "one\nfive\n"},
+		{args: []string{"2,4!d"}, in: input, want: "two\nthree\nfour\n"},
+		{args: []string{"$d"}, in: input, want: "one\ntwo\nthree\nfour\n"},
+		{args: []string{"/^t/d"}, in: input, want: "one\nfour\nfive\n"},
+		{args: []string{"/two/,/four/d"}, in: input, want: "one\nfive\n"},
+		{args: []string{"/two/,$d"}, in: input, want: "one\n"},
+		{args: []string{"3,1d"}, in: input, want: "one\ntwo\nfour\nfive\n"},
+		{args: []string{"/t/,2d"}, in: input, want: "one\nfour\nfive\n"},
+		{args: []string{"4,/o/d"}, in: input, want: "one\ntwo\nthree\n"},
+		{args: []string{"/E/Id"}, in: input, want: "two\nfour\n"},
+		{args: []string{`\,o,d`}, in: input, want: "three\nfive\n"},
+		{args: []string{"2p"}, quiet: true, in: input, want: "two\n"},
+		{args: []string{"2,3p"}, in: "a\nb\nc\n", want: "a\nb\nb\nc\nc\n"},
+		{args: []string{"s/e/E/p"}, quiet: true, in: input, want: "onE\nthrEe\nfivE\n"},
+		{args: []string{"/f/s//F/"}, in: input, want: "one\ntwo\nthree\nFour\nFive\n"},
+		{args: []string{"2q"}, in: input, want: "one\ntwo\n"},
+		{args: []string{"$="}, quiet: true, in: input, want: "5\n"},
+		{args: []string{"# comment\ns/one/1/;s/two/2/ ; 3d"}, in: input, want: "1\n2\nfour\nfive\n"},
+		{args: []string{"-e", "s/one/1/", "-e", "s/two/2/"}, in: "one two\n", want: "1 2\n"},
+		{args: []string{"p"}, in: "a\nb", want: "a\na\nb\nb"},
+		{args: []string{"s//x/"}, in: "a\n", err: true},
+	} {
+		*quiet, *extended = tt.quiet, tt.ext
+		*expressions = nil
+		args := tt.args
+		if args[0] == "-e" {
+			*expressions = []string{args[1], args[3]}
+			args = nil
+		}
+		var out bytes.Buffer
+		err := run(args, strings.NewReader(tt.in), &out)
+		if (err != nil) != tt.err || out.String() != tt.want {
+			t.Errorf("sed %q < %q = %q, %v, want %q, error %t", tt.args, tt.in, out.String(), err, tt.want, tt.err)
+		}
+	}
+	*quiet, *extended, *expressions = false, false, nil
+}
+
+func TestFilesAsOneStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("a1\na2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("b1\nb2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := run([]string{"1d;$d", a, "-", b}, strings.NewReader("s1\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a2\ns1\nb1\n"; out.String() != want {
+		t.Errorf("sed 1d;$d = %q, want %q", out.String(), want)
+	}
+}
+
+func TestInPlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := ioutil.WriteFile(a, []byte("x=1\ny=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(b, []byte("x=3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { *inPlace = "" }()
+	for _, suffix := range []string{noBackup, ".orig"} {
+		*inPlace = suffix
+		var out bytes.Buffer
+		if err := run([]string{"1s/=/:=/", a, b}, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("sed -i printed %q, want nothing", out.String())
+		}
+	}
+	for file, want := range map[string]string{
+		a:           "x::=1\ny=2\n",
+		b:           "x::=3\n",
+		a + ".orig": "x:=1\ny=2\n",
+		b + ".orig": "x:=3\n",
+	} {
+		if got, err := ioutil.ReadFile(file); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+	if fi, err := os.Stat(a); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("mode of %s = %v, %v, want 0600", a, fi.Mode(), err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 4 {
+		t.Errorf("%s has %d files, want 4", dir, len(entries))
+	}
+}
+
+func TestInPlaceArgs(t *testing.T) {
+	for _, tt := range []struct {
+		in   []string
+		want []string
+	}{
+		{[]string{"-i", "s/a/b/", "f"}, []string{"-i", "s/a/b/", "f"}},
+		{[]string{"-i.bak", "s/a/b/", "f"}, []string{"--in-place=.bak", "s/a/b/", "f"}},
+		{[]string{"-i=.bak", "f"}, []string{"-i=.bak", "f"}},
+		{[]string{"-n", "--", "-i.bak"}, []string{"-n", "--", "-i.bak"}},
+	} {
+		if got := inPlaceArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inPlaceArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBackupName(t *testing.T) {
+	for _, tt := range []struct {
+		file, suffix, want string
+	}{
+		{"/etc/fstab", ".bak", "/etc/fstab.bak"},
+		{"/etc/fstab", "old_*", "/etc/old_fstab"},
+		{"/etc/fstab", "/tmp/*.orig", "/tmp/fstab.orig"},
+	} {
+		if got := backupName(tt.file, tt.suffix); got != tt.want {
+			t.Errorf("backupName(%q, %q) = %q, want %q", tt.file, tt.suffix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// setsid runs a program in a new session.
+//
+// Synopsis:
+//     setsid [-c] [-f] [-w] PROGRAM [ARG...]
+//
+// Description:
+//     Run PROGRAM in a new session, without a controlling terminal. A
+//     process group leader, e.g. a command started by a shell, cannot
+//     start a new session, so setsid then runs PROGRAM in a child process
+//     and exits without waiting for it, unless -w is given.
+//
+// Options:
+//     -c: make the terminal on stdin the controlling terminal of the session
+//     -f: always run PROGRAM in a child process
+//     -w: wait for the child process and exit with its exit status
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	ctty = flag.Bool("c", false, "Make the terminal on stdin the controlling terminal of the new session")
+	fork = flag.Bool("f", false, "Always run the program in a child process")
+	wait = flag.Bool("w", false, "Wait for the child process and exit with its exit status")
+)
+
+// setctty makes the terminal on stdin the controlling terminal of the
+// session, which the calling process must lead.
+func setctty() error {
+	return unix.IoctlSetInt(0, unix.TIOCSCTTY, 1)
+}
+
+// run runs args in a new session and returns the exit status.
+func run(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, errors.New("usage: setsid [-c] [-f] [-w] PROGRAM [ARG...]")
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return 127, err
+	}
+
+	// Without a fork, setsid fails for process group leaders.
+	if !*fork && !*wait {
+		if _, err := unix.Setsid(); err == nil {
+			if *ctty {
+				if err := setctty(); err != nil {
+					return 1, fmt.Errorf("failed to set the controlling terminal: %v", err)
+				}
+			}
+			return 126, syscall.Exec(path, args, os.Environ())
+		}
+	}
+
+	c := exec.Command(path, args[1:]...)
+	c.Args[0] = args[0]
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: *ctty}
+	if err := c.Start(); err != nil {
+		return 126, err
+	}
+	if !*wait {
+		return 0, nil
+	}
+	if err := c.Wait(); err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return e.Sys().(syscall.WaitStatus).ExitStatus(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("setsid: ")
+	flag.Parse()
+	status, err := run(flag.Args())
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(status)
+}
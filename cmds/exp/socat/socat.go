@@ -0,0 +1,309 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// socat relays data between two endpoints.
+//
+// Synopsis:
+//     socat [-t TIMEOUT] [-v] ADDRESS ADDRESS
+//
+// Description:
+//     Open both ADDRESSes and copy data between them in both directions.
+//     When one side ends, the write side of the other is shut down, and
+//     socat exits once the other side ends too, or after TIMEOUT.
+//
+//     ADDRESS is one of
+//
+//     TCP:HOST:PORT       connect to HOST:PORT
+//     TCP-LISTEN:PORT     accept one connection on PORT
+//     UDP:HOST:PORT       send datagrams to HOST:PORT
+//     UDP-LISTEN:PORT     serve the first peer that sends a datagram to PORT
+//     FILE:PATH           open PATH for reading and writing, e.g. /dev/ttyS0
+//     EXEC:COMMAND        run COMMAND, which may have arguments
+//     STDIO or -          standard input and output
+//
+//     The type is case insensitive. For example, to make a serial console
+//     available on port 2323:
+//
+//     socat TCP-LISTEN:2323 FILE:/dev/ttyS0
+//
+// Options:
+//     -t: time to wait for the other side after one side ended
+//     -v: log the endpoints
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/u-root/u-root/pkg/shlex"
+)
+
+const usage = "usage: socat [-t TIMEOUT] [-v] ADDRESS ADDRESS"
+
+var (
+	timeout = flag.Duration("t", 500*time.Millisecond, "Time to wait for the other side after one side ended")
+	verbose = flag.Bool("v", false, "Log the endpoints")
+)
+
+// endpoint is one side of the relay.
+type endpoint interface {
+	io.ReadWriteCloser
+	// CloseWrite signals the end of the data to the endpoint, if it can.
+	CloseWrite() error
+}
+
+// connEndpoint is a network connection, which is half-closed if it is TCP.
+type connEndpoint struct {
+	net.Conn
+}
+
+func (c connEndpoint) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// udpEndpoint is a UDP listener that talks to the first peer that sent it a
+// datagram.
+type udpEndpoint struct {
+	*net.UDPConn
+	peer    net.Addr
+	pending []byte
+}
+
+func (u *udpEndpoint) Read(b []byte) (int, error) {
+	if u.pending != nil {
+		n := copy(b, u.pending)
+		u.pending = nil
+		return n, nil
+	}
+	for {
+		n, addr, err := u.ReadFrom(b)
+		if err != nil || addr.String() == u.peer.String() {
+			return n, err
+		}
+	}
+}
+
+func (u *udpEndpoint) Write(b []byte) (int, error) {
+	return u.WriteTo(b, u.peer)
+}
+
+func (u *udpEndpoint) CloseWrite() error {
+	return nil
+}
+
+// fileEndpoint is a file, e.g. a serial port.
+type fileEndpoint struct {
+	*os.File
+}
+
+// CloseWrite does nothing, the file stays open for reading.
+func (f fileEndpoint) CloseWrite() error {
+	return nil
+}
+
+// stdioEndpoint is standard input and output.
+type stdioEndpoint struct {
+	io.Reader
+	w io.WriteCloser
+}
+
+func (s stdioEndpoint) Write(b []byte) (int, error) {
+	return s.w.Write(b)
+}
+
+func (s stdioEndpoint) CloseWrite() error {
+	return s.w.Close()
+}
+
+func (s stdioEndpoint) Close() error {
+	return nil
+}
+
+// execEndpoint is a command, written to on its standard input and read from
+// on its standard output.
+type execEndpoint struct {
+	cmd *exec.Cmd
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+func (e *execEndpoint) Write(b []byte) (int, error) {
+	return e.stdin.Write(b)
+}
+
+func (e *execEndpoint) CloseWrite() error {
+	return e.stdin.Close()
+}
+
+func (e *execEndpoint) Close() error {
+	e.stdin.Close()
+	// Do not wait for a command that ignores the end of its input.
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+	return nil
+}
+
+func openExec(command string) (endpoint, error) {
+	argv := shlex.Argv(command)
+	if len(argv) == 0 {
+		return nil, errors.New("EXEC needs a command")
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &execEndpoint{cmd: cmd, ReadCloser: stdout, stdin: stdin}, nil
+}
+
+func listenTCP(port string) (endpoint, error) {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	if *verbose {
+		log.Printf("Listening on %v", ln.Addr())
+	}
+	c, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return connEndpoint{c}, nil
+}
+
+func listenUDP(port string) (endpoint, error) {
+	pc, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+	if *verbose {
+		log.Printf("Listening on %v", pc.LocalAddr())
+	}
+	b := make([]byte, 64*1024)
+	n, peer, err := pc.ReadFrom(b)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	return &udpEndpoint{UDPConn: pc.(*net.UDPConn), peer: peer, pending: b[:n]}, nil
+}
+
+func dial(network, addr string) (endpoint, error) {
+	c, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return connEndpoint{c}, nil
+}
+
+// open opens the endpoint of the address specifier addr.
+func open(addr string) (endpoint, error) {
+	if addr == "-" || strings.EqualFold(addr, "STDIO") {
+		return stdioEndpoint{Reader: os.Stdin, w: os.Stdout}, nil
+	}
+	i := strings.Index(addr, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("%q: want TYPE:ARGUMENT", addr)
+	}
+	typ, arg := strings.ToUpper(addr[:i]), addr[i+1:]
+	var (
+		e   endpoint
+		err error
+	)
+	switch typ {
+	case "TCP":
+		e, err = dial("tcp", arg)
+	case "UDP":
+		e, err = dial("udp", arg)
+	case "TCP-LISTEN":
+		e, err = listenTCP(arg)
+	case "UDP-LISTEN":
+		e, err = listenUDP(arg)
+	case "FILE":
+		var f *os.File
+		if f, err = os.OpenFile(arg, os.O_RDWR, 0); err == nil {
+			e = fileEndpoint{f}
+		}
+	case "EXEC":
+		e, err = openExec(arg)
+	default:
+		return nil, fmt.Errorf("%q: unknown address type %s", addr, typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", addr, err)
+	}
+	if *verbose {
+		log.Printf("Opened %s", addr)
+	}
+	return e, nil
+}
+
+// relay copies data between a and b in both directions. Once one direction
+// ends, it waits at most timeout for the other.
+func relay(a, b endpoint, timeout time.Duration) error {
+	errs := make(chan error, 2)
+	cp := func(dst, src endpoint) {
+		_, err := io.Copy(dst, src)
+		dst.CloseWrite()
+		errs <- err
+	}
+	go cp(a, b)
+	go cp(b, a)
+
+	err := <-errs
+	select {
+	case err2 := <-errs:
+		if err == nil {
+			err = err2
+		}
+	case <-time.After(timeout):
+	}
+	return err
+}
+
+func run(args []string) error {
+	if len(args) != 2 {
+		return errors.New(usage)
+	}
+	a, err := open(args[0])
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := open(args[1])
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return relay(a, b, *timeout)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("socat: ")
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
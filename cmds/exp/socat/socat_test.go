@@ -0,0 +1,100 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenErrors(t *testing.T) {
+	for _, addr := range []string{
+		"localhost",
+		"SCTP:localhost:80",
+		"EXEC:",
+		"FILE:/does/not/exist",
+	} {
+		if e, err := open(addr); err == nil {
+			e.Close()
+			t.Errorf("open(%q) = nil, want error", addr)
+		}
+	}
+}
+
+func TestRelayTCPExec(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := make(chan []byte)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			close(done)
+			return
+		}
+		defer c.Close()
+		c.Write([]byte("hello"))
+		c.(*net.TCPConn).CloseWrite()
+		b, _ := ioutil.ReadAll(c)
+		done <- b
+	}()
+
+	a, err := open("tcp:" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := open("EXEC:tr a-z A-Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if err := relay(a, b, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(<-done); got != "HELLO" {
+		t.Errorf("peer got %q, want %q", got, "HELLO")
+	}
+}
+
+func TestRelayFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "console")
+	if err := ioutil.WriteFile(path, []byte("boot log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := open("FILE:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := open("EXEC:cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	// The file is read to its end, and cat echoes it back, appended.
+	if err := relay(a, b, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "boot log\nboot log\n" {
+		t.Errorf("file = %q, want the contents twice", got)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// su runs a shell as another user.
+//
+// Synopsis:
+//     su [-] [-l] [-c COMMAND] [-s SHELL] [USER [ARG...]]
+//
+// Description:
+//     su runs the shell of USER, root by default, with the user ID, group
+//     ID and supplementary groups of USER, as listed in /etc/passwd and
+//     /etc/group. ARGs are passed to the shell. HOME, SHELL, USER and
+//     LOGNAME are set for USER.
+//
+//     With - or -l, the shell is a login shell: it starts in the home
+//     directory of USER, and the environment is cleared except for TERM
+//     and PATH.
+//
+//     Passwords are not checked, so only root may run su.
+//
+// Options:
+//     -c: run COMMAND with the shell
+//     -l: run a login shell
+//     -s: run SHELL instead of the shell of USER
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+var (
+	command = flag.StringP("command", "c", "", "run COMMAND with the shell")
+	login   = flag.BoolP("login", "l", false, "run a login shell")
+	shell   = flag.StringP("shell", "s", "", "run SHELL instead of the shell of USER")
+)
+
+// Overridden in tests.
+var geteuid = os.Geteuid
+
+// defaultShell is the shell of users with none in /etc/passwd.
+const defaultShell = "/bin/sh"
+
+// options are how to run the shell.
+type options struct {
+	login   bool
+	command string
+	shell   string
+	args    []string
+}
+
+// env returns the environment of the shell of p, based on environ.
+func env(p getent.Passwd, shell string, login bool, environ []string) []string {
+	var e []string
+	for _, kv := range environ {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case "HOME", "SHELL", "USER", "LOGNAME":
+			continue
+		case "TERM", "PATH":
+		default:
+			if login {
+				continue
+			}
+		}
+		e = append(e, kv)
+	}
+	return append(e, "HOME="+p.Dir, "SHELL="+shell, "USER="+p.Name, "LOGNAME="+p.Name)
+}
+
+// newCmd returns the command that runs the shell of p, who is a member of
+// groups.
+func newCmd(p getent.Passwd, groups []getent.Group, o options, environ []string) *exec.Cmd {
+	sh := o.shell
+	if sh == "" {
+		sh = p.Shell
+	}
+	if sh == "" {
+		sh = defaultShell
+	}
+	args := o.args
+	if o.command != "" {
+		args = append([]string{"-c", o.command}, args...)
+	}
+
+	cmd := exec.Command(sh, args...)
+	cmd.Args[0] = filepath.Base(sh)
+	cmd.Env = env(p, sh, o.login, environ)
+	if o.login {
+		cmd.Args[0] = "-" + cmd.Args[0]
+		cmd.Dir = p.Dir
+		if fi, err := os.Stat(p.Dir); err != nil || !fi.IsDir() {
+			log.Printf("warning: cannot change directory to %s", p.Dir)
+			cmd.Dir = "/"
+		}
+	}
+
+	cred := &syscall.Credential{Uid: uint32(p.UID), Gid: uint32(p.GID)}
+	seen := map[int]bool{}
+	for _, gid := range append([]int{p.GID}, gids(groups)...) {
+		if !seen[gid] {
+			seen[gid] = true
+			cred.Groups = append(cred.Groups, uint32(gid))
+		}
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	return cmd
+}
+
+func gids(groups []getent.Group) []int {
+	var ids []int
+	for _, g := range groups {
+		ids = append(ids, g.GID)
+	}
+	return ids
+}
+
+// run runs the shell of user and returns its exit status.
+func run(user string, o options) (int, error) {
+	if geteuid() != 0 {
+		return 1, fmt.Errorf("only root can switch users")
+	}
+	pw, err := getent.ReadPasswd(getent.PasswdFile)
+	if err != nil {
+		return 1, err
+	}
+	p, ok := getent.FindPasswd(pw, user)
+	if !ok {
+		return 1, fmt.Errorf("user %s does not exist", user)
+	}
+	// Without /etc/group, the user has only the primary group.
+	gr, _ := getent.ReadGroup(getent.GroupFile)
+
+	cmd := newCmd(p, getent.MemberOf(gr, p.Name), o, os.Environ())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() >= 0 {
+			return ee.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("su: ")
+	flag.Parse()
+	o := options{login: *login, command: *command, shell: *shell}
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "-" {
+		o.login = true
+		args = args[1:]
+	}
+	user := "root"
+	if len(args) > 0 {
+		user, o.args = args[0], args[1:]
+	}
+	code, err := run(user, o)
+	if err != nil {
+		log.Print(err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+func TestNewCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "su")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	user := getent.Passwd{Name: "user", UID: 1000, GID: 100, Dir: dir, Shell: "/bin/ash"}
+	groups := []getent.Group{{Name: "wheel", GID: 10}, {Name: "users", GID: 100}}
+	environ := []string{"TERM=vt100", "PATH=/bbin", "HOME=/root", "EDITOR=ed", "USER=root"}
+
+	for _, tt := range []struct {
+		name string
+		user getent.Passwd
+		o    options
+		args []string
+		env  []string
+		dir  string
+	}{
+		{
+			name: "shell",
+			user: user,
+			args: []string{"ash"},
+			env:  []string{"TERM=vt100", "PATH=/bbin", "EDITOR=ed", "HOME=" + dir, "SHELL=/bin/ash", "USER=user", "LOGNAME=user"},
+		},
+		{
+			name: "login",
+			user: user,
+			o:    options{login: true, command: "echo $HOME", args: []string{"a", "b"}},
+			args: []string{"-ash", "-c", "echo $HOME", "a", "b"},
+			env:  []string{"TERM=vt100", "PATH=/bbin", "HOME=" + dir, "SHELL=/bin/ash", "USER=user", "LOGNAME=user"},
+			dir:  dir,
+		},
+		{
+			name: "login without home",
+			user: getent.Passwd{Name: "user", UID: 1000, GID: 100, Dir: filepath.Join(dir, "nonexistent")},
+			o:    options{login: true, shell: "/bin/rush"},
+			args: []string{"-rush"},
+			env:  []string{"TERM=vt100", "PATH=/bbin", "HOME=" + filepath.Join(dir, "nonexistent"), "SHELL=/bin/rush", "USER=user", "LOGNAME=user"},
+			dir:  "/",
+		},
+		{
+			name: "default shell",
+			user: getent.Passwd{Name: "user", UID: 1000, GID: 100, Dir: dir},
+			args: []string{"sh"},
+			env:  []string{"TERM=vt100", "PATH=/bbin", "EDITOR=ed", "HOME=" + dir, "SHELL=/bin/sh", "USER=user", "LOGNAME=user"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newCmd(tt.user, groups, tt.o, environ)
+			if !reflect.DeepEqual(cmd.Args, tt.args) {
+				t.Errorf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+			if !reflect.DeepEqual(cmd.Env, tt.env) {
+				t.Errorf("Env = %q, want %q", cmd.Env, tt.env)
+			}
+			if cmd.Dir != tt.dir {
+				t.Errorf("Dir = %q, want %q", cmd.Dir, tt.dir)
+			}
+			cred := cmd.SysProcAttr.Credential
+			if cred.Uid != 1000 || cred.Gid != 100 || !reflect.DeepEqual(cred.Groups, []uint32{100, 10}) {
+				t.Errorf("Credential = %+v, want uid 1000, gid 100 and groups [100 10]", cred)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	f, err := ioutil.TempFile("", "passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("root:x:0:0:root:/root:/bin/sh\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer func(p string) { getent.PasswdFile = p }(getent.PasswdFile)
+	getent.PasswdFile = f.Name()
+	defer func(f func() int) { geteuid = f }(geteuid)
+
+	geteuid = func() int { return 1000 }
+	if code, err := run("root", options{}); code != 1 || err == nil {
+		t.Errorf("run() as a user = %d, %v, want 1 and an error", code, err)
+	}
+	geteuid = func() int { return 0 }
+	if code, err := run("nobody", options{}); code != 1 || err == nil {
+		t.Errorf("run(nobody) = %d, %v, want 1 and an error", code, err)
+	}
+}
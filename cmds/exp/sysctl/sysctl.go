@@ -0,0 +1,291 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// sysctl reads and writes kernel parameters in /proc/sys.
+//
+// Synopsis:
+//     sysctl [-n] [-e] NAME...
+//     sysctl [-q] [-e] [-w] NAME=VALUE...
+//     sysctl [-n] -a
+//     sysctl [-q] [-e] -p [FILE...]
+//
+// Description:
+//     Print or set the kernel parameters NAME, which are the paths below
+//     /proc/sys with dots instead of slashes, e.g. net.ipv4.ip_forward.
+//     Dots in path elements become slashes, as in
+//     net.ipv4.conf.eth0/100.forwarding. A name with a slash before any dot
+//     is used as a path as is, so net/ipv4/conf/eth0.100/forwarding works
+//     too.
+//
+//     -p loads parameters from FILE, /etc/sysctl.conf by default. Its
+//     lines are NAME = VALUE; empty lines and lines starting with # or ;
+//     are skipped, and errors of lines starting with - are ignored.
+//
+// Options:
+//     -a: print all parameters
+//     -e: ignore unknown parameters
+//     -n: print only the values
+//     -p: load parameters from files
+//     -q: do not print the parameters that are set
+//     -w: set the parameters
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	all      = flag.Bool("a", false, "Print all parameters")
+	ignore   = flag.Bool("e", false, "Ignore unknown parameters")
+	noNames  = flag.Bool("n", false, "Print only the values")
+	load     = flag.Bool("p", false, "Load parameters from files, /etc/sysctl.conf by default")
+	quiet    = flag.Bool("q", false, "Do not print the parameters that are set")
+	write    = flag.Bool("w", false, "Set the parameters")
+	procSys  = "/proc/sys"
+	confFile = "/etc/sysctl.conf"
+)
+
+var errFailed = errors.New("some parameters could not be read or set")
+
+// toPath returns the path below procSys of a parameter name.
+func toPath(name string) string {
+	// A name that has a slash before any dot is a path. Otherwise, dots
+	// and slashes swap places, since names use dots like paths use
+	// slashes.
+	slash, dot := strings.IndexByte(name, '/'), strings.IndexByte(name, '.')
+	if slash < 0 || (dot >= 0 && dot < slash) {
+		name = strings.Map(func(r rune) rune {
+			switch r {
+			case '.':
+				return '/'
+			case '/':
+				return '.'
+			}
+			return r
+		}, name)
+	}
+	return filepath.Join(procSys, filepath.Clean("/"+name))
+}
+
+// toName returns the parameter name of a path below procSys.
+func toName(path string) string {
+	rel, err := filepath.Rel(procSys, path)
+	if err != nil {
+		rel = path
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		}
+		return r
+	}, rel)
+}
+
+type sysctl struct {
+	w       io.Writer
+	noNames bool
+	quiet   bool
+	ignore  bool
+}
+
+func (s *sysctl) print(name, value string) {
+	if s.noNames {
+		fmt.Fprintln(s.w, value)
+	} else {
+		fmt.Fprintf(s.w, "%s = %s\n", name, value)
+	}
+}
+
+// describe explains errors of the parameter name.
+func describe(name string, err error) error {
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("cannot stat %s: no such parameter %q", toPath(name), name)
+	case os.IsPermission(err):
+		return fmt.Errorf("permission denied on key %q (are you root?)", name)
+	}
+	return fmt.Errorf("%s: %v", name, err)
+}
+
+// read prints the parameter name.
+func (s *sysctl) read(name string) error {
+	b, err := ioutil.ReadFile(toPath(name))
+	if err != nil {
+		if os.IsNotExist(err) && s.ignore {
+			return nil
+		}
+		return describe(name, err)
+	}
+	s.print(name, strings.TrimSuffix(string(b), "\n"))
+	return nil
+}
+
+// set writes value to the parameter name.
+func (s *sysctl) set(name, value string) error {
+	p := toPath(name)
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		if os.IsNotExist(err) && s.ignore {
+			return nil
+		}
+		return describe(name, err)
+	}
+	if _, err := f.Write([]byte(value)); err != nil {
+		f.Close()
+		return fmt.Errorf("setting key %q to %q: %v", name, value, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("setting key %q to %q: %v", name, value, err)
+	}
+	if !s.quiet {
+		s.print(name, value)
+	}
+	return nil
+}
+
+// assignment splits NAME=VALUE, trimming the spaces around both.
+func assignment(s string) (string, string, error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", fmt.Errorf("%q must be of the form NAME=VALUE", s)
+	}
+	name, value := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+	if name == "" {
+		return "", "", fmt.Errorf("%q has no name", s)
+	}
+	return name, value, nil
+}
+
+// readAll prints all readable parameters.
+func (s *sysctl) readAll() error {
+	return filepath.Walk(procSys, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Directories of exiting processes and unreadable
+			// directories are skipped.
+			return nil
+		}
+		if fi.IsDir() || fi.Mode().Perm()&0444 == 0 {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		s.print(toName(path), strings.TrimSuffix(string(b), "\n"))
+		return nil
+	})
+}
+
+// loadFile sets the parameters of the file r.
+func (s *sysctl) loadFile(r io.Reader) error {
+	var failed bool
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		ignoreErr := line[0] == '-'
+		if ignoreErr {
+			line = line[1:]
+		}
+		name, value, err := assignment(line)
+		if err == nil {
+			err = s.set(name, value)
+		}
+		if err != nil && !ignoreErr {
+			log.Printf("line %d: %v", n, err)
+			failed = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func (s *sysctl) run(args []string) error {
+	switch {
+	case *all:
+		if len(args) > 0 {
+			return errors.New("-a takes no parameters")
+		}
+		return s.readAll()
+	case *load:
+		if len(args) == 0 {
+			args = []string{confFile}
+		}
+		var failed bool
+		for _, a := range args {
+			var err error
+			if a == "-" {
+				err = s.loadFile(os.Stdin)
+			} else if f, oerr := os.Open(a); oerr != nil {
+				err = oerr
+			} else {
+				err = s.loadFile(f)
+				f.Close()
+			}
+			if err != nil {
+				log.Printf("%s: %v", a, err)
+				failed = true
+			}
+		}
+		if failed {
+			return errFailed
+		}
+		return nil
+	case len(args) == 0:
+		return errors.New("no parameters given; use -a to print all")
+	}
+
+	var failed bool
+	for _, a := range args {
+		var err error
+		if *write || strings.Contains(a, "=") {
+			var name, value string
+			if name, value, err = assignment(a); err == nil {
+				err = s.set(name, value)
+			}
+		} else {
+			err = s.read(a)
+		}
+		if err != nil {
+			log.Print(err)
+			failed = true
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sysctl: ")
+	flag.Parse()
+	s := &sysctl{w: os.Stdout, noNames: *noNames, quiet: *quiet, ignore: *ignore}
+	if err := s.run(flag.Args()); err != nil {
+		if err != errFailed {
+			log.Print(err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupProcSys(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "sysctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := procSys
+	procSys = dir
+	for p, v := range map[string]string{
+		"net/ipv4/ip_forward":               "0\n",
+		"net/ipv4/tcp_rmem":                 "4096\t131072\t6291456\n",
+		"net/ipv4/conf/eth0.100/forwarding": "1\n",
+		"kernel/ostype":                     "Linux\n",
+		"vm/drop_caches":                    "",
+		"vm/max_map_count":                  "65530\n",
+	} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Write-only, like vm.drop_caches.
+	if err := os.Chmod(filepath.Join(dir, "vm/drop_caches"), 0200); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		procSys = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNames(t *testing.T) {
+	defer func(p string) { procSys = p }(procSys)
+	procSys = "/proc/sys"
+	for _, tt := range []struct {
+		name, path string
+	}{
+		{"net.ipv4.ip_forward", "/proc/sys/net/ipv4/ip_forward"},
+		{"net.ipv4.conf.eth0/100.forwarding", "/proc/sys/net/ipv4/conf/eth0.100/forwarding"},
+		{"vm.max_map_count", "/proc/sys/vm/max_map_count"},
+	} {
+		if got := toPath(tt.name); got != tt.path {
+			t.Errorf("toPath(%q) = %q, want %q", tt.name, got, tt.path)
+		}
+		if got := toName(tt.path); got != tt.name {
+			t.Errorf("toName(%q) = %q, want %q", tt.path, got, tt.name)
+		}
+	}
+	for name, path := range map[string]string{
+		"net/ipv4/conf/eth0.100/forwarding": "/proc/sys/net/ipv4/conf/eth0.100/forwarding",
+		"/../../etc/passwd":                 "/proc/sys/etc/passwd",
+		"/vm/max_map_count":                 "/proc/sys/vm/max_map_count",
+	} {
+		if got := toPath(name); got != path {
+			t.Errorf("toPath(%q) = %q, want %q", name, got, path)
+		}
+	}
+}
+
+func TestReadSet(t *testing.T) {
+	defer setupProcSys(t)()
+	var b bytes.Buffer
+	s := &sysctl{w: &b}
+	for _, name := range []string{"net.ipv4.tcp_rmem", "kernel/ostype", "net.ipv4.conf.eth0/100.forwarding"} {
+		if err := s.read(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "net.ipv4.tcp_rmem = 4096\t131072\t6291456\nkernel/ostype = Linux\nnet.ipv4.conf.eth0/100.forwarding = 1\n"
+	if b.String() != want {
+		t.Errorf("read() printed %q, want %q", b.String(), want)
+	}
+	if err := s.read("no.such"); err == nil || !strings.Contains(err.Error(), "no such parameter") {
+		t.Errorf("read(no.such) = %v, want no such parameter error", err)
+	}
+	s.ignore = true
+	if err := s.read("no.such"); err != nil {
+		t.Errorf("read(no.such) with -e = %v, want nil", err)
+	}
+
+	b.Reset()
+	s.noNames = true
+	if err := s.set("net.ipv4.ip_forward", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "1\n" {
+		t.Errorf("set() printed %q, want %q", b.String(), "1\n")
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(procSys, "net/ipv4/ip_forward")); err != nil || string(got) != "1" {
+		t.Errorf("ip_forward = %q, %v, want 1", got, err)
+	}
+	if err := s.set("net.ipv4.nothing", "1"); err != nil {
+		t.Errorf("set(net.ipv4.nothing) with -e = %v, want nil", err)
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	for _, tt := range []struct {
+		in, name, value string
+		err             bool
+	}{
+		{in: "a.b=1", name: "a.b", value: "1"},
+		{in: " a.b = 4096 131072 ", name: "a.b", value: "4096 131072"},
+		{in: "a.b=", name: "a.b", value: ""},
+		{in: "a.b=c=d", name: "a.b", value: "c=d"},
+		{in: "a.b", err: true},
+		{in: "=1", err: true},
+	} {
+		name, value, err := assignment(tt.in)
+		if (err != nil) != tt.err || name != tt.name || value != tt.value {
+			t.Errorf("assignment(%q) = %q, %q, %v, want %q, %q, error %t", tt.in, name, value, err, tt.name, tt.value, tt.err)
+		}
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	defer setupProcSys(t)()
+	var b bytes.Buffer
+	s := &sysctl{w: &b}
+	if err := s.readAll(); err != nil {
+		t.Fatal(err)
+	}
+	want := `kernel.ostype = Linux
+net.ipv4.conf.eth0/100.forwarding = 1
+net.ipv4.ip_forward = 0
+net.ipv4.tcp_rmem = 4096	131072	6291456
+vm.max_map_count = 65530
+`
+	if b.String() != want {
+		t.Errorf("readAll() printed %q, want %q", b.String(), want)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	defer setupProcSys(t)()
+	var b bytes.Buffer
+	s := &sysctl{w: &b}
+	conf := `# Forwarding
+net.ipv4.ip_forward = 1
+; comment
+
+vm/max_map_count=262144
+-net.ipv4.missing = 1
+`
+	if err := s.loadFile(strings.NewReader(conf)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "net.ipv4.ip_forward = 1\nvm/max_map_count = 262144\n"; b.String() != want {
+		t.Errorf("loadFile() printed %q, want %q", b.String(), want)
+	}
+	if got, err := ioutil.ReadFile(filepath.Join(procSys, "vm/max_map_count")); err != nil || string(got) != "262144" {
+		t.Errorf("max_map_count = %q, %v, want 262144", got, err)
+	}
+
+	s.quiet = true
+	for _, conf := range []string{"net.ipv4.missing = 1\n", "garbage\n"} {
+		if err := s.loadFile(strings.NewReader(conf)); err != errFailed {
+			t.Errorf("loadFile(%q) = %v, want %v", conf, err, errFailed)
+		}
+	}
+}
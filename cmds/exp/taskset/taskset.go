@@ -0,0 +1,253 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// taskset gets or sets the CPU affinity of a process.
+//
+// Synopsis:
+//     taskset [-c] MASK COMMAND [ARG...]
+//     taskset [-c] -p [MASK] PID
+//
+// Description:
+//     Run COMMAND on the CPUs in MASK, or get or set the CPUs process PID
+//     may run on. MASK is a hexadecimal bit mask, e.g. 0x5 for CPUs 0 and
+//     2, or with -c a list like 0-3,5 or 0-7:2, where :2 takes every
+//     second CPU of the range.
+//
+// Options:
+//     -c, --cpu-list: MASK is a list of CPUs
+//     -p, --pid:      operate on the existing process PID
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	list = flag.BoolP("cpu-list", "c", false, "interpret MASK as a list of CPUs like 0-3,5")
+	pid  = flag.BoolP("pid", "p", false, "operate on the existing process PID")
+)
+
+const usage = "usage: taskset [-c] MASK COMMAND [ARG...] | taskset [-c] -p [MASK] PID"
+
+// Overridden in tests.
+var (
+	getaffinity = unix.SchedGetaffinity
+	setaffinity = unix.SchedSetaffinity
+)
+
+// maxCPUs is the number of CPUs a unix.CPUSet holds.
+const maxCPUs = len(unix.CPUSet{}) * 64
+
+// parseCPUList parses a list like "0-3,5" or "0-7:2" into a set.
+func parseCPUList(s string) (*unix.CPUSet, error) {
+	var set unix.CPUSet
+	for _, r := range strings.Split(strings.TrimSpace(s), ",") {
+		stride := 1
+		if i := strings.IndexByte(r, ':'); i >= 0 {
+			n, err := strconv.Atoi(r[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid stride in CPU list %q", s)
+			}
+			r, stride = r[:i], n
+		}
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		l, err := strconv.Atoi(lo)
+		if err != nil || l < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q", s)
+		}
+		h, err := strconv.Atoi(hi)
+		if err != nil || h < l {
+			return nil, fmt.Errorf("invalid CPU list %q", s)
+		}
+		if h >= maxCPUs {
+			return nil, fmt.Errorf("CPU %d in list %q is out of range [0,%d]", h, s, maxCPUs-1)
+		}
+		for cpu := l; cpu <= h; cpu += stride {
+			set.Set(cpu)
+		}
+	}
+	return &set, nil
+}
+
+// parseMask parses a hexadecimal mask like "0x5" or "ff" into a set.
+func parseMask(s string) (*unix.CPUSet, error) {
+	var set unix.CPUSet
+	h := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+	if h == "" {
+		return nil, fmt.Errorf("invalid mask %q", s)
+	}
+	for i := 0; i < len(h); i++ {
+		d, err := strconv.ParseUint(h[len(h)-1-i:len(h)-i], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask %q", s)
+		}
+		for b := 0; b < 4; b++ {
+			if d&(1<<uint(b)) == 0 {
+				continue
+			}
+			cpu := 4*i + b
+			if cpu >= maxCPUs {
+				return nil, fmt.Errorf("mask %q has CPUs out of range [0,%d]", s, maxCPUs-1)
+			}
+			set.Set(cpu)
+		}
+	}
+	return &set, nil
+}
+
+// formatCPUList formats set as a list like "0-3,5".
+func formatCPUList(set *unix.CPUSet) string {
+	var r []string
+	for cpu := 0; cpu < maxCPUs; cpu++ {
+		if !set.IsSet(cpu) {
+			continue
+		}
+		end := cpu
+		for end+1 < maxCPUs && set.IsSet(end+1) {
+			end++
+		}
+		if end == cpu {
+			r = append(r, strconv.Itoa(cpu))
+		} else {
+			r = append(r, fmt.Sprintf("%d-%d", cpu, end))
+		}
+		cpu = end
+	}
+	return strings.Join(r, ",")
+}
+
+// formatMask formats set as a hexadecimal mask without leading zeros.
+func formatMask(set *unix.CPUSet) string {
+	var b strings.Builder
+	for i := len(set) - 1; i >= 0; i-- {
+		w := uint64(set[i])
+		if b.Len() == 0 {
+			if w == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "%x", w)
+			continue
+		}
+		fmt.Fprintf(&b, "%016x", w)
+	}
+	if b.Len() == 0 {
+		return "0"
+	}
+	return b.String()
+}
+
+func parseSet(s string, list bool) (*unix.CPUSet, error) {
+	var set *unix.CPUSet
+	var err error
+	if list {
+		set, err = parseCPUList(s)
+	} else {
+		set, err = parseMask(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if set.Count() == 0 {
+		return nil, fmt.Errorf("%q has no CPUs", s)
+	}
+	return set, nil
+}
+
+// printAffinity prints the affinity of pid, which is current or new.
+func printAffinity(w io.Writer, pid int, which string, set *unix.CPUSet, list bool) {
+	if list {
+		fmt.Fprintf(w, "pid %d's %s affinity list: %s\n", pid, which, formatCPUList(set))
+	} else {
+		fmt.Fprintf(w, "pid %d's %s affinity mask: %s\n", pid, which, formatMask(set))
+	}
+}
+
+// runPID gets or sets the affinity of an existing process.
+func runPID(w io.Writer, args []string, list bool) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New(usage)
+	}
+	p, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || p < 0 {
+		return fmt.Errorf("invalid PID %q", args[len(args)-1])
+	}
+	var cur unix.CPUSet
+	if err := getaffinity(p, &cur); err != nil {
+		return fmt.Errorf("failed to get pid %d's affinity: %v", p, err)
+	}
+	printAffinity(w, p, "current", &cur, list)
+	if len(args) == 1 {
+		return nil
+	}
+
+	set, err := parseSet(args[0], list)
+	if err != nil {
+		return err
+	}
+	if err := setaffinity(p, set); err != nil {
+		return fmt.Errorf("failed to set pid %d's affinity: %v", p, err)
+	}
+	// The kernel drops CPUs that are not online.
+	if err := getaffinity(p, &cur); err != nil {
+		return fmt.Errorf("failed to get pid %d's affinity: %v", p, err)
+	}
+	printAffinity(w, p, "new", &cur, list)
+	return nil
+}
+
+// runCommand runs a command with the affinity of args[0].
+func runCommand(args []string, list bool) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	set, err := parseSet(args[0], list)
+	if err != nil {
+		return err
+	}
+	path, err := exec.LookPath(args[1])
+	if err != nil {
+		return err
+	}
+	// The affinity is set for this thread only, which must also be the one
+	// to exec the command.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := setaffinity(0, set); err != nil {
+		return fmt.Errorf("failed to set affinity: %v", err)
+	}
+	return syscall.Exec(path, args[1:], os.Environ())
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("taskset: ")
+	// Options after MASK belong to COMMAND.
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+
+	var err error
+	if *pid {
+		err = runPID(os.Stdout, flag.Args(), *list)
+	} else {
+		err = runCommand(flag.Args(), *list)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		list string
+		mask string
+		err  bool
+	}{
+		{in: "0", list: "0", mask: "1"},
+		{in: "0-3,5", list: "0-3,5", mask: "2f"},
+		{in: "5,0-3\n", list: "0-3,5", mask: "2f"},
+		{in: "0-7:2", list: "0,2,4,6", mask: "55"},
+		{in: "64,1023", list: "64,1023", mask: "8" + zeros(255-16-1) + "1" + zeros(16)},
+		{in: "", err: true},
+		{in: "3-1", err: true},
+		{in: "a", err: true},
+		{in: "-1", err: true},
+		{in: "0-7:0", err: true},
+		{in: "1024", err: true},
+	} {
+		set, err := parseCPUList(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseCPUList(%q) = %v, want error %t", tt.in, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := formatCPUList(set); got != tt.list {
+			t.Errorf("formatCPUList(parseCPUList(%q)) = %q, want %q", tt.in, got, tt.list)
+		}
+		if got := formatMask(set); got != tt.mask {
+			t.Errorf("formatMask(parseCPUList(%q)) = %q, want %q", tt.in, got, tt.mask)
+		}
+	}
+}
+
+func zeros(n int) string {
+	return strings.Repeat("0", n)
+}
+
+func TestParseMask(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		list string
+		err  bool
+	}{
+		{in: "1", list: "0"},
+		{in: "0x5", list: "0,2"},
+		{in: "FF", list: "0-7"},
+		{in: "10000000000000000", list: "64"},
+		{in: "0", list: ""},
+		{in: "", err: true},
+		{in: "0x", err: true},
+		{in: "g", err: true},
+		{in: "1" + zeros(256), err: true},
+	} {
+		set, err := parseMask(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseMask(%q) = %v, want error %t", tt.in, err, tt.err)
+			continue
+		}
+		if err == nil && formatCPUList(set) != tt.list {
+			t.Errorf("parseMask(%q) = %q, want %q", tt.in, formatCPUList(set), tt.list)
+		}
+	}
+	if _, err := parseSet("0", false); err == nil {
+		t.Error("parseSet(0) = nil, want error for an empty set")
+	}
+}
+
+func TestRunPID(t *testing.T) {
+	defer func(g, s func(int, *unix.CPUSet) error) { getaffinity, setaffinity = g, s }(getaffinity, setaffinity)
+	affinity := map[int]unix.CPUSet{}
+	var all unix.CPUSet
+	for cpu := 0; cpu < 4; cpu++ {
+		all.Set(cpu)
+	}
+	affinity[42] = all
+	getaffinity = func(pid int, set *unix.CPUSet) error {
+		a, ok := affinity[pid]
+		if !ok {
+			return unix.ESRCH
+		}
+		*set = a
+		return nil
+	}
+	setaffinity = func(pid int, set *unix.CPUSet) error {
+		affinity[pid] = *set
+		return nil
+	}
+
+	for _, tt := range []struct {
+		args []string
+		list bool
+		want string
+		err  bool
+	}{
+		{args: []string{"42"}, want: "pid 42's current affinity mask: f\n"},
+		{args: []string{"42"}, list: true, want: "pid 42's current affinity list: 0-3\n"},
+		{args: []string{"1-2", "42"}, list: true, want: "pid 42's current affinity list: 0-3\npid 42's new affinity list: 1-2\n"},
+		{args: []string{"0x9", "42"}, want: "pid 42's current affinity mask: 6\npid 42's new affinity mask: 9\n"},
+		{args: []string{"43"}, err: true},
+		{args: []string{"x"}, err: true},
+		{args: nil, err: true},
+		{args: []string{"1", "2", "42"}, err: true},
+	} {
+		var b bytes.Buffer
+		err := runPID(&b, tt.args, tt.list)
+		if (err != nil) != tt.err || b.String() != tt.want {
+			t.Errorf("runPID(%q, %t) = %q, %v, want %q, error %t", tt.args, tt.list, b.String(), err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const etherTypeVLAN = 0x8100
+
+var etherTypeNames = map[uint16]string{
+	etherTypeIPv4: "IPv4",
+	etherTypeARP:  "ARP",
+	etherTypeIPv6: "IPv6",
+	etherTypeVLAN: "802.1Q",
+}
+
+// summarize returns a one line summary of the Ethernet frame pkt, which was
+// origLen bytes long before it was truncated to the snap length. With
+// linkHeader, the summary starts with the Ethernet header.
+func summarize(pkt []byte, origLen int, linkHeader bool) string {
+	if len(pkt) < ethHeaderLen {
+		return fmt.Sprintf("[|ether], length %d", origLen)
+	}
+	et := binary.BigEndian.Uint16(pkt[12:14])
+	var b strings.Builder
+	if linkHeader {
+		name := etherTypeNames[et]
+		if name == "" {
+			name = "Unknown"
+		}
+		fmt.Fprintf(&b, "%s > %s, ethertype %s (%#04x), length %d: ", net.HardwareAddr(pkt[6:12]), net.HardwareAddr(pkt[0:6]), name, et, origLen)
+	}
+	payload := pkt[ethHeaderLen:]
+	if et == etherTypeVLAN && len(payload) >= 4 {
+		fmt.Fprintf(&b, "vlan %d, ", binary.BigEndian.Uint16(payload[0:2])&0xfff)
+		et = binary.BigEndian.Uint16(payload[2:4])
+		payload = payload[4:]
+	}
+	switch et {
+	case etherTypeIPv4:
+		b.WriteString(summarizeIPv4(payload))
+	case etherTypeIPv6:
+		b.WriteString(summarizeIPv6(payload))
+	case etherTypeARP:
+		b.WriteString(summarizeARP(payload))
+	default:
+		fmt.Fprintf(&b, "ethertype %#04x, length %d", et, origLen)
+	}
+	return b.String()
+}
+
+func summarizeIPv4(p []byte) string {
+	if len(p) < 20 {
+		return "IP [|ip]"
+	}
+	hl := int(p[0]&0xf) * 4
+	total := int(binary.BigEndian.Uint16(p[2:4]))
+	proto := p[9]
+	srcIP, dstIP := net.IP(p[12:16]), net.IP(p[16:20])
+	if hl < 20 || hl > len(p) || total < hl {
+		return fmt.Sprintf("IP %s > %s: bad header", srcIP, dstIP)
+	}
+	// Only the first fragment has the transport header.
+	if frag := binary.BigEndian.Uint16(p[6:8]) & 0x1fff; frag != 0 {
+		return fmt.Sprintf("IP %s > %s: fragment offset %d, proto %d, length %d", srcIP, dstIP, int(frag)*8, proto, total-hl)
+	}
+	return "IP " + summarizeTransport(proto, srcIP, dstIP, p[hl:], total-hl)
+}
+
+func summarizeIPv6(p []byte) string {
+	if len(p) < 40 {
+		return "IP6 [|ip6]"
+	}
+	length := int(binary.BigEndian.Uint16(p[4:6]))
+	return "IP6 " + summarizeTransport(p[6], net.IP(p[8:24]), net.IP(p[24:40]), p[40:], length)
+}
+
+// summarizeTransport summarizes the transport header in p of an IP packet
+// from srcIP to dstIP, whose payload is length bytes.
+func summarizeTransport(proto byte, srcIP, dstIP net.IP, p []byte, length int) string {
+	switch proto {
+	case protoTCP:
+		if len(p) < 20 {
+			return fmt.Sprintf("%s > %s: [|tcp]", srcIP, dstIP)
+		}
+		off := int(p[12]>>4) * 4
+		return fmt.Sprintf("%s.%d > %s.%d: Flags [%s], seq %d, ack %d, win %d, length %d",
+			srcIP, binary.BigEndian.Uint16(p[0:2]), dstIP, binary.BigEndian.Uint16(p[2:4]),
+			tcpFlags(p[13]), binary.BigEndian.Uint32(p[4:8]), binary.BigEndian.Uint32(p[8:12]),
+			binary.BigEndian.Uint16(p[14:16]), length-off)
+	case protoUDP:
+		if len(p) < 8 {
+			return fmt.Sprintf("%s > %s: [|udp]", srcIP, dstIP)
+		}
+		return fmt.Sprintf("%s.%d > %s.%d: UDP, length %d",
+			srcIP, binary.BigEndian.Uint16(p[0:2]), dstIP, binary.BigEndian.Uint16(p[2:4]),
+			int(binary.BigEndian.Uint16(p[4:6]))-8)
+	case protoICMP, protoICMPv6:
+		name := "ICMP"
+		echoRequest, echoReply := byte(8), byte(0)
+		if proto == protoICMPv6 {
+			name, echoRequest, echoReply = "ICMP6", 128, 129
+		}
+		if len(p) < 8 {
+			return fmt.Sprintf("%s > %s: [|%s]", srcIP, dstIP, strings.ToLower(name))
+		}
+		id, seq := binary.BigEndian.Uint16(p[4:6]), binary.BigEndian.Uint16(p[6:8])
+		switch p[0] {
+		case echoRequest:
+			return fmt.Sprintf("%s > %s: %s echo request, id %d, seq %d, length %d", srcIP, dstIP, name, id, seq, length)
+		case echoReply:
+			return fmt.Sprintf("%s > %s: %s echo reply, id %d, seq %d, length %d", srcIP, dstIP, name, id, seq, length)
+		}
+		return fmt.Sprintf("%s > %s: %s type %d, code %d, length %d", srcIP, dstIP, name, p[0], p[1], length)
+	}
+	return fmt.Sprintf("%s > %s: proto %d, length %d", srcIP, dstIP, proto, length)
+}
+
+// tcpFlags formats TCP flags the way tcpdump does, with "." for ACK.
+func tcpFlags(f byte) string {
+	var b strings.Builder
+	for _, fl := range []struct {
+		bit  byte
+		name byte
+	}{
+		{0x02, 'S'}, {0x01, 'F'}, {0x08, 'P'}, {0x04, 'R'}, {0x20, 'U'}, {0x40, 'E'}, {0x80, 'W'}, {0x10, '.'},
+	} {
+		if f&fl.bit != 0 {
+			b.WriteByte(fl.name)
+		}
+	}
+	if b.Len() == 0 {
+		return "none"
+	}
+	return b.String()
+}
+
+func summarizeARP(p []byte) string {
+	// Only Ethernet and IPv4 addresses are decoded.
+	if len(p) < 28 || p[4] != 6 || p[5] != 4 {
+		return "ARP [|arp]"
+	}
+	sha, spa := net.HardwareAddr(p[8:14]), net.IP(p[14:18])
+	tpa := net.IP(p[24:28])
+	switch binary.BigEndian.Uint16(p[6:8]) {
+	case 1:
+		return fmt.Sprintf("ARP, Request who-has %s tell %s, length 28", tpa, spa)
+	case 2:
+		return fmt.Sprintf("ARP, Reply %s is-at %s, length 28", spa, sha)
+	}
+	return fmt.Sprintf("ARP, op %d, length 28", binary.BigEndian.Uint16(p[6:8]))
+}
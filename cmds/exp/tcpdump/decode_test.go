@@ -0,0 +1,103 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		pkt        []byte
+		linkHeader bool
+		want       string
+	}{
+		{
+			name: "udp",
+			pkt:  ether(macB, macA, etherTypeIPv4, ipv4(protoUDP, "0.0.0.0", "255.255.255.255", 0, udpHeader(68, 67, []byte("discover")))),
+			want: "IP 0.0.0.0.68 > 255.255.255.255.67: UDP, length 8",
+		},
+		{
+			name:       "udp with link header",
+			pkt:        ether(macB, macA, etherTypeIPv4, ipv4(protoUDP, "0.0.0.0", "255.255.255.255", 0, udpHeader(68, 67, nil))),
+			linkHeader: true,
+			want:       "02:00:00:00:00:0a > 02:00:00:00:00:0b, ethertype IPv4 (0x0800), length 42: IP 0.0.0.0.68 > 255.255.255.255.67: UDP, length 0",
+		},
+		{
+			name: "tcp",
+			pkt:  ether(macA, macB, etherTypeIPv4, ipv4(protoTCP, "10.0.0.2", "10.0.0.1", 0, tcpHeader(80, 40000, 0x12, 1, 2, []byte("hi")))),
+			want: "IP 10.0.0.2.80 > 10.0.0.1.40000: Flags [S.], seq 1, ack 2, win 65535, length 2",
+		},
+		{
+			name: "icmp echo",
+			pkt:  ether(macB, macA, etherTypeIPv4, ipv4(protoICMP, "10.0.0.1", "10.0.0.2", 0, []byte{8, 0, 0, 0, 0, 7, 0, 1})),
+			want: "IP 10.0.0.1 > 10.0.0.2: ICMP echo request, id 7, seq 1, length 8",
+		},
+		{
+			name: "udp6",
+			pkt:  ether(macB, macA, etherTypeIPv6, ipv6(protoUDP, "fe80::1", "ff02::1:2", udpHeader(546, 547, nil))),
+			want: "IP6 fe80::1.546 > ff02::1:2.547: UDP, length 0",
+		},
+		{
+			name: "arp",
+			pkt:  ether(macB, macA, etherTypeARP, arpRequest(macA, "10.0.0.1", "10.0.0.2")),
+			want: "ARP, Request who-has 10.0.0.2 tell 10.0.0.1, length 28",
+		},
+		{
+			name: "truncated ip",
+			pkt:  ether(macB, macA, etherTypeIPv4, []byte{0x45, 0}),
+			want: "IP [|ip]",
+		},
+		{
+			name: "unknown",
+			pkt:  ether(macB, macA, 0x88cc, []byte{1, 2}),
+			want: "ethertype 0x88cc, length 16",
+		},
+		{
+			name: "runt",
+			pkt:  []byte{1, 2},
+			want: "[|ether], length 2",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarize(tt.pkt, len(tt.pkt), tt.linkHeader); got != tt.want {
+				t.Errorf("summarize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPcapWriter(t *testing.T) {
+	var b bytes.Buffer
+	w, err := newPcapWriter(&b, 96)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt := ether(macB, macA, etherTypeARP, arpRequest(macA, "10.0.0.1", "10.0.0.2"))
+	ts := time.Unix(1600000000, 123456000)
+	if err := w.writePacket(ts, pkt, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.Bytes()
+	if len(out) != 24+16+len(pkt) {
+		t.Fatalf("pcap file is %d bytes, want %d", len(out), 24+16+len(pkt))
+	}
+	le := binary.LittleEndian
+	if le.Uint32(out[0:]) != 0xa1b2c3d4 || le.Uint16(out[4:]) != 2 || le.Uint16(out[6:]) != 4 || le.Uint32(out[16:]) != 96 || le.Uint32(out[20:]) != linkTypeEthernet {
+		t.Errorf("bad global header % x", out[:24])
+	}
+	rec := out[24:40]
+	if le.Uint32(rec[0:]) != 1600000000 || le.Uint32(rec[4:]) != 123456 || le.Uint32(rec[8:]) != uint32(len(pkt)) || le.Uint32(rec[12:]) != 60 {
+		t.Errorf("bad record header % x", rec)
+	}
+	if !bytes.Equal(out[40:], pkt) {
+		t.Errorf("packet data = % x, want % x", out[40:], pkt)
+	}
+}
@@ -0,0 +1,518 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Classic BPF opcodes, from linux/bpf_common.h.
+const (
+	bpfLD  = 0x00
+	bpfLDX = 0x01
+	bpfALU = 0x04
+	bpfJMP = 0x05
+	bpfRET = 0x06
+
+	bpfW = 0x00
+	bpfH = 0x08
+	bpfB = 0x10
+
+	bpfABS = 0x20
+	bpfIND = 0x40
+	bpfMSH = 0xa0
+
+	bpfAND = 0x50
+	bpfJA  = 0x00
+	bpfJEQ = 0x10
+	bpfK   = 0x00
+)
+
+// Ethernet types and IP protocols the filter knows.
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeARP  = 0x0806
+	etherTypeIPv6 = 0x86dd
+
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+// ethHeaderLen is the length of the Ethernet header, which all offsets in the
+// filter are relative to.
+const ethHeaderLen = 14
+
+// bpfInsn is a classic BPF instruction, struct sock_filter.
+type bpfInsn struct {
+	Code uint16
+	Jt   uint8
+	Jf   uint8
+	K    uint32
+}
+
+// node is a node of a parsed filter expression.
+type node interface{}
+
+type andNode struct{ a, b node }
+
+type orNode struct{ a, b node }
+
+type notNode struct{ a node }
+
+// cmpNode matches if the size bytes at off, masked with mask if it is not
+// 0, equal val. If ipPayload is set, off is relative to the payload of the
+// IPv4 header, whose length varies.
+type cmpNode struct {
+	ipPayload bool
+	size      int
+	off       uint32
+	mask      uint32
+	val       uint32
+}
+
+func and(nodes ...node) node {
+	n := nodes[0]
+	for _, m := range nodes[1:] {
+		n = andNode{n, m}
+	}
+	return n
+}
+
+func or(nodes ...node) node {
+	n := nodes[0]
+	for _, m := range nodes[1:] {
+		n = orNode{n, m}
+	}
+	return n
+}
+
+func etherType(t uint32) node {
+	return cmpNode{size: 2, off: 12, val: t}
+}
+
+func ipv4Proto(p uint32) node {
+	return and(etherType(etherTypeIPv4), cmpNode{size: 1, off: ethHeaderLen + 9, val: p})
+}
+
+// ipv6Proto matches the next header of the fixed IPv6 header. Extension
+// headers are not followed.
+func ipv6Proto(p uint32) node {
+	return and(etherType(etherTypeIPv6), cmpNode{size: 1, off: ethHeaderLen + 6, val: p})
+}
+
+// ipv4Addr matches the 4 bytes of a at off.
+func ipv4Addr(off uint32, a net.IP, mask net.IPMask) node {
+	n := cmpNode{size: 4, off: off, val: binary.BigEndian.Uint32(a.To4())}
+	if mask != nil {
+		if m := binary.BigEndian.Uint32(mask); m != 0xffffffff {
+			n.mask = m
+		}
+	}
+	return n
+}
+
+// bytesAt matches b at off, 4 bytes at a time.
+func bytesAt(off uint32, b []byte) node {
+	var nodes []node
+	for len(b) >= 4 {
+		nodes = append(nodes, cmpNode{size: 4, off: off, val: binary.BigEndian.Uint32(b)})
+		off, b = off+4, b[4:]
+	}
+	if len(b) == 2 {
+		nodes = append(nodes, cmpNode{size: 2, off: off, val: uint32(binary.BigEndian.Uint16(b))})
+	}
+	return and(nodes...)
+}
+
+// direction is the src or dst qualifier of a primitive.
+type direction int
+
+const (
+	srcOrDst direction = iota
+	src
+	dst
+)
+
+func (d direction) pick(srcNode, dstNode func() node) node {
+	switch d {
+	case src:
+		return srcNode()
+	case dst:
+		return dstNode()
+	}
+	return or(srcNode(), dstNode())
+}
+
+func hostNode(d direction, addr string) (node, error) {
+	ip := net.ParseIP(addr)
+	switch {
+	case ip == nil:
+		return nil, fmt.Errorf("invalid host %q", addr)
+	case ip.To4() != nil:
+		return and(etherType(etherTypeIPv4), d.pick(
+			func() node { return ipv4Addr(ethHeaderLen+12, ip, nil) },
+			func() node { return ipv4Addr(ethHeaderLen+16, ip, nil) },
+		)), nil
+	}
+	return and(etherType(etherTypeIPv6), d.pick(
+		func() node { return bytesAt(ethHeaderLen+8, ip) },
+		func() node { return bytesAt(ethHeaderLen+24, ip) },
+	)), nil
+}
+
+func netNode(d direction, cidr string) (node, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil || n.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 network %q", cidr)
+	}
+	return and(etherType(etherTypeIPv4), d.pick(
+		func() node { return ipv4Addr(ethHeaderLen+12, n.IP, n.Mask) },
+		func() node { return ipv4Addr(ethHeaderLen+16, n.IP, n.Mask) },
+	)), nil
+}
+
+func etherNode(d direction, addr string) (node, error) {
+	mac, err := net.ParseMAC(addr)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("invalid Ethernet address %q", addr)
+	}
+	return d.pick(
+		func() node { return bytesAt(6, mac) },
+		func() node { return bytesAt(0, mac) },
+	), nil
+}
+
+// portNode matches TCP or UDP, or only proto if it is not 0, packets with
+// port. IPv4 fragments other than the first are not matched.
+func portNode(d direction, proto uint32, port string) (node, error) {
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+	protos := []uint32{protoTCP, protoUDP}
+	if proto != 0 {
+		protos = []uint32{proto}
+	}
+	var v4, v6 []node
+	for _, pr := range protos {
+		v4 = append(v4, ipv4Proto(pr))
+		v6 = append(v6, ipv6Proto(pr))
+	}
+	firstFragment := cmpNode{size: 2, off: ethHeaderLen + 6, mask: 0x1fff, val: 0}
+	v4Ports := d.pick(
+		func() node { return cmpNode{ipPayload: true, size: 2, off: 0, val: uint32(p)} },
+		func() node { return cmpNode{ipPayload: true, size: 2, off: 2, val: uint32(p)} },
+	)
+	v6Ports := d.pick(
+		func() node { return cmpNode{size: 2, off: ethHeaderLen + 40, val: uint32(p)} },
+		func() node { return cmpNode{size: 2, off: ethHeaderLen + 42, val: uint32(p)} },
+	)
+	return or(
+		and(or(v4...), firstFragment, v4Ports),
+		and(or(v6...), v6Ports),
+	), nil
+}
+
+// parser parses a filter expression, a subset of the pcap-filter syntax:
+//
+//     expr:      and { (or | ||) and }
+//     and:       unary { (and | &&) unary }
+//     unary:     (not | !) unary | ( expr ) | primitive
+//     primitive: ip | ip6 | arp | icmp | icmp6
+//                [tcp | udp] [src | dst] port PORT
+//                [src | dst] [host] ADDRESS
+//                [src | dst] net CIDR
+//                ether [src | dst] [host] MAC
+type parser struct {
+	tokens []string
+}
+
+func tokenize(s string) []string {
+	for _, op := range []string{"(", ")", "!", "&&", "||"} {
+		s = strings.Replace(s, op, " "+op+" ", -1)
+	}
+	return strings.Fields(s)
+}
+
+func (p *parser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	if len(p.tokens) > 0 {
+		p.tokens = p.tokens[1:]
+	}
+	return t
+}
+
+func (p *parser) want(what string) (string, error) {
+	t := p.next()
+	if t == "" {
+		return "", fmt.Errorf("missing %s", what)
+	}
+	return t, nil
+}
+
+func (p *parser) expr() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" || p.peek() == "||" {
+		p.next()
+		m, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		n = orNode{n, m}
+	}
+	return n, nil
+}
+
+func (p *parser) and() (node, error) {
+	n, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" || p.peek() == "&&" {
+		p.next()
+		m, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		n = andNode{n, m}
+	}
+	return n, nil
+}
+
+func (p *parser) unary() (node, error) {
+	switch p.peek() {
+	case "not", "!":
+		p.next()
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case "(":
+		p.next()
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t != ")" {
+			return nil, fmt.Errorf("want ), got %q", t)
+		}
+		return n, nil
+	}
+	return p.primitive()
+}
+
+func (p *parser) direction() direction {
+	switch p.peek() {
+	case "src":
+		p.next()
+		return src
+	case "dst":
+		p.next()
+		return dst
+	}
+	return srcOrDst
+}
+
+func (p *parser) primitive() (node, error) {
+	t := p.peek()
+	switch t {
+	case "":
+		return nil, fmt.Errorf("missing primitive")
+	case "ip":
+		p.next()
+		return etherType(etherTypeIPv4), nil
+	case "ip6":
+		p.next()
+		return etherType(etherTypeIPv6), nil
+	case "arp":
+		p.next()
+		return etherType(etherTypeARP), nil
+	case "icmp":
+		p.next()
+		return ipv4Proto(protoICMP), nil
+	case "icmp6":
+		p.next()
+		return ipv6Proto(protoICMPv6), nil
+	case "tcp", "udp":
+		p.next()
+		proto := uint32(protoTCP)
+		if t == "udp" {
+			proto = protoUDP
+		}
+		if q := p.peek(); q != "src" && q != "dst" && q != "port" {
+			return or(ipv4Proto(proto), ipv6Proto(proto)), nil
+		}
+		d := p.direction()
+		if q := p.next(); q != "port" {
+			return nil, fmt.Errorf("want port after %s, got %q", t, q)
+		}
+		port, err := p.want("port")
+		if err != nil {
+			return nil, err
+		}
+		return portNode(d, proto, port)
+	case "ether":
+		p.next()
+		d := p.direction()
+		if p.peek() == "host" {
+			p.next()
+		}
+		addr, err := p.want("Ethernet address")
+		if err != nil {
+			return nil, err
+		}
+		return etherNode(d, addr)
+	}
+
+	d := p.direction()
+	switch p.peek() {
+	case "port":
+		p.next()
+		port, err := p.want("port")
+		if err != nil {
+			return nil, err
+		}
+		return portNode(d, 0, port)
+	case "net":
+		p.next()
+		cidr, err := p.want("network")
+		if err != nil {
+			return nil, err
+		}
+		return netNode(d, cidr)
+	case "host":
+		p.next()
+	}
+	addr, err := p.want("host")
+	if err != nil {
+		return nil, err
+	}
+	return hostNode(d, addr)
+}
+
+func parseFilter(expr string) (node, error) {
+	p := &parser{tokens: tokenize(expr)}
+	if len(p.tokens) == 0 {
+		return nil, nil
+	}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.tokens) > 0 {
+		return nil, fmt.Errorf("unexpected %q", p.peek())
+	}
+	return n, nil
+}
+
+// asmInsn is an instruction whose jump targets are labels.
+type asmInsn struct {
+	bpfInsn
+	jump   bool
+	jt, jf int
+}
+
+type assembler struct {
+	prog []asmInsn
+	// labels are the instruction indexes of the labels.
+	labels []int
+}
+
+func (a *assembler) label() int {
+	a.labels = append(a.labels, -1)
+	return len(a.labels) - 1
+}
+
+// place makes l refer to the next instruction.
+func (a *assembler) place(l int) {
+	a.labels[l] = len(a.prog)
+}
+
+func (a *assembler) emit(code uint16, k uint32) {
+	a.prog = append(a.prog, asmInsn{bpfInsn: bpfInsn{Code: code, K: k}})
+}
+
+// gen emits code for n that jumps to label t if the packet matches, and to f
+// otherwise.
+func (a *assembler) gen(n node, t, f int) {
+	switch n := n.(type) {
+	case andNode:
+		m := a.label()
+		a.gen(n.a, m, f)
+		a.place(m)
+		a.gen(n.b, t, f)
+	case orNode:
+		m := a.label()
+		a.gen(n.a, t, m)
+		a.place(m)
+		a.gen(n.b, t, f)
+	case notNode:
+		a.gen(n.a, f, t)
+	case cmpNode:
+		size := map[int]uint16{1: bpfB, 2: bpfH, 4: bpfW}[n.size]
+		if n.ipPayload {
+			// X = length of the IPv4 header.
+			a.emit(bpfLDX|bpfB|bpfMSH, ethHeaderLen)
+			a.emit(bpfLD|size|bpfIND, ethHeaderLen+n.off)
+		} else {
+			a.emit(bpfLD|size|bpfABS, n.off)
+		}
+		if n.mask != 0 {
+			a.emit(bpfALU|bpfAND|bpfK, n.mask)
+		}
+		a.prog = append(a.prog, asmInsn{bpfInsn: bpfInsn{Code: bpfJMP | bpfJEQ | bpfK, K: n.val}, jump: true, jt: t, jf: f})
+	default:
+		panic(fmt.Sprintf("unknown filter node %T", n))
+	}
+}
+
+// compileFilter compiles a filter expression to a classic BPF program that
+// returns snaplen for matching packets and 0 for all others.
+func compileFilter(expr string, snaplen uint32) ([]bpfInsn, error) {
+	n, err := parseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return []bpfInsn{{Code: bpfRET | bpfK, K: snaplen}}, nil
+	}
+	var a assembler
+	accept, reject := a.label(), a.label()
+	a.gen(n, accept, reject)
+	a.place(accept)
+	a.emit(bpfRET|bpfK, snaplen)
+	a.place(reject)
+	a.emit(bpfRET|bpfK, 0)
+
+	prog := make([]bpfInsn, len(a.prog))
+	for pc, in := range a.prog {
+		if in.jump {
+			jt, jf := a.labels[in.jt]-pc-1, a.labels[in.jf]-pc-1
+			if jt > 255 || jf > 255 {
+				return nil, fmt.Errorf("filter is too long")
+			}
+			in.Jt, in.Jf = uint8(jt), uint8(jf)
+		}
+		prog[pc] = in.bpfInsn
+	}
+	return prog, nil
+}
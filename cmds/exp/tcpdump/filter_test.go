@@ -0,0 +1,202 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// runFilter runs prog on pkt like the kernel does, and returns how many
+// bytes of pkt it accepts.
+func runFilter(t *testing.T, prog []bpfInsn, pkt []byte) uint32 {
+	load := func(off uint32, size uint16) (uint32, bool) {
+		n := map[uint16]uint32{bpfB: 1, bpfH: 2, bpfW: 4}[size]
+		if uint64(off)+uint64(n) > uint64(len(pkt)) {
+			return 0, false
+		}
+		switch n {
+		case 1:
+			return uint32(pkt[off]), true
+		case 2:
+			return uint32(binary.BigEndian.Uint16(pkt[off:])), true
+		}
+		return binary.BigEndian.Uint32(pkt[off:]), true
+	}
+	var a, x uint32
+	for pc := 0; pc < len(prog); pc++ {
+		in := prog[pc]
+		switch {
+		case in.Code == bpfRET|bpfK:
+			return in.K
+		case in.Code == bpfLDX|bpfB|bpfMSH:
+			if int(in.K) >= len(pkt) {
+				return 0
+			}
+			x = uint32(pkt[in.K]&0xf) * 4
+		case in.Code&0x07 == bpfLD && in.Code&0xe0 == bpfABS:
+			v, ok := load(in.K, in.Code&0x18)
+			if !ok {
+				return 0
+			}
+			a = v
+		case in.Code&0x07 == bpfLD && in.Code&0xe0 == bpfIND:
+			v, ok := load(x+in.K, in.Code&0x18)
+			if !ok {
+				return 0
+			}
+			a = v
+		case in.Code == bpfALU|bpfAND|bpfK:
+			a &= in.K
+		case in.Code == bpfJMP|bpfJEQ|bpfK:
+			if a == in.K {
+				pc += int(in.Jt)
+			} else {
+				pc += int(in.Jf)
+			}
+		default:
+			t.Fatalf("unknown instruction %+v", in)
+		}
+	}
+	t.Fatal("filter ran past its end")
+	return 0
+}
+
+var (
+	macA = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x0a}
+	macB = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x0b}
+)
+
+func ether(dst, src net.HardwareAddr, et uint16, payload []byte) []byte {
+	b := append(append([]byte{}, dst...), src...)
+	b = append(b, byte(et>>8), byte(et))
+	return append(b, payload...)
+}
+
+// ipv4 returns an IPv4 packet with a header of 20 bytes plus options bytes.
+func ipv4(proto byte, src, dst string, options int, payload []byte) []byte {
+	hl := 20 + options
+	b := make([]byte, hl)
+	b[0] = 0x40 | byte(hl/4)
+	binary.BigEndian.PutUint16(b[2:], uint16(hl+len(payload)))
+	b[8], b[9] = 64, proto
+	copy(b[12:], net.ParseIP(src).To4())
+	copy(b[16:], net.ParseIP(dst).To4())
+	return append(b, payload...)
+}
+
+func ipv6(next byte, src, dst string, payload []byte) []byte {
+	b := make([]byte, 40)
+	b[0] = 0x60
+	binary.BigEndian.PutUint16(b[4:], uint16(len(payload)))
+	b[6], b[7] = next, 64
+	copy(b[8:], net.ParseIP(src))
+	copy(b[24:], net.ParseIP(dst))
+	return append(b, payload...)
+}
+
+func udpHeader(sport, dport uint16, payload []byte) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[0:], sport)
+	binary.BigEndian.PutUint16(b[2:], dport)
+	binary.BigEndian.PutUint16(b[4:], uint16(8+len(payload)))
+	return append(b, payload...)
+}
+
+func tcpHeader(sport, dport uint16, flags byte, seq, ack uint32, payload []byte) []byte {
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint16(b[0:], sport)
+	binary.BigEndian.PutUint16(b[2:], dport)
+	binary.BigEndian.PutUint32(b[4:], seq)
+	binary.BigEndian.PutUint32(b[8:], ack)
+	b[12], b[13] = 5<<4, flags
+	binary.BigEndian.PutUint16(b[14:], 65535)
+	return append(b, payload...)
+}
+
+func arpRequest(sha net.HardwareAddr, spa, tpa string) []byte {
+	b := []byte{0, 1, 0x08, 0, 6, 4, 0, 1}
+	b = append(b, sha...)
+	b = append(b, net.ParseIP(spa).To4()...)
+	b = append(b, make([]byte, 6)...)
+	return append(b, net.ParseIP(tpa).To4()...)
+}
+
+func TestFilter(t *testing.T) {
+	dhcp := ether(macB, macA, etherTypeIPv4, ipv4(protoUDP, "0.0.0.0", "255.255.255.255", 0, udpHeader(68, 67, []byte("discover"))))
+	// IP options move the UDP header.
+	dhcpOptions := ether(macB, macA, etherTypeIPv4, ipv4(protoUDP, "10.0.0.1", "10.0.0.2", 8, udpHeader(68, 67, nil)))
+	fragment := ether(macB, macA, etherTypeIPv4, ipv4(protoUDP, "10.0.0.1", "10.0.0.2", 0, udpHeader(68, 67, nil)))
+	binary.BigEndian.PutUint16(fragment[ethHeaderLen+6:], 100)
+	http := ether(macA, macB, etherTypeIPv4, ipv4(protoTCP, "10.0.0.2", "10.0.0.1", 0, tcpHeader(80, 40000, 0x12, 1, 2, nil)))
+	dhcp6 := ether(macB, macA, etherTypeIPv6, ipv6(protoUDP, "fe80::1", "ff02::1:2", udpHeader(546, 547, nil)))
+	ping := ether(macB, macA, etherTypeIPv4, ipv4(protoICMP, "10.0.0.1", "10.0.0.2", 0, []byte{8, 0, 0, 0, 0, 1, 0, 1}))
+	arp := ether(net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, macA, etherTypeARP, arpRequest(macA, "10.0.0.1", "10.0.0.2"))
+	runt := []byte{1, 2, 3}
+
+	for _, tt := range []struct {
+		expr  string
+		match [][]byte
+		miss  [][]byte
+	}{
+		{expr: "", match: [][]byte{dhcp, http, arp, runt}},
+		{expr: "ip", match: [][]byte{dhcp, http, ping}, miss: [][]byte{dhcp6, arp, runt}},
+		{expr: "ip6", match: [][]byte{dhcp6}, miss: [][]byte{dhcp, arp}},
+		{expr: "arp", match: [][]byte{arp}, miss: [][]byte{dhcp}},
+		{expr: "icmp", match: [][]byte{ping}, miss: [][]byte{dhcp, http}},
+		{expr: "tcp", match: [][]byte{http}, miss: [][]byte{dhcp, dhcp6}},
+		{expr: "udp", match: [][]byte{dhcp, dhcp6}, miss: [][]byte{http, ping}},
+		{expr: "port 67", match: [][]byte{dhcp, dhcpOptions}, miss: [][]byte{fragment, http, dhcp6, runt}},
+		{expr: "udp dst port 547", match: [][]byte{dhcp6}, miss: [][]byte{dhcp}},
+		{expr: "src port 80", match: [][]byte{http}, miss: [][]byte{dhcp}},
+		{expr: "tcp port 67", miss: [][]byte{dhcp}},
+		{expr: "udp port 67 or udp port 68 or arp", match: [][]byte{dhcp, arp}, miss: [][]byte{http, dhcp6}},
+		{expr: "host 10.0.0.1", match: [][]byte{http, ping, dhcpOptions}, miss: [][]byte{dhcp, arp}},
+		{expr: "src 10.0.0.2", match: [][]byte{http}, miss: [][]byte{ping}},
+		{expr: "dst host ff02::1:2", match: [][]byte{dhcp6}, miss: [][]byte{dhcp}},
+		{expr: "net 10.0.0.0/24", match: [][]byte{http, ping}, miss: [][]byte{dhcp}},
+		{expr: "dst net 255.0.0.0/8", match: [][]byte{dhcp}, miss: [][]byte{http}},
+		{expr: "ether src 02:00:00:00:00:0a", match: [][]byte{dhcp, arp}, miss: [][]byte{http}},
+		{expr: "ether host 02:00:00:00:00:0a", match: [][]byte{dhcp, http}},
+		{expr: "not arp and not ip6", match: [][]byte{dhcp, http}, miss: [][]byte{arp, dhcp6}},
+		{expr: "!(udp || icmp) && ip", match: [][]byte{http}, miss: [][]byte{dhcp, ping, arp}},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			prog, err := compileFilter(tt.expr, 1500)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, pkt := range tt.match {
+				if got := runFilter(t, prog, pkt); got != 1500 {
+					t.Errorf("packet %d: filter returned %d, want a match", i, got)
+				}
+			}
+			for i, pkt := range tt.miss {
+				if got := runFilter(t, prog, pkt); got != 0 {
+					t.Errorf("packet %d: filter returned %d, want no match", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"port",
+		"port http",
+		"host 10.0.0",
+		"net 10.0.0.0",
+		"ether host 02:00",
+		"tcp and",
+		"(ip",
+		"ip)",
+		"udp src 10.0.0.1",
+	} {
+		if _, err := compileFilter(expr, 1500); err == nil {
+			t.Errorf("compileFilter(%q) = nil, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// linkTypeEthernet is LINKTYPE_ETHERNET of pcap files.
+const linkTypeEthernet = 1
+
+// pcapHeader is the global header of a pcap file with microsecond
+// timestamps.
+type pcapHeader struct {
+	Magic        uint32
+	VersionMajor uint16
+	VersionMinor uint16
+	ThisZone     int32
+	SigFigs      uint32
+	SnapLen      uint32
+	LinkType     uint32
+}
+
+// pcapRecord is the header of each packet in a pcap file.
+type pcapRecord struct {
+	Sec     uint32
+	Usec    uint32
+	InclLen uint32
+	OrigLen uint32
+}
+
+// pcapWriter writes Ethernet frames in the pcap format.
+type pcapWriter struct {
+	w io.Writer
+}
+
+func newPcapWriter(w io.Writer, snaplen uint32) (*pcapWriter, error) {
+	h := pcapHeader{
+		Magic:        0xa1b2c3d4,
+		VersionMajor: 2,
+		VersionMinor: 4,
+		SnapLen:      snaplen,
+		LinkType:     linkTypeEthernet,
+	}
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return nil, err
+	}
+	return &pcapWriter{w: w}, nil
+}
+
+// writePacket writes pkt, captured at ts, which was origLen bytes before it
+// was truncated to the snap length.
+func (p *pcapWriter) writePacket(ts time.Time, pkt []byte, origLen int) error {
+	r := pcapRecord{
+		Sec:     uint32(ts.Unix()),
+		Usec:    uint32(ts.Nanosecond() / 1000),
+		InclLen: uint32(len(pkt)),
+		OrigLen: uint32(origLen),
+	}
+	if err := binary.Write(p.w, binary.LittleEndian, r); err != nil {
+		return err
+	}
+	_, err := p.w.Write(pkt)
+	return err
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// tcpdump captures packets and prints a summary of each.
+//
+// Synopsis:
+//     tcpdump [-i INTERFACE] [-c COUNT] [-s SNAPLEN] [-w FILE] [-e] [-p] [-d] [EXPRESSION]
+//
+// Description:
+//     Capture packets on INTERFACE, or all interfaces, with an AF_PACKET
+//     socket, and print a line with the Ethernet, IP, TCP, UDP, ICMP or ARP
+//     headers of each. Only packets that match EXPRESSION are captured. It
+//     is compiled to a classic BPF program that the kernel runs on each
+//     packet. EXPRESSION is a subset of the pcap-filter syntax:
+//
+//     ip, ip6, arp, icmp, icmp6, tcp, udp
+//     [tcp|udp] [src|dst] port PORT
+//     [src|dst] [host] ADDRESS
+//     [src|dst] net CIDR            (IPv4 only)
+//     ether [src|dst] [host] MAC
+//
+//     combined with and (&&), or (||), not (!) and parentheses, e.g.
+//
+//     tcpdump -i eth0 'udp port 67 or udp port 68 or arp'
+//
+// Options:
+//     -i: interface to capture on, all interfaces if empty
+//     -c: exit after COUNT packets
+//     -s: capture at most SNAPLEN bytes of each packet
+//     -w: write the packets to FILE in the pcap format instead of printing them
+//     -e: print the Ethernet header
+//     -p: do not put the interface into promiscuous mode
+//     -d: print the compiled filter and exit
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	iface      = flag.String("i", "", "Interface to capture on, all interfaces if empty")
+	count      = flag.Int("c", 0, "Exit after this many packets")
+	snaplen    = flag.Int("s", 262144, "Capture at most this many bytes of each packet")
+	writeFile  = flag.String("w", "", "Write the packets to this file in the pcap format instead of printing them")
+	linkHeader = flag.Bool("e", false, "Print the Ethernet header")
+	noPromisc  = flag.Bool("p", false, "Do not put the interface into promiscuous mode")
+	dumpFilter = flag.Bool("d", false, "Print the compiled filter and exit")
+)
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// openSocket opens an AF_PACKET socket that receives the packets of ifindex,
+// or all interfaces if it is 0, that prog accepts.
+func openSocket(ifindex int, prog []bpfInsn, promisc bool) (int, error) {
+	// The socket receives no packets until it is bound, so none get in
+	// before the filter is attached.
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("opening packet socket: %v", err)
+	}
+	filter := make([]unix.SockFilter, len(prog))
+	for i, in := range prog {
+		filter[i] = unix.SockFilter{Code: in.Code, Jt: in.Jt, Jf: in.Jf, K: in.K}
+	}
+	fprog := unix.SockFprog{Len: uint16(len(filter)), Filter: (*unix.SockFilter)(unsafe.Pointer(&filter[0]))}
+	if err := unix.SetsockoptSockFprog(fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, &fprog); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("attaching filter: %v", err)
+	}
+	// Wake up regularly to check for signals.
+	tv := unix.NsecToTimeval((200 * time.Millisecond).Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: ifindex}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("binding packet socket: %v", err)
+	}
+	if promisc && ifindex != 0 {
+		mreq := unix.PacketMreq{Ifindex: int32(ifindex), Type: unix.PACKET_MR_PROMISC}
+		if err := unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+			unix.Close(fd)
+			return -1, fmt.Errorf("enabling promiscuous mode: %v", err)
+		}
+	}
+	return fd, nil
+}
+
+// handler handles a captured packet.
+type handler func(ts time.Time, pkt []byte, origLen int) error
+
+func capture(fd int, max int, snap int, stop *int32, h handler) (int, error) {
+	buf := make([]byte, snap)
+	n := 0
+	for (max == 0 || n < max) && atomic.LoadInt32(stop) == 0 {
+		// MSG_TRUNC returns the length of the packet before it was
+		// truncated.
+		l, from, err := unix.Recvfrom(fd, buf, unix.MSG_TRUNC)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		// Loopback packets are seen both going out and coming in,
+		// show them once, as libpcap does.
+		if ll, ok := from.(*unix.SockaddrLinklayer); ok && ll.Pkttype == unix.PACKET_OUTGOING && ll.Hatype == unix.ARPHRD_LOOPBACK {
+			continue
+		}
+		pkt := buf
+		if l < len(buf) {
+			pkt = buf[:l]
+		}
+		if err := h(time.Now(), pkt, l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func run(args []string, stdout io.Writer) error {
+	if *snaplen <= 0 {
+		return fmt.Errorf("invalid snap length %d", *snaplen)
+	}
+	prog, err := compileFilter(strings.Join(args, " "), uint32(*snaplen))
+	if err != nil {
+		return fmt.Errorf("filter: %v", err)
+	}
+	if *dumpFilter {
+		for _, in := range prog {
+			fmt.Fprintf(stdout, "{ %#04x, %d, %d, %#08x },\n", in.Code, in.Jt, in.Jf, in.K)
+		}
+		return nil
+	}
+
+	ifindex := 0
+	if *iface != "" {
+		ifc, err := net.InterfaceByName(*iface)
+		if err != nil {
+			return err
+		}
+		ifindex = ifc.Index
+	}
+	fd, err := openSocket(ifindex, prog, !*noPromisc)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	var h handler
+	if *writeFile != "" {
+		f, err := os.Create(*writeFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		bw := bufio.NewWriter(f)
+		defer bw.Flush()
+		pw, err := newPcapWriter(bw, uint32(*snaplen))
+		if err != nil {
+			return err
+		}
+		h = pw.writePacket
+	} else {
+		h = func(ts time.Time, pkt []byte, origLen int) error {
+			_, err := fmt.Fprintf(stdout, "%s %s\n", ts.Format("15:04:05.000000"), summarize(pkt, origLen, *linkHeader))
+			return err
+		}
+	}
+
+	var stop int32
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, unix.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		<-sig
+		atomic.StoreInt32(&stop, 1)
+	}()
+
+	where := "all interfaces"
+	if *iface != "" {
+		where = *iface
+	}
+	log.Printf("listening on %s, capture size %d bytes", where, *snaplen)
+	n, err := capture(fd, *count, *snaplen, &stop, h)
+	log.Printf("%d packets captured", n)
+	return err
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tcpdump: ")
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
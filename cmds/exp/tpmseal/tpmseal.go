@@ -0,0 +1,170 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// tpmseal seals data, e.g. a disk encryption key, to TPM 2.0 PCR values.
+//
+// Synopsis:
+//     tpmseal [-a ALG] [-p PCRS] [-nv INDEX] [-o PASSWORD] seal [FILE]
+//     tpmseal [-a ALG] [-p PCRS] [-nv INDEX] [-o PASSWORD] unseal [FILE]
+//
+// Description:
+//     seal:   seal the contents of FILE, or stdin, to the current values of
+//             PCRS. The sealed blob is written to stdout, or to INDEX.
+//     unseal: unseal the blob in FILE, stdin, or INDEX, and write the data
+//             to stdout. This fails if the PCRs changed since sealing.
+//
+//     The sealed blob is not secret; only the TPM it was sealed by can
+//     unseal it. To unlock a LUKS volume during boot:
+//
+//     tpmseal -nv 0x1500001 unseal | cryptsetup open --key-file=- /dev/sda2 root
+//
+// Options:
+//     -a:  PCR bank, sha1, sha256, sha384 or sha512 (default sha256)
+//     -p:  comma separated list of PCRs (default 0,2,4,7)
+//     -nv: NV index to store the sealed blob in, instead of a file
+//     -o:  owner password for the NV index
+package main
+
+import (
+	"crypto"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/tss"
+)
+
+const usage = "usage: tpmseal [-a ALG] [-p PCRS] [-nv INDEX] [-o PASSWORD] seal|unseal [FILE]"
+
+var (
+	algName  = flag.String("a", "sha256", "PCR bank: sha1, sha256, sha384 or sha512")
+	pcrList  = flag.String("p", "0,2,4,7", "comma separated list of PCRs to seal to")
+	nvIndex  = flag.String("nv", "", "NV index to store the sealed blob in")
+	ownerPwd = flag.String("o", "", "owner password for the NV index")
+)
+
+var algs = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// parsePCRs parses a comma separated list of PCR indices.
+func parsePCRs(s string) ([]int, error) {
+	var pcrs []int
+	for _, f := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || i < 0 || i > 23 {
+			return nil, fmt.Errorf("invalid PCR index %q, must be 0-23", f)
+		}
+		pcrs = append(pcrs, i)
+	}
+	return pcrs, nil
+}
+
+// parseIndex parses an NV index. It has to be in the owner range.
+func parseIndex(s string) (uint32, error) {
+	i, err := strconv.ParseUint(s, 0, 32)
+	if err != nil || i < 0x01000000 || i > 0x01ffffff {
+		return 0, fmt.Errorf("invalid NV index %q, must be 0x01000000-0x01ffffff", s)
+	}
+	return uint32(i), nil
+}
+
+func readInput(args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(args[0])
+}
+
+func run(args []string, stdout io.Writer) error {
+	alg, ok := algs[*algName]
+	if !ok {
+		return fmt.Errorf("unknown hash algorithm %q", *algName)
+	}
+	pcrs, err := parsePCRs(*pcrList)
+	if err != nil {
+		return err
+	}
+	var index uint32
+	if *nvIndex != "" {
+		if index, err = parseIndex(*nvIndex); err != nil {
+			return err
+		}
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(usage)
+	}
+	cmd, args := args[0], args[1:]
+
+	var in []byte
+	switch cmd {
+	case "seal":
+		if in, err = readInput(args); err != nil {
+			return err
+		}
+		if len(in) == 0 {
+			return errors.New("nothing to seal")
+		}
+	case "unseal":
+		if index != 0 {
+			if len(args) != 0 {
+				return errors.New(usage)
+			}
+			break
+		}
+		if in, err = readInput(args); err != nil {
+			return err
+		}
+	default:
+		return errors.New(usage)
+	}
+
+	tpm, err := tss.NewTPM()
+	if err != nil {
+		return fmt.Errorf("no TPM found: %v", err)
+	}
+	defer tpm.Close()
+
+	if cmd == "seal" {
+		blob, err := tpm.Seal(in, pcrs, alg)
+		if err != nil {
+			return err
+		}
+		if index != 0 {
+			return tpm.WriteSealedNV(index, blob, *ownerPwd)
+		}
+		_, err = stdout.Write(blob)
+		return err
+	}
+
+	if index != 0 {
+		if in, err = tpm.ReadSealedNV(index, *ownerPwd); err != nil {
+			return err
+		}
+	}
+	data, err := tpm.Unseal(in, pcrs, alg)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tpmseal: ")
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
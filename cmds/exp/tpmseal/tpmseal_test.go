@@ -0,0 +1,69 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParsePCRs(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []int
+		err  bool
+	}{
+		{in: "7", want: []int{7}},
+		{in: "0,2, 4,7", want: []int{0, 2, 4, 7}},
+		{in: "24", err: true},
+		{in: "1,,2", err: true},
+		{in: "", err: true},
+	} {
+		got, err := parsePCRs(tt.in)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePCRs(%q) = (%v, %v), want (%v, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want uint32
+		err  bool
+	}{
+		{in: "0x1500001", want: 0x1500001},
+		{in: "22020097", want: 0x1500001},
+		{in: "0x1", err: true},
+		{in: "0x81000001", err: true},
+		{in: "x", err: true},
+	} {
+		got, err := parseIndex(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseIndex(%q) = (%#x, %v), want (%#x, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	empty, err := ioutil.TempFile("", "tpmseal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty.Close()
+	for _, args := range [][]string{
+		nil,
+		{"foo"},
+		{"seal", "a", "b"},
+		{"seal", "/nonexistent"},
+		{"seal", empty.Name()},
+		{"unseal", "/nonexistent"},
+	} {
+		if err := run(args, ioutil.Discard); err == nil {
+			t.Errorf("run(%q) succeeded, want error", args)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ICMP and ICMPv6 types.
+const (
+	icmpEchoReply     = 0
+	icmpUnreachable   = 3
+	icmpEchoRequest   = 8
+	icmpTimeExceeded  = 11
+	icmp6Unreachable  = 1
+	icmp6TimeExceeded = 3
+	icmp6EchoRequest  = 128
+	icmp6EchoReply    = 129
+)
+
+// Port unreachable codes.
+const (
+	icmpPortUnreach  = 3
+	icmp6PortUnreach = 4
+)
+
+// IP protocol numbers.
+const (
+	protoICMP   = 1
+	protoUDP    = 17
+	protoICMPv6 = 58
+)
+
+const (
+	icmpHeaderLen = 8
+	ipv6HeaderLen = 40
+	// transportHeaderLen is the length of the probe that ICMP errors
+	// carry at least.
+	transportHeaderLen = 8
+)
+
+// Kinds of replies to a probe.
+const (
+	timeExceeded = iota
+	unreachable
+	echoReply
+)
+
+// probeKey identifies a probe: by its source and destination port for UDP,
+// and by its identifier and sequence number for ICMP.
+type probeKey struct {
+	a, b uint16
+}
+
+// icmpReply is an ICMP message in reply to a probe.
+type icmpReply struct {
+	kind int
+	code byte
+	// proto is the protocol of the probe, UDP or ICMP.
+	proto byte
+	key   probeKey
+}
+
+// parseICMP parses the ICMP or ICMPv6 message b, without the IP header.
+func parseICMP(b []byte, v6 bool) (*icmpReply, error) {
+	if len(b) < icmpHeaderLen {
+		return nil, errors.New("short ICMP message")
+	}
+	r := &icmpReply{code: b[1]}
+	teType, unType, echoType, reqType, icmpProto := byte(icmpTimeExceeded), byte(icmpUnreachable), byte(icmpEchoReply), byte(icmpEchoRequest), byte(protoICMP)
+	if v6 {
+		teType, unType, echoType, reqType, icmpProto = icmp6TimeExceeded, icmp6Unreachable, icmp6EchoReply, icmp6EchoRequest, protoICMPv6
+	}
+	switch b[0] {
+	case echoType:
+		r.kind, r.proto = echoReply, icmpProto
+		r.key = probeKey{binary.BigEndian.Uint16(b[4:6]), binary.BigEndian.Uint16(b[6:8])}
+		return r, nil
+	case teType:
+		r.kind = timeExceeded
+	case unType:
+		r.kind = unreachable
+	default:
+		return nil, fmt.Errorf("ICMP type %d is not a reply", b[0])
+	}
+
+	// Errors carry the IP header and 8 bytes of the probe.
+	inner := b[icmpHeaderLen:]
+	var hl int
+	if v6 {
+		if len(inner) < ipv6HeaderLen {
+			return nil, errors.New("short ICMP error")
+		}
+		hl, r.proto = ipv6HeaderLen, inner[6]
+	} else {
+		if len(inner) < 20 {
+			return nil, errors.New("short ICMP error")
+		}
+		hl, r.proto = int(inner[0]&0xf)*4, inner[9]
+	}
+	if len(inner) < hl+transportHeaderLen {
+		return nil, errors.New("short ICMP error")
+	}
+	t := inner[hl:]
+	switch {
+	case r.proto == protoUDP:
+		r.key = probeKey{binary.BigEndian.Uint16(t[0:2]), binary.BigEndian.Uint16(t[2:4])}
+	case r.proto == icmpProto && t[0] == reqType:
+		r.key = probeKey{binary.BigEndian.Uint16(t[4:6]), binary.BigEndian.Uint16(t[6:8])}
+	default:
+		return nil, fmt.Errorf("ICMP error for protocol %d", r.proto)
+	}
+	return r, nil
+}
+
+// reached returns whether r comes from the destination.
+func (r *icmpReply) reached(v6 bool) bool {
+	if r.kind == echoReply {
+		return true
+	}
+	portUnreach := byte(icmpPortUnreach)
+	if v6 {
+		portUnreach = icmp6PortUnreach
+	}
+	return r.kind == unreachable && r.code == portUnreach
+}
+
+// annotation returns the traceroute annotation of an unreachable r, e.g.
+// "!H", or "" for other replies.
+func (r *icmpReply) annotation(v6 bool) string {
+	if r.kind != unreachable || r.reached(v6) {
+		return ""
+	}
+	codes := map[byte]string{0: "!N", 1: "!H", 2: "!P", 13: "!X"}
+	if v6 {
+		codes = map[byte]string{0: "!N", 1: "!X", 3: "!H"}
+	}
+	if a, ok := codes[r.code]; ok {
+		return a
+	}
+	return fmt.Sprintf("!<%d>", r.code)
+}
+
+// echoRequest returns an ICMP or ICMPv6 echo request. The kernel computes
+// the ICMPv6 checksum.
+func echoRequest(id, seq uint16, v6 bool, size int) []byte {
+	if size < icmpHeaderLen {
+		size = icmpHeaderLen
+	}
+	b := make([]byte, size)
+	b[0] = icmpEchoRequest
+	if v6 {
+		b[0] = icmp6EchoRequest
+	}
+	binary.BigEndian.PutUint16(b[4:6], id)
+	binary.BigEndian.PutUint16(b[6:8], seq)
+	if !v6 {
+		binary.BigEndian.PutUint16(b[2:4], checksum(b))
+	}
+	return b
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// probeResult is the outcome of one probe. A nil addr means no reply.
+type probeResult struct {
+	addr       net.IP
+	rtt        time.Duration
+	annotation string
+}
+
+// formatHop formats the results of the probes of hop ttl, like
+//
+//     1  192.168.1.1  0.512 ms  0.400 ms  0.380 ms
+//
+// The address is printed again when it changes between probes.
+func formatHop(ttl int, results []probeResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%2d ", ttl)
+	var last net.IP
+	for _, r := range results {
+		if r.addr == nil {
+			b.WriteString(" *")
+			continue
+		}
+		if !r.addr.Equal(last) {
+			fmt.Fprintf(&b, " %s", r.addr)
+			last = r.addr
+		}
+		fmt.Fprintf(&b, "  %.3f ms", float64(r.rtt)/float64(time.Millisecond))
+		if r.annotation != "" {
+			fmt.Fprintf(&b, " %s", r.annotation)
+		}
+	}
+	return b.String()
+}
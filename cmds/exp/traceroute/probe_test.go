@@ -0,0 +1,218 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ipv4Header returns an IPv4 header with protocol proto.
+func ipv4Header(proto byte) []byte {
+	h := make([]byte, 20)
+	h[0] = 0x45
+	h[9] = proto
+	return h
+}
+
+func ipv6Header(next byte) []byte {
+	h := make([]byte, 40)
+	h[0] = 0x60
+	h[6] = next
+	return h
+}
+
+// icmpError returns an ICMP error of type typ and code carrying ip and the
+// first 8 bytes of the probe.
+func icmpError(typ, code byte, ip, probe []byte) []byte {
+	b := []byte{typ, code, 0, 0, 0, 0, 0, 0}
+	b = append(b, ip...)
+	return append(b, probe...)
+}
+
+var udpProbe = []byte{0x9c, 0x40, 0x82, 0x9a, 0, 40, 0, 0} // 40000 -> 33434
+
+func TestParseICMP(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		msg  []byte
+		v6   bool
+		want *icmpReply
+		err  bool
+	}{
+		{
+			name: "v4 UDP time exceeded",
+			msg:  icmpError(icmpTimeExceeded, 0, ipv4Header(protoUDP), udpProbe),
+			want: &icmpReply{kind: timeExceeded, proto: protoUDP, key: probeKey{40000, 33434}},
+		},
+		{
+			name: "v4 UDP port unreachable",
+			msg:  icmpError(icmpUnreachable, icmpPortUnreach, ipv4Header(protoUDP), udpProbe),
+			want: &icmpReply{kind: unreachable, code: icmpPortUnreach, proto: protoUDP, key: probeKey{40000, 33434}},
+		},
+		{
+			name: "v4 header with options",
+			msg:  icmpError(icmpTimeExceeded, 0, append([]byte{0x46, 0, 0, 0, 0, 0, 0, 0, 0, protoUDP}, make([]byte, 14)...), udpProbe),
+			want: &icmpReply{kind: timeExceeded, proto: protoUDP, key: probeKey{40000, 33434}},
+		},
+		{
+			name: "v4 ICMP time exceeded",
+			msg:  icmpError(icmpTimeExceeded, 0, ipv4Header(protoICMP), echoRequest(7, 3, false, 8)),
+			want: &icmpReply{kind: timeExceeded, proto: protoICMP, key: probeKey{7, 3}},
+		},
+		{
+			name: "v4 echo reply",
+			msg:  []byte{icmpEchoReply, 0, 0, 0, 0, 7, 0, 3},
+			want: &icmpReply{kind: echoReply, proto: protoICMP, key: probeKey{7, 3}},
+		},
+		{
+			name: "v6 UDP time exceeded",
+			msg:  icmpError(icmp6TimeExceeded, 0, ipv6Header(protoUDP), udpProbe),
+			v6:   true,
+			want: &icmpReply{kind: timeExceeded, proto: protoUDP, key: probeKey{40000, 33434}},
+		},
+		{
+			name: "v6 ICMP unreachable",
+			msg:  icmpError(icmp6Unreachable, 3, ipv6Header(protoICMPv6), echoRequest(7, 3, true, 8)),
+			v6:   true,
+			want: &icmpReply{kind: unreachable, code: 3, proto: protoICMPv6, key: probeKey{7, 3}},
+		},
+		{
+			name: "v6 echo reply",
+			msg:  []byte{icmp6EchoReply, 0, 0, 0, 0, 7, 0, 3},
+			v6:   true,
+			want: &icmpReply{kind: echoReply, proto: protoICMPv6, key: probeKey{7, 3}},
+		},
+		{
+			name: "echo request",
+			msg:  echoRequest(7, 3, false, 8),
+			err:  true,
+		},
+		{
+			name: "short",
+			msg:  []byte{icmpTimeExceeded, 0, 0},
+			err:  true,
+		},
+		{
+			name: "short error",
+			msg:  icmpError(icmpTimeExceeded, 0, ipv4Header(protoUDP), udpProbe[:4]),
+			err:  true,
+		},
+		{
+			name: "short v6 error",
+			msg:  icmpError(icmp6TimeExceeded, 0, ipv6Header(protoUDP)[:20], nil),
+			v6:   true,
+			err:  true,
+		},
+		{
+			name: "TCP",
+			msg:  icmpError(icmpTimeExceeded, 0, ipv4Header(6), udpProbe),
+			err:  true,
+		},
+		{
+			name: "ICMP error for an ICMP error",
+			msg:  icmpError(icmpTimeExceeded, 0, ipv4Header(protoICMP), []byte{icmpUnreachable, 0, 0, 0, 0, 0, 0, 0}),
+			err:  true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseICMP(tt.msg, tt.v6)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseICMP() = %v, want error %t", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseICMP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReachedAnnotation(t *testing.T) {
+	for _, tt := range []struct {
+		r          icmpReply
+		v6         bool
+		reached    bool
+		annotation string
+	}{
+		{r: icmpReply{kind: timeExceeded}},
+		{r: icmpReply{kind: echoReply}, reached: true},
+		{r: icmpReply{kind: unreachable, code: icmpPortUnreach}, reached: true},
+		{r: icmpReply{kind: unreachable, code: icmp6PortUnreach}, v6: true, reached: true},
+		{r: icmpReply{kind: unreachable, code: 0}, annotation: "!N"},
+		{r: icmpReply{kind: unreachable, code: 1}, annotation: "!H"},
+		{r: icmpReply{kind: unreachable, code: 2}, annotation: "!P"},
+		{r: icmpReply{kind: unreachable, code: 13}, annotation: "!X"},
+		{r: icmpReply{kind: unreachable, code: 9}, annotation: "!<9>"},
+		{r: icmpReply{kind: unreachable, code: 1}, v6: true, annotation: "!X"},
+		{r: icmpReply{kind: unreachable, code: 3}, v6: true, annotation: "!H"},
+	} {
+		if got := tt.r.reached(tt.v6); got != tt.reached {
+			t.Errorf("%+v.reached(%t) = %t, want %t", tt.r, tt.v6, got, tt.reached)
+		}
+		if got := tt.r.annotation(tt.v6); got != tt.annotation {
+			t.Errorf("%+v.annotation(%t) = %q, want %q", tt.r, tt.v6, got, tt.annotation)
+		}
+	}
+}
+
+func TestEchoRequest(t *testing.T) {
+	b := echoRequest(0x1234, 2, false, 32)
+	if len(b) != 32 || b[0] != icmpEchoRequest || b[4] != 0x12 || b[5] != 0x34 || b[7] != 2 {
+		t.Fatalf("echoRequest() = %x", b)
+	}
+	// A message with a correct checksum sums to 0.
+	if c := checksum(b); c != 0 {
+		t.Errorf("checksum of the echo request = %#x, want 0", c)
+	}
+	if b := echoRequest(1, 1, true, 4); len(b) != icmpHeaderLen || b[0] != icmp6EchoRequest || b[2] != 0 || b[3] != 0 {
+		t.Errorf("echoRequest(v6) = %x", b)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	for _, tt := range []struct {
+		b    []byte
+		want uint16
+	}{
+		{[]byte{}, 0xffff},
+		{[]byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}, 0x220d},
+		{[]byte{0xff, 0xff, 0x01}, 0xfeff},
+	} {
+		if got := checksum(tt.b); got != tt.want {
+			t.Errorf("checksum(%x) = %#x, want %#x", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHop(t *testing.T) {
+	a, b := net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.254")
+	for _, tt := range []struct {
+		ttl     int
+		results []probeResult
+		want    string
+	}{
+		{
+			ttl:     1,
+			results: []probeResult{{addr: a, rtt: 512 * time.Microsecond}, {addr: a, rtt: 400 * time.Microsecond}, {addr: a, rtt: 380 * time.Microsecond}},
+			want:    " 1  192.0.2.1  0.512 ms  0.400 ms  0.380 ms",
+		},
+		{
+			ttl:     2,
+			results: []probeResult{{}, {}, {}},
+			want:    " 2  * * *",
+		},
+		{
+			ttl:     12,
+			results: []probeResult{{addr: a, rtt: time.Millisecond}, {}, {addr: b, rtt: 2 * time.Millisecond, annotation: "!H"}},
+			want:    "12  192.0.2.1  1.000 ms * 192.0.2.254  2.000 ms !H",
+		},
+	} {
+		if got := formatHop(tt.ttl, tt.results); got != tt.want {
+			t.Errorf("formatHop(%d, %v) = %q, want %q", tt.ttl, tt.results, got, tt.want)
+		}
+	}
+}
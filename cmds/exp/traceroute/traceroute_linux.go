@@ -0,0 +1,231 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// traceroute prints the route packets take to a host.
+//
+// Synopsis:
+//     traceroute [-4|-6] [-I] [-m MAXHOPS] [-q QUERIES] [-w WAIT] [-p PORT] HOST
+//
+// Description:
+//     Send probes with increasing TTLs, or hop limits, to HOST, and print
+//     the address of the router that reports each expired probe, with the
+//     round trip time of each probe. Probes are UDP datagrams to unlikely
+//     ports, starting at PORT, or with -I, ICMP echo requests. traceroute
+//     stops when HOST replies or reports the port unreachable.
+//
+//     A * is printed for probes without a reply, and annotations follow
+//     the times of unreachable replies: !N network, !H host, !P protocol
+//     unreachable and !X administratively prohibited.
+//
+// Options:
+//     -4: use IPv4
+//     -6: use IPv6
+//     -I: use ICMP echo requests instead of UDP
+//     -m: maximum number of hops
+//     -q: number of probes per hop
+//     -w: seconds to wait for a reply to each probe
+//     -p: destination port of the first UDP probe
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const usage = "usage: traceroute [-4|-6] [-I] [-m MAXHOPS] [-q QUERIES] [-w WAIT] [-p PORT] HOST"
+
+var (
+	ipv4    = flag.Bool("4", false, "Use IPv4")
+	ipv6    = flag.Bool("6", false, "Use IPv6")
+	useICMP = flag.Bool("I", false, "Use ICMP echo requests instead of UDP")
+	maxHops = flag.Int("m", 30, "Maximum number of hops")
+	queries = flag.Int("q", 3, "Number of probes per hop")
+	wait    = flag.Float64("w", 5, "Seconds to wait for a reply to each probe")
+	port    = flag.Int("p", 33434, "Destination port of the first UDP probe")
+)
+
+// packetSize is the size of the probes, without the IP header.
+const packetSize = 32
+
+// tracer sends probes and reads the replies.
+type tracer struct {
+	dst  net.IP
+	v6   bool
+	icmp *net.IPConn
+	// udp sends the probes, if they are not ICMP.
+	udp  *net.UDPConn
+	id   uint16
+	seq  uint16
+	wait time.Duration
+}
+
+// setTTL sets the TTL, or hop limit, of the packets c sends.
+func setTTL(c syscall.Conn, ttl int, v6 bool) error {
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		if v6 {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, ttl)
+		} else {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TTL, ttl)
+		}
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// probe sends one probe with ttl and waits for its reply.
+func (t *tracer) probe(ttl int) (probeResult, *icmpReply, error) {
+	t.seq++
+	var (
+		key  probeKey
+		conn syscall.Conn
+		send func() error
+	)
+	if t.udp != nil {
+		dport := uint16(*port) + t.seq - 1
+		key = probeKey{uint16(t.udp.LocalAddr().(*net.UDPAddr).Port), dport}
+		conn = t.udp
+		send = func() error {
+			_, err := t.udp.WriteTo(make([]byte, packetSize), &net.UDPAddr{IP: t.dst, Port: int(dport)})
+			return err
+		}
+	} else {
+		key = probeKey{t.id, t.seq}
+		conn = t.icmp
+		send = func() error {
+			_, err := t.icmp.WriteTo(echoRequest(t.id, t.seq, t.v6, packetSize), &net.IPAddr{IP: t.dst})
+			return err
+		}
+	}
+	if err := setTTL(conn, ttl, t.v6); err != nil {
+		return probeResult{}, nil, err
+	}
+	start := time.Now()
+	if err := send(); err != nil {
+		return probeResult{}, nil, err
+	}
+
+	deadline := start.Add(t.wait)
+	if err := t.icmp.SetReadDeadline(deadline); err != nil {
+		return probeResult{}, nil, err
+	}
+	b := make([]byte, 1500)
+	for {
+		n, from, err := t.icmp.ReadFrom(b)
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return probeResult{}, nil, nil
+		}
+		if err != nil {
+			return probeResult{}, nil, err
+		}
+		r, err := parseICMP(b[:n], t.v6)
+		// Echo requests and replies of others, e.g. ping, are
+		// received too.
+		if err != nil || r.key != key || (r.kind == echoReply && !from.(*net.IPAddr).IP.Equal(t.dst)) {
+			continue
+		}
+		return probeResult{addr: from.(*net.IPAddr).IP, rtt: time.Since(start), annotation: r.annotation(t.v6)}, r, nil
+	}
+}
+
+func resolve(host string) (net.IP, error) {
+	network := "ip"
+	switch {
+	case *ipv4 && *ipv6:
+		return nil, errors.New("-4 and -6 are mutually exclusive")
+	case *ipv4:
+		network = "ip4"
+	case *ipv6:
+		network = "ip6"
+	}
+	a, err := net.ResolveIPAddr(network, host)
+	if err != nil {
+		return nil, err
+	}
+	return a.IP, nil
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(usage)
+	}
+	if *maxHops < 1 || *maxHops > 255 || *queries < 1 {
+		return errors.New("the maximum number of hops must be between 1 and 255, and there must be at least one probe per hop")
+	}
+	dst, err := resolve(args[0])
+	if err != nil {
+		return err
+	}
+	t := &tracer{
+		dst:  dst,
+		v6:   dst.To4() == nil,
+		id:   uint16(os.Getpid()),
+		wait: time.Duration(*wait * float64(time.Second)),
+	}
+	icmpNet, udpNet := "ip4:icmp", "udp4"
+	if t.v6 {
+		icmpNet, udpNet = "ip6:ipv6-icmp", "udp6"
+	}
+	c, err := net.ListenPacket(icmpNet, "")
+	if err != nil {
+		return fmt.Errorf("opening ICMP socket: %v", err)
+	}
+	defer c.Close()
+	t.icmp = c.(*net.IPConn)
+	if !*useICMP {
+		u, err := net.ListenPacket(udpNet, "")
+		if err != nil {
+			return err
+		}
+		defer u.Close()
+		t.udp = u.(*net.UDPConn)
+	}
+
+	fmt.Fprintf(stdout, "traceroute to %s (%s), %d hops max, %d byte packets\n", args[0], dst, *maxHops, packetSize)
+	for ttl := 1; ttl <= *maxHops; ttl++ {
+		var (
+			results []probeResult
+			done    bool
+		)
+		for q := 0; q < *queries; q++ {
+			res, r, err := t.probe(ttl)
+			if err != nil {
+				return err
+			}
+			results = append(results, res)
+			// Unreachable routers end the trace too.
+			if r != nil && (r.reached(t.v6) || r.kind == unreachable) {
+				done = true
+			}
+		}
+		fmt.Fprintln(stdout, formatHop(ttl, results))
+		if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("traceroute: ")
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
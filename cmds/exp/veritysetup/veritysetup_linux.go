@@ -0,0 +1,171 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// veritysetup opens and closes dm-verity devices.
+//
+// Synopsis:
+//     veritysetup open [--hash-offset OFFSET] [--restart-on-corruption|--ignore-corruption] DATA NAME HASH ROOTHASH
+//     veritysetup close NAME
+//     veritysetup dump [--hash-offset OFFSET] HASH
+//
+// Description:
+//     open:  map DATA to the read-only /dev/mapper/NAME, verified with the
+//            hash tree on HASH against ROOTHASH, given in hex
+//     close: remove the mapping NAME
+//     dump:  print the verity superblock of HASH
+//
+//     HASH has to be formatted by veritysetup format, with a superblock.
+//     After open, the first block of NAME is read, so that a wrong
+//     ROOTHASH or HASH is reported right away and not as an I/O error on
+//     first access, e.g. by switch_root.
+//
+// Options:
+//     --hash-offset:           byte offset of the superblock on HASH
+//     --restart-on-corruption: restart the system if corruption is detected
+//     --ignore-corruption:     only log corruption
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/u-root/u-root/pkg/dm"
+)
+
+const usage = `usage: veritysetup open [--hash-offset OFFSET] [--restart-on-corruption|--ignore-corruption] DATA NAME HASH ROOTHASH
+       veritysetup close NAME
+       veritysetup dump [--hash-offset OFFSET] HASH`
+
+var (
+	hashOffset          = flag.Uint64("hash-offset", 0, "byte offset of the superblock on the hash device")
+	restartOnCorruption = flag.Bool("restart-on-corruption", false, "restart the system if corruption is detected")
+	ignoreCorruption    = flag.Bool("ignore-corruption", false, "only log corruption")
+)
+
+func readSuperblock(hashDev string) (*dm.VerityParams, error) {
+	f, err := os.Open(hashDev)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := dm.ReadVeritySuperblock(f, *hashOffset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", hashDev, err)
+	}
+	return p, nil
+}
+
+func dump(w io.Writer, p *dm.VerityParams) {
+	fmt.Fprintf(w, "VERITY header information for %s\n", p.HashDevice)
+	fmt.Fprintf(w, "Hash type:       \t%d\n", p.HashType)
+	fmt.Fprintf(w, "Data blocks:     \t%d\n", p.DataBlocks)
+	fmt.Fprintf(w, "Data block size: \t%d\n", p.DataBlockSize)
+	fmt.Fprintf(w, "Hash block size: \t%d\n", p.HashBlockSize)
+	fmt.Fprintf(w, "Hash algorithm:  \t%s\n", p.Algorithm)
+	if len(p.Salt) == 0 {
+		fmt.Fprintf(w, "Salt:            \t-\n")
+	} else {
+		fmt.Fprintf(w, "Salt:            \t%x\n", p.Salt)
+	}
+}
+
+// checkSize checks that dev holds size bytes.
+func checkSize(dev string, size uint64) error {
+	f, err := os.Open(dev)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if uint64(n) < size {
+		return fmt.Errorf("%s is %d bytes, but the hash tree covers %d bytes", dev, n, size)
+	}
+	return nil
+}
+
+// verifyFirstBlock reads the first block of node. dm-verity returns EIO if
+// it does not match the hash tree.
+func verifyFirstBlock(node string, blockSize uint32) error {
+	f, err := os.Open(node)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Read(make([]byte, blockSize)); err != nil {
+		return fmt.Errorf("verification failed, the root hash does not match the data or hash device: %v", err)
+	}
+	return nil
+}
+
+func open(data, name, hashDev, rootHash string) error {
+	if *restartOnCorruption && *ignoreCorruption {
+		return errors.New("--restart-on-corruption and --ignore-corruption are mutually exclusive")
+	}
+	p, err := readSuperblock(hashDev)
+	if err != nil {
+		return err
+	}
+	if p.RootDigest, err = dm.ParseRootDigest(rootHash, p.Algorithm); err != nil {
+		return err
+	}
+	if err := checkSize(data, p.DataBlocks*uint64(p.DataBlockSize)); err != nil {
+		return err
+	}
+	p.DataDevice, p.HashDevice = data, hashDev
+	switch {
+	case *restartOnCorruption:
+		p.Options = []string{"restart_on_corruption"}
+	case *ignoreCorruption:
+		p.Options = []string{"ignore_corruption"}
+	}
+	t, err := p.Target()
+	if err != nil {
+		return err
+	}
+	node, err := dm.Create(name, dm.ReadOnly, []dm.Target{t})
+	if err != nil {
+		return err
+	}
+	if err := verifyFirstBlock(node, p.DataBlockSize); err != nil && !*ignoreCorruption {
+		if rerr := dm.Remove(name); rerr != nil {
+			log.Print(rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func run(args []string, stdout io.Writer) error {
+	switch {
+	case len(args) == 5 && args[0] == "open":
+		return open(args[1], args[2], args[3], args[4])
+	case len(args) == 2 && args[0] == "close":
+		return dm.Remove(args[1])
+	case len(args) == 2 && args[0] == "dump":
+		p, err := readSuperblock(args[1])
+		if err != nil {
+			return err
+		}
+		p.HashDevice = args[1]
+		dump(stdout, p)
+		return nil
+	}
+	return errors.New(usage)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("veritysetup: ")
+	flag.Parse()
+	if err := run(flag.Args(), os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeHashDevice writes a hash device with a superblock for 256 blocks of
+// 4096 bytes, hashed with sha256.
+func writeHashDevice(t *testing.T, path string) {
+	var b bytes.Buffer
+	b.WriteString("verity\x00\x00")
+	binary.Write(&b, binary.LittleEndian, []uint32{1, 1})
+	b.Write(make([]byte, 16))
+	alg := make([]byte, 32)
+	copy(alg, "sha256")
+	b.Write(alg)
+	binary.Write(&b, binary.LittleEndian, []uint32{4096, 4096})
+	binary.Write(&b, binary.LittleEndian, uint64(256))
+	binary.Write(&b, binary.LittleEndian, uint16(2))
+	b.Write(make([]byte, 6))
+	b.Write([]byte{0xca, 0xfe})
+	b.Write(make([]byte, 4096-b.Len()))
+	if err := ioutil.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "veritysetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hash := filepath.Join(dir, "hash")
+	writeHashDevice(t, hash)
+
+	var b bytes.Buffer
+	if err := run([]string{"dump", hash}, &b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Data blocks:     \t256\n",
+		"Hash algorithm:  \tsha256\n",
+		"Salt:            \tcafe\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("dump output does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "veritysetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hash := filepath.Join(dir, "hash")
+	writeHashDevice(t, hash)
+	small := filepath.Join(dir, "small")
+	if err := ioutil.WriteFile(small, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := strings.Repeat("ab", 32)
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"open", small, "root", hash, root[:40]}, "root hash is 20 bytes"},
+		{[]string{"open", small, "root", hash, "xyz"}, "not hex"},
+		{[]string{"open", small, "root", hash, root}, "hash tree covers 1048576 bytes"},
+		{[]string{"open", small, "root", small, root}, "no verity superblock"},
+		{[]string{"dump"}, "usage"},
+	} {
+		err := run(tt.args, ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("run(%q) = %v, want error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
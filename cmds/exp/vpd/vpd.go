@@ -0,0 +1,203 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// vpd reads and writes the VPD (Vital Product Data) key-value store.
+//
+// Synopsis:
+//     vpd [-f FILE] [-i PARTITION] [-l] [-g KEY]
+//     vpd -f FILE [-i PARTITION] [-O] [-d KEY]... [-s KEY=VALUE]...
+//
+// Description:
+//     Without -f, vpd reads the VPD the firmware exposes in
+//     /sys/firmware/vpd, which can not be written.
+//
+//     With -f, vpd reads and writes the VPD partition in FILE. If FILE is a
+//     flash image with a flash map, the partition is its area PARTITION.
+//     Otherwise FILE is the partition, e.g. read by flashrom:
+//
+//     flashrom -p internal --fmap -i RW_VPD -r rw_vpd.bin
+//     vpd -f rw_vpd.bin -s serial_number=1234
+//
+//     -O, -d and -s are applied in this order.
+//
+// Options:
+//     -f: file with the VPD partition or a flash image
+//     -i: partition, RO_VPD or RW_VPD (default RO_VPD)
+//     -l: list all entries as "KEY"="VALUE", the default if nothing is
+//         changed and -g is not given
+//     -g: print the value of KEY
+//     -s: set KEY to VALUE
+//     -d: delete KEY
+//     -O: erase all entries
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/vpd"
+)
+
+// multiFlag is a flag that can be given more than once.
+type multiFlag []string
+
+func (m *multiFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *multiFlag) Set(s string) error {
+	*m = append(*m, s)
+	return nil
+}
+
+var (
+	file      = flag.String("f", "", "file with the VPD partition or a flash image")
+	partition = flag.String("i", "RO_VPD", "partition, RO_VPD or RW_VPD")
+	list      = flag.Bool("l", false, "list all entries")
+	get       = flag.String("g", "", "print the value of `KEY`")
+	format    = flag.Bool("O", false, "erase all entries")
+	sets      multiFlag
+	deletes   multiFlag
+)
+
+func init() {
+	flag.Var(&sets, "s", "set `KEY=VALUE`")
+	flag.Var(&deletes, "d", "delete `KEY`")
+}
+
+func printEntries(w io.Writer, entries []vpd.Entry) {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%q=%q\n", e.Key, e.Value)
+	}
+}
+
+func printValue(w io.Writer, key string, value []byte, ok bool) error {
+	if !ok {
+		return fmt.Errorf("key %q not found", key)
+	}
+	_, err := w.Write(value)
+	return err
+}
+
+// runSysfs reads the VPD from sysfs.
+func runSysfs(w io.Writer) error {
+	if *format || len(sets) > 0 || len(deletes) > 0 {
+		return errors.New("the VPD in sysfs is read-only, use -f to write")
+	}
+	var readOnly bool
+	switch *partition {
+	case "RO_VPD":
+		readOnly = true
+	case "RW_VPD":
+	default:
+		return fmt.Errorf("partition %q is not RO_VPD or RW_VPD", *partition)
+	}
+	if *get != "" {
+		v, err := vpd.Get(*get, readOnly)
+		if os.IsNotExist(err) {
+			return printValue(w, *get, nil, false)
+		}
+		if err != nil {
+			return err
+		}
+		return printValue(w, *get, v, true)
+	}
+	m, err := vpd.GetAll(readOnly)
+	if err != nil {
+		return err
+	}
+	var entries []vpd.Entry
+	for k, v := range m {
+		entries = append(entries, vpd.Entry{Key: k, Value: v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	printEntries(w, entries)
+	return nil
+}
+
+// runFile reads and writes the VPD partition in a file.
+func runFile(w io.Writer) error {
+	image, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	off, size, _, err := vpd.FMAPArea(image, *partition)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *file, err)
+	}
+	if size == 0 {
+		off, size = 0, len(image)
+	}
+	b := image[off : off+size]
+
+	var p *vpd.Partition
+	if *format {
+		p = vpd.NewPartition(size)
+	} else if p, err = vpd.ParsePartition(b); err != nil {
+		return fmt.Errorf("%s: %v", *file, err)
+	}
+	for _, k := range deletes {
+		if !p.Delete(k) {
+			return fmt.Errorf("key %q not found", k)
+		}
+	}
+	for _, kv := range sets {
+		i := strings.Index(kv, "=")
+		if i < 1 {
+			return fmt.Errorf("%q is not KEY=VALUE", kv)
+		}
+		p.Set(kv[:i], []byte(kv[i+1:]))
+	}
+	modify := *format || len(sets) > 0 || len(deletes) > 0
+	if modify {
+		nb, err := p.Bytes()
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(*file, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(nb, int64(off)); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if *get != "" {
+		v, ok := p.Get(*get)
+		return printValue(w, *get, v, ok)
+	}
+	if *list || !modify {
+		printEntries(w, p.Entries)
+	}
+	return nil
+}
+
+func run(w io.Writer) error {
+	if flag.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %q", flag.Args())
+	}
+	if *file == "" {
+		return runSysfs(w)
+	}
+	return runFile(w)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("vpd: ")
+	flag.Parse()
+	if err := run(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
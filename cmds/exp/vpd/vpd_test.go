@@ -0,0 +1,106 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/vpd"
+)
+
+func reset() {
+	*file, *partition, *list, *get, *format = "", "RO_VPD", false, "", false
+	sets, deletes = nil, nil
+}
+
+func TestFile(t *testing.T) {
+	defer reset()
+	d, err := ioutil.TempDir("", "vpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	f := filepath.Join(d, "rw_vpd.bin")
+	// An erased partition.
+	if err := ioutil.WriteFile(f, bytes.Repeat([]byte{0xff}, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		setup   func()
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", want: ""},
+		{name: "set", setup: func() { sets = multiFlag{"serial_number=1234", "a=b=c"} }, want: ""},
+		{name: "list", setup: func() { *list = true }, want: "\"serial_number\"=\"1234\"\n\"a\"=\"b=c\"\n"},
+		{name: "get", setup: func() { *get = "a" }, want: "b=c"},
+		{name: "missing", setup: func() { *get = "b" }, wantErr: true},
+		{name: "delete", setup: func() { deletes = multiFlag{"serial_number"}; *list = true }, want: "\"a\"=\"b=c\"\n"},
+		{name: "delete missing", setup: func() { deletes = multiFlag{"serial_number"} }, wantErr: true},
+		{name: "bad set", setup: func() { sets = multiFlag{"=x"} }, wantErr: true},
+		{name: "format", setup: func() { *format = true; sets = multiFlag{"x=y"}; *list = true }, want: "\"x\"=\"y\"\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			*file = f
+			if tt.setup != nil {
+				tt.setup()
+			}
+			var b bytes.Buffer
+			if err := run(&b); (err != nil) != tt.wantErr {
+				t.Fatalf("run = %v, want error %v", err, tt.wantErr)
+			}
+			if b.String() != tt.want {
+				t.Errorf("run printed %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 1024 {
+		t.Errorf("%s is %d bytes after writing, want 1024", f, len(b))
+	}
+	if p, err := vpd.ParsePartition(b); err != nil || len(p.Entries) != 1 {
+		t.Errorf("ParsePartition = %v, %v, want 1 entry", p, err)
+	}
+}
+
+func TestSysfs(t *testing.T) {
+	defer reset()
+	defer func(old string) { vpd.VpdDir = old }(vpd.VpdDir)
+	vpd.VpdDir = "../../../pkg/vpd/tests"
+
+	reset()
+	*partition = "RW_VPD"
+	var b bytes.Buffer
+	if err := run(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"mysecretpassword\"=\"passw0rd\\n\"\n"; b.String() != want {
+		t.Errorf("run printed %q, want %q", b.String(), want)
+	}
+
+	reset()
+	*get = "key1"
+	b.Reset()
+	if err := run(&b); err != nil || b.String() != "value1\n" {
+		t.Errorf("run -g key1 = %q, %v, want value1", b.String(), err)
+	}
+
+	reset()
+	sets = multiFlag{"a=b"}
+	if err := run(&b); err == nil {
+		t.Errorf("run -s without -f succeeded")
+	}
+}
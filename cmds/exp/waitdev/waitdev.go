@@ -0,0 +1,133 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// waitdev waits for devices to appear.
+//
+// Synopsis:
+//     waitdev [-q] [-i INTERVAL] [-t TIMEOUT] DEVICE...
+//
+// Description:
+//     Wait until all DEVICEs exist, so that boot scripts do not have to
+//     guess how long to sleep before mounting. Each DEVICE is a path,
+//     such as a device node or a sysfs attribute, or a block device given
+//     as UUID=, LABEL=, PARTUUID= or PARTLABEL=, which are found by
+//     probing the block devices like blkid does. A block device is only
+//     present once its device node exists.
+//
+//     The exit status is 0 when all DEVICEs are present and 1 if the
+//     timeout expires first, in which case the missing ones are printed.
+//     A timeout of 0 checks once.
+//
+// Options:
+//     -i, --interval: how often to check for the devices
+//     -q, --quiet:    do not print the missing devices
+//     -t, --timeout:  how long to wait
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+)
+
+var (
+	interval = flag.DurationP("interval", "i", 100*time.Millisecond, "how often to check for the devices")
+	quiet    = flag.BoolP("quiet", "q", false, "do not print the missing devices")
+	timeout  = flag.DurationP("timeout", "t", 30*time.Second, "how long to wait")
+
+	// Overridden in tests.
+	getBlockDevices = block.GetBlockDevices
+)
+
+// devicePath returns the path of the device spec, which is spec itself
+// unless it is a block device tag such as UUID=..., which absolute paths
+// never are. It returns an empty path if no block device matches the tag.
+func devicePath(spec string) (string, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) == 1 || strings.HasPrefix(spec, "/") {
+		return spec, nil
+	}
+	devices, err := getBlockDevices()
+	if err != nil {
+		return "", err
+	}
+	var matches block.BlockDevices
+	switch key, value := strings.ToUpper(kv[0]), kv[1]; key {
+	case "UUID":
+		matches = devices.FilterFSUUID(strings.ToLower(value))
+	case "LABEL":
+		matches = devices.FilterFSLabel(value)
+	case "PARTUUID":
+		matches = devices.FilterPartID(value)
+	case "PARTLABEL":
+		if matches, err = devices.FilterPartLabel(value); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%q: unknown tag %q", spec, kv[0])
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0].DevicePath(), nil
+}
+
+// missing returns the specs whose device is not present.
+func missing(specs []string) ([]string, error) {
+	var m []string
+	for _, s := range specs {
+		p, err := devicePath(s)
+		if err != nil {
+			return nil, err
+		}
+		if p == "" {
+			m = append(m, s)
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			m = append(m, s)
+		}
+	}
+	return m, nil
+}
+
+// wait checks for the devices of specs every interval until all are
+// present or timeout expires, and returns the missing ones.
+func wait(specs []string, timeout, interval time.Duration) ([]string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		m, err := missing(specs)
+		if err != nil || len(m) == 0 || !time.Now().Before(deadline) {
+			return m, err
+		}
+		specs = m
+		time.Sleep(interval)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("waitdev: ")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	m, err := wait(flag.Args(), *timeout, *interval)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(m) > 0 {
+		if !*quiet {
+			log.Printf("timed out waiting for %s", strings.Join(m, ", "))
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/u-root/u-root/pkg/mount/block"
+)
+
+func TestDevicePath(t *testing.T) {
+	defer func(f func() (block.BlockDevices, error)) { getBlockDevices = f }(getBlockDevices)
+	getBlockDevices = func() (block.BlockDevices, error) {
+		return block.BlockDevices{
+			{Name: "sda1", FsUUID: "2c5a3f1e-7d43-4c1b-9a2e-3f6d8b9c0a11"},
+			{Name: "sdb1", FsUUID: "1234-ABCD"},
+		}, nil
+	}
+	for _, tt := range []struct {
+		spec string
+		want string
+		err  bool
+	}{
+		{spec: "/dev/sda1", want: "/dev/sda1"},
+		{spec: "/dev/disk/by-id/a=b", want: "/dev/disk/by-id/a=b"},
+		{spec: "/sys/class/net/eth0/address", want: "/sys/class/net/eth0/address"},
+		{spec: "UUID=2c5a3f1e-7d43-4c1b-9a2e-3f6d8b9c0a11", want: "/dev/sda1"},
+		{spec: "UUID=2C5A3F1E-7D43-4C1B-9A2E-3F6D8B9C0A11", want: "/dev/sda1"},
+		{spec: "uuid=2c5a3f1e-7d43-4c1b-9a2e-3f6d8b9c0a11", want: "/dev/sda1"},
+		{spec: "UUID=00000000-0000-0000-0000-000000000000", want: ""},
+		{spec: "SERIAL=1", err: true},
+	} {
+		got, err := devicePath(tt.spec)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("devicePath(%q) = %q, %v, want %q, error %t", tt.spec, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	dir, err := ioutil.TempDir("", "waitdev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	present, late, never := filepath.Join(dir, "present"), filepath.Join(dir, "late"), filepath.Join(dir, "never")
+	if err := ioutil.WriteFile(present, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := wait([]string{present}, 0, time.Millisecond); err != nil || len(m) != 0 {
+		t.Errorf("wait(present) = %v, %v, want none missing", m, err)
+	}
+	if m, err := wait([]string{present, never}, 10*time.Millisecond, time.Millisecond); err != nil || !reflect.DeepEqual(m, []string{never}) {
+		t.Errorf("wait(present, never) = %v, %v, want %v missing", m, err, never)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ioutil.WriteFile(late, nil, 0644)
+	}()
+	if m, err := wait([]string{present, late}, 5*time.Second, time.Millisecond); err != nil || len(m) != 0 {
+		t.Errorf("wait(present, late) = %v, %v, want none missing", m, err)
+	}
+}
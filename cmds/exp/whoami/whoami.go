@@ -0,0 +1,41 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// whoami prints the name of the effective user.
+//
+// Synopsis:
+//     whoami
+//
+// Description:
+//     whoami prints the name of the effective user ID of the calling
+//     process, as looked up in /etc/passwd, or the ID as a number if it has
+//     no name.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+// Overridden in tests.
+var geteuid = os.Geteuid
+
+func run(w io.Writer) {
+	// Without the file, the user has no name.
+	pw, _ := getent.ReadPasswd(getent.PasswdFile)
+	fmt.Fprintln(w, getent.UserName(pw, geteuid()))
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("whoami: ")
+	if len(os.Args) > 1 {
+		log.Fatalf("extra operand %q", os.Args[1])
+	}
+	run(os.Stdout)
+}
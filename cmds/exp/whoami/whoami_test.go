@@ -0,0 +1,45 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/getent"
+)
+
+func TestRun(t *testing.T) {
+	f, err := ioutil.TempFile("", "passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("root:x:0:0:root:/root:/bin/sh\nuser:x:1000:100::/home/user:/bin/sh\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer func(p string) { getent.PasswdFile = p }(getent.PasswdFile)
+	getent.PasswdFile = f.Name()
+
+	defer func(f func() int) { geteuid = f }(geteuid)
+	for _, tt := range []struct {
+		uid  int
+		want string
+	}{
+		{0, "root\n"},
+		{1000, "user\n"},
+		{4242, "4242\n"},
+	} {
+		geteuid = func() int { return tt.uid }
+		var out bytes.Buffer
+		run(&out)
+		if out.String() != tt.want {
+			t.Errorf("run() with euid %d = %q, want %q", tt.uid, out.String(), tt.want)
+		}
+	}
+}
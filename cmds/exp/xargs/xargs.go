@@ -0,0 +1,411 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// xargs builds and runs commands from standard input.
+//
+// Synopsis:
+//     xargs [OPTIONS] [COMMAND [INITIAL-ARGS]...]
+//
+// Description:
+//     Items are read from standard input and appended to COMMAND, which
+//     defaults to echo. By default, items are separated by blanks and
+//     newlines, and may be quoted with ' or " or escaped with a backslash.
+//
+//     With -P, up to N commands run at once. Their output is buffered and
+//     written in the order the commands were started.
+//
+//     xargs exits with 123 if any command exited with a status from 1
+//     to 125, 124 if a command exited with 255, 125 if a command was
+//     killed by a signal, 126 if COMMAND could not be run and 127 if it
+//     was not found.
+//
+// Options:
+//     -0, --null:              items are separated by NUL, not blanks
+//     -d, --delimiter=DELIM:   items are separated by DELIM, which may be an escape like \n
+//     -I REPLSTR:              replace REPLSTR in INITIAL-ARGS with each item, one item per command
+//     -n, --max-args=MAX:      use at most MAX items per command
+//     -P, --max-procs=N:       run up to N commands at once
+//     -r, --no-run-if-empty:   do not run COMMAND if there are no items
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// maxChars limits the length of the items passed to one command
+	// when -n is not given.
+	maxChars = 128 * 1024
+
+	exitFailed   = 123
+	exitStopped  = 124
+	exitSignaled = 125
+	exitCannot   = 126
+	exitNotFound = 127
+)
+
+var (
+	null      = flag.BoolP("null", "0", false, "items are separated by NUL, not blanks")
+	delimiter = flag.StringP("delimiter", "d", "", "items are separated by DELIM")
+	replace   = flag.StringP("replace", "I", "", "replace REPLSTR in INITIAL-ARGS with each item")
+	maxArgs   = flag.IntP("max-args", "n", 0, "use at most MAX items per command")
+	maxProcs  = flag.IntP("max-procs", "P", 1, "run up to N commands at once")
+	noRun     = flag.BoolP("no-run-if-empty", "r", false, "do not run COMMAND if there are no items")
+)
+
+type xargs struct {
+	cmd     []string
+	replace string
+	maxArgs int
+	procs   int
+	noRun   bool
+	split   bufio.SplitFunc
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type result struct {
+	stdout, stderr bytes.Buffer
+	code           int
+}
+
+// splitDelim returns a split function for items separated by delim.
+func splitDelim(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// splitLines splits lines, ignoring leading blanks, as done with -I.
+func splitLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, tok, err := bufio.ScanLines(data, atEOF)
+	if tok != nil {
+		tok = bytes.TrimLeft(tok, " \t")
+	}
+	return advance, tok, err
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// splitBlanks splits items separated by blanks, honoring quotes and
+// backslash escapes.
+func splitBlanks(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && isBlank(data[start]) {
+		start++
+	}
+	var (
+		tok   []byte
+		quote byte
+	)
+	for i := start; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case quote != 0:
+			switch c {
+			case quote:
+				quote = 0
+			case '\n':
+				return 0, nil, fmt.Errorf("unmatched %c quote", quote)
+			default:
+				tok = append(tok, c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			if tok == nil {
+				tok = []byte{}
+			}
+		case c == '\\':
+			if i+1 == len(data) {
+				if !atEOF {
+					return start, nil, nil
+				}
+				break
+			}
+			i++
+			tok = append(tok, data[i])
+		case isBlank(c):
+			return i + 1, tok, nil
+		default:
+			tok = append(tok, c)
+		}
+	}
+	if !atEOF {
+		return start, nil, nil
+	}
+	if quote != 0 {
+		return 0, nil, fmt.Errorf("unmatched %c quote", quote)
+	}
+	return len(data), tok, nil
+}
+
+// parseDelim interprets the -d argument, which is a single character or
+// a backslash escape.
+func parseDelim(d string) (byte, error) {
+	if len(d) == 1 {
+		return d[0], nil
+	}
+	if len(d) < 2 || d[0] != '\\' {
+		return 0, fmt.Errorf("invalid delimiter %q", d)
+	}
+	if e, ok := map[string]byte{`\a`: '\a', `\b`: '\b', `\f`: '\f', `\n`: '\n', `\r`: '\r', `\t`: '\t', `\v`: '\v', `\\`: '\\'}[d]; ok {
+		return e, nil
+	}
+	var (
+		v   uint64
+		err error
+	)
+	switch {
+	case d[1] == 'x':
+		v, err = strconv.ParseUint(d[2:], 16, 8)
+	case d[1] >= '0' && d[1] <= '7':
+		v, err = strconv.ParseUint(d[1:], 8, 8)
+	default:
+		err = strconv.ErrSyntax
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid delimiter %q", d)
+	}
+	return byte(v), nil
+}
+
+// status maps the outcome of running a command to the xargs exit status.
+func status(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		ws, ok := ee.Sys().(syscall.WaitStatus)
+		switch {
+		case ok && ws.Signaled():
+			return exitSignaled
+		case ee.ExitCode() == 255:
+			return exitStopped
+		default:
+			return exitFailed
+		}
+	}
+	if errors.Is(err, exec.ErrNotFound) || os.IsNotExist(err) {
+		return exitNotFound
+	}
+	return exitCannot
+}
+
+// worse returns whichever exit status is more severe.
+func worse(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// command returns the command line for one batch of items.
+func (x *xargs) command(items []string) []string {
+	if x.replace == "" {
+		return append(append([]string{}, x.cmd...), items...)
+	}
+	c := make([]string, len(x.cmd))
+	for i, a := range x.cmd {
+		if i == 0 {
+			c[i] = a
+			continue
+		}
+		c[i] = strings.Replace(a, x.replace, items[0], -1)
+	}
+	return c
+}
+
+// exec runs one command. With a single process, the output goes straight
+// to x.stdout and x.stderr; otherwise it is buffered in the result.
+func (x *xargs) exec(args []string) *result {
+	r := &result{}
+	c := exec.Command(args[0], args[1:]...)
+	if x.procs == 1 {
+		c.Stdout, c.Stderr = x.stdout, x.stderr
+	} else {
+		c.Stdout, c.Stderr = &r.stdout, &r.stderr
+	}
+	err := c.Run()
+	if err != nil && c.ProcessState == nil {
+		fmt.Fprintf(&r.stderr, "xargs: %v\n", err)
+	}
+	r.code = status(err)
+	return r
+}
+
+// batches reads items from r and sends the command line for each batch
+// on the returned channel. Reading stops when stop is closed.
+func (x *xargs) batches(r io.Reader, stop <-chan struct{}) (<-chan []string, <-chan error) {
+	cmds := make(chan []string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(cmds)
+		send := func(items []string) bool {
+			select {
+			case cmds <- x.command(items):
+				return true
+			case <-stop:
+				return false
+			}
+		}
+
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, maxChars)
+		s.Split(x.split)
+		var (
+			items []string
+			size  int
+			sent  bool
+		)
+		for s.Scan() {
+			t := s.Text()
+			if len(items) > 0 && x.maxArgs == 0 && size+len(t)+1 > maxChars {
+				if !send(items) {
+					return
+				}
+				items, size, sent = nil, 0, true
+			}
+			items = append(items, t)
+			size += len(t) + 1
+			if len(items) == x.maxArgs {
+				if !send(items) {
+					return
+				}
+				items, size, sent = nil, 0, true
+			}
+		}
+		if err := s.Err(); err != nil {
+			errc <- err
+			return
+		}
+		if len(items) > 0 || (!sent && !x.noRun && x.replace == "") {
+			send(items)
+		}
+	}()
+	return cmds, errc
+}
+
+// run runs the commands built from r and returns the exit status.
+func (x *xargs) run(r io.Reader) int {
+	if x.replace != "" {
+		x.maxArgs = 1
+	}
+
+	// Each started command queues a channel for its result, so that
+	// output is written in order. The queue also bounds how many results
+	// may be buffered.
+	order := make(chan chan *result, x.procs)
+	code := make(chan int)
+	go func() {
+		st := 0
+		for c := range order {
+			res := <-c
+			x.stdout.Write(res.stdout.Bytes())
+			x.stderr.Write(res.stderr.Bytes())
+			st = worse(st, res.code)
+		}
+		code <- st
+	}()
+
+	// Like GNU xargs, do not start any more commands once one exits with
+	// 255 or cannot be run.
+	var once sync.Once
+	stop := make(chan struct{})
+	sem := make(chan struct{}, x.procs)
+	cmds, errc := x.batches(r, stop)
+	for args := range cmds {
+		sem <- struct{}{}
+		select {
+		case <-stop:
+			<-sem
+			continue
+		default:
+		}
+		c := make(chan *result, 1)
+		order <- c
+		go func(args []string) {
+			res := x.exec(args)
+			if res.code >= exitStopped && res.code != exitSignaled {
+				once.Do(func() { close(stop) })
+			}
+			c <- res
+			<-sem
+		}(args)
+	}
+	close(order)
+	st := <-code
+
+	select {
+	case err := <-errc:
+		fmt.Fprintf(x.stderr, "xargs: %v\n", err)
+		st = worse(st, 1)
+	default:
+	}
+	return st
+}
+
+func main() {
+	flag.CommandLine.SetInterspersed(false)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("xargs: ")
+
+	x := &xargs{
+		cmd:     flag.Args(),
+		replace: *replace,
+		maxArgs: *maxArgs,
+		procs:   *maxProcs,
+		noRun:   *noRun,
+		split:   splitBlanks,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+	}
+	if len(x.cmd) == 0 {
+		x.cmd = []string{"echo"}
+	}
+	if x.procs < 1 {
+		log.Fatalf("invalid number of processes %d", x.procs)
+	}
+	if x.maxArgs < 0 {
+		log.Fatalf("invalid number of arguments %d", x.maxArgs)
+	}
+
+	switch {
+	case *null:
+		x.split = splitDelim(0)
+	case *delimiter != "":
+		d, err := parseDelim(*delimiter)
+		if err != nil {
+			log.Fatal(err)
+		}
+		x.split = splitDelim(d)
+	case x.replace != "":
+		x.split = splitLines
+	}
+
+	os.Exit(x.run(os.Stdin))
+}
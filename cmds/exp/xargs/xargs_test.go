@@ -0,0 +1,162 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/testutil"
+)
+
+func scan(t *testing.T, split bufio.SplitFunc, in string) ([]string, error) {
+	t.Helper()
+	s := bufio.NewScanner(strings.NewReader(in))
+	s.Split(split)
+	var items []string
+	for s.Scan() {
+		items = append(items, s.Text())
+	}
+	return items, s.Err()
+}
+
+func TestSplit(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		split bufio.SplitFunc
+		in    string
+		want  []string
+		err   bool
+	}{
+		{name: "blanks", split: splitBlanks, in: "a b\tc\n\n  d  ", want: []string{"a", "b", "c", "d"}},
+		{name: "quotes", split: splitBlanks, in: `'a b' "c 'd'" e\ f ''`, want: []string{"a b", "c 'd'", "e f", ""}},
+		{name: "unmatched", split: splitBlanks, in: `'a b`, err: true},
+		{name: "newline in quote", split: splitBlanks, in: "'a\nb'", err: true},
+		{name: "null", split: splitDelim(0), in: "a b\x00c\nd\x00", want: []string{"a b", "c\nd"}},
+		{name: "delim", split: splitDelim(','), in: "a,,b", want: []string{"a", "", "b"}},
+		{name: "lines", split: splitLines, in: "  a b\n c\n", want: []string{"a b", "c"}},
+	} {
+		got, err := scan(t, tt.split, tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: got err %v, want err %v", tt.name, err, tt.err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDelim(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want byte
+		err  bool
+	}{
+		{in: ",", want: ','},
+		{in: `\n`, want: '\n'},
+		{in: `\x41`, want: 'A'},
+		{in: `\0`, want: 0},
+		{in: `\101`, want: 'A'},
+		{in: "ab", err: true},
+		{in: `\q`, err: true},
+	} {
+		got, err := parseDelim(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseDelim(%q) = (%q, %v), want (%q, err %v)", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestXargs(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skipf("test needs /bin/sh: %v", err)
+	}
+
+	for _, tt := range []struct {
+		args []string
+		in   string
+		out  string
+		code int
+	}{
+		{in: "a b\nc", out: "a b c\n"},
+		{in: "", out: "\n"},
+		{args: []string{"-r"}, in: ""},
+		{args: []string{"-n", "2", "echo", "x"}, in: "a b c", out: "x a b\nx c\n"},
+		{args: []string{"-0"}, in: "a b\x00c\x00", out: "a b c\n"},
+		{args: []string{"-d", ",", "echo"}, in: "a,b", out: "a b\n"},
+		{args: []string{"-I", "{}", "echo", "<{}>", "-{}-"}, in: "a b\n c\n", out: "<a b> -a b-\n<c> -c-\n"},
+		// Output stays in input order, although the later commands
+		// finish first.
+		{args: []string{"-P", "3", "-n", "1", "sh", "-c", "sleep 0.$1; echo $1", "sh"}, in: "3 2 1", out: "3\n2\n1\n"},
+		{args: []string{"-n", "1", "sh", "-c", "exit $1", "sh"}, in: "0 1 0", code: 123},
+		{args: []string{"-P", "2", "-n", "1", "sh", "-c", "kill -9 $$"}, in: "a", code: 125},
+		// A command exiting with 255 stops xargs.
+		{args: []string{"-n", "1", "sh", "-c", "echo $1; exit $1", "sh"}, in: "1 255 2", out: "1\n255\n", code: 124},
+		{args: []string{"-n", "1", "/nonexistent/command"}, in: "a", code: 127},
+	} {
+		c := testutil.Command(t, tt.args...)
+		c.Stdin = strings.NewReader(tt.in)
+		var out bytes.Buffer
+		c.Stdout = &out
+		if err := testutil.IsExitCode(c.Run(), tt.code); err != nil {
+			t.Errorf("xargs %q: %v", tt.args, err)
+		}
+		if out.String() != tt.out {
+			t.Errorf("xargs %q: got %q, want %q", tt.args, out.String(), tt.out)
+		}
+	}
+}
+
+func TestXargsConcurrency(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skipf("test needs /bin/sh: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "xargs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	running := filepath.Join(dir, "running")
+	if err := os.Mkdir(running, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each command marks itself as running and prints how many commands
+	// are running at that time.
+	const procs = 3
+	script := `mkdir "$0/$1"; ls "$0" | wc -l; sleep 0.2; rmdir "$0/$1"`
+	c := testutil.Command(t, "-P", strconv.Itoa(procs), "-n", "1", "sh", "-c", script, running)
+	c.Stdin = strings.NewReader("1 2 3 4 5 6 7 8 9")
+	out, err := c.Output()
+	if err != nil {
+		t.Fatalf("xargs: %v", err)
+	}
+
+	lines := strings.Fields(string(out))
+	if len(lines) != 9 {
+		t.Fatalf("got %d commands, want 9: %q", len(lines), out)
+	}
+	for _, l := range lines {
+		n, err := strconv.Atoi(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > procs {
+			t.Errorf("got %d commands running at once, want at most %d", n, procs)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	testutil.Run(m, main)
+}
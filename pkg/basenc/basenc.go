@@ -0,0 +1,163 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package basenc encodes and decodes base64 and base32 streams the way the
+// base64 and base32 commands do.
+package basenc
+
+import (
+	"bufio"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidInput is returned when decoding input that is not encoded.
+var ErrInvalidInput = errors.New("invalid input")
+
+// Encoding is implemented by base64.Encoding and base32.Encoding.
+type Encoding interface {
+	Encode(dst, src []byte)
+	Decode(dst, src []byte) (int, error)
+	DecodedLen(n int) int
+}
+
+// Codec is an encoding, which encodes blocks of BlockSize bytes into
+// Quantum characters of Alphabet.
+type Codec struct {
+	Encoding  Encoding
+	Alphabet  string
+	BlockSize int
+	Quantum   int
+}
+
+var (
+	// Base64 is the standard base64 encoding of RFC 4648.
+	Base64 = &Codec{
+		Encoding:  base64.StdEncoding,
+		Alphabet:  "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/",
+		BlockSize: 3,
+		Quantum:   4,
+	}
+	// Base32 is the standard base32 encoding of RFC 4648.
+	Base32 = &Codec{
+		Encoding:  base32.StdEncoding,
+		Alphabet:  "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567",
+		BlockSize: 5,
+		Quantum:   8,
+	}
+)
+
+// blocks is how many blocks are encoded at a time.
+const blocks = 1024
+
+// wrapWriter breaks what is written into lines of wrap characters, unless
+// wrap is 0.
+type wrapWriter struct {
+	w    io.Writer
+	wrap int
+	col  int
+}
+
+func (ww *wrapWriter) Write(p []byte) (int, error) {
+	if ww.wrap == 0 {
+		return ww.w.Write(p)
+	}
+	n := 0
+	for len(p) > 0 {
+		if ww.col == ww.wrap {
+			if _, err := ww.w.Write([]byte{'\n'}); err != nil {
+				return n, err
+			}
+			ww.col = 0
+		}
+		c := ww.wrap - ww.col
+		if c > len(p) {
+			c = len(p)
+		}
+		m, err := ww.w.Write(p[:c])
+		n += m
+		ww.col += m
+		if err != nil {
+			return n, err
+		}
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// Encode encodes r to w, in lines of wrap characters, or in one line
+// without a newline if wrap is 0.
+func (c *Codec) Encode(w io.Writer, r io.Reader, wrap int) error {
+	ww := &wrapWriter{w: w, wrap: wrap}
+	src := make([]byte, c.BlockSize*blocks)
+	dst := make([]byte, c.Quantum*blocks)
+	for {
+		n, err := io.ReadFull(r, src)
+		if n > 0 {
+			m := (n + c.BlockSize - 1) / c.BlockSize * c.Quantum
+			c.Encoding.Encode(dst, src[:n])
+			if _, err := ww.Write(dst[:m]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if ww.col > 0 && wrap > 0 {
+		_, err := w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+// Decode decodes r to w. Newlines are skipped, and so are other characters
+// that are not in the alphabet if ignoreGarbage is set. Concatenated
+// encodings, each padded, are decoded. What can be decoded of a final
+// incomplete quantum is written before ErrInvalidInput is returned.
+func (c *Codec) Decode(w io.Writer, r io.Reader, ignoreGarbage bool) error {
+	br := bufio.NewReader(r)
+	src := make([]byte, 0, c.Quantum)
+	dst := make([]byte, c.Encoding.DecodedLen(c.Quantum))
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if b == '\n' || ignoreGarbage && b != '=' && strings.IndexByte(c.Alphabet, b) < 0 {
+			continue
+		}
+		if src = append(src, b); len(src) < c.Quantum {
+			continue
+		}
+		n, err := c.Encoding.Decode(dst, src)
+		if _, werr := w.Write(dst[:n]); werr != nil {
+			return werr
+		}
+		if err != nil {
+			return ErrInvalidInput
+		}
+		src = src[:0]
+	}
+	if len(src) == 0 {
+		return nil
+	}
+	for len(src) < c.Quantum {
+		src = append(src, '=')
+	}
+	n, _ := c.Encoding.Decode(dst, src)
+	if _, err := w.Write(dst[:n]); err != nil {
+		return err
+	}
+	return ErrInvalidInput
+}
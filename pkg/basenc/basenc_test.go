@@ -0,0 +1,83 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package basenc
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		codec *Codec
+		in    string
+		wrap  int
+		want  string
+	}{
+		{Base64, "", 76, ""},
+		{Base64, "", 0, ""},
+		{Base64, "abc", 0, "YWJj"},
+		{Base64, "abc", 76, "YWJj\n"},
+		{Base64, "abcdef", 4, "YWJj\nZGVm\n"},
+		{Base64, "abcde", 3, "YWJ\njZG\nU=\n"},
+		{Base64, "a", 1, "Y\nQ\n=\n=\n"},
+		{Base32, "abc", 76, "MFRGG===\n"},
+		{Base32, "abcde", 0, "MFRGGZDF"},
+		{Base32, "abcdef", 8, "MFRGGZDF\nMY======\n"},
+	} {
+		var out bytes.Buffer
+		if err := tt.codec.Encode(&out, strings.NewReader(tt.in), tt.wrap); err != nil || out.String() != tt.want {
+			t.Errorf("Encode(%q, %d) = %q, %v, want %q, nil", tt.in, tt.wrap, out.String(), err, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, tt := range []struct {
+		codec  *Codec
+		in     string
+		ignore bool
+		want   string
+		err    error
+	}{
+		{Base64, "", false, "", nil},
+		{Base64, "YWJj\nZGVm\n", false, "abcdef", nil},
+		{Base64, "YQ==YQ==", false, "aa", nil},
+		{Base64, "YW*Jj", false, "", ErrInvalidInput},
+		{Base64, "YW*Jj", true, "abc", nil},
+		{Base64, "Y W\tJ j\r\n", true, "abc", nil},
+		{Base64, "YWJjYWJ", false, "abcab", ErrInvalidInput},
+		{Base64, "YWJj=", false, "abc", ErrInvalidInput},
+		{Base32, "MFRGG===\n", false, "abc", nil},
+		{Base32, "MFRG-GZDF", true, "abcde", nil},
+		{Base32, "mfrggzdf", false, "", ErrInvalidInput},
+	} {
+		var out bytes.Buffer
+		if err := tt.codec.Decode(&out, strings.NewReader(tt.in), tt.ignore); err != tt.err || out.String() != tt.want {
+			t.Errorf("Decode(%q, %v) = %q, %v, want %q, %v", tt.in, tt.ignore, out.String(), err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(data)
+	for _, c := range []*Codec{Base64, Base32} {
+		for _, wrap := range []int{0, 1, 7, 76} {
+			var enc, dec bytes.Buffer
+			if err := c.Encode(&enc, bytes.NewReader(data), wrap); err != nil {
+				t.Fatalf("Encode() = %v", err)
+			}
+			if err := c.Decode(&dec, &enc, false); err != nil {
+				t.Fatalf("Decode() = %v", err)
+			}
+			if !bytes.Equal(dec.Bytes(), data) {
+				t.Errorf("Decode(Encode(data, %d)) != data", wrap)
+			}
+		}
+	}
+}
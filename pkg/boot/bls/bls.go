@@ -8,18 +8,26 @@
 // entries are supported at the moment, while Type #2 EFI entries are left
 // unimplemented awaiting EFI boot support in u-root/LinuxBoot.
 //
+// Entries are ranked by version, newest first. Variables in entries are
+// expanded: $BOOT, $machine_id and the variables of the GRUB environment
+// block, which Fedora and RHEL use for the kernel options.
+//
 // This package also supports the systemd-boot loader.conf as described in
 // https://www.freedesktop.org/software/systemd/man/loader.conf.html. Only the
-// "default" keyword is implemented.
+// "default" and "timeout" keywords are implemented.
 package bls
 
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/u-root/u-root/pkg/boot"
 	"github.com/u-root/u-root/pkg/ulog"
@@ -30,10 +38,44 @@ const (
 )
 
 func cutConf(s string) string {
-	if strings.HasSuffix(s, ".conf") {
-		return s[:len(s)-6]
+	return strings.TrimSuffix(s, ".conf")
+}
+
+// LoaderConf is the systemd-boot loader.conf.
+type LoaderConf struct {
+	// Default is a glob pattern of the entries to boot by default,
+	// without the .conf suffix.
+	Default string
+
+	// Timeout is how long the menu is shown before the default entry is
+	// booted. If it is 0, the menu is not shown. If it is negative
+	// ("menu-force"), the menu is shown until an entry is chosen.
+	Timeout time.Duration
+}
+
+// ReadLoaderConf reads loader/loader.conf from the filesystem root. The
+// file is optional: if it does not exist, the zero LoaderConf is returned.
+func ReadLoaderConf(fsRoot string) (LoaderConf, error) {
+	var lc LoaderConf
+	vals, err := parseConf(filepath.Join(fsRoot, "loader", "loader.conf"))
+	if os.IsNotExist(err) {
+		return lc, nil
+	} else if err != nil {
+		return lc, err
+	}
+	lc.Default = cutConf(last(vals, "default"))
+	switch t := last(vals, "timeout"); t {
+	case "", "menu-hidden":
+	case "menu-force":
+		lc.Timeout = -1
+	default:
+		sec, err := strconv.ParseUint(t, 10, 32)
+		if err != nil {
+			return lc, fmt.Errorf("invalid loader.conf timeout %q", t)
+		}
+		lc.Timeout = time.Duration(sec) * time.Second
 	}
-	return s
+	return lc, nil
 }
 
 // ScanBLSEntries scans the filesystem root for valid BLS entries.
@@ -50,35 +92,42 @@ func ScanBLSEntries(log ulog.Logger, fsRoot string) ([]boot.OSImage, error) {
 	// loader.conf is not in the real spec; it's an implementation detail
 	// of systemd-boot. It is specified in
 	// https://www.freedesktop.org/software/systemd/man/loader.conf.html
-	loaderConf, err := parseConf(filepath.Join(fsRoot, "loader", "loader.conf"))
+	loaderConf, err := ReadLoaderConf(fsRoot)
 	if err != nil {
-		// loader.conf is optional.
-		loaderConf = make(map[string]string)
+		log.Printf("BootLoaderSpec ignoring loader.conf: %v", err)
 	}
 
-	// TODO: Rank entries by version or machine-id attribute as suggested
-	// in the spec (but not mandated, surprisingly).
-	imgs := make(map[string]boot.OSImage)
+	env := readGrubEnv(fsRoot)
+	entries := make(map[string]*entry)
 	for _, f := range files {
 		identifier := cutConf(filepath.Base(f))
 
-		img, err := parseBLSEntry(f, fsRoot)
+		e, err := parseBLSEntry(f, fsRoot, env)
 		if err != nil {
 			log.Printf("BootLoaderSpec skipping entry %s: %v", f, err)
 			continue
 		}
-		imgs[identifier] = img
+		entries[identifier] = e
 	}
 
-	return sortImages(loaderConf, imgs), nil
+	return sortImages(loaderConf, entries), nil
 }
 
-func sortImages(loaderConf map[string]string, imgs map[string]boot.OSImage) []boot.OSImage {
+// entry is a parsed BLS entry.
+type entry struct {
+	boot.OSImage
+	version string
+}
+
+// sortImages ranks the entries matching the loader.conf default first. Both
+// groups are sorted by version and then identifier, newest first, as the spec
+// suggests.
+func sortImages(loaderConf LoaderConf, entries map[string]*entry) []boot.OSImage {
 	// rankedImages = sort(default-images) + sort(remaining images)
 	var rankedImages []boot.OSImage
 
-	pattern, ok := loaderConf["default"]
-	if !ok {
+	pattern := loaderConf.Default
+	if pattern == "" {
 		// All images are default.
 		pattern = "*"
 	}
@@ -87,9 +136,9 @@ func sortImages(loaderConf map[string]string, imgs map[string]boot.OSImage) []bo
 	var otherIdents []string
 
 	// Find default and non-default identifiers.
-	for ident := range imgs {
+	for ident := range entries {
 		ok, err := filepath.Match(pattern, ident)
-		if err != nil && ok {
+		if err == nil && ok {
 			defaultIdents = append(defaultIdents, ident)
 		} else {
 			otherIdents = append(otherIdents, ident)
@@ -97,115 +146,257 @@ func sortImages(loaderConf map[string]string, imgs map[string]boot.OSImage) []bo
 	}
 
 	// Sort them in the order we want them.
-	sort.Sort(sort.Reverse(sort.StringSlice(defaultIdents)))
-	sort.Sort(sort.Reverse(sort.StringSlice(otherIdents)))
+	newest := func(idents []string) func(i, j int) bool {
+		return func(i, j int) bool {
+			if c := compareVersions(entries[idents[i]].version, entries[idents[j]].version); c != 0 {
+				return c > 0
+			}
+			return idents[i] > idents[j]
+		}
+	}
+	sort.Slice(defaultIdents, newest(defaultIdents))
+	sort.Slice(otherIdents, newest(otherIdents))
 
 	// Add images to rankedImages in that sorted order, defaults first.
 	for _, ident := range defaultIdents {
-		rankedImages = append(rankedImages, imgs[ident])
+		rankedImages = append(rankedImages, entries[ident].OSImage)
 	}
 	for _, ident := range otherIdents {
-		rankedImages = append(rankedImages, imgs[ident])
+		rankedImages = append(rankedImages, entries[ident].OSImage)
 	}
 	return rankedImages
 }
 
-func parseConf(entryPath string) (map[string]string, error) {
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isSeparator(r rune) bool {
+	return r >= 128 || r != '~' && !isDigit(byte(r)) && !isAlpha(byte(r))
+}
+
+// compareVersions compares two versions like rpm and systemd-boot do, and
+// returns -1, 0 or 1 if a is older than, the same as or newer than b. Runs of
+// digits are compared numerically and runs of letters lexically, a number
+// being newer than letters. Other characters separate runs, except for "~",
+// which sorts before anything, even the end of a version.
+func compareVersions(a, b string) int {
+	for {
+		a, b = strings.TrimLeftFunc(a, isSeparator), strings.TrimLeftFunc(b, isSeparator)
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		run := isAlpha
+		if isDigit(a[0]) {
+			run = isDigit
+		}
+		if !run(b[0]) {
+			// A number is newer than letters.
+			if isDigit(a[0]) {
+				return 1
+			}
+			return -1
+		}
+		i, j := 0, 0
+		for i < len(a) && run(a[i]) {
+			i++
+		}
+		for j < len(b) && run(b[j]) {
+			j++
+		}
+		ra, rb := a[:i], b[:j]
+		a, b = a[i:], b[j:]
+		if isDigit(ra[0]) {
+			ra, rb = strings.TrimLeft(ra, "0"), strings.TrimLeft(rb, "0")
+			if len(ra) != len(rb) {
+				if len(ra) > len(rb) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// readGrubEnv reads the variables of the GRUB environment block on $BOOT,
+// which Fedora and RHEL entries use in their options, e.g. $kernelopts.
+func readGrubEnv(fsRoot string) map[string]string {
+	env := make(map[string]string)
+	for _, p := range []string{"grub2/grubenv", "grub/grubenv"} {
+		b, err := ioutil.ReadFile(filepath.Join(fsRoot, p))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+				env[kv[0]] = kv[1]
+			}
+		}
+		break
+	}
+	return env
+}
+
+// expand substitutes variables in the values of an entry: $BOOT is the root
+// of the file system, which paths are relative to anyway, $machine_id is the
+// machine-id of the entry, and other variables come from the GRUB
+// environment env. Unknown variables are empty, like in GRUB.
+func expand(vals map[string][]string, env map[string]string) {
+	machineID := last(vals, "machine-id")
+	mapping := func(name string) string {
+		switch name {
+		case "BOOT":
+			return ""
+		case "machine_id", "MACHINE_ID":
+			return machineID
+		}
+		return env[name]
+	}
+	for _, key := range []string{"linux", "initrd", "options", "devicetree"} {
+		for i, v := range vals[key] {
+			if strings.Contains(v, "$") {
+				vals[key][i] = strings.TrimSpace(os.Expand(v, mapping))
+			}
+		}
+	}
+}
+
+func parseConf(entryPath string) (map[string][]string, error) {
 	f, err := os.Open(entryPath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	vals := make(map[string]string)
+	vals := make(map[string][]string)
 
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := strings.TrimSpace(scanner.Text())
 		if strings.HasPrefix(line, "#") {
 			continue
 		}
-		line = strings.TrimSpace(line)
 
-		sline := strings.SplitN(line, " ", 2)
-		if len(sline) != 2 {
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
 			continue
 		}
-		vals[sline[0]] = strings.TrimSpace(sline[1])
+		key := line[:i]
+		vals[key] = append(vals[key], strings.TrimSpace(line[i+1:]))
 	}
-	return vals, nil
+	return vals, scanner.Err()
 }
 
-// The spec says "$BOOT/loader/ is the directory containing all files needed
-// for Type #1 entries", but that's bullshit. Relative file names are indeed in
-// the $BOOT/loader/ directory, but absolute path names are in $BOOT, as
-// evidenced by the entries that kernel-install installs on Fedora 32.
+// last returns the last value of key, which takes precedence for keys that
+// may only appear once.
+func last(vals map[string][]string, key string) string {
+	if v := vals[key]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+// filePath resolves a path of an entry. The spec says paths are relative to
+// the root of $BOOT, which is how kernel-install writes them on Fedora 32.
+// Older entries have paths relative to $BOOT/loader/, which is used if the
+// file does not exist relative to $BOOT.
 func filePath(fsRoot, value string) string {
-	if !filepath.IsAbs(value) {
+	p := filepath.Join(fsRoot, value)
+	if filepath.IsAbs(value) {
+		return p
+	}
+	if _, err := os.Stat(p); err != nil {
 		return filepath.Join(fsRoot, "loader", value)
 	}
-	return filepath.Join(fsRoot, value)
+	return p
 }
 
-func parseLinuxImage(vals map[string]string, fsRoot string) (boot.OSImage, error) {
+func parseLinuxImage(vals map[string][]string, fsRoot string) (boot.OSImage, error) {
 	linux := &boot.LinuxImage{}
 
-	var cmdlines []string
-	for key, val := range vals {
-		switch key {
-		case "linux":
-			f, err := os.Open(filePath(fsRoot, val))
-			if err != nil {
-				return nil, err
-			}
-			linux.Kernel = f
-
-		// TODO: initrd may be specified more than once.
-		case "initrd":
-			f, err := os.Open(filePath(fsRoot, val))
-			if err != nil {
-				return nil, err
-			}
-			linux.Initrd = f
-
-		case "devicetree":
-			// Explicitly return an error rather than ignore this,
-			// because the intended kernel likely won't boot
-			// correctly if we silently ignore this attribute.
-			return nil, fmt.Errorf("devicetree attribute unsupported for Linux entries")
+	// Spec says kernel is required.
+	kernel := last(vals, "linux")
+	if kernel == "" {
+		return nil, fmt.Errorf("malformed Linux config: linux keyword missing")
+	}
+	f, err := os.Open(filePath(fsRoot, kernel))
+	if err != nil {
+		return nil, err
+	}
+	linux.Kernel = f
 
-		// options may appear more than once.
-		case "options":
-			cmdlines = append(cmdlines, val)
+	// initrd may be specified more than once; the initrds are
+	// concatenated in order.
+	var initrds []io.ReaderAt
+	for _, val := range vals["initrd"] {
+		f, err := os.Open(filePath(fsRoot, val))
+		if err != nil {
+			return nil, err
 		}
+		initrds = append(initrds, f)
+	}
+	switch len(initrds) {
+	case 0:
+	case 1:
+		linux.Initrd = initrds[0]
+	default:
+		linux.Initrd = boot.CatInitrds(initrds...)
 	}
 
-	// Spec says kernel is required.
-	if linux.Kernel == nil {
-		return nil, fmt.Errorf("malformed Linux config: linux keyword missing")
+	if _, ok := vals["devicetree"]; ok {
+		// Explicitly return an error rather than ignore this,
+		// because the intended kernel likely won't boot
+		// correctly if we silently ignore this attribute.
+		return nil, fmt.Errorf("devicetree attribute unsupported for Linux entries")
 	}
 
 	var name []string
-	if title, ok := vals["title"]; ok && len(title) > 0 {
+	if title := last(vals, "title"); len(title) > 0 {
 		name = append(name, title)
 	}
-	if version, ok := vals["version"]; ok && len(version) > 0 {
+	if version := last(vals, "version"); len(version) > 0 {
 		name = append(name, version)
 	}
 	// If both title and version were empty, so will this.
 	linux.Name = strings.Join(name, " ")
-	linux.Cmdline = strings.Join(cmdlines, " ")
+	// options may appear more than once.
+	linux.Cmdline = strings.Join(vals["options"], " ")
 	return linux, nil
 }
 
 // parseBLSEntry takes a Type #1 BLS entry and the directory of entries, and
-// returns a LinuxImage.
+// returns a LinuxImage. Variables in the entry are expanded with env.
 // An error is returned if the syntax is wrong or required keys are missing.
-func parseBLSEntry(entryPath, fsRoot string) (boot.OSImage, error) {
+func parseBLSEntry(entryPath, fsRoot string, env map[string]string) (*entry, error) {
 	vals, err := parseConf(entryPath)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing config in %s: %w", entryPath, err)
 	}
+	expand(vals, env)
 
 	var img boot.OSImage
 	err = fmt.Errorf("neither linux, efi, nor multiboot present in BootLoaderSpec config")
@@ -219,5 +410,5 @@ func parseBLSEntry(entryPath, fsRoot string) (boot.OSImage, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error parsing config in %s: %w", entryPath, err)
 	}
-	return img, nil
+	return &entry{OSImage: img, version: last(vals, "version")}, nil
 }
@@ -5,11 +5,15 @@
 package bls
 
 import (
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/u-root/u-root/pkg/boot"
 	"github.com/u-root/u-root/pkg/boot/boottest"
 	"github.com/u-root/u-root/pkg/ulog/ulogtest"
 )
@@ -33,7 +37,7 @@ func TestParseBLSEntries(t *testing.T) {
 
 	for _, tt := range blsEntries {
 		t.Run(tt.entry, func(t *testing.T) {
-			image, err := parseBLSEntry(filepath.Join(dir, tt.entry), fsRoot)
+			image, err := parseBLSEntry(filepath.Join(dir, tt.entry), fsRoot, nil)
 			if err != nil {
 				if tt.err == "" {
 					t.Fatalf("Got error %v", err)
@@ -78,6 +82,149 @@ func TestScanBLSEntries(t *testing.T) {
 	}
 }
 
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "bls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadLoaderConf(t *testing.T) {
+	for _, tt := range []struct {
+		conf string
+		want LoaderConf
+		err  bool
+	}{
+		{conf: "", want: LoaderConf{}},
+		{conf: "default arch.conf\ntimeout 5\n", want: LoaderConf{Default: "arch", Timeout: 5 * time.Second}},
+		{conf: "#timeout 3\ndefault\tfedora-*\n", want: LoaderConf{Default: "fedora-*"}},
+		{conf: "timeout menu-force\n", want: LoaderConf{Timeout: -1}},
+		{conf: "timeout menu-hidden\n", want: LoaderConf{}},
+		{conf: "timeout 3\ntimeout 0\n", want: LoaderConf{}},
+		{conf: "timeout soon\n", err: true},
+	} {
+		dir := writeFiles(t, map[string]string{"loader/loader.conf": tt.conf})
+		defer os.RemoveAll(dir)
+
+		got, err := ReadLoaderConf(dir)
+		if (err != nil) != tt.err {
+			t.Errorf("ReadLoaderConf(%q) = %v, want error %t", tt.conf, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ReadLoaderConf(%q) = %+v, want %+v", tt.conf, got, tt.want)
+		}
+	}
+
+	got, err := ReadLoaderConf("testdata/madeup")
+	if err != nil || got != (LoaderConf{}) {
+		t.Errorf("ReadLoaderConf() without loader.conf = %+v, %v, want zero LoaderConf", got, err)
+	}
+}
+
+func TestScanESP(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"loader/loader.conf":      "default b.conf\ntimeout 3\n",
+		"loader/entries/a.conf":   "title A\nlinux /a/vmlinuz\ninitrd a/ucode.img\ninitrd /a/initrd\noptions root=/dev/sda2\noptions quiet\n",
+		"loader/entries/b.conf":   "title B\nlinux b/vmlinuz\n",
+		"loader/entries/bad.conf": "title Bad\nlinux missing\n",
+		"a/vmlinuz":               "a kernel",
+		"a/ucode.img":             "ucode",
+		"a/initrd":                "initrd",
+		"b/vmlinuz":               "b kernel",
+	})
+	defer os.RemoveAll(dir)
+
+	imgs, err := ScanBLSEntries(ulogtest.Logger{TB: t}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != 2 {
+		t.Fatalf("ScanBLSEntries() = %v, want 2 images", imgs)
+	}
+	if got := imgs[0].Label(); got != "B" {
+		t.Errorf("default image = %s, want B", got)
+	}
+	a, ok := imgs[1].(*boot.LinuxImage)
+	if !ok {
+		t.Fatalf("image = %T, want *boot.LinuxImage", imgs[1])
+	}
+	if want := "root=/dev/sda2 quiet"; a.Cmdline != want {
+		t.Errorf("cmdline = %q, want %q", a.Cmdline, want)
+	}
+	initrd, err := ioutil.ReadAll(io.NewSectionReader(a.Initrd, 0, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(initrd), "ucode") || !strings.Contains(string(initrd), "initrd") {
+		t.Errorf("initrd = %q, want concatenation of ucode and initrd", initrd)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"5.6.6-300.fc32.x86_64", "5.6.6-300.fc32.x86_64", 0},
+		{"5.10.0", "5.9.12", 1},
+		{"5.9.12", "5.10.0", -1},
+		{"5.8.0-1", "5.8.0", 1},
+		{"5.8.0~rc1", "5.8.0", -1},
+		{"5.8.0~rc1", "5.8.0~rc2", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.01", -1},
+		{"010", "9", 1},
+		{"", "1", -1},
+		{"", "", 0},
+	} {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScanFedoraBoot(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"grub2/grubenv":                  "# GRUB Environment Block\nkernelopts=root=/dev/mapper/fedora-root ro\nsaved_entry=x\n####\n",
+		"loader/entries/m-5.8.conf":      "title Fedora\nversion 5.8.15-301.fc33.x86_64\nmachine-id m\nlinux /vmlinuz-5.8\noptions $kernelopts quiet\n",
+		"loader/entries/m-5.10.conf":     "title Fedora\nversion 5.10.7-200.fc33.x86_64\nmachine-id m\nlinux $BOOT/$machine_id/vmlinuz-5.10\noptions $kernelopts $unset\n",
+		"loader/entries/m-0-rescue.conf": "title Fedora Rescue\nversion 0-rescue\nmachine-id m\nlinux /vmlinuz-0-rescue\n",
+		"vmlinuz-5.8":                    "5.8",
+		"m/vmlinuz-5.10":                 "5.10",
+		"vmlinuz-0-rescue":               "rescue",
+	})
+	defer os.RemoveAll(dir)
+
+	imgs, err := ScanBLSEntries(ulogtest.Logger{TB: t}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, img := range imgs {
+		li := img.(*boot.LinuxImage)
+		got = append(got, li.Name+": "+li.Cmdline)
+	}
+	want := []string{
+		"Fedora 5.10.7-200.fc33.x86_64: root=/dev/mapper/fedora-root ro",
+		"Fedora 5.8.15-301.fc33.x86_64: root=/dev/mapper/fedora-root ro quiet",
+		"Fedora Rescue 0-rescue: ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ScanBLSEntries() = %q, want %q", got, want)
+	}
+}
+
 // Enable this temporarily to generate new configs. Double-check them by hand.
 func DISABLEDTestGenerateConfigs(t *testing.T) {
 	tests, err := filepath.Glob("testdata/*.json")
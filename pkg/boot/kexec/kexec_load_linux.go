@@ -5,7 +5,9 @@
 package kexec
 
 import (
+	"debug/elf"
 	"fmt"
+	"io"
 	"syscall"
 	"unsafe"
 
@@ -30,6 +32,24 @@ func Load(entry uintptr, segments Segments, flags uint64) error {
 	return rawLoad(entry, segments, flags)
 }
 
+// LoadELF loads the loadable segments of the ELF file r to be executed on a
+// kexec-reboot, which jumps to the entry point of r.
+//
+// Unlike FileLoad, LoadELF does not let the kernel verify the signature of
+// r, and it does not set up any boot protocol, e.g. to pass an initramfs or
+// command line.
+func LoadELF(r io.ReaderAt) error {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return err
+	}
+	var m Memory
+	if err := m.LoadElfSegments(r); err != nil {
+		return err
+	}
+	return Load(uintptr(f.Entry), m.Segments, 0)
+}
+
 // ErrKexec is returned by Load if the kexec failed. It describes entry point,
 // flags, errno and kernel layout.
 type ErrKexec struct {
@@ -5,12 +5,14 @@
 package boot
 
 import (
+	"debug/elf"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"syscall"
 
 	"github.com/u-root/u-root/pkg/boot/kexec"
 	"github.com/u-root/u-root/pkg/boot/util"
@@ -24,6 +26,12 @@ type LinuxImage struct {
 	Kernel  io.ReaderAt
 	Initrd  io.ReaderAt
 	Cmdline string
+
+	// FileLoadOnly makes Load fail if kexec_file_load is not available,
+	// rather than fall back to kexec_load, so that the running kernel
+	// always gets to verify the signature of Kernel on systems that
+	// enforce them, e.g. with secure boot or IMA.
+	FileLoadOnly bool
 }
 
 var _ OSImage = &LinuxImage{}
@@ -56,6 +64,12 @@ func (li *LinuxImage) String() string {
 	return fmt.Sprintf("LinuxImage(\n  Name: %s\n  Kernel: %s\n  Initrd: %s\n  Cmdline: %s\n)\n", li.Name, stringer(li.Kernel), stringer(li.Initrd), li.Cmdline)
 }
 
+// Overridden in tests.
+var (
+	fileLoad = kexec.FileLoad
+	loadELF  = kexec.LoadELF
+)
+
 func copyToFile(r io.Reader) (*os.File, error) {
 	f, err := ioutil.TempFile("", "kexec-image")
 	if err != nil {
@@ -131,5 +145,44 @@ func (li *LinuxImage) Load(verbose bool) error {
 		}
 		log.Printf("Command line: %s", li.Cmdline)
 	}
-	return kexec.FileLoad(k, i, li.Cmdline)
+	return li.load(k, i)
+}
+
+// load loads the kernel k with kexec_file_load, or with kexec_load if the
+// running kernel does not support kexec_file_load, k is an ELF file, and
+// FileLoadOnly is not set. kexec_load only loads the segments of k and
+// jumps to its entry point, without boot parameters, so it is not used if
+// there is an initramfs or a command line to pass.
+func (li *LinuxImage) load(k, i *os.File) error {
+	err := fileLoad(k, i, li.Cmdline)
+	if err == nil {
+		log.Printf("Loaded %s with kexec_file_load", li.Label())
+		return nil
+	}
+	if !errors.Is(err, syscall.ENOSYS) {
+		return err
+	}
+	if li.FileLoadOnly {
+		return fmt.Errorf("kexec_file_load is required, but not supported by the running kernel: %w", err)
+	}
+	if !isELF(k) {
+		return fmt.Errorf("kexec_file_load is not supported by the running kernel, and kexec_load needs an ELF kernel: %w", err)
+	}
+	if i != nil || li.Cmdline != "" {
+		return fmt.Errorf("kexec_file_load is not supported by the running kernel, and kexec_load cannot pass an initramfs or command line: %w", err)
+	}
+	if err := loadELF(k); err != nil {
+		return err
+	}
+	log.Printf("kexec_file_load is not supported; loaded %s with kexec_load, without verifying its signature", li.Label())
+	return nil
+}
+
+// isELF returns whether r starts with the ELF magic.
+func isELF(r io.ReaderAt) bool {
+	magic := make([]byte, len(elf.ELFMAG))
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return false
+	}
+	return string(magic) == elf.ELFMAG
 }
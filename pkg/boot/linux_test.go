@@ -7,10 +7,12 @@ package boot
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/u-root/u-root/pkg/curl"
@@ -122,3 +124,66 @@ func TestCopyToFile(t *testing.T) {
 		t.Errorf("got %s, expected %s", string(got), "abcdefg hijklmnop")
 	}
 }
+
+func TestLinuxLoadFallback(t *testing.T) {
+	defer func(f func(k, i *os.File, cmdline string) error, l func(io.ReaderAt) error) {
+		fileLoad, loadELF = f, l
+	}(fileLoad, loadELF)
+
+	dir, err := ioutil.TempDir("", "linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	elfKernel := filepath.Join(dir, "vmlinux")
+	if err := ioutil.WriteFile(elfKernel, []byte("\x7fELF\x02\x01\x01"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bzImage := filepath.Join(dir, "bzImage")
+	if err := ioutil.WriteFile(bzImage, []byte("MZ\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		desc         string
+		kernel       string
+		fileLoadErr  error
+		fileLoadOnly bool
+		cmdline      string
+		initrd       bool
+		wantELF      bool
+		wantErr      bool
+	}{
+		{desc: "kexec_file_load", kernel: bzImage},
+		{desc: "kexec_file_load error", kernel: bzImage, fileLoadErr: syscall.EPERM, wantErr: true},
+		{desc: "fallback", kernel: elfKernel, fileLoadErr: fmt.Errorf("sys_kexec: %w", syscall.ENOSYS), wantELF: true},
+		{desc: "no fallback for bzImage", kernel: bzImage, fileLoadErr: syscall.ENOSYS, wantErr: true},
+		{desc: "file load only", kernel: elfKernel, fileLoadErr: syscall.ENOSYS, fileLoadOnly: true, wantErr: true},
+		{desc: "no fallback with a command line", kernel: elfKernel, fileLoadErr: syscall.ENOSYS, cmdline: "console=ttyS0", wantErr: true},
+		{desc: "no fallback with an initramfs", kernel: elfKernel, fileLoadErr: syscall.ENOSYS, initrd: true, wantErr: true},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			var usedELF bool
+			fileLoad = func(k, i *os.File, cmdline string) error { return tt.fileLoadErr }
+			loadELF = func(io.ReaderAt) error {
+				usedELF = true
+				return nil
+			}
+			li := &LinuxImage{
+				Kernel:       uio.NewLazyFile(tt.kernel),
+				Cmdline:      tt.cmdline,
+				FileLoadOnly: tt.fileLoadOnly,
+			}
+			if tt.initrd {
+				li.Initrd = uio.NewLazyFile(bzImage)
+			}
+			err := li.Load(false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() = %v, want error %t", err, tt.wantErr)
+			}
+			if usedELF != tt.wantELF {
+				t.Errorf("Load() used kexec_load: %t, want %t", usedELF, tt.wantELF)
+			}
+		})
+	}
+}
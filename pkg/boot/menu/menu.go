@@ -7,6 +7,7 @@
 package menu
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log"
@@ -27,6 +28,13 @@ var (
 	subsequentTimeout = 60 * time.Second
 )
 
+// SetInitialTimeout sets how long the menu waits for the user to choose an
+// entry before it falls back to the default entries. Any key press extends the
+// timeout.
+func SetInitialTimeout(timeout time.Duration) {
+	initialTimeout = timeout
+}
+
 // Entry is a menu entry.
 type Entry interface {
 	// Label is the string displayed to the user in the menu.
@@ -50,6 +58,89 @@ type Entry interface {
 	IsDefault() bool
 }
 
+// ANSI escape sequences of the up and down arrow keys, in normal and
+// application cursor key mode.
+var (
+	arrowUp   = []string{"\x1b[A", "\x1bOA"}
+	arrowDown = []string{"\x1b[B", "\x1bOB"}
+)
+
+// replaceLine are the keys that replace the input line of a
+// terminal.Terminal: End, then Ctrl-U to erase to the start of the line.
+const replaceLine = "\x1b[F\x15"
+
+// arrowKeys translates the up and down arrow keys into entry numbers, so
+// that entries can be selected with the arrow keys on terminals that send
+// ANSI escape sequences. Entry numbers can still be typed.
+type arrowKeys struct {
+	r io.Reader
+	// entries is the number of entries.
+	entries int
+	// enabled is set while the user chooses an entry.
+	enabled bool
+	// sel is the selected entry number, 0 if none was selected yet.
+	sel int
+	// pending is the start of an escape sequence cut off by a read.
+	pending []byte
+	out     []byte
+}
+
+func (a *arrowKeys) Read(p []byte) (int, error) {
+	for len(a.out) == 0 {
+		b := make([]byte, len(p))
+		n, err := a.r.Read(b)
+		if n == 0 {
+			return 0, err
+		}
+		a.out = a.translate(append(a.pending, b[:n]...))
+	}
+	n := copy(p, a.out)
+	a.out = a.out[n:]
+	return n, nil
+}
+
+func seqPrefix(b []byte, seqs []string) int {
+	for _, seq := range seqs {
+		if bytes.HasPrefix(b, []byte(seq)) {
+			return len(seq)
+		}
+	}
+	return 0
+}
+
+// translate replaces the arrow keys in b with keys that replace the input
+// line with the selected entry number.
+func (a *arrowKeys) translate(b []byte) []byte {
+	a.pending = nil
+	if !a.enabled {
+		return b
+	}
+	var out []byte
+	for len(b) > 0 {
+		if n := seqPrefix(b, arrowUp); n > 0 {
+			if a.sel--; a.sel < 1 {
+				a.sel = 1
+			}
+			b = b[n:]
+		} else if n := seqPrefix(b, arrowDown); n > 0 {
+			if a.sel++; a.sel > a.entries {
+				a.sel = a.entries
+			}
+			b = b[n:]
+		} else if b[0] == '\x1b' && len(b) < 3 {
+			// Wait for the rest of the escape sequence.
+			a.pending = append([]byte(nil), b...)
+			break
+		} else {
+			out = append(out, b[0])
+			b = b[1:]
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s%02d", replaceLine, a.sel)...)
+	}
+	return out
+}
+
 func parseBootNum(choice string, entries []Entry) (int, error) {
 	num, err := strconv.Atoi(choice)
 	if err != nil {
@@ -96,7 +187,11 @@ func Choose(input *os.File, entries ...Entry) Entry {
 		//
 		//     Select a boot option to edit:
 		//      >
-		term := terminal.NewTerminal(input, "")
+		arrows := &arrowKeys{r: input, entries: len(entries)}
+		term := terminal.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{arrows, input}, "")
 
 		term.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
 			// We ain't gonna autocomplete, but we'll reset the countdown timer when you press a key.
@@ -105,8 +200,10 @@ func Choose(input *os.File, entries ...Entry) Entry {
 		}
 
 		for {
-			term.SetPrompt("Enter an option ('01' is the default, 'e' to edit kernel cmdline):\r\n > ")
+			term.SetPrompt("Enter an option ('01' is the default, up/down to select, 'e' to edit kernel cmdline):\r\n > ")
+			arrows.enabled = true
 			choice, err := term.ReadLine()
+			arrows.enabled = false
 			if err != nil {
 				if err != io.EOF {
 					fmt.Printf("BUG: Please report: Terminal read error: %v.\n", err)
@@ -127,6 +127,18 @@ func TestChoose(t *testing.T) {
 			userEntry: []byte("abc\r\n"),
 			want:      nil,
 		},
+		{
+			name:      "arrow_down_twice",
+			entries:   []Entry{entry1, entry2, entry3},
+			userEntry: []byte("\x1b[B\x1b[B\r\n"),
+			want:      entry2,
+		},
+		{
+			name:      "arrow_past_the_end",
+			entries:   []Entry{entry1, entry2, entry3},
+			userEntry: []byte("\x1b[B\x1b[B\x1b[B\x1b[B\x1bOA\r\n"),
+			want:      entry2,
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			pty, err := term.OpenPTY()
@@ -161,6 +173,51 @@ func TestChoose(t *testing.T) {
 	}
 }
 
+func TestArrowKeys(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		reads []string
+		want  string
+	}{
+		{
+			name:  "typed",
+			reads: []string{"12\r"},
+			want:  "12\r",
+		},
+		{
+			name:  "down",
+			reads: []string{"\x1b[B"},
+			want:  replaceLine + "01",
+		},
+		{
+			name:  "down up clamps",
+			reads: []string{"\x1b[B\x1b[B\x1b[A\x1b[A\x1b[A"},
+			want:  replaceLine + "01" + replaceLine + "02" + replaceLine + "01" + replaceLine + "01" + replaceLine + "01",
+		},
+		{
+			name:  "split escape sequence",
+			reads: []string{"\x1b", "[B", "\x1bO", "B\r"},
+			want:  replaceLine + "01" + replaceLine + "02\r",
+		},
+		{
+			name:  "other escape sequences",
+			reads: []string{"\x1b[D1\r"},
+			want:  "\x1b[D1\r",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &arrowKeys{entries: 3, enabled: true}
+			var got []byte
+			for _, r := range tt.reads {
+				got = append(got, a.translate(append(a.pending, r...))...)
+			}
+			if string(got) != tt.want {
+				t.Errorf("translate(%q) = %q, want %q", tt.reads, got, tt.want)
+			}
+		})
+	}
+}
+
 func contains(s []string, t string) bool {
 	for _, u := range s {
 		if u == t {
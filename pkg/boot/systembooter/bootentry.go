@@ -26,6 +26,8 @@ type BootEntry struct {
 	Name   string
 	Config []byte
 	Booter Booter
+	// Match is the optional hardware condition of the entry.
+	Match *Match
 }
 
 var supportedBooterParsers = []func([]byte) (Booter, error){
@@ -84,6 +86,13 @@ func GetBootEntries() []BootEntry {
 		if entry.Booter == nil {
 			log.Printf("No booter found for entry: %+v", entry)
 		}
+		match, err := ParseMatch(entry.Config)
+		if err != nil {
+			// Do not boot an entry on hardware it may not be meant for.
+			log.Printf("Invalid match block in entry %s: %v", entry.Name, err)
+			entry.Booter = &NullBooter{}
+		}
+		entry.Match = match
 		bootEntries[idx] = entry
 	}
 	return bootEntries
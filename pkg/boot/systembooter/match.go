@@ -0,0 +1,114 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package systembooter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/u-root/u-root/pkg/smbios"
+)
+
+// Match is an optional condition on SMBIOS fields in a boot entry config. An
+// entry with a match block is only booted on matching hardware, e.g.
+//
+//	{
+//	    "type": "netboot",
+//	    "method": "dhcpv6",
+//	    "mac": "aa:bb:cc:dd:ee:ff",
+//	    "match": {"baseboard_product": "^Tioga Pass"}
+//	}
+//
+// Each field is a regular expression that the SMBIOS field has to match. Empty
+// fields match anything.
+type Match struct {
+	SystemManufacturer    string `json:"system_manufacturer,omitempty"`
+	SystemProduct         string `json:"system_product,omitempty"`
+	BaseboardManufacturer string `json:"baseboard_manufacturer,omitempty"`
+	BaseboardProduct      string `json:"baseboard_product,omitempty"`
+}
+
+// Hardware holds the SMBIOS fields that a Match checks.
+type Hardware struct {
+	SystemManufacturer    string
+	SystemProduct         string
+	BaseboardManufacturer string
+	BaseboardProduct      string
+}
+
+// HardwareFromSMBIOS returns the fields of the system (type 1) and first
+// baseboard (type 2) tables of si.
+func HardwareFromSMBIOS(si *smbios.Info) (*Hardware, error) {
+	sys, err := si.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	hw := &Hardware{
+		SystemManufacturer: sys.Manufacturer,
+		SystemProduct:      sys.ProductName,
+	}
+	// Not every system has a baseboard table.
+	if bb, err := si.GetBaseboardInfo(); err == nil && len(bb) > 0 {
+		hw.BaseboardManufacturer = bb[0].Manufacturer
+		hw.BaseboardProduct = bb[0].Product
+	}
+	return hw, nil
+}
+
+// ParseMatch returns the match block of a boot entry config, or nil if there
+// is none.
+func ParseMatch(config []byte) (*Match, error) {
+	var c struct {
+		Match *Match `json:"match"`
+	}
+	if err := json.Unmarshal(config, &c); err != nil {
+		return nil, err
+	}
+	if c.Match == nil {
+		return nil, nil
+	}
+	for _, f := range c.Match.fields(&Hardware{}) {
+		if _, err := regexp.Compile(f.pattern); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.name, err)
+		}
+	}
+	return c.Match, nil
+}
+
+type matchField struct {
+	name, pattern, value string
+}
+
+// fields returns the non-empty fields of m with their values in hw.
+func (m *Match) fields(hw *Hardware) []matchField {
+	var fs []matchField
+	for _, f := range []matchField{
+		{"system_manufacturer", m.SystemManufacturer, hw.SystemManufacturer},
+		{"system_product", m.SystemProduct, hw.SystemProduct},
+		{"baseboard_manufacturer", m.BaseboardManufacturer, hw.BaseboardManufacturer},
+		{"baseboard_product", m.BaseboardProduct, hw.BaseboardProduct},
+	} {
+		if f.pattern != "" {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// Check returns nil if hw matches m, or an error that says which field did
+// not match.
+func (m *Match) Check(hw *Hardware) error {
+	for _, f := range m.fields(hw) {
+		re, err := regexp.Compile(f.pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.name, err)
+		}
+		if !re.MatchString(f.value) {
+			return fmt.Errorf("%s %q does not match %q", f.name, f.value, f.pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package systembooter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatch(t *testing.T) {
+	m, err := ParseMatch([]byte(`{"type": "netboot", "method": "dhcpv6", "mac": "aa:bb:cc:dd:ee:ff"}`))
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	m, err = ParseMatch([]byte(`{"type": "netboot", "match": {"system_product": "^Tioga", "baseboard_manufacturer": "Wiwynn"}}`))
+	require.NoError(t, err)
+	require.Equal(t, &Match{SystemProduct: "^Tioga", BaseboardManufacturer: "Wiwynn"}, m)
+
+	_, err = ParseMatch([]byte(`{"type": "netboot", "match": {"system_product": "("}}`))
+	require.Error(t, err)
+}
+
+func TestMatchCheck(t *testing.T) {
+	hw := &Hardware{
+		SystemManufacturer:    "Wiwynn",
+		SystemProduct:         "Tioga Pass Single Side",
+		BaseboardManufacturer: "Wiwynn",
+		BaseboardProduct:      "Tioga Pass",
+	}
+	for _, tt := range []struct {
+		name  string
+		match Match
+		ok    bool
+	}{
+		{name: "empty", ok: true},
+		{name: "system product", match: Match{SystemProduct: "^Tioga Pass"}, ok: true},
+		{name: "all fields", match: Match{SystemManufacturer: "Wiwynn", SystemProduct: "Single", BaseboardManufacturer: "^Wiwynn$", BaseboardProduct: "Tioga|Mono"}, ok: true},
+		{name: "baseboard mismatch", match: Match{SystemProduct: "Tioga", BaseboardProduct: "^Mono Lake"}},
+		{name: "invalid pattern", match: Match{SystemManufacturer: "["}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.match.Check(hw)
+			if tt.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -27,7 +27,10 @@ import (
 type CmdLine struct {
 	Raw   string
 	AsMap map[string]string
-	Err   error
+	// InitArgs are the arguments after "--", which the kernel passes to
+	// init instead of interpreting them.
+	InitArgs []string
+	Err      error
 }
 
 var (
@@ -65,21 +68,79 @@ func FullCmdLine() string {
 // parse returns the current command line, trimmed
 func parse(cmdlineReader io.Reader) CmdLine {
 	raw, err := ioutil.ReadAll(cmdlineReader)
-	line := CmdLine{}
 	if err != nil {
 		log.Printf("Can't read command line: %v", err)
-		line.Err = err
-		line.Raw = ""
-	} else {
-		line.Raw = strings.TrimRight(string(raw), "\n")
-		line.AsMap = parseToMap(line.Raw)
+		return CmdLine{Err: err}
+	}
+	return Parse(string(raw))
+}
+
+// Parse parses a kernel command line, e.g. one to pass to the next kernel.
+func Parse(s string) CmdLine {
+	line := CmdLine{
+		Raw: strings.TrimRight(s, "\n"),
+	}
+	line.AsMap = parseToMap(line.Raw)
+	if _, initArgs, ok := splitInitArgs(line.Raw); ok {
+		for _, arg := range fields(initArgs) {
+			line.InitArgs = append(line.InitArgs, strings.Trim(arg, "\"'"))
+		}
 	}
 	return line
 }
 
-//
-func doParse(input string, handler func(flag, key, canonicalKey, value, trimmedValue string)) {
+// Flag returns the value of a flag, and whether it was set. Flags without a
+// value have the value "1".
+func (c CmdLine) Flag(flag string) (string, bool) {
+	value, present := c.AsMap[strings.Replace(flag, "-", "_", -1)]
+	return value, present
+}
+
+// ContainsFlag returns whether a flag is set.
+func (c CmdLine) ContainsFlag(flag string) bool {
+	_, present := c.Flag(flag)
+	return present
+}
+
+// Get returns the value of key, or "" if it is not set.
+func (c CmdLine) Get(key string) string {
+	value, _ := c.Flag(key)
+	return value
+}
 
+// splitInitArgs splits input at the first unquoted "--" into kernel
+// parameters and init arguments. ok is false if there is no "--".
+func splitInitArgs(input string) (params, initArgs string, ok bool) {
+	var quote rune
+	start := -1
+	for i, c := range input {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case unicode.IsSpace(c):
+			if start != -1 && input[start:i] == "--" {
+				return strings.TrimSpace(input[:start]), strings.TrimSpace(input[i:]), true
+			}
+			start = -1
+		default:
+			if unicode.In(c, unicode.Quotation_Mark) {
+				quote = c
+			}
+			if start == -1 {
+				start = i
+			}
+		}
+	}
+	if start != -1 && input[start:] == "--" {
+		return strings.TrimSpace(input[:start]), "", true
+	}
+	return input, "", false
+}
+
+// fields splits input at spaces that are not quoted.
+func fields(input string) []string {
 	lastQuote := rune(0)
 	quotedFieldsCheck := func(c rune) bool {
 		switch {
@@ -95,8 +156,15 @@ func doParse(input string, handler func(flag, key, canonicalKey, value, trimmedV
 			return unicode.IsSpace(c)
 		}
 	}
+	return strings.FieldsFunc(input, quotedFieldsCheck)
+}
+
+//
+func doParse(input string, handler func(flag, key, canonicalKey, value, trimmedValue string)) {
+	// Init arguments are not kernel parameters.
+	input, _, _ = splitInitArgs(input)
 
-	for _, flag := range strings.FieldsFunc(string(input), quotedFieldsCheck) {
+	for _, flag := range fields(input) {
 		// kernel variables must allow '-' and '_' to be equivalent in variable
 		// names. We will replace dashes with underscores for processing.
 
@@ -147,9 +215,7 @@ func ContainsFlag(flag string) bool {
 // Flag returns the a flag, and whether it was set
 func Flag(flag string) (string, bool) {
 	once.Do(cmdLineOpener)
-	canonicalFlag := strings.Replace(flag, "-", "_", -1)
-	value, present := procCmdLine.AsMap[canonicalFlag]
-	return value, present
+	return procCmdLine.Flag(flag)
 }
 
 // getFlagMap gets specified flags as a map
@@ -5,6 +5,7 @@
 package cmdline
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -101,3 +102,46 @@ func TestCmdlineModules(t *testing.T) {
 		t.Errorf("my_module flags got: %v, want opt1=world opt_2=22-22 ", flags)
 	}
 }
+
+func TestParse(t *testing.T) {
+	c := Parse(`ro root=/dev/sda1 quiet uroot.uinitargs="-v -- x" log-level=3 -- single "a b" init=/bin/x` + "\n")
+	for _, tt := range []struct {
+		key     string
+		want    string
+		present bool
+	}{
+		{"root", "/dev/sda1", true},
+		{"quiet", "1", true},
+		{"uroot.uinitargs", "-v -- x", true},
+		{"log_level", "3", true},
+		{"log-level", "3", true},
+		// init is an init argument here, not a kernel parameter.
+		{"init", "", false},
+		{"single", "", false},
+	} {
+		if got, present := c.Flag(tt.key); got != tt.want || present != tt.present {
+			t.Errorf("Flag(%q) = %q, %v, want %q, %v", tt.key, got, present, tt.want, tt.present)
+		}
+		if got := c.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+		if got := c.ContainsFlag(tt.key); got != tt.present {
+			t.Errorf("ContainsFlag(%q) = %v, want %v", tt.key, got, tt.present)
+		}
+	}
+	if want := []string{"single", "a b", "init=/bin/x"}; !reflect.DeepEqual(c.InitArgs, want) {
+		t.Errorf("InitArgs = %q, want %q", c.InitArgs, want)
+	}
+
+	for in, want := range map[string][]string{
+		"ro":         nil,
+		"ro --":      nil,
+		"ro -- -- a": {"--", "a"},
+		`a="--" b`:   nil,
+		"--x -- 1":   {"1"},
+	} {
+		if got := Parse(in).InitArgs; !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%q).InitArgs = %q, want %q", in, got, want)
+		}
+	}
+}
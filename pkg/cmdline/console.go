@@ -0,0 +1,61 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Console is a console given by a console= parameter, e.g.
+// console=ttyS0,115200n8.
+type Console struct {
+	// Name is the device name, e.g. ttyS0.
+	Name string
+	// Options are the device options, e.g. 115200n8.
+	Options string
+}
+
+// Baud returns the baud rate in the options of a serial console, or 0 if
+// there is none.
+func (c Console) Baud() int {
+	i := strings.IndexFunc(c.Options, func(r rune) bool { return r < '0' || r > '9' })
+	if i == -1 {
+		i = len(c.Options)
+	}
+	baud, err := strconv.Atoi(c.Options[:i])
+	if err != nil {
+		return 0
+	}
+	return baud
+}
+
+// Consoles returns the consoles given by console= parameters, in the order
+// they are given. The last one is /dev/console.
+func Consoles() []Console {
+	once.Do(cmdLineOpener)
+	return consoles(procCmdLine.Raw)
+}
+
+func consoles(input string) []Console {
+	var cs []Console
+	seen := map[string]bool{}
+	doParse(input, func(flag, key, canonicalKey, value, trimmedValue string) {
+		if canonicalKey != "console" || trimmedValue == "" {
+			return
+		}
+		c := Console{Name: trimmedValue}
+		if i := strings.Index(trimmedValue, ","); i != -1 {
+			c.Name, c.Options = trimmedValue[:i], trimmedValue[i+1:]
+		}
+		c.Name = strings.TrimPrefix(c.Name, "/dev/")
+		if seen[c.Name] {
+			return
+		}
+		seen[c.Name] = true
+		cs = append(cs, c)
+	})
+	return cs
+}
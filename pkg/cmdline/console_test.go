@@ -0,0 +1,42 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsoles(t *testing.T) {
+	for _, tt := range []struct {
+		cmdline string
+		want    []Console
+	}{
+		{"ro root=/dev/sda1", nil},
+		{"console=tty1", []Console{{Name: "tty1"}}},
+		{
+			"console=tty1 quiet console=ttyS0,115200n8 console=ttyS0,9600",
+			[]Console{{Name: "tty1"}, {Name: "ttyS0", Options: "115200n8"}},
+		},
+		{"console=/dev/hvc0 console=", []Console{{Name: "hvc0"}}},
+	} {
+		if got := consoles(tt.cmdline); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("consoles(%q) = %+v, want %+v", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleBaud(t *testing.T) {
+	for opts, want := range map[string]int{
+		"":         0,
+		"115200n8": 115200,
+		"9600":     9600,
+		"n8":       0,
+	} {
+		if got := (Console{Name: "ttyS0", Options: opts}).Baud(); got != want {
+			t.Errorf("Baud of options %q = %d, want %d", opts, got, want)
+		}
+	}
+}
@@ -9,21 +9,24 @@ import (
 	"strings"
 )
 
-// RemoveFilter filters out variable for a given space-separated kernel commandline
+// RemoveFilter filters out variable for a given space-separated kernel commandline.
+// A variable ending in "*" filters out all variables with that prefix.
+// Init arguments after "--" are dropped.
 func removeFilter(input string, variables []string) string {
 	var newCl []string
 
 	// kernel variables must allow '-' and '_' to be equivalent in variable
 	// names. We will replace dashes with underscores for processing as
 	// `doParse` is doing.
+	canonical := make([]string, len(variables))
 	for i, v := range variables {
-		variables[i] = strings.Replace(v, "-", "_", -1)
+		canonical[i] = strings.Replace(v, "-", "_", -1)
 	}
 
 	doParse(input, func(flag, key, canonicalKey, value, trimmedValue string) {
 		skip := false
-		for _, v := range variables {
-			if canonicalKey == v {
+		for _, v := range canonical {
+			if canonicalKey == v || (strings.HasSuffix(v, "*") && strings.HasPrefix(canonicalKey, strings.TrimSuffix(v, "*"))) {
 				skip = true
 				break
 			}
@@ -36,6 +39,35 @@ func removeFilter(input string, variables []string) string {
 	return strings.Join(newCl, " ")
 }
 
+// joinInitArgs is the inverse of splitInitArgs.
+func joinInitArgs(params, initArgs string, ok bool) string {
+	if !ok {
+		return params
+	}
+	return strings.TrimSpace(params + " -- " + initArgs)
+}
+
+// RemoveParams returns cmdline without the parameters named keys. A key
+// ending in "*" removes all parameters with that prefix, e.g.
+// "systemboot.*". As in the kernel, dashes and underscores in keys are
+// equivalent. The other parameters keep their order and quoting, and init
+// arguments after "--" are kept as is.
+//
+// It is meant to sanitize the command line passed to the next kernel, e.g.
+// with boot.OSImage.Edit.
+func RemoveParams(cmdline string, keys ...string) string {
+	params, initArgs, ok := splitInitArgs(cmdline)
+	return joinInitArgs(removeFilter(params, keys), initArgs, ok)
+}
+
+// AppendParams returns cmdline with params appended to the kernel
+// parameters, before any init arguments.
+func AppendParams(cmdline string, params ...string) string {
+	kernel, initArgs, ok := splitInitArgs(cmdline)
+	kernel = strings.TrimSpace(strings.Join(append([]string{kernel}, params...), " "))
+	return joinInitArgs(kernel, initArgs, ok)
+}
+
 // Filter represents and kernel commandline filter
 type Filter interface {
 	// Update filters a given space-separated kernel commandline
@@ -72,5 +104,7 @@ func (u *updater) Update(cmdline string) string {
 		}
 	}
 
-	return removeFilter(cmdline, u.removeVar) + acl
+	// Parameters are added before init arguments, which are kept as is.
+	params, initArgs, ok := splitInitArgs(cmdline)
+	return joinInitArgs(removeFilter(params, u.removeVar)+acl, initArgs, ok)
 }
@@ -51,4 +51,68 @@ func TestUpdateFilter(t *testing.T) {
 	if got != want {
 		t.Errorf("Update(%v) = %v, want %v", cl, got, want)
 	}
+
+	// Parameters go before init arguments, which are not filtered.
+	cl = `keep=5 console=ttyS1 -- console=x single`
+	want = `keep=5 append=me console=ttyS0,115200 -- console=x single`
+	if got := filter.Update(cl); got != want {
+		t.Errorf("Update(%v) = %v, want %v", cl, got, want)
+	}
+}
+
+func TestRemoveAppendParams(t *testing.T) {
+	for _, tt := range []struct {
+		cmdline string
+		remove  []string
+		append  []string
+		want    string
+	}{
+		{
+			cmdline: `console=ttyS0,115200 ro quiet`,
+			remove:  []string{"console"},
+			want:    `ro quiet`,
+		},
+		{
+			cmdline: `systemboot.debug=1 ro systemboot.mode=x root=/dev/sda1 systemboot=1`,
+			remove:  []string{"systemboot.*"},
+			append:  []string{"console=tty0"},
+			want:    `ro root=/dev/sda1 systemboot=1 console=tty0`,
+		},
+		{
+			// Quoting and order of other parameters is kept.
+			cmdline: `a=1 uroot.uinitargs="-v  -x" log-level=3 b='c d'`,
+			remove:  []string{"log_level"},
+			append:  []string{`e="f g"`},
+			want:    `a=1 uroot.uinitargs="-v  -x" b='c d' e="f g"`,
+		},
+		{
+			// Parameters are appended before init arguments, which
+			// are not filtered.
+			cmdline: `console=tty0 ro -- console=x single`,
+			remove:  []string{"console"},
+			append:  []string{"console=ttyS1", "panic=5"},
+			want:    `ro console=ttyS1 panic=5 -- console=x single`,
+		},
+		{
+			cmdline: `console=tty0 --`,
+			remove:  []string{"console"},
+			append:  []string{"quiet"},
+			want:    `quiet --`,
+		},
+		{
+			cmdline: ``,
+			append:  []string{"quiet"},
+			want:    `quiet`,
+		},
+		{
+			cmdline: `ro`,
+			remove:  []string{"not-present", "*"},
+			want:    ``,
+		},
+	} {
+		got := AppendParams(RemoveParams(tt.cmdline, tt.remove...), tt.append...)
+		if got != tt.want {
+			t.Errorf("remove %q and append %q to %q = %q, want %q", tt.remove, tt.append, tt.cmdline, got, tt.want)
+		}
+	}
 }
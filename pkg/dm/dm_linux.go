@@ -0,0 +1,221 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dm creates and removes device-mapper devices.
+package dm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioctls from linux/dm-ioctl.h.
+const (
+	dmDevCreate  = 0xc138fd03 // _IOWR(0xfd, 3, struct dm_ioctl)
+	dmDevRemove  = 0xc138fd04
+	dmDevSuspend = 0xc138fd06
+	dmTableLoad  = 0xc138fd09
+
+	// ioctlSize is sizeof(struct dm_ioctl).
+	ioctlSize = 312
+	// specSize is sizeof(struct dm_target_spec).
+	specSize = 40
+)
+
+// Flags of struct dm_ioctl.
+const (
+	ReadOnly   = 1 << 0
+	secureData = 1 << 15
+)
+
+var (
+	// Control is the device-mapper control device.
+	Control = "/dev/mapper/control"
+	// Dir is where device nodes are created.
+	Dir = "/dev/mapper"
+)
+
+// header is struct dm_ioctl.
+type header struct {
+	Version     [3]uint32
+	DataSize    uint32
+	DataStart   uint32
+	TargetCount uint32
+	OpenCount   int32
+	Flags       uint32
+	EventNr     uint32
+	_           uint32
+	Dev         uint64
+	Name        [128]byte
+	UUID        [129]byte
+	_           [7]byte
+}
+
+// targetSpec is struct dm_target_spec.
+type targetSpec struct {
+	SectorStart uint64
+	Length      uint64
+	Status      int32
+	Next        uint32
+	TargetType  [16]byte
+}
+
+// Target maps a range of sectors of a device-mapper device.
+type Target struct {
+	Start  uint64
+	Length uint64
+	// Type is the target type, e.g. "crypt" or "linear".
+	Type string
+	// Params are the target type specific parameters.
+	Params string
+}
+
+func control() (*os.File, error) {
+	f, err := os.OpenFile(Control, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		// Without udev, the control node may not exist yet.
+		if err := os.MkdirAll(filepath.Dir(Control), 0755); err != nil {
+			return nil, err
+		}
+		if err := unix.Mknod(Control, unix.S_IFCHR|0600, int(unix.Mkdev(10, 236))); err != nil {
+			return nil, fmt.Errorf("creating %s: %v", Control, err)
+		}
+		f, err = os.OpenFile(Control, os.O_RDWR, 0)
+	}
+	return f, err
+}
+
+func newHeader(name string, flags uint32) (*header, error) {
+	h := &header{Version: [3]uint32{4, 0, 0}, Flags: flags}
+	if len(name) >= len(h.Name) || name == "" {
+		return nil, fmt.Errorf("invalid device-mapper name %q", name)
+	}
+	copy(h.Name[:], name)
+	return h, nil
+}
+
+// encodeTargets encodes targets as the data of a table load.
+func encodeTargets(targets []Target) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, t := range targets {
+		if len(t.Type) >= 16 {
+			return nil, fmt.Errorf("invalid target type %q", t.Type)
+		}
+		// The params are NUL terminated and padded to 8 bytes.
+		params := make([]byte, (len(t.Params)+1+7)&^7)
+		copy(params, t.Params)
+		spec := targetSpec{
+			SectorStart: t.Start,
+			Length:      t.Length,
+			Next:        uint32(specSize + len(params)),
+		}
+		copy(spec.TargetType[:], t.Type)
+		binary.Write(&buf, binary.LittleEndian, spec)
+		buf.Write(params)
+	}
+	return buf.Bytes(), nil
+}
+
+// ioctl runs the device-mapper ioctl req with h and data.
+func ioctl(f *os.File, req uintptr, h *header, data []byte) error {
+	h.DataStart = ioctlSize
+	h.DataSize = uint32(ioctlSize + len(data))
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, h)
+	buf.Write(data)
+	b := buf.Bytes()
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(&b[0])))
+	for i := ioctlSize; i < len(b); i++ {
+		b[i] = 0
+	}
+	if errno != 0 {
+		return errno
+	}
+	// Copy back what the kernel returned, e.g. the device number.
+	return binary.Read(bytes.NewReader(b), binary.LittleEndian, h)
+}
+
+// Create creates the device-mapper device name with targets and activates
+// it. It returns the path of the device node, which is created in Dir.
+// flags may be ReadOnly.
+func Create(name string, flags uint32, targets []Target) (string, error) {
+	f, err := control()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h, err := newHeader(name, flags)
+	if err != nil {
+		return "", err
+	}
+	if err := ioctl(f, dmDevCreate, h, nil); err != nil {
+		return "", fmt.Errorf("creating %s: %v", name, err)
+	}
+	dev := h.Dev
+
+	data, err := encodeTargets(targets)
+	if err == nil {
+		h, _ = newHeader(name, flags|secureData)
+		h.TargetCount = uint32(len(targets))
+		if err = ioctl(f, dmTableLoad, h, data); err != nil {
+			err = fmt.Errorf("loading table of %s: %v", name, err)
+		}
+	}
+	if err == nil {
+		// Resuming a device without an active table activates the
+		// loaded one.
+		h, _ = newHeader(name, flags)
+		if err = ioctl(f, dmDevSuspend, h, nil); err != nil {
+			err = fmt.Errorf("resuming %s: %v", name, err)
+		}
+	}
+	// Wipe the table, which may contain keys.
+	for i := range data {
+		data[i] = 0
+	}
+	if err != nil {
+		h, _ = newHeader(name, 0)
+		ioctl(f, dmDevRemove, h, nil)
+		return "", err
+	}
+
+	node := filepath.Join(Dir, name)
+	if _, err := os.Stat(node); err == nil {
+		// udev created it.
+		return node, nil
+	}
+	major, minor := unix.Major(dev), unix.Minor(dev)
+	if err := unix.Mknod(node, unix.S_IFBLK|0600, int(unix.Mkdev(major, minor))); err != nil {
+		return "", fmt.Errorf("creating %s: %v", node, err)
+	}
+	return node, nil
+}
+
+// Remove removes the device-mapper device name and its node in Dir.
+func Remove(name string) error {
+	f, err := control()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h, err := newHeader(name, 0)
+	if err != nil {
+		return err
+	}
+	if err := ioctl(f, dmDevRemove, h, nil); err != nil {
+		return fmt.Errorf("removing %s: %v", name, err)
+	}
+	if err := os.Remove(filepath.Join(Dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unsafe"
+)
+
+func TestSizes(t *testing.T) {
+	if s := binary.Size(header{}); s != ioctlSize {
+		t.Errorf("sizeof(struct dm_ioctl) = %d, want %d", s, ioctlSize)
+	}
+	if s := int(unsafe.Sizeof(targetSpec{})); s != specSize {
+		t.Errorf("sizeof(struct dm_target_spec) = %d, want %d", s, specSize)
+	}
+}
+
+func TestEncodeTargets(t *testing.T) {
+	b, err := encodeTargets([]Target{
+		{Start: 0, Length: 8, Type: "linear", Params: "/dev/sda 0"},
+		{Start: 8, Length: 16, Type: "zero"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "/dev/sda 0" and its NUL are padded to 16 bytes, "" to 8.
+	if len(b) != specSize+16+specSize+8 {
+		t.Fatalf("encodeTargets is %d bytes, want %d", len(b), specSize+16+specSize+8)
+	}
+	var spec targetSpec
+	binary.Read(bytes.NewReader(b), binary.LittleEndian, &spec)
+	if spec.Length != 8 || spec.Next != specSize+16 || string(bytes.TrimRight(spec.TargetType[:], "\x00")) != "linear" {
+		t.Errorf("first target = %+v", spec)
+	}
+	if p := string(b[specSize : specSize+11]); p != "/dev/sda 0\x00" {
+		t.Errorf("first params = %q", p)
+	}
+	binary.Read(bytes.NewReader(b[spec.Next:]), binary.LittleEndian, &spec)
+	if spec.SectorStart != 8 || spec.Length != 16 || spec.Next != specSize+8 {
+		t.Errorf("second target = %+v", spec)
+	}
+
+	if _, err := encodeTargets([]Target{{Type: "a-very-long-target-type"}}); err == nil {
+		t.Errorf("encodeTargets with long type succeeded, want error")
+	}
+}
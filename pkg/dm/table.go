@@ -0,0 +1,73 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// String formats t as a line of a dmsetup table.
+func (t Target) String() string {
+	s := fmt.Sprintf("%d %d %s", t.Start, t.Length, t.Type)
+	if t.Params != "" {
+		s += " " + t.Params
+	}
+	return s
+}
+
+// ParseTable parses a dmsetup table, which has one target per line:
+//
+//     START LENGTH TYPE [PARAMS...]
+//
+// START and LENGTH are in 512 byte sectors. Empty lines and lines starting
+// with # are ignored. The targets have to be contiguous, starting at 0.
+func ParseTable(table string) ([]Target, error) {
+	var targets []Target
+	var next uint64
+	for i, line := range strings.Split(table, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			return nil, fmt.Errorf("line %d: want START LENGTH TYPE [PARAMS...], got %q", i+1, line)
+		}
+		start, err := strconv.ParseUint(f[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start %q", i+1, f[0])
+		}
+		length, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil || length == 0 {
+			return nil, fmt.Errorf("line %d: invalid length %q", i+1, f[1])
+		}
+		if start != next {
+			return nil, fmt.Errorf("line %d: target starts at %d, want %d", i+1, start, next)
+		}
+		next = start + length
+		targets = append(targets, Target{
+			Start:  start,
+			Length: length,
+			Type:   f[2],
+			Params: strings.Join(f[3:], " "),
+		})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("table has no targets")
+	}
+	return targets, nil
+}
+
+// Linear returns a linear target of length sectors that maps to device,
+// starting at sector offset.
+func Linear(device string, offset, length uint64) Target {
+	return Target{
+		Length: length,
+		Type:   "linear",
+		Params: fmt.Sprintf("%s %d", device, offset),
+	}
+}
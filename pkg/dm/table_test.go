@@ -0,0 +1,48 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	got, err := ParseTable("# two disks\n0 2048 linear /dev/sda 0\n\n2048 4096  linear  /dev/sdb 2048\n6144 1 zero\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{
+		{Start: 0, Length: 2048, Type: "linear", Params: "/dev/sda 0"},
+		{Start: 2048, Length: 4096, Type: "linear", Params: "/dev/sdb 2048"},
+	}
+	if !reflect.DeepEqual(got[:2], want) || len(got) != 3 || got[2].String() != "6144 1 zero" {
+		t.Errorf("ParseTable = %+v, want %+v and a zero target", got, want)
+	}
+	if s := got[0].String(); s != "0 2048 linear /dev/sda 0" {
+		t.Errorf("String = %q", s)
+	}
+
+	for _, table := range []string{
+		"",
+		"# nothing",
+		"0 2048",
+		"x 2048 linear /dev/sda 0",
+		"0 0 zero",
+		"0 -1 zero",
+		"0 2048 zero\n4096 2048 zero",
+		"1 2048 zero",
+	} {
+		if _, err := ParseTable(table); err == nil {
+			t.Errorf("ParseTable(%q) succeeded, want error", table)
+		}
+	}
+}
+
+func TestLinear(t *testing.T) {
+	if s := Linear("/dev/sda", 2048, 1024).String(); s != "0 1024 linear /dev/sda 2048" {
+		t.Errorf("Linear = %q", s)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dm
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// veritySuperblockSize is the size of the superblock that veritysetup
+// format writes at the start of the hash area.
+const veritySuperblockSize = 512
+
+var verityMagic = [8]byte{'v', 'e', 'r', 'i', 't', 'y'}
+
+// veritySuperblock is struct verity_sb of cryptsetup.
+type veritySuperblock struct {
+	Signature     [8]byte
+	Version       uint32
+	HashType      uint32
+	UUID          [16]byte
+	Algorithm     [32]byte
+	DataBlockSize uint32
+	HashBlockSize uint32
+	DataBlocks    uint64
+	SaltSize      uint16
+	_             [6]byte
+	Salt          [256]byte
+	_             [168]byte
+}
+
+// verityAlgorithms are the hash algorithms dm-verity supports, by their
+// name in the kernel.
+var verityAlgorithms = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+// VerityParams are the parameters of a dm-verity target.
+type VerityParams struct {
+	// HashType is 0 for the original Chrome OS format, 1 otherwise.
+	HashType      uint32
+	DataDevice    string
+	HashDevice    string
+	DataBlockSize uint32
+	HashBlockSize uint32
+	DataBlocks    uint64
+	// HashStartBlock is the block of HashDevice, in HashBlockSize
+	// units, where the hash tree starts.
+	HashStartBlock uint64
+	Algorithm      string
+	RootDigest     []byte
+	Salt           []byte
+	// Options are optional parameters, e.g. "restart_on_corruption".
+	Options []string
+}
+
+func validBlockSize(n uint32) bool {
+	return n >= 512 && n <= 1<<20 && n&(n-1) == 0
+}
+
+// ReadVeritySuperblock reads the superblock at offset hashOffset of the hash
+// device r, and returns the parameters it describes. The devices and the
+// root digest are left for the caller to fill in.
+func ReadVeritySuperblock(r io.ReaderAt, hashOffset uint64) (*VerityParams, error) {
+	b := make([]byte, veritySuperblockSize)
+	if _, err := r.ReadAt(b, int64(hashOffset)); err != nil {
+		return nil, fmt.Errorf("reading verity superblock: %v", err)
+	}
+	var sb veritySuperblock
+	if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &sb); err != nil {
+		return nil, err
+	}
+	if sb.Signature != verityMagic {
+		return nil, errors.New("no verity superblock found")
+	}
+	if sb.Version != 1 {
+		return nil, fmt.Errorf("verity superblock version %d is not supported", sb.Version)
+	}
+	if sb.HashType > 1 {
+		return nil, fmt.Errorf("unsupported verity hash type %d", sb.HashType)
+	}
+	if !validBlockSize(sb.DataBlockSize) || !validBlockSize(sb.HashBlockSize) {
+		return nil, fmt.Errorf("invalid verity block sizes %d and %d", sb.DataBlockSize, sb.HashBlockSize)
+	}
+	if int(sb.SaltSize) > len(sb.Salt) {
+		return nil, fmt.Errorf("invalid verity salt size %d", sb.SaltSize)
+	}
+	alg := strings.ToLower(string(bytes.TrimRight(sb.Algorithm[:], "\x00")))
+	if _, ok := verityAlgorithms[alg]; !ok {
+		return nil, fmt.Errorf("unsupported verity hash algorithm %q", alg)
+	}
+	// The hash tree starts at the first hash block after the superblock.
+	start := (hashOffset + veritySuperblockSize + uint64(sb.HashBlockSize) - 1) / uint64(sb.HashBlockSize)
+	return &VerityParams{
+		HashType:       sb.HashType,
+		DataBlockSize:  sb.DataBlockSize,
+		HashBlockSize:  sb.HashBlockSize,
+		DataBlocks:     sb.DataBlocks,
+		HashStartBlock: start,
+		Algorithm:      alg,
+		Salt:           append([]byte(nil), sb.Salt[:sb.SaltSize]...),
+	}, nil
+}
+
+// ParseRootDigest parses a root hash of algorithm alg, given in hex.
+func ParseRootDigest(s, alg string) ([]byte, error) {
+	h, ok := verityAlgorithms[alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported verity hash algorithm %q", alg)
+	}
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("root hash %q is not hex", s)
+	}
+	if len(d) != h.Size() {
+		return nil, fmt.Errorf("root hash is %d bytes, but a %s hash is %d bytes", len(d), alg, h.Size())
+	}
+	return d, nil
+}
+
+// Target returns the dm-verity target for p.
+func (p *VerityParams) Target() (Target, error) {
+	h, ok := verityAlgorithms[p.Algorithm]
+	if !ok {
+		return Target{}, fmt.Errorf("unsupported verity hash algorithm %q", p.Algorithm)
+	}
+	if len(p.RootDigest) != h.Size() {
+		return Target{}, fmt.Errorf("root hash is %d bytes, but a %s hash is %d bytes", len(p.RootDigest), p.Algorithm, h.Size())
+	}
+	if !validBlockSize(p.DataBlockSize) || !validBlockSize(p.HashBlockSize) {
+		return Target{}, fmt.Errorf("invalid verity block sizes %d and %d", p.DataBlockSize, p.HashBlockSize)
+	}
+	if p.DataBlocks == 0 {
+		return Target{}, errors.New("verity data device has no blocks")
+	}
+	salt := "-"
+	if len(p.Salt) > 0 {
+		salt = hex.EncodeToString(p.Salt)
+	}
+	params := fmt.Sprintf("%d %s %s %d %d %d %d %s %x %s",
+		p.HashType, p.DataDevice, p.HashDevice, p.DataBlockSize, p.HashBlockSize,
+		p.DataBlocks, p.HashStartBlock, p.Algorithm, p.RootDigest, salt)
+	if len(p.Options) > 0 {
+		params += fmt.Sprintf(" %d %s", len(p.Options), strings.Join(p.Options, " "))
+	}
+	return Target{
+		Length: p.DataBlocks * uint64(p.DataBlockSize) / 512,
+		Type:   "verity",
+		Params: params,
+	}, nil
+}
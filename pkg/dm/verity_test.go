@@ -0,0 +1,136 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// superblock returns a hash device with a superblock at off, like
+// veritysetup format creates it.
+func superblock(t *testing.T, off int, change func(*veritySuperblock)) []byte {
+	sb := veritySuperblock{
+		Signature:     verityMagic,
+		Version:       1,
+		HashType:      1,
+		DataBlockSize: 4096,
+		HashBlockSize: 4096,
+		DataBlocks:    256,
+		SaltSize:      4,
+	}
+	copy(sb.Algorithm[:], "sha256")
+	copy(sb.Salt[:], []byte{0xca, 0xfe, 0xba, 0xbe})
+	if change != nil {
+		change(&sb)
+	}
+	var b bytes.Buffer
+	b.Write(make([]byte, off))
+	if err := binary.Write(&b, binary.LittleEndian, sb); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSuperblockSize(t *testing.T) {
+	if s := binary.Size(veritySuperblock{}); s != veritySuperblockSize {
+		t.Errorf("sizeof(struct verity_sb) = %d, want %d", s, veritySuperblockSize)
+	}
+}
+
+func TestReadVeritySuperblock(t *testing.T) {
+	p, err := ReadVeritySuperblock(bytes.NewReader(superblock(t, 0, nil)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HashType != 1 || p.DataBlocks != 256 || p.DataBlockSize != 4096 || p.Algorithm != "sha256" ||
+		p.HashStartBlock != 1 || !bytes.Equal(p.Salt, []byte{0xca, 0xfe, 0xba, 0xbe}) {
+		t.Errorf("ReadVeritySuperblock = %+v", p)
+	}
+
+	// With an offset, the hash tree starts at the next hash block.
+	p, err = ReadVeritySuperblock(bytes.NewReader(superblock(t, 1<<20, nil)), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.HashStartBlock != 257 {
+		t.Errorf("HashStartBlock = %d, want 257", p.HashStartBlock)
+	}
+
+	for _, change := range []func(*veritySuperblock){
+		func(sb *veritySuperblock) { sb.Signature[0] = 'x' },
+		func(sb *veritySuperblock) { sb.Version = 2 },
+		func(sb *veritySuperblock) { sb.HashType = 2 },
+		func(sb *veritySuperblock) { sb.DataBlockSize = 1000 },
+		func(sb *veritySuperblock) { sb.SaltSize = 257 },
+		func(sb *veritySuperblock) { copy(sb.Algorithm[:], "md5\x00\x00\x00") },
+	} {
+		if _, err := ReadVeritySuperblock(bytes.NewReader(superblock(t, 0, change)), 0); err == nil {
+			t.Errorf("ReadVeritySuperblock of an invalid superblock succeeded, want error")
+		}
+	}
+	if _, err := ReadVeritySuperblock(bytes.NewReader(nil), 0); err == nil {
+		t.Errorf("ReadVeritySuperblock of an empty device succeeded, want error")
+	}
+}
+
+func TestParseRootDigest(t *testing.T) {
+	sha256 := strings.Repeat("ab", 32)
+	for _, tt := range []struct {
+		s, alg string
+		err    bool
+	}{
+		{s: sha256, alg: "sha256"},
+		{s: strings.Repeat("ab", 20), alg: "sha1"},
+		{s: sha256, alg: "sha1", err: true},
+		{s: sha256[:63], alg: "sha256", err: true},
+		{s: strings.Repeat("zz", 32), alg: "sha256", err: true},
+		{s: sha256, alg: "md5", err: true},
+	} {
+		if _, err := ParseRootDigest(tt.s, tt.alg); (err != nil) != tt.err {
+			t.Errorf("ParseRootDigest(%q, %q) = %v, want error %v", tt.s, tt.alg, err, tt.err)
+		}
+	}
+}
+
+func TestVerityTarget(t *testing.T) {
+	root, _ := ParseRootDigest(strings.Repeat("01", 32), "sha256")
+	p := &VerityParams{
+		HashType:       1,
+		DataDevice:     "/dev/sda1",
+		HashDevice:     "/dev/sda2",
+		DataBlockSize:  4096,
+		HashBlockSize:  4096,
+		DataBlocks:     256,
+		HashStartBlock: 1,
+		Algorithm:      "sha256",
+		RootDigest:     root,
+	}
+	got, err := p.Target()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0 2048 verity 1 /dev/sda1 /dev/sda2 4096 4096 256 1 sha256 " + strings.Repeat("01", 32) + " -"
+	if got.String() != want {
+		t.Errorf("Target = %q, want %q", got, want)
+	}
+
+	p.Salt = []byte{0xca, 0xfe}
+	p.Options = []string{"restart_on_corruption"}
+	got, err = p.Target()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := " cafe 1 restart_on_corruption"; !strings.HasSuffix(got.Params, want) {
+		t.Errorf("Target params = %q, want suffix %q", got.Params, want)
+	}
+
+	p.RootDigest = root[:20]
+	if _, err := p.Target(); err == nil {
+		t.Errorf("Target with a short root hash succeeded, want error")
+	}
+}
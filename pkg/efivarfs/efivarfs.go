@@ -0,0 +1,69 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package efivarfs reads and writes EFI variables through efivarfs, which
+// is usually mounted on /sys/firmware/efi/efivars.
+//
+// Each variable is a file named NAME-GUID. Its content is the 4-byte
+// attributes of the variable, followed by the data. To protect the
+// firmware, the kernel makes most existing variables immutable; Set and
+// Delete clear the immutable flag, which needs CAP_LINUX_IMMUTABLE.
+package efivarfs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Attributes are the attributes of an EFI variable.
+type Attributes uint32
+
+// Attributes from the UEFI spec.
+const (
+	AttributeNonVolatile                       Attributes = 0x1
+	AttributeBootserviceAccess                 Attributes = 0x2
+	AttributeRuntimeAccess                     Attributes = 0x4
+	AttributeHardwareErrorRecord               Attributes = 0x8
+	AttributeAuthenticatedWriteAccess          Attributes = 0x10
+	AttributeTimeBasedAuthenticatedWriteAccess Attributes = 0x20
+	AttributeAppendWrite                       Attributes = 0x40
+)
+
+// GlobalVariable is the GUID of the variables defined by the UEFI spec,
+// e.g. BootOrder, BootNext and Boot0001.
+const GlobalVariable = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// Var identifies an EFI variable.
+type Var struct {
+	Name string
+	// GUID is the vendor GUID, in lower case, e.g. GlobalVariable.
+	GUID string
+}
+
+// String returns the file name of v in efivarfs.
+func (v Var) String() string {
+	return v.Name + "-" + v.GUID
+}
+
+var guidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// guidLen is the length of a GUID string.
+const guidLen = 36
+
+// ParseVar parses the efivarfs file name of a variable, NAME-GUID.
+func ParseVar(s string) (Var, error) {
+	// Names may contain dashes, GUIDs have a fixed length.
+	if len(s) < guidLen+2 || s[len(s)-guidLen-1] != '-' {
+		return Var{}, fmt.Errorf("%q is not NAME-GUID", s)
+	}
+	v := Var{
+		Name: s[:len(s)-guidLen-1],
+		GUID: strings.ToLower(s[len(s)-guidLen:]),
+	}
+	if !guidRE.MatchString(v.GUID) {
+		return Var{}, fmt.Errorf("%q is not NAME-GUID: invalid GUID %q", s, v.GUID)
+	}
+	return v, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivarfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Dir is where efivarfs is mounted. It is a variable so it can be changed
+// in tests.
+var Dir = "/sys/firmware/efi/efivars"
+
+// fsImmutableFlag is FS_IMMUTABLE_FL from linux/fs.h.
+const fsImmutableFlag = 0x10
+
+func path(v Var) string {
+	return filepath.Join(Dir, v.String())
+}
+
+// Get returns the attributes and data of v. If v does not exist, the error
+// satisfies os.IsNotExist.
+func Get(v Var) (Attributes, []byte, error) {
+	b, err := ioutil.ReadFile(path(v))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) < 4 {
+		return 0, nil, fmt.Errorf("%s: %d bytes is too short for an EFI variable", v, len(b))
+	}
+	return Attributes(binary.LittleEndian.Uint32(b)), b[4:], nil
+}
+
+// List returns the variables in efivarfs.
+func List() ([]Var, error) {
+	fis, err := ioutil.ReadDir(Dir)
+	if err != nil {
+		return nil, err
+	}
+	var vars []Var
+	for _, fi := range fis {
+		if v, err := ParseVar(fi.Name()); err == nil && fi.Mode().IsRegular() {
+			vars = append(vars, v)
+		}
+	}
+	return vars, nil
+}
+
+// setImmutable sets or clears the immutable flag of p, and returns whether
+// it was set.
+func setImmutable(p string, immutable bool) (bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	flags, err := unix.IoctlGetUint32(int(f.Fd()), unix.FS_IOC_GETFLAGS)
+	if err == unix.ENOTTY || err == unix.EOPNOTSUPP {
+		// The file system has no flags, so nothing is immutable.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("getting flags of %s: %v", p, err)
+	}
+	was := flags&fsImmutableFlag != 0
+	if was == immutable {
+		return was, nil
+	}
+	flags ^= fsImmutableFlag
+	if err := unix.IoctlSetPointerInt(int(f.Fd()), unix.FS_IOC_SETFLAGS, int(flags)); err != nil {
+		if err == unix.EPERM {
+			return was, fmt.Errorf("changing the immutable flag of %s needs CAP_LINUX_IMMUTABLE: %v", p, err)
+		}
+		return was, fmt.Errorf("changing the immutable flag of %s: %v", p, err)
+	}
+	return was, nil
+}
+
+// makeMutable clears the immutable flag of p if p exists. It returns
+// whether the flag was set.
+func makeMutable(p string) (bool, error) {
+	was, err := setImmutable(p, false)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return was, err
+}
+
+// Set creates v or replaces its data. If attrs contains AttributeAppendWrite,
+// data is appended to v instead. An existing immutable v is made mutable for
+// the write, and immutable again afterwards.
+func Set(v Var, attrs Attributes, data []byte) error {
+	// The firmware deletes a variable when it is set to nothing.
+	if len(data) == 0 {
+		return fmt.Errorf("%s: no data, use Delete to delete a variable", v)
+	}
+	p := path(v)
+	immutable, err := makeMutable(p)
+	if err != nil {
+		return err
+	}
+	// efivarfs takes the attributes and data in one write, which sets
+	// the whole variable, or appends to it. efivarfs ignores O_TRUNC, but
+	// a copy of the variables in a plain directory needs it.
+	b := make([]byte, 4+len(data))
+	binary.LittleEndian.PutUint32(b, uint32(attrs))
+	copy(b[4:], data)
+	flags := os.O_WRONLY | os.O_CREATE
+	if attrs&AttributeAppendWrite == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(p, flags, 0644)
+	if err != nil {
+		return permError(v, err)
+	}
+	_, werr := f.Write(b)
+	if err := f.Close(); werr == nil {
+		werr = err
+	}
+	if werr != nil {
+		werr = permError(v, werr)
+	}
+	if immutable {
+		if _, err := setImmutable(p, true); werr == nil {
+			werr = err
+		}
+	}
+	return werr
+}
+
+// Append appends data to v, see Set.
+func Append(v Var, attrs Attributes, data []byte) error {
+	return Set(v, attrs|AttributeAppendWrite, data)
+}
+
+// Delete deletes v, even if it is immutable.
+func Delete(v Var) error {
+	p := path(v)
+	if _, err := makeMutable(p); err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return permError(v, err)
+	}
+	return nil
+}
+
+// permError explains EPERM, which efivarfs also returns for variables the
+// firmware refuses to change, e.g. authenticated ones.
+func permError(v Var, err error) error {
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		return fmt.Errorf("%s: %v; it is immutable, write protected by the firmware, or this needs root", v, err)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivarfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testDir(t *testing.T) func() {
+	d, err := ioutil.TempDir("", "efivarfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Dir
+	Dir = d
+	return func() {
+		Dir = old
+		os.RemoveAll(d)
+	}
+}
+
+func TestSetGetDelete(t *testing.T) {
+	defer testDir(t)()
+	// Not a variable.
+	if err := ioutil.WriteFile(filepath.Join(Dir, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bootNext := Var{Name: "BootNext", GUID: GlobalVariable}
+	attrs := AttributeNonVolatile | AttributeBootserviceAccess | AttributeRuntimeAccess
+	if _, _, err := Get(bootNext); !os.IsNotExist(err) {
+		t.Errorf("Get of a missing variable: got %v, want a not exist error", err)
+	}
+	if err := Set(bootNext, attrs, []byte{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(Dir, bootNext.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{7, 0, 0, 0, 1, 0}; !bytes.Equal(b, want) {
+		t.Errorf("efivarfs file is %v, want %v", b, want)
+	}
+	gotAttrs, data, err := Get(bootNext)
+	if err != nil || gotAttrs != attrs || !bytes.Equal(data, []byte{1, 0}) {
+		t.Errorf("Get = %#x, %v, %v, want %#x, [1 0], nil", gotAttrs, data, err, attrs)
+	}
+	if err := Set(bootNext, attrs, nil); err == nil {
+		t.Errorf("Set with no data succeeded")
+	}
+
+	vars, err := List()
+	if err != nil || !reflect.DeepEqual(vars, []Var{bootNext}) {
+		t.Errorf("List = %v, %v, want [%v]", vars, err, bootNext)
+	}
+
+	if err := Delete(bootNext); err != nil {
+		t.Fatal(err)
+	}
+	if vars, err := List(); err != nil || len(vars) != 0 {
+		t.Errorf("List after Delete = %v, %v, want none", vars, err)
+	}
+}
+
+func TestSetImmutable(t *testing.T) {
+	defer testDir(t)()
+	v := Var{Name: "Boot0001", GUID: GlobalVariable}
+	p := filepath.Join(Dir, v.String())
+	if err := ioutil.WriteFile(p, []byte{7, 0, 0, 0, 1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setImmutable(p, true); err != nil {
+		t.Skipf("Can not make files immutable: %v", err)
+	}
+	if immutable, _ := setImmutable(p, true); !immutable {
+		t.Skipf("%s has no immutable flag", Dir)
+	}
+	defer setImmutable(p, false)
+
+	if err := Set(v, AttributeNonVolatile, []byte{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := Get(v); err != nil || !bytes.Equal(data, []byte{2, 3}) {
+		t.Errorf("Get = %v, %v, want [2 3]", data, err)
+	}
+	if immutable, err := setImmutable(p, true); err != nil || !immutable {
+		t.Errorf("%s is not immutable after Set: %v", p, err)
+	}
+	if err := Delete(v); err != nil {
+		t.Fatal(err)
+	}
+}
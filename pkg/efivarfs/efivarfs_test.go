@@ -0,0 +1,30 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package efivarfs
+
+import "testing"
+
+func TestParseVar(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Var
+		err  bool
+	}{
+		{in: "BootOrder-" + GlobalVariable, want: Var{Name: "BootOrder", GUID: GlobalVariable}},
+		{in: "Key-Option-8BE4DF61-93CA-11D2-AA0D-00E098032B8C", want: Var{Name: "Key-Option", GUID: GlobalVariable}},
+		{in: "-" + GlobalVariable, err: true},
+		{in: "BootOrder", err: true},
+		{in: "BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8", err: true},
+		{in: "BootOrder-8be4df61x93ca-11d2-aa0d-00e098032b8c", err: true},
+	} {
+		got, err := ParseVar(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseVar(%q) = %+v, %v, want %+v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+		if err == nil && got.String() != tt.want.Name+"-"+tt.want.GUID {
+			t.Errorf("%+v.String() = %q", got, got.String())
+		}
+	}
+}
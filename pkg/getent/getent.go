@@ -0,0 +1,272 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package getent reads the passwd, group and hosts databases from their
+// files, /etc/passwd, /etc/group and /etc/hosts.
+//
+// Lines that are empty, comments or malformed are skipped, as the C
+// library does, so one bad line does not hide the rest of a file.
+package getent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The files of the databases.
+var (
+	PasswdFile = "/etc/passwd"
+	GroupFile  = "/etc/group"
+	HostsFile  = "/etc/hosts"
+)
+
+// Passwd is an entry of the passwd database.
+type Passwd struct {
+	Name   string
+	Passwd string
+	UID    int
+	GID    int
+	Gecos  string
+	Dir    string
+	Shell  string
+}
+
+// String returns the entry as a line of /etc/passwd, without a newline.
+func (p Passwd) String() string {
+	return fmt.Sprintf("%s:%s:%d:%d:%s:%s:%s", p.Name, p.Passwd, p.UID, p.GID, p.Gecos, p.Dir, p.Shell)
+}
+
+// Group is an entry of the group database.
+type Group struct {
+	Name    string
+	Passwd  string
+	GID     int
+	Members []string
+}
+
+// String returns the entry as a line of /etc/group, without a newline.
+func (g Group) String() string {
+	return fmt.Sprintf("%s:%s:%d:%s", g.Name, g.Passwd, g.GID, strings.Join(g.Members, ","))
+}
+
+// Host is an entry of the hosts database. The first name is the canonical
+// name, and the rest are aliases.
+type Host struct {
+	Addr  net.IP
+	Names []string
+}
+
+// String returns the entry the way getent prints it, without a newline.
+func (h Host) String() string {
+	return fmt.Sprintf("%-15s %s", h.Addr, strings.Join(h.Names, " "))
+}
+
+// lines calls f with each line of r that is not empty or a comment.
+func lines(r io.Reader, f func(string)) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSuffix(s.Text(), "\r")
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		f(l)
+	}
+	return s.Err()
+}
+
+// parseID parses a user or group ID.
+func parseID(s string) (int, bool) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return int(n), err == nil
+}
+
+// ParsePasswd parses passwd entries in the format of /etc/passwd.
+func ParsePasswd(r io.Reader) ([]Passwd, error) {
+	var ents []Passwd
+	err := lines(r, func(l string) {
+		f := strings.Split(l, ":")
+		if len(f) != 7 || f[0] == "" {
+			return
+		}
+		uid, ok := parseID(f[2])
+		if !ok {
+			return
+		}
+		gid, ok := parseID(f[3])
+		if !ok {
+			return
+		}
+		ents = append(ents, Passwd{Name: f[0], Passwd: f[1], UID: uid, GID: gid, Gecos: f[4], Dir: f[5], Shell: f[6]})
+	})
+	return ents, err
+}
+
+// ParseGroup parses group entries in the format of /etc/group.
+func ParseGroup(r io.Reader) ([]Group, error) {
+	var ents []Group
+	err := lines(r, func(l string) {
+		f := strings.Split(l, ":")
+		if len(f) != 4 || f[0] == "" {
+			return
+		}
+		gid, ok := parseID(f[2])
+		if !ok {
+			return
+		}
+		g := Group{Name: f[0], Passwd: f[1], GID: gid}
+		for _, m := range strings.Split(f[3], ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				g.Members = append(g.Members, m)
+			}
+		}
+		ents = append(ents, g)
+	})
+	return ents, err
+}
+
+// ParseHosts parses hosts entries in the format of /etc/hosts.
+func ParseHosts(r io.Reader) ([]Host, error) {
+	var ents []Host
+	err := lines(r, func(l string) {
+		if i := strings.IndexByte(l, '#'); i >= 0 {
+			l = l[:i]
+		}
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			return
+		}
+		addr := net.ParseIP(f[0])
+		if addr == nil {
+			return
+		}
+		ents = append(ents, Host{Addr: addr, Names: f[1:]})
+	})
+	return ents, err
+}
+
+// readFile parses a file with parse.
+func readFile(name string, parse func(io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// ReadPasswd returns the passwd entries of a file, typically PasswdFile.
+func ReadPasswd(name string) ([]Passwd, error) {
+	var ents []Passwd
+	err := readFile(name, func(r io.Reader) (err error) {
+		ents, err = ParsePasswd(r)
+		return err
+	})
+	return ents, err
+}
+
+// ReadGroup returns the group entries of a file, typically GroupFile.
+func ReadGroup(name string) ([]Group, error) {
+	var ents []Group
+	err := readFile(name, func(r io.Reader) (err error) {
+		ents, err = ParseGroup(r)
+		return err
+	})
+	return ents, err
+}
+
+// ReadHosts returns the hosts entries of a file, typically HostsFile.
+func ReadHosts(name string) ([]Host, error) {
+	var ents []Host
+	err := readFile(name, func(r io.Reader) (err error) {
+		ents, err = ParseHosts(r)
+		return err
+	})
+	return ents, err
+}
+
+// FindPasswd returns the first entry of ents with the UID key, if key is a
+// number, or else with the name key.
+func FindPasswd(ents []Passwd, key string) (Passwd, bool) {
+	uid, byID := parseID(key)
+	for _, p := range ents {
+		if (byID && p.UID == uid) || (!byID && p.Name == key) {
+			return p, true
+		}
+	}
+	return Passwd{}, false
+}
+
+// FindGroup returns the first entry of ents with the GID key, if key is a
+// number, or else with the name key.
+func FindGroup(ents []Group, key string) (Group, bool) {
+	gid, byID := parseID(key)
+	for _, g := range ents {
+		if (byID && g.GID == gid) || (!byID && g.Name == key) {
+			return g, true
+		}
+	}
+	return Group{}, false
+}
+
+// FindHost returns the first entry of ents with the address key, if key is
+// an IP address, or else with the name key, which is matched without regard
+// to case.
+func FindHost(ents []Host, key string) (Host, bool) {
+	addr := net.ParseIP(key)
+	for _, h := range ents {
+		if addr != nil {
+			if h.Addr.Equal(addr) {
+				return h, true
+			}
+			continue
+		}
+		for _, n := range h.Names {
+			if strings.EqualFold(n, key) {
+				return h, true
+			}
+		}
+	}
+	return Host{}, false
+}
+
+// MemberOf returns the entries of ents that list user as a member.
+func MemberOf(ents []Group, user string) []Group {
+	var gs []Group
+	for _, g := range ents {
+		for _, m := range g.Members {
+			if m == user {
+				gs = append(gs, g)
+				break
+			}
+		}
+	}
+	return gs
+}
+
+// UserName returns the name of the first entry of ents with the UID uid, or
+// uid as a number if there is none.
+func UserName(ents []Passwd, uid int) string {
+	for _, p := range ents {
+		if p.UID == uid {
+			return p.Name
+		}
+	}
+	return strconv.Itoa(uid)
+}
+
+// GroupName returns the name of the first entry of ents with the GID gid,
+// or gid as a number if there is none.
+func GroupName(ents []Group, gid int) string {
+	for _, g := range ents {
+		if g.GID == gid {
+			return g.Name
+		}
+	}
+	return strconv.Itoa(gid)
+}
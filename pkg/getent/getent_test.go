@@ -0,0 +1,148 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package getent
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePasswd(t *testing.T) {
+	ents, err := ParsePasswd(strings.NewReader(`# comment
+root:x:0:0:root:/root:/bin/sh
+
+bin:x:1:1::/bin:/sbin/nologin` + "\r" + `
+short:x:2:2
+baduid:x:-1:2:::
+:x:3:3:::
+user:x:1000:100:A User,,,:/home/user:/bin/sh
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Passwd{
+		{"root", "x", 0, 0, "root", "/root", "/bin/sh"},
+		{"bin", "x", 1, 1, "", "/bin", "/sbin/nologin"},
+		{"user", "x", 1000, 100, "A User,,,", "/home/user", "/bin/sh"},
+	}
+	if !reflect.DeepEqual(ents, want) {
+		t.Fatalf("ParsePasswd() = %+v, want %+v", ents, want)
+	}
+	if got := ents[2].String(); got != "user:x:1000:100:A User,,,:/home/user:/bin/sh" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := UserName(ents, 1000); got != "user" {
+		t.Errorf("UserName(1000) = %q, want user", got)
+	}
+	if got := UserName(ents, 4242); got != "4242" {
+		t.Errorf("UserName(4242) = %q, want 4242", got)
+	}
+	for _, tt := range []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"root", "root", true},
+		{"1000", "user", true},
+		{"1", "bin", true},
+		{"nobody", "", false},
+		{"42", "", false},
+	} {
+		p, ok := FindPasswd(ents, tt.key)
+		if p.Name != tt.want || ok != tt.ok {
+			t.Errorf("FindPasswd(%q) = %q, %v, want %q, %v", tt.key, p.Name, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseGroup(t *testing.T) {
+	ents, err := ParseGroup(strings.NewReader(`root:x:0:
+# comment
+wheel:x:10:root,user
+users:x:100:user,,other
+bad:x:nan:
+short:x:1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Group{
+		{"root", "x", 0, nil},
+		{"wheel", "x", 10, []string{"root", "user"}},
+		{"users", "x", 100, []string{"user", "other"}},
+	}
+	if !reflect.DeepEqual(ents, want) {
+		t.Fatalf("ParseGroup() = %+v, want %+v", ents, want)
+	}
+	if got := ents[1].String(); got != "wheel:x:10:root,user" {
+		t.Errorf("String() = %q", got)
+	}
+	if g, ok := FindGroup(ents, "100"); !ok || g.Name != "users" {
+		t.Errorf("FindGroup(100) = %q, %v, want users", g.Name, ok)
+	}
+	if g, ok := FindGroup(ents, "wheel"); !ok || g.GID != 10 {
+		t.Errorf("FindGroup(wheel) = %d, %v, want 10", g.GID, ok)
+	}
+	if _, ok := FindGroup(ents, "bad"); ok {
+		t.Errorf("FindGroup(bad) found a malformed entry")
+	}
+	var names []string
+	for _, g := range MemberOf(ents, "user") {
+		names = append(names, g.Name)
+	}
+	if want := []string{"wheel", "users"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("MemberOf(user) = %q, want %q", names, want)
+	}
+	if got := GroupName(ents, 10); got != "wheel" {
+		t.Errorf("GroupName(10) = %q, want wheel", got)
+	}
+	if got := GroupName(ents, 4242); got != "4242" {
+		t.Errorf("GroupName(4242) = %q, want 4242", got)
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	ents, err := ParseHosts(strings.NewReader(`127.0.0.1	localhost
+::1 localhost ip6-localhost # comment
+# 10.0.0.1 commented
+10.0.0.2
+notanaddr host
+192.168.1.1 Router.lan router	# comment
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Host{
+		{net.ParseIP("127.0.0.1"), []string{"localhost"}},
+		{net.ParseIP("::1"), []string{"localhost", "ip6-localhost"}},
+		{net.ParseIP("192.168.1.1"), []string{"Router.lan", "router"}},
+	}
+	if !reflect.DeepEqual(ents, want) {
+		t.Fatalf("ParseHosts() = %+v, want %+v", ents, want)
+	}
+	if got := ents[1].String(); got != "::1             localhost ip6-localhost" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, tt := range []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"localhost", "127.0.0.1", true},
+		{"ip6-localhost", "::1", true},
+		{"ROUTER.LAN", "192.168.1.1", true},
+		{"0:0::1", "::1", true},
+		{"192.168.1.1", "192.168.1.1", true},
+		{"10.0.0.2", "<nil>", false},
+		{"example.com", "<nil>", false},
+	} {
+		h, ok := FindHost(ents, tt.key)
+		if h.Addr.String() != tt.want || ok != tt.ok {
+			t.Errorf("FindHost(%q) = %v, %v, want %s, %v", tt.key, h.Addr, ok, tt.want, tt.ok)
+		}
+	}
+}
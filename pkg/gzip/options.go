@@ -75,6 +75,7 @@ func (o *Options) ParseArgs(args []string, cmdLine *flag.FlagSet) error {
 }
 
 // Validate checks options.
+// The number of threads and the block size must be positive.
 // Forces decompression to be enabled when test mode is enabled.
 // It further modifies options if the running binary is named
 // gunzip or gzcat to allow for expected behavor. Checks if there is piped stdin data.
@@ -88,6 +89,14 @@ func (o *Options) validate(moreArgs bool) error {
 		return errors.New("")
 	}
 
+	if o.Processes < 1 {
+		return fmt.Errorf("gzip: number of threads must be at least 1, got %d", o.Processes)
+	}
+
+	if o.Blocksize < 1 {
+		return fmt.Errorf("gzip: block size must be at least 1 KiB, got %d", o.Blocksize)
+	}
+
 	if o.Test {
 		o.Decompress = true
 	}
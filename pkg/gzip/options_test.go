@@ -93,6 +93,24 @@ func TestOptions_validate(t *testing.T) {
 			args:    args{moreArgs: false},
 			wantErr: true,
 		},
+		{
+			name:    "Default values with args",
+			fields:  fields{Blocksize: 128, Level: -1, Processes: runtime.NumCPU()},
+			args:    args{moreArgs: true},
+			wantErr: false,
+		},
+		{
+			name:    "Zero threads",
+			fields:  fields{Blocksize: 128, Level: -1, Processes: 0},
+			args:    args{moreArgs: true},
+			wantErr: true,
+		},
+		{
+			name:    "Zero block size",
+			fields:  fields{Blocksize: 0, Level: -1, Processes: 1},
+			args:    args{moreArgs: true},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
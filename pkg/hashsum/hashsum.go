@@ -0,0 +1,181 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hashsum prints and checks the hashes of files the way the *sum
+// commands do, in lines of a hash in hex, two spaces and a file name.
+package hashsum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// Sum returns the hash of r in hex.
+func Sum(h hash.Hash, r io.Reader) (string, error) {
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// open opens a file to hash, which is stdin if it is named -.
+func open(name string, stdin io.Reader) (io.ReadCloser, error) {
+	if name == "-" {
+		return ioutil.NopCloser(stdin), nil
+	}
+	return os.Open(name)
+}
+
+// SumFile returns the hash of the file name, or of stdin if name is -.
+func SumFile(h hash.Hash, name string, stdin io.Reader) (string, error) {
+	f, err := open(name, stdin)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return Sum(h, f)
+}
+
+// Line formats the line of the hash of a file.
+func Line(sum, name string) string {
+	return sum + "  " + name + "\n"
+}
+
+// Result counts the problems found by Check.
+type Result struct {
+	Failed     int
+	Unreadable int
+	Malformed  int
+}
+
+// OK reports whether all files had their hashes.
+func (r Result) OK() bool {
+	return r.Failed == 0 && r.Unreadable == 0
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// Warnings returns the warnings that summarize r.
+func (r Result) Warnings() []string {
+	var w []string
+	if r.Malformed > 0 {
+		w = append(w, fmt.Sprintf("WARNING: %s improperly formatted", plural(r.Malformed, "line is", "lines are")))
+	}
+	if r.Unreadable > 0 {
+		w = append(w, fmt.Sprintf("WARNING: %s could not be read", plural(r.Unreadable, "listed file", "listed files")))
+	}
+	if r.Failed > 0 {
+		w = append(w, fmt.Sprintf("WARNING: %s did NOT match", plural(r.Failed, "computed checksum", "computed checksums")))
+	}
+	return w
+}
+
+// Check reads lines of hashes and file names from r, hashes the files, and
+// prints to w whether each one matches. newHash returns the hash for a
+// line, which may depend on the length of its hash, or an error if there
+// is none. It is an error if there are no lines to check.
+func Check(w io.Writer, r io.Reader, stdin io.Reader, newHash func(sum string) (hash.Hash, error)) (Result, error) {
+	var res Result
+	var checked int
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		i := strings.IndexByte(line, ' ')
+		if i < 0 || len(line) < i+2 || (line[i+1] != ' ' && line[i+1] != '*') {
+			res.Malformed++
+			continue
+		}
+		want, name := strings.ToLower(line[:i]), line[i+2:]
+		if _, err := hex.DecodeString(want); err != nil || name == "" {
+			res.Malformed++
+			continue
+		}
+		h, err := newHash(want)
+		if err != nil {
+			res.Malformed++
+			continue
+		}
+		checked++
+		got, err := SumFile(h, name, stdin)
+		switch {
+		case err != nil:
+			res.Unreadable++
+			fmt.Fprintf(w, "%s: FAILED open or read\n", name)
+		case got != want:
+			res.Failed++
+			fmt.Fprintf(w, "%s: FAILED\n", name)
+		default:
+			fmt.Fprintf(w, "%s: OK\n", name)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return res, err
+	}
+	if checked == 0 {
+		return res, errors.New("no properly formatted checksum lines found")
+	}
+	return res, nil
+}
+
+// CheckFile checks the lines of the file name, or of stdin if name is -.
+func CheckFile(w io.Writer, name string, stdin io.Reader, newHash func(sum string) (hash.Hash, error)) (Result, error) {
+	f, err := open(name, stdin)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	return Check(w, f, stdin, newHash)
+}
+
+// Run prints the hashes of the files names, or of stdin if there are none,
+// or checks the hashes in them if check is set. newHash is called with an
+// empty sum for printing. Errors and warnings are logged. Run returns
+// whether all files were read and, when checking, matched.
+func Run(w io.Writer, stdin io.Reader, names []string, check bool, newHash func(sum string) (hash.Hash, error)) bool {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	ok := true
+	for _, name := range names {
+		if check {
+			res, err := CheckFile(w, name, stdin, newHash)
+			if err != nil {
+				log.Printf("%s: %v", name, err)
+				ok = false
+				continue
+			}
+			for _, warning := range res.Warnings() {
+				log.Print(warning)
+			}
+			ok = ok && res.OK()
+			continue
+		}
+		h, err := newHash("")
+		if err != nil {
+			log.Print(err)
+			return false
+		}
+		sum, err := SumFile(h, name, stdin)
+		if err != nil {
+			log.Print(err)
+			ok = false
+			continue
+		}
+		io.WriteString(w, Line(sum, name))
+	}
+	return ok
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashsum
+
+import (
+	"bytes"
+	"crypto/md5"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newMD5(string) (hash.Hash, error) {
+	return md5.New(), nil
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hashsum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := filepath.Join(dir, "a")
+	if err := ioutil.WriteFile(a, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const sumABC = "900150983cd24fb0d6963f7d28e17f72"
+	const sumEmpty = "d41d8cd98f00b204e9800998ecf8427e"
+	for _, tt := range []struct {
+		name  string
+		names []string
+		check bool
+		stdin string
+		want  string
+		ok    bool
+	}{
+		{name: "stdin", want: Line(sumEmpty, "-"), ok: true},
+		{name: "files", names: []string{a, "-"}, stdin: "abc", want: Line(sumABC, a) + Line(sumABC, "-"), ok: true},
+		{name: "missing", names: []string{filepath.Join(dir, "b"), a}, want: Line(sumABC, a)},
+		{
+			name:  "check",
+			check: true,
+			stdin: Line(sumABC, a) + Line(strings.ToUpper(sumABC), a) + sumABC + " *" + a + "\n",
+			want:  a + ": OK\n" + a + ": OK\n" + a + ": OK\n",
+			ok:    true,
+		},
+		{
+			name:  "check failed",
+			check: true,
+			stdin: Line(sumEmpty, a) + Line(sumABC, filepath.Join(dir, "b")) + "bad line\n",
+			want:  a + ": FAILED\n" + filepath.Join(dir, "b") + ": FAILED open or read\n",
+		},
+		{name: "check nothing", check: true, stdin: "bad\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			ok := Run(&out, strings.NewReader(tt.stdin), tt.names, tt.check, newMD5)
+			if out.String() != tt.want || ok != tt.ok {
+				t.Errorf("Run() = %q, %v, want %q, %v", out.String(), ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	for _, tt := range []struct {
+		res  Result
+		want []string
+	}{
+		{Result{}, nil},
+		{Result{Failed: 1}, []string{"WARNING: 1 computed checksum did NOT match"}},
+		{Result{Failed: 2, Unreadable: 1, Malformed: 3}, []string{
+			"WARNING: 3 lines are improperly formatted",
+			"WARNING: 1 listed file could not be read",
+			"WARNING: 2 computed checksums did NOT match",
+		}},
+	} {
+		if got := tt.res.Warnings(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.Warnings() = %q, want %q", tt.res, got, tt.want)
+		}
+	}
+}
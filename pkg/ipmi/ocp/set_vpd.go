@@ -6,12 +6,15 @@
 package ocp
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"syscall"
+
+	"github.com/u-root/u-root/pkg/vpd"
 )
 
 func handler(c <-chan os.Signal) {
@@ -20,67 +23,68 @@ func handler(c <-chan os.Signal) {
 	}
 }
 
-// Set RW_VPD key-value
-func Set(key string, value []byte) error {
-	file, err := ioutil.TempFile("/tmp", "rwvpd*.bin")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(file.Name())
-
-	c := make(chan os.Signal)
-	go handler(c)
-	defer close(c)
+// flashrom runs flashrom on the internal flash chip.
+func flashrom(args ...string) *exec.Cmd {
+	return exec.Command("flashrom", append([]string{"-p", "internal:ich_spi_mode=hwseq", "-c", "Opaque flash chip", "--fmap"}, args...)...)
+}
 
-	cmd := exec.Command("flashrom", "-p", "internal:ich_spi_mode=hwseq", "-c", "Opaque flash chip", "--fmap", "-i", "RW_VPD", "-r", file.Name())
-	cmd.Stdin, cmd.Stdout = os.Stdin, os.Stdout
-	if err = cmd.Run(); err != nil {
-		log.Printf("flashrom failed to read RW_VPD: %v", err)
+// editImage applies edit to the RW_VPD partition of a flash image, which
+// needs to contain the flash map. A corrupt RW_VPD is replaced by an empty
+// one.
+func editImage(image []byte, edit func(p *vpd.Partition)) error {
+	off, size, ok, err := vpd.FMAPArea(image, "RW_VPD")
+	if err != nil {
 		return err
 	}
-	cmd = exec.Command("vpd", "-f", file.Name(), "-s", key+"="+string(value[:]))
-	if err = cmd.Run(); err != nil {
-		log.Printf("vpd failed to set key: %v value: %v, err: %v", key, string(value[:]), err)
-		return err
+	if !ok {
+		return fmt.Errorf("no flash map found")
 	}
-	cmd = exec.Command("flashrom", "-p", "internal:ich_spi_mode=hwseq", "-c", "Opaque flash chip", "--fmap", "-i", "RW_VPD", "--noverify-all", "-w", file.Name())
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
+	b := image[off : off+size]
+	p, err := vpd.ParsePartition(b)
+	if err != nil {
+		log.Printf("RW_VPD is corrupt, re-formatting it: %v", err)
+		p = vpd.NewPartition(size)
 	}
-	signal.Notify(c, syscall.SIGINT)
-	defer signal.Reset(syscall.SIGINT)
-	if err = cmd.Run(); err != nil {
-		log.Printf("flashrom failed to write RW_VPD: %v", err)
+	edit(p)
+	nb, err := p.Bytes()
+	if err != nil {
 		return err
 	}
+	copy(b, nb)
 	return nil
 }
 
-// ClearRwVpd re-format RW_VPD
-func ClearRwVpd() error {
+// editRwVpd reads RW_VPD from flash, applies edit to it and writes it back.
+func editRwVpd(edit func(p *vpd.Partition)) error {
 	file, err := ioutil.TempFile("/tmp", "rwvpd*.bin")
 	if err != nil {
 		return err
 	}
 	defer os.Remove(file.Name())
+	file.Close()
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	go handler(c)
 	defer close(c)
 
-	log.Printf("Reading RW_VPD...")
-	cmd := exec.Command("flashrom", "-p", "internal:ich_spi_mode=hwseq", "-c", "Opaque flash chip", "--fmap", "-i", "RW_VPD", "-r", file.Name())
+	// The flash map is read along with RW_VPD, to find RW_VPD in the image.
+	cmd := flashrom("-i", "FMAP", "-i", "RW_VPD", "-r", file.Name())
 	cmd.Stdin, cmd.Stdout = os.Stdin, os.Stdout
 	if err = cmd.Run(); err != nil {
 		log.Printf("flashrom failed to read RW_VPD: %v", err)
 		return err
 	}
-	cmd = exec.Command("vpd", "-f", file.Name(), "-O")
-	if err = cmd.Run(); err != nil {
-		log.Printf("vpd failed to re-format RW_VPD: %v", err)
+	image, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	if err := editImage(image, edit); err != nil {
+		return fmt.Errorf("RW_VPD: %v", err)
+	}
+	if err := ioutil.WriteFile(file.Name(), image, 0600); err != nil {
 		return err
 	}
-	cmd = exec.Command("flashrom", "-p", "internal:ich_spi_mode=hwseq", "-c", "Opaque flash chip", "--fmap", "-i", "RW_VPD", "--noverify-all", "-w", file.Name())
+	cmd = flashrom("-i", "RW_VPD", "--noverify-all", "-w", file.Name())
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
@@ -92,3 +96,37 @@ func ClearRwVpd() error {
 	}
 	return nil
 }
+
+// Set RW_VPD key-value
+func Set(key string, value []byte) error {
+	return editRwVpd(func(p *vpd.Partition) {
+		p.Set(key, value)
+	})
+}
+
+// resetEntries deletes keys, or all entries if no keys are given, and logs
+// what it deleted.
+func resetEntries(p *vpd.Partition, keys []string) {
+	if len(keys) == 0 {
+		for _, e := range p.Entries {
+			log.Printf("Deleting RW_VPD %s=%q", e.Key, e.Value)
+		}
+		p.Entries = nil
+		return
+	}
+	for _, k := range keys {
+		if v, ok := p.Get(k); ok {
+			log.Printf("Deleting RW_VPD %s=%q", k, v)
+			p.Delete(k)
+		}
+	}
+}
+
+// ClearRwVpd resets RW_VPD to its defaults by deleting keys, or all keys if
+// none are given.
+func ClearRwVpd(keys ...string) error {
+	log.Printf("Resetting RW_VPD...")
+	return editRwVpd(func(p *vpd.Partition) {
+		resetEntries(p, keys)
+	})
+}
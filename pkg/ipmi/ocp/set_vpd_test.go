@@ -0,0 +1,90 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/vpd"
+)
+
+// testImage returns a flash image with a flash map, and RW_VPD at 0x1000
+// with the given contents.
+func testImage(t *testing.T, rwVPD []byte) []byte {
+	image := bytes.Repeat([]byte{0xff}, 0x2000)
+	fmap := image[0x100:]
+	copy(fmap, "__FMAP__\x01\x01")
+	binary.LittleEndian.PutUint16(fmap[54:], 1)
+	area := fmap[56:]
+	binary.LittleEndian.PutUint32(area, 0x1000)
+	binary.LittleEndian.PutUint32(area[4:], 0x1000)
+	copy(area[8:40], append([]byte("RW_VPD"), make([]byte, 26)...))
+	copy(image[0x1000:], rwVPD)
+	return image
+}
+
+func rwVPD(t *testing.T, image []byte) []vpd.Entry {
+	p, err := vpd.ParsePartition(image[0x1000:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Entries
+}
+
+func TestEditImage(t *testing.T) {
+	p := vpd.NewPartition(0x1000)
+	p.Set("a", []byte("1"))
+	p.Set("b", []byte("2"))
+	p.Set("c", []byte("3"))
+	b, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := testImage(t, b)
+
+	if err := editImage(image, func(p *vpd.Partition) { p.Set("d", []byte("4")) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := rwVPD(t, image); len(got) != 4 {
+		t.Errorf("RW_VPD after Set = %q, want 4 entries", got)
+	}
+	if image[0xfff] != 0xff || image[0x100] != '_' {
+		t.Errorf("editImage changed the image outside of RW_VPD")
+	}
+
+	// A corrupt RW_VPD is re-formatted.
+	image = testImage(t, []byte("garbage"))
+	if err := editImage(image, func(p *vpd.Partition) { p.Set("a", []byte("1")) }); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rwVPD(t, image), []vpd.Entry{{Key: "a", Value: []byte("1")}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RW_VPD = %q, want %q", got, want)
+	}
+
+	if err := editImage(make([]byte, 0x2000), func(*vpd.Partition) {}); err == nil {
+		t.Errorf("editImage without flash map succeeded")
+	}
+}
+
+func TestResetEntries(t *testing.T) {
+	for _, tt := range []struct {
+		keys []string
+		want []vpd.Entry
+	}{
+		{nil, nil},
+		{[]string{"b", "missing"}, []vpd.Entry{{Key: "a", Value: []byte("1")}}},
+	} {
+		p := vpd.NewPartition(0x100)
+		p.Set("a", []byte("1"))
+		p.Set("b", []byte("2"))
+		resetEntries(p, tt.keys)
+		if !reflect.DeepEqual(p.Entries, tt.want) {
+			t.Errorf("resetEntries(%q) left %q, want %q", tt.keys, p.Entries, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+// alias maps names matching a shell pattern to a module.
+type alias struct {
+	pattern string
+	module  string
+}
+
+// config is the module configuration from modprobe.d, modules.alias and
+// the kernel command line.
+type config struct {
+	// aliases are set in modprobe.d and take precedence over modAliases,
+	// which come from modules.alias.
+	aliases    []alias
+	modAliases []alias
+	blacklist  map[string]bool
+	options    map[string][]string
+}
+
+// configDirs are searched for modprobe.d *.conf files, in this order.
+var configDirs = []string{"/lib/modprobe.d", "/run/modprobe.d", "/etc/modprobe.d"}
+
+// modName returns the canonical name of a module given by name or path:
+// without directory and .ko suffix, and with underscores for hyphens.
+func modName(s string) string {
+	s = path.Base(s)
+	for _, suffix := range moduleSuffixes {
+		s = strings.TrimSuffix(s, suffix)
+	}
+	return strings.Replace(s, "-", "_", -1)
+}
+
+func readConfig(moduleDir string, opts ProbeOpts) (*config, error) {
+	c := &config{
+		blacklist: make(map[string]bool),
+		options:   make(map[string][]string),
+	}
+	for _, d := range configDirs {
+		files, err := filepath.Glob(filepath.Join(opts.RootDir, d, "*.conf"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if err := c.readFile(file, c.parseConfig); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := c.readFile(filepath.Join(moduleDir, "modules.alias"), c.parseAliases); err != nil {
+		return nil, err
+	}
+
+	if !opts.IgnoreCmdline {
+		if v, ok := cmdline.Flag("modprobe.blacklist"); ok {
+			for _, m := range strings.Split(v, ",") {
+				c.blacklist[modName(m)] = true
+			}
+		}
+	}
+	return c, nil
+}
+
+// readFile calls parse with the named file. Missing files are ignored.
+func (c *config) readFile(name string, parse func(io.Reader) error) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := parse(f); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// lines calls fn with the fields of every line in r, skipping comments and
+// empty lines and joining lines ending in a backslash.
+func lines(r io.Reader, fn func([]string)) error {
+	scanner := bufio.NewScanner(r)
+	var line string
+	for scanner.Scan() {
+		txt := scanner.Text()
+		if strings.HasSuffix(txt, `\`) {
+			line += strings.TrimSuffix(txt, `\`) + " "
+			continue
+		}
+		line += txt
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if f := strings.Fields(line); len(f) > 0 {
+			fn(f)
+		}
+		line = ""
+	}
+	return scanner.Err()
+}
+
+// parseConfig parses a modprobe.d file. The alias, blacklist and options
+// commands are supported, others are ignored.
+func (c *config) parseConfig(r io.Reader) error {
+	return lines(r, func(f []string) {
+		switch {
+		case f[0] == "alias" && len(f) == 3:
+			c.aliases = append(c.aliases, alias{pattern: f[1], module: modName(f[2])})
+		case f[0] == "blacklist" && len(f) == 2:
+			c.blacklist[modName(f[1])] = true
+		case f[0] == "options" && len(f) > 2:
+			m := modName(f[1])
+			c.options[m] = append(c.options[m], f[2:]...)
+		}
+	})
+}
+
+// parseAliases parses modules.alias as written by depmod.
+func (c *config) parseAliases(r io.Reader) error {
+	return lines(r, func(f []string) {
+		if f[0] == "alias" && len(f) == 3 {
+			c.modAliases = append(c.modAliases, alias{pattern: f[1], module: modName(f[2])})
+		}
+	})
+}
+
+// lookup returns the modules the aliases map name to, in order and
+// without duplicates.
+func lookup(aliases []alias, name string) []string {
+	var mods []string
+	seen := make(map[string]bool)
+	for _, a := range aliases {
+		if ok, _ := filepath.Match(a.pattern, name); ok && !seen[a.module] {
+			seen[a.module] = true
+			mods = append(mods, a.module)
+		}
+	}
+	return mods
+}
+
+// resolve returns the paths of the modules name refers to. name is looked
+// up in the modprobe.d aliases first, then as a module name and last in
+// modules.alias. Blacklisted modules are skipped when found through an
+// alias, or always if useBlacklist is set.
+func (c *config) resolve(name string, deps depMap, useBlacklist bool) ([]string, error) {
+	mods := lookup(c.aliases, name)
+	if len(mods) == 0 {
+		if p, err := findModPath(name, deps); err == nil {
+			if useBlacklist && c.blacklist[modName(name)] {
+				return nil, fmt.Errorf("module %q is blacklisted", name)
+			}
+			return []string{p}, nil
+		}
+		mods = lookup(c.modAliases, name)
+	}
+	if len(mods) == 0 {
+		return nil, fmt.Errorf("could not find module path %q", name)
+	}
+
+	var paths []string
+	for _, m := range mods {
+		if c.blacklist[m] {
+			continue
+		}
+		p, err := findModPath(m, deps)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("all modules for %q are blacklisted", name)
+	}
+	return paths, nil
+}
+
+// params returns the parameters for the module at modPath: those from
+// modprobe.d, then the kernel command line and last modParams, so that
+// later ones override earlier ones.
+func (c *config) params(modPath, modParams string, opts ProbeOpts) string {
+	m := modName(modPath)
+	p := append([]string(nil), c.options[m]...)
+	if !opts.IgnoreCmdline {
+		p = append(p, strings.Fields(cmdline.FlagsForModule(m))...)
+	}
+	p = append(p, strings.Fields(modParams)...)
+	return strings.Join(p, " ")
+}
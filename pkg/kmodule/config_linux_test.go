@@ -0,0 +1,159 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	modulesDep = `kernel/drivers/net/ethernet/intel/e1000e/e1000e.ko.xz: kernel/drivers/ptp/ptp.ko.xz kernel/drivers/pps/pps_core.ko.xz
+kernel/drivers/ptp/ptp.ko.xz: kernel/drivers/pps/pps_core.ko.xz
+kernel/drivers/pps/pps_core.ko.xz:
+kernel/drivers/net/ethernet/intel/igb/igb.ko:
+kernel/drivers/nvme/host/nvme-core.ko:
+kernel/drivers/nvme/host/nvme.ko: kernel/drivers/nvme/host/nvme-core.ko
+kernel/sound/pci/snd-hda-intel.ko:
+kernel/drivers/video/nouveau.ko:
+`
+	modulesAlias = `# Aliases extracted from modules themselves.
+alias pci:v00008086d000010D3sv*sd*bc*sc*i* e1000e
+alias pci:v00008086d000010C9sv*sd*bc*sc*i* igb
+alias pci:v*d*sv*sd*bc01sc08i02* nvme
+alias pci:v000010DEd*sv*sd*bc03sc*i* nouveau
+`
+	modprobeConf = `# Test configuration.
+blacklist nouveau
+alias sound snd-hda-intel
+options snd-hda-intel index=1 \
+	model=auto
+options nvme_core multipath=N
+install foo /bin/true
+`
+)
+
+func setupRoot(t *testing.T) (string, ProbeOpts) {
+	root, err := ioutil.TempDir("", "kmodule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "lib/modules/5.10.0")
+	for _, d := range []string{dir, filepath.Join(root, "etc/modprobe.d")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, data := range map[string]string{
+		filepath.Join(dir, "modules.dep"):                 modulesDep,
+		filepath.Join(dir, "modules.alias"):               modulesAlias,
+		filepath.Join(dir, "modules.builtin"):             "kernel/drivers/nvme/host/nvme-core.ko\n",
+		filepath.Join(root, "etc/modprobe.d/test.conf"):   modprobeConf,
+		filepath.Join(root, "etc/modprobe.d/ignored.txt"): "blacklist igb\n",
+	} {
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, ProbeOpts{RootDir: root, KVer: "5.10.0", IgnoreProcMods: true, IgnoreCmdline: true}
+}
+
+func TestProbeOptions(t *testing.T) {
+	root, opts := setupRoot(t)
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "lib/modules/5.10.0")
+
+	for _, tt := range []struct {
+		name      string
+		blacklist bool
+		want      []string
+		err       bool
+	}{
+		{
+			name: "e1000e",
+			want: []string{
+				"kernel/drivers/pps/pps_core.ko.xz",
+				"kernel/drivers/ptp/ptp.ko.xz",
+				"kernel/drivers/net/ethernet/intel/e1000e/e1000e.ko.xz",
+			},
+		},
+		{
+			name: "pci:v00008086d000010D3sv00008086sd0000A01Fbc02sc00i00",
+			want: []string{
+				"kernel/drivers/pps/pps_core.ko.xz",
+				"kernel/drivers/ptp/ptp.ko.xz",
+				"kernel/drivers/net/ethernet/intel/e1000e/e1000e.ko.xz",
+			},
+		},
+		// nvme-core is builtin.
+		{name: "pci:v0000144Dd0000A808sv0000144Dsd0000A801bc01sc08i02", want: []string{"kernel/drivers/nvme/host/nvme.ko"}},
+		{name: "snd_hda_intel", want: []string{"kernel/sound/pci/snd-hda-intel.ko"}},
+		{name: "sound", want: []string{"kernel/sound/pci/snd-hda-intel.ko"}},
+		{name: "nouveau", want: []string{"kernel/drivers/video/nouveau.ko"}},
+		{name: "nouveau", blacklist: true, err: true},
+		{name: "pci:v000010DEd00001C03sv*sd*bc03sc00i00", err: true},
+		{name: "nosuchmodule", err: true},
+	} {
+		var got []string
+		opts.DryRunCB = func(p string) {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, rel)
+		}
+		opts.UseBlacklist = tt.blacklist
+		err := ProbeOptions(tt.name, "", opts)
+		if (err != nil) != tt.err {
+			t.Errorf("ProbeOptions(%q) = %v, want error %v", tt.name, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ProbeOptions(%q) loaded %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	root, opts := setupRoot(t)
+	defer os.RemoveAll(root)
+	dir, err := findModuleDir(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := readConfig(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path, params, want string
+	}{
+		{path: "kernel/sound/pci/snd-hda-intel.ko", want: "index=1 model=auto"},
+		{path: "kernel/sound/pci/snd-hda-intel.ko", params: "index=0", want: "index=1 model=auto index=0"},
+		{path: "kernel/drivers/nvme/host/nvme-core.ko", want: "multipath=N"},
+		{path: "kernel/drivers/net/ethernet/intel/igb/igb.ko", params: "max_vfs=7", want: "max_vfs=7"},
+	} {
+		if got := c.params(tt.path, tt.params, opts); got != tt.want {
+			t.Errorf("params(%q, %q) = %q, want %q", tt.path, tt.params, got, tt.want)
+		}
+	}
+	if c.blacklist["igb"] {
+		t.Errorf("blacklist from a file without .conf suffix was used")
+	}
+}
+
+func TestPath(t *testing.T) {
+	root, opts := setupRoot(t)
+	defer os.RemoveAll(root)
+	got, err := Path("pci:v00008086d000010C9sv*sd*bc*sc*i*", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "lib/modules/5.10.0/kernel/drivers/net/ethernet/intel/igb/igb.ko"); got != want {
+		t.Errorf("Path = %q, want %q", got, want)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/klauspost/pgzip"
 	"github.com/ulikunitz/xz"
 	"golang.org/x/sys/unix"
@@ -38,44 +39,67 @@ func Init(image []byte, opts string) error {
 	return unix.InitModule(image, opts)
 }
 
+// moduleSuffixes are the file name suffixes of modules, uncompressed and
+// compressed.
+var moduleSuffixes = []string{".ko", ".ko.gz", ".ko.xz", ".ko.zst"}
+
 // FileInit loads the kernel module contained by `f` with the given opts and
-// flags. Uncompresses modules with a .xz and .gz suffix before loading.
+// flags. Uncompresses modules with a .xz, .gz and .zst suffix before loading.
 //
 // FileInit falls back to init_module(2) via Init when the finit_module(2)
 // syscall is not available and when loading compressed modules.
 func FileInit(f *os.File, opts string, flags uintptr) error {
-	var r io.Reader
-	if strings.HasSuffix(f.Name(), ".xz") {
-		var err error
-		if r, err = xz.NewReader(f); err != nil {
+	img, err := decompress(f)
+	if err != nil {
+		return err
+	}
+
+	if img == nil {
+		err := unix.FinitModule(int(f.Fd()), opts, int(flags))
+		if err != unix.ENOSYS {
 			return err
 		}
-
-	} else if strings.HasSuffix(f.Name(), ".gz") {
-		var err error
-		if r, err = pgzip.NewReader(f); err != nil {
+		if flags != 0 {
 			return err
 		}
-	}
-
-	if r == nil {
-		err := unix.FinitModule(int(f.Fd()), opts, int(flags))
-		if err == unix.ENOSYS {
-			if flags != 0 {
-				return err
-			}
-			// Fall back to init_module(2).
-			r = f
-		} else {
+		// Fall back to init_module(2).
+		if img, err = ioutil.ReadAll(f); err != nil {
 			return err
 		}
 	}
+	return Init(img, opts)
+}
 
-	img, err := ioutil.ReadAll(r)
-	if err != nil {
-		return err
+// decompress returns the uncompressed contents of a module with a .xz, .gz
+// or .zst suffix, or nil if it is not compressed.
+func decompress(f *os.File) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case strings.HasSuffix(f.Name(), ".xz"):
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = xr
+	case strings.HasSuffix(f.Name(), ".gz"):
+		gr, err := pgzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case strings.HasSuffix(f.Name(), ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		// Close stops the goroutines of the decoder.
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, nil
 	}
-	return Init(img, opts)
+	return ioutil.ReadAll(r)
 }
 
 // Delete removes a kernel module.
@@ -107,6 +131,14 @@ type ProbeOpts struct {
 	RootDir        string
 	KVer           string
 	IgnoreProcMods bool
+
+	// IgnoreCmdline ignores module parameters and modprobe.blacklist
+	// given on the kernel command line.
+	IgnoreCmdline bool
+
+	// UseBlacklist refuses to load blacklisted modules even if they are
+	// given by name. Blacklisted modules are never loaded through aliases.
+	UseBlacklist bool
 }
 
 // Probe loads the given kernel module and its dependencies.
@@ -117,17 +149,40 @@ func Probe(name string, modParams string) error {
 
 // ProbeOptions loads the given kernel module and its dependencies.
 // This functions takes ProbeOpts.
+//
+// name is either a module name or an alias, such as the modalias of a
+// device. An alias may resolve to several modules, all of which are loaded.
+// modParams are appended to the parameters configured in modprobe.d and on
+// the kernel command line.
 func ProbeOptions(name, modParams string, opts ProbeOpts) error {
-	deps, err := genDeps(opts)
+	moduleDir, err := findModuleDir(opts)
+	if err != nil {
+		return err
+	}
+	deps, err := genDeps(moduleDir, opts)
 	if err != nil {
 		return fmt.Errorf("could not generate dependency map %v", err)
 	}
+	c, err := readConfig(moduleDir, opts)
+	if err != nil {
+		return fmt.Errorf("could not read module configuration: %v", err)
+	}
 
-	modPath, err := findModPath(name, deps)
+	modPaths, err := c.resolve(name, deps, opts.UseBlacklist)
 	if err != nil {
-		return fmt.Errorf("could not find module path %q: %v", name, err)
+		return err
+	}
+
+	var firstErr error
+	for _, modPath := range modPaths {
+		if err := probe(modPath, modParams, deps, c, opts); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	return firstErr
+}
 
+func probe(modPath, modParams string, deps depMap, c *config, opts ProbeOpts) error {
 	dep := deps[modPath]
 
 	if dep.state == builtin || dep.state == loaded {
@@ -136,11 +191,39 @@ func ProbeOptions(name, modParams string, opts ProbeOpts) error {
 
 	dep.state = loading
 	for _, d := range dep.deps {
-		if err := loadDeps(d, deps, opts); err != nil {
+		if err := loadDeps(d, deps, c, opts); err != nil {
 			return err
 		}
 	}
-	return loadModule(modPath, modParams, opts)
+	if err := loadModule(modPath, c.params(modPath, modParams, opts), opts); err != nil {
+		return err
+	}
+	dep.state = loaded
+	return nil
+}
+
+// Path returns the path of the given kernel module, as found in
+// modules.dep. Like for Probe, hyphens and underscores in name are
+// interchangeable, and aliases are resolved. If an alias resolves to
+// several modules, the first one is returned.
+func Path(name string, opts ProbeOpts) (string, error) {
+	moduleDir, err := findModuleDir(opts)
+	if err != nil {
+		return "", err
+	}
+	deps, err := genDeps(moduleDir, opts)
+	if err != nil {
+		return "", fmt.Errorf("could not generate dependency map %v", err)
+	}
+	c, err := readConfig(moduleDir, opts)
+	if err != nil {
+		return "", fmt.Errorf("could not read module configuration: %v", err)
+	}
+	modPaths, err := c.resolve(name, deps, opts.UseBlacklist)
+	if err != nil {
+		return "", err
+	}
+	return modPaths[0], nil
 }
 
 func checkBuiltin(moduleDir string, deps depMap) error {
@@ -165,14 +248,15 @@ func checkBuiltin(moduleDir string, deps depMap) error {
 	return scanner.Err()
 }
 
-func genDeps(opts ProbeOpts) (depMap, error) {
-	deps := make(depMap)
+// findModuleDir returns the module directory of the kernel release in
+// opts, or the running kernel's.
+func findModuleDir(opts ProbeOpts) (string, error) {
 	rel := opts.KVer
 
 	if rel == "" {
 		var u unix.Utsname
 		if err := unix.Uname(&u); err != nil {
-			return nil, fmt.Errorf("could not get release (uname -r): %v", err)
+			return "", fmt.Errorf("could not get release (uname -r): %v", err)
 		}
 		rel = string(u.Release[:bytes.IndexByte(u.Release[:], 0)])
 	}
@@ -184,6 +268,11 @@ func genDeps(opts ProbeOpts) (depMap, error) {
 			break
 		}
 	}
+	return moduleDir, nil
+}
+
+func genDeps(moduleDir string, opts ProbeOpts) (depMap, error) {
+	deps := make(depMap)
 
 	f, err := os.Open(filepath.Join(moduleDir, "modules.dep"))
 	if err != nil {
@@ -234,18 +323,18 @@ func findModPath(name string, m depMap) (string, error) {
 	nameU := strings.Replace(name, "-", "_", -1)
 
 	for mp := range m {
-		switch path.Base(mp) {
-		case nameH + ".ko", nameH + ".ko.gz", nameH + ".ko.xz":
-			return mp, nil
-		case nameU + ".ko", nameU + ".ko.gz", nameU + ".ko.xz":
-			return mp, nil
+		base := path.Base(mp)
+		for _, suffix := range moduleSuffixes {
+			if base == nameH+suffix || base == nameU+suffix {
+				return mp, nil
+			}
 		}
 	}
 
 	return "", fmt.Errorf("could not find path for module %q", name)
 }
 
-func loadDeps(path string, m depMap, opts ProbeOpts) error {
+func loadDeps(path string, m depMap, c *config, opts ProbeOpts) error {
 	dependency, ok := m[path]
 	if !ok {
 		return fmt.Errorf("could not find dependency %q", path)
@@ -260,13 +349,13 @@ func loadDeps(path string, m depMap, opts ProbeOpts) error {
 	m[path].state = loading
 
 	for _, dep := range dependency.deps {
-		if err := loadDeps(dep, m, opts); err != nil {
+		if err := loadDeps(dep, m, c, opts); err != nil {
 			return err
 		}
 	}
 
 	// done with dependencies, load module
-	if err := loadModule(path, "", opts); err != nil {
+	if err := loadModule(path, c.params(path, "", opts), opts); err != nil {
 		return err
 	}
 	m[path].state = loaded
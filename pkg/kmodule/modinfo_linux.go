@@ -0,0 +1,69 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Info is a field of the .modinfo section of a kernel module, such as
+// license=GPL or parm=debug:Enable debugging.
+type Info struct {
+	Key   string
+	Value string
+}
+
+// ReadInfo returns the fields of the .modinfo section of a kernel module,
+// in the order they appear in.
+func ReadInfo(r io.ReaderAt) ([]Info, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	s := f.Section(".modinfo")
+	if s == nil {
+		return nil, fmt.Errorf("no .modinfo section")
+	}
+	b, err := s.Data()
+	if err != nil {
+		return nil, err
+	}
+	var info []Info
+	// Fields are NUL terminated and may be padded with more NULs.
+	for _, field := range bytes.Split(b, []byte{0}) {
+		if len(field) == 0 {
+			continue
+		}
+		kv := strings.SplitN(string(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		info = append(info, Info{Key: kv[0], Value: kv[1]})
+	}
+	return info, nil
+}
+
+// FileInfo returns the fields of the .modinfo section of the kernel module
+// at path. Modules with a .xz, .gz or .zst suffix are uncompressed first.
+func FileInfo(path string) ([]Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := decompress(f)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return ReadInfo(f)
+	}
+	return ReadInfo(bytes.NewReader(b))
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmodule
+
+import (
+	"bytes"
+	"compress/gzip"
+	"debug/elf"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// module returns a minimal ELF relocatable with a .modinfo section.
+func module(t *testing.T, modinfo string) []byte {
+	const shstrtab = "\x00.modinfo\x00.shstrtab\x00"
+	ehsize := binary.Size(elf.Header64{})
+	shoff := ehsize + len(modinfo) + len(shstrtab)
+	h := elf.Header64{
+		Type:      uint16(elf.ET_REL),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     uint64(shoff),
+		Ehsize:    uint16(ehsize),
+		Shentsize: uint16(binary.Size(elf.Section64{})),
+		Shnum:     3,
+		Shstrndx:  2,
+	}
+	copy(h.Ident[:], elf.ELFMAG)
+	h.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	h.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	h.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var b bytes.Buffer
+	for _, v := range []interface{}{
+		h,
+		[]byte(modinfo),
+		[]byte(shstrtab),
+		elf.Section64{},
+		elf.Section64{Name: 1, Type: uint32(elf.SHT_PROGBITS), Flags: uint64(elf.SHF_ALLOC), Off: uint64(ehsize), Size: uint64(len(modinfo)), Addralign: 1},
+		elf.Section64{Name: 10, Type: uint32(elf.SHT_STRTAB), Off: uint64(ehsize + len(modinfo)), Size: uint64(len(shstrtab)), Addralign: 1},
+	} {
+		if err := binary.Write(&b, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Bytes()
+}
+
+func TestReadInfo(t *testing.T) {
+	m := module(t, "license=GPL\x00author=A. Hacker <a@example.com>\x00\x00\x00parm=debug:Enable debugging\x00parmtype=debug:int\x00bogus\x00vermagic=5.10.0 SMP mod_unload \x00")
+	want := []Info{
+		{"license", "GPL"},
+		{"author", "A. Hacker <a@example.com>"},
+		{"parm", "debug:Enable debugging"},
+		{"parmtype", "debug:int"},
+		{"vermagic", "5.10.0 SMP mod_unload "},
+	}
+	got, err := ReadInfo(bytes.NewReader(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadInfo = %q, want %q", got, want)
+	}
+
+	d, err := ioutil.TempDir("", "modinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	compressed := map[string][]byte{"m.ko": m}
+	for ext, newWriter := range map[string]func(io.Writer) (io.WriteCloser, error){
+		".gz": func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		".xz": func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) },
+		".zst": func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		},
+	} {
+		var b bytes.Buffer
+		w, err := newWriter(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		compressed["m.ko"+ext] = b.Bytes()
+	}
+	for name, b := range compressed {
+		p := filepath.Join(d, name)
+		if err := ioutil.WriteFile(p, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := FileInfo(p)
+		if err != nil {
+			t.Errorf("FileInfo(%s): %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("FileInfo(%s) = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := ReadInfo(bytes.NewReader([]byte("not an ELF file"))); err == nil {
+		t.Errorf("ReadInfo(not ELF): got nil, want error")
+	}
+}
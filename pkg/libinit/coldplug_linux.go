@@ -0,0 +1,129 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+// SysDevices is the sysfs directory of all devices.
+const SysDevices = "/sys/devices"
+
+// walkDevices calls fn with the path of each file called name below root.
+// Symlinks, such as the subsystem and driver links, are not followed.
+func walkDevices(root, name string, fn func(path string) error) error {
+	return filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Devices come and go, and some attributes are not
+			// readable, so skip what can not be read.
+			if path != root {
+				return nil
+			}
+			return err
+		}
+		if fi.Mode().IsRegular() && fi.Name() == name {
+			return fn(path)
+		}
+		return nil
+	})
+}
+
+// Modaliases returns the modaliases of the devices below root, each once,
+// in the order they are found.
+func Modaliases(root string) ([]string, error) {
+	var aliases []string
+	seen := make(map[string]bool)
+	err := walkDevices(root, "modalias", func(path string) error {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		a := strings.TrimSpace(string(b))
+		if a != "" && !seen[a] {
+			seen[a] = true
+			aliases = append(aliases, a)
+		}
+		return nil
+	})
+	return aliases, err
+}
+
+// UeventFiles returns the uevent files of the devices below root.
+func UeventFiles(root string) ([]string, error) {
+	var files []string
+	err := walkDevices(root, "uevent", func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// TriggerUevents writes action, such as "add", to the uevent file of each
+// device below root, which makes the kernel send the uevent again. It
+// returns the uevent files written.
+func TriggerUevents(root, action string) ([]string, error) {
+	files, err := UeventFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, f := range files {
+		if err := ioutil.WriteFile(f, []byte(action), 0); err != nil {
+			log.Printf("Triggering %s: %v", f, err)
+			continue
+		}
+		written = append(written, f)
+	}
+	return written, nil
+}
+
+// LoadModaliasModules loads the modules matching aliases, skipping modules
+// blacklisted by modprobe.d or modprobe.blacklist=. Aliases without a
+// module are ignored. It returns the paths of the modules loaded.
+func LoadModaliasModules(aliases []string) []string {
+	var loaded []string
+	seen := make(map[string]bool)
+	opts := kmodule.ProbeOpts{UseBlacklist: true}
+	for _, a := range aliases {
+		p, err := kmodule.Path(a, opts)
+		if err != nil || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if err := kmodule.ProbeOptions(a, "", opts); err != nil {
+			log.Printf("Loading module for %s failed: %v", a, err)
+			continue
+		}
+		loaded = append(loaded, p)
+	}
+	return loaded
+}
+
+// Coldplug brings up the devices that appeared before userspace started,
+// like udev's trigger does: it loads the modules for the modaliases of the
+// devices in SysDevices, so drivers bind to them, and then sends an add
+// uevent for every device. Drivers may request firmware while binding,
+// which the kernel or fwload serves.
+func Coldplug() {
+	aliases, err := Modaliases(SysDevices)
+	if err != nil {
+		log.Printf("Coldplug: %v", err)
+		return
+	}
+	for _, m := range LoadModaliasModules(aliases) {
+		log.Printf("Loaded module %s", m)
+	}
+	written, err := TriggerUevents(SysDevices, "add")
+	if err != nil {
+		log.Printf("Coldplug: %v", err)
+	}
+	log.Printf("Coldplug: triggered %d devices", len(written))
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func fakeSysDevices(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "coldplug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		"pci0000:00/uevent":                      "",
+		"pci0000:00/0000:00:01.0/uevent":         "",
+		"pci0000:00/0000:00:01.0/modalias":       "pci:v00008086d00001237sv*\n",
+		"pci0000:00/0000:00:02.0/uevent":         "",
+		"pci0000:00/0000:00:02.0/modalias":       "pci:v00001AF4d00001000sv*\n",
+		"pci0000:00/0000:00:03.0/modalias":       "pci:v00001AF4d00001000sv*\n",
+		"pci0000:00/0000:00:02.0/virtio0/vendor": "0x1af4\n",
+	} {
+		p := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Links such as subsystem and driver must not be followed.
+	if err := os.Symlink(filepath.Join(dir, "pci0000:00"), filepath.Join(dir, "pci0000:00/0000:00:01.0/subsystem")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestModaliases(t *testing.T) {
+	dir := fakeSysDevices(t)
+	defer os.RemoveAll(dir)
+
+	got, err := Modaliases(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"pci:v00008086d00001237sv*", "pci:v00001AF4d00001000sv*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Modaliases() = %v, want %v", got, want)
+	}
+}
+
+func TestTriggerUevents(t *testing.T) {
+	dir := fakeSysDevices(t)
+	defer os.RemoveAll(dir)
+
+	got, err := TriggerUevents(dir, "add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	var want []string
+	for _, p := range []string{"pci0000:00/0000:00:01.0/uevent", "pci0000:00/0000:00:02.0/uevent", "pci0000:00/uevent"} {
+		want = append(want, filepath.Join(dir, p))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TriggerUevents() = %v, want %v", got, want)
+	}
+	for _, p := range want {
+		if b, err := ioutil.ReadFile(p); err != nil || string(b) != "add" {
+			t.Errorf("%s = %q, %v, want add", p, b, err)
+		}
+	}
+
+	if _, err := TriggerUevents(filepath.Join(dir, "missing"), "add"); err == nil {
+		t.Error("TriggerUevents() of a missing directory = nil, want error")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+	"github.com/u-root/u-root/pkg/kmodule"
+)
+
+// modulesLoadDirs are searched for modules-load.d(5) *.conf files. A file
+// hides files of the same name in later directories.
+var modulesLoadDirs = []string{"/etc/modules-load.d", "/run/modules-load.d", "/lib/modules-load.d"}
+
+// parseModulesLoad returns the module names of a modules-load.d file: one
+// per line, skipping empty lines and comments starting with # or ;.
+func parseModulesLoad(r io.Reader) ([]string, error) {
+	var mods []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		mods = append(mods, line)
+	}
+	return mods, scanner.Err()
+}
+
+// readModulesLoad returns the modules of the *.conf files in dirs, in the
+// order of the file names.
+func readModulesLoad(dirs []string) ([]string, error) {
+	files := make(map[string]string)
+	var names []string
+	for _, d := range dirs {
+		m, err := filepath.Glob(filepath.Join(d, "*.conf"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range m {
+			if _, ok := files[filepath.Base(f)]; !ok {
+				files[filepath.Base(f)] = f
+				names = append(names, filepath.Base(f))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var mods []string
+	for _, n := range names {
+		f, err := os.Open(files[n])
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseModulesLoad(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, m...)
+	}
+	return mods, nil
+}
+
+// cmdlineModules returns the modules of the modules-load= and
+// rd.modules-load= kernel parameters, which are comma separated lists.
+func cmdlineModules(c cmdline.CmdLine) []string {
+	var mods []string
+	for _, p := range []string{"modules-load", "rd.modules-load"} {
+		v, ok := c.Flag(p)
+		if !ok {
+			continue
+		}
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				mods = append(mods, m)
+			}
+		}
+	}
+	return mods
+}
+
+// LoadConfiguredModules loads the modules listed in modules-load.d *.conf
+// files and by the modules-load= kernel parameter, with their dependencies.
+// Nothing is loaded unless modules are configured.
+//
+// Modules are found through modules.dep, like with modprobe, so parameters
+// given on the kernel command line as module.param=value are used, and
+// modules blacklisted by modprobe.blacklist= are skipped.
+func LoadConfiguredModules() {
+	mods, err := readModulesLoad(modulesLoadDirs)
+	if err != nil {
+		log.Printf("Reading modules-load.d: %v", err)
+	}
+	mods = append(mods, cmdlineModules(cmdline.NewCmdLine())...)
+
+	loaded := make(map[string]bool)
+	for _, m := range mods {
+		if loaded[m] {
+			continue
+		}
+		loaded[m] = true
+		if err := kmodule.ProbeOptions(m, "", kmodule.ProbeOpts{UseBlacklist: true}); err != nil {
+			log.Printf("Loading module %s failed: %v", m, err)
+			continue
+		}
+		log.Printf("Loaded module %s", m)
+	}
+}
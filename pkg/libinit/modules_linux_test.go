@@ -0,0 +1,65 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/cmdline"
+)
+
+func TestParseModulesLoad(t *testing.T) {
+	got, err := parseModulesLoad(strings.NewReader("# comment\nloop\n\n; other comment\n  virtio_net  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"loop", "virtio_net"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseModulesLoad() = %v, want %v", got, want)
+	}
+}
+
+func TestReadModulesLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modules-load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	etc, lib := filepath.Join(dir, "etc"), filepath.Join(dir, "lib")
+	for path, content := range map[string]string{
+		filepath.Join(etc, "b.conf"):   "etc_b\n",
+		filepath.Join(lib, "a.conf"):   "lib_a\n",
+		filepath.Join(lib, "b.conf"):   "lib_b\n",
+		filepath.Join(lib, "c.ignore"): "lib_c\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := readModulesLoad([]string{etc, filepath.Join(dir, "missing"), lib})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lib_a", "etc_b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readModulesLoad() = %v, want %v", got, want)
+	}
+}
+
+func TestCmdlineModules(t *testing.T) {
+	c := cmdline.Parse("console=ttyS0 modules-load=loop,,virtio_net rd.modules-load=e1000")
+	got := cmdlineModules(c)
+	if want := []string{"loop", "virtio_net", "e1000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cmdlineModules() = %v, want %v", got, want)
+	}
+}
@@ -234,8 +234,21 @@ func InstallAllModules() {
 	}
 }
 
+// blacklistedModules returns the modules of the modprobe.blacklist= kernel
+// parameter, with underscores for hyphens.
+func blacklistedModules() map[string]bool {
+	b := make(map[string]bool)
+	if v, ok := cmdline.Flag("modprobe.blacklist"); ok {
+		for _, m := range strings.Split(v, ",") {
+			b[strings.Replace(m, "-", "_", -1)] = true
+		}
+	}
+	return b
+}
+
 // InstallModules installs kernel modules (.ko files) from /lib/modules that
-// match the given pattern, skipping those in the exclude list.
+// match the given pattern, skipping those in the exclude list and those
+// blacklisted by modprobe.blacklist= on the kernel command line.
 func InstallModules(pattern string, exclude map[string]bool) error {
 	files, err := filepath.Glob(pattern)
 	if err != nil {
@@ -245,6 +258,7 @@ func InstallModules(pattern string, exclude map[string]bool) error {
 		return fmt.Errorf("no modules found matching '%s'", pattern)
 	}
 
+	blacklist := blacklistedModules()
 	for _, filename := range files {
 		f, err := os.Open(filename)
 		if err != nil {
@@ -254,7 +268,7 @@ func InstallModules(pattern string, exclude map[string]bool) error {
 		// Module flags are passed to the command line in the form modulename.flag=val
 		// And must be passed to FileInit as flag=val to be installed properly
 		moduleName := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
-		if _, ok := exclude[moduleName]; ok {
+		if _, ok := exclude[moduleName]; ok || blacklist[strings.Replace(moduleName, "-", "_", -1)] {
 			log.Printf("Skipping module %s", moduleName)
 			continue
 		}
@@ -264,7 +278,9 @@ func InstallModules(pattern string, exclude map[string]bool) error {
 		f.Close()
 		if err != nil {
 			log.Printf("installModules: can't install %q: %v", filename, err)
+			continue
 		}
+		log.Printf("Loaded module %s", moduleName)
 	}
 
 	return nil
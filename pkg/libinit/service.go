@@ -0,0 +1,122 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/u-root/u-root/pkg/shlex"
+)
+
+// RestartPolicy says when a service is restarted after it exited.
+type RestartPolicy int
+
+// Restart policies.
+const (
+	// RestartNo never restarts a service.
+	RestartNo RestartPolicy = iota
+	// RestartOnFailure restarts a service that exited with a non-zero
+	// status or was killed by a signal.
+	RestartOnFailure
+	// RestartAlways restarts a service whenever it exits.
+	RestartAlways
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartNo:
+		return "no"
+	case RestartOnFailure:
+		return "on-failure"
+	case RestartAlways:
+		return "always"
+	}
+	return fmt.Sprintf("RestartPolicy(%d)", int(p))
+}
+
+// Service is a process supervised by init.
+type Service struct {
+	Name string
+	// Args are the command and its arguments.
+	Args    []string
+	Restart RestartPolicy
+	// Wait makes init wait for the service to exit before the next one
+	// is started. It is used for setup steps, e.g. to configure the
+	// network before starting a server.
+	Wait bool
+	// TTY is the terminal device, e.g. ttyS0, the service runs on. It
+	// becomes the controlling terminal of the service, so that job
+	// control works. Without a TTY, the service inherits init's standard
+	// input and output and has no controlling terminal.
+	TTY string
+	// Baud is the baud rate of a serial TTY. If 0, it is left as is.
+	Baud int
+}
+
+// ParseServices parses a service list, which has one service per line:
+//
+//     NAME POLICY COMMAND [ARGS...]
+//
+// POLICY is one of
+//
+//     once:       run COMMAND once
+//     wait:       run COMMAND once, and wait for it before starting the
+//                 next service
+//     on-failure: restart COMMAND when it fails
+//     always:     restart COMMAND whenever it exits
+//
+// Services are started in the order they are listed. ARGS may be quoted.
+// Empty lines and lines starting with # are ignored.
+func ParseServices(r io.Reader) ([]Service, error) {
+	var services []Service
+	names := map[string]bool{}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := shlex.Argv(line)
+		if len(f) < 3 {
+			return nil, fmt.Errorf("line %d: want NAME POLICY COMMAND [ARGS...], got %q", n, line)
+		}
+		svc := Service{Name: f[0], Args: f[2:]}
+		switch f[1] {
+		case "once":
+		case "wait":
+			svc.Wait = true
+		case "on-failure":
+			svc.Restart = RestartOnFailure
+		case "always":
+			svc.Restart = RestartAlways
+		default:
+			return nil, fmt.Errorf("line %d: unknown policy %q, want once, wait, on-failure or always", n, f[1])
+		}
+		if names[svc.Name] {
+			return nil, fmt.Errorf("line %d: duplicate service %q", n, svc.Name)
+		}
+		names[svc.Name] = true
+		services = append(services, svc)
+	}
+	return services, s.Err()
+}
+
+// ReadServices reads a service list from the file path.
+func ReadServices(path string) ([]Service, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	services, err := ParseServices(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return services, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/u-root/u-root/pkg/termios"
+	"golang.org/x/sys/unix"
+)
+
+// Restart backoff. A service that fails right away is restarted after
+// minBackoff, then after twice as long each time, up to maxBackoff. Once it
+// ran for stableTime, the backoff is reset.
+var (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	stableTime = 10 * time.Second
+)
+
+// service is the state of a supervised service.
+type service struct {
+	Service
+	pid     int
+	started time.Time
+	backoff time.Duration
+	// pending is set while a restart is scheduled.
+	pending bool
+}
+
+type supervisor struct {
+	services []*service
+	sigchld  chan os.Signal
+	restart  chan *service
+}
+
+// Supervise starts services in order and restarts them according to their
+// restart policies. It returns when no service runs or will be restarted.
+//
+// Only the services are waited for. Other children of init, e.g. commands
+// run alongside and waited for with exec.Cmd.Wait, or orphans, which
+// WaitOrphans reaps later, are left alone.
+func Supervise(services []Service) {
+	s := &supervisor{
+		sigchld: make(chan os.Signal, 1),
+		restart: make(chan *service),
+	}
+	// Subscribe before starting anything, so no exit is missed.
+	signal.Notify(s.sigchld, syscall.SIGCHLD)
+	defer signal.Stop(s.sigchld)
+
+	for _, svc := range services {
+		sv := &service{Service: svc, backoff: minBackoff}
+		s.services = append(s.services, sv)
+		s.start(sv)
+		// Ordered startup: a wait service has to finish first.
+		for sv.Wait && sv.pid != 0 {
+			s.step()
+		}
+	}
+	for s.active() {
+		s.step()
+	}
+	log.Printf("All services exited")
+}
+
+// active returns whether a service runs or will be restarted.
+func (s *supervisor) active() bool {
+	for _, sv := range s.services {
+		if sv.pid != 0 || sv.pending {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *supervisor) start(sv *service) {
+	sv.pending = false
+	cmd := Command(sv.Args[0], WithArguments(sv.Args[1:]...), WithTTYControl(false))
+	// Each service gets its own session, so signals to one do not
+	// reach the others.
+	cmd.SysProcAttr.Setsid = true
+	sv.started = time.Now()
+	err := startOnTTY(cmd, sv.TTY, sv.Baud)
+	if err != nil {
+		log.Printf("Service %s: %v", sv.Name, err)
+		s.exited(sv, false)
+		return
+	}
+	sv.pid = cmd.Process.Pid
+	log.Printf("Service %s started, PID %d", sv.Name, sv.pid)
+	// The process is reaped by reap, not by cmd.Wait.
+	cmd.Process.Release()
+}
+
+// startOnTTY starts cmd with tty, if any, as its standard input and output
+// and its controlling terminal.
+func startOnTTY(cmd *exec.Cmd, tty string, baud int) error {
+	if tty == "" {
+		return cmd.Start()
+	}
+	// O_NOCTTY: the terminal must not become init's.
+	f, err := os.OpenFile(filepath.Join("/dev", tty), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if baud != 0 {
+		if err := setBaud(f, baud); err != nil {
+			return fmt.Errorf("%s: %v", tty, err)
+		}
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = f, f, f
+	cmd.SysProcAttr.Setctty = true
+	// Ctty is a file descriptor in the child, standard input.
+	cmd.SysProcAttr.Ctty = 0
+	return cmd.Start()
+}
+
+func setBaud(f *os.File, baud int) error {
+	t, err := termios.GetTermios(f.Fd())
+	if err != nil {
+		return err
+	}
+	if t, err = termios.MakeSerialBaud(termios.MakeSerialDefault(t), baud); err != nil {
+		return err
+	}
+	return termios.SetTermios(f.Fd(), t)
+}
+
+// step handles the next child exit or scheduled restart.
+func (s *supervisor) step() {
+	select {
+	case <-s.sigchld:
+		s.reap()
+	case sv := <-s.restart:
+		s.start(sv)
+	}
+}
+
+// reap reaps all exited services. SIGCHLDs are coalesced, so there may be
+// more than one.
+func (s *supervisor) reap() {
+	for _, sv := range s.services {
+		if sv.pid == 0 {
+			continue
+		}
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(sv.pid, &ws, syscall.WNOHANG, nil)
+		if pid != sv.pid || err != nil {
+			continue
+		}
+		sv.pid = 0
+		log.Printf("Service %s exited, %v", sv.Name, ws)
+		s.exited(sv, ws.Exited() && ws.ExitStatus() == 0)
+	}
+}
+
+// exited schedules a restart of sv if its policy asks for one.
+func (s *supervisor) exited(sv *service, success bool) {
+	if sv.Restart == RestartNo || (sv.Restart == RestartOnFailure && success) {
+		return
+	}
+	if time.Since(sv.started) >= stableTime {
+		sv.backoff = minBackoff
+	}
+	delay := sv.backoff
+	if sv.backoff *= 2; sv.backoff > maxBackoff {
+		sv.backoff = maxBackoff
+	}
+	log.Printf("Service %s: restarting in %v", sv.Name, delay)
+	sv.pending = true
+	time.AfterFunc(delay, func() { s.restart <- sv })
+}
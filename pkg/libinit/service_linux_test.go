@@ -0,0 +1,110 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSupervise(t *testing.T) {
+	defer func(min, stable time.Duration) { minBackoff, stableTime = min, stable }(minBackoff, stableTime)
+	minBackoff, stableTime = 10*time.Millisecond, time.Hour
+
+	dir, err := ioutil.TempDir("", "supervise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	sh := func(script string) []string {
+		return []string{"/bin/sh", "-c", script}
+	}
+
+	Supervise([]Service{
+		// setup runs before everything else.
+		{Name: "setup", Args: sh("sleep 0.1; echo setup >> " + log), Wait: true},
+		// flaky fails twice, then succeeds.
+		{Name: "flaky", Args: sh(`echo flaky >> ` + log + `; test $(grep -c flaky ` + log + `) -ge 3`), Restart: RestartOnFailure},
+		{Name: "once", Args: sh("echo once >> " + log + "; exit 1")},
+		// missing can not start and is not restarted.
+		{Name: "missing", Args: []string{filepath.Join(dir, "missing")}},
+	})
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Fields(string(b))
+	if len(lines) != 5 || lines[0] != "setup" {
+		t.Fatalf("services logged %q, want setup first, then 3 flaky and 1 once", lines)
+	}
+	count := map[string]int{}
+	for _, l := range lines {
+		count[l]++
+	}
+	if count["flaky"] != 3 || count["once"] != 1 {
+		t.Errorf("services logged %q, want 3 flaky and 1 once", lines)
+	}
+}
+
+func TestSuperviseOtherChildren(t *testing.T) {
+	// other exits while the service runs, and is waited for by its Cmd,
+	// not by the supervisor.
+	other := exec.Command("/bin/sh", "-c", "sleep 0.1; exit 3")
+	if err := other.Start(); err != nil {
+		t.Fatal(err)
+	}
+	Supervise([]Service{{Name: "sleep", Args: []string{"/bin/sh", "-c", "sleep 0.5"}}})
+
+	err := other.Wait()
+	if ee, ok := err.(*exec.ExitError); !ok || ee.ExitCode() != 3 {
+		t.Errorf("Wait() of another child = %v, want exit status 3", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer func(min, max, stable time.Duration) { minBackoff, maxBackoff, stableTime = min, max, stable }(minBackoff, maxBackoff, stableTime)
+	minBackoff, maxBackoff, stableTime = time.Hour, 4*time.Hour, 24*time.Hour
+
+	s := &supervisor{restart: make(chan *service)}
+	sv := &service{Service: Service{Name: "a", Restart: RestartAlways}, backoff: minBackoff, started: time.Now()}
+	var got []time.Duration
+	for i := 0; i < 4; i++ {
+		got = append(got, sv.backoff)
+		s.exited(sv, true)
+	}
+	if want := []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour, 4 * time.Hour}; !equalDurations(got, want) {
+		t.Errorf("backoffs = %v, want %v", got, want)
+	}
+	// A service that ran for a while starts over.
+	sv.started = time.Now().Add(-48 * time.Hour)
+	s.exited(sv, true)
+	if sv.backoff != 2*time.Hour || !sv.pending {
+		t.Errorf("backoff after a stable run = %v, pending %v; want 2h, true", sv.backoff, sv.pending)
+	}
+
+	sv = &service{Service: Service{Name: "b", Restart: RestartOnFailure}, backoff: minBackoff}
+	if s.exited(sv, true); sv.pending {
+		t.Errorf("on-failure service is restarted after success")
+	}
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
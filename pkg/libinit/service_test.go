@@ -0,0 +1,56 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libinit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseServices(t *testing.T) {
+	got, err := ParseServices(strings.NewReader(`# network first
+dhcp  wait       /bbin/dhclient -ipv6=false
+
+sshd  always     /bbin/sshd -keys "/etc/ssh keys"
+job   once       /bin/job
+shell on-failure /bin/sh
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Service{
+		{Name: "dhcp", Args: []string{"/bbin/dhclient", "-ipv6=false"}, Wait: true},
+		{Name: "sshd", Args: []string{"/bbin/sshd", "-keys", "/etc/ssh keys"}, Restart: RestartAlways},
+		{Name: "job", Args: []string{"/bin/job"}},
+		{Name: "shell", Args: []string{"/bin/sh"}, Restart: RestartOnFailure},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseServices = %+v, want %+v", got, want)
+	}
+
+	for _, in := range []string{
+		"sshd always",
+		"sshd sometimes /bbin/sshd",
+		"a once /bin/a\na always /bin/b",
+	} {
+		if _, err := ParseServices(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseServices(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestRestartPolicyString(t *testing.T) {
+	for p, want := range map[RestartPolicy]string{
+		RestartNo:        "no",
+		RestartOnFailure: "on-failure",
+		RestartAlways:    "always",
+		RestartPolicy(7): "RestartPolicy(7)",
+	} {
+		if got := p.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(p), got, want)
+		}
+	}
+}
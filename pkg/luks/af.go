@@ -0,0 +1,44 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package luks
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// diffuse replaces b with its hash, computed block by block. Each block
+// of the hash's size is hashed together with its big endian block number.
+func diffuse(b []byte, newHash func() hash.Hash) {
+	h := newHash()
+	var iv [4]byte
+	for i := 0; i*h.Size() < len(b); i++ {
+		block := b[i*h.Size():]
+		if len(block) > h.Size() {
+			block = block[:h.Size()]
+		}
+		h.Reset()
+		binary.BigEndian.PutUint32(iv[:], uint32(i))
+		h.Write(iv[:])
+		h.Write(block)
+		copy(block, h.Sum(nil))
+	}
+}
+
+// afMerge recovers a key of keySize bytes from material that was split
+// into stripes with the LUKS1 anti-forensic splitter.
+func afMerge(material []byte, keySize, stripes int, newHash func() hash.Hash) []byte {
+	key := make([]byte, keySize)
+	for i := 0; i < stripes; i++ {
+		stripe := material[i*keySize : (i+1)*keySize]
+		for j := range key {
+			key[j] ^= stripe[j]
+		}
+		if i < stripes-1 {
+			diffuse(key, newHash)
+		}
+	}
+	return key
+}
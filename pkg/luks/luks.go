@@ -0,0 +1,483 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package luks reads LUKS2 headers and unlocks their keyslots.
+//
+// The on-disk format is described in the LUKS2 On-Disk Format
+// Specification, https://gitlab.com/cryptsetup/LUKS2-docs.
+package luks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/xts"
+)
+
+const (
+	// binHeaderSize is the size of the binary header.
+	binHeaderSize = 4096
+	// SectorSize is the unit of offsets in device-mapper tables.
+	SectorSize = 512
+)
+
+var (
+	magic1 = []byte("LUKS\xba\xbe")
+	magic2 = []byte("SKUL\xba\xbe")
+
+	// secondaryOffsets are the offsets the secondary header can be at.
+	secondaryOffsets = []int64{0x4000, 0x8000, 0x10000, 0x20000, 0x40000, 0x80000, 0x100000, 0x200000, 0x400000}
+
+	// ErrNoKey is returned when no keyslot can be unlocked with a
+	// passphrase.
+	ErrNoKey = errors.New("no key available with this passphrase")
+)
+
+// binHeader is the binary header that precedes the JSON metadata.
+type binHeader struct {
+	Magic       [6]byte
+	Version     uint16
+	HdrSize     uint64
+	SeqID       uint64
+	Label       [48]byte
+	ChecksumAlg [32]byte
+	Salt        [64]byte
+	UUID        [40]byte
+	Subsystem   [48]byte
+	HdrOffset   uint64
+	_           [184]byte
+	Checksum    [64]byte
+}
+
+// csumOffset is the offset of binHeader.Checksum.
+const csumOffset = 448
+
+// number is a 64 bit number, which LUKS2 stores as a JSON string.
+type number uint64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = number(v)
+	return nil
+}
+
+// KDF is a key derivation function.
+type KDF struct {
+	Type string `json:"type"`
+	Salt []byte `json:"salt"`
+	// Hash and Iterations are for pbkdf2.
+	Hash       string `json:"hash"`
+	Iterations int    `json:"iterations"`
+	// Time, Memory (in KiB) and CPUs are for argon2i and argon2id.
+	Time   uint32 `json:"time"`
+	Memory uint32 `json:"memory"`
+	CPUs   uint8  `json:"cpus"`
+}
+
+// AF is an anti-forensic splitter.
+type AF struct {
+	Type    string `json:"type"`
+	Stripes int    `json:"stripes"`
+	Hash    string `json:"hash"`
+}
+
+// Area is where a keyslot's key material is stored.
+type Area struct {
+	Type       string `json:"type"`
+	Offset     number `json:"offset"`
+	Size       number `json:"size"`
+	Encryption string `json:"encryption"`
+	KeySize    int    `json:"key_size"`
+}
+
+// Keyslot is an encrypted copy of the volume key.
+type Keyslot struct {
+	Type     string `json:"type"`
+	KeySize  int    `json:"key_size"`
+	Area     Area   `json:"area"`
+	KDF      KDF    `json:"kdf"`
+	AF       AF     `json:"af"`
+	Priority *int   `json:"priority"`
+}
+
+// Segment is an encrypted area of the device.
+type Segment struct {
+	Type   string `json:"type"`
+	Offset number `json:"offset"`
+	// Size is "dynamic" if the segment extends to the end of the device.
+	Size       string `json:"size"`
+	IVTweak    number `json:"iv_tweak"`
+	Encryption string `json:"encryption"`
+	SectorSize int    `json:"sector_size"`
+}
+
+// Digest verifies volume keys.
+type Digest struct {
+	Type       string   `json:"type"`
+	Keyslots   []string `json:"keyslots"`
+	Segments   []string `json:"segments"`
+	Hash       string   `json:"hash"`
+	Iterations int      `json:"iterations"`
+	Salt       []byte   `json:"salt"`
+	Digest     []byte   `json:"digest"`
+}
+
+// Metadata is the JSON metadata of a LUKS2 header.
+type Metadata struct {
+	Keyslots map[string]Keyslot `json:"keyslots"`
+	Segments map[string]Segment `json:"segments"`
+	Digests  map[string]Digest  `json:"digests"`
+	Config   struct {
+		JSONSize     number   `json:"json_size"`
+		KeyslotsSize number   `json:"keyslots_size"`
+		Flags        []string `json:"flags"`
+	} `json:"config"`
+}
+
+// Header is a LUKS2 header.
+type Header struct {
+	Version   int
+	SeqID     uint64
+	Label     string
+	UUID      string
+	Subsystem string
+	// Size is the size of the binary header and the JSON area.
+	Size uint64
+	Metadata
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// readHeaderAt reads and verifies the header at off.
+func readHeaderAt(r io.ReaderAt, off int64, want []byte) (*Header, error) {
+	bin := make([]byte, binHeaderSize)
+	if _, err := r.ReadAt(bin, off); err != nil {
+		return nil, err
+	}
+	var bh binHeader
+	if err := binary.Read(bytes.NewReader(bin), binary.BigEndian, &bh); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(bh.Magic[:], want) {
+		return nil, errors.New("not a LUKS header")
+	}
+	if bh.Version != 2 {
+		return nil, fmt.Errorf("LUKS version %d is not supported", bh.Version)
+	}
+	if bh.HdrOffset != uint64(off) {
+		return nil, fmt.Errorf("header at %#x claims to be at %#x", off, bh.HdrOffset)
+	}
+	if bh.HdrSize <= binHeaderSize || bh.HdrSize > 0x400000 {
+		return nil, fmt.Errorf("invalid header size %d", bh.HdrSize)
+	}
+
+	hdr := make([]byte, bh.HdrSize)
+	copy(hdr, bin)
+	if _, err := r.ReadAt(hdr[binHeaderSize:], off+binHeaderSize); err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(hdr, cString(bh.ChecksumAlg[:])); err != nil {
+		return nil, err
+	}
+
+	h := &Header{
+		Version:   int(bh.Version),
+		SeqID:     bh.SeqID,
+		Label:     cString(bh.Label[:]),
+		UUID:      cString(bh.UUID[:]),
+		Subsystem: cString(bh.Subsystem[:]),
+		Size:      bh.HdrSize,
+	}
+	if err := json.Unmarshal([]byte(cString(hdr[binHeaderSize:])), &h.Metadata); err != nil {
+		return nil, fmt.Errorf("parsing JSON metadata: %v", err)
+	}
+	return h, nil
+}
+
+// verifyChecksum checks the checksum of hdr, which is the checksum of the
+// header with the checksum field zeroed.
+func verifyChecksum(hdr []byte, alg string) error {
+	newHash, err := hashFunc(alg)
+	if err != nil {
+		return err
+	}
+	h := newHash()
+	csum := hdr[csumOffset : csumOffset+64]
+	h.Write(hdr[:csumOffset])
+	h.Write(make([]byte, len(csum)))
+	h.Write(hdr[csumOffset+len(csum):])
+	if sum := h.Sum(nil); !bytes.Equal(sum, csum[:len(sum)]) {
+		return errors.New("header checksum mismatch")
+	}
+	return nil
+}
+
+// ReadHeader reads the LUKS2 header of r. The primary and secondary header
+// are checked, and the valid one with the higher sequence ID is returned.
+func ReadHeader(r io.ReaderAt) (*Header, error) {
+	primary, perr := readHeaderAt(r, 0, magic1)
+	var secondary *Header
+	if perr == nil {
+		secondary, _ = readHeaderAt(r, int64(primary.Size), magic2)
+	} else {
+		for _, off := range secondaryOffsets {
+			if h, err := readHeaderAt(r, off, magic2); err == nil {
+				secondary = h
+				break
+			}
+		}
+	}
+	switch {
+	case primary == nil && secondary == nil:
+		return nil, perr
+	case secondary == nil:
+		return primary, nil
+	case primary == nil || secondary.SeqID > primary.SeqID:
+		return secondary, nil
+	}
+	return primary, nil
+}
+
+func hashFunc(name string) (func() hash.Hash, error) {
+	switch name {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported hash %q", name)
+}
+
+// deriveKey derives a key of size bytes from passphrase.
+func (k *KDF) deriveKey(passphrase []byte, size int) ([]byte, error) {
+	switch k.Type {
+	case "pbkdf2":
+		h, err := hashFunc(k.Hash)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(passphrase, k.Salt, k.Iterations, size, h), nil
+	case "argon2i":
+		return argon2.Key(passphrase, k.Salt, k.Time, k.Memory, k.CPUs, uint32(size)), nil
+	case "argon2id":
+		return argon2.IDKey(passphrase, k.Salt, k.Time, k.Memory, k.CPUs, uint32(size)), nil
+	}
+	return nil, fmt.Errorf("unsupported KDF %q", k.Type)
+}
+
+// decryptArea decrypts data, which starts at sector 0, with key.
+func decryptArea(data []byte, encryption string, key []byte) error {
+	if encryption != "aes-xts-plain64" {
+		return fmt.Errorf("unsupported encryption %q", encryption)
+	}
+	c, err := xts.NewCipher(aes.NewCipher, key)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(data); i += SectorSize {
+		s := data[i : i+SectorSize]
+		c.Decrypt(s, s, uint64(i/SectorSize))
+	}
+	return nil
+}
+
+// verify checks key against the digest of keyslot.
+func (m *Metadata) verify(keyslot string, key []byte) error {
+	for _, d := range m.Digests {
+		if !contains(d.Keyslots, keyslot) {
+			continue
+		}
+		if d.Type != "pbkdf2" {
+			return fmt.Errorf("unsupported digest %q", d.Type)
+		}
+		h, err := hashFunc(d.Hash)
+		if err != nil {
+			return err
+		}
+		sum := pbkdf2.Key(key, d.Salt, d.Iterations, len(d.Digest), h)
+		if subtle.ConstantTimeCompare(sum, d.Digest) != 1 {
+			return ErrNoKey
+		}
+		return nil
+	}
+	return fmt.Errorf("keyslot %s has no digest", keyslot)
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unlockKeyslot returns the volume key stored in keyslot id.
+func (h *Header) unlockKeyslot(r io.ReaderAt, id string, passphrase []byte) ([]byte, error) {
+	ks := h.Keyslots[id]
+	if ks.Type != "luks2" {
+		return nil, fmt.Errorf("unsupported keyslot type %q", ks.Type)
+	}
+	if ks.AF.Type != "luks1" {
+		return nil, fmt.Errorf("unsupported anti-forensic splitter %q", ks.AF.Type)
+	}
+	if ks.Area.Type != "raw" {
+		return nil, fmt.Errorf("unsupported keyslot area %q", ks.Area.Type)
+	}
+	afHash, err := hashFunc(ks.AF.Hash)
+	if err != nil {
+		return nil, err
+	}
+	size := ks.KeySize * ks.AF.Stripes
+	sectors := (size + SectorSize - 1) / SectorSize
+	if size <= 0 || uint64(sectors*SectorSize) > uint64(ks.Area.Size) {
+		return nil, fmt.Errorf("keyslot %s does not fit its area", id)
+	}
+
+	key, err := ks.KDF.deriveKey(passphrase, ks.Area.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	material := make([]byte, sectors*SectorSize)
+	if _, err := r.ReadAt(material, int64(ks.Area.Offset)); err != nil {
+		return nil, fmt.Errorf("reading keyslot %s: %v", id, err)
+	}
+	if err := decryptArea(material, ks.Area.Encryption, key); err != nil {
+		return nil, err
+	}
+	volumeKey := afMerge(material[:size], ks.KeySize, ks.AF.Stripes, afHash)
+	if err := h.verify(id, volumeKey); err != nil {
+		return nil, err
+	}
+	return volumeKey, nil
+}
+
+// keyslotIDs returns the IDs of the keyslots in the order they are tried:
+// high priority first, then in numerical order. Keyslots with priority 0
+// are only tried if they are the only ones.
+func (h *Header) keyslotIDs() []string {
+	prio := func(id string) int {
+		if p := h.Keyslots[id].Priority; p != nil {
+			return *p
+		}
+		return 1
+	}
+	var ids []string
+	for id := range h.Keyslots {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if pi, pj := prio(ids[i]), prio(ids[j]); pi != pj {
+			return pi > pj
+		}
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	for i, id := range ids {
+		if prio(id) == 0 && i > 0 {
+			return ids[:i]
+		}
+	}
+	return ids
+}
+
+// Unlock returns the volume key and the keyslot it was unlocked from. It
+// returns ErrNoKey if no keyslot can be unlocked with passphrase.
+func (h *Header) Unlock(r io.ReaderAt, passphrase []byte) ([]byte, string, error) {
+	for _, id := range h.keyslotIDs() {
+		key, err := h.unlockKeyslot(r, id, passphrase)
+		if err == ErrNoKey {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("keyslot %s: %v", id, err)
+		}
+		return key, id, nil
+	}
+	return nil, "", ErrNoKey
+}
+
+// Segment returns the data segment that is unlocked by keyslot. LUKS2
+// volumes that are not being reencrypted have one segment.
+func (h *Header) Segment(keyslot string) (*Segment, error) {
+	for _, d := range h.Digests {
+		if !contains(d.Keyslots, keyslot) {
+			continue
+		}
+		for _, id := range d.Segments {
+			if s, ok := h.Segments[id]; ok && s.Type == "crypt" {
+				return &s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("keyslot %s unlocks no crypt segment", keyslot)
+}
+
+// Sectors returns the size of the segment in 512 byte sectors on a device
+// of devSize bytes.
+func (s *Segment) Sectors(devSize int64) (uint64, error) {
+	off := uint64(s.Offset)
+	if s.Size == "dynamic" {
+		if uint64(devSize) <= off {
+			return 0, fmt.Errorf("device of %d bytes ends before the segment at %d", devSize, off)
+		}
+		return (uint64(devSize) - off) / SectorSize, nil
+	}
+	size, err := strconv.ParseUint(s.Size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid segment size %q", s.Size)
+	}
+	if off+size > uint64(devSize) {
+		return 0, fmt.Errorf("segment at %d of %d bytes does not fit the device of %d bytes", off, size, devSize)
+	}
+	return size / SectorSize, nil
+}
+
+// CryptParams returns the parameters of a dm-crypt target that maps the
+// segment of device with key.
+func (s *Segment) CryptParams(key []byte, device string, allowDiscards bool) string {
+	var opts []string
+	if allowDiscards {
+		opts = append(opts, "allow_discards")
+	}
+	if s.SectorSize != 0 && s.SectorSize != SectorSize {
+		// LUKS2 counts IVs in units of the sector size.
+		opts = append(opts, fmt.Sprintf("sector_size:%d", s.SectorSize), "iv_large_sectors")
+	}
+	p := fmt.Sprintf("%s %x %d %s %d", s.Encryption, key, s.IVTweak, device, uint64(s.Offset)/SectorSize)
+	if len(opts) > 0 {
+		p += fmt.Sprintf(" %d %s", len(opts), strings.Join(opts, " "))
+	}
+	return p
+}
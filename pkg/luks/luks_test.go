@@ -0,0 +1,321 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package luks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/xts"
+)
+
+const (
+	hdrSize     = 0x4000
+	keySize     = 32
+	stripes     = 4000
+	areaSize    = 0x20000
+	dataOffset  = 0x48000
+	testUUID    = "5f5fa7b6-1b8b-4c3a-8f6c-0f4e3c6a9d21"
+	dataSectors = 8
+)
+
+// afSplit is the inverse of afMerge.
+func afSplit(key []byte, stripes int, newHash func() hash.Hash, rnd *rand.Rand) []byte {
+	material := make([]byte, len(key)*stripes)
+	d := make([]byte, len(key))
+	for i := 0; i < stripes-1; i++ {
+		stripe := material[i*len(key) : (i+1)*len(key)]
+		rnd.Read(stripe)
+		for j := range d {
+			d[j] ^= stripe[j]
+		}
+		diffuse(d, newHash)
+	}
+	last := material[(stripes-1)*len(key):]
+	for j := range last {
+		last[j] = d[j] ^ key[j]
+	}
+	return material
+}
+
+func encryptSectors(t *testing.T, data, key []byte) {
+	c, err := xts.NewCipher(aes.NewCipher, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i += SectorSize {
+		s := data[i : i+SectorSize]
+		c.Encrypt(s, s, uint64(i/SectorSize))
+	}
+}
+
+// writeHeader writes a binary header followed by metadata at off.
+func writeHeader(t *testing.T, img []byte, off int64, magic []byte, seqID uint64, metadata string) {
+	bh := binHeader{Version: 2, HdrSize: hdrSize, SeqID: seqID, HdrOffset: uint64(off)}
+	copy(bh.Magic[:], magic)
+	copy(bh.Label[:], "root")
+	copy(bh.ChecksumAlg[:], "sha256")
+	copy(bh.UUID[:], testUUID)
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, bh); err != nil {
+		t.Fatal(err)
+	}
+	hdr := img[off : off+hdrSize]
+	for i := range hdr {
+		hdr[i] = 0
+	}
+	copy(hdr, buf.Bytes())
+	copy(hdr[binHeaderSize:], metadata)
+	sum := sha256.Sum256(hdr)
+	copy(hdr[csumOffset:], sum[:])
+}
+
+// testImage is a LUKS2 volume like cryptsetup luksFormat creates it, with
+// a pbkdf2 keyslot 0 for "pass0" and an argon2id keyslot 1 for "pass1".
+type testImage struct {
+	img       []byte
+	volumeKey []byte
+	plaintext []byte
+}
+
+func newTestImage(t *testing.T) *testImage {
+	rnd := rand.New(rand.NewSource(0))
+	ti := &testImage{
+		img:       make([]byte, dataOffset+dataSectors*SectorSize),
+		volumeKey: make([]byte, keySize),
+		plaintext: make([]byte, dataSectors*SectorSize),
+	}
+	rnd.Read(ti.volumeKey)
+	rnd.Read(ti.plaintext)
+
+	salts := make([][]byte, 3)
+	for i := range salts {
+		salts[i] = make([]byte, 32)
+		rnd.Read(salts[i])
+	}
+	b64 := base64.StdEncoding.EncodeToString
+	keys := [][]byte{
+		pbkdf2.Key([]byte("pass0"), salts[0], 1000, keySize, sha256.New),
+		argon2.IDKey([]byte("pass1"), salts[1], 1, 32, 1, keySize),
+	}
+	for i, key := range keys {
+		area := ti.img[2*hdrSize+i*areaSize:]
+		m := afSplit(ti.volumeKey, stripes, sha256.New, rnd)
+		m = append(m, make([]byte, (SectorSize-len(m)%SectorSize)%SectorSize)...)
+		encryptSectors(t, m, key)
+		copy(area, m)
+	}
+	digest := pbkdf2.Key(ti.volumeKey, salts[2], 1000, 32, sha256.New)
+
+	data := append([]byte(nil), ti.plaintext...)
+	encryptSectors(t, data, ti.volumeKey)
+	copy(ti.img[dataOffset:], data)
+
+	metadata := fmt.Sprintf(`{"keyslots":{`+
+		`"0":{"type":"luks2","key_size":32,"af":{"type":"luks1","stripes":4000,"hash":"sha256"},`+
+		`"area":{"type":"raw","offset":"%d","size":"%d","encryption":"aes-xts-plain64","key_size":32},`+
+		`"kdf":{"type":"pbkdf2","hash":"sha256","iterations":1000,"salt":"%s"}},`+
+		`"1":{"type":"luks2","key_size":32,"af":{"type":"luks1","stripes":4000,"hash":"sha256"},`+
+		`"area":{"type":"raw","offset":"%d","size":"%d","encryption":"aes-xts-plain64","key_size":32},`+
+		`"kdf":{"type":"argon2id","time":1,"memory":32,"cpus":1,"salt":"%s"}}},`+
+		`"tokens":{},`+
+		`"segments":{"0":{"type":"crypt","offset":"%d","size":"dynamic","iv_tweak":"0","encryption":"aes-xts-plain64","sector_size":512}},`+
+		`"digests":{"0":{"type":"pbkdf2","keyslots":["0","1"],"segments":["0"],"hash":"sha256","iterations":1000,"salt":"%s","digest":"%s"}},`+
+		`"config":{"json_size":"12288","keyslots_size":"%d"}}`,
+		2*hdrSize, areaSize, b64(salts[0]),
+		2*hdrSize+areaSize, areaSize, b64(salts[1]),
+		dataOffset, b64(salts[2]), b64(digest),
+		dataOffset-2*hdrSize)
+	writeHeader(t, ti.img, 0, magic1, 1, metadata)
+	writeHeader(t, ti.img, hdrSize, magic2, 1, metadata)
+	return ti
+}
+
+func TestReadHeader(t *testing.T) {
+	ti := newTestImage(t)
+	h, err := ReadHeader(bytes.NewReader(ti.img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.UUID != testUUID || h.Label != "root" || h.Size != hdrSize {
+		t.Errorf("ReadHeader = UUID %q, label %q, size %d, want %q, %q, %d", h.UUID, h.Label, h.Size, testUUID, "root", hdrSize)
+	}
+	if len(h.Keyslots) != 2 || h.Keyslots["1"].KDF.Type != "argon2id" || h.Keyslots["0"].Area.Offset != 2*hdrSize {
+		t.Errorf("ReadHeader keyslots = %+v", h.Keyslots)
+	}
+	if s := h.Segments["0"]; s.Offset != dataOffset || s.Size != "dynamic" || s.SectorSize != 512 {
+		t.Errorf("ReadHeader segment = %+v", s)
+	}
+
+	// A corrupt primary header falls back to the secondary.
+	ti.img[binHeaderSize+1] ^= 1
+	if _, err := ReadHeader(bytes.NewReader(ti.img)); err != nil {
+		t.Errorf("ReadHeader with corrupt primary header: %v", err)
+	}
+	ti.img[hdrSize+binHeaderSize+1] ^= 1
+	if _, err := ReadHeader(bytes.NewReader(ti.img)); err == nil {
+		t.Errorf("ReadHeader with corrupt headers succeeded, want error")
+	}
+
+	if _, err := ReadHeader(bytes.NewReader(make([]byte, 2*hdrSize))); err == nil {
+		t.Errorf("ReadHeader of zeros succeeded, want error")
+	}
+}
+
+func TestReadHeaderSeqID(t *testing.T) {
+	ti := newTestImage(t)
+	writeHeader(t, ti.img, hdrSize, magic2, 2, `{"keyslots":{},"segments":{},"digests":{}}`)
+	h, err := ReadHeader(bytes.NewReader(ti.img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.SeqID != 2 || len(h.Keyslots) != 0 {
+		t.Errorf("ReadHeader = seqid %d with %d keyslots, want the secondary header", h.SeqID, len(h.Keyslots))
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	ti := newTestImage(t)
+	r := bytes.NewReader(ti.img)
+	h, err := ReadHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		pass    string
+		keyslot string
+	}{
+		{"pass0", "0"},
+		{"pass1", "1"},
+	} {
+		key, keyslot, err := h.Unlock(r, []byte(tt.pass))
+		if err != nil {
+			t.Errorf("Unlock(%q) = %v", tt.pass, err)
+			continue
+		}
+		if !bytes.Equal(key, ti.volumeKey) || keyslot != tt.keyslot {
+			t.Errorf("Unlock(%q) = (%x, %s), want (%x, %s)", tt.pass, key, keyslot, ti.volumeKey, tt.keyslot)
+		}
+	}
+	if _, _, err := h.Unlock(r, []byte("wrong")); err != ErrNoKey {
+		t.Errorf("Unlock with wrong passphrase = %v, want %v", err, ErrNoKey)
+	}
+
+	// The key decrypts the data segment.
+	key, keyslot, _ := h.Unlock(r, []byte("pass0"))
+	s, err := h.Segment(keyslot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(nil), ti.img[s.Offset:]...)
+	if err := decryptArea(data, s.Encryption, key); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, ti.plaintext) {
+		t.Errorf("data segment does not decrypt with the volume key")
+	}
+	n, err := s.Sectors(int64(len(ti.img)))
+	if err != nil || n != dataSectors {
+		t.Errorf("Sectors = (%d, %v), want %d", n, err, dataSectors)
+	}
+}
+
+func TestSectors(t *testing.T) {
+	for _, tt := range []struct {
+		s       Segment
+		devSize int64
+		want    uint64
+		err     bool
+	}{
+		{s: Segment{Offset: 4096, Size: "dynamic"}, devSize: 8192, want: 8},
+		{s: Segment{Offset: 4096, Size: "dynamic"}, devSize: 4096, err: true},
+		{s: Segment{Offset: 4096, Size: "2048"}, devSize: 8192, want: 4},
+		{s: Segment{Offset: 4096, Size: "8192"}, devSize: 8192, err: true},
+		{s: Segment{Offset: 4096, Size: "x"}, devSize: 8192, err: true},
+	} {
+		got, err := tt.s.Sectors(tt.devSize)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("%+v.Sectors(%d) = (%d, %v), want (%d, err %v)", tt.s, tt.devSize, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestKeyslotIDs(t *testing.T) {
+	zero, two := 0, 2
+	h := &Header{Metadata: Metadata{Keyslots: map[string]Keyslot{
+		"10": {},
+		"2":  {},
+		"3":  {Priority: &zero},
+		"7":  {Priority: &two},
+	}}}
+	if got, want := h.keyslotIDs(), []string{"7", "2", "10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyslotIDs = %v, want %v", got, want)
+	}
+}
+
+func TestReadMetadataFixture(t *testing.T) {
+	// testdata/luks2.json is the metadata of a volume created with
+	// cryptsetup luksFormat --type luks2.
+	b, err := ioutil.ReadFile("testdata/luks2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := make([]byte, 2*hdrSize)
+	writeHeader(t, img, 0, magic1, 3, string(b))
+	h, err := ReadHeader(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := h.Keyslots["0"]
+	if ks.KeySize != 64 || ks.AF.Stripes != 4000 || ks.KDF.Type != "argon2id" || ks.KDF.Memory != 1048576 || ks.KDF.CPUs != 4 || len(ks.KDF.Salt) != 32 {
+		t.Errorf("keyslot 0 = %+v", ks)
+	}
+	if ks.Area.Offset != 32768 || ks.Area.Size != 258048 {
+		t.Errorf("keyslot 0 area = %+v", ks.Area)
+	}
+	if s := h.Segments["0"]; s.Offset != 16777216 || s.Encryption != "aes-xts-plain64" {
+		t.Errorf("segment 0 = %+v", s)
+	}
+	if d := h.Digests["0"]; d.Iterations != 129774 || len(d.Digest) != 32 {
+		t.Errorf("digest 0 = %+v", d)
+	}
+	if h.Config.KeyslotsSize != 16744448 {
+		t.Errorf("keyslots_size = %d, want 16744448", h.Config.KeyslotsSize)
+	}
+}
+
+func TestCryptParams(t *testing.T) {
+	key := []byte{0xde, 0xad, 0xbe, 0xef}
+	for _, tt := range []struct {
+		s        Segment
+		discards bool
+		want     string
+	}{
+		{
+			s:    Segment{Offset: 16777216, Encryption: "aes-xts-plain64", SectorSize: 512},
+			want: "aes-xts-plain64 deadbeef 0 /dev/sda2 32768",
+		},
+		{
+			s:        Segment{Offset: 16777216, IVTweak: 8, Encryption: "aes-xts-plain64", SectorSize: 4096},
+			discards: true,
+			want:     "aes-xts-plain64 deadbeef 8 /dev/sda2 32768 3 allow_discards sector_size:4096 iv_large_sectors",
+		},
+	} {
+		if got := tt.s.CryptParams(key, "/dev/sda2", tt.discards); got != tt.want {
+			t.Errorf("CryptParams = %q, want %q", got, tt.want)
+		}
+	}
+}
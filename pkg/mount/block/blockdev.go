@@ -7,6 +7,7 @@ package block
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -270,29 +271,14 @@ func GetBlockDevices() (BlockDevices, error) {
 }
 
 func getFSUUID(devpath string) (string, error) {
-	file, err := os.Open(devpath)
+	fs, err := probeDevice(devpath)
 	if err != nil {
 		return "", err
 	}
-	defer file.Close()
-
-	fsuuid, err := tryFAT32(file)
-	if err == nil {
-		return fsuuid, nil
-	}
-	fsuuid, err = tryFAT16(file)
-	if err == nil {
-		return fsuuid, nil
-	}
-	fsuuid, err = tryEXT4(file)
-	if err == nil {
-		return fsuuid, nil
-	}
-	fsuuid, err = tryXFS(file)
-	if err == nil {
-		return fsuuid, nil
+	if fs.UUID == "" {
+		return "", fmt.Errorf("%s file system has no UUID", fs.Type)
 	}
-	return "", fmt.Errorf("unknown UUID (not vfat, ext4, nor xfs)")
+	return fs.UUID, nil
 }
 
 // See https://www.nongnu.org/ext2-doc/ext2.html#DISK-ORGANISATION.
@@ -429,6 +415,223 @@ func tryXFS(file io.ReaderAt) (string, error) {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
+// See https://btrfs.wiki.kernel.org/index.php/On-disk_Format#Superblock.
+const (
+	// Offset of superblock in partition.
+	btrfsSprblkOff = 0x10000
+
+	btrfsMagic    = "_BHRfS_M"
+	btrfsMagicOff = btrfsSprblkOff + 0x40
+
+	btrfsUUIDOff = btrfsSprblkOff + 0x20
+)
+
+func tryBtrfs(file io.ReaderAt) (string, error) {
+	if !hasMagic(file, btrfsMagicOff, btrfsMagic) {
+		return "", fmt.Errorf("btrfs magic not found")
+	}
+	return readUUID(file, btrfsUUIDOff)
+}
+
+// Only swap areas made for 4KiB pages are found, see union swap_header in
+// include/linux/swap.h of Linux.
+const (
+	swapMagic = "SWAPSPACE2"
+	// The magic ends the first page.
+	swapMagicOff = 4096 - 10
+
+	swapUUIDOff = 0x40c
+)
+
+func trySwap(file io.ReaderAt) (string, error) {
+	if !hasMagic(file, swapMagicOff, swapMagic) {
+		return "", fmt.Errorf("swap magic not found")
+	}
+	return readUUID(file, swapUUIDOff)
+}
+
+// See https://wiki.osdev.org/ISO_9660#The_Primary_Volume_Descriptor.
+const (
+	// Offset of the primary volume descriptor.
+	iso9660PVDOff = 0x8000
+
+	iso9660Magic    = "CD001"
+	iso9660MagicOff = iso9660PVDOff + 1
+
+	// Offset of the creation date, YYYYMMDDHHMMSScc in ASCII.
+	iso9660DateOff  = iso9660PVDOff + 0x32d
+	iso9660DateSize = 16
+)
+
+// tryISO9660 uses the creation date of the volume as UUID, like blkid. It
+// is empty if the volume has no date.
+func tryISO9660(file io.ReaderAt) (string, error) {
+	if !hasMagic(file, iso9660MagicOff, iso9660Magic) {
+		return "", fmt.Errorf("iso9660 magic not found")
+	}
+
+	b := make([]byte, iso9660DateSize)
+	if _, err := file.ReadAt(b, iso9660DateOff); err != nil {
+		return "", err
+	}
+	if b[0] == 0 || string(b[:4]) == "0000" {
+		return "", nil
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s-%s-%s", b[0:4], b[4:6], b[6:8], b[8:10], b[10:12], b[12:14], b[14:]), nil
+}
+
+// See https://dr-emann.github.io/squashfs/#superblock.
+const squashfsMagic = "hsqs"
+
+// trySquashfs returns an empty UUID, squashfs has none.
+func trySquashfs(file io.ReaderAt) (string, error) {
+	if !hasMagic(file, 0, squashfsMagic) {
+		return "", fmt.Errorf("squashfs magic not found")
+	}
+	return "", nil
+}
+
+// hasMagic returns whether file holds magic at off.
+func hasMagic(file io.ReaderAt, off int64, magic string) bool {
+	b := make([]byte, len(magic))
+	_, err := file.ReadAt(b, off)
+	return err == nil && string(b) == magic
+}
+
+// readUUID reads the 16 byte UUID at off. It is empty if it is all zeros,
+// like that of a swap area made without one.
+func readUUID(file io.ReaderAt, off int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := file.ReadAt(b, off); err != nil {
+		return "", err
+	}
+	if bytes.Equal(b, make([]byte, 16)) {
+		return "", nil
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Offsets and sizes of file system labels, see the references above.
+const (
+	ext2SprblkLabelOff  = 120
+	ext2SprblkLabelSize = 16
+	fat16LabelOff       = 0x2b
+	fat32LabelOff       = 0x47
+	fatLabelSize        = 11
+	xfsLabelOff         = 108
+	xfsLabelSize        = 12
+	btrfsLabelOff       = btrfsSprblkOff + 0x12b
+	btrfsLabelSize      = 256
+	swapLabelOff        = 0x41c
+	swapLabelSize       = 16
+	iso9660LabelOff     = iso9660PVDOff + 0x28
+	iso9660LabelSize    = 32
+)
+
+// fileSystem is a file system found by its superblock.
+type fileSystem struct {
+	fsType string
+	// try returns the UUID of the file system, or an error if file
+	// does not hold it.
+	try       func(file io.ReaderAt) (string, error)
+	labelOff  int64
+	labelSize int
+}
+
+// fileSystems are tried in order.
+var fileSystems = []fileSystem{
+	{"vfat", tryFAT32, fat32LabelOff, fatLabelSize},
+	{"vfat", tryFAT16, fat16LabelOff, fatLabelSize},
+	{"ext4", tryEXT4, ext2SprblkOff + ext2SprblkLabelOff, ext2SprblkLabelSize},
+	{"xfs", tryXFS, xfsLabelOff, xfsLabelSize},
+	{"btrfs", tryBtrfs, btrfsLabelOff, btrfsLabelSize},
+	{"swap", trySwap, swapLabelOff, swapLabelSize},
+	{"iso9660", tryISO9660, iso9660LabelOff, iso9660LabelSize},
+	{"squashfs", trySquashfs, 0, 0},
+}
+
+// See https://www.nongnu.org/ext2-doc/ext2.html#s-feature-compat.
+const (
+	ext2SprblkFeatureCompatOff = 92
+
+	ext3FeatureCompatHasJournal = 0x4
+	ext4FeatureIncompatExtents  = 0x40
+	ext4FeatureIncompat64Bit    = 0x80
+	ext4FeatureIncompatFlexBG   = 0x200
+)
+
+// ext2Type tells ext2, ext3 and ext4 apart by their features.
+func ext2Type(file io.ReaderAt) string {
+	// The compatible features are followed by the incompatible ones.
+	b := make([]byte, 8)
+	if _, err := file.ReadAt(b, ext2SprblkOff+ext2SprblkFeatureCompatOff); err != nil {
+		return "ext4"
+	}
+	compat, incompat := binary.LittleEndian.Uint32(b[0:4]), binary.LittleEndian.Uint32(b[4:8])
+	switch {
+	case incompat&(ext4FeatureIncompatExtents|ext4FeatureIncompat64Bit|ext4FeatureIncompatFlexBG) != 0:
+		return "ext4"
+	case compat&ext3FeatureCompatHasJournal != 0:
+		return "ext3"
+	}
+	return "ext2"
+}
+
+// FSInfo is what a superblock says about its file system.
+type FSInfo struct {
+	Type  string
+	UUID  string
+	Label string
+}
+
+// ProbeFS returns the type, UUID and label of the vfat, ext2/3/4, xfs,
+// btrfs, swap, iso9660 or squashfs file system in file. UUID and label
+// may be empty.
+func ProbeFS(file io.ReaderAt) (FSInfo, error) {
+	for _, fs := range fileSystems {
+		uuid, err := fs.try(file)
+		if err != nil {
+			continue
+		}
+		info := FSInfo{Type: fs.fsType, UUID: uuid}
+		if info.Type == "ext4" {
+			info.Type = ext2Type(file)
+		}
+		b := make([]byte, fs.labelSize)
+		if _, err := file.ReadAt(b, fs.labelOff); err != nil {
+			return FSInfo{}, err
+		}
+		// Labels are NUL-padded, except FAT and iso9660 labels, which
+		// are space-padded.
+		info.Label = strings.TrimRight(string(b), "\x00 ")
+		// FAT file systems without a label say so.
+		if info.Label == "NO NAME" {
+			info.Label = ""
+		}
+		return info, nil
+	}
+	return FSInfo{}, fmt.Errorf("unknown file system (not vfat, ext2/3/4, xfs, btrfs, swap, iso9660, nor squashfs)")
+}
+
+func probeDevice(devpath string) (FSInfo, error) {
+	file, err := os.Open(devpath)
+	if err != nil {
+		return FSInfo{}, err
+	}
+	defer file.Close()
+	return ProbeFS(file)
+}
+
+// getFSLabel returns the label of the file system on devpath, which may be
+// empty.
+func getFSLabel(devpath string) (string, error) {
+	fs, err := probeDevice(devpath)
+	if err != nil {
+		return "", err
+	}
+	return fs.Label, nil
+}
+
 // BlockDevices is a list of block devices.
 type BlockDevices []*BlockDev
 
@@ -527,6 +730,19 @@ func (b BlockDevices) FilterFSUUID(fsuuid string) BlockDevices {
 	return partitions
 }
 
+// FilterFSLabel returns a list of BlockDev objects whose underlying block
+// device has a vfat, ext2/3/4, xfs, btrfs, swap or iso9660 filesystem with
+// the given label.
+func (b BlockDevices) FilterFSLabel(label string) BlockDevices {
+	partitions := make(BlockDevices, 0)
+	for _, device := range b {
+		if l, err := getFSLabel(device.DevicePath()); err == nil && l == label {
+			partitions = append(partitions, device)
+		}
+	}
+	return partitions
+}
+
 // filterUsingSymlink resolves the given symlink and filters out all block
 // devices which do not match the resolved symlink. The intended purpose is to
 // filter using a symlink like "/dev/disk/by-partlabel/UBUNTU".
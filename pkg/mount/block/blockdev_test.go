@@ -5,7 +5,11 @@
 package block
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 
@@ -110,3 +114,149 @@ func TestParsePCIBlockList(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFSLabel(t *testing.T) {
+	ext4 := make([]byte, 4096)
+	binary.LittleEndian.PutUint16(ext4[ext2SprblkOff+ext2SprblkMagicOff:], ext2SprblkMagic)
+	copy(ext4[ext2SprblkOff+ext2SprblkLabelOff:], "rootfs")
+	fat32 := make([]byte, 4096)
+	copy(fat32[fat32MagicOff:], fat32Magic)
+	copy(fat32[fat32LabelOff:], "EFI        ")
+	fat16 := make([]byte, 4096)
+	copy(fat16[fat16MagicOff:], fat16Magic)
+	copy(fat16[fat16LabelOff:], "NO NAME    ")
+	xfs := make([]byte, 4096)
+	copy(xfs, xfsMagic)
+	copy(xfs[xfsLabelOff:], "data")
+
+	for _, tt := range []struct {
+		name  string
+		image []byte
+		label string
+		err   bool
+	}{
+		{name: "ext4", image: ext4, label: "rootfs"},
+		{name: "fat32", image: fat32, label: "EFI"},
+		{name: "fat16 without label", image: fat16, label: ""},
+		{name: "xfs", image: xfs, label: "data"},
+		{name: "unknown", image: make([]byte, 4096), err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "blockdev-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(tt.image); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			label, err := getFSLabel(f.Name())
+			if (err != nil) != tt.err {
+				t.Fatalf("getFSLabel() = %v, want error %t", err, tt.err)
+			}
+			if label != tt.label {
+				t.Errorf("getFSLabel() = %q, want %q", label, tt.label)
+			}
+		})
+	}
+}
+
+func TestGetFSUUID(t *testing.T) {
+	xfs := make([]byte, 4096)
+	copy(xfs, xfsMagic)
+	copy(xfs[xfsUUIDOff:], []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15})
+
+	for _, tt := range []struct {
+		name  string
+		image []byte
+		uuid  string
+		err   bool
+	}{
+		{name: "xfs", image: xfs, uuid: "00010203-0405-0607-0809-0a0b0c0d0e0f"},
+		// squashfs has no UUID, which is not an empty one.
+		{name: "squashfs", image: []byte("hsqs\x00\x00"), err: true},
+		{name: "unknown", image: make([]byte, 4096), err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "blockdev-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(tt.image); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			uuid, err := getFSUUID(f.Name())
+			if (err != nil) != tt.err {
+				t.Fatalf("getFSUUID() = %v, want error %t", err, tt.err)
+			}
+			if uuid != tt.uuid {
+				t.Errorf("getFSUUID() = %q, want %q", uuid, tt.uuid)
+			}
+		})
+	}
+}
+
+func TestProbeFS(t *testing.T) {
+	uuid := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	ext := func(compat, incompat uint32) []byte {
+		b := make([]byte, 4096)
+		binary.LittleEndian.PutUint16(b[ext2SprblkOff+ext2SprblkMagicOff:], ext2SprblkMagic)
+		binary.LittleEndian.PutUint32(b[ext2SprblkOff+ext2SprblkFeatureCompatOff:], compat)
+		binary.LittleEndian.PutUint32(b[ext2SprblkOff+ext2SprblkFeatureCompatOff+4:], incompat)
+		copy(b[ext2SprblkOff+ext2SprblkUUIDOff:], uuid)
+		copy(b[ext2SprblkOff+ext2SprblkLabelOff:], "root")
+		return b
+	}
+	fat32 := make([]byte, 512)
+	copy(fat32[fat32MagicOff:], fat32Magic)
+	copy(fat32[fat32IDOff:], []byte{0xef, 0xbe, 0xad, 0xde})
+	copy(fat32[fat32LabelOff:], "EFI        ")
+	btrfs := make([]byte, btrfsSprblkOff+4096)
+	copy(btrfs[btrfsMagicOff:], btrfsMagic)
+	copy(btrfs[btrfsUUIDOff:], uuid)
+	copy(btrfs[btrfsLabelOff:], "pool")
+	swap := make([]byte, 8192)
+	copy(swap[swapMagicOff:], swapMagic)
+	copy(swap[swapLabelOff:], "myswap")
+	iso := make([]byte, iso9660PVDOff+2048)
+	copy(iso[iso9660MagicOff:], iso9660Magic)
+	copy(iso[iso9660LabelOff:], "CDROM                           ")
+	copy(iso[iso9660DateOff:], "2021030412345600")
+	isoNoDate := make([]byte, len(iso))
+	copy(isoNoDate, iso)
+	copy(isoNoDate[iso9660DateOff:], "0000000000000000")
+
+	for _, tt := range []struct {
+		name  string
+		image []byte
+		want  FSInfo
+		err   bool
+	}{
+		{name: "ext4", image: ext(ext3FeatureCompatHasJournal, ext4FeatureIncompatExtents), want: FSInfo{"ext4", "00010203-0405-0607-0809-0a0b0c0d0e0f", "root"}},
+		{name: "ext3", image: ext(ext3FeatureCompatHasJournal, 0), want: FSInfo{"ext3", "00010203-0405-0607-0809-0a0b0c0d0e0f", "root"}},
+		{name: "ext2", image: ext(0, 0), want: FSInfo{"ext2", "00010203-0405-0607-0809-0a0b0c0d0e0f", "root"}},
+		{name: "fat32", image: fat32, want: FSInfo{"vfat", "dead-beef", "EFI"}},
+		{name: "btrfs", image: btrfs, want: FSInfo{"btrfs", "00010203-0405-0607-0809-0a0b0c0d0e0f", "pool"}},
+		{name: "swap", image: swap, want: FSInfo{"swap", "", "myswap"}},
+		{name: "iso9660", image: iso, want: FSInfo{"iso9660", "2021-03-04-12-34-56-00", "CDROM"}},
+		{name: "iso9660 without date", image: isoNoDate, want: FSInfo{"iso9660", "", "CDROM"}},
+		{name: "squashfs", image: []byte("hsqs\x00\x00"), want: FSInfo{Type: "squashfs"}},
+		{name: "short", image: []byte("XF"), err: true},
+		{name: "unknown", image: make([]byte, btrfsSprblkOff+4096), err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProbeFS(bytes.NewReader(tt.image))
+			if (err != nil) != tt.err {
+				t.Fatalf("ProbeFS() = %v, want error %t", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ProbeFS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -152,5 +152,79 @@ func unpackIdentify(s statusBlock, d dataBlock, w wordBlock) *Info {
 	info.SecurityStatus = DiskSecurityStatus(binary.LittleEndian.Uint16(d[256:258]))
 
 	info.TrustedComputingSupport = w[48]
+
+	word := func(n int) uint16 {
+		return binary.LittleEndian.Uint16(d[2*n : 2*n+2])
+	}
+	bit := func(n int, b uint) bool {
+		return word(n)&(1<<b) != 0
+	}
+
+	info.MajorVersion = majorVersion(word(80))
+	info.RotationRate = word(217)
+	info.LogicalSectorSize, info.PhysicalSectorSize = sectorSizes(word(106), uint32(word(117))|uint32(word(118))<<16)
+	if bit(84, 8) && bit(87, 8) {
+		info.WWN = uint64(word(108))<<48 | uint64(word(109))<<32 | uint64(word(110))<<16 | uint64(word(111))
+	}
+
+	// Words 82-84 report supported, 85-87 enabled features. Words 83,
+	// 84 and 87 are only valid if bit 14 is set and bit 15 clear.
+	valid := func(n int) bool {
+		return word(n)&0xc000 == 0x4000
+	}
+	f := &info.Features
+	f.SMART, f.SMARTEnabled = bit(82, 0), bit(85, 0)
+	f.Security, f.SecurityEnabled = bit(82, 1), bit(85, 1)
+	f.WriteCache, f.WriteCacheEnabled = bit(82, 5), bit(85, 5)
+	f.ReadLookAhead, f.ReadLookAheadEnabled = bit(82, 6), bit(85, 6)
+	f.LBA48 = valid(83) && bit(83, 10)
+	f.TRIM = bit(169, 0)
+	// Word 76 is reserved for parallel ATA, where it is 0 or 0xffff.
+	if sata := word(76); sata != 0 && sata != 0xffff {
+		f.NCQ = bit(76, 8)
+		if f.NCQ {
+			f.NCQDepth = int(word(75)&0x1f) + 1
+		}
+		for i, speed := range []string{"1.5", "3.0", "6.0"} {
+			if bit(76, uint(i+1)) {
+				f.SATASpeeds = append(f.SATASpeeds, speed+" Gb/s")
+			}
+		}
+	}
 	return &info
 }
+
+// majorVersion returns the newest standard in the major version word 80.
+func majorVersion(w uint16) string {
+	if w == 0 || w == 0xffff {
+		return ""
+	}
+	names := []string{
+		1: "ATA-1", "ATA-2", "ATA-3", "ATA/ATAPI-4", "ATA/ATAPI-5", "ATA/ATAPI-6",
+		"ATA/ATAPI-7", "ATA8-ACS", "ACS-2", "ACS-3", "ACS-4", "ACS-5",
+	}
+	for b := len(names) - 1; b > 0; b-- {
+		if w&(1<<uint(b)) != 0 {
+			return names[b]
+		}
+	}
+	return ""
+}
+
+// sectorSizes decodes the logical and physical sector size from word 106
+// and the logical sector size in words, from words 117-118.
+func sectorSizes(w106 uint16, logicalWords uint32) (uint32, uint32) {
+	logical := uint32(oldSchoolBlockLen)
+	// Word 106 is only valid if bit 14 is set and bit 15 clear.
+	if w106&0xc000 != 0x4000 {
+		return logical, logical
+	}
+	if w106&(1<<12) != 0 && logicalWords != 0 {
+		logical = 2 * logicalWords
+	}
+	physical := logical
+	if w106&(1<<13) != 0 {
+		physical <<= w106 & 0xf
+	}
+	return logical, physical
+}
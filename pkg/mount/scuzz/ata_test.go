@@ -5,6 +5,8 @@
 package scuzz
 
 import (
+	"encoding/binary"
+	"reflect"
 	"testing"
 )
 
@@ -51,3 +53,96 @@ func TestMustLBA(t *testing.T) {
 		t.Errorf("good mustLBA: got %v, want nil", err)
 	}
 }
+
+func TestUnpackIdentify(t *testing.T) {
+	var d dataBlock
+	set := func(n int, v uint16) {
+		binary.LittleEndian.PutUint16(d[2*n:], v)
+	}
+	// ATA strings are space padded with the bytes of each pair swapped.
+	str := func(b []byte, s string) {
+		for i := range b {
+			b[i] = ' '
+		}
+		copy(b, s)
+		for i := 0; i < len(b); i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	str(d[20:40], "S3YHNX0K123456")
+	str(d[46:54], "RVT04B6Q")
+	str(d[54:94], "Samsung SSD 860 EVO 2TB")
+	set(75, 31)
+	set(76, 0x010e)
+	set(80, 0x07f0)
+	set(82, 0x7463)
+	set(83, 0x7501)
+	set(84, 0x4163)
+	set(85, 0x7461)
+	set(87, 0x4163)
+	set(106, 0x6003)
+	set(108, 0x5002)
+	set(109, 0x538e)
+	set(110, 0x4050)
+	set(111, 0x1234)
+	set(169, 0x0001)
+	set(217, 1)
+	binary.LittleEndian.PutUint64(d[200:208], 1953525168)
+
+	w, err := d.toWordBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := unpackIdentify(statusBlock{}, d, w)
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"Model", i.Model, "Samsung SSD 860 EVO 2TB"},
+		{"Serial", i.Serial, "S3YHNX0K123456"},
+		{"FirmwareRevision", i.FirmwareRevision, "RVT04B6Q"},
+		{"NumberSectors", i.NumberSectors, uint64(1953525168)},
+		{"MajorVersion", i.MajorVersion, "ACS-3"},
+		{"RotationRate", i.RotationRate, uint16(1)},
+		{"LogicalSectorSize", i.LogicalSectorSize, uint32(512)},
+		{"PhysicalSectorSize", i.PhysicalSectorSize, uint32(4096)},
+		{"WWN", i.WWN, uint64(0x5002538e40501234)},
+		{"Features", i.Features, Features{
+			SMART:                true,
+			SMARTEnabled:         true,
+			Security:             true,
+			WriteCache:           true,
+			WriteCacheEnabled:    true,
+			ReadLookAhead:        true,
+			ReadLookAheadEnabled: true,
+			LBA48:                true,
+			TRIM:                 true,
+			NCQ:                  true,
+			NCQDepth:             32,
+			SATASpeeds:           []string{"1.5 Gb/s", "3.0 Gb/s", "6.0 Gb/s"},
+		}},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestSectorSizes(t *testing.T) {
+	for _, tt := range []struct {
+		w106          uint16
+		words         uint32
+		logical, phys uint32
+	}{
+		{w106: 0, logical: 512, phys: 512},
+		{w106: 0xffff, logical: 512, phys: 512},
+		{w106: 0x4000, logical: 512, phys: 512},
+		{w106: 0x6003, logical: 512, phys: 4096},
+		{w106: 0x5000, words: 2048, logical: 4096, phys: 4096},
+	} {
+		l, p := sectorSizes(tt.w106, tt.words)
+		if l != tt.logical || p != tt.phys {
+			t.Errorf("sectorSizes(%#x, %d) = (%d, %d), want (%d, %d)", tt.w106, tt.words, l, p, tt.logical, tt.phys)
+		}
+	}
+}
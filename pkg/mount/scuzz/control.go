@@ -39,6 +39,18 @@ type Info struct {
 	SecurityStatus          DiskSecurityStatus
 	TrustedComputingSupport uint16
 
+	// MajorVersion is the newest ATA standard the disk supports, e.g.
+	// ACS-3.
+	MajorVersion string
+	// RotationRate is the nominal media rotation rate in rpm. It is 1
+	// for non-rotating media, e.g. SSDs, and 0 if not reported.
+	RotationRate       uint16
+	LogicalSectorSize  uint32
+	PhysicalSectorSize uint32
+	// WWN is the World Wide Name, or 0 if the disk has none.
+	WWN      uint64
+	Features Features
+
 	Serial           string
 	Model            string
 	FirmwareRevision string
@@ -51,6 +63,25 @@ type Info struct {
 	OrigFirmwareRevision string
 }
 
+// Features are the supported and enabled features of a disk.
+type Features struct {
+	SMART                bool
+	SMARTEnabled         bool
+	Security             bool
+	SecurityEnabled      bool
+	WriteCache           bool
+	WriteCacheEnabled    bool
+	ReadLookAhead        bool
+	ReadLookAheadEnabled bool
+	LBA48                bool
+	TRIM                 bool
+
+	// These are only set for SATA disks.
+	NCQ        bool
+	NCQDepth   int
+	SATASpeeds []string
+}
+
 // Disk is the interface to a disk, with operations to create packets and
 // operate on them.
 type Disk interface {
@@ -153,6 +153,33 @@ func SameFilesystem(path1, path2 string) (bool, error) {
 	return stat1.Dev == stat2.Dev, nil
 }
 
+// statfs is a variable so it can be changed in tests.
+var statfs = unix.Statfs
+
+// checkSwitchRoot checks that switching from / to newRootDir is safe: / has
+// to be an initramfs, i.e. a tmpfs or ramfs, since everything on it is
+// deleted, newRootDir has to be a mount point, and init has to exist in it.
+func checkSwitchRoot(newRootDir, init string) error {
+	var st unix.Statfs_t
+	if err := statfs("/", &st); err != nil {
+		return fmt.Errorf("switch_root: %v", err)
+	}
+	if st.Type != unix.TMPFS_MAGIC && st.Type != unix.RAMFS_MAGIC {
+		return fmt.Errorf("switch_root: / is not a tmpfs or ramfs (type %#x), refusing to delete it", st.Type)
+	}
+	if same, err := SameFilesystem("/", newRootDir); err != nil {
+		return fmt.Errorf("switch_root: %v", err)
+	} else if same {
+		return fmt.Errorf("switch_root: %s is not a mount point", newRootDir)
+	}
+	// init may be a symlink that is absolute in the new root, so it is
+	// not followed.
+	if _, err := os.Lstat(filepath.Join(newRootDir, init)); err != nil {
+		return fmt.Errorf("switch_root: init not found in new root: %v", err)
+	}
+	return nil
+}
+
 // SwitchRoot makes newRootDir the new root directory of the system.
 //
 // To be exact, it makes newRootDir the new root directory of the calling
@@ -160,15 +187,20 @@ func SameFilesystem(path1, path2 string) (bool, error) {
 //
 // It moves special mounts (dev, proc, sys, run) to the new directory, then
 // does a chroot, moves the root mount to the new directory and finally
-// DELETES EVERYTHING in the old root and execs the given init.
-func SwitchRoot(newRootDir string, init string) error {
+// DELETES EVERYTHING in the old root and execs the given init with args.
+//
+// The old root has to be a tmpfs or ramfs, newRootDir a mount point, and
+// init has to exist in it; otherwise nothing is changed.
+func SwitchRoot(newRootDir string, init string, args ...string) error {
+	if err := checkSwitchRoot(newRootDir, init); err != nil {
+		return err
+	}
 	err := newRoot(newRootDir)
 	if err != nil {
 		return err
 	}
-	return execInit(init)
+	return execInit(init, args)
 }
-
 // newRoot is the "first half" of SwitchRoot - that is, it creates special mounts
 // in newRoot, chroot's there, and RECURSIVELY DELETES everything in the old root.
 func newRoot(newRootDir string) error {
@@ -206,9 +238,9 @@ func newRoot(newRootDir string) error {
 // execInit is generally only useful as part of SwitchRoot or similar.
 // It exec's the given binary in place of the current binary, necessary so that
 // the new binary can be pid 1.
-func execInit(init string) error {
+func execInit(init string, args []string) error {
 	log.Printf("switch_root: executing init")
-	if err := unix.Exec(init, []string{init}, []string{}); err != nil {
+	if err := unix.Exec(init, append([]string{init}, args...), os.Environ()); err != nil {
 		return fmt.Errorf("switch_root: exec failed %v", err)
 	}
 	return nil
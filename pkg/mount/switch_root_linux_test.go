@@ -0,0 +1,59 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckSwitchRoot(t *testing.T) {
+	defer func(old func(string, *unix.Statfs_t) error) { statfs = old }(statfs)
+	fsType := int64(unix.TMPFS_MAGIC)
+	statfs = func(path string, st *unix.Statfs_t) error {
+		st.Type = fsType
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "switch_root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// /proc is a mount point that exists everywhere tests run.
+	for _, tt := range []struct {
+		fsType  int64
+		newRoot string
+		init    string
+		want    string
+	}{
+		{fsType: unix.TMPFS_MAGIC, newRoot: "/proc", init: "/version"},
+		{fsType: unix.RAMFS_MAGIC, newRoot: "/proc", init: "/version"},
+		{fsType: unix.EXT4_SUPER_MAGIC, newRoot: "/proc", init: "/version", want: "not a tmpfs or ramfs"},
+		{fsType: unix.TMPFS_MAGIC, newRoot: "/proc", init: "/sbin/init", want: "init not found"},
+		{fsType: unix.TMPFS_MAGIC, newRoot: "/nonexistent", init: "/sbin/init", want: "no such file"},
+	} {
+		fsType = tt.fsType
+		err := checkSwitchRoot(tt.newRoot, tt.init)
+		if tt.want == "" && err != nil {
+			t.Errorf("checkSwitchRoot(%q, %q) with fs type %#x = %v, want nil", tt.newRoot, tt.init, tt.fsType, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("checkSwitchRoot(%q, %q) with fs type %#x = %v, want error containing %q", tt.newRoot, tt.init, tt.fsType, err, tt.want)
+		}
+	}
+
+	fsType = unix.TMPFS_MAGIC
+	if same, err := SameFilesystem("/", dir); err == nil && same {
+		if err := checkSwitchRoot(dir, "/"); err == nil || !strings.Contains(err.Error(), "not a mount point") {
+			t.Errorf("checkSwitchRoot(%q, \"/\") = %v, want not a mount point error", dir, err)
+		}
+	}
+}
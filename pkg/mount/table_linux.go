@@ -0,0 +1,111 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PseudoFS are the types of filesystems that are not backed by storage,
+// like proc, and are left alone when all filesystems are unmounted.
+var PseudoFS = map[string]bool{
+	"autofs":      true,
+	"binfmt_misc": true,
+	"bpf":         true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"configfs":    true,
+	"debugfs":     true,
+	"devpts":      true,
+	"devtmpfs":    true,
+	"efivarfs":    true,
+	"fusectl":     true,
+	"hugetlbfs":   true,
+	"mqueue":      true,
+	"nfsd":        true,
+	"proc":        true,
+	"pstore":      true,
+	"rpc_pipefs":  true,
+	"securityfs":  true,
+	"sysfs":       true,
+	"tracefs":     true,
+}
+
+// Entry is a line of fstab(5), or of a mount table like /proc/self/mounts,
+// which has the same format.
+type Entry struct {
+	Source  string
+	Target  string
+	FSType  string
+	Options []string
+}
+
+// HasOption returns whether the entry has the option o.
+func (e *Entry) HasOption(o string) bool {
+	for _, opt := range e.Options {
+		if opt == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Unescape replaces the octal escapes fstab and the mount table use for
+// spaces and other special characters, e.g. \040.
+func Unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ParseTable parses the entries of fstab or of a mount table, in order,
+// skipping comments and empty lines. The dump and pass fields are ignored.
+func ParseTable(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 3 || len(f) > 6 {
+			return nil, fmt.Errorf("line %d: %q does not have 3 to 6 fields", n, line)
+		}
+		e := Entry{
+			Source: Unescape(f[0]),
+			Target: Unescape(f[1]),
+			FSType: f[2],
+		}
+		if len(f) > 3 {
+			for _, o := range strings.Split(f[3], ",") {
+				if o != "" {
+					e.Options = append(e.Options, Unescape(o))
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mount
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	fstab := `# <file system> <mount point> <type> <options> <dump> <pass>
+UUID=1234-abcd  /     ext4  errors=remount-ro  0  1
+
+/dev/sda2	/my\040data	vfat	noauto,,user	0 0
+tmpfs /tmp tmpfs
+proc /proc proc defaults 0
+`
+	got, err := ParseTable(strings.NewReader(fstab))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{"UUID=1234-abcd", "/", "ext4", []string{"errors=remount-ro"}},
+		{"/dev/sda2", "/my data", "vfat", []string{"noauto", "user"}},
+		{"tmpfs", "/tmp", "tmpfs", nil},
+		{"proc", "/proc", "proc", []string{"defaults"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTable() = %v, want %v", got, want)
+	}
+	if !got[1].HasOption("user") || got[1].HasOption("auto") {
+		t.Errorf("HasOption() of %v is wrong", got[1].Options)
+	}
+
+	for _, bad := range []string{"/dev/sda1 /\n", "a b c d e f g\n"} {
+		if _, err := ParseTable(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseTable(%q) = nil, want error", bad)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for in, want := range map[string]string{
+		"/mnt":        "/mnt",
+		`/my\040data`: "/my data",
+		`a\011b\134c`: "a\tb\\c",
+		`\04`:         `\04`,
+		`\999`:        `\999`,
+		`/end\040`:    "/end ",
+	} {
+		if got := Unescape(in); got != want {
+			t.Errorf("Unescape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
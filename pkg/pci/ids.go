@@ -15,9 +15,8 @@ type idMap map[string]Vendor
 
 var ids idMap
 
-// newIDs contains the plain text contents of pci.ids. It returns
-// a map to be used as lookup from hex ID to human readable lable.
-// We do not admit of the possibility of error, any failure
+// newIDs returns a map to be used as lookup from hex ID to human
+// readable lable. We do not admit of the possibility of error, any failure
 // should be caught by the test. We might just want to just always
 // create ids since the most common use of pci will be with names,
 // not numbers.
@@ -27,7 +26,12 @@ func newIDs() idMap {
 		return ids
 	}
 
-	var pciids = []byte(`0001  SafeNet (wrong ID)
+	ids = parse(pciids)
+	return ids
+}
+
+// pciids contains the plain text contents of pci.ids.
+var pciids = []byte(`0001  SafeNet (wrong ID)
 0010  Allied Telesis, Inc (Wrong ID)
 	8139  AT-2500TX V3 Ethernet
 001c  PEAK-System Technik GmbH
@@ -16407,6 +16411,3 @@ fffe  VMWare Inc (temporary ID)
 	0710  Virtual SVGA
 ffff  Illegal Vendor ID
 `)
-	ids = parse(pciids)
-	return ids
-}
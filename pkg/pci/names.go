@@ -0,0 +1,325 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pci
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IDs is a database of vendor, device, subsystem and class names, as found
+// in a pci.ids file. IDs are kept as numbers, which takes a lot less memory
+// than strings.
+type IDs struct {
+	vendors map[uint16]*vendorNames
+	classes map[uint8]*classNames
+}
+
+type vendorNames struct {
+	name    string
+	devices map[uint16]*deviceNames
+}
+
+type deviceNames struct {
+	name string
+	// subsystems is keyed by subvendor<<16 | subdevice.
+	subsystems map[uint32]string
+}
+
+type classNames struct {
+	name       string
+	subclasses map[uint8]*subclassNames
+}
+
+type subclassNames struct {
+	name    string
+	progIfs map[uint8]string
+}
+
+// classids are the device classes from pci.ids, which the built in
+// database does not have otherwise.
+var classids = []byte(`C 00  Unclassified device
+	00  Non-VGA unclassified device
+	01  VGA compatible unclassified device
+C 01  Mass storage controller
+	00  SCSI storage controller
+	01  IDE interface
+	02  Floppy disk controller
+	04  RAID bus controller
+	05  ATA controller
+	06  SATA controller
+		01  AHCI 1.0
+	07  Serial Attached SCSI controller
+	08  Non-Volatile memory controller
+		02  NVM Express
+	80  Mass storage controller
+C 02  Network controller
+	00  Ethernet controller
+	07  Infiniband controller
+	80  Network controller
+C 03  Display controller
+	00  VGA compatible controller
+	02  3D controller
+	80  Display controller
+C 04  Multimedia controller
+	00  Multimedia video controller
+	01  Multimedia audio controller
+	03  Audio device
+	80  Multimedia controller
+C 05  Memory controller
+	00  RAM memory
+	01  FLASH memory
+	80  Memory controller
+C 06  Bridge
+	00  Host bridge
+	01  ISA bridge
+	04  PCI bridge
+	07  CardBus bridge
+	80  Bridge
+C 07  Communication controller
+	00  Serial controller
+		02  16550
+	01  Parallel controller
+	03  Modem
+	80  Communication controller
+C 08  Generic system peripheral
+	00  PIC
+	01  DMA controller
+	02  Timer
+	03  RTC
+	05  SD Host controller
+	06  IOMMU
+	80  System peripheral
+C 09  Input device controller
+C 0a  Docking station
+C 0b  Processor
+C 0c  Serial bus controller
+	00  FireWire (IEEE 1394)
+	03  USB controller
+		00  UHCI
+		10  OHCI
+		20  EHCI
+		30  XHCI
+	04  Fibre Channel
+	05  SMBus
+	07  IPMI Interface
+	80  Serial bus controller
+C 0d  Wireless controller
+	11  Bluetooth
+	80  Network controller
+C 0e  Intelligent controller
+C 0f  Satellite communications controller
+C 10  Encryption controller
+C 11  Signal processing controller
+	80  Signal processing controller
+C 12  Processing accelerators
+C 13  Non-Essential Instrumentation
+C 40  Coprocessor
+C ff  Unassigned class
+`)
+
+var (
+	builtinIDs     *IDs
+	builtinIDsOnce sync.Once
+)
+
+// BuiltinIDs returns the built in database. It has most vendors and
+// devices, the main classes and no subsystems.
+func BuiltinIDs() *IDs {
+	builtinIDsOnce.Do(func() {
+		// Neither can fail, which the tests check.
+		builtinIDs, _ = ReadIDs(bytes.NewReader(pciids))
+		builtinIDs.readClasses(bufio.NewScanner(bytes.NewReader(classids)))
+	})
+	return builtinIDs
+}
+
+// LoadIDs reads a pci.ids file, such as /usr/share/misc/pci.ids. If path
+// is empty, it returns the built in database.
+func LoadIDs(path string) (*IDs, error) {
+	if path == "" {
+		return BuiltinIDs(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadIDs(f)
+}
+
+// hexPrefix parses the n hex digits at the start of s, which have to be
+// followed by two spaces and a name.
+func hexPrefix(s string, n int) (uint64, string, bool) {
+	if len(s) < n+2 || s[n:n+2] != "  " {
+		return 0, "", false
+	}
+	v, err := strconv.ParseUint(s[:n], 16, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return v, s[n+2:], true
+}
+
+// ReadIDs reads a database in pci.ids format. Lines that can not be
+// parsed are skipped, as they may come from a newer format.
+func ReadIDs(r io.Reader) (*IDs, error) {
+	ids := &IDs{vendors: map[uint16]*vendorNames{}, classes: map[uint8]*classNames{}}
+	s := bufio.NewScanner(r)
+	var v *vendorNames
+	var d *deviceNames
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		switch {
+		case line == "" || line[0] == '#':
+		case strings.HasPrefix(line, "C "):
+			// The classes follow the vendors.
+			ids.readClasses(s)
+			return ids, s.Err()
+		case strings.HasPrefix(line, "\t\t"):
+			if d == nil || len(line) < 12 {
+				continue
+			}
+			sv, err1 := strconv.ParseUint(line[2:6], 16, 16)
+			sd, name, ok := hexPrefix(line[7:], 4)
+			if err1 != nil || !ok || line[6] != ' ' {
+				continue
+			}
+			if d.subsystems == nil {
+				d.subsystems = map[uint32]string{}
+			}
+			d.subsystems[uint32(sv)<<16|uint32(sd)] = name
+		case line[0] == '\t':
+			id, name, ok := hexPrefix(line[1:], 4)
+			if v == nil || !ok {
+				d = nil
+				continue
+			}
+			d = &deviceNames{name: name}
+			v.devices[uint16(id)] = d
+		default:
+			id, name, ok := hexPrefix(line, 4)
+			v, d = nil, nil
+			if !ok {
+				continue
+			}
+			v = &vendorNames{name: name, devices: map[uint16]*deviceNames{}}
+			ids.vendors[uint16(id)] = v
+		}
+	}
+	return ids, s.Err()
+}
+
+// readClasses reads the class section of pci.ids. The scanner has just
+// read the first class line.
+func (ids *IDs) readClasses(s *bufio.Scanner) {
+	var c *classNames
+	var sc *subclassNames
+	for ok := true; ok; ok = s.Scan() {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		switch {
+		case line == "" || line[0] == '#':
+		case strings.HasPrefix(line, "C "):
+			id, name, ok := hexPrefix(line[2:], 2)
+			c, sc = nil, nil
+			if !ok {
+				continue
+			}
+			c = &classNames{name: name, subclasses: map[uint8]*subclassNames{}}
+			ids.classes[uint8(id)] = c
+		case strings.HasPrefix(line, "\t\t"):
+			id, name, ok := hexPrefix(line[2:], 2)
+			if sc == nil || !ok {
+				continue
+			}
+			if sc.progIfs == nil {
+				sc.progIfs = map[uint8]string{}
+			}
+			sc.progIfs[uint8(id)] = name
+		case line[0] == '\t':
+			id, name, ok := hexPrefix(line[1:], 2)
+			if c == nil || !ok {
+				sc = nil
+				continue
+			}
+			sc = &subclassNames{name: name}
+			c.subclasses[uint8(id)] = sc
+		default:
+			// Some other section.
+			c, sc = nil, nil
+		}
+	}
+}
+
+// LookupVendor returns the name of a vendor.
+func (ids *IDs) LookupVendor(vendor uint16) (string, bool) {
+	v, ok := ids.vendors[vendor]
+	if !ok {
+		return "", false
+	}
+	return v.name, true
+}
+
+// LookupDevice returns the name of a device.
+func (ids *IDs) LookupDevice(vendor, device uint16) (string, bool) {
+	v, ok := ids.vendors[vendor]
+	if !ok {
+		return "", false
+	}
+	d, ok := v.devices[device]
+	if !ok {
+		return "", false
+	}
+	return d.name, true
+}
+
+// LookupSubsystem returns the name of a subsystem of a device.
+func (ids *IDs) LookupSubsystem(vendor, device, subvendor, subdevice uint16) (string, bool) {
+	v, ok := ids.vendors[vendor]
+	if !ok {
+		return "", false
+	}
+	d, ok := v.devices[device]
+	if !ok {
+		return "", false
+	}
+	n, ok := d.subsystems[uint32(subvendor)<<16|uint32(subdevice)]
+	return n, ok
+}
+
+// LookupClass returns the most specific name of a 24 bit class code, as
+// found in the class file in sysfs: the name of its subclass, or, if that
+// is not known, of its class. The programming interface, the low 8 bits,
+// is only named by LookupProgIf.
+func (ids *IDs) LookupClass(class uint32) (string, bool) {
+	c, ok := ids.classes[uint8(class>>16)]
+	if !ok {
+		return "", false
+	}
+	if sc, ok := c.subclasses[uint8(class>>8)]; ok {
+		return sc.name, true
+	}
+	return c.name, true
+}
+
+// LookupProgIf returns the name of the programming interface of a 24 bit
+// class code.
+func (ids *IDs) LookupProgIf(class uint32) (string, bool) {
+	c, ok := ids.classes[uint8(class>>16)]
+	if !ok {
+		return "", false
+	}
+	sc, ok := c.subclasses[uint8(class>>8)]
+	if !ok {
+		return "", false
+	}
+	n, ok := sc.progIfs[uint8(class)]
+	return n, ok
+}
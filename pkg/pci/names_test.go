@@ -0,0 +1,186 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pci
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testIDs = `#
+# List of PCI ID's
+#
+
+1af4  Red Hat, Inc.
+	1000  Virtio network device
+		1af4 0001  Virtio network device
+	1041  Virtio 1.0 network device
+8086  Intel Corporation
+	2922  82801IR/IO/IH (ICH9R/DO/DH) 6 port SATA Controller [AHCI mode]
+		1af4 1100  QEMU Virtual Machine
+bogus line
+
+# List of known device classes, subclasses and programming interfaces
+C 01  Mass storage controller
+	06  SATA controller
+		01  AHCI 1.0
+C 02  Network controller
+	00  Ethernet controller
+`
+
+func TestReadIDs(t *testing.T) {
+	ids, err := ReadIDs(strings.NewReader(testIDs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(what, got string, ok bool, want string) {
+		t.Helper()
+		if (want != "") != ok || got != want {
+			t.Errorf("%s = (%q, %v), want %q", what, got, ok, want)
+		}
+	}
+
+	n, ok := ids.LookupVendor(0x1af4)
+	check("LookupVendor(1af4)", n, ok, "Red Hat, Inc.")
+	n, ok = ids.LookupVendor(0x10de)
+	check("LookupVendor(10de)", n, ok, "")
+	n, ok = ids.LookupDevice(0x1af4, 0x1041)
+	check("LookupDevice(1af4, 1041)", n, ok, "Virtio 1.0 network device")
+	n, ok = ids.LookupDevice(0x8086, 0x1237)
+	check("LookupDevice(8086, 1237)", n, ok, "")
+	n, ok = ids.LookupSubsystem(0x8086, 0x2922, 0x1af4, 0x1100)
+	check("LookupSubsystem(8086, 2922, 1af4, 1100)", n, ok, "QEMU Virtual Machine")
+	n, ok = ids.LookupSubsystem(0x1af4, 0x1041, 0x1af4, 0x0001)
+	check("LookupSubsystem(1af4, 1041, 1af4, 0001)", n, ok, "")
+	n, ok = ids.LookupClass(0x020000)
+	check("LookupClass(020000)", n, ok, "Ethernet controller")
+	n, ok = ids.LookupClass(0x018000)
+	check("LookupClass(018000)", n, ok, "Mass storage controller")
+	n, ok = ids.LookupClass(0x030000)
+	check("LookupClass(030000)", n, ok, "")
+	n, ok = ids.LookupProgIf(0x010601)
+	check("LookupProgIf(010601)", n, ok, "AHCI 1.0")
+	n, ok = ids.LookupProgIf(0x010600)
+	check("LookupProgIf(010600)", n, ok, "")
+}
+
+func TestLookupIDs(t *testing.T) {
+	ids, err := ReadIDs(strings.NewReader(`#
+# List of PCI ID's
+#
+
+1af4  Red Hat, Inc.
+	1000  Virtio network device
+		1af4 0001  Virtio network device
+	1041  Virtio network device
+8086  Intel Corporation
+
+# List of known device classes
+C 00  Unclassified device
+	00  Non-VGA unclassified device
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		vendor, device uint16
+		wantV, wantD   string
+	}{
+		{0x1af4, 0x1000, "Red Hat, Inc.", "Virtio network device"},
+		{0x1af4, 0x1041, "Red Hat, Inc.", "Virtio network device"},
+		// A subsystem is not a device.
+		{0x1af4, 0x0001, "Red Hat, Inc.", ""},
+		{0x8086, 0x1237, "Intel Corporation", ""},
+		{0x0000, 0x0000, "", ""},
+	} {
+		v, vok := ids.LookupVendor(tt.vendor)
+		d, dok := ids.LookupDevice(tt.vendor, tt.device)
+		if v != tt.wantV || vok != (tt.wantV != "") || d != tt.wantD || dok != (tt.wantD != "") {
+			t.Errorf("Lookup(%04x, %04x) = (%q, %v), (%q, %v), want %q, %q", tt.vendor, tt.device, v, vok, d, dok, tt.wantV, tt.wantD)
+		}
+	}
+	for _, tt := range []struct {
+		class          uint32
+		want, wantProg string
+	}{
+		{0x000000, "Non-VGA unclassified device", ""},
+		{0x000100, "Unclassified device", ""},
+		{0x010000, "", ""},
+	} {
+		n, ok := ids.LookupClass(tt.class)
+		if n != tt.want || ok != (tt.want != "") {
+			t.Errorf("LookupClass(%06x) = (%q, %v), want %q", tt.class, n, ok, tt.want)
+		}
+		n, ok = ids.LookupProgIf(tt.class)
+		if n != tt.wantProg || ok != (tt.wantProg != "") {
+			t.Errorf("LookupProgIf(%06x) = (%q, %v), want %q", tt.class, n, ok, tt.wantProg)
+		}
+	}
+}
+
+func TestBuiltinClasses(t *testing.T) {
+	ids := BuiltinIDs()
+	for _, tt := range []struct {
+		class          uint32
+		want, wantProg string
+	}{
+		{0x010802, "Non-Volatile memory controller", "NVM Express"},
+		{0x018000, "Mass storage controller", ""},
+		{0x020000, "Ethernet controller", ""},
+		{0x030000, "VGA compatible controller", ""},
+		{0x0c0330, "USB controller", "XHCI"},
+		{0x7f0000, "", ""},
+	} {
+		n, ok := ids.LookupClass(tt.class)
+		if n != tt.want || ok != (tt.want != "") {
+			t.Errorf("LookupClass(%06x) = (%q, %v), want %q", tt.class, n, ok, tt.want)
+		}
+		n, ok = ids.LookupProgIf(tt.class)
+		if n != tt.wantProg || ok != (tt.wantProg != "") {
+			t.Errorf("LookupProgIf(%06x) = (%q, %v), want %q", tt.class, n, ok, tt.wantProg)
+		}
+	}
+}
+
+func TestBuiltinIDs(t *testing.T) {
+	ids, err := LoadIDs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := ids.LookupDevice(0x8086, 0x100e); !ok || n != "82540EM Gigabit Ethernet Controller" {
+		t.Errorf("LookupDevice(8086, 100e) = (%q, %v), want 82540EM Gigabit Ethernet Controller", n, ok)
+	}
+	if n, ok := ids.LookupClass(0x010601); !ok || n != "SATA controller" {
+		t.Errorf("LookupClass(010601) = (%q, %v), want SATA controller", n, ok)
+	}
+	if len(ids.vendors) != len(newIDs()) {
+		t.Errorf("got %d vendors, want %d", len(ids.vendors), len(newIDs()))
+	}
+}
+
+func TestLoadIDs(t *testing.T) {
+	d, err := ioutil.TempDir("", "pciids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	p := filepath.Join(d, "pci.ids")
+	if err := ioutil.WriteFile(p, []byte(testIDs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := LoadIDs(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.vendors) != 2 || len(ids.classes) != 2 {
+		t.Errorf("got %d vendors and %d classes, want 2 and 2", len(ids.vendors), len(ids.classes))
+	}
+	if _, err := LoadIDs(filepath.Join(d, "nonexistent")); err == nil {
+		t.Errorf("LoadIDs(nonexistent): got nil, want error")
+	}
+}
@@ -15,6 +15,8 @@ func isHex(b byte) bool {
 
 // scan searches for Vendor and Device lines from the input *bufio.Scanner based
 // on pci.ids format. Found Vendors and Devices are added to the input ids map.
+// Comments, subsystems and the device classes at the end of a full pci.ids
+// are skipped.
 func scan(s *bufio.Scanner, ids map[string]Vendor) {
 	var currentVendor string
 	var line string
@@ -23,10 +25,13 @@ func scan(s *bufio.Scanner, ids map[string]Vendor) {
 		line = s.Text()
 
 		switch {
-		case isHex(line[0]) && isHex(line[1]):
+		case len(line) >= 6 && isHex(line[0]) && isHex(line[1]) && isHex(line[2]) && isHex(line[3]):
 			currentVendor = line[:4]
 			ids[currentVendor] = Vendor{Name: line[6:], Devices: make(map[string]Device)}
-		case currentVendor != "" && line[0] == '\t' && isHex(line[1]) && isHex(line[3]):
+		case len(line) >= 2 && line[0] == 'C' && line[1] == ' ':
+			// The classes follow the vendors.
+			return
+		case currentVendor != "" && len(line) >= 7 && line[0] == '\t' && isHex(line[1]) && isHex(line[3]):
 			ids[currentVendor].Devices[line[1:5]] = Device(line[7:])
 		}
 	}
@@ -35,12 +35,49 @@ func OpenRTC() (*RTC, error) {
 	return nil, errors.New("no RTC device found")
 }
 
+// Open opens the RTC device at path, e.g. /dev/rtc1.
+func Open(path string) (*RTC, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RTC{f}, nil
+}
+
+// Close closes the RTC device.
+func (r *RTC) Close() error {
+	return r.file.Close()
+}
+
+// Read returns the time of the RTC, which keeps UTC.
 func (r *RTC) Read() (time.Time, error) {
+	return r.ReadIn(time.UTC)
+}
+
+// ReadIn returns the time of the RTC, which keeps the time of loc, e.g.
+// time.Local for an RTC shared with Windows.
+func (r *RTC) ReadIn(loc *time.Location) (time.Time, error) {
 	rt, err := unix.IoctlGetRTCTime(int(r.file.Fd()))
 	if err != nil {
 		return time.Time{}, err
 	}
+	return fromRTCTime(rt, loc), nil
+}
 
+// Set sets the RTC to the date and time of tu in its location.
+func (r *RTC) Set(tu time.Time) error {
+	rt := toRTCTime(tu)
+	return unix.IoctlSetRTCTime(int(r.file.Fd()), &rt)
+}
+
+// SetIn sets the RTC, which keeps the time of loc, to t.
+func (r *RTC) SetIn(t time.Time, loc *time.Location) error {
+	return r.Set(t.In(loc))
+}
+
+// fromRTCTime converts rt, the time in loc, to a time.Time. The kernel
+// driver has already decoded the BCD registers of the RTC.
+func fromRTCTime(rt *unix.RTCTime, loc *time.Location) time.Time {
 	return time.Date(int(rt.Year)+1900,
 		time.Month(rt.Mon+1),
 		int(rt.Mday),
@@ -48,19 +85,19 @@ func (r *RTC) Read() (time.Time, error) {
 		int(rt.Min),
 		int(rt.Sec),
 		0,
-		time.UTC), nil
+		loc)
 }
 
-func (r *RTC) Set(tu time.Time) error {
-	rt := unix.RTCTime{Sec: int32(tu.Second()),
-		Min:   int32(tu.Minute()),
-		Hour:  int32(tu.Hour()),
-		Mday:  int32(tu.Day()),
-		Mon:   int32(tu.Month() - 1),
-		Year:  int32(tu.Year() - 1900),
-		Wday:  int32(0),
-		Yday:  int32(0),
+// toRTCTime converts the date and time of t in its location to a struct
+// rtc_time.
+func toRTCTime(t time.Time) unix.RTCTime {
+	return unix.RTCTime{Sec: int32(t.Second()),
+		Min:   int32(t.Minute()),
+		Hour:  int32(t.Hour()),
+		Mday:  int32(t.Day()),
+		Mon:   int32(t.Month() - 1),
+		Year:  int32(t.Year() - 1900),
+		Wday:  int32(t.Weekday()),
+		Yday:  int32(t.YearDay() - 1),
 		Isdst: int32(0)}
-
-	return unix.IoctlSetRTCTime(int(r.file.Fd()), &rt)
 }
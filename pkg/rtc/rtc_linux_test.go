@@ -0,0 +1,42 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestRTCTime(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	for _, tt := range []struct {
+		t   time.Time
+		loc *time.Location
+		rt  unix.RTCTime
+	}{
+		{
+			t:   time.Date(2021, time.March, 1, 12, 30, 45, 0, time.UTC),
+			loc: time.UTC,
+			rt:  unix.RTCTime{Sec: 45, Min: 30, Hour: 12, Mday: 1, Mon: 2, Year: 121, Wday: 1, Yday: 59},
+		},
+		{
+			// An RTC in local time keeps 00:15 on New Year's Day in
+			// Berlin, when it is still 1999 in UTC.
+			t:   time.Date(1999, time.December, 31, 23, 15, 0, 0, time.UTC),
+			loc: cet,
+			rt:  unix.RTCTime{Sec: 0, Min: 15, Hour: 0, Mday: 1, Mon: 0, Year: 100, Wday: 6, Yday: 0},
+		},
+	} {
+		if got := toRTCTime(tt.t.In(tt.loc)); got != tt.rt {
+			t.Errorf("toRTCTime(%v) = %+v, want %+v", tt.t.In(tt.loc), got, tt.rt)
+		}
+		rt := tt.rt
+		if got := fromRTCTime(&rt, tt.loc); !got.Equal(tt.t) {
+			t.Errorf("fromRTCTime(%+v, %v) = %v, want %v", tt.rt, tt.loc, got, tt.t)
+		}
+	}
+}
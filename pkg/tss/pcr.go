@@ -5,6 +5,7 @@
 package tss
 
 import (
+	"crypto"
 	"fmt"
 	"io"
 
@@ -20,8 +21,23 @@ func extendPCR12(rwc io.ReadWriter, pcrIndex uint32, hash [20]byte) error {
 	return nil
 }
 
-func extendPCR20(rwc io.ReadWriter, pcrIndex uint32, hash []byte) error {
-	if err := tpm2.PCRExtend(rwc, tpmutil.Handle(pcrIndex), tpm2.AlgSHA256, hash, ""); err != nil {
+// tpm2Alg returns the TPM 2.0 algorithm ID of a hash.
+func tpm2Alg(alg crypto.Hash) (tpm2.Algorithm, error) {
+	switch alg {
+	case crypto.SHA1:
+		return tpm2.AlgSHA1, nil
+	case crypto.SHA256:
+		return tpm2.AlgSHA256, nil
+	case crypto.SHA384:
+		return tpm2.AlgSHA384, nil
+	case crypto.SHA512:
+		return tpm2.AlgSHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported hash algorithm: %v", alg)
+}
+
+func extendPCR20(rwc io.ReadWriter, pcrIndex uint32, alg tpm2.Algorithm, hash []byte) error {
+	if err := tpm2.PCRExtend(rwc, tpmutil.Handle(pcrIndex), alg, hash, ""); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,208 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tss
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// nvChunkSize is the number of bytes written to NV per command. It is below
+// TPM_PT_NV_BUFFER_MAX of all TPMs we know of.
+const nvChunkSize = 512
+
+// srkTemplate is the template of the storage root key that sealed data is
+// created under. The key is derived from the owner hierarchy's seed, so it
+// is the same every time it is created and does not have to be persisted.
+var srkTemplate = tpm2.Public{
+	Type:       tpm2.AlgRSA,
+	NameAlg:    tpm2.AlgSHA256,
+	Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA,
+	RSAParameters: &tpm2.RSAParams{
+		Symmetric: &tpm2.SymScheme{
+			Alg:     tpm2.AlgAES,
+			KeyBits: 128,
+			Mode:    tpm2.AlgCFB,
+		},
+		KeyBits: 2048,
+	},
+}
+
+// marshalSealed packs the private and public parts of a sealed object into
+// one blob.
+func marshalSealed(private, public []byte) ([]byte, error) {
+	return tpmutil.Pack(tpmutil.U16Bytes(private), tpmutil.U16Bytes(public))
+}
+
+// unmarshalSealed is the inverse of marshalSealed.
+func unmarshalSealed(blob []byte) ([]byte, []byte, error) {
+	var private, public tpmutil.U16Bytes
+	n, err := tpmutil.Unpack(blob, &private, &public)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sealed blob: %v", err)
+	}
+	if n != len(blob) {
+		return nil, nil, fmt.Errorf("invalid sealed blob: %d trailing bytes", len(blob)-n)
+	}
+	return private, public, nil
+}
+
+// pcrSelection returns the selection of pcrs in the bank of alg.
+func pcrSelection(pcrs []int, alg crypto.Hash) (tpm2.PCRSelection, error) {
+	talg, err := tpm2Alg(alg)
+	if err != nil {
+		return tpm2.PCRSelection{}, err
+	}
+	if len(pcrs) == 0 {
+		return tpm2.PCRSelection{}, errors.New("no PCRs selected")
+	}
+	for _, p := range pcrs {
+		if p < 0 || p > 23 {
+			return tpm2.PCRSelection{}, fmt.Errorf("invalid PCR index %d", p)
+		}
+	}
+	return tpm2.PCRSelection{Hash: talg, PCRs: pcrs}, nil
+}
+
+// pcrPolicySession starts a session of type se and binds it to the current
+// values of the PCRs in sel. The caller has to flush the session.
+func pcrPolicySession(rw io.ReadWriter, sel tpm2.PCRSelection, se tpm2.SessionType) (tpmutil.Handle, error) {
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, 16), nil, se, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return 0, fmt.Errorf("starting policy session: %v", err)
+	}
+	if err := tpm2.PolicyPCR(rw, session, nil, sel); err != nil {
+		tpm2.FlushContext(rw, session)
+		return 0, fmt.Errorf("PolicyPCR: %v", err)
+	}
+	return session, nil
+}
+
+func seal20(rw io.ReadWriter, data []byte, sel tpm2.PCRSelection) ([]byte, error) {
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("creating storage root key: %v", err)
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	// A trial session computes the policy digest of the current PCR
+	// values without authorizing anything.
+	session, err := pcrPolicySession(rw, sel, tpm2.SessionTrial)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := tpm2.PolicyGetDigest(rw, session)
+	tpm2.FlushContext(rw, session)
+	if err != nil {
+		return nil, fmt.Errorf("PolicyGetDigest: %v", err)
+	}
+
+	private, public, err := tpm2.Seal(rw, srk, "", "", policy, data)
+	if err != nil {
+		return nil, fmt.Errorf("sealing data: %v", err)
+	}
+	return marshalSealed(private, public)
+}
+
+func unseal20(rw io.ReadWriter, blob []byte, sel tpm2.PCRSelection) ([]byte, error) {
+	private, public, err := unmarshalSealed(blob)
+	if err != nil {
+		return nil, err
+	}
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("creating storage root key: %v", err)
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	obj, _, err := tpm2.Load(rw, srk, "", public, private)
+	if err != nil {
+		return nil, fmt.Errorf("loading sealed object: %v", err)
+	}
+	defer tpm2.FlushContext(rw, obj)
+
+	session, err := pcrPolicySession(rw, sel, tpm2.SessionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, session)
+
+	data, err := tpm2.UnsealWithSession(rw, session, obj, "")
+	if err != nil {
+		return nil, fmt.Errorf("unsealing data, PCRs may not match: %v", err)
+	}
+	return data, nil
+}
+
+// Seal seals data to the current values of pcrs in the bank of alg. The
+// returned blob can only be unsealed by this TPM, and only while the PCRs
+// have the same values. The blob is not secret and can be stored anywhere,
+// e.g. with WriteSealedNV. Sealing is only supported by TPM 2.0.
+func (t *TPM) Seal(data []byte, pcrs []int, alg crypto.Hash) ([]byte, error) {
+	if t.Version != TPMVersion20 {
+		return nil, fmt.Errorf("sealing is not supported by TPM version: %x", t.Version)
+	}
+	sel, err := pcrSelection(pcrs, alg)
+	if err != nil {
+		return nil, err
+	}
+	return seal20(t.RWC, data, sel)
+}
+
+// Unseal unseals a blob returned by Seal. pcrs and alg have to be the ones
+// it was sealed with.
+func (t *TPM) Unseal(blob []byte, pcrs []int, alg crypto.Hash) ([]byte, error) {
+	if t.Version != TPMVersion20 {
+		return nil, fmt.Errorf("unsealing is not supported by TPM version: %x", t.Version)
+	}
+	sel, err := pcrSelection(pcrs, alg)
+	if err != nil {
+		return nil, err
+	}
+	return unseal20(t.RWC, blob, sel)
+}
+
+// WriteSealedNV stores a blob returned by Seal in the NV index. The index
+// is defined by the owner, replacing an index that already exists, and is
+// readable and writable with the owner password.
+func (t *TPM) WriteSealedNV(index uint32, blob []byte, ownerPassword string) error {
+	if t.Version != TPMVersion20 {
+		return fmt.Errorf("unsupported TPM version: %x", t.Version)
+	}
+	if len(blob) > 0xffff {
+		return fmt.Errorf("sealed blob is %d bytes, too large for an NV index", len(blob))
+	}
+	h := tpmutil.Handle(index)
+	// Undefining fails if the index does not exist, which is fine.
+	tpm2.NVUndefineSpace(t.RWC, ownerPassword, tpm2.HandleOwner, h)
+	attr := tpm2.AttrOwnerWrite | tpm2.AttrOwnerRead | tpm2.AttrNoDA
+	if err := tpm2.NVDefineSpace(t.RWC, tpm2.HandleOwner, h, ownerPassword, "", nil, attr, uint16(len(blob))); err != nil {
+		return fmt.Errorf("defining NV index %#x: %v", index, err)
+	}
+	for off := 0; off < len(blob); off += nvChunkSize {
+		end := off + nvChunkSize
+		if end > len(blob) {
+			end = len(blob)
+		}
+		if err := tpm2.NVWrite(t.RWC, tpm2.HandleOwner, h, ownerPassword, blob[off:end], uint16(off)); err != nil {
+			return fmt.Errorf("writing NV index %#x: %v", index, err)
+		}
+	}
+	return nil
+}
+
+// ReadSealedNV reads a blob stored with WriteSealedNV.
+func (t *TPM) ReadSealedNV(index uint32, ownerPassword string) ([]byte, error) {
+	if t.Version != TPMVersion20 {
+		return nil, fmt.Errorf("unsupported TPM version: %x", t.Version)
+	}
+	return nvRead20(t.RWC, tpmutil.Handle(index), tpm2.HandleOwner, ownerPassword, nvChunkSize)
+}
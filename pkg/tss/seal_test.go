@@ -0,0 +1,49 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tss
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+)
+
+func TestSealedBlob(t *testing.T) {
+	private, public := []byte("private part"), []byte("public")
+	blob, err := marshalSealed(private, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, gotPub, err := unmarshalSealed(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotPriv, private) || !bytes.Equal(gotPub, public) {
+		t.Errorf("unmarshalSealed = (%q, %q), want (%q, %q)", gotPriv, gotPub, private, public)
+	}
+
+	for _, b := range [][]byte{nil, blob[:len(blob)-1], append(blob, 0)} {
+		if _, _, err := unmarshalSealed(b); err == nil {
+			t.Errorf("unmarshalSealed(%x) succeeded, want error", b)
+		}
+	}
+}
+
+func TestPCRSelection(t *testing.T) {
+	for _, tt := range []struct {
+		pcrs []int
+		alg  crypto.Hash
+		err  bool
+	}{
+		{pcrs: []int{0, 7}, alg: crypto.SHA256},
+		{pcrs: nil, alg: crypto.SHA256, err: true},
+		{pcrs: []int{24}, alg: crypto.SHA256, err: true},
+		{pcrs: []int{0}, alg: crypto.MD5, err: true},
+	} {
+		if _, err := pcrSelection(tt.pcrs, tt.alg); (err != nil) != tt.err {
+			t.Errorf("pcrSelection(%v, %v) = %v, want error %v", tt.pcrs, tt.alg, err, tt.err)
+		}
+	}
+}
@@ -67,24 +67,40 @@ func (t *TPM) Close() error {
 
 // ReadPCRs reads all PCRs into the PCR structure
 func (t *TPM) ReadPCRs() ([]PCR, error) {
+	switch t.Version {
+	case TPMVersion12:
+		return t.ReadPCRBank(crypto.SHA1)
+	case TPMVersion20:
+		return t.ReadPCRBank(crypto.SHA256)
+	}
+	return nil, fmt.Errorf("unsupported TPM version: %x", t.Version)
+}
+
+// ReadPCRBank reads all PCRs of the bank of the hash algorithm alg. TPM 1.2
+// only has a SHA-1 bank.
+func (t *TPM) ReadPCRBank(alg crypto.Hash) ([]PCR, error) {
 	var PCRs map[uint32][]byte
 	var err error
-	var alg crypto.Hash
 
 	switch t.Version {
 	case TPMVersion12:
+		if alg != crypto.SHA1 {
+			return nil, fmt.Errorf("TPM 1.2 has no %v PCR bank", alg)
+		}
 		PCRs, err = readAllPCRs12(t.RWC)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read PCRs: %v", err)
 		}
-		alg = crypto.SHA1
 
 	case TPMVersion20:
-		PCRs, err = readAllPCRs20(t.RWC, tpm2.AlgSHA256)
+		talg, err := tpm2Alg(alg)
+		if err != nil {
+			return nil, err
+		}
+		PCRs, err = readAllPCRs20(t.RWC, talg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read PCRs: %v", err)
 		}
-		alg = crypto.SHA1
 
 	default:
 		return nil, fmt.Errorf("unsupported TPM version: %x", t.Version)
@@ -106,26 +122,38 @@ func (t *TPM) ReadPCRs() ([]PCR, error) {
 func (t *TPM) Extend(hash []byte, pcrIndex uint32) error {
 	switch t.Version {
 	case TPMVersion12:
-		var thash [20]byte
-		hashlen := len(hash)
-		if hashlen != 20 {
-			return fmt.Errorf("hash length invalid - need 20, got: %v", hashlen)
-		}
-		copy(thash[:], hash[:20])
-		err := extendPCR12(t.RWC, pcrIndex, thash)
-		if err != nil {
-			return err
+		return t.ExtendBank(hash, pcrIndex, crypto.SHA1)
+	case TPMVersion20:
+		return t.ExtendBank(hash, pcrIndex, crypto.SHA256)
+	}
+	return fmt.Errorf("unsupported TPM version: %x", t.Version)
+}
+
+// ExtendBank extends hash into pcrIndex of the bank of the hash algorithm
+// alg. hash has to be a digest of alg.
+func (t *TPM) ExtendBank(hash []byte, pcrIndex uint32, alg crypto.Hash) error {
+	if !alg.Available() {
+		return fmt.Errorf("unsupported hash algorithm: %v", alg)
+	}
+	if len(hash) != alg.Size() {
+		return fmt.Errorf("hash length invalid - need %d, got: %v", alg.Size(), len(hash))
+	}
+	switch t.Version {
+	case TPMVersion12:
+		if alg != crypto.SHA1 {
+			return fmt.Errorf("TPM 1.2 has no %v PCR bank", alg)
 		}
+		var thash [20]byte
+		copy(thash[:], hash)
+		return extendPCR12(t.RWC, pcrIndex, thash)
 	case TPMVersion20:
-		err := extendPCR20(t.RWC, pcrIndex, hash)
+		talg, err := tpm2Alg(alg)
 		if err != nil {
 			return err
 		}
-	default:
-		return fmt.Errorf("unsupported TPM version: %x", t.Version)
+		return extendPCR20(t.RWC, pcrIndex, talg, hash)
 	}
-
-	return nil
+	return fmt.Errorf("unsupported TPM version: %x", t.Version)
 }
 
 // Measure measures data with a specific hash algorithm and extends it into the pcrIndex
@@ -139,7 +167,7 @@ func (t *TPM) Measure(data []byte, pcrIndex uint32) error {
 		}
 	case TPMVersion20:
 		hash := sha256.Sum256(data)
-		err := extendPCR20(t.RWC, pcrIndex, hash[:])
+		err := extendPCR20(t.RWC, pcrIndex, tpm2.AlgSHA256, hash[:])
 		if err != nil {
 			return err
 		}
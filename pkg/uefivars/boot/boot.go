@@ -8,7 +8,6 @@
 package boot
 
 import (
-	"encoding/binary"
 	"fmt"
 	"log"
 	"strconv"
@@ -122,27 +121,13 @@ func BootVar(v uefivars.EfiVar) (b *BootEntryVar) {
 	if err != nil {
 		log.Printf("error parsing boot var %s: %s", v.Name, err)
 	}
-	b = new(BootEntryVar)
-	b.Number = uint16(num)
-	b.Attributes = binary.LittleEndian.Uint32(v.Data[:4])
-	b.FilePathListLength = binary.LittleEndian.Uint16(v.Data[4:6])
-
-	//Description is null-terminated utf16
-	var i uint16
-	for i = 6; ; i += 2 {
-		if v.Data[i] == 0 {
-			break
-		}
-	}
-	b.Description, err = uefivars.DecodeUTF16(v.Data[6:i])
+	b = &BootEntryVar{Number: uint16(num)}
+	o, err := ParseLoadOption(v.Data)
 	if err != nil {
-		log.Printf("reading description: %s (%d -> %x)", err, i, v.Data[6:i])
+		log.Printf("parsing boot var %s: %s", v.Name, err)
 	}
-	b.OptionalData = v.Data[i+2+b.FilePathListLength:]
-
-	b.FilePathList, err = ParseFilePathList(v.Data[i+2 : i+2+b.FilePathListLength])
-	if err != nil {
-		log.Printf("parsing FilePathList in %s: %s", b.String(), err)
+	if o != nil {
+		b.EfiLoadOption = *o
 	}
 	return
 }
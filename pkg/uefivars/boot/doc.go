@@ -7,4 +7,7 @@
 
 // Package boot manipulates UEFI boot variables, and can identify and mount
 // the volume referenced by a boot var.
+//
+// LoadOption, BootOrder, SetBootOrder and SetBootNext use efivarfs; the
+// Read functions use the deprecated sysfs interface.
 package boot
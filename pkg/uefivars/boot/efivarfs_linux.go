@@ -0,0 +1,95 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: BSD-3-Clause
+//
+
+package boot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/u-root/u-root/pkg/efivarfs"
+)
+
+// bootVarAttrs are the attributes of BootOrder and BootNext.
+const bootVarAttrs = efivarfs.AttributeNonVolatile | efivarfs.AttributeBootserviceAccess | efivarfs.AttributeRuntimeAccess
+
+func globalVar(name string) efivarfs.Var {
+	return efivarfs.Var{Name: name, GUID: efivarfs.GlobalVariable}
+}
+
+// LoadOption reads BootXXXX from efivarfs.
+func LoadOption(num uint16) (*BootEntryVar, error) {
+	name := fmt.Sprintf("Boot%04X", num)
+	_, data, err := efivarfs.Get(globalVar(name))
+	if err != nil {
+		return nil, err
+	}
+	o, err := ParseLoadOption(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return &BootEntryVar{Number: num, EfiLoadOption: *o}, nil
+}
+
+// LoadOptions reads all BootXXXX vars from efivarfs, in order of their
+// numbers. Vars that can not be decoded are skipped.
+func LoadOptions() (BootEntryVars, error) {
+	vars, err := efivarfs.List()
+	if err != nil {
+		return nil, err
+	}
+	var entries BootEntryVars
+	for _, v := range vars {
+		if !BootEntryFilter(v.GUID, v.Name) {
+			continue
+		}
+		num, err := strconv.ParseUint(v.Name[4:], 16, 16)
+		if err != nil {
+			continue
+		}
+		e, err := LoadOption(uint16(num))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Number < entries[j].Number })
+	return entries, nil
+}
+
+// BootOrder reads BootOrder from efivarfs.
+func BootOrder() ([]uint16, error) {
+	_, data, err := efivarfs.Get(globalVar("BootOrder"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseBootOrder(data)
+}
+
+// SetBootOrder writes BootOrder, after checking that all entries exist.
+func SetBootOrder(order []uint16) error {
+	seen := map[uint16]bool{}
+	for _, n := range order {
+		if seen[n] {
+			return fmt.Errorf("Boot%04X is in the boot order twice", n)
+		}
+		seen[n] = true
+		if _, _, err := efivarfs.Get(globalVar(fmt.Sprintf("Boot%04X", n))); err != nil {
+			return err
+		}
+	}
+	return efivarfs.Set(globalVar("BootOrder"), bootVarAttrs, EncodeBootOrder(order))
+}
+
+// SetBootNext makes the firmware boot BootXXXX on the next boot only.
+func SetBootNext(num uint16) error {
+	if _, _, err := efivarfs.Get(globalVar(fmt.Sprintf("Boot%04X", num))); err != nil {
+		return err
+	}
+	return efivarfs.Set(globalVar("BootNext"), bootVarAttrs, EncodeBootOrder([]uint16{num}))
+}
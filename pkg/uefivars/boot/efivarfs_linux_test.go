@@ -0,0 +1,63 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: BSD-3-Clause
+//
+
+package boot
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/u-root/u-root/pkg/efivarfs"
+)
+
+func TestEfivarfsBootVars(t *testing.T) {
+	d, err := ioutil.TempDir("", "efivarfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	defer func(old string) { efivarfs.Dir = old }(efivarfs.Dir)
+	efivarfs.Dir = d
+
+	for _, n := range []string{"Boot0001", "Boot000A"} {
+		if err := efivarfs.Set(globalVar(n), bootVarAttrs, testLoadOption()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := LoadOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Number != 1 || entries[1].Number != 0xa || entries[1].Description != "debian" {
+		t.Errorf("LoadOptions = %v, want Boot0001 and Boot000A", entries)
+	}
+
+	if err := SetBootOrder([]uint16{0xa, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if order, err := BootOrder(); err != nil || !reflect.DeepEqual(order, []uint16{0xa, 1}) {
+		t.Errorf("BootOrder = %v, %v, want [10 1]", order, err)
+	}
+	if err := SetBootOrder([]uint16{1, 2}); err == nil {
+		t.Errorf("SetBootOrder with a missing entry succeeded")
+	}
+	if err := SetBootOrder([]uint16{1, 1}); err == nil {
+		t.Errorf("SetBootOrder with a duplicate entry succeeded")
+	}
+
+	if err := SetBootNext(2); err == nil {
+		t.Errorf("SetBootNext of a missing entry succeeded")
+	}
+	if err := SetBootNext(0xa); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, data, err := efivarfs.Get(globalVar("BootNext")); err != nil || attrs != bootVarAttrs || !reflect.DeepEqual(data, []byte{0xa, 0}) {
+		t.Errorf("BootNext = %#x, %v, %v, want %#x, [10 0]", attrs, data, err, bootVarAttrs)
+	}
+}
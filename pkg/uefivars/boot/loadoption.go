@@ -0,0 +1,127 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: BSD-3-Clause
+//
+
+package boot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/u-root/u-root/pkg/uefivars"
+)
+
+// Load option attributes, from the UEFI spec.
+const (
+	LoadOptionActive         = 0x1
+	LoadOptionForceReconnect = 0x2
+	LoadOptionHidden         = 0x8
+)
+
+// ParseLoadOption decodes an EFI_LOAD_OPTION, the data of a BootXXXX var.
+// If only the FilePathList can not be decoded, the rest is returned along
+// with the error.
+func ParseLoadOption(data []byte) (*EfiLoadOption, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("load option is %d bytes, too short", len(data))
+	}
+	o := &EfiLoadOption{
+		Attributes:         binary.LittleEndian.Uint32(data[:4]),
+		FilePathListLength: binary.LittleEndian.Uint16(data[4:6]),
+	}
+	// Description is null-terminated utf16.
+	i := 6
+	for ; ; i += 2 {
+		if i+1 >= len(data) {
+			return nil, errors.New("load option description is not terminated")
+		}
+		if data[i] == 0 && data[i+1] == 0 {
+			break
+		}
+	}
+	var err error
+	if o.Description, err = uefivars.DecodeUTF16(data[6:i]); err != nil {
+		return nil, fmt.Errorf("load option description: %v", err)
+	}
+	fpl := data[i+2:]
+	if len(fpl) < int(o.FilePathListLength) {
+		return nil, fmt.Errorf("load option FilePathList is %d bytes, want %d", len(fpl), o.FilePathListLength)
+	}
+	o.OptionalData = fpl[o.FilePathListLength:]
+	if o.FilePathList, err = ParseFilePathList(fpl[:o.FilePathListLength]); err != nil {
+		return o, fmt.Errorf("load option FilePathList: %v", err)
+	}
+	return o, nil
+}
+
+// ParseBootOrder decodes the data of the BootOrder var, the numbers of the
+// BootXXXX vars in the order they are tried.
+func ParseBootOrder(data []byte) ([]uint16, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("BootOrder is %d bytes, not a multiple of 2", len(data))
+	}
+	order := make([]uint16, len(data)/2)
+	for i := range order {
+		order[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return order, nil
+}
+
+// EncodeBootOrder encodes order as the data of the BootOrder var.
+func EncodeBootOrder(order []uint16) []byte {
+	b := make([]byte, 2*len(order))
+	for i, n := range order {
+		binary.LittleEndian.PutUint16(b[2*i:], n)
+	}
+	return b
+}
+
+// encodeUTF16z encodes s as null-terminated utf16.
+func encodeUTF16z(s string) []byte {
+	var b []byte
+	for _, c := range append(utf16.Encode([]rune(s)), 0) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
+
+// EncodeLoadOption encodes an EFI_LOAD_OPTION, the data of a BootXXXX var.
+// filePathList is encoded e.g. by GPTFilePathList.
+func EncodeLoadOption(attributes uint32, description string, filePathList, optionalData []byte) []byte {
+	b := make([]byte, 6)
+	binary.LittleEndian.PutUint32(b, attributes)
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(filePathList)))
+	b = append(b, encodeUTF16z(description)...)
+	b = append(b, filePathList...)
+	return append(b, optionalData...)
+}
+
+// GPTFilePathList encodes a FilePathList for the file path on GPT partition
+// number partNum, which starts at LBA start and is size LBAs long. path
+// uses either slashes or backslashes.
+func GPTFilePathList(partNum uint32, start, size uint64, partGUID uefivars.MixedGUID, path string) []byte {
+	hd := make([]byte, 42)
+	hd[0], hd[1] = byte(DppTypeMedia), byte(DppMTypeHdd)
+	binary.LittleEndian.PutUint16(hd[2:], uint16(len(hd)))
+	binary.LittleEndian.PutUint32(hd[4:], partNum)
+	binary.LittleEndian.PutUint64(hd[8:], start)
+	binary.LittleEndian.PutUint64(hd[16:], size)
+	copy(hd[24:40], partGUID[:])
+	// GPT, GUID signature.
+	hd[40], hd[41] = 2, 2
+
+	p := encodeUTF16z(strings.Replace(path, "/", `\`, -1))
+	fp := make([]byte, 4, 4+len(p))
+	fp[0], fp[1] = byte(DppTypeMedia), byte(DppMTypeFilePath)
+	binary.LittleEndian.PutUint16(fp[2:], uint16(4+len(p)))
+	fp = append(fp, p...)
+
+	end := []byte{byte(DppTypeEnd), byte(DppETypeEndEntire), 4, 0}
+	return append(append(hd, fp...), end...)
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+// SPDX-License-Identifier: BSD-3-Clause
+//
+
+package boot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/u-root/u-root/pkg/uefivars"
+)
+
+func utf16z(s string) []byte {
+	var b []byte
+	for _, c := range append(utf16.Encode([]rune(s)), 0) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
+
+// testLoadOption returns an EFI_LOAD_OPTION as written by efibootmgr for
+// \EFI\debian\shimx64.efi on GPT partition 1.
+func testLoadOption() []byte {
+	var fpl []byte
+	// HD(1,GPT,...,0x800,0x100000)
+	hd := make([]byte, 42)
+	hd[0], hd[1], hd[2] = 4, 1, 42
+	binary.LittleEndian.PutUint32(hd[4:], 1)
+	binary.LittleEndian.PutUint64(hd[8:], 0x800)
+	binary.LittleEndian.PutUint64(hd[16:], 0x100000)
+	copy(hd[24:40], []byte{0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x34, 0x12, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0})
+	hd[40], hd[41] = 2, 2
+	fpl = append(fpl, hd...)
+	path := utf16z(`\EFI\debian\shimx64.efi`)
+	fpl = append(fpl, 4, 4, byte(4+len(path)), 0)
+	fpl = append(fpl, path...)
+	fpl = append(fpl, 0x7f, 0xff, 4, 0)
+
+	b := []byte{LoadOptionActive, 0, 0, 0, byte(len(fpl)), 0}
+	b = append(b, utf16z("debian")...)
+	b = append(b, fpl...)
+	return append(b, 'o', 'p', 't')
+}
+
+func TestParseLoadOption(t *testing.T) {
+	o, err := ParseLoadOption(testLoadOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Attributes != LoadOptionActive || o.Description != "debian" || !bytes.Equal(o.OptionalData, []byte("opt")) {
+		t.Errorf("ParseLoadOption = attributes %#x, description %q, optional data %q; want 0x1, debian, opt", o.Attributes, o.Description, o.OptionalData)
+	}
+	if len(o.FilePathList) != 2 {
+		t.Fatalf("FilePathList = %v, want 2 nodes", o.FilePathList)
+	}
+	hd, ok := o.FilePathList[0].(*DppMediaHDD)
+	if !ok {
+		t.Fatalf("first node is %T, want *DppMediaHDD", o.FilePathList[0])
+	}
+	if want := "HD(1,GPT,12345678-1234-1234-1234-56789abcdef0,0x800,0x100000)"; hd.String() != want {
+		t.Errorf("HD node = %s, want %s", hd, want)
+	}
+	fp, ok := o.FilePathList[1].(*DppMediaFilePath)
+	if !ok {
+		t.Fatalf("second node is %T, want *DppMediaFilePath", o.FilePathList[1])
+	}
+	if want := "/EFI/debian/shimx64.efi"; fp.PathNameDecoded != want {
+		t.Errorf("File node = %q, want %q", fp.PathNameDecoded, want)
+	}
+
+	full := testLoadOption()
+	for _, n := range []int{0, 5, 10, 20} {
+		if _, err := ParseLoadOption(full[:n]); err == nil {
+			t.Errorf("ParseLoadOption of %d bytes succeeded", n)
+		}
+	}
+}
+
+func TestBootOrder(t *testing.T) {
+	order := []uint16{3, 0, 0x1a}
+	b := EncodeBootOrder(order)
+	if want := []byte{3, 0, 0, 0, 0x1a, 0}; !bytes.Equal(b, want) {
+		t.Errorf("EncodeBootOrder(%v) = %v, want %v", order, b, want)
+	}
+	got, err := ParseBootOrder(b)
+	if err != nil || !reflect.DeepEqual(got, order) {
+		t.Errorf("ParseBootOrder(%v) = %v, %v, want %v", b, got, err, order)
+	}
+	if _, err := ParseBootOrder([]byte{1, 0, 2}); err == nil {
+		t.Errorf("ParseBootOrder of 3 bytes succeeded")
+	}
+}
+
+func TestEncodeLoadOption(t *testing.T) {
+	guid := uefivars.MixedGUID{0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x34, 0x12, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}
+	got := EncodeLoadOption(LoadOptionActive, "debian", GPTFilePathList(1, 0x800, 0x100000, guid, "/EFI/debian/shimx64.efi"), []byte("opt"))
+	if want := testLoadOption(); !bytes.Equal(got, want) {
+		t.Errorf("EncodeLoadOption = %x, want %x", got, want)
+	}
+}
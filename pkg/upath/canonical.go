@@ -0,0 +1,122 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9
+
+package upath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Mode controls how Canonicalize treats path components that do not exist.
+type Mode int
+
+const (
+	// AllButLast requires every component except the last to exist.
+	AllButLast Mode = iota
+	// Existing requires every component to exist.
+	Existing
+	// Missing does not require any component to exist.
+	Missing
+)
+
+// MaxSymlinks is the number of symlinks Canonicalize follows before giving
+// up with ELOOP. It matches the Linux kernel's limit.
+const MaxSymlinks = 40
+
+// Canonicalize returns an absolute path for name with no ".", ".." or
+// repeated separators. If follow is true, symlinks are resolved one component
+// at a time, so a link pointing to a missing file can still be reported in
+// the Missing and AllButLast modes. Symlink chains longer than MaxSymlinks
+// fail with ELOOP.
+//
+// If follow is false, symlinks are left alone and ".." is resolved
+// lexically.
+func Canonicalize(name string, mode Mode, follow bool) (string, error) {
+	if name == "" {
+		return "", &os.PathError{Op: "canonicalize", Path: name, Err: syscall.ENOENT}
+	}
+	if !filepath.IsAbs(name) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		name = wd + "/" + name
+	}
+
+	var (
+		result = "/"
+		rest   = components(name)
+		links  int
+	)
+	for len(rest) > 0 {
+		c := rest[0]
+		rest = rest[1:]
+		if c == ".." {
+			result = filepath.Dir(result)
+			continue
+		}
+		next := filepath.Join(result, c)
+
+		stat := os.Stat
+		if follow {
+			stat = os.Lstat
+		}
+		fi, err := stat(next)
+		if err != nil {
+			if !os.IsNotExist(err) && !isNotDir(err) {
+				return "", err
+			}
+			if mode == Existing || (mode == AllButLast && len(rest) > 0) {
+				return "", err
+			}
+			result = next
+			continue
+		}
+
+		if follow && fi.Mode()&os.ModeSymlink != 0 {
+			links++
+			if links > MaxSymlinks {
+				return "", &os.PathError{Op: "canonicalize", Path: name, Err: syscall.ELOOP}
+			}
+			target, err := os.Readlink(next)
+			if err != nil {
+				return "", err
+			}
+			if filepath.IsAbs(target) {
+				result = "/"
+			}
+			rest = append(components(target), rest...)
+			continue
+		}
+
+		if !fi.IsDir() && len(rest) > 0 && mode != Missing {
+			return "", &os.PathError{Op: "canonicalize", Path: next, Err: syscall.ENOTDIR}
+		}
+		result = next
+	}
+	return result, nil
+}
+
+// components splits p on "/", dropping empty and "." elements.
+func components(p string) []string {
+	var c []string
+	for _, e := range strings.Split(p, "/") {
+		if e != "" && e != "." {
+			c = append(c, e)
+		}
+	}
+	return c
+}
+
+func isNotDir(err error) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		return pe.Err == syscall.ENOTDIR
+	}
+	return false
+}
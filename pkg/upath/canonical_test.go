@@ -0,0 +1,87 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9
+
+package upath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	td, err := ioutil.TempDir("", "canonicalize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	// Resolve the temp dir itself, e.g. /tmp may be a symlink.
+	if td, err = filepath.EvalSymlinks(td); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(td, "a/b"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(td, "a/f"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []struct{ target, name string }{
+		{"a/b", "l1"},
+		{"l1", "l2"},
+		{filepath.Join(td, "a"), "abs"},
+		{"missing", "dangling"},
+		{"loop2", "loop1"},
+		{"loop1", "loop2"},
+	} {
+		if err := os.Symlink(l.target, filepath.Join(td, l.name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name   string
+		mode   Mode
+		follow bool
+		want   string
+		err    bool
+	}{
+		{name: "a/./b/", follow: true, want: "a/b"},
+		{name: "a//b/../f", follow: true, want: "a/f"},
+		{name: "l2", follow: true, want: "a/b"},
+		{name: "l2/..", follow: true, want: "a"},
+		{name: "abs/b", follow: true, want: "a/b"},
+		{name: "l2/..", follow: false, want: ""},
+		{name: "l2", follow: false, want: "l2"},
+		{name: "dangling", follow: true, want: "missing"},
+		{name: "dangling", mode: Existing, follow: true, err: true},
+		{name: "dangling", mode: Missing, follow: true, want: "missing"},
+		{name: "x/y", follow: true, err: true},
+		{name: "x/y", mode: Missing, follow: true, want: "x/y"},
+		{name: "x/../a", mode: Missing, follow: true, want: "a"},
+		{name: "a/f/g", mode: Missing, follow: true, want: "a/f/g"},
+		{name: "a/f/g", follow: true, err: true},
+		{name: "a/f/", mode: Existing, follow: true, want: "a/f"},
+		{name: "loop1", follow: true, err: true},
+		{name: "loop1", mode: Missing, follow: true, err: true},
+	} {
+		got, err := Canonicalize(td+"/"+tt.name, tt.mode, tt.follow)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Canonicalize(%q, %v, %v) = %q, want error", tt.name, tt.mode, tt.follow, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Canonicalize(%q, %v, %v) = %v, want nil", tt.name, tt.mode, tt.follow, err)
+			continue
+		}
+		if want := filepath.Join(td, tt.want); got != want {
+			t.Errorf("Canonicalize(%q, %v, %v) = %q, want %q", tt.name, tt.mode, tt.follow, got, want)
+		}
+	}
+}
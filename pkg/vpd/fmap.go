@@ -0,0 +1,52 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var fmapSignature = []byte("__FMAP__")
+
+const (
+	fmapHeaderSize = 56
+	fmapAreaSize   = 42
+)
+
+// FMAPArea returns the offset and size of the area name, e.g. RW_VPD, in
+// the flash map of a flash image. ok is false if image has no flash map.
+func FMAPArea(image []byte, name string) (offset, size int, ok bool, err error) {
+	for i := 0; ; {
+		j := bytes.Index(image[i:], fmapSignature)
+		if j == -1 {
+			return 0, 0, false, nil
+		}
+		h := image[i+j:]
+		i += j + 1
+		// The signature may appear in code, so check the version.
+		if len(h) < fmapHeaderSize || h[8] != 1 {
+			continue
+		}
+		nareas := int(binary.LittleEndian.Uint16(h[54:]))
+		if len(h) < fmapHeaderSize+nareas*fmapAreaSize {
+			continue
+		}
+		for a := 0; a < nareas; a++ {
+			area := h[fmapHeaderSize+a*fmapAreaSize:]
+			if string(bytes.TrimRight(area[8:40], "\x00")) != name {
+				continue
+			}
+			offset = int(binary.LittleEndian.Uint32(area))
+			size = int(binary.LittleEndian.Uint32(area[4:]))
+			if offset+size > len(image) {
+				return 0, 0, true, fmt.Errorf("flash map area %s exceeds the image", name)
+			}
+			return offset, size, true, nil
+		}
+		return 0, 0, true, fmt.Errorf("flash map has no area %s", name)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Record types of the VPD 2.0 binary format.
+const (
+	typeTerminator         = 0x00
+	typeString             = 0x01
+	typeInfo               = 0xfe
+	typeImplicitTerminator = 0xff
+)
+
+// infoMagic starts the header of the key-value data. It is itself a
+// record: type info, a 9-byte key of version 1 and "gVpdInfo", and a 4-byte
+// value, the size of the data that follows.
+var infoMagic = []byte("\xfe\x09\x01gVpdInfo\x04")
+
+const (
+	infoSize = 16
+	// legacyOffset is where the header is when the partition starts with
+	// an SMBIOS entry point, as written by old versions of the vpd tool.
+	legacyOffset = 0x600
+)
+
+// The VPD 2.0 format has no checksum or CRC of its own; only the SMBIOS 2.1
+// entry point of the legacy layout has one, and its intermediate entry point
+// another. Each makes the bytes it covers sum to 0. See section 5.2.1 of
+// https://www.dmtf.org/sites/default/files/standards/documents/DSP0134_3.4.0.pdf.
+const (
+	smbiosChecksumOff    = 4
+	smbiosLenOff         = 5
+	smbiosDMIOff         = 0x10
+	smbiosDMISize        = 15
+	smbiosDMIChecksumOff = smbiosDMIOff + 5
+)
+
+func checksum(b []byte) byte {
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	return sum
+}
+
+// smbiosLen returns the length of the SMBIOS entry point that starts eps.
+func smbiosLen(eps []byte) (int, error) {
+	n := int(eps[smbiosLenOff])
+	if n < smbiosDMIOff+smbiosDMISize || n > len(eps) {
+		return 0, fmt.Errorf("SMBIOS entry point length %d is invalid", n)
+	}
+	return n, nil
+}
+
+// checkSMBIOS verifies the checksums of the SMBIOS entry point that starts
+// eps.
+func checkSMBIOS(eps []byte) error {
+	n, err := smbiosLen(eps)
+	if err != nil {
+		return err
+	}
+	if checksum(eps[:n]) != 0 {
+		return errors.New("SMBIOS entry point checksum mismatch")
+	}
+	if checksum(eps[smbiosDMIOff:smbiosDMIOff+smbiosDMISize]) != 0 {
+		return errors.New("SMBIOS intermediate entry point checksum mismatch")
+	}
+	return nil
+}
+
+// fixSMBIOS updates the checksums of the SMBIOS entry point that starts
+// eps. The intermediate one is covered by the other, so it comes first.
+func fixSMBIOS(eps []byte) error {
+	n, err := smbiosLen(eps)
+	if err != nil {
+		return err
+	}
+	eps[smbiosDMIChecksumOff] = 0
+	eps[smbiosDMIChecksumOff] = -checksum(eps[smbiosDMIOff : smbiosDMIOff+smbiosDMISize])
+	eps[smbiosChecksumOff] = 0
+	eps[smbiosChecksumOff] = -checksum(eps[:n])
+	return nil
+}
+
+// Entry is a key-value pair.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+// Partition is a VPD partition of flash, e.g. RO_VPD or RW_VPD.
+type Partition struct {
+	// Entries are the entries in the order they are stored.
+	Entries []Entry
+	// prefix is kept as is in front of the header.
+	prefix []byte
+	size   int
+}
+
+// NewPartition returns an empty partition of size bytes.
+func NewPartition(size int) *Partition {
+	return &Partition{size: size}
+}
+
+// decodeLen decodes a length, which is stored big-endian in 7-bit groups.
+// The top bit of each byte is set if more bytes follow.
+func decodeLen(b []byte) (n int, used int, err error) {
+	for i, c := range b {
+		if i == 4 {
+			break
+		}
+		n = n<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			return n, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("invalid VPD length")
+}
+
+func encodeLen(n int) []byte {
+	b := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		b = append([]byte{byte(n&0x7f) | 0x80}, b...)
+	}
+	return b
+}
+
+// ParsePartition decodes a VPD partition. An erased partition, all 0xff,
+// has no entries.
+func ParsePartition(b []byte) (*Partition, error) {
+	p := &Partition{size: len(b)}
+	off := 0
+	if bytes.HasPrefix(b, []byte("_SM_")) {
+		off = legacyOffset
+	}
+	if len(b) < off+infoSize {
+		return nil, fmt.Errorf("VPD partition is %d bytes, too small", len(b))
+	}
+	hdr := b[off : off+infoSize]
+	if bytes.Equal(hdr, bytes.Repeat([]byte{0xff}, infoSize)) {
+		return p, nil
+	}
+	if !bytes.HasPrefix(hdr, infoMagic) {
+		return nil, errors.New("no VPD header found")
+	}
+	if off == legacyOffset {
+		if err := checkSMBIOS(b[:off]); err != nil {
+			return nil, err
+		}
+	}
+	p.prefix = append([]byte(nil), b[:off]...)
+	size := int(binary.LittleEndian.Uint32(hdr[len(infoMagic):]))
+	data := b[off+infoSize:]
+	if size > len(data) {
+		return nil, fmt.Errorf("VPD data is %d bytes, but the partition has room for %d", size, len(data))
+	}
+	data = data[:size]
+	for len(data) > 0 {
+		t := data[0]
+		if t == typeTerminator || t == typeImplicitTerminator {
+			break
+		}
+		data = data[1:]
+		var kv [2][]byte
+		for i := range kv {
+			n, used, err := decodeLen(data)
+			if err != nil {
+				return nil, err
+			}
+			if used+n > len(data) {
+				return nil, errors.New("VPD record exceeds the data")
+			}
+			kv[i], data = data[used:used+n], data[used+n:]
+		}
+		if t != typeString {
+			continue
+		}
+		p.Entries = append(p.Entries, Entry{Key: string(kv[0]), Value: append([]byte(nil), kv[1]...)})
+	}
+	return p, nil
+}
+
+// Get returns the value of key.
+func (p *Partition) Get(key string) ([]byte, bool) {
+	for _, e := range p.Entries {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets key to value, replacing its value if key exists and adding it at
+// the end otherwise.
+func (p *Partition) Set(key string, value []byte) {
+	for i, e := range p.Entries {
+		if e.Key == key {
+			p.Entries[i].Value = value
+			return
+		}
+	}
+	p.Entries = append(p.Entries, Entry{Key: key, Value: value})
+}
+
+// Delete deletes key, and returns whether it existed.
+func (p *Partition) Delete(key string) bool {
+	for i, e := range p.Entries {
+		if e.Key == key {
+			p.Entries = append(p.Entries[:i], p.Entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes encodes the partition. It has the size the partition was parsed
+// from or created with, padded with 0xff as erased flash. The checksums of
+// a legacy SMBIOS entry point are updated.
+func (p *Partition) Bytes() ([]byte, error) {
+	var data []byte
+	for _, e := range p.Entries {
+		if e.Key == "" {
+			return nil, errors.New("VPD keys can not be empty")
+		}
+		data = append(data, typeString)
+		data = append(data, encodeLen(len(e.Key))...)
+		data = append(data, e.Key...)
+		data = append(data, encodeLen(len(e.Value))...)
+		data = append(data, e.Value...)
+	}
+	data = append(data, typeTerminator)
+
+	b := append([]byte(nil), p.prefix...)
+	if len(b) > 0 {
+		if err := fixSMBIOS(b); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, infoMagic...)
+	b = append(b, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(len(data)))
+	b = append(b, data...)
+	if len(b) > p.size {
+		return nil, fmt.Errorf("VPD data needs %d bytes, but the partition is %d bytes", len(b), p.size)
+	}
+	return append(b, bytes.Repeat([]byte{0xff}, p.size-len(b))...), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 the u-root Authors. All rights reserved
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLen(t *testing.T) {
+	for n, want := range map[int][]byte{
+		0:       {0},
+		0x7f:    {0x7f},
+		0x80:    {0x81, 0x00},
+		300:     {0x82, 0x2c},
+		1 << 14: {0x81, 0x80, 0x00},
+	} {
+		b := encodeLen(n)
+		if !bytes.Equal(b, want) {
+			t.Errorf("encodeLen(%d) = %x, want %x", n, b, want)
+		}
+		if got, used, err := decodeLen(append(b, 0x55)); err != nil || got != n || used != len(want) {
+			t.Errorf("decodeLen(%x) = %d, %d, %v, want %d, %d", b, got, used, err, n, len(want))
+		}
+	}
+	if _, _, err := decodeLen([]byte{0x80, 0x80}); err == nil {
+		t.Errorf("decodeLen of an unterminated length succeeded")
+	}
+}
+
+func TestPartition(t *testing.T) {
+	// serial_number=ABC, an info record that is skipped, and a long value.
+	long := strings.Repeat("x", 200)
+	data := []byte("\x01\x0dserial_number\x03ABC\xfe\x01k\x01v\x01\x04long\x81\x48" + long + "\x00")
+	b := append([]byte(nil), infoMagic...)
+	b = append(b, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[len(infoMagic):], uint32(len(data)))
+	b = append(b, data...)
+	b = append(b, bytes.Repeat([]byte{0xff}, 512-len(b))...)
+
+	p, err := ParsePartition(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{"serial_number", []byte("ABC")}, {"long", []byte(long)}}
+	if !reflect.DeepEqual(p.Entries, want) {
+		t.Fatalf("Entries = %q, want %q", p.Entries, want)
+	}
+
+	p.Set("serial_number", []byte("DEF"))
+	p.Set("new", []byte("1"))
+	if !p.Delete("long") || p.Delete("long") {
+		t.Errorf("Delete(long) did not delete it once")
+	}
+	out, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 512 || out[511] != 0xff {
+		t.Errorf("Bytes is %d bytes ending in %#x, want 512 ending in 0xff", len(out), out[len(out)-1])
+	}
+	p, err = ParsePartition(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Entry{{"serial_number", []byte("DEF")}, {"new", []byte("1")}}
+	if !reflect.DeepEqual(p.Entries, want) {
+		t.Errorf("Entries after Set and Delete = %q, want %q", p.Entries, want)
+	}
+	if v, ok := p.Get("new"); !ok || string(v) != "1" {
+		t.Errorf("Get(new) = %q, %v, want 1, true", v, ok)
+	}
+
+	p.Set("big", bytes.Repeat([]byte{1}, 512))
+	if _, err := p.Bytes(); err == nil {
+		t.Errorf("Bytes of a value larger than the partition succeeded")
+	}
+}
+
+func TestParsePartition(t *testing.T) {
+	erased := bytes.Repeat([]byte{0xff}, 64)
+	if p, err := ParsePartition(erased); err != nil || len(p.Entries) != 0 {
+		t.Errorf("ParsePartition(erased) = %v, %v, want no entries", p, err)
+	}
+
+	// The legacy layout keeps the SMBIOS entry point, and Bytes fixes its
+	// checksums.
+	legacy := NewPartition(legacyOffset + 64)
+	legacy.prefix = make([]byte, legacyOffset)
+	copy(legacy.prefix, "_SM_\x00\x1f\x02\x01")
+	copy(legacy.prefix[smbiosDMIOff:], "_DMI_")
+	legacy.Set("a", []byte("b"))
+	b, err := legacy.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSMBIOS(b[:legacyOffset]); err != nil {
+		t.Errorf("Bytes() of legacy: %v", err)
+	}
+	p, err := ParsePartition(b)
+	if err != nil || !reflect.DeepEqual(p.Entries, legacy.Entries) || !bytes.Equal(p.prefix, b[:legacyOffset]) {
+		t.Errorf("ParsePartition(legacy) = %v, %v, want %v", p, err, legacy.Entries)
+	}
+	b[smbiosDMIOff+8]++
+	if _, err := ParsePartition(b); err == nil {
+		t.Errorf("ParsePartition(legacy) with a bad checksum succeeded")
+	}
+	legacy.prefix[smbiosLenOff] = 0
+	if _, err := legacy.Bytes(); err == nil {
+		t.Errorf("Bytes() of legacy with an invalid entry point length succeeded")
+	}
+
+	for _, b := range [][]byte{
+		make([]byte, 8),
+		make([]byte, 64),
+		append(append([]byte(nil), infoMagic...), 0xff, 0, 0, 0),
+		append(append([]byte(nil), infoMagic...), 3, 0, 0, 0, 1, 5, 'a'),
+	} {
+		if _, err := ParsePartition(b); err == nil {
+			t.Errorf("ParsePartition(%q) succeeded", b)
+		}
+	}
+}
+
+func TestFMAPArea(t *testing.T) {
+	image := make([]byte, 0x2000)
+	// A signature in code, with a wrong version.
+	copy(image[0x10:], "__FMAP__\x07")
+	h := image[0x100:]
+	copy(h, "__FMAP__\x01\x01")
+	binary.LittleEndian.PutUint16(h[54:], 2)
+	for i, a := range []struct {
+		name         string
+		offset, size uint32
+	}{
+		{"RO_VPD", 0x1000, 0x800},
+		{"RW_VPD", 0x1800, 0x800},
+	} {
+		area := h[fmapHeaderSize+i*fmapAreaSize:]
+		binary.LittleEndian.PutUint32(area, a.offset)
+		binary.LittleEndian.PutUint32(area[4:], a.size)
+		copy(area[8:], a.name)
+	}
+
+	if off, size, ok, err := FMAPArea(image, "RW_VPD"); err != nil || !ok || off != 0x1800 || size != 0x800 {
+		t.Errorf("FMAPArea(RW_VPD) = %#x, %#x, %v, %v, want 0x1800, 0x800, true", off, size, ok, err)
+	}
+	if _, _, ok, err := FMAPArea(image, "GBB"); !ok || err == nil {
+		t.Errorf("FMAPArea(GBB) = %v, %v, want true and an error", ok, err)
+	}
+	if _, _, ok, err := FMAPArea(make([]byte, 100), "RW_VPD"); ok || err != nil {
+		t.Errorf("FMAPArea without flash map = %v, %v, want false, nil", ok, err)
+	}
+}